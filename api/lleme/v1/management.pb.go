@@ -0,0 +1,789 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: lleme/v1/management.proto
+
+package llemev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EventType int32
+
+const (
+	EventType_EVENT_TYPE_UNSPECIFIED EventType = 0
+	EventType_EVENT_TYPE_LOADING     EventType = 1
+	EventType_EVENT_TYPE_LOADED      EventType = 2
+	EventType_EVENT_TYPE_LOAD_FAILED EventType = 3
+	EventType_EVENT_TYPE_UNLOADED    EventType = 4
+)
+
+// Enum value maps for EventType.
+var (
+	EventType_name = map[int32]string{
+		0: "EVENT_TYPE_UNSPECIFIED",
+		1: "EVENT_TYPE_LOADING",
+		2: "EVENT_TYPE_LOADED",
+		3: "EVENT_TYPE_LOAD_FAILED",
+		4: "EVENT_TYPE_UNLOADED",
+	}
+	EventType_value = map[string]int32{
+		"EVENT_TYPE_UNSPECIFIED": 0,
+		"EVENT_TYPE_LOADING":     1,
+		"EVENT_TYPE_LOADED":      2,
+		"EVENT_TYPE_LOAD_FAILED": 3,
+		"EVENT_TYPE_UNLOADED":    4,
+	}
+)
+
+func (x EventType) Enum() *EventType {
+	p := new(EventType)
+	*p = x
+	return p
+}
+
+func (x EventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_lleme_v1_management_proto_enumTypes[0].Descriptor()
+}
+
+func (EventType) Type() protoreflect.EnumType {
+	return &file_lleme_v1_management_proto_enumTypes[0]
+}
+
+func (x EventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EventType.Descriptor instead.
+func (EventType) EnumDescriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{0}
+}
+
+type Backend struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full model reference, e.g. "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M".
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// One of "starting", "ready", "stopping", "stopped".
+	Status        string                 `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Pid           int32                  `protobuf:"varint,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	LastActivity  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=last_activity,json=lastActivity,proto3" json:"last_activity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Backend) Reset() {
+	*x = Backend{}
+	mi := &file_lleme_v1_management_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Backend) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Backend) ProtoMessage() {}
+
+func (x *Backend) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Backend.ProtoReflect.Descriptor instead.
+func (*Backend) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Backend) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Backend) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Backend) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *Backend) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *Backend) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Backend) GetLastActivity() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastActivity
+	}
+	return nil
+}
+
+type LoadModelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Model name or fuzzy query, resolved like the OpenAI "model" field.
+	Model string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	// llama-server options such as "ctx-size" or "gpu-layers".
+	Options       map[string]*structpb.Value `protobuf:"bytes,2,rep,name=options,proto3" json:"options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadModelRequest) Reset() {
+	*x = LoadModelRequest{}
+	mi := &file_lleme_v1_management_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadModelRequest) ProtoMessage() {}
+
+func (x *LoadModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadModelRequest.ProtoReflect.Descriptor instead.
+func (*LoadModelRequest) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *LoadModelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *LoadModelRequest) GetOptions() map[string]*structpb.Value {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type LoadModelResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Backend       *Backend               `protobuf:"bytes,1,opt,name=backend,proto3" json:"backend,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadModelResponse) Reset() {
+	*x = LoadModelResponse{}
+	mi := &file_lleme_v1_management_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadModelResponse) ProtoMessage() {}
+
+func (x *LoadModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadModelResponse.ProtoReflect.Descriptor instead.
+func (*LoadModelResponse) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *LoadModelResponse) GetBackend() *Backend {
+	if x != nil {
+		return x.Backend
+	}
+	return nil
+}
+
+type UnloadModelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Model name or fuzzy query. Ignored when all is set.
+	Model         string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	All           bool   `protobuf:"varint,2,opt,name=all,proto3" json:"all,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelRequest) Reset() {
+	*x = UnloadModelRequest{}
+	mi := &file_lleme_v1_management_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelRequest) ProtoMessage() {}
+
+func (x *UnloadModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelRequest.ProtoReflect.Descriptor instead.
+func (*UnloadModelRequest) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UnloadModelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *UnloadModelRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+type UnloadModelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full names of the models that were unloaded.
+	Models        []string `protobuf:"bytes,1,rep,name=models,proto3" json:"models,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnloadModelResponse) Reset() {
+	*x = UnloadModelResponse{}
+	mi := &file_lleme_v1_management_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnloadModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnloadModelResponse) ProtoMessage() {}
+
+func (x *UnloadModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnloadModelResponse.ProtoReflect.Descriptor instead.
+func (*UnloadModelResponse) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UnloadModelResponse) GetModels() []string {
+	if x != nil {
+		return x.Models
+	}
+	return nil
+}
+
+type ListBackendsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBackendsRequest) Reset() {
+	*x = ListBackendsRequest{}
+	mi := &file_lleme_v1_management_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBackendsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBackendsRequest) ProtoMessage() {}
+
+func (x *ListBackendsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBackendsRequest.ProtoReflect.Descriptor instead.
+func (*ListBackendsRequest) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{5}
+}
+
+type ListBackendsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Backends      []*Backend             `protobuf:"bytes,1,rep,name=backends,proto3" json:"backends,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBackendsResponse) Reset() {
+	*x = ListBackendsResponse{}
+	mi := &file_lleme_v1_management_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBackendsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBackendsResponse) ProtoMessage() {}
+
+func (x *ListBackendsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBackendsResponse.ProtoReflect.Descriptor instead.
+func (*ListBackendsResponse) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListBackendsResponse) GetBackends() []*Backend {
+	if x != nil {
+		return x.Backends
+	}
+	return nil
+}
+
+type StreamEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_lleme_v1_management_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{7}
+}
+
+type StreamEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          EventType              `protobuf:"varint,1,opt,name=type,proto3,enum=lleme.v1.EventType" json:"type,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsResponse) Reset() {
+	*x = StreamEventsResponse{}
+	mi := &file_lleme_v1_management_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsResponse) ProtoMessage() {}
+
+func (x *StreamEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsResponse.ProtoReflect.Descriptor instead.
+func (*StreamEventsResponse) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamEventsResponse) GetType() EventType {
+	if x != nil {
+		return x.Type
+	}
+	return EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *StreamEventsResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *StreamEventsResponse) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type PullModelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Hugging Face reference "user/repo" or "user/repo:quant". Without a
+	// quant, the configured default or the best available one is used.
+	Model         string `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullModelRequest) Reset() {
+	*x = PullModelRequest{}
+	mi := &file_lleme_v1_management_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullModelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullModelRequest) ProtoMessage() {}
+
+func (x *PullModelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullModelRequest.ProtoReflect.Descriptor instead.
+func (*PullModelRequest) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *PullModelRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+type PullModelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "download" or "verify" while in progress, "done" in the final message.
+	Phase     string `protobuf:"bytes,1,opt,name=phase,proto3" json:"phase,omitempty"`
+	Completed int64  `protobuf:"varint,2,opt,name=completed,proto3" json:"completed,omitempty"`
+	Total     int64  `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	// Full name of the pulled model, set in the final message.
+	Model         string `protobuf:"bytes,4,opt,name=model,proto3" json:"model,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PullModelResponse) Reset() {
+	*x = PullModelResponse{}
+	mi := &file_lleme_v1_management_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PullModelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PullModelResponse) ProtoMessage() {}
+
+func (x *PullModelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lleme_v1_management_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PullModelResponse.ProtoReflect.Descriptor instead.
+func (*PullModelResponse) Descriptor() ([]byte, []int) {
+	return file_lleme_v1_management_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PullModelResponse) GetPhase() string {
+	if x != nil {
+		return x.Phase
+	}
+	return ""
+}
+
+func (x *PullModelResponse) GetCompleted() int64 {
+	if x != nil {
+		return x.Completed
+	}
+	return 0
+}
+
+func (x *PullModelResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *PullModelResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+var File_lleme_v1_management_proto protoreflect.FileDescriptor
+
+const file_lleme_v1_management_proto_rawDesc = "" +
+	"\n" +
+	"\x19lleme/v1/management.proto\x12\blleme.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd7\x01\n" +
+	"\aBackend\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\x05R\x03pid\x129\n" +
+	"\n" +
+	"started_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12?\n" +
+	"\rlast_activity\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\flastActivity\"\xbf\x01\n" +
+	"\x10LoadModelRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12A\n" +
+	"\aoptions\x18\x02 \x03(\v2'.lleme.v1.LoadModelRequest.OptionsEntryR\aoptions\x1aR\n" +
+	"\fOptionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x02 \x01(\v2\x16.google.protobuf.ValueR\x05value:\x028\x01\"@\n" +
+	"\x11LoadModelResponse\x12+\n" +
+	"\abackend\x18\x01 \x01(\v2\x11.lleme.v1.BackendR\abackend\"<\n" +
+	"\x12UnloadModelRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x10\n" +
+	"\x03all\x18\x02 \x01(\bR\x03all\"-\n" +
+	"\x13UnloadModelResponse\x12\x16\n" +
+	"\x06models\x18\x01 \x03(\tR\x06models\"\x15\n" +
+	"\x13ListBackendsRequest\"E\n" +
+	"\x14ListBackendsResponse\x12-\n" +
+	"\bbackends\x18\x01 \x03(\v2\x11.lleme.v1.BackendR\bbackends\"\x15\n" +
+	"\x13StreamEventsRequest\"\x85\x01\n" +
+	"\x14StreamEventsResponse\x12'\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x13.lleme.v1.EventTypeR\x04type\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12.\n" +
+	"\x04time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"(\n" +
+	"\x10PullModelRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\"s\n" +
+	"\x11PullModelResponse\x12\x14\n" +
+	"\x05phase\x18\x01 \x01(\tR\x05phase\x12\x1c\n" +
+	"\tcompleted\x18\x02 \x01(\x03R\tcompleted\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x03R\x05total\x12\x14\n" +
+	"\x05model\x18\x04 \x01(\tR\x05model*\x8b\x01\n" +
+	"\tEventType\x12\x1a\n" +
+	"\x16EVENT_TYPE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12EVENT_TYPE_LOADING\x10\x01\x12\x15\n" +
+	"\x11EVENT_TYPE_LOADED\x10\x02\x12\x1a\n" +
+	"\x16EVENT_TYPE_LOAD_FAILED\x10\x03\x12\x17\n" +
+	"\x13EVENT_TYPE_UNLOADED\x10\x042\x8d\x03\n" +
+	"\x11ManagementService\x12D\n" +
+	"\tLoadModel\x12\x1a.lleme.v1.LoadModelRequest\x1a\x1b.lleme.v1.LoadModelResponse\x12J\n" +
+	"\vUnloadModel\x12\x1c.lleme.v1.UnloadModelRequest\x1a\x1d.lleme.v1.UnloadModelResponse\x12M\n" +
+	"\fListBackends\x12\x1d.lleme.v1.ListBackendsRequest\x1a\x1e.lleme.v1.ListBackendsResponse\x12O\n" +
+	"\fStreamEvents\x12\x1d.lleme.v1.StreamEventsRequest\x1a\x1e.lleme.v1.StreamEventsResponse0\x01\x12F\n" +
+	"\tPullModel\x12\x1a.lleme.v1.PullModelRequest\x1a\x1b.lleme.v1.PullModelResponse0\x01B0Z.github.com/nchapman/lleme/api/lleme/v1;llemev1b\x06proto3"
+
+var (
+	file_lleme_v1_management_proto_rawDescOnce sync.Once
+	file_lleme_v1_management_proto_rawDescData []byte
+)
+
+func file_lleme_v1_management_proto_rawDescGZIP() []byte {
+	file_lleme_v1_management_proto_rawDescOnce.Do(func() {
+		file_lleme_v1_management_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_lleme_v1_management_proto_rawDesc), len(file_lleme_v1_management_proto_rawDesc)))
+	})
+	return file_lleme_v1_management_proto_rawDescData
+}
+
+var file_lleme_v1_management_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_lleme_v1_management_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_lleme_v1_management_proto_goTypes = []any{
+	(EventType)(0),                // 0: lleme.v1.EventType
+	(*Backend)(nil),               // 1: lleme.v1.Backend
+	(*LoadModelRequest)(nil),      // 2: lleme.v1.LoadModelRequest
+	(*LoadModelResponse)(nil),     // 3: lleme.v1.LoadModelResponse
+	(*UnloadModelRequest)(nil),    // 4: lleme.v1.UnloadModelRequest
+	(*UnloadModelResponse)(nil),   // 5: lleme.v1.UnloadModelResponse
+	(*ListBackendsRequest)(nil),   // 6: lleme.v1.ListBackendsRequest
+	(*ListBackendsResponse)(nil),  // 7: lleme.v1.ListBackendsResponse
+	(*StreamEventsRequest)(nil),   // 8: lleme.v1.StreamEventsRequest
+	(*StreamEventsResponse)(nil),  // 9: lleme.v1.StreamEventsResponse
+	(*PullModelRequest)(nil),      // 10: lleme.v1.PullModelRequest
+	(*PullModelResponse)(nil),     // 11: lleme.v1.PullModelResponse
+	nil,                           // 12: lleme.v1.LoadModelRequest.OptionsEntry
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+	(*structpb.Value)(nil),        // 14: google.protobuf.Value
+}
+var file_lleme_v1_management_proto_depIdxs = []int32{
+	13, // 0: lleme.v1.Backend.started_at:type_name -> google.protobuf.Timestamp
+	13, // 1: lleme.v1.Backend.last_activity:type_name -> google.protobuf.Timestamp
+	12, // 2: lleme.v1.LoadModelRequest.options:type_name -> lleme.v1.LoadModelRequest.OptionsEntry
+	1,  // 3: lleme.v1.LoadModelResponse.backend:type_name -> lleme.v1.Backend
+	1,  // 4: lleme.v1.ListBackendsResponse.backends:type_name -> lleme.v1.Backend
+	0,  // 5: lleme.v1.StreamEventsResponse.type:type_name -> lleme.v1.EventType
+	13, // 6: lleme.v1.StreamEventsResponse.time:type_name -> google.protobuf.Timestamp
+	14, // 7: lleme.v1.LoadModelRequest.OptionsEntry.value:type_name -> google.protobuf.Value
+	2,  // 8: lleme.v1.ManagementService.LoadModel:input_type -> lleme.v1.LoadModelRequest
+	4,  // 9: lleme.v1.ManagementService.UnloadModel:input_type -> lleme.v1.UnloadModelRequest
+	6,  // 10: lleme.v1.ManagementService.ListBackends:input_type -> lleme.v1.ListBackendsRequest
+	8,  // 11: lleme.v1.ManagementService.StreamEvents:input_type -> lleme.v1.StreamEventsRequest
+	10, // 12: lleme.v1.ManagementService.PullModel:input_type -> lleme.v1.PullModelRequest
+	3,  // 13: lleme.v1.ManagementService.LoadModel:output_type -> lleme.v1.LoadModelResponse
+	5,  // 14: lleme.v1.ManagementService.UnloadModel:output_type -> lleme.v1.UnloadModelResponse
+	7,  // 15: lleme.v1.ManagementService.ListBackends:output_type -> lleme.v1.ListBackendsResponse
+	9,  // 16: lleme.v1.ManagementService.StreamEvents:output_type -> lleme.v1.StreamEventsResponse
+	11, // 17: lleme.v1.ManagementService.PullModel:output_type -> lleme.v1.PullModelResponse
+	13, // [13:18] is the sub-list for method output_type
+	8,  // [8:13] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_lleme_v1_management_proto_init() }
+func file_lleme_v1_management_proto_init() {
+	if File_lleme_v1_management_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_lleme_v1_management_proto_rawDesc), len(file_lleme_v1_management_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_lleme_v1_management_proto_goTypes,
+		DependencyIndexes: file_lleme_v1_management_proto_depIdxs,
+		EnumInfos:         file_lleme_v1_management_proto_enumTypes,
+		MessageInfos:      file_lleme_v1_management_proto_msgTypes,
+	}.Build()
+	File_lleme_v1_management_proto = out.File
+	file_lleme_v1_management_proto_goTypes = nil
+	file_lleme_v1_management_proto_depIdxs = nil
+}
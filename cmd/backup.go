@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/backup"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var backupForce bool
+
+var backupCmd = &cobra.Command{
+	Use:     "backup",
+	Short:   "Back up and restore settings for moving to another machine",
+	GroupID: "config",
+	Long: `Back up lleme's settings into a single tarball and restore them elsewhere.
+
+A backup contains config.yaml, personas, saved prompts, and model metadata
+(tags, notes, and manifests). Model weights are not included; restore prints
+the pull commands to fetch them again.
+
+Examples:
+  lleme backup create                     # Write lleme-backup-<date>.tar.gz
+  lleme backup create ~/lleme.tar.gz      # Write to a specific file
+  lleme backup restore ~/lleme.tar.gz     # Restore, keeping local changes
+  lleme backup restore ~/lleme.tar.gz -f  # Restore, overwriting local files`,
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create [file]",
+	Short: "Write a backup tarball",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := fmt.Sprintf("lleme-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			ui.Fatal("Failed to create backup: %v", err)
+		}
+		info, err := backup.Create(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			ui.Fatal("Failed to create backup: %v", err)
+		}
+
+		fmt.Printf("%s Backed up %d file(s) to %s\n", ui.Success("✓"), len(info.Files), path)
+		fmt.Println(ui.Muted(fmt.Sprintf("  Includes metadata for %d model(s); weights are not included", len(info.Models))))
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore settings from a backup tarball",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			ui.Fatal("Failed to open backup: %v", err)
+		}
+		defer f.Close()
+
+		result, err := backup.Restore(f, backupForce)
+		if err != nil {
+			ui.Fatal("Failed to restore backup: %v", err)
+		}
+
+		fmt.Printf("%s Restored %d file(s) from backup made %s\n", ui.Success("✓"),
+			len(result.Restored), result.Info.Created.Local().Format("2006-01-02 15:04"))
+		if len(result.Unchanged) > 0 {
+			fmt.Println(ui.Muted(fmt.Sprintf("  %d file(s) already up to date", len(result.Unchanged))))
+		}
+		if len(result.Skipped) > 0 {
+			fmt.Println()
+			fmt.Printf("%s Kept %d local file(s) that differ from the backup (use --force to overwrite):\n",
+				ui.Warning("!"), len(result.Skipped))
+			for _, name := range result.Skipped {
+				fmt.Printf("  %s\n", name)
+			}
+		}
+
+		var missing []string
+		for _, name := range result.Info.Models {
+			user, repo, quant := parseBackupModel(name)
+			if hf.FindModelFile(user, repo, quant) == "" {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			fmt.Println()
+			fmt.Printf("Model weights aren't included in backups. To download the %d missing model(s):\n", len(missing))
+			for _, name := range missing {
+				fmt.Printf("  lleme pull %s\n", name)
+			}
+		}
+	},
+}
+
+// parseBackupModel splits a user/repo:quant name from a backup
+func parseBackupModel(name string) (user, repo, quant string) {
+	name, quant, _ = strings.Cut(name, ":")
+	user, repo, _ = strings.Cut(name, "/")
+	return user, repo, quant
+}
+
+func init() {
+	backupRestoreCmd.Flags().BoolVarP(&backupForce, "force", "f", false, "Overwrite local files that differ from the backup")
+	backupCmd.AddCommand(backupCreateCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
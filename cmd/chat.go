@@ -3,9 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
 
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/filter"
+	"github.com/nchapman/lleme/internal/lang"
 	"github.com/nchapman/lleme/internal/options"
 	"github.com/nchapman/lleme/internal/server"
 	"github.com/nchapman/lleme/internal/ui"
@@ -17,6 +20,8 @@ type ChatSession struct {
 	model    string
 	persona  *config.Persona
 	resolver *options.Resolver
+	filter   *filter.Filter
+	output   io.Writer
 	messages []server.ChatMessage
 
 	// Options
@@ -27,6 +32,8 @@ type ChatSession struct {
 	topK          int
 	repeatPenalty float64
 	minP          float64
+	maxContinues  int
+	respondIn     string
 }
 
 // NewChatSession creates a new chat session.
@@ -35,8 +42,11 @@ func NewChatSession(api *server.APIClient, model string, cfg *config.Config, per
 		api:      api,
 		model:    model,
 		persona:  persona,
-		resolver: options.NewResolver(persona, cfg),
+		resolver: options.NewResolver(persona, cfg).WithModel(model),
 		messages: []server.ChatMessage{},
+
+		maxContinues: cfg.Chat.MaxContinues,
+		respondIn:    persona.ResponseLanguage(cfg),
 	}
 }
 
@@ -45,6 +55,21 @@ func (s *ChatSession) SetSystemPrompt(prompt string) {
 	s.systemPrompt = prompt
 }
 
+// SetOutputFilter sets the filter applied to responses before they're printed.
+func (s *ChatSession) SetOutputFilter(f *filter.Filter) {
+	s.filter = f
+}
+
+// SetOutput sets a writer that receives a copy of the response as it streams.
+func (s *ChatSession) SetOutput(w io.Writer) {
+	s.output = w
+}
+
+// SetMaxContinues sets how many times a reply cut off by the token limit is continued.
+func (s *ChatSession) SetMaxContinues(n int) {
+	s.maxContinues = n
+}
+
 // SetSamplingOptions sets the sampling parameters for generation.
 func (s *ChatSession) SetSamplingOptions(temp, topP, minP, repeatPenalty float64, topK, maxTokens int) {
 	s.temp = temp
@@ -71,11 +96,22 @@ func (s *ChatSession) initSystemPrompt() {
 	if sysPrompt == "" {
 		sysPrompt = config.DefaultSystemPrompt()
 	}
+	if s.respondIn != "" {
+		sysPrompt += "\n\n" + lang.Instruction(s.respondIn)
+	}
 	s.messages = []server.ChatMessage{{Role: "system", Content: sysPrompt}}
 }
 
-// streamResponse sends the chat completion request and streams output.
-func (s *ChatSession) streamResponse() error {
+// Request returns the chat completion request Run would send for prompt,
+// for running it elsewhere such as in a background job.
+func (s *ChatSession) Request(prompt string) *server.ChatCompletionRequest {
+	s.initSystemPrompt()
+	s.messages = append(s.messages, server.ChatMessage{Role: "user", Content: prompt})
+	return s.request()
+}
+
+// request builds the chat completion request for the messages so far.
+func (s *ChatSession) request() *server.ChatCompletionRequest {
 	req := &server.ChatCompletionRequest{
 		Model:           s.model,
 		Messages:        s.messages,
@@ -90,10 +126,33 @@ func (s *ChatSession) streamResponse() error {
 	req.TopK = s.resolver.ResolveInt(s.topK, "top-k")
 	req.MinP = s.resolver.ResolveFloat(s.minP, "min-p")
 	req.RepeatPenalty = s.resolver.ResolveFloat(s.repeatPenalty, "repeat-penalty")
+	return req
+}
+
+// streamResponse sends the chat completion request and streams output.
+func (s *ChatSession) streamResponse() error {
+	req := s.request()
 
 	var fullResponse strings.Builder
 	hadReasoning := false
 	inReasoning := false
+	stream := s.filter.Stream()
+	var outputErr error
+
+	printContent := func(content string) {
+		if content == "" {
+			return
+		}
+		if inReasoning {
+			fmt.Print("\n\n")
+			inReasoning = false
+		}
+		fullResponse.WriteString(content)
+		fmt.Print(content)
+		if s.output != nil && outputErr == nil {
+			_, outputErr = io.WriteString(s.output, content)
+		}
+	}
 
 	cb := server.StreamCallback{
 		ReasoningCallback: func(reasoning string) {
@@ -102,16 +161,12 @@ func (s *ChatSession) streamResponse() error {
 			fmt.Print(ui.Muted(reasoning))
 		},
 		ContentCallback: func(content string) {
-			if inReasoning {
-				fmt.Print("\n\n")
-				inReasoning = false
-			}
-			fullResponse.WriteString(content)
-			fmt.Print(content)
+			printContent(stream.Write(content))
 		},
 	}
 
-	err := s.api.StreamChatCompletion(context.Background(), req, cb)
+	err := s.api.StreamChatCompletionInLanguage(context.Background(), req, cb, s.maxContinues, s.respondIn)
+	printContent(stream.Flush())
 
 	if hadReasoning && fullResponse.Len() == 0 {
 		fmt.Println()
@@ -122,5 +177,11 @@ func (s *ChatSession) streamResponse() error {
 	}
 
 	fmt.Println()
+	if s.output != nil && outputErr == nil {
+		_, outputErr = io.WriteString(s.output, "\n")
+	}
+	if outputErr != nil {
+		return fmt.Errorf("failed to write output: %w", outputErr)
+	}
 	return nil
 }
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var cleanCmd = &cobra.Command{
+	Use:     "clean",
+	Short:   "Remove cached files no model uses",
+	GroupID: "config",
+	Long: `Remove cached files that no downloaded model uses.
+
+Patched chat templates are cached by content in the templates directory of
+the cache and shared between models; templates left behind by removed or updated
+models are deleted. Use 'lleme remove --partial-downloads' for interrupted
+downloads.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, err := proxy.PruneTemplateCache()
+		if err != nil {
+			ui.Fatal("Failed to prune template cache: %v", err)
+		}
+		if removed == 0 {
+			fmt.Println("Nothing to clean")
+			return
+		}
+		fmt.Printf("Removed %d unused chat template(s)\n", removed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanCmd)
+}
@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	comparePrompts []string
+	compareSystem  string
+	compareTokens  int
+	compareFormat  string
+	compareOutput  string
+)
+
+var compareCmd = &cobra.Command{
+	Use:     "compare <model> <model>... -p <prompt>",
+	Short:   "Ask several models the same prompts and report how they did",
+	GroupID: "model",
+	Long: `Ask several models the same prompts, one model at a time, and write a
+report: a summary of each model's load time, time to first token, tokens
+per second and output length, then every prompt with each model's answer.
+
+Prompts come from -p, which can be repeated, or from stdin. The report is
+markdown, for pasting into a doc, or JSON with --format json or an -o file
+ending in .json.
+
+Examples:
+  lleme compare llama3.2 qwen2.5 -p "Explain RAID 5 in two sentences"
+  lleme compare llama3.2 qwen2.5 gemma3 -p "..." -p "..." -o report.md
+  cat prompt.txt | lleme compare llama3.2 qwen2.5 --format json`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			ui.Fatal("Failed to load config: %v", err)
+		}
+
+		system, err := config.ResolveSystemPrompt(compareSystem)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		prompts := comparePrompts
+		if len(prompts) == 0 {
+			stat, _ := os.Stdin.Stat()
+			if stat.Mode()&os.ModeCharDevice == 0 {
+				input, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					ui.Fatal("Failed to read stdin: %v", err)
+				}
+				if s := strings.TrimSpace(string(input)); s != "" {
+					prompts = []string{s}
+				}
+			}
+		}
+		if len(prompts) == 0 {
+			ui.Fatal("No prompt given; pass one with -p or on stdin")
+		}
+
+		format := compareFormat
+		if format == "" {
+			format = "markdown"
+			if strings.EqualFold(filepath.Ext(compareOutput), ".json") {
+				format = "json"
+			}
+		}
+		if format != "markdown" && format != "json" {
+			ui.Fatal("Unknown format %q; use markdown or json", format)
+		}
+
+		api, models := compareModels(args, cfg)
+		report := &compareReport{
+			Created: time.Now(),
+			System:  system,
+			Prompts: prompts,
+		}
+		for i, model := range models {
+			fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("[%d/%d] %s", i+1, len(models), model)))
+			report.Models = append(report.Models, runComparison(api, model, system, prompts))
+		}
+
+		out := io.Writer(os.Stdout)
+		if compareOutput != "" {
+			f, err := os.Create(compareOutput)
+			if err != nil {
+				ui.Fatal("Failed to create report: %v", err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if format == "json" {
+			err = writeCompareJSON(out, report)
+		} else {
+			err = writeCompareMarkdown(out, report)
+		}
+		if err != nil {
+			ui.Fatal("Failed to write report: %v", err)
+		}
+		if compareOutput != "" {
+			fmt.Printf("%s Wrote report to %s\n", ui.Success("✓"), compareOutput)
+		}
+	},
+}
+
+// compareModels resolves each query to a model name on the server that
+// will run it, starting the local server if needed
+func compareModels(queries []string, cfg *config.Config) (*server.APIClient, []string) {
+	var models []string
+	if remote := remoteURL(); remote != "" {
+		for _, q := range queries {
+			name, err := resolveRemoteModel(remote, q)
+			if err != nil {
+				ui.Fatal("%v", err)
+			}
+			models = append(models, name)
+		}
+		return remoteAPI(remote), models
+	}
+
+	if !llama.IsInstalled() && !mock.Enabled() {
+		if err := ensureLlamaInstalled(); err != nil {
+			ui.Fatal("%v", err)
+		}
+	}
+	for _, q := range queries {
+		model, err := validateModel(q, cfg)
+		if errors.Is(err, ui.ErrCancelled) {
+			os.Exit(1)
+		} else if err != nil {
+			ui.Fatal("%v", err)
+		}
+		models = append(models, model.FullName)
+	}
+
+	proxyURL, err := ensureProxyRunning(cfg)
+	if err != nil {
+		ui.Fatal("Failed to start proxy: %v", err)
+	}
+	api := server.NewAPIClientFromURL(proxyURL)
+	if err := api.Health(); err != nil {
+		ui.Fatal("Proxy health check failed: %v", err)
+	}
+	return api, models
+}
+
+// runComparison loads model and asks it each prompt in turn. Loading comes
+// first so it isn't counted against the first answer.
+func runComparison(api *server.APIClient, model, system string, prompts []string) compareModel {
+	result := compareModel{Model: model}
+
+	start := time.Now()
+	if err := api.Run(model, nil); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.LoadSeconds = time.Since(start).Seconds()
+
+	for _, prompt := range prompts {
+		var messages []server.ChatMessage
+		if system != "" {
+			messages = append(messages, server.ChatMessage{Role: "system", Content: system})
+		}
+		messages = append(messages, server.ChatMessage{Role: "user", Content: prompt})
+		result.Answers = append(result.Answers, askForComparison(api, &server.ChatCompletionRequest{
+			Model:           model,
+			Messages:        messages,
+			Stream:          true,
+			StreamOptions:   &server.StreamOptions{IncludeUsage: true},
+			MaxTokens:       compareTokens,
+			ReasoningFormat: "auto",
+		}))
+	}
+	return result
+}
+
+// askForComparison streams one reply, timing it
+func askForComparison(api *server.APIClient, req *server.ChatCompletionRequest) compareAnswer {
+	var answer compareAnswer
+	var content strings.Builder
+	start := time.Now()
+	var first time.Time
+	mark := func() {
+		if first.IsZero() {
+			first = time.Now()
+		}
+	}
+
+	cb := server.StreamCallback{
+		ContentCallback: func(s string) {
+			mark()
+			content.WriteString(s)
+		},
+		ReasoningCallback: func(string) { mark() },
+		TimingsCallback: func(t *server.Timings) {
+			if t != nil {
+				answer.TokensPerSecond = t.PredictedPerSecond
+			}
+		},
+		UsageCallback: func(u *server.Usage) {
+			if u != nil {
+				answer.PromptTokens = u.PromptTokens
+				answer.CompletionTokens = u.CompletionTokens
+			}
+		},
+	}
+	err := api.StreamChatCompletion(context.Background(), req, cb)
+
+	answer.Content = strings.TrimSpace(content.String())
+	answer.TotalSeconds = time.Since(start).Seconds()
+	if !first.IsZero() {
+		answer.FirstTokenSeconds = first.Sub(start).Seconds()
+	}
+	// Servers that send no timings still report usage
+	if answer.TokensPerSecond == 0 && answer.CompletionTokens > 0 && !first.IsZero() {
+		if gen := time.Since(first).Seconds(); gen > 0 {
+			answer.TokensPerSecond = float64(answer.CompletionTokens) / gen
+		}
+	}
+	if err != nil {
+		answer.Error = err.Error()
+	}
+	return answer
+}
+
+// compareReport is the result of a compare run
+type compareReport struct {
+	Created time.Time      `json:"created"`
+	System  string         `json:"system,omitempty"`
+	Prompts []string       `json:"prompts"`
+	Models  []compareModel `json:"models"`
+}
+
+// compareModel is how one model did
+type compareModel struct {
+	Model       string          `json:"model"`
+	LoadSeconds float64         `json:"load_seconds"`
+	Answers     []compareAnswer `json:"answers"` // One per prompt, in order
+	Error       string          `json:"error,omitempty"`
+}
+
+// compareAnswer is one model's reply to one prompt
+type compareAnswer struct {
+	Content           string  `json:"content"`
+	FirstTokenSeconds float64 `json:"first_token_seconds"`
+	TotalSeconds      float64 `json:"total_seconds"`
+	TokensPerSecond   float64 `json:"tokens_per_second"`
+	PromptTokens      int     `json:"prompt_tokens"`
+	CompletionTokens  int     `json:"completion_tokens"`
+	Error             string  `json:"error,omitempty"`
+}
+
+// compareSummary averages a model's answers, leaving out failed ones
+type compareSummary struct {
+	FirstTokenSeconds float64 `json:"first_token_seconds"`
+	TokensPerSecond   float64 `json:"tokens_per_second"`
+	CompletionTokens  float64 `json:"completion_tokens"`
+	TotalSeconds      float64 `json:"total_seconds"` // Summed, not averaged
+	Failed            int     `json:"failed"`
+}
+
+func (m compareModel) summary() compareSummary {
+	var s compareSummary
+	n := 0
+	for _, a := range m.Answers {
+		s.TotalSeconds += a.TotalSeconds
+		if a.Error != "" {
+			s.Failed++
+			continue
+		}
+		n++
+		s.FirstTokenSeconds += a.FirstTokenSeconds
+		s.TokensPerSecond += a.TokensPerSecond
+		s.CompletionTokens += float64(a.CompletionTokens)
+	}
+	if n > 0 {
+		s.FirstTokenSeconds /= float64(n)
+		s.TokensPerSecond /= float64(n)
+		s.CompletionTokens /= float64(n)
+	}
+	return s
+}
+
+// writeCompareJSON writes the report with each model's summary alongside
+func writeCompareJSON(w io.Writer, r *compareReport) error {
+	type model struct {
+		compareModel
+		Summary compareSummary `json:"summary"`
+	}
+	out := struct {
+		*compareReport
+		Models []model `json:"models"`
+	}{compareReport: r}
+	for _, m := range r.Models {
+		out.Models = append(out.Models, model{m, m.summary()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// writeCompareMarkdown writes the report as a summary table followed by
+// each prompt with every model's answer
+func writeCompareMarkdown(w io.Writer, r *compareReport) error {
+	var b strings.Builder
+	b.WriteString("# Model comparison\n\n")
+	fmt.Fprintf(&b, "%s · %d models · %d prompts\n\n", r.Created.Local().Format("2006-01-02 15:04"), len(r.Models), len(r.Prompts))
+	if r.System != "" {
+		b.WriteString("System prompt:\n\n")
+		writeQuote(&b, r.System)
+	}
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Model | Load | First token | Tokens/s | Output tokens | Total time | Failed |\n")
+	b.WriteString("|---|--:|--:|--:|--:|--:|--:|\n")
+	for _, m := range r.Models {
+		if m.Error != "" {
+			fmt.Fprintf(&b, "| %s | failed | | | | | |\n", m.Model)
+			continue
+		}
+		s := m.summary()
+		fmt.Fprintf(&b, "| %s | %.1fs | %.2fs | %.1f | %.0f | %.1fs | %d |\n",
+			m.Model, m.LoadSeconds, s.FirstTokenSeconds, s.TokensPerSecond, s.CompletionTokens, s.TotalSeconds, s.Failed)
+	}
+	b.WriteString("\nFirst token, tokens/s and output tokens are averages over the prompts each model answered.\n")
+
+	for i, prompt := range r.Prompts {
+		fmt.Fprintf(&b, "\n## Prompt %d\n\n", i+1)
+		writeQuote(&b, prompt)
+		for _, m := range r.Models {
+			fmt.Fprintf(&b, "### %s\n\n", m.Model)
+			if m.Error != "" {
+				fmt.Fprintf(&b, "Failed to load: %s\n\n", m.Error)
+				continue
+			}
+			a := m.Answers[i]
+			fmt.Fprintf(&b, "*%.2fs to first token · %.1f tokens/s · %d tokens · %.1fs*\n\n",
+				a.FirstTokenSeconds, a.TokensPerSecond, a.CompletionTokens, a.TotalSeconds)
+			if a.Error != "" {
+				fmt.Fprintf(&b, "Failed: %s\n\n", a.Error)
+			}
+			if a.Content != "" {
+				b.WriteString(a.Content + "\n\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+	return err
+}
+
+// writeQuote writes s as a markdown blockquote
+func writeQuote(b *strings.Builder, s string) {
+	for line := range strings.SplitSeq(s, "\n") {
+		b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	b.WriteString("\n")
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+	addRemoteFlag(compareCmd)
+
+	compareCmd.Flags().StringArrayVarP(&comparePrompts, "prompt", "p", nil, "Prompt to ask every model (repeatable)")
+	compareCmd.Flags().StringVarP(&compareSystem, "system", "s", "", "System prompt, or @name for a saved prompt")
+	compareCmd.Flags().IntVarP(&compareTokens, "predict", "n", 0, "Max tokens per answer")
+	compareCmd.Flags().StringVar(&compareFormat, "format", "", "Report format: markdown or json (default: from -o, else markdown)")
+	compareCmd.Flags().StringVarP(&compareOutput, "output", "o", "", "Write the report to this file instead of stdout")
+}
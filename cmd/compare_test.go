@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+func testCompareReport() *compareReport {
+	return &compareReport{
+		Created: time.Date(2026, 10, 15, 14, 22, 0, 0, time.Local),
+		Prompts: []string{"Explain RAID 5", "Name a prime"},
+		Models: []compareModel{
+			{
+				Model:       "user/fast:Q4_K_M",
+				LoadSeconds: 2.5,
+				Answers: []compareAnswer{
+					{Content: "Striping with parity.", FirstTokenSeconds: 0.25, TotalSeconds: 1, TokensPerSecond: 50, CompletionTokens: 40},
+					{Content: "7", FirstTokenSeconds: 0.4, TotalSeconds: 0.5, TokensPerSecond: 30, CompletionTokens: 2},
+				},
+			},
+			{
+				Model:       "user/flaky:Q8_0",
+				LoadSeconds: 4,
+				Answers: []compareAnswer{
+					{Content: "Parity spread across disks.", FirstTokenSeconds: 1, TotalSeconds: 3, TokensPerSecond: 10, CompletionTokens: 30},
+					{FirstTokenSeconds: 0, TotalSeconds: 2, Error: "backend crashed"},
+				},
+			},
+			{Model: "user/huge:F16", Error: "not enough memory"},
+		},
+	}
+}
+
+func TestCompareSummary(t *testing.T) {
+	r := testCompareReport()
+
+	s := r.Models[0].summary()
+	if s.FirstTokenSeconds != 0.325 || s.TokensPerSecond != 40 || s.CompletionTokens != 21 || s.TotalSeconds != 1.5 || s.Failed != 0 {
+		t.Errorf("summary = %+v", s)
+	}
+
+	// A failed answer counts toward time but not the averages
+	s = r.Models[1].summary()
+	if s.FirstTokenSeconds != 1 || s.TokensPerSecond != 10 || s.TotalSeconds != 5 || s.Failed != 1 {
+		t.Errorf("summary with failure = %+v", s)
+	}
+}
+
+func TestWriteCompareMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := writeCompareMarkdown(&b, testCompareReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# Model comparison",
+		"3 models · 2 prompts",
+		"| user/fast:Q4_K_M | 2.5s | 0.33s | 40.0 | 21 | 1.5s | 0 |",
+		"| user/huge:F16 | failed |",
+		"## Prompt 1\n\n> Explain RAID 5\n",
+		"### user/fast:Q4_K_M\n\n*0.25s to first token · 50.0 tokens/s · 40 tokens · 1.0s*\n\nStriping with parity.",
+		"Failed: backend crashed",
+		"Failed to load: not enough memory",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "## Prompt 1") > strings.Index(out, "## Prompt 2") {
+		t.Error("prompts out of order")
+	}
+}
+
+func TestWriteCompareJSON(t *testing.T) {
+	var b strings.Builder
+	if err := writeCompareJSON(&b, testCompareReport()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Prompts []string `json:"prompts"`
+		Models  []struct {
+			Model   string          `json:"model"`
+			Answers []compareAnswer `json:"answers"`
+			Error   string          `json:"error"`
+			Summary compareSummary  `json:"summary"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, b.String())
+	}
+	if len(got.Prompts) != 2 || len(got.Models) != 3 {
+		t.Fatalf("got %d prompts, %d models", len(got.Prompts), len(got.Models))
+	}
+	if m := got.Models[0]; m.Summary.TokensPerSecond != 40 || m.Answers[1].Content != "7" {
+		t.Errorf("first model = %+v", m)
+	}
+	if got.Models[2].Error != "not enough memory" {
+		t.Errorf("load error = %q", got.Models[2].Error)
+	}
+}
+
+func TestAskForComparison(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, chunk := range []server.StreamChunk{
+			{Choices: []server.StreamChoice{{Delta: server.StreamDelta{Content: " Seven"}}}},
+			{
+				Choices: []server.StreamChoice{{FinishReason: "stop"}},
+				Usage:   &server.Usage{PromptTokens: 12, CompletionTokens: 3},
+				Timings: &server.Timings{PredictedPerSecond: 25},
+			},
+		} {
+			data, _ := json.Marshal(chunk)
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	api := server.NewAPIClientFromURL(ts.URL)
+	a := askForComparison(api, &server.ChatCompletionRequest{Model: "m", Stream: true})
+	if a.Content != "Seven" || a.PromptTokens != 12 || a.CompletionTokens != 3 || a.TokensPerSecond != 25 {
+		t.Errorf("answer = %+v", a)
+	}
+	if a.Error != "" || a.TotalSeconds <= 0 || a.FirstTokenSeconds > a.TotalSeconds {
+		t.Errorf("timing = %+v", a)
+	}
+}
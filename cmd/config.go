@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -30,7 +31,7 @@ var configEditCmd = &cobra.Command{
 	Use:   "edit",
 	Short: "Open config in $EDITOR",
 	Run: func(cmd *cobra.Command, args []string) {
-		openConfigInEditor(config.ConfigPath())
+		openConfigInEditor(paths.Config())
 	},
 }
 
@@ -46,7 +47,7 @@ var configPathCmd = &cobra.Command{
 	Use:   "path",
 	Short: "Print config file path",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(config.ConfigPath())
+		fmt.Println(paths.Config())
 	},
 }
 
@@ -54,7 +55,7 @@ var configResetCmd = &cobra.Command{
 	Use:   "reset",
 	Short: "Reset config to defaults",
 	Run: func(cmd *cobra.Command, args []string) {
-		resetToDefaults(config.ConfigPath())
+		resetToDefaults(paths.Config())
 	},
 }
 
@@ -98,7 +99,11 @@ Values are auto-detected as bool, int, float, or string.
 Examples:
   lleme config set server.port 8080
   lleme config set llamacpp.options.ctx-size 8192
-  lleme config set llamacpp.options.flash-attn true`,
+  lleme config set llamacpp.options.flash-attn on
+  lleme config set llamacpp.passthrough.some-new-flag 1
+
+llamacpp.options are checked against the llama-server options lleme knows;
+use llamacpp.passthrough for anything else.`,
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg, err := config.Load()
@@ -112,6 +117,9 @@ Examples:
 		}
 
 		value := parseValue(args[1])
+		if err := validateConfigValue(args[0], value); err != nil {
+			ui.Fatal("%v", err)
+		}
 
 		if err := setValueByPath(m, args[0], value); err != nil {
 			ui.Fatal("%v", err)
@@ -235,6 +243,26 @@ func parseValue(s string) any {
 	return s
 }
 
+// validateConfigValue checks a value being set under llamacpp.options or
+// llamacpp.model_options against the llama-server option catalog, and
+// server.memory_guard against its policies. Other paths, including
+// llamacpp.passthrough, aren't checked.
+func validateConfigValue(path string, value any) error {
+	if path == "server.memory_guard" {
+		return config.ValidateMemoryGuard(fmt.Sprint(value))
+	}
+	parts := strings.Split(path, ".")
+	if len(parts) < 3 || parts[0] != "llamacpp" {
+		return nil
+	}
+	switch {
+	case parts[1] == "options" && len(parts) == 3,
+		parts[1] == "model_options" && len(parts) >= 4:
+		return config.ValidateServerOption(parts[len(parts)-1], value)
+	}
+	return nil
+}
+
 // configToMap converts a Config to map[string]any via YAML round-trip.
 func configToMap(cfg *config.Config) (map[string]any, error) {
 	data, err := yaml.Marshal(cfg)
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
@@ -110,7 +111,7 @@ func TestPrintConfigOutput(t *testing.T) {
 }
 
 func TestConfigPath(t *testing.T) {
-	path := config.ConfigPath()
+	path := paths.Config()
 
 	if path == "" {
 		t.Error("Expected non-empty config path")
@@ -120,19 +121,17 @@ func TestConfigPath(t *testing.T) {
 		t.Errorf("Expected path to end with 'config.yaml', got '%s'", path)
 	}
 
-	if !strings.Contains(path, ".lleme") {
-		t.Errorf("Expected path to contain '.lleme', got '%s'", path)
+	if !strings.Contains(path, "lleme") {
+		t.Errorf("Expected path to contain 'lleme', got '%s'", path)
 	}
 }
 
 func TestOpenInEditorCreatesDefaultConfig(t *testing.T) {
 	// Create temp directory
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
-	configPath := filepath.Join(tmpDir, ".lleme", "config.yaml")
+	configPath := filepath.Join(tmpDir, "config.yaml")
 
 	// Verify config doesn't exist
 	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
@@ -243,11 +242,9 @@ func TestGetEditorWithPath(t *testing.T) {
 func TestResetToDefaults(t *testing.T) {
 	// Create temp directory
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
-	configPath := filepath.Join(tmpDir, ".lleme", "config.yaml")
+	configPath := filepath.Join(tmpDir, "config.yaml")
 
 	// Create a modified config
 	cfg := config.DefaultConfig()
@@ -473,9 +470,7 @@ func TestSetValueByPath(t *testing.T) {
 func TestConfigSetGetIntegration(t *testing.T) {
 	// Create temp directory for isolated config
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	// Start with default config
 	cfg := config.DefaultConfig()
@@ -591,9 +586,7 @@ func TestConfigSetSilentFailures(t *testing.T) {
 	// TODO: Consider adding validation to detect these cases.
 
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	cfg := config.DefaultConfig()
 	config.Save(cfg)
@@ -663,3 +656,28 @@ func TestConfigSetSilentFailures(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateConfigValue(t *testing.T) {
+	tests := []struct {
+		path    string
+		value   any
+		wantErr bool
+	}{
+		{"llamacpp.options.ctx-size", 8192, false},
+		{"llamacpp.options.ctx-size", "big", true},
+		{"llamacpp.options.top-p", 1.5, true},
+		{"llamacpp.options.some-new-flag", 1, true},
+		{"llamacpp.model_options.Qwen3-8B-GGUF.cache-type-k", "q8_0", false},
+		{"llamacpp.model_options.Qwen3-8B-GGUF.cache-type-k", "q3", true},
+		{"llamacpp.passthrough.some-new-flag", 1, false},
+		{"llamacpp.server_path", "/opt/llama-server", false},
+		{"server.port", 8080, false},
+		{"server.memory_guard", "reduce", false},
+		{"server.memory_guard", "refuze", true},
+	}
+	for _, tt := range tests {
+		if err := validateConfigValue(tt.path, tt.value); (err != nil) != tt.wantErr {
+			t.Errorf("validateConfigValue(%s, %v) error = %v, wantErr %v", tt.path, tt.value, err, tt.wantErr)
+		}
+	}
+}
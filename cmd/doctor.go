@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Short:   "Show detected hardware and llama.cpp build",
+	GroupID: "config",
+	Run: func(cmd *cobra.Command, args []string) {
+		info := hw.Detect()
+
+		fmt.Println(ui.Header("System"))
+		fmt.Printf("  %-10s %s/%s\n", "Platform", info.OS, info.Arch)
+		if info.CPU.Model != "" {
+			fmt.Printf("  %-10s %s\n", "CPU", info.CPU.Model)
+		}
+		fmt.Printf("  %-10s %d\n", "Cores", info.CPU.Cores)
+		fmt.Printf("  %-10s %s\n", "Features", formatFeatures(info.CPU.Features))
+		fmt.Printf("  %-10s %s\n", "Memory", formatMemory(info.Memory))
+		fmt.Println()
+
+		fmt.Println(ui.Header("GPUs"))
+		if len(info.GPUs) == 0 {
+			fmt.Println(ui.Muted("  No GPUs detected, models will run on CPU"))
+		}
+		for _, gpu := range info.GPUs {
+			fmt.Printf("  %s\n", formatGPU(gpu))
+		}
+		fmt.Println()
+
+		fmt.Println(ui.Header("llama.cpp"))
+		platform := llama.Platform()
+		if platform == "" {
+			platform = ui.ErrorMsg("no prebuilt binary for this platform")
+		}
+		fmt.Printf("  %-10s %s\n", "Build", platform)
+		if installed, _ := llama.GetInstalledVersion(); installed != nil {
+			fmt.Printf("  %-10s %s\n", "Installed", installed.TagName)
+			if installed.ArchiveSHA256 != "" {
+				fmt.Printf("  %-10s %s\n", "SHA256", installed.ArchiveSHA256)
+			}
+			printInstallIntegrity()
+		} else {
+			fmt.Printf("  %-10s %s\n", "Installed", ui.Muted("no (run 'lleme update')"))
+		}
+		fmt.Println()
+
+		fmt.Println(ui.Header("stable-diffusion.cpp"))
+		if installed, _ := llama.GetInstalledSDVersion(); installed != nil {
+			fmt.Printf("  %-10s %s\n", "Installed", installed.TagName)
+		} else {
+			fmt.Printf("  %-10s %s\n", "Installed", ui.Muted("no (run 'lleme update stable-diffusion')"))
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = nil
+		}
+		printFeatures(cfg)
+	},
+}
+
+// printFeatures lists the experimental features this build has, and which
+// are on, warning about names turned on that it doesn't know
+func printFeatures(cfg *config.Config) {
+	features := config.ExperimentalFeatures()
+	unknown := cfg.UnknownFeatures()
+	if len(features) == 0 && len(unknown) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(ui.Header("Experimental features"))
+	for _, f := range features {
+		state := ui.Muted("off")
+		if cfg.FeatureEnabled(f.Name) {
+			state = ui.Success("on ")
+		}
+		fmt.Printf("  %-16s %s %s\n", f.Name, state, ui.Muted(f.Description))
+	}
+	for _, name := range unknown {
+		fmt.Printf("  %-16s %s\n", name, ui.Warning("unknown to this version, ignored"))
+	}
+}
+
+// printInstallIntegrity re-hashes the installed llama.cpp files and reports
+// any that changed since they were installed
+func printInstallIntegrity() {
+	problems, err := llama.VerifyInstalled()
+	switch {
+	case errors.Is(err, llama.ErrNoChecksums):
+		fmt.Printf("  %-10s %s\n", "Verified", ui.Muted("no checksums recorded (run 'lleme update llama.cpp' to reinstall)"))
+	case err != nil:
+		fmt.Printf("  %-10s %s\n", "Verified", ui.ErrorMsg(err.Error()))
+	case len(problems) > 0:
+		fmt.Printf("  %-10s %s\n", "Verified", ui.ErrorMsg(fmt.Sprintf("%d files changed or missing (run 'lleme update llama.cpp' to reinstall)", len(problems))))
+		for _, problem := range problems {
+			fmt.Printf("  %-10s %s\n", "", problem)
+		}
+	default:
+		fmt.Printf("  %-10s %s\n", "Verified", ui.Success(ui.IconCheck+" installed files match their checksums"))
+	}
+}
+
+func formatFeatures(features []string) string {
+	if len(features) == 0 {
+		return ui.Muted("none detected")
+	}
+	return strings.Join(features, " ")
+}
+
+func formatMemory(mem hw.MemoryInfo) string {
+	if mem.Total == 0 {
+		return ui.Muted("unknown")
+	}
+	s := fmt.Sprintf("%s total, %s available", ui.FormatBytes(mem.Total), ui.FormatBytes(mem.Available))
+	if mem.Unified {
+		s += " " + ui.Muted("(unified)")
+	}
+	return s
+}
+
+func formatGPU(gpu hw.GPU) string {
+	var details []string
+	if gpu.Cores > 0 {
+		details = append(details, fmt.Sprintf("%d cores", gpu.Cores))
+	}
+	if gpu.Unified {
+		details = append(details, "unified memory")
+	} else if gpu.VRAMTotal > 0 {
+		details = append(details, fmt.Sprintf("%s VRAM, %s free", ui.FormatBytes(gpu.VRAMTotal), ui.FormatBytes(gpu.VRAMFree)))
+	}
+	if len(details) == 0 {
+		return gpu.Name
+	}
+	return fmt.Sprintf("%s %s", gpu.Name, ui.Muted("("+strings.Join(details, ", ")+")"))
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -83,3 +83,22 @@ func TestFormatNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestGroupModelsByRepo(t *testing.T) {
+	now := time.Now()
+	models := []ModelInfo{
+		{User: "a", Repo: "old", Quant: "Q4_K_M", LastUsed: now.Add(-48 * time.Hour)},
+		{User: "b", Repo: "multi", Quant: "Q8_0", LastUsed: now.Add(-72 * time.Hour)},
+		{User: "c", Repo: "new", Quant: "Q4_K_M", LastUsed: now},
+		{User: "b", Repo: "multi", Quant: "Q4_K_M", LastUsed: now.Add(-time.Hour)},
+	}
+
+	groupModelsByRepo(models)
+
+	want := []string{"c/new:Q4_K_M", "b/multi:Q4_K_M", "b/multi:Q8_0", "a/old:Q4_K_M"}
+	for i, m := range models {
+		if got := m.User + "/" + m.Repo + ":" + m.Quant; got != want[i] {
+			t.Errorf("models[%d] = %s, want %s", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/conversation"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyForce bool
+	historyFrom  string
+)
+
+var historyCmd = &cobra.Command{
+	Use:     "history",
+	Short:   "List and read conversations saved with /save",
+	GroupID: "model",
+	Long: `List and read conversations saved with /save in lleme run.
+
+Each conversation is titled when it's first saved, by the model it was
+held with in one short request, or with the words given to /save.
+
+Examples:
+  lleme history list                   # Saved conversations, newest first
+  lleme history show 20261015-142233-a1b2
+  lleme history rm 20261015-142233-a1b2
+  lleme history import --from chatgpt-export ~/Downloads/chatgpt-export.zip`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List saved conversations",
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := conversation.List()
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		if len(list) == 0 {
+			fmt.Println(ui.Muted("No conversations saved"))
+			fmt.Println()
+			fmt.Println("Save one with /save in: lleme run <model>")
+			return
+		}
+
+		fmt.Println(ui.Header("Conversations"))
+		fmt.Println()
+
+		table := ui.NewTable().
+			AddColumn("ID", 0, ui.AlignLeft).
+			AddColumn("TITLE", 50, ui.AlignLeft).
+			AddColumn("MODEL", 0, ui.AlignLeft).
+			AddColumn("EXCHANGES", 0, ui.AlignRight).
+			AddColumn("UPDATED", 0, ui.AlignRight)
+
+		for _, c := range list {
+			table.AddRow(c.ID, c.Title, c.Model, strconv.Itoa(c.Exchanges()), formatTime(c.Updated))
+		}
+
+		fmt.Print(table.Render())
+		fmt.Println()
+		fmt.Printf("%d conversation(s)\n", len(list))
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Print a saved conversation",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := conversation.Load(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		fmt.Printf("%s\n", ui.Header(c.Title))
+		fmt.Printf("%s %s\n", ui.Muted("Model:"), c.Model)
+		fmt.Printf("%s %s\n", ui.Muted("Saved:"), c.Updated.Local().Format("2006-01-02 15:04"))
+
+		for _, msg := range c.Messages {
+			fmt.Printf("\n%s\n%s\n", ui.Bold(roleLabel(msg.Role)), msg.Content)
+			if n := len(msg.Images); n > 0 {
+				fmt.Println(ui.Muted(fmt.Sprintf("[%d image(s)]", n)))
+			}
+		}
+	},
+}
+
+var historyRmCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Delete a saved conversation",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := conversation.Load(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		if !historyForce {
+			if !ui.PromptYesNo(i18n.Tf("Delete conversation '%s'?", c.Title), false) {
+				fmt.Println(ui.Muted(i18n.T("Cancelled")))
+				return
+			}
+		}
+
+		if err := conversation.Delete(c.ID); err != nil {
+			ui.Fatal("%v", err)
+		}
+		fmt.Printf("Deleted conversation '%s'\n", c.Title)
+	},
+}
+
+var historyImportCmd = &cobra.Command{
+	Use:   "import --from <format> <path>",
+	Short: "Import conversations exported from another tool",
+	Long: `Import conversations exported from another tool, keeping their titles
+and times, so they can be listed and read with lleme history.
+
+Formats:
+  ollama          A Modelfile from 'ollama show --modelfile <name>' of a
+                  session saved with /save, or a directory of them
+  openwebui       The JSON file from Open WebUI's chat export
+  chatgpt-export  ChatGPT's data export, as the zip, the unzipped
+                  directory, or its conversations.json
+
+Only the text of each conversation is kept: images, files and tool output
+are left out, and for chats that were edited or regenerated, the branch
+that was shown. Conversations imported before are skipped, so importing a
+newer export only adds what's new.
+
+Examples:
+  lleme history import --from chatgpt-export ~/Downloads/chatgpt-export.zip
+  lleme history import --from openwebui chat-export.json
+  ollama show --modelfile my-session > my-session.modelfile
+  lleme history import --from ollama my-session.modelfile`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if historyFrom == "" {
+			ui.Fatal("--from is required (%s)", strings.Join(conversation.ImportFormats, ", "))
+		}
+
+		result, err := conversation.Import(historyFrom, args[0])
+		if err != nil {
+			if result == nil || len(result.Imported) == 0 {
+				ui.Fatal("%v", err)
+			}
+			ui.PrintError("%v", err)
+		}
+
+		for _, c := range result.Imported {
+			fmt.Printf("%s %s\n", ui.Muted(c.ID), c.Title)
+		}
+		if len(result.Imported) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Imported %d conversation(s)\n", len(result.Imported))
+		if result.Skipped > 0 {
+			fmt.Println(ui.Muted(fmt.Sprintf("%d already imported", result.Skipped)))
+		}
+		if result.Empty > 0 {
+			fmt.Println(ui.Muted(fmt.Sprintf("%d without any messages", result.Empty)))
+		}
+	},
+}
+
+// roleLabel names who wrote a message in a printed conversation
+func roleLabel(role string) string {
+	switch role {
+	case "system":
+		return "System:"
+	case "user":
+		return "You:"
+	default:
+		return "Assistant:"
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyRmCmd)
+	historyCmd.AddCommand(historyImportCmd)
+
+	historyRmCmd.Flags().BoolVarP(&historyForce, "force", "f", false, "Skip confirmation")
+	historyImportCmd.Flags().StringVar(&historyFrom, "from", "", "Format of the export: "+strings.Join(conversation.ImportFormats, ", "))
+}
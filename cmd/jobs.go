@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var jobsFollow bool
+
+var jobsCmd = &cobra.Command{
+	Use:     "jobs",
+	Short:   "Manage background jobs started with run --detach",
+	GroupID: "model",
+	Long: `Manage prompts running as background jobs on the server.
+
+'lleme run <model> "prompt" --detach' hands the prompt to the server and
+prints a job ID at once. The server keeps generating after the command
+exits, and keeps the reply in memory until it restarts. Background jobs
+are experimental, so the server needs the background-jobs feature turned
+on in config.
+
+Examples:
+  lleme run llama "Write a long story" --detach
+  lleme jobs list                      # Jobs and their status
+  lleme jobs logs job_1a2b3c4d5e6f     # The reply so far
+  lleme jobs logs -f job_1a2b3c4d5e6f  # Follow the reply until it's done
+  lleme jobs cancel job_1a2b3c4d5e6f`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List background jobs",
+	Run: func(cmd *cobra.Command, args []string) {
+		baseURL := jobsServerURL()
+
+		resp, err := proxyHTTPClient(5 * time.Second).Get(baseURL + "/api/jobs")
+		if err != nil {
+			ui.Fatal("Failed to list jobs: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			ui.Fatal("Failed to list jobs: %v", responseError(resp))
+		}
+
+		var list struct {
+			Jobs []proxy.JobInfo `json:"jobs"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			ui.Fatal("Failed to decode jobs: %v", err)
+		}
+
+		if len(list.Jobs) == 0 {
+			fmt.Println(ui.Muted("No jobs"))
+			fmt.Println()
+			fmt.Println(`Start one with: lleme run <model> "prompt" --detach`)
+			return
+		}
+
+		fmt.Println(ui.Header("Jobs"))
+		fmt.Println()
+
+		table := ui.NewTable().
+			AddColumn("ID", 0, ui.AlignLeft).
+			AddColumn("MODEL", 0, ui.AlignLeft).
+			AddColumn("STATUS", 0, ui.AlignLeft).
+			AddColumn("STARTED", 0, ui.AlignRight)
+
+		for _, j := range list.Jobs {
+			table.AddRow(j.ID, j.Model, jobStatusText(j), formatTime(j.Created))
+		}
+
+		fmt.Print(table.Render())
+		fmt.Println()
+		fmt.Printf("%d job(s)\n", len(list.Jobs))
+	},
+}
+
+var jobsLogsCmd = &cobra.Command{
+	Use:   "logs <id>",
+	Short: "Print a job's reply",
+	Long: `Print a job's reply so far. With --follow, keep printing it as it's
+generated until the job ends.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		baseURL := jobsServerURL()
+		id := args[0]
+
+		u := baseURL + "/api/jobs/" + url.PathEscape(id) + "/logs"
+		if jobsFollow {
+			u += "?follow=true"
+		}
+		resp, err := proxyHTTPClient(0).Get(u)
+		if err != nil {
+			ui.Fatal("Failed to read job %s: %v", id, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			ui.Fatal("Failed to read job %s: %v", id, responseError(resp))
+		}
+		if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+			ui.Fatal("Failed to read job %s: %v", id, err)
+		}
+		fmt.Println()
+
+		// Say how the job ended, or that it's still going
+		job, err := getJob(baseURL, id)
+		if err != nil {
+			return
+		}
+		switch job.Status {
+		case proxy.JobRunning:
+			fmt.Fprintln(os.Stderr, ui.Muted("Still running; follow it with: lleme jobs logs -f "+id))
+		case proxy.JobFailed:
+			ui.Fatal("Job failed: %s", job.Error)
+		case proxy.JobCancelled:
+			fmt.Fprintln(os.Stderr, ui.Muted("Job was cancelled"))
+		}
+	},
+}
+
+var jobsCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Stop a running job, or forget a finished one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		baseURL := jobsServerURL()
+		id := args[0]
+
+		job, err := getJob(baseURL, id)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		req, err := http.NewRequest(http.MethodDelete, baseURL+"/api/jobs/"+url.PathEscape(id), nil)
+		if err != nil {
+			ui.Fatal("Failed to cancel job %s: %v", id, err)
+		}
+		resp, err := proxyHTTPClient(5 * time.Second).Do(req)
+		if err != nil {
+			ui.Fatal("Failed to cancel job %s: %v", id, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			ui.Fatal("Failed to cancel job %s: %v", id, responseError(resp))
+		}
+
+		if job.Status == proxy.JobRunning {
+			fmt.Printf("%s Cancelled job %s\n", ui.Success("✓"), id)
+		} else {
+			fmt.Printf("%s Removed job %s\n", ui.Success("✓"), id)
+		}
+	},
+}
+
+// jobsServerURL returns the address of the server jobs run on: the remote
+// one with --host, else the local one, which must be running
+func jobsServerURL() string {
+	if u := remoteURL(); u != "" {
+		return u
+	}
+	state := proxy.GetRunningProxyState()
+	if state == nil {
+		ui.Fatal("Server is not running")
+	}
+	return fmt.Sprintf("http://%s:%d", state.Host, state.Port)
+}
+
+// submitJob hands req to the server at baseURL to run in the background
+func submitJob(baseURL string, req *server.ChatCompletionRequest) (*proxy.JobInfo, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	resp, err := proxyHTTPClient(30*time.Second).Post(baseURL+"/api/jobs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, responseError(resp)
+	}
+
+	var job proxy.JobInfo
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// getJob fetches a job with its reply so far
+func getJob(baseURL, id string) (*proxy.JobInfo, error) {
+	resp, err := proxyHTTPClient(5 * time.Second).Get(baseURL + "/api/jobs/" + url.PathEscape(id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var job proxy.JobInfo
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// printSubmittedJob prints a detached job's ID on stdout, so scripts can
+// capture it, and how to read its reply on stderr
+func printSubmittedJob(job *proxy.JobInfo) {
+	fmt.Println(job.ID)
+	fmt.Fprintln(os.Stderr, ui.Muted("Read the reply with: lleme jobs logs -f "+job.ID))
+}
+
+// jobStatusText is a job's status for the list, with its error if it failed
+func jobStatusText(j proxy.JobInfo) string {
+	if j.Status == proxy.JobFailed && j.Error != "" {
+		return j.Status + ": " + j.Error
+	}
+	return j.Status
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+
+	jobsCmd.AddCommand(jobsListCmd)
+	jobsCmd.AddCommand(jobsLogsCmd)
+	jobsCmd.AddCommand(jobsCancelCmd)
+
+	for _, c := range []*cobra.Command{jobsListCmd, jobsLogsCmd, jobsCancelCmd} {
+		addRemoteFlag(c)
+	}
+
+	jobsLogsCmd.Flags().BoolVarP(&jobsFollow, "follow", "f", false, "Keep printing the reply until the job ends")
+}
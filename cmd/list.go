@@ -5,12 +5,13 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -21,100 +22,29 @@ var listCmd = &cobra.Command{
 	Short:   "List downloaded models",
 	GroupID: "model",
 	Run: func(cmd *cobra.Command, args []string) {
-		modelsDir := config.ModelsPath()
-
 		var models []ModelInfo
-		var totalSize int64
-		seenSplitDirs := make(map[string]bool)
-
-		err := filepath.WalkDir(modelsDir, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-
-			if d.IsDir() {
-				return nil
-			}
-
-			if filepath.Ext(d.Name()) != ".gguf" {
-				return nil
+		var err error
+		if remote := remoteURL(); remote != "" {
+			models, err = listRemoteModels(remote)
+			if listTag != "" {
+				models = filterModelsByTag(models, listTag)
 			}
-
-			relPath, err := filepath.Rel(modelsDir, path)
-			if err != nil {
-				return err
-			}
-
-			parts := strings.Split(relPath, string(filepath.Separator))
-			if len(parts) < 3 {
-				return nil
-			}
-
-			user := parts[0]
-			repo := parts[1]
-			var quant string
-			var modelSize int64
-
-			// Check if this is a split file (in a quant subdirectory)
-			// Structure: user/repo/quant/model-00001-of-NNNNN.gguf
-			if len(parts) == 4 && hf.SplitFilePattern.MatchString(d.Name()) {
-				quant = parts[2]
-				splitDirKey := filepath.Join(user, repo, quant)
-
-				// Only add the first split file we encounter for this quant
-				if seenSplitDirs[splitDirKey] {
-					return nil
-				}
-				seenSplitDirs[splitDirKey] = true
-
-				// Calculate total size of all split files
-				splitDir := filepath.Dir(path)
-				entries, _ := os.ReadDir(splitDir)
-				for _, entry := range entries {
-					if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".gguf") {
-						continue
-					}
-					if info, err := entry.Info(); err == nil {
-						modelSize += info.Size()
-					}
-				}
-			} else {
-				// Standard single-file model: user/repo/quant.gguf
-				quant = strings.TrimSuffix(d.Name(), ".gguf")
-				info, err := d.Info()
-				if err != nil {
-					return err
-				}
-				modelSize = info.Size()
-			}
-
-			lastUsed := hf.GetLastUsed(user, repo, quant)
-			if lastUsed.IsZero() {
-				info, _ := d.Info()
-				if info != nil {
-					lastUsed = info.ModTime() // Fall back to download time
-				} else {
-					lastUsed = time.Now()
-				}
-			}
-
-			models = append(models, ModelInfo{
-				User:     user,
-				Repo:     repo,
-				Quant:    quant,
-				Size:     modelSize,
-				LastUsed: lastUsed,
-			})
-
-			totalSize += modelSize
-
-			return nil
-		})
-
+		} else {
+			models, err = listLocalModels()
+		}
 		if err != nil {
 			ui.Fatal("Failed to list models: %v", err)
 		}
 
+		var totalSize int64
+		for _, m := range models {
+			totalSize += m.Size
+		}
+
+		if len(models) == 0 && listTag != "" {
+			fmt.Println(ui.Muted(fmt.Sprintf("No models tagged '%s'", listTag)))
+			return
+		}
 		if len(models) == 0 {
 			fmt.Println(ui.Muted("No models downloaded yet"))
 			fmt.Println()
@@ -122,10 +52,7 @@ var listCmd = &cobra.Command{
 			return
 		}
 
-		// Sort by most recently used
-		sort.Slice(models, func(i, j int) bool {
-			return models[i].LastUsed.After(models[j].LastUsed)
-		})
+		groupModelsByRepo(models)
 
 		table := ui.NewTable().
 			Indent(0).
@@ -134,18 +61,183 @@ var listCmd = &cobra.Command{
 			AddColumn("SIZE", 10, ui.AlignRight).
 			AddColumn("LAST USED", 12, ui.AlignRight)
 
+		var hasTags, hasNotes bool
 		for _, m := range models {
+			hasTags = hasTags || len(m.Tags) > 0
+			hasNotes = hasNotes || m.Note != ""
+		}
+		if hasTags {
+			table.AddColumn("TAGS", 0, ui.AlignLeft)
+		}
+		if hasNotes {
+			table.AddColumn("NOTE", 0, ui.AlignLeft)
+		}
+
+		// Name each repo once, with its other quants listed beneath it
+		repos := 0
+		for i, m := range models {
 			modelRef := fmt.Sprintf("%s/%s", m.User, m.Repo)
-			table.AddRow(modelRef, m.Quant, ui.FormatBytes(m.Size), formatTime(m.LastUsed))
+			if i > 0 && models[i-1].User == m.User && models[i-1].Repo == m.Repo {
+				modelRef = ""
+			} else {
+				repos++
+			}
+			// Tags and notes belong to the repo, so only show them on its first row
+			tags, note := strings.Join(m.Tags, ","), m.Note
+			if modelRef == "" {
+				tags, note = "", ""
+			}
+			row := []string{modelRef, m.Quant, ui.FormatBytes(m.Size), formatTime(m.LastUsed)}
+			if hasTags {
+				row = append(row, tags)
+			}
+			if hasNotes {
+				row = append(row, note)
+			}
+			table.AddRow(row...)
 		}
 
 		fmt.Print(table.Render())
 		fmt.Println()
-		fmt.Printf("%d models, %s total\n", len(models), ui.FormatBytes(totalSize))
+		if repos == len(models) {
+			fmt.Printf("%d models, %s total\n", len(models), ui.FormatBytes(totalSize))
+		} else {
+			fmt.Printf("%d models (%d quants), %s total\n", repos, len(models), ui.FormatBytes(totalSize))
+		}
 	},
 }
 
+// listLocalModels returns the downloaded models, filtered by --tag
+func listLocalModels() ([]ModelInfo, error) {
+	modelsDir := paths.Models()
+
+	var models []ModelInfo
+	seenSplitDirs := make(map[string]bool)
+
+	err := filepath.WalkDir(modelsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		if filepath.Ext(d.Name()) != ".gguf" {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(modelsDir, path)
+		if err != nil {
+			return err
+		}
+
+		parts := strings.Split(relPath, string(filepath.Separator))
+		if len(parts) < 3 {
+			return nil
+		}
+
+		user := parts[0]
+		repo := parts[1]
+		var quant string
+		var modelSize int64
+
+		// Check if this is a split file (in a quant subdirectory)
+		// Structure: user/repo/quant/model-00001-of-NNNNN.gguf
+		if len(parts) == 4 && hf.SplitFilePattern.MatchString(d.Name()) {
+			quant = parts[2]
+			splitDirKey := filepath.Join(user, repo, quant)
+
+			// Only add the first split file we encounter for this quant
+			if seenSplitDirs[splitDirKey] {
+				return nil
+			}
+			seenSplitDirs[splitDirKey] = true
+
+			// Calculate total size of all split files
+			splitDir := filepath.Dir(path)
+			entries, _ := os.ReadDir(splitDir)
+			for _, entry := range entries {
+				if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".gguf") {
+					continue
+				}
+				if info, err := entry.Info(); err == nil {
+					modelSize += info.Size()
+				}
+			}
+		} else {
+			// Standard single-file model: user/repo/quant.gguf
+			quant = strings.TrimSuffix(d.Name(), ".gguf")
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			modelSize = info.Size()
+		}
+
+		meta, err := hf.LoadMetadata(user, repo)
+		if err != nil {
+			meta = &hf.ModelMetadata{}
+		}
+		if listTag != "" && !slices.Contains(meta.Tags, strings.ToLower(listTag)) {
+			return nil
+		}
+
+		lastUsed := meta.Quants[quant].LastUsed
+		if lastUsed.IsZero() {
+			info, _ := d.Info()
+			if info != nil {
+				lastUsed = info.ModTime() // Fall back to download time
+			} else {
+				lastUsed = time.Now()
+			}
+		}
+
+		models = append(models, ModelInfo{
+			User:     user,
+			Repo:     repo,
+			Quant:    quant,
+			Size:     modelSize,
+			LastUsed: lastUsed,
+			Tags:     meta.Tags,
+			Note:     meta.Note,
+		})
+
+		return nil
+	})
+
+	return models, err
+}
+
+// groupModelsByRepo sorts models so quants of the same repo are adjacent.
+// Repos are ordered by their most recently used quant, and quants within a
+// repo by hf.GetQuantPriority.
+func groupModelsByRepo(models []ModelInfo) {
+	lastUsed := make(map[string]time.Time)
+	for _, m := range models {
+		key := m.User + "/" + m.Repo
+		if m.LastUsed.After(lastUsed[key]) {
+			lastUsed[key] = m.LastUsed
+		}
+	}
+
+	sort.SliceStable(models, func(i, j int) bool {
+		a, b := models[i], models[j]
+		keyA, keyB := a.User+"/"+a.Repo, b.User+"/"+b.Repo
+		if keyA != keyB {
+			if !lastUsed[keyA].Equal(lastUsed[keyB]) {
+				return lastUsed[keyA].After(lastUsed[keyB])
+			}
+			return keyA < keyB
+		}
+		return hf.GetQuantPriority(a.Quant) < hf.GetQuantPriority(b.Quant)
+	})
+}
+
 func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-" // Remote servers only report use of loaded models
+	}
 	now := time.Now()
 	diff := now.Sub(t)
 
@@ -161,6 +253,10 @@ func formatTime(t time.Time) string {
 	}
 }
 
+var listTag string
+
 func init() {
+	listCmd.Flags().StringVar(&listTag, "tag", "", "Only list models with this tag")
 	rootCmd.AddCommand(listCmd)
+	addRemoteFlag(listCmd)
 }
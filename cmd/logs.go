@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditLines int
+	auditJSON  bool
+)
+
+var logsCmd = &cobra.Command{
+	Use:     "logs",
+	Short:   "Show server logs",
+	GroupID: "server",
+}
+
+var logsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show administrative actions taken through the server",
+	Long: `Show the audit log: every model load (/api/run), unload (/api/stop,
+/api/stop-all), download (/api/pull), update and removal (/api/models) and
+their gRPC equivalents, with who made them and the parameters they passed. The log is append-only and never rotated.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := logs.ReadAudit()
+		if err != nil {
+			ui.Fatal("Failed to read audit log: %v", err)
+		}
+		if auditLines > 0 && len(entries) > auditLines {
+			entries = entries[len(entries)-auditLines:]
+		}
+
+		if auditJSON {
+			enc := json.NewEncoder(os.Stdout)
+			for _, e := range entries {
+				enc.Encode(e)
+			}
+			return
+		}
+
+		if len(entries) == 0 {
+			fmt.Println(ui.Muted("No administrative actions recorded"))
+			return
+		}
+
+		table := ui.NewTable().
+			AddColumn("TIME", 0, ui.AlignLeft).
+			AddColumn("ACTION", 0, ui.AlignLeft).
+			AddColumn("CLIENT", 0, ui.AlignLeft).
+			AddColumn("PARAMETERS", 0, ui.AlignLeft).
+			AddColumn("RESULT", 0, ui.AlignLeft)
+		for _, e := range entries {
+			result := "ok"
+			if e.Error != "" {
+				result = "error: " + e.Error
+			}
+			table.AddRow(e.Time.Local().Format("2006-01-02 15:04:05"), e.Action, auditCaller(e), formatAuditParams(e.Params), result)
+		}
+		fmt.Print(table.Render())
+		fmt.Println()
+		fmt.Println(ui.Muted(logs.AuditLogPath()))
+	},
+}
+
+// auditCaller describes who made an audited request
+func auditCaller(e logs.AuditEntry) string {
+	caller := e.Client
+	if caller == "" {
+		caller = e.Remote
+	}
+	if caller == "" {
+		caller = "-"
+	}
+	if e.Via == "grpc" {
+		caller += " (gRPC)"
+	}
+	return caller
+}
+
+// formatAuditParams renders params as sorted key=value pairs
+func formatAuditParams(params map[string]any) string {
+	var parts []string
+	for k, v := range params {
+		if m, ok := v.(map[string]any); ok && len(m) == 0 {
+			continue
+		}
+		data, _ := json.Marshal(v)
+		parts = append(parts, k+"="+strings.Trim(string(data), `"`))
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, " ")
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+	logsCmd.AddCommand(logsAuditCmd)
+
+	logsAuditCmd.Flags().IntVarP(&auditLines, "lines", "n", 50, "Show the last n entries (0 = all)")
+	logsAuditCmd.Flags().BoolVar(&auditJSON, "json", false, "Print entries as JSON lines")
+}
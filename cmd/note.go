@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var noteClear bool
+
+var noteCmd = &cobra.Command{
+	Use:     "note <model> [text]",
+	Short:   "Add a note to a downloaded model",
+	GroupID: "model",
+	Long: `Attach a short note to a downloaded model, or show its note.
+Notes appear in 'lleme list' and the web UI.
+
+Examples:
+  lleme note llama "great for SQL"     # Set the note
+  lleme note llama                     # Show the note
+  lleme note llama --clear             # Remove the note`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, repo, err := resolveDownloadedRepo(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		if noteClear {
+			if err := hf.SetNote(user, repo, ""); err != nil {
+				ui.Fatal("Failed to save note: %v", err)
+			}
+			fmt.Printf("Removed note from %s/%s\n", user, repo)
+			return
+		}
+
+		if len(args) == 1 {
+			meta, err := hf.LoadMetadata(user, repo)
+			if err != nil {
+				ui.Fatal("Failed to load metadata: %v", err)
+			}
+			if meta.Note == "" {
+				fmt.Println(ui.Muted("No note"))
+				return
+			}
+			fmt.Println(meta.Note)
+			return
+		}
+
+		note := strings.TrimSpace(strings.Join(args[1:], " "))
+		if err := hf.SetNote(user, repo, note); err != nil {
+			ui.Fatal("Failed to save note: %v", err)
+		}
+		fmt.Printf("Saved note for %s/%s\n", user, repo)
+	},
+}
+
+func init() {
+	noteCmd.Flags().BoolVar(&noteClear, "clear", false, "Remove the note")
+	rootCmd.AddCommand(noteCmd)
+}
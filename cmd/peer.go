@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/peer"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
@@ -64,7 +65,7 @@ var peerListCmd = &cobra.Command{
 
 		if !cfg.Peer.Enabled {
 			fmt.Println(ui.Muted("Peer discovery is disabled."))
-			fmt.Println(ui.Muted("Enable with 'peer.enabled: true' in ~/.lleme/config.yaml"))
+			fmt.Println(ui.Muted("Enable with 'peer.enabled: true' in " + paths.Config()))
 			return
 		}
 
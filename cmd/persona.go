@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -105,6 +106,18 @@ var personaShowCmd = &cobra.Command{
 			fmt.Print(string(data))
 		}
 
+		if len(persona.Passthrough) > 0 {
+			fmt.Printf("\n%s\n", ui.Bold("Passthrough:"))
+			data, _ := yaml.Marshal(persona.Passthrough)
+			fmt.Print(string(data))
+		}
+
+		if persona.Filters != nil {
+			fmt.Printf("\n%s\n", ui.Bold("Filters:"))
+			data, _ := yaml.Marshal(persona.Filters)
+			fmt.Print(string(data))
+		}
+
 		fmt.Printf("\n%s %s\n", ui.Muted("Path:"), config.PersonaPath(name))
 	},
 }
@@ -190,8 +203,8 @@ var personaRmCmd = &cobra.Command{
 		}
 
 		if !personaForce {
-			if !ui.PromptYesNo(fmt.Sprintf("Remove persona '%s'?", name), false) {
-				fmt.Println(ui.Muted("Cancelled"))
+			if !ui.PromptYesNo(i18n.Tf("Remove persona '%s'?", name), false) {
+				fmt.Println(ui.Muted(i18n.T("Cancelled")))
 				return
 			}
 		}
@@ -209,6 +222,9 @@ func openPersonaInEditor(name string) {
 	if err := openInEditor(path); err != nil {
 		ui.Fatal("%v", err)
 	}
+	if _, err := config.LoadPersona(name); err != nil {
+		fmt.Printf("%s %v\n", ui.Warning("!"), err)
+	}
 }
 
 func init() {
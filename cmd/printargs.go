@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/options"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/ui"
+)
+
+// printLaunch prints the command modelQuery's backend would start with,
+// without starting anything. A running server is asked, so its config and
+// free memory count; with none running the plan is worked out here.
+func printLaunch(cfg *config.Config, remote, modelQuery string, opts *server.RunOptions, requested options.Resolved) {
+	var plan *server.LaunchPlan
+	var err error
+	switch state := proxy.GetRunningProxyState(); {
+	case remote != "":
+		plan, err = remoteAPI(remote).PlanRun(modelQuery, opts)
+	case state != nil:
+		plan, err = server.NewAPIClientFromURL(fmt.Sprintf("http://%s:%d", state.Host, state.Port)).PlanRun(modelQuery, opts)
+	default:
+		plan, err = planLaunch(cfg, modelQuery, opts)
+	}
+	if err != nil {
+		ui.Fatal("%v", err)
+	}
+
+	// The server sees persona and flag options as one request; only this
+	// side can tell them apart
+	for key, source := range plan.Sources {
+		if source != options.SourceRequest.String() {
+			continue
+		}
+		if explicit := (key == "ctx-size" && opts.CtxSize != nil) || (key == "gpu-layers" && opts.GpuLayers != nil) ||
+			(key == "threads" && opts.Threads != nil); explicit {
+			plan.Sources[key] = options.SourceFlag.String()
+		} else if v, ok := requested.Get(key); ok {
+			plan.Sources[key] = v.Source.String()
+		} else if _, ok := opts.Passthrough[key]; ok {
+			plan.Sources[key] = options.SourcePersona.String()
+		}
+	}
+	fmt.Print(formatLaunch(plan))
+}
+
+// planLaunch works out the launch in this process, as a server started now
+// with cfg would
+func planLaunch(cfg *config.Config, modelQuery string, opts *server.RunOptions) (*server.LaunchPlan, error) {
+	req := proxy.RunRequest{
+		Model:       modelQuery,
+		CtxSize:     opts.CtxSize,
+		GpuLayers:   opts.GpuLayers,
+		Threads:     opts.Threads,
+		Options:     opts.Options,
+		Passthrough: opts.Passthrough,
+	}
+	launchOpts, err := req.LaunchOptions()
+	if err != nil {
+		return nil, err
+	}
+	manager := proxy.NewModelManager(proxy.ConfigFromAppConfig(cfg.Server), cfg)
+	model, lc, err := manager.PlanLaunch(modelQuery, launchOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &server.LaunchPlan{
+		Model:    model,
+		Path:     lc.Path,
+		Args:     lc.Args,
+		Env:      lc.Env,
+		Dir:      lc.Dir,
+		Template: lc.Template,
+		MMProj:   lc.MMProj,
+		Options:  lc.Options,
+		Sources:  lc.Sources,
+	}, nil
+}
+
+// formatLaunch renders a plan as a shell command, one flag per line, with
+// what it was resolved from in comments above it
+func formatLaunch(plan *server.LaunchPlan) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Model: %s\n", plan.Model)
+	if plan.Template != "" {
+		fmt.Fprintf(&b, "# Chat template: %s (patched)\n", plan.Template)
+	}
+	if plan.MMProj != "" {
+		fmt.Fprintf(&b, "# Vision projector: %s\n", plan.MMProj)
+	}
+	if len(plan.Options) > 0 {
+		b.WriteString("# Options:\n")
+		for _, key := range slices.Sorted(maps.Keys(plan.Options)) {
+			source := plan.Sources[key]
+			if source == "" {
+				source = options.SourceRequest.String()
+			}
+			fmt.Fprintf(&b, "#   %s = %v (%s)\n", key, plan.Options[key], source)
+		}
+	}
+
+	for _, kv := range plan.Env {
+		name, value, _ := strings.Cut(kv, "=")
+		b.WriteString(name + "=" + shellQuote(value) + " ")
+	}
+	b.WriteString(shellQuote(plan.Path))
+	for _, arg := range plan.Args {
+		// Start a new line at each flag, keeping its value beside it
+		if strings.HasPrefix(arg, "--") {
+			b.WriteString(" \\\n  ")
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString(shellQuote(arg))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// shellQuote quotes s for a POSIX shell when it needs it
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,+@%", r))
+	}) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
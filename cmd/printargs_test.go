@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+func TestFormatLaunch(t *testing.T) {
+	plan := &server.LaunchPlan{
+		Model:    "user/repo:Q4_K_M",
+		Path:     "/opt/lleme/bin/llama-server",
+		Args:     []string{"--model", "/models/My Model.gguf", "--port", "49152", "--embeddings", "--gpu-layers", "-1", "--override-kv", "tokenizer.name=str:it's"},
+		Env:      []string{"CUDA_VISIBLE_DEVICES=0,1"},
+		Template: "/cache/templates/abc.jinja",
+	}
+	want := `# Model: user/repo:Q4_K_M
+# Chat template: /cache/templates/abc.jinja (patched)
+CUDA_VISIBLE_DEVICES=0,1 /opt/lleme/bin/llama-server \
+  --model '/models/My Model.gguf' \
+  --port 49152 \
+  --embeddings \
+  --gpu-layers -1 \
+  --override-kv 'tokenizer.name=str:it'\''s'
+`
+	if got := formatLaunch(plan); got != want {
+		t.Errorf("formatLaunch() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string]string{
+		"plain":         "plain",
+		"/a/b-c_d.gguf": "/a/b-c_d.gguf",
+		"":              "''",
+		"two words":     "'two words'",
+		"$HOME":         "'$HOME'",
+		"it's":          `'it'\''s'`,
+	}
+	for in, want := range tests {
+		if got := shellQuote(in); got != want {
+			t.Errorf("shellQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
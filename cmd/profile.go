@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// profileKind is the --profile flag: cpu, mem, or empty for none
+var profileKind string
+
+// addProfileFlag adds --profile to a command whose work is worth profiling
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&profileKind, "profile", "", "Write a pprof profile of this command (cpu or mem) to lleme-<kind>.pprof")
+}
+
+// startProfile starts the profile --profile asks for and returns a function
+// that writes it out when the command is done. The file goes in the working
+// directory, for `go tool pprof lleme <file>`.
+func startProfile() (stop func(), err error) {
+	if profileKind == "" {
+		return func() {}, nil
+	}
+	if profileKind != "cpu" && profileKind != "mem" {
+		return nil, fmt.Errorf("unknown profile %q (use cpu or mem)", profileKind)
+	}
+
+	path := "lleme-" + profileKind + ".pprof"
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if profileKind == "cpu" {
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+
+	return func() {
+		if profileKind == "cpu" {
+			pprof.StopCPUProfile()
+		} else {
+			runtime.GC() // Bring the in-use numbers up to date
+			if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+				fmt.Fprintf(os.Stderr, "%s Failed to write profile: %v\n", ui.ErrorMsg("Warning:"), err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s Failed to write profile: %v\n", ui.ErrorMsg("Warning:"), err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s %s profile written to %s\n", ui.Muted("•"), profileKind, path)
+	}, nil
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStartProfile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	defer func() { profileKind = "" }()
+
+	profileKind = "disk"
+	if _, err := startProfile(); err == nil {
+		t.Error("startProfile() accepted an unknown kind")
+	}
+
+	for _, kind := range []string{"cpu", "mem"} {
+		profileKind = kind
+		stop, err := startProfile()
+		if err != nil {
+			t.Fatalf("startProfile(%s) error = %v", kind, err)
+		}
+		stop()
+		info, err := os.Stat("lleme-" + kind + ".pprof")
+		if err != nil || info.Size() == 0 {
+			t.Errorf("%s profile not written: %v", kind, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/ui"
+)
+
+const (
+	progressFormatBar  = "bar"
+	progressFormatJSON = "json"
+)
+
+// progressFormat is set by --progress on pull and update
+var progressFormat = progressFormatBar
+
+// progressEvents is non-nil when --progress json is in effect. Its methods
+// are no-ops on nil, so callers don't need to check the format.
+var progressEvents *progressEmitter
+
+const progressUsage = "Progress output: bar, or json for newline-delimited events on stdout"
+
+// initProgress validates --progress and, for json, starts the emitter with
+// target as the subject of events until Start names another. Human-readable
+// output moves to stderr so stdout carries only events.
+func initProgress(op, target string) {
+	switch progressFormat {
+	case progressFormatBar:
+	case progressFormatJSON:
+		progressEvents = newProgressEmitter(os.Stdout, op)
+		progressEvents.target = target
+	default:
+		ui.Fatal("Invalid --progress %q: must be %s or %s", progressFormat, progressFormatBar, progressFormatJSON)
+	}
+}
+
+// humanOut is where commands with --progress print messages meant for people
+func humanOut() io.Writer {
+	if progressEvents != nil {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// progressFatal reports a fatal error as an error event, then exits like
+// ui.Fatal.
+func progressFatal(format string, args ...any) {
+	progressEvents.Error(fmt.Sprintf(format, args...))
+	ui.Fatal(format, args...)
+}
+
+// progressEvent is one line of --progress json output. Fields that don't
+// apply to an event are omitted; a missing number means zero.
+type progressEvent struct {
+	Event    string    `json:"event"` // start, status, progress, done, or error
+	Op       string    `json:"op"`    // pull or update
+	Target   string    `json:"target,omitempty"`
+	Phase    string    `json:"phase,omitempty"` // download or verify
+	Current  int64     `json:"current,omitempty"`
+	Total    int64     `json:"total,omitempty"`
+	Message  string    `json:"message,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	UpToDate bool      `json:"up_to_date,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// progressEmitter writes progressEvents as newline-delimited JSON.
+// Progress events are throttled to one per interval, except the first and
+// last of each phase.
+type progressEmitter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	op       string
+	interval time.Duration
+	now      func() time.Time
+
+	target string
+	phase  string
+	last   time.Time
+}
+
+func newProgressEmitter(w io.Writer, op string) *progressEmitter {
+	return &progressEmitter{
+		enc:      json.NewEncoder(w),
+		op:       op,
+		interval: 100 * time.Millisecond,
+		now:      time.Now,
+	}
+}
+
+func (e *progressEmitter) emit(ev progressEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.write(ev)
+}
+
+// write sends ev; the caller holds e.mu
+func (e *progressEmitter) write(ev progressEvent) {
+	ev.Op = e.op
+	if ev.Target == "" {
+		ev.Target = e.target
+	}
+	ev.Time = e.now().UTC()
+	e.enc.Encode(ev)
+}
+
+// Start begins reporting on target, which later events default to
+func (e *progressEmitter) Start(target, message string, total int64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.target, e.phase = target, ""
+	e.write(progressEvent{Event: "start", Message: message, Total: total})
+}
+
+func (e *progressEmitter) Status(message string) {
+	e.emit(progressEvent{Event: "status", Message: message})
+}
+
+// Progress reports bytes done in phase, dropping updates that arrive within
+// the throttle interval of the previous one.
+func (e *progressEmitter) Progress(phase string, current, total int64) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	changed := phase != e.phase
+	finished := total > 0 && current >= total
+	if !changed && !finished && now.Sub(e.last) < e.interval {
+		return
+	}
+	e.phase, e.last = phase, now
+	e.write(progressEvent{Event: "progress", Phase: phase, Current: current, Total: total})
+}
+
+// Done reports success. ev.Event and ev.Op are filled in.
+func (e *progressEmitter) Done(ev progressEvent) {
+	ev.Event = "done"
+	e.emit(ev)
+}
+
+func (e *progressEmitter) Error(message string) {
+	e.emit(progressEvent{Event: "error", Message: message})
+}
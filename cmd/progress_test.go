@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressEmitter(t *testing.T) {
+	var buf bytes.Buffer
+	e := newProgressEmitter(&buf, "pull")
+	clock := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	e.now = func() time.Time { return clock }
+	tick := func(d time.Duration) { clock = clock.Add(d) }
+
+	e.Start("user/repo:Q4_K_M", "", 300)
+	e.Progress("download", 0, 300)
+	tick(10 * time.Millisecond)
+	e.Progress("download", 50, 300) // Throttled
+	tick(100 * time.Millisecond)
+	e.Progress("download", 100, 300)
+	tick(time.Millisecond)
+	e.Progress("download", 300, 300) // Last of the phase
+	e.Progress("verify", 0, 300)     // New phase
+	e.Progress("verify", 10, 300)    // Throttled
+	e.Done(progressEvent{Path: "/models/x.gguf"})
+
+	var events []progressEvent
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev progressEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q is not JSON: %v", line, err)
+		}
+		events = append(events, ev)
+	}
+
+	want := []struct {
+		event, phase string
+		current      int64
+	}{
+		{"start", "", 0},
+		{"progress", "download", 0},
+		{"progress", "download", 100},
+		{"progress", "download", 300},
+		{"progress", "verify", 0},
+		{"done", "", 0},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(events), len(want), buf.String())
+	}
+	for i, w := range want {
+		ev := events[i]
+		if ev.Event != w.event || ev.Phase != w.phase || ev.Current != w.current {
+			t.Errorf("event %d = %s/%s/%d, want %s/%s/%d", i, ev.Event, ev.Phase, ev.Current, w.event, w.phase, w.current)
+		}
+		if ev.Op != "pull" || ev.Target != "user/repo:Q4_K_M" {
+			t.Errorf("event %d op/target = %q/%q", i, ev.Op, ev.Target)
+		}
+	}
+	if events[5].Path != "/models/x.gguf" || events[0].Total != 300 {
+		t.Errorf("done path = %q, start total = %d", events[5].Path, events[0].Total)
+	}
+	if strings.Contains(buf.String(), `"up_to_date"`) {
+		t.Error("unset fields should be omitted")
+	}
+}
+
+func TestProgressEmitterNil(t *testing.T) {
+	var e *progressEmitter
+	e.Start("x", "", 0)
+	e.Status("x")
+	e.Progress("download", 1, 2)
+	e.Done(progressEvent{})
+	e.Error("x")
+}
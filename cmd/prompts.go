@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var promptForce bool
+
+var promptsCmd = &cobra.Command{
+	Use:     "prompts",
+	Aliases: []string{"prompt"},
+	Short:   "Manage a library of named system prompts",
+	GroupID: "persona",
+	Long: `Manage a library of named system prompts.
+
+A saved prompt is plain text in <name>.md in lleme's prompts directory.
+Unlike a persona it holds only the system prompt, so it works with any
+model. Use one with --system @name or with /system @name in chat. Write @@
+for a prompt that starts with a literal @.
+
+Examples:
+  lleme prompts list                            # List saved prompts
+  lleme prompts add reviewer "You review code"  # Save from text
+  git diff | lleme prompts add diff-review      # Save from stdin
+  lleme prompts add writer                      # Write in $EDITOR
+  lleme prompts show reviewer                   # Print a prompt
+  lleme prompts edit reviewer                   # Edit in $EDITOR
+  lleme prompts rm reviewer                     # Delete a prompt
+
+Use a prompt:
+  lleme run llama3.2 --system @reviewer`,
+}
+
+var promptsListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List saved prompts",
+	Run: func(cmd *cobra.Command, args []string) {
+		prompts, err := config.ListPrompts()
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		if len(prompts) == 0 {
+			fmt.Println(ui.Muted("No prompts saved"))
+			fmt.Println()
+			fmt.Println("Add one with: lleme prompts add <name> [text]")
+			return
+		}
+
+		fmt.Println(ui.Header("Prompts"))
+		fmt.Println()
+
+		table := ui.NewTable().
+			AddColumn("NAME", 0, ui.AlignLeft).
+			AddColumn("PROMPT", 60, ui.AlignLeft)
+
+		for _, p := range prompts {
+			table.AddRow(p.Name, p.Summary)
+		}
+
+		fmt.Print(table.Render())
+		fmt.Println()
+		fmt.Printf("%d prompt(s)\n", len(prompts))
+	},
+}
+
+var promptsShowCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: "Print a saved prompt",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		text, err := config.LoadPrompt(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		// Just the text, so it can be piped elsewhere
+		fmt.Println(text)
+	},
+}
+
+var promptsAddCmd = &cobra.Command{
+	Use:   "add <name> [text]",
+	Short: "Save a prompt from text, stdin, or $EDITOR",
+	Long: `Save a named system prompt.
+
+The text comes from the arguments, or from stdin when it's piped. With
+neither, the prompt opens in $EDITOR.
+
+Examples:
+  lleme prompts add reviewer "You are a careful code reviewer."
+  lleme prompts add summarizer < summarizer.md
+  lleme prompts add writer`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		if err := config.ValidatePromptName(name); err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		if config.PromptExists(name) && !promptForce {
+			ui.Fatal("Prompt '%s' already exists. Use --force to overwrite.", name)
+		}
+
+		text := strings.Join(args[1:], " ")
+		if text == "" {
+			if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice == 0 {
+				data, err := io.ReadAll(os.Stdin)
+				if err != nil {
+					ui.Fatal("Failed to read stdin: %v", err)
+				}
+				text = string(data)
+			}
+		}
+
+		if strings.TrimSpace(text) != "" {
+			if err := config.SavePrompt(name, text); err != nil {
+				ui.Fatal("%v", err)
+			}
+			fmt.Printf("%s Saved prompt '%s'\n", ui.Success("✓"), name)
+			fmt.Printf("  %s\n", ui.Muted(config.PromptPath(name)))
+			return
+		}
+
+		// Nothing given, so write it in the editor
+		if err := config.SavePrompt(name, ""); err != nil {
+			ui.Fatal("%v", err)
+		}
+		editPrompt(name)
+		if text, _ := config.LoadPrompt(name); text == "" {
+			config.DeletePrompt(name)
+			fmt.Println(ui.Muted("Prompt is empty, not saved"))
+			return
+		}
+		fmt.Printf("%s Saved prompt '%s'\n", ui.Success("✓"), name)
+	},
+}
+
+var promptsEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: "Edit a prompt in $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		if !config.PromptExists(name) {
+			ui.Fatal("Prompt '%s' not found", name)
+		}
+
+		editPrompt(name)
+	},
+}
+
+var promptsRmCmd = &cobra.Command{
+	Use:     "rm <name>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a saved prompt",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		if !config.PromptExists(name) {
+			ui.Fatal("Prompt '%s' not found", name)
+		}
+
+		if !promptForce {
+			if !ui.PromptYesNo(i18n.Tf("Remove prompt '%s'?", name), false) {
+				fmt.Println(ui.Muted(i18n.T("Cancelled")))
+				return
+			}
+		}
+
+		if err := config.DeletePrompt(name); err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		fmt.Printf("Removed prompt '%s'\n", name)
+	},
+}
+
+func editPrompt(name string) {
+	if err := openInEditor(config.PromptPath(name)); err != nil {
+		ui.Fatal("%v", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(promptsCmd)
+
+	promptsCmd.AddCommand(promptsListCmd)
+	promptsCmd.AddCommand(promptsShowCmd)
+	promptsCmd.AddCommand(promptsAddCmd)
+	promptsCmd.AddCommand(promptsEditCmd)
+	promptsCmd.AddCommand(promptsRmCmd)
+
+	promptsAddCmd.Flags().BoolVarP(&promptForce, "force", "f", false, "Overwrite existing prompt")
+	promptsRmCmd.Flags().BoolVarP(&promptForce, "force", "f", false, "Skip confirmation")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -13,26 +14,57 @@ import (
 )
 
 var pullCmd = &cobra.Command{
-	Use:     "pull <user/repo>[:quant]",
-	Short:   "Download a model from Hugging Face",
+	Use:     "pull <user/repo|name|s3://bucket/prefix>[:quant]",
+	Short:   "Download a model from Hugging Face or S3",
 	GroupID: "model",
-	Long: `Download a model from Hugging Face.
+	Long: `Download a model from Hugging Face or S3-compatible storage.
+
+S3 models are stored locally as <bucket>/<last prefix element>:<quant>.
+Credentials come from the AWS chain; set s3.endpoint in the config for MinIO.
 
 Examples:
   lleme pull unsloth/Llama-3.2-1B-Instruct-GGUF           # Download default quant
-  lleme pull unsloth/Llama-3.2-1B-Instruct-GGUF:Q8_0      # Download specific quant`,
+  lleme pull unsloth/Llama-3.2-1B-Instruct-GGUF:Q8_0      # Download specific quant
+  lleme pull llama3.2                                     # Download by registry short name
+  lleme pull s3://models/llama-3.2-1b:Q8_0                # Download from a bucket prefix
+  lleme pull s3://models/llama-3.2-1b/model-Q8_0.gguf     # Download a specific file`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		modelRef := args[0]
+		initProgress("pull", modelRef)
+		stopProfile, err := startProfile()
+		if err != nil {
+			progressFatal("%v", err)
+		}
+		defer stopProfile()
+		remote := remoteURL()
+
+		if hf.IsS3Ref(modelRef) {
+			if remote != "" {
+				progressFatal("S3 models can't be pulled on a remote server")
+			}
+			pullFromS3(modelRef)
+			return
+		}
 
 		user, repo, quant, err := parseModelRef(modelRef)
 		if err != nil {
-			ui.Fatal("%s", err)
+			var ok bool
+			if user, repo, quant, _, ok = lookupShortName(modelRef); !ok {
+				progressFatal("%s (see 'lleme registry list' for short names)", err)
+			}
+			fmt.Fprintln(humanOut(), ui.Muted(fmt.Sprintf("%s is %s", modelRef, hf.FormatModelName(user, repo, quant))))
+		}
+
+		// The remote server picks the quant and downloads on its side
+		if remote != "" {
+			pullRemote(remote, hf.FormatModelName(user, repo, quant))
+			return
 		}
 
 		cfg, err := config.Load()
 		if err != nil {
-			ui.Fatal("Failed to load config: %v", err)
+			progressFatal("Failed to load config: %v", err)
 		}
 
 		client := hf.NewClient(cfg)
@@ -40,109 +72,171 @@ Examples:
 		modelInfo, err := client.GetModel(user, repo)
 		if err != nil {
 			handleModelError(err, user, repo)
+			progressEvents.Error(err.Error())
 			os.Exit(1)
 		}
 
 		if bool(modelInfo.Gated) && !hf.HasToken(cfg) {
+			out := humanOut()
 			ui.PrintError("Authentication required")
-			fmt.Printf("\nThe repository '%s/%s' requires authentication.\n\n", user, repo)
-			fmt.Println("To access gated models, provide a Hugging Face token:")
-			fmt.Println("  1. Get a token at https://huggingface.co/settings/tokens")
-			fmt.Println("  2. Run: hf auth login")
-			fmt.Println("     Or set: export HF_TOKEN=hf_xxxxx")
+			fmt.Fprintf(out, "\nThe repository '%s/%s' requires authentication.\n\n", user, repo)
+			fmt.Fprintln(out, "To access gated models, provide a Hugging Face token:")
+			fmt.Fprintln(out, "  1. Get a token at https://huggingface.co/settings/tokens")
+			fmt.Fprintln(out, "  2. Run: hf auth login")
+			fmt.Fprintln(out, "     Or set: export HF_TOKEN=hf_xxxxx")
+			progressEvents.Error("authentication required")
 			os.Exit(1)
 		}
 
 		files, err := client.ListFiles(user, repo, "main")
 		if err != nil {
-			ui.Fatal("Failed to list files: %v", err)
+			progressFatal("Failed to list files: %v", err)
 		}
 
 		quants := hf.ExtractQuantizations(files)
 		if len(quants) == 0 {
 			ui.PrintError("No GGUF files found")
-			fmt.Printf("\nThe repository '%s/%s' exists but contains no GGUF files.\n", user, repo)
+			fmt.Fprintf(humanOut(), "\nThe repository '%s/%s' exists but contains no GGUF files.\n", user, repo)
+			progressEvents.Error("no GGUF files found")
 			os.Exit(1)
 		}
 
 		// Find the quantization to use
 		var selectedQuant hf.Quantization
 		if quant == "" {
-			quant = hf.GetBestQuantization(quants)
+			quant, err = chooseQuant(client, user, repo, quants, cfg.HuggingFace.DefaultQuant)
+			if errors.Is(err, ui.ErrCancelled) {
+				return
+			} else if err != nil {
+				progressFatal("%v", err)
+			}
 			selectedQuant, _ = hf.FindQuantization(quants, quant)
 		} else {
 			var found bool
 			selectedQuant, found = hf.FindQuantization(quants, quant)
 			if !found {
-				ui.PrintError("Quantization '%s' not found", quant)
-				fmt.Println("\nAvailable quantizations:")
 				client.FetchFolderQuantSizes(user, repo, "main", quants)
-				for _, q := range hf.SortQuantizations(quants) {
-					fmt.Printf("  • %s (%s)\n", q.Name, ui.FormatBytes(q.Size))
-				}
-				os.Exit(1)
+				quantNotFound(quant, quants)
 			}
 		}
 
-		// Check if local files are up to date with remote manifest
-		upToDate, saveManifest, _, manifestJSON, err := hf.CheckForUpdates(client, user, repo, selectedQuant)
-		if err != nil {
-			ui.Fatal("%v", err)
+		pullFromSource(hf.NewHFSource(client), cfg, user, repo, selectedQuant)
+	},
+}
+
+// pullFromS3 pulls a model from an s3:// URL.
+func pullFromS3(ref string) {
+	s3Ref, err := hf.ParseS3Ref(ref)
+	if err != nil {
+		progressFatal("%v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		progressFatal("Failed to load config: %v", err)
+	}
+
+	source, err := hf.NewS3Source(cfg, s3Ref)
+	if err != nil {
+		progressFatal("%v", err)
+	}
+
+	quants, err := source.ListQuantizations("", "")
+	if err != nil {
+		progressFatal("%v", err)
+	}
+
+	quant := s3Ref.Quant
+	if quant == "" {
+		quant = hf.GetBestQuantization(quants)
+		if q, ok := hf.FindQuantization(quants, cfg.HuggingFace.DefaultQuant); ok {
+			quant = q.Name
 		}
-		if upToDate {
-			if saveManifest {
-				// Legacy model without manifest - save it now
-				manifestPath := hf.GetManifestFilePath(user, repo, quant)
-				if err := os.WriteFile(manifestPath, manifestJSON, 0644); err != nil {
-					ui.Fatal("Failed to save manifest: %v", err)
-				}
-			}
-			// Find the actual model path (handles both single and split files)
-			modelPath := hf.FindModelFile(user, repo, quant)
-			if modelPath == "" {
-				modelPath = hf.GetModelFilePath(user, repo, quant) // Fallback for display
+	}
+	selectedQuant, found := hf.FindQuantization(quants, quant)
+	if !found {
+		quantNotFound(quant, quants)
+	}
+
+	user, repo := s3Ref.LocalName()
+	pullFromSource(source, cfg, user, repo, selectedQuant)
+}
+
+// quantNotFound lists the available quantizations and exits.
+func quantNotFound(quant string, quants []hf.Quantization) {
+	out := humanOut()
+	ui.PrintError("Quantization '%s' not found", quant)
+	fmt.Fprintln(out, "\nAvailable quantizations:")
+	for _, q := range hf.SortQuantizations(quants) {
+		fmt.Fprintf(out, "  • %s (%s)\n", q.Name, ui.FormatBytes(q.Size))
+	}
+	progressEvents.Error(fmt.Sprintf("quantization %s not found", quant))
+	os.Exit(1)
+}
+
+// pullFromSource pulls a quantization unless the local copy is up to date.
+func pullFromSource(source hf.ModelSource, cfg *config.Config, user, repo string, quant hf.Quantization) {
+	// Check if local files are up to date with remote manifest
+	upToDate, saveManifest, _, manifestJSON, err := hf.CheckForUpdates(source, user, repo, quant)
+	if err != nil {
+		progressFatal("%v", err)
+	}
+	if upToDate {
+		if saveManifest {
+			// Legacy model without manifest - save it now
+			manifestPath := hf.GetManifestFilePath(user, repo, quant.Name)
+			if err := os.WriteFile(manifestPath, manifestJSON, 0644); err != nil {
+				progressFatal("Failed to save manifest: %v", err)
 			}
-			fmt.Printf("Model is up to date: %s\n", ui.Bold(modelPath))
-			return
 		}
-
-		// Pull the model (tries peers first if enabled, then HuggingFace)
-		result, err := pullModelWithProgress(client, cfg, user, repo, selectedQuant)
-		if err != nil {
-			ui.Fatal("%v", err)
+		// Find the actual model path (handles both single and split files)
+		modelPath := hf.FindModelFile(user, repo, quant.Name)
+		if modelPath == "" {
+			modelPath = hf.GetModelFilePath(user, repo, quant.Name) // Fallback for display
 		}
+		fmt.Fprintf(humanOut(), "Model is up to date: %s\n", ui.Bold(modelPath))
+		progressEvents.Done(progressEvent{Target: hf.FormatModelName(user, repo, quant.Name), Path: modelPath, UpToDate: true})
+		return
+	}
 
-		// Update peer sharing index
-		if err := peer.RebuildPeerFileIndex(); err != nil {
-			ui.PrintError("Failed to update peer index: %v", err)
-		}
+	// Pull the model (tries peers first if enabled, then the source)
+	result, err := pullModelWithProgress(source, cfg, user, repo, quant)
+	if err != nil {
+		progressFatal("%v", err)
+	}
 
-		modelName := hf.FormatModelName(user, repo, selectedQuant.Name)
-		if result.IsVision {
-			fmt.Printf("Pulled %s (vision model)\n", modelName)
-		} else {
-			fmt.Printf("Pulled %s\n", modelName)
-		}
-	},
+	// Update peer sharing index
+	if err := peer.RebuildPeerFileIndex(); err != nil {
+		ui.PrintError("Failed to update peer index: %v", err)
+	}
+
+	modelName := hf.FormatModelName(user, repo, quant.Name)
+	if result.IsVision {
+		fmt.Fprintf(humanOut(), "Pulled %s (vision model)\n", modelName)
+	} else {
+		fmt.Fprintf(humanOut(), "Pulled %s\n", modelName)
+	}
+	progressEvents.Done(progressEvent{Path: hf.FindModelFile(user, repo, quant.Name)})
 }
 
 // pullModelWithProgress wraps hf.PullModel with progress bar display and peer support.
-func pullModelWithProgress(client *hf.Client, cfg *config.Config, user, repo string, quant hf.Quantization) (*hf.PullResult, error) {
+func pullModelWithProgress(source hf.ModelSource, cfg *config.Config, user, repo string, quant hf.Quantization) (*hf.PullResult, error) {
 	// Get manifest info for display (also returns manifest to pass to PullModel)
-	info, manifest, manifestJSON, err := hf.GetManifestInfo(client, user, repo, quant)
+	info, manifest, manifestJSON, err := hf.GetManifestInfo(source, user, repo, quant)
 	if err != nil {
 		return nil, err
 	}
 
 	modelName := ui.Keyword(hf.FormatModelName(user, repo, quant.Name))
 	if info.IsVision {
-		fmt.Printf("Pulling %s (%s + %s mmproj)\n",
+		fmt.Fprintf(humanOut(), "Pulling %s (%s + %s mmproj)\n",
 			modelName,
 			ui.FormatBytes(info.GGUFSize),
 			ui.FormatBytes(info.MMProjSize))
 	} else {
-		fmt.Printf("Pulling %s (%s)\n", modelName, ui.FormatBytes(info.GGUFSize))
+		fmt.Fprintf(humanOut(), "Pulling %s (%s)\n", modelName, ui.FormatBytes(info.GGUFSize))
 	}
+	progressEvents.Start(hf.FormatModelName(user, repo, quant.Name), "", info.GGUFSize+info.MMProjSize)
 
 	opts := &hf.PullOptions{
 		Manifest:     manifest,
@@ -154,7 +248,12 @@ func pullModelWithProgress(client *hf.Client, cfg *config.Config, user, repo str
 		opts.PeerDownload = peer.CreateDownloader()
 	}
 
-	return hf.PullModelWithProgressFactory(client, user, repo, quant, opts, newProgressBar)
+	if progressEvents != nil {
+		return hf.PullModel(source, user, repo, quant, opts, func(p hf.PullProgress) {
+			progressEvents.Progress(p.Phase, p.Current, p.Total)
+		})
+	}
+	return hf.PullModelWithProgressFactory(source, user, repo, quant, opts, newProgressBar)
 }
 
 // newProgressBar creates a new progress bar that implements hf.ProgressDisplay.
@@ -185,17 +284,21 @@ func parseModelRef(ref string) (user, repo, quant string, err error) {
 func handleModelError(err error, user, repo string) {
 	errStr := err.Error()
 
+	out := humanOut()
 	if strings.Contains(errStr, "404") {
-		fmt.Printf("%s Model not found\n", ui.ErrorMsg("Error:"))
-		fmt.Printf("\nCould not find '%s/%s' on Hugging Face.\n\n", user, repo)
-		fmt.Println("Tips:")
-		fmt.Println("  • Check the spelling of the repository name")
-		fmt.Println("  • Use 'lleme search <query>' to find models")
+		fmt.Fprintf(out, "%s Model not found\n", ui.ErrorMsg("Error:"))
+		fmt.Fprintf(out, "\nCould not find '%s/%s' on Hugging Face.\n\n", user, repo)
+		fmt.Fprintln(out, "Tips:")
+		fmt.Fprintln(out, "  • Check the spelling of the repository name")
+		fmt.Fprintln(out, "  • Use 'lleme search <query>' to find models")
 	} else {
-		fmt.Printf("%s %v\n", ui.ErrorMsg("Error:"), err)
+		fmt.Fprintf(out, "%s %v\n", ui.ErrorMsg("Error:"), err)
 	}
 }
 
 func init() {
+	pullCmd.Flags().StringVar(&progressFormat, "progress", progressFormatBar, progressUsage)
+	addProfileFlag(pullCmd)
+	addRemoteFlag(pullCmd)
 	rootCmd.AddCommand(pullCmd)
 }
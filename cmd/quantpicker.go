@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/nchapman/lleme/internal/catalog"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+)
+
+// chooseQuant asks which quantization to download when running in a terminal
+// without --progress json. Otherwise it returns preferred if the repo has it, or the one
+// GetBestQuantization picks.
+func chooseQuant(client *hf.Client, user, repo string, quants []hf.Quantization, preferred string) (string, error) {
+	best := hf.GetBestQuantization(quants)
+	if q, ok := hf.FindQuantization(quants, preferred); ok && preferred != "" {
+		best = q.Name
+	}
+	logs.Debug("Default quantization", "repo", user+"/"+repo, "quant", best, "preferred", preferred, "available", len(quants))
+	if len(quants) < 2 || !isInteractive() || progressEvents != nil {
+		return best, nil
+	}
+
+	client.FetchFolderQuantSizes(user, repo, "main", quants)
+	sorted := slices.Clone(quants)
+	slices.SortStableFunc(sorted, func(a, b hf.Quantization) int {
+		return cmp.Compare(a.Size, b.Size)
+	})
+
+	table, defaultIndex := quantTable(sorted, best, catalog.BudgetFor(hw.Detect()))
+	title := fmt.Sprintf("Select a quantization for %s", ui.Keyword(user+"/"+repo))
+	idx, err := ui.Pick(title, table, defaultIndex)
+	if err != nil {
+		return "", err
+	}
+	return sorted[idx].Name, nil
+}
+
+// quantTable builds the picker rows and returns the index of best.
+func quantTable(quants []hf.Quantization, best string, budget catalog.Budget) (*ui.Table, int) {
+	table := ui.NewTable().
+		AddColumn("QUANT", 0, ui.AlignLeft).
+		AddColumn("SIZE", 9, ui.AlignRight).
+		AddColumn("EST. RAM", 9, ui.AlignRight).
+		AddColumn("QUALITY", 0, ui.AlignLeft).
+		AddColumn("FITS", 0, ui.AlignLeft)
+
+	defaultIndex := 0
+	for i, q := range quants {
+		name := q.Name
+		if q.Name == best {
+			name += " (default)"
+			defaultIndex = i
+		}
+
+		size, ram, fits := "?", "?", "?"
+		if q.Size > 0 {
+			size = ui.FormatBytes(q.Size)
+			ram = ui.FormatBytes(catalog.RequiredMemory(q.Size))
+			if budget.Bytes > 0 {
+				fits = "no"
+				if budget.Fits(q.Size) {
+					fits = "yes"
+				}
+			}
+		}
+		table.AddRow(name, size, ram, hf.QuantQualityTier(q.Name), fits)
+	}
+	return table, defaultIndex
+}
+
+// isInteractive reports whether both stdin and stdout are terminals.
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		stat, err := f.Stat()
+		if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pickDownloadedModel asks which of several downloaded models to run,
+// starting on suggested if given.
+func pickDownloadedModel(models []proxy.DownloadedModel, suggested *proxy.DownloadedModel) (*proxy.DownloadedModel, error) {
+	table := ui.NewTable().
+		AddColumn("MODEL", 0, ui.AlignLeft).
+		AddColumn("QUANT", 0, ui.AlignLeft).
+		AddColumn("QUALITY", 0, ui.AlignLeft)
+
+	defaultIndex := 0
+	for i, m := range models {
+		if suggested != nil && m.FullName == suggested.FullName {
+			defaultIndex = i
+		}
+		table.AddRow(m.User+"/"+m.Repo, m.Quant, hf.QuantQualityTier(m.Quant))
+	}
+
+	idx, err := ui.Pick("Several downloaded models match, which one?", table, defaultIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &models[idx], nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/catalog"
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestQuantTable(t *testing.T) {
+	quants := []hf.Quantization{
+		{Name: "Q2_K", Size: 1 << 30},
+		{Name: "Q4_K_M", Size: 2 << 30},
+		{Name: "Q8_0", Size: 4 << 30},
+		{Name: "BF16", Size: 0},
+	}
+
+	table, defaultIndex := quantTable(quants, "Q4_K_M", catalog.Budget{Bytes: 4 << 30})
+	if defaultIndex != 1 {
+		t.Errorf("defaultIndex = %d, want 1", defaultIndex)
+	}
+
+	lines := strings.Split(strings.TrimRight(table.Render(), "\n"), "\n")[1:]
+	tests := []struct {
+		line int
+		want []string
+	}{
+		{0, []string{"Q2_K", "poor", "yes"}},
+		{1, []string{"Q4_K_M (default)", "good", "yes"}},
+		{2, []string{"Q8_0", "excellent", "no"}},
+		{3, []string{"BF16", "full", "?"}},
+	}
+	for _, tt := range tests {
+		for _, want := range tt.want {
+			if !strings.Contains(lines[tt.line], want) {
+				t.Errorf("row %d = %q, missing %q", tt.line, lines[tt.line], want)
+			}
+		}
+	}
+}
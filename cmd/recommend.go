@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/catalog"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+const maxRecommendations = 5
+
+var recommendCmd = &cobra.Command{
+	Use:     "recommend",
+	Short:   "Suggest models that fit your hardware",
+	GroupID: "discovery",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		useCase, _ := cmd.Flags().GetString("use-case")
+		update, _ := cmd.Flags().GetBool("update")
+
+		if !slices.Contains(catalog.UseCases, useCase) {
+			ui.Fatal("Invalid use case '%s': must be one of %s", useCase, strings.Join(catalog.UseCases, ", "))
+		}
+
+		var cat *catalog.Catalog
+		var err error
+		if update {
+			err = ui.WithSpinner("Updating model catalog...", func() error {
+				cat, err = catalog.Update()
+				return err
+			})
+			if err != nil {
+				ui.Fatal("Failed to update catalog: %v", err)
+			}
+		} else if cat, err = catalog.Load(); err != nil {
+			ui.Fatal("Failed to load catalog: %v", err)
+		}
+
+		info := hw.Detect()
+		budget := catalog.BudgetFor(info)
+		if budget.Bytes == 0 {
+			ui.Fatal("Could not detect system memory")
+		}
+
+		recs := cat.Recommend(useCase, budget)
+		fmt.Printf("%s %s available for models on %s\n\n", ui.Header("Hardware:"), ui.FormatBytes(budget.Bytes), budget.Device)
+
+		if len(recs) == 0 {
+			fmt.Println(ui.Muted("No catalog models fit this machine"))
+			return
+		}
+		if len(recs) > maxRecommendations {
+			recs = recs[:maxRecommendations]
+		}
+
+		// Errors are ignored since install indicators are non-critical UI hints.
+		installed := make(map[string]bool)
+		if downloaded, err := proxy.NewModelResolver().ListDownloadedModels(); err == nil {
+			for _, m := range downloaded {
+				installed[strings.ToLower(m.User+"/"+m.Repo+":"+m.Quant)] = true
+			}
+		}
+
+		table := ui.NewTable().
+			Indent(0).
+			AddColumn("MODEL", 0, ui.AlignLeft).
+			AddColumn("SIZE", 9, ui.AlignRight).
+			AddColumn("DESCRIPTION", 0, ui.AlignLeft)
+
+		for _, r := range recs {
+			indicator := "○"
+			if installed[strings.ToLower(r.Ref())] {
+				indicator = "✓"
+			}
+			table.AddRow(indicator+" "+r.Ref(), ui.FormatBytes(r.Quant.Size()), r.Model.Description)
+		}
+
+		fmt.Print(table.Render())
+		fmt.Println("\n✓ = installed")
+		fmt.Printf("Try it: lleme run %s\n", recs[0].Ref())
+	},
+}
+
+func init() {
+	recommendCmd.Flags().String("use-case", catalog.UseCaseChat, "What the model is for: "+strings.Join(catalog.UseCases, ", "))
+	recommendCmd.Flags().Bool("update", false, "Download the latest model catalog first")
+	rootCmd.AddCommand(recommendCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/catalog"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var registryCmd = &cobra.Command{
+	Use:     "registry",
+	Short:   "List and update short model names",
+	GroupID: "discovery",
+	Long: `The registry maps short names to Hugging Face models so you can run
+'lleme pull llama3.2' instead of the full user/repo:quant reference.`,
+}
+
+var registryListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List short model names",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cat, err := catalog.Load()
+		if err != nil {
+			ui.Fatal("Failed to load registry: %v", err)
+		}
+
+		table := ui.NewTable().
+			Indent(0).
+			AddColumn("NAME", 0, ui.AlignLeft).
+			AddColumn("MODEL", 0, ui.AlignLeft).
+			AddColumn("USE", 0, ui.AlignLeft)
+		for _, m := range cat.Models {
+			table.AddRow(m.Name, m.Repo+":"+m.DefaultQuant(), strings.Join(m.UseCases, ", "))
+		}
+
+		fmt.Print(table.Render())
+		fmt.Printf("\nUpdated %s. Use 'lleme registry update' to refresh.\n", cat.Updated)
+	},
+}
+
+var registryUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Download the latest registry",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var cat *catalog.Catalog
+		err := ui.WithSpinner("Updating registry...", func() error {
+			var err error
+			cat, err = catalog.Update()
+			return err
+		})
+		if err != nil {
+			ui.Fatal("Failed to update registry: %v", err)
+		}
+		fmt.Printf("Registry updated: %d models, %d sampler presets (%s)\n", len(cat.Models), len(cat.Samplers), cat.Updated)
+	},
+}
+
+// lookupShortName resolves a registry short name to a Hugging Face reference.
+// quant is the one given after the name, or the registry default if none was.
+// explicit reports whether the quant was given.
+func lookupShortName(ref string) (user, repo, quant string, explicit, ok bool) {
+	cat, err := catalog.Load()
+	if err != nil {
+		return "", "", "", false, false
+	}
+	m, quant, ok := cat.Lookup(ref)
+	if !ok {
+		return "", "", "", false, false
+	}
+	user, repo, _ = strings.Cut(m.Repo, "/")
+	explicit = quant != ""
+	if !explicit {
+		quant = m.DefaultQuant()
+	}
+	logs.Debug("Registry short name", "name", ref, "repo", m.Repo, "quant", quant, "explicit_quant", explicit)
+	return user, repo, quant, explicit, true
+}
+
+func init() {
+	registryCmd.AddCommand(registryListCmd)
+	registryCmd.AddCommand(registryUpdateCmd)
+	rootCmd.AddCommand(registryCmd)
+}
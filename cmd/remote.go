@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// remoteHost is set by --host on the commands that can manage a server on
+// another machine
+var remoteHost string
+
+// addRemoteFlag lets cmd target the server given by --host or LLEME_HOST
+func addRemoteFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&remoteHost, "host", "H", "", "Use the lleme server at this address instead of the local one (or set LLEME_HOST)")
+}
+
+// remoteURL returns the base URL of the server named by --host or
+// LLEME_HOST, or "" to use the local server.
+func remoteURL() string {
+	host := remoteHost
+	if host == "" {
+		host = os.Getenv("LLEME_HOST")
+	}
+	if host == "" {
+		return ""
+	}
+	u, err := parseRemoteURL(host)
+	if err != nil {
+		ui.Fatal("Invalid host %q: %v", host, err)
+	}
+	return u
+}
+
+// parseRemoteURL normalizes a server address: a bare host gets http://, and
+// plain http gets the default port.
+func parseRemoteURL(host string) (string, error) {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme %s", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	if u.Port() == "" && u.Scheme == "http" {
+		u.Host = net.JoinHostPort(u.Hostname(), "11313")
+	}
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/"), nil
+}
+
+// proxyHTTPClient returns a client for talking to a lleme server. It sends
+// LLEME_API_KEY, which servers on a public address require from other
+// machines.
+func proxyHTTPClient(timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if key := os.Getenv("LLEME_API_KEY"); key != "" {
+		client.Transport = server.APIKeyTransport(key, nil)
+	}
+	return client
+}
+
+// remoteAPI returns an API client for the server at baseURL
+func remoteAPI(baseURL string) *server.APIClient {
+	return server.NewAPIClientFromURL(baseURL).WithAPIKey(os.Getenv("LLEME_API_KEY"))
+}
+
+// responseError turns a failed server response into an error carrying the
+// server's message when it sent one
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var e proxy.OpenAIError
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		return fmt.Errorf("%s (HTTP %d)", e.Error.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("HTTP %d", resp.StatusCode)
+}
+
+// resolveRemoteModel resolves a model name on the remote server the same way
+// a completion request would, returning its full name
+func resolveRemoteModel(baseURL, query string) (string, error) {
+	resp, err := proxyHTTPClient(30 * time.Second).Get(baseURL + (&url.URL{Path: "/v1/models/" + query}).EscapedPath())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("no model matching '%s' is downloaded on %s (pull it with: lleme pull --host %s <user/repo>)", query, baseURL, baseURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", responseError(resp)
+	}
+
+	var model proxy.OpenAIModelInfo
+	if err := json.NewDecoder(resp.Body).Decode(&model); err != nil {
+		return "", err
+	}
+	return model.ID, nil
+}
+
+// listRemoteModels returns the models downloaded on the remote server
+func listRemoteModels(baseURL string) ([]ModelInfo, error) {
+	resp, err := proxyHTTPClient(30 * time.Second).Get(baseURL + "/v1/models")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	var list proxy.OpenAIModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+
+	var models []ModelInfo
+	for _, m := range list.Data {
+		user, repo, quant, err := parseModelRef(m.ID)
+		if err != nil {
+			continue
+		}
+		info := ModelInfo{User: user, Repo: repo, Quant: quant}
+		if l := m.Lleme; l != nil {
+			info.Size = l.SizeBytes
+			info.LastUsed = l.LastActivity
+			info.Tags = l.Tags
+			info.Note = l.Note
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// filterModelsByTag keeps the models whose repo has tag
+func filterModelsByTag(models []ModelInfo, tag string) []ModelInfo {
+	return slices.DeleteFunc(models, func(m ModelInfo) bool {
+		return !slices.Contains(m.Tags, strings.ToLower(tag))
+	})
+}
+
+// pullRemote downloads a model on the remote server, showing the progress it
+// streams back
+func pullRemote(baseURL, ref string) {
+	body, err := json.Marshal(proxy.PullRequest{Model: ref})
+	if err != nil {
+		progressFatal("Failed to encode request: %v", err)
+	}
+	resp, err := proxyHTTPClient(0).Post(baseURL+"/api/pull", "application/json", bytes.NewReader(body))
+	if err != nil {
+		progressFatal("Failed to pull %s: %v", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		progressFatal("Failed to pull %s: %v", ref, responseError(resp))
+	}
+
+	fmt.Fprintf(humanOut(), "Pulling %s on %s\n", ui.Keyword(ref), baseURL)
+	progressEvents.Start(ref, "", 0)
+
+	var bar *ui.ProgressBar
+	var phase string
+	finishBar := func() {
+		if bar == nil {
+			return
+		}
+		if phase == "download" {
+			bar.Finish("Downloaded")
+		} else {
+			bar.Finish("Verified")
+		}
+		bar = nil
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var e proxy.PullEvent
+		if err := dec.Decode(&e); err != nil {
+			if bar != nil {
+				bar.Stop()
+			}
+			progressFatal("Lost connection to %s: %v", baseURL, err)
+		}
+
+		switch e.Phase {
+		case "deferred":
+			msg := fmt.Sprintf("Waiting to download until power is back (%s)", e.Reason)
+			progressEvents.Status(msg)
+			fmt.Fprintln(humanOut(), ui.Muted(msg))
+		case "done":
+			finishBar()
+			fmt.Fprintf(humanOut(), "Pulled %s\n", e.Model)
+			progressEvents.Done(progressEvent{Target: e.Model})
+			return
+		case "error":
+			if bar != nil {
+				bar.Stop()
+			}
+			progressFatal("%s", e.Error)
+		default:
+			if progressEvents != nil {
+				progressEvents.Progress(e.Phase, e.Completed, e.Total)
+				continue
+			}
+			if e.Phase != phase {
+				finishBar()
+				phase = e.Phase
+				bar = ui.NewProgressBar()
+				if phase == "download" {
+					bar.Start("", e.Total)
+				} else {
+					bar.Start("Verifying", e.Total)
+				}
+			}
+			bar.Update(e.Completed, e.Total)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantErr bool
+	}{
+		{"gpu-box", "http://gpu-box:11313", false},
+		{"192.168.1.20:8080", "http://192.168.1.20:8080", false},
+		{"http://gpu-box:11313/", "http://gpu-box:11313", false},
+		{"https://llm.example.com", "https://llm.example.com", false},
+		{"https://llm.example.com/lleme", "https://llm.example.com/lleme", false},
+		{"[::1]", "http://[::1]:11313", false},
+		{"ftp://gpu-box", "", true},
+		{"http://", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := parseRemoteURL(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRemoteURL(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseRemoteURL(%q) = %q, want %q", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteModels(t *testing.T) {
+	t.Setenv("LLEME_API_KEY", "secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"Invalid API key","type":"authentication_error"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object":"list","data":[
+				{"id":"user/repo:Q4_K_M","lleme":{"status":"ready","size_bytes":1024,"tags":["coding"]}},
+				{"id":"user/other:Q8_0","lleme":{"size_bytes":2048}}
+			]}`))
+		case "/v1/models/repo":
+			w.Write([]byte(`{"id":"user/repo:Q4_K_M"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	models, err := listRemoteModels(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 || models[0].Repo != "repo" || models[0].Quant != "Q4_K_M" || models[1].Size != 2048 {
+		t.Errorf("listRemoteModels() = %+v", models)
+	}
+	if tagged := filterModelsByTag(models, "Coding"); len(tagged) != 1 || tagged[0].Repo != "repo" {
+		t.Errorf("filterModelsByTag() = %+v", tagged)
+	}
+
+	name, err := resolveRemoteModel(srv.URL, "repo")
+	if err != nil || name != "user/repo:Q4_K_M" {
+		t.Errorf("resolveRemoteModel() = %q, %v", name, err)
+	}
+	if _, err := resolveRemoteModel(srv.URL, "missing"); err == nil {
+		t.Error("resolveRemoteModel() of a missing model should fail")
+	}
+
+	t.Setenv("LLEME_API_KEY", "wrong")
+	if _, err := listRemoteModels(srv.URL); err == nil || err.Error() != "Invalid API key (HTTP 401)" {
+		t.Errorf("listRemoteModels() error = %v, want the server's message", err)
+	}
+}
@@ -10,8 +10,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/peer"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
@@ -124,13 +125,13 @@ Examples:
 			var prompt string
 			if len(models) == 1 {
 				m := models[0]
-				prompt = fmt.Sprintf("Remove %s (%s)?", hf.FormatModelName(m.User, m.Repo, m.Quant), ui.FormatBytes(m.Size))
+				prompt = i18n.Tf("Remove %s (%s)?", hf.FormatModelName(m.User, m.Repo, m.Quant), ui.FormatBytes(m.Size))
 			} else {
-				prompt = fmt.Sprintf("Remove %d model(s), %s total?", len(models), ui.FormatBytes(totalSize))
+				prompt = i18n.Tf("Remove %d model(s), %s total?", len(models), ui.FormatBytes(totalSize))
 			}
 
 			if !ui.PromptYesNo(prompt, false) {
-				fmt.Println(ui.Muted("Cancelled"))
+				fmt.Println(ui.Muted(i18n.T("Cancelled")))
 				return
 			}
 		}
@@ -139,40 +140,11 @@ Examples:
 		removed := 0
 		var freedSize int64
 		for _, m := range models {
-			// Find the actual model path (handles both single and split files)
-			modelPath := hf.FindModelFile(m.User, m.Repo, m.Quant)
-			if modelPath == "" {
-				modelPath = hf.GetModelFilePath(m.User, m.Repo, m.Quant)
-			}
-
-			// Check if this is a split file (stored in a quant subdirectory)
-			splitDir := hf.GetSplitModelDir(m.User, m.Repo, m.Quant)
-			if info, err := os.Stat(splitDir); err == nil && info.IsDir() {
-				// Remove the entire split directory
-				if err := os.RemoveAll(splitDir); err != nil {
-					ui.PrintError("Failed to remove %s: %v", hf.FormatModelName(m.User, m.Repo, m.Quant), err)
-					continue
-				}
-			} else {
-				// Single file - remove it directly
-				if err := os.Remove(modelPath); err != nil {
-					ui.PrintError("Failed to remove %s: %v", hf.FormatModelName(m.User, m.Repo, m.Quant), err)
-					continue
-				}
+			if err := hf.RemoveModel(m.User, m.Repo, m.Quant); err != nil {
+				ui.PrintError("Failed to remove %s: %v", hf.FormatModelName(m.User, m.Repo, m.Quant), err)
+				continue
 			}
 			freedSize += m.Size
-
-			// Also remove associated files (manifest, mmproj, metadata)
-			// These may not exist; errors are expected and safe to ignore
-			os.Remove(hf.GetManifestFilePath(m.User, m.Repo, m.Quant))
-			os.Remove(hf.GetMMProjFilePath(m.User, m.Repo, m.Quant))
-
-			// Clean up empty directories
-			modelDir := hf.GetModelPath(m.User, m.Repo)
-			cleanEmptyDir(modelDir)
-			userDir := filepath.Dir(modelDir)
-			cleanEmptyDir(userDir)
-
 			removed++
 		}
 
@@ -194,7 +166,7 @@ Examples:
 
 // findModels returns models matching the pattern and filters
 func findModels(pattern string, olderThan time.Duration, largerThan int64) ([]ModelInfo, error) {
-	return findModelsInDir(config.ModelsPath(), pattern, olderThan, largerThan)
+	return findModelsInDir(paths.Models(), pattern, olderThan, largerThan)
 }
 
 // findModelsInDir is the testable version of findModels
@@ -384,17 +356,6 @@ func parseSize(s string) (int64, error) {
 	}
 }
 
-// cleanEmptyDir removes a directory if it's empty
-func cleanEmptyDir(dir string) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return
-	}
-	if len(entries) == 0 {
-		os.Remove(dir)
-	}
-}
-
 func init() {
 	removeCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "Skip confirmation")
 	removeCmd.Flags().StringVar(&rmOlderThan, "older-than", "", "Remove models not used in this duration (e.g., 24h, 7d, 4w)")
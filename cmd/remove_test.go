@@ -375,44 +375,6 @@ func TestFindModelsSplitFiles(t *testing.T) {
 	}
 }
 
-func TestCleanEmptyDir(t *testing.T) {
-	t.Run("removes empty directory", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		emptyDir := filepath.Join(tmpDir, "empty")
-		if err := os.Mkdir(emptyDir, 0755); err != nil {
-			t.Fatalf("Failed to create dir: %v", err)
-		}
-
-		cleanEmptyDir(emptyDir)
-
-		if _, err := os.Stat(emptyDir); !os.IsNotExist(err) {
-			t.Error("cleanEmptyDir() did not remove empty directory")
-		}
-	})
-
-	t.Run("keeps non-empty directory", func(t *testing.T) {
-		tmpDir := t.TempDir()
-		nonEmptyDir := filepath.Join(tmpDir, "nonempty")
-		if err := os.Mkdir(nonEmptyDir, 0755); err != nil {
-			t.Fatalf("Failed to create dir: %v", err)
-		}
-		if err := os.WriteFile(filepath.Join(nonEmptyDir, "file.txt"), []byte("data"), 0644); err != nil {
-			t.Fatalf("Failed to create file: %v", err)
-		}
-
-		cleanEmptyDir(nonEmptyDir)
-
-		if _, err := os.Stat(nonEmptyDir); os.IsNotExist(err) {
-			t.Error("cleanEmptyDir() removed non-empty directory")
-		}
-	})
-
-	t.Run("handles non-existent directory", func(t *testing.T) {
-		// Should not panic
-		cleanEmptyDir("/nonexistent/path/that/does/not/exist")
-	})
-}
-
 // createTestFile creates a sparse file of the given size
 func createTestFile(path string, size int64) error {
 	f, err := os.Create(path)
@@ -3,14 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
 	"github.com/nchapman/lleme/internal/version"
 	"github.com/spf13/cobra"
 )
 
-var verbose bool
+var verbosity int
 
 var rootCmd = &cobra.Command{
 	Use:     "lleme",
@@ -21,14 +25,67 @@ var rootCmd = &cobra.Command{
 Point it at any GGUF model on Hugging Face, and it handles the rest—downloading,
 caching, and running inference.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		logs.InitLogger(nil, verbose)
+		logs.InitLogger(nil, verbosity)
+		migrateLayout()
+		applyUISettings()
 		if err := config.EnsureDirectories(); err != nil {
 			fmt.Printf("Error: Failed to create directories: %v\n", err)
 			os.Exit(1)
 		}
+		recordUsage(cmd)
 	},
 }
 
+// migrateLayout moves ~/.lleme into the XDG directories the first time a
+// newer lleme runs. It waits while a server is running from the old
+// location, and leaves ~/.lleme in use if the move fails.
+func migrateLayout() {
+	if proxy.GetRunningProxyState() != nil {
+		return
+	}
+	moved, err := paths.Migrate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, ui.Warning(fmt.Sprintf("Still using %s: %v", paths.Legacy(), err)))
+		fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("Set LLEME_HOME=%s to keep it there and silence this", paths.Legacy())))
+		return
+	}
+	if moved {
+		fmt.Fprintln(os.Stderr, ui.Muted(fmt.Sprintf("Moved %s to %s", paths.Legacy(), paths.DataDir())))
+	}
+}
+
+// applyUISettings applies the ui section of the config. A config that fails
+// to load is left for the command itself to report.
+func applyUISettings() {
+	mode := ui.ColorAuto
+	if cfg, err := config.Load(); err == nil {
+		if cfg.UI.Color != "" {
+			mode = cfg.UI.Color
+		}
+		ui.SetAccessible(cfg.UI.Accessible)
+		if err := ui.SetTheme(cfg.UI.Theme, cfg.UI.Colors); err != nil {
+			ui.PrintError("%v", err)
+		}
+	}
+	profile, err := ui.SetColorMode(mode)
+	if err != nil {
+		ui.PrintError("%v", err)
+		return
+	}
+	if mode != ui.ColorAuto {
+		logs.SetColorProfile(profile)
+	}
+}
+
+// verbosityArgs returns the -v flags to pass to a child lleme process so
+// it logs at the same level
+func verbosityArgs() []string {
+	if verbosity == 0 {
+		return nil
+	}
+	return []string{"-" + strings.Repeat("v", verbosity)}
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -36,7 +93,7 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	rootCmd.PersistentFlags().CountVarP(&verbosity, "verbose", "v", "Log debug details to stderr (-vv adds HTTP headers)")
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
 	// Add command groups
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,13 +13,19 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/filter"
 	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/i18n"
 	"github.com/nchapman/lleme/internal/llama"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/options"
 	"github.com/nchapman/lleme/internal/peer"
 	"github.com/nchapman/lleme/internal/proxy"
 	"github.com/nchapman/lleme/internal/server"
 	"github.com/nchapman/lleme/internal/tui/chat"
+	"github.com/nchapman/lleme/internal/tui/history"
+	"github.com/nchapman/lleme/internal/tui/termimg"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
 )
@@ -31,11 +38,27 @@ var (
 	minP          float64
 	repeatPenalty float64
 	systemPrompt  string
+	outputFile    string
+	maxContinues  int
+	detach        bool
+	noSmooth      bool
+	printArgs     bool
 
 	// Server options (require model reload)
 	ctxSize   int
 	gpuLayers int
 	threads   int
+
+	// Long-context options (require model reload)
+	ropeScaling   string
+	ropeFreqBase  float64
+	ropeFreqScale float64
+	yarnOrigCtx   int
+	overrideKV    []string
+
+	// Context window options (require model reload)
+	contextShift bool
+	cacheReuse   int
 )
 
 var runCmd = &cobra.Command{
@@ -54,7 +77,19 @@ Personas:
   - Personas provide saved system prompts and options
 
 The proxy server will be auto-started if not running.
-Models are loaded on-demand and unloaded after idle timeout.`,
+Models are loaded on-demand and unloaded after idle timeout.
+
+With --host (or LLEME_HOST), the model runs on that machine's server instead,
+and must already be downloaded there.
+
+With --detach, a prompt is handed to the server as a background job and its
+job ID printed at once; read the reply later with 'lleme jobs logs <id>'.
+Background jobs are experimental: the server needs the background-jobs
+feature turned on (see 'lleme doctor').
+
+With --print-args, nothing is started: the llama-server command the model
+would be launched with is printed instead, after config, persona, flag and
+per-model options are merged and the chat template is patched.`,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg, err := config.Load()
@@ -62,8 +97,16 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 			ui.Fatal("Failed to load config: %v", err)
 		}
 
+		// --system @name uses a prompt from the library
+		systemPrompt, err = config.ResolveSystemPrompt(systemPrompt)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		remote := remoteURL()
+
 		// Step 1: Ensure llama.cpp is installed
-		if !llama.IsInstalled() {
+		if !llama.IsInstalled() && !mock.Enabled() && remote == "" && !printArgs {
 			if err := ensureLlamaInstalled(); err != nil {
 				ui.Fatal("%v", err)
 			}
@@ -75,6 +118,7 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 
 		// Check if this is a persona (personas take precedence over model names)
 		var activePersona *config.Persona
+		var outputFilter *filter.Filter
 		if config.PersonaExists(modelQuery) {
 			personaName = modelQuery // Save persona name before modelQuery changes
 			persona, err := config.LoadPersona(modelQuery)
@@ -99,36 +143,70 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 			if systemPrompt == "" && persona.System != "" {
 				systemPrompt = persona.System
 			}
+
+			outputFilter, err = filter.Compile(persona.Filters)
+			if err != nil {
+				ui.Fatal("Invalid filters in persona '%s': %v", personaName, err)
+			}
 		}
 
-		// Step 2: Validate model exists (or offer to pull)
-		resolvedModel, err := validateModel(modelQuery, cfg)
-		if err != nil {
+		// Track which server options were explicitly set
+		ctxSizeSet := cmd.Flags().Changed("ctx-size")
+		gpuLayersSet := cmd.Flags().Changed("gpu-layers")
+		threadsSet := cmd.Flags().Changed("threads")
+
+		// Persona server options, with server option flags taking precedence
+		requested := options.Resolve(options.Layers{
+			Persona: activePersona.GetServerOptions(),
+			Flags:   serverFlagOptions(cmd),
+		})
+		var serverOpts map[string]any
+		if requested.Len() > 0 {
+			serverOpts = requested.Map()
+		}
+		if err := config.ValidateServerOptions(serverOpts); err != nil {
 			ui.Fatal("%v", err)
 		}
 
-		// Step 3: Ensure proxy is running
-		proxyURL, err := ensureProxyRunning(cfg)
-		if err != nil {
-			ui.Fatal("Failed to start proxy: %v", err)
+		if printArgs {
+			printLaunch(cfg, remote, modelQuery, runOptions(cmd, serverOpts, activePersona.GetPassthrough()), requested)
+			return
 		}
 
-		// Create API client pointing to proxy
-		api := server.NewAPIClientFromURL(proxyURL)
+		var api *server.APIClient
+		var modelName string
+		baseURL := remote
+		if remote != "" {
+			// The remote server resolves names against its own models
+			modelName, err = resolveRemoteModel(remote, modelQuery)
+			if err != nil {
+				ui.Fatal("%v", err)
+			}
+			api = remoteAPI(remote)
+		} else {
+			// Step 2: Validate model exists (or offer to pull)
+			resolvedModel, err := validateModel(modelQuery, cfg)
+			if errors.Is(err, ui.ErrCancelled) {
+				return
+			} else if err != nil {
+				ui.Fatal("%v", err)
+			}
+			modelName = resolvedModel.FullName
+
+			// Step 3: Ensure proxy is running
+			proxyURL, err := ensureProxyRunning(cfg)
+			if err != nil {
+				ui.Fatal("Failed to start proxy: %v", err)
+			}
+			api = server.NewAPIClientFromURL(proxyURL)
+			baseURL = proxyURL
+		}
 
 		// Check health
 		if err := api.Health(); err != nil {
 			ui.Fatal("Proxy health check failed: %v", err)
 		}
 
-		// Use the resolved full model name
-		modelName := resolvedModel.FullName
-
-		// Track which server options were explicitly set
-		ctxSizeSet := cmd.Flags().Changed("ctx-size")
-		gpuLayersSet := cmd.Flags().Changed("gpu-layers")
-		threadsSet := cmd.Flags().Changed("threads")
-
 		promptArg := ""
 		if len(args) > promptStartIdx {
 			promptArg = strings.Join(args[promptStartIdx:], " ")
@@ -154,34 +232,43 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 			}
 		}
 
+		if detach && promptArg == "" {
+			ui.Fatal("--detach needs a prompt, as an argument or on stdin")
+		}
+
 		// One-shot mode for CLI prompts or piped input
 		if promptArg != "" {
 			// Preload model with options (sync - user is blocked waiting for output anyway)
-			var personaOpts map[string]any
-			if activePersona != nil {
-				personaOpts = activePersona.GetServerOptions()
-			}
-			if ctxSizeSet || gpuLayersSet || threadsSet || personaOpts != nil {
-				opts := &server.RunOptions{
-					Options: personaOpts,
-				}
-				if ctxSizeSet {
-					opts.CtxSize = server.IntPtr(ctxSize)
-				}
-				if gpuLayersSet {
-					opts.GpuLayers = server.IntPtr(gpuLayers)
-				}
-				if threadsSet {
-					opts.Threads = server.IntPtr(threads)
-				}
-				if err := api.Run(modelName, opts); err != nil {
+			passthrough := activePersona.GetPassthrough()
+			if ctxSizeSet || gpuLayersSet || threadsSet || serverOpts != nil || passthrough != nil {
+				if err := api.Run(modelName, runOptions(cmd, serverOpts, passthrough)); err != nil {
 					ui.Fatal("Failed to load model: %v", err)
 				}
 			}
 
 			session := NewChatSession(api, modelName, cfg, activePersona)
 			session.SetSystemPrompt(systemPrompt)
+			session.SetOutputFilter(outputFilter)
+			if cmd.Flags().Changed("max-continues") {
+				session.SetMaxContinues(maxContinues)
+			}
 			session.SetSamplingOptions(temperature, topP, minP, repeatPenalty, topK, tokens)
+			if detach {
+				job, err := submitJob(baseURL, session.Request(promptArg))
+				if err != nil {
+					ui.Fatal("Failed to submit job: %v", err)
+				}
+				printSubmittedJob(job)
+				return
+			}
+			if outputFile != "" {
+				f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+				if err != nil {
+					ui.Fatal("Failed to open output file: %v", err)
+				}
+				defer f.Close()
+				session.SetOutput(f)
+			}
 			if err := session.Run(promptArg); err != nil {
 				ui.Fatal("Chat failed: %v", err)
 			}
@@ -191,8 +278,44 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 		// Launch TUI for interactive mode
 		m := chat.New(api, modelName, cfg, activePersona, personaName)
 		m.SetInitialServerOptions(ctxSize, gpuLayers, threads, ctxSizeSet, gpuLayersSet, threadsSet)
+		m.SetServerOptions(serverOpts)
 		m.SetSamplingOptions(temperature, topP, minP, repeatPenalty, topK, tokens)
 		m.SetSystemPrompt(systemPrompt)
+		m.SetOutputFilter(outputFilter)
+		keys, err := chat.NewKeyMap(cfg.Chat.Keymap, cfg.Chat.Keys)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		m.SetKeyMap(keys)
+		imageProtocol, err := termimg.ParseProtocol(cfg.Chat.Images)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		m.SetImageProtocol(imageProtocol)
+		inputHistory, err := history.Load(modelName, cfg.Chat.HistorySize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s %s\n", ui.Warning("!"), i18n.Tf("Input history unavailable: %v", err))
+		}
+		m.SetHistory(inputHistory)
+		if cmd.Flags().Changed("max-continues") {
+			m.SetMaxContinues(maxContinues)
+		}
+		if noSmooth {
+			m.SetSmoothStream(false)
+		}
+		if outputFile != "" {
+			if err := m.StartTee(outputFile); err != nil {
+				ui.Fatal("%v", err)
+			}
+		}
+		defer m.Close()
+
+		if ui.Accessible() {
+			if err := m.RunLinear(os.Stdin, os.Stdout); err != nil {
+				ui.Fatal("Chat failed: %v", err)
+			}
+			return
+		}
 
 		p := tea.NewProgram(m, tea.WithAltScreen())
 		m.SetProgram(p)
@@ -203,6 +326,51 @@ Models are loaded on-demand and unloaded after idle timeout.`,
 	},
 }
 
+// runOptions returns the server options to load the model with: the
+// explicit --ctx-size, --gpu-layers and --threads flags over serverOpts,
+// and the persona's unchecked passthrough options
+func runOptions(cmd *cobra.Command, serverOpts, passthrough map[string]any) *server.RunOptions {
+	opts := &server.RunOptions{Options: serverOpts, Passthrough: passthrough}
+	if cmd.Flags().Changed("ctx-size") {
+		opts.CtxSize = server.IntPtr(ctxSize)
+	}
+	if cmd.Flags().Changed("gpu-layers") {
+		opts.GpuLayers = server.IntPtr(gpuLayers)
+	}
+	if cmd.Flags().Changed("threads") {
+		opts.Threads = server.IntPtr(threads)
+	}
+	return opts
+}
+
+// serverFlagOptions returns the llama-server options set by the long-context
+// and context window flags, keyed by their llama-server names.
+func serverFlagOptions(cmd *cobra.Command) map[string]any {
+	opts := make(map[string]any)
+	if cmd.Flags().Changed("rope-scaling") {
+		opts["rope-scaling"] = ropeScaling
+	}
+	if cmd.Flags().Changed("rope-freq-base") {
+		opts["rope-freq-base"] = ropeFreqBase
+	}
+	if cmd.Flags().Changed("rope-freq-scale") {
+		opts["rope-freq-scale"] = ropeFreqScale
+	}
+	if cmd.Flags().Changed("yarn-orig-ctx") {
+		opts["yarn-orig-ctx"] = yarnOrigCtx
+	}
+	if len(overrideKV) > 0 {
+		opts["override-kv"] = overrideKV
+	}
+	if cmd.Flags().Changed("context-shift") {
+		opts["context-shift"] = contextShift
+	}
+	if cmd.Flags().Changed("cache-reuse") {
+		opts["cache-reuse"] = cacheReuse
+	}
+	return opts
+}
+
 // ensureLlamaInstalled installs llama.cpp if not present
 func ensureLlamaInstalled() error {
 	fmt.Println("Installing llama.cpp...")
@@ -218,18 +386,25 @@ func ensureLlamaInstalled() error {
 
 // validateModel checks if a model exists, offering to pull it if not found
 func validateModel(query string, cfg *config.Config) (*proxy.DownloadedModel, error) {
-	resolver := proxy.NewModelResolver()
+	resolver := proxy.NewModelResolver().PreferQuant(cfg.HuggingFace.DefaultQuant)
 	result, err := resolver.Resolve(query)
 	if err != nil {
 		return nil, err
 	}
 
-	// Model resolved successfully
+	// Model resolved successfully. Several quants of one model only need a
+	// prompt when none of them is the preferred quant.
 	if result.Model != nil {
+		if len(result.Matches) > 1 && isInteractive() && !strings.EqualFold(result.Model.Quant, cfg.HuggingFace.DefaultQuant) {
+			return pickDownloadedModel(result.Matches, result.Model)
+		}
 		return result.Model, nil
 	}
 
-	// Ambiguous match - user needs to be more specific
+	// Ambiguous match - let the user choose, or ask them to be more specific
+	if len(result.Matches) > 1 && isInteractive() {
+		return pickDownloadedModel(result.Matches, nil)
+	}
 	if len(result.Matches) > 1 {
 		var b strings.Builder
 		b.WriteString(fmt.Sprintf("'%s' matches multiple models:\n\n", query))
@@ -243,8 +418,20 @@ func validateModel(query string, cfg *config.Config) (*proxy.DownloadedModel, er
 	// Model not found locally - check if it looks like a HuggingFace ref
 	user, repo, quant, parseErr := parseModelRef(query)
 	if parseErr != nil {
-		// Not a valid model ref format, show suggestions
-		return nil, modelNotFoundError(query, result.Suggestions)
+		var explicit, ok bool
+		if user, repo, quant, explicit, ok = lookupShortName(query); !ok {
+			// Not a valid model ref format, show suggestions
+			return nil, modelNotFoundError(query, result.Suggestions)
+		}
+
+		// Use any downloaded quant of a registry model unless one was named
+		ref := user + "/" + repo
+		if explicit {
+			ref += ":" + quant
+		}
+		if local, err := resolver.Resolve(ref); err == nil && local.Model != nil {
+			return local.Model, nil
+		}
 	}
 
 	// Try to pull from HuggingFace
@@ -302,7 +489,9 @@ func offerToPull(cfg *config.Config, user, repo, quant string) (*proxy.Downloade
 
 	// Select quantization
 	if quant == "" {
-		quant = hf.GetBestQuantization(quants)
+		if quant, err = chooseQuant(client, user, repo, quants, cfg.HuggingFace.DefaultQuant); err != nil {
+			return nil, err
+		}
 	} else {
 		if _, found := hf.FindQuantization(quants, quant); !found {
 			var b strings.Builder
@@ -317,7 +506,8 @@ func offerToPull(cfg *config.Config, user, repo, quant string) (*proxy.Downloade
 	selectedQuant, _ := hf.FindQuantization(quants, quant)
 
 	// Get manifest info for display (also returns manifest to pass to PullModel)
-	info, manifest, manifestJSON, err := hf.GetManifestInfo(client, user, repo, selectedQuant)
+	source := hf.NewHFSource(client)
+	info, manifest, manifestJSON, err := hf.GetManifestInfo(source, user, repo, selectedQuant)
 	if err != nil {
 		return nil, err
 	}
@@ -343,7 +533,7 @@ func offerToPull(cfg *config.Config, user, repo, quant string) (*proxy.Downloade
 		opts.PeerDownload = peer.CreateDownloader()
 	}
 
-	result, err := hf.PullModelWithProgressFactory(client, user, repo, selectedQuant, opts, func() hf.ProgressDisplay {
+	result, err := hf.PullModelWithProgressFactory(source, user, repo, selectedQuant, opts, func() hf.ProgressDisplay {
 		return ui.NewProgressBar()
 	})
 	if err != nil {
@@ -376,7 +566,11 @@ func offerToPull(cfg *config.Config, user, repo, quant string) (*proxy.Downloade
 func ensureProxyRunning(cfg *config.Config) (string, error) {
 	// Check if proxy is already running
 	if state := proxy.GetRunningProxyState(); state != nil {
-		return fmt.Sprintf("http://%s:%d", state.Host, state.Port), nil
+		proxyURL, restarted, err := checkBinaryUpgrade(cfg, state)
+		if err != nil || restarted {
+			return proxyURL, err
+		}
+		return checkServerVersion(cfg, proxyURL)
 	}
 
 	// Need to start proxy
@@ -402,6 +596,7 @@ func ensureProxyRunning(cfg *config.Config) (string, error) {
 		"--host", host,
 		"--port", fmt.Sprintf("%d", port),
 	}
+	args = append(args, verbosityArgs()...)
 
 	cmd := exec.Command(executable, args...)
 	cmd.Env = os.Environ()
@@ -444,6 +639,7 @@ func ensureProxyRunning(cfg *config.Config) (string, error) {
 
 func init() {
 	rootCmd.AddCommand(runCmd)
+	addRemoteFlag(runCmd)
 
 	// Sampling options (apply per-request)
 	runCmd.Flags().Float64VarP(&temperature, "temp", "t", 0, "Temperature")
@@ -452,10 +648,26 @@ func init() {
 	runCmd.Flags().Float64Var(&minP, "min-p", 0, "Min-p sampling")
 	runCmd.Flags().Float64Var(&repeatPenalty, "repeat-penalty", 0, "Repeat penalty")
 	runCmd.Flags().IntVarP(&tokens, "predict", "n", 0, "Max tokens to generate")
-	runCmd.Flags().StringVarP(&systemPrompt, "system", "s", "", "System prompt")
+	runCmd.Flags().StringVarP(&systemPrompt, "system", "s", "", "System prompt, or @name for a saved prompt")
+	runCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Also append responses to this file")
+	runCmd.Flags().IntVar(&maxContinues, "max-continues", 0, "Continue replies cut off by the token limit up to this many times")
+	runCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run the prompt as a background job on the server and print its ID")
+	runCmd.Flags().BoolVar(&noSmooth, "no-smooth", false, "Show replies as they arrive, ignoring ui.smooth_stream")
+	runCmd.Flags().BoolVar(&printArgs, "print-args", false, "Print the llama-server command the model would start with, without starting it")
 
 	// Server options (affect model loading)
 	runCmd.Flags().IntVar(&ctxSize, "ctx-size", 0, "Context size (0 = model default)")
 	runCmd.Flags().IntVar(&gpuLayers, "gpu-layers", 0, "GPU layers to offload (0 = auto)")
 	runCmd.Flags().IntVar(&threads, "threads", 0, "CPU threads (0 = auto)")
+
+	// Long-context options (affect model loading)
+	runCmd.Flags().StringVar(&ropeScaling, "rope-scaling", "", "RoPE scaling method (none, linear, yarn)")
+	runCmd.Flags().Float64Var(&ropeFreqBase, "rope-freq-base", 0, "RoPE base frequency (0 = from model)")
+	runCmd.Flags().Float64Var(&ropeFreqScale, "rope-freq-scale", 0, "RoPE frequency scaling factor")
+	runCmd.Flags().IntVar(&yarnOrigCtx, "yarn-orig-ctx", 0, "YaRN original context size (0 = from model)")
+	runCmd.Flags().StringArrayVar(&overrideKV, "override-kv", nil, "Override model metadata (KEY=TYPE:VALUE, repeatable)")
+
+	// Context window options (affect model loading)
+	runCmd.Flags().BoolVar(&contextShift, "context-shift", false, "Slide the context window instead of failing when full")
+	runCmd.Flags().IntVar(&cacheReuse, "cache-reuse", 0, "Min chunk size for KV cache reuse via shifting (0 = off)")
 }
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/script"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scriptVars  []string
+	scriptQuiet bool
+)
+
+var scriptCmd = &cobra.Command{
+	Use:     "script <file.lls>",
+	Short:   "Run a chain of prompts from a script",
+	GroupID: "model",
+	Long: `Run a chain of prompts from a script, for experiments that can be rerun
+exactly without writing code. Each reply is printed as it streams, and can
+be kept in a variable to use in later prompts.
+
+Statements, one per line:
+  model <name>              Model for the prompts that follow
+  set <option> <value>      llama-server option, as with /set in chat
+  unset <option>            Go back to the option's default
+  system <text>             System prompt for the prompts that follow
+  let <name> = <text>       Set a variable
+  prompt [-> <name>]        Ask the lines up to "end", keeping the reply
+  print <text>              Print text
+
+{{name}} is replaced with a variable in system, let, prompt and print text,
+and system or let without text on the line read lines up to "end". Lines
+starting with # are comments. The whole script is checked before anything
+runs. Use - to read it from stdin.
+
+Example script:
+  model llama3.2
+  set temp 0.2
+  let topic = RAID 5
+
+  prompt -> outline
+  Write a three-point outline about {{topic}}.
+  end
+
+  prompt -> summary
+  Turn this outline into one paragraph:
+  {{outline}}
+  end
+
+Examples:
+  lleme script experiment.lls
+  lleme script experiment.lls --var topic="ZFS snapshots" -q`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := readScript(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		vars, err := parseScriptVars(scriptVars)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			ui.Fatal("Failed to load config: %v", err)
+		}
+
+		runner := &script.Runner{
+			Out:   os.Stdout,
+			Quiet: scriptQuiet,
+			Vars:  vars,
+			Progress: func(msg string) {
+				fmt.Fprintln(os.Stderr, ui.Muted(msg))
+			},
+		}
+		if remote := remoteURL(); remote != "" {
+			runner.Client = remoteAPI(remote)
+			runner.ResolveModel = func(query string) (string, error) {
+				return resolveRemoteModel(remote, query)
+			}
+		} else {
+			runner.Client = scriptProxy(cfg)
+			runner.ResolveModel = func(query string) (string, error) {
+				model, err := validateModel(query, cfg)
+				if err != nil {
+					return "", err
+				}
+				return model.FullName, nil
+			}
+		}
+
+		if err := runner.Run(context.Background(), s); err != nil {
+			if errors.Is(err, ui.ErrCancelled) {
+				os.Exit(1)
+			}
+			ui.Fatal("%v", err)
+		}
+	},
+}
+
+// readScript parses a script file, or stdin for "-"
+func readScript(path string) (*script.Script, error) {
+	if path == "-" {
+		return script.Parse("stdin", os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open script: %w", err)
+	}
+	defer f.Close()
+	return script.Parse(path, f)
+}
+
+// parseScriptVars reads --var name=value flags
+func parseScriptVars(flags []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, v := range flags {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --var %q; use name=value", v)
+		}
+		vars[strings.TrimSpace(name)] = value
+	}
+	return vars, nil
+}
+
+// scriptProxy starts the local server if needed and returns a client for it
+func scriptProxy(cfg *config.Config) *server.APIClient {
+	if !llama.IsInstalled() && !mock.Enabled() {
+		if err := ensureLlamaInstalled(); err != nil {
+			ui.Fatal("%v", err)
+		}
+	}
+	proxyURL, err := ensureProxyRunning(cfg)
+	if err != nil {
+		ui.Fatal("Failed to start proxy: %v", err)
+	}
+	api := server.NewAPIClientFromURL(proxyURL)
+	if err := api.Health(); err != nil {
+		ui.Fatal("Proxy health check failed: %v", err)
+	}
+	return api
+}
+
+func init() {
+	rootCmd.AddCommand(scriptCmd)
+	addRemoteFlag(scriptCmd)
+
+	scriptCmd.Flags().StringArrayVar(&scriptVars, "var", nil, "Set a variable before the script runs, as name=value (repeatable)")
+	scriptCmd.Flags().BoolVarP(&scriptQuiet, "quiet", "q", false, "Only print what print statements write, not every reply")
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -12,6 +13,8 @@ import (
 	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/llama"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/process"
 	"github.com/nchapman/lleme/internal/proxy"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/spf13/cobra"
@@ -22,6 +25,8 @@ var (
 	serverPort      int
 	serverMaxModels int
 	serverDetach    bool
+	serverInsecure  bool
+	serverQuiet     bool
 )
 
 var serverCmd = &cobra.Command{
@@ -39,6 +44,7 @@ The proxy server:
 Examples:
   lleme server start          # Start in foreground
   lleme server start -d       # Start in background (detached)
+  lleme server start -q       # Start without the startup banner
   lleme server stop           # Stop the server
   lleme server restart        # Restart the server (always in background)`,
 }
@@ -47,7 +53,7 @@ var serverStartCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start the proxy server",
 	PreRun: func(cmd *cobra.Command, args []string) {
-		if !llama.IsInstalled() {
+		if !llama.IsInstalled() && !mock.Enabled() {
 			fmt.Println("Installing llama.cpp...")
 			fmt.Println()
 			if _, err := llama.InstallLatest(func(msg string) { fmt.Println(msg) }); err != nil {
@@ -96,7 +102,13 @@ var serverStopCmd = &cobra.Command{
 var serverRestartCmd = &cobra.Command{
 	Use:   "restart",
 	Short: "Restart the proxy server",
+	Long:  "Restart the proxy server. Models that were loaded are loaded again.",
 	Run: func(cmd *cobra.Command, args []string) {
+		if state := proxy.GetRunningProxyState(); state != nil {
+			if err := proxy.SaveRestoreList(state.Backends); err != nil {
+				logs.Warn("Failed to save models to restore", "error", err)
+			}
+		}
 		stopped, _ := stopServer()
 		if stopped {
 			fmt.Println("Stopped server")
@@ -112,7 +124,8 @@ var serverRestartCmd = &cobra.Command{
 func stopServer() (bool, error) {
 	state := proxy.GetRunningProxyState()
 	if state == nil {
-		// No state file found - try to find process by port (for servers started by older versions)
+		// No usable state file - try to find process by port (for servers started
+		// by older versions, or a state file cut off by a crash)
 		port := 11313 // Default port as fallback
 		if cfg, err := config.Load(); err == nil {
 			port = cfg.Server.Port
@@ -120,6 +133,14 @@ func stopServer() (bool, error) {
 		if serverPort != 0 {
 			port = serverPort
 		}
+		if _, err := proxy.LoadProxyState(); errors.Is(err, proxy.ErrCorruptState) {
+			logs.Debug("Unreadable server state, looking for the server by port", "port", port, "error", err)
+			stopped, err := stopServerByPort(port)
+			if err == nil {
+				proxy.ClearProxyState()
+			}
+			return stopped, err
+		}
 		return stopServerByPort(port)
 	}
 
@@ -150,66 +171,48 @@ func stopServer() (bool, error) {
 // stopServerByPort finds and stops a process listening on the given port.
 // Used as a fallback when no state file exists (e.g., server started by older version).
 func stopServerByPort(port int) (bool, error) {
-	pid := findProcessOnPort(port)
-	if pid == 0 {
+	table, err := snapshotProcesses()
+	if err != nil {
+		return false, fmt.Errorf("could not list processes: %w", err)
+	}
+	owner, ok := table.Owner(port)
+	if !ok {
 		return false, nil
 	}
 
 	// Verify it's a lleme process before killing
-	if !isLlemeProcess(pid) {
-		return false, fmt.Errorf("process on port %d (PID %d) is not a lleme server", port, pid)
+	if !isLlemeProcess(owner) {
+		return false, fmt.Errorf("process on port %d (PID %d) is not a lleme server", port, owner.PID)
 	}
 
-	process, err := os.FindProcess(pid)
+	proc, err := os.FindProcess(owner.PID)
 	if err != nil {
-		return false, fmt.Errorf("could not find process %d: %w", pid, err)
+		return false, fmt.Errorf("could not find process %d: %w", owner.PID, err)
 	}
 
-	if err := process.Signal(syscall.SIGTERM); err != nil {
-		return false, fmt.Errorf("could not signal process %d: %w", pid, err)
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		return false, fmt.Errorf("could not signal process %d: %w", owner.PID, err)
 	}
 
 	for range 40 { // 4 seconds max
 		time.Sleep(100 * time.Millisecond)
-		if err := process.Signal(syscall.Signal(0)); err != nil {
+		if err := proc.Signal(syscall.Signal(0)); err != nil {
 			return true, nil
 		}
 	}
 
-	if err := process.Kill(); err != nil {
-		return false, fmt.Errorf("failed to kill process %d: %w", pid, err)
+	if err := proc.Kill(); err != nil {
+		return false, fmt.Errorf("failed to kill process %d: %w", owner.PID, err)
 	}
 	return true, nil
 }
 
-// findProcessOnPort uses lsof to find the PID of a process listening on a port.
-func findProcessOnPort(port int) int {
-	cmd := exec.Command("lsof", "-ti", fmt.Sprintf("tcp:%d", port))
-	output, err := cmd.Output()
-	if err != nil {
-		return 0
-	}
-
-	// lsof may return multiple PIDs (parent/child); take the first one
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) == 0 {
-		return 0
-	}
-	var pid int
-	if _, err := fmt.Sscanf(lines[0], "%d", &pid); err != nil {
-		return 0
-	}
-	return pid
-}
+// snapshotProcesses lists running processes; tests swap in a fake table
+var snapshotProcesses = process.Snapshot
 
-// isLlemeProcess checks if the given PID is a lleme process.
-func isLlemeProcess(pid int) bool {
-	cmd := exec.Command("ps", "-p", fmt.Sprintf("%d", pid), "-o", "args=")
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-	return strings.Contains(string(output), "lleme")
+// isLlemeProcess checks if a process is a lleme server.
+func isLlemeProcess(p process.Process) bool {
+	return strings.Contains(strings.ToLower(p.CommandLine()), "lleme")
 }
 
 func startServerForeground() {
@@ -230,6 +233,9 @@ func startServerForeground() {
 	if serverMaxModels != 0 {
 		proxyCfg.MaxModels = serverMaxModels
 	}
+	if serverInsecure {
+		proxyCfg.Insecure = true
+	}
 
 	// Create and start server (handles orphan cleanup internally)
 	server := proxy.NewServer(proxyCfg, cfg)
@@ -237,12 +243,36 @@ func startServerForeground() {
 		ui.Fatal("Failed to start server: %v", err)
 	}
 
-	// Print startup info
 	fmt.Printf("Server started on http://%s:%d\n", proxyCfg.Host, proxyCfg.Port)
+	if !serverQuiet {
+		printServerBanner(proxyCfg)
+	}
+
+	// Wait for shutdown signal
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	fmt.Println()
+	fmt.Println("Shutting down...")
+
+	if err := server.Stop(); err != nil {
+		fmt.Printf("%s Failed to stop server cleanly: %v\n", ui.ErrorMsg("Warning:"), err)
+	}
+
+	proxy.ClearProxyState()
+	fmt.Println("Server stopped")
+}
+
+// printServerBanner shows the settings and endpoints of a foreground server
+func printServerBanner(proxyCfg *proxy.Config) {
 	fmt.Println()
 	fmt.Printf("  %-14s %d\n", "Max models", proxyCfg.MaxModels)
 	fmt.Printf("  %-14s %v\n", "Idle timeout", proxyCfg.IdleTimeout)
 	fmt.Printf("  %-14s %d-%d\n", "Backend ports", proxyCfg.BackendPortMin, proxyCfg.BackendPortMax)
+	if proxyCfg.GRPCPort > 0 {
+		fmt.Printf("  %-14s %s:%d\n", "gRPC", proxyCfg.Host, proxyCfg.GRPCPort)
+	}
 	fmt.Println()
 	fmt.Println(ui.Header("Endpoints"))
 	fmt.Printf("  %-12s %s %s\n", "Web UI", ui.Muted("GET"), "/")
@@ -251,28 +281,27 @@ func startServerForeground() {
 	fmt.Printf("  %-12s %s %s\n", "Messages", ui.Muted("POST"), "/v1/messages")
 	fmt.Printf("  %-12s %s %s\n", "Models", ui.Muted("GET"), "/v1/models")
 	fmt.Printf("  %-12s %s %s\n", "Status", ui.Muted("GET"), "/api/status")
+	if proxyCfg.StatusToken != "" {
+		fmt.Printf("  %-12s %s %s\n", "Status page", ui.Muted("GET"), "/status?token=...")
+	}
+	if proxyCfg.HFCache {
+		fmt.Printf("  %-12s %s %s\n", "HF cache", ui.Muted("GET"), "/hf")
+	}
 	fmt.Println()
 
+	if proxyCfg.Public() {
+		fmt.Printf("%s listening on %s, reachable from other machines:\n", ui.ErrorMsg("Warning:"), proxyCfg.Host)
+		for _, e := range proxyCfg.ExposedEndpoints() {
+			fmt.Printf("  %s\n", e)
+		}
+		fmt.Println()
+	}
+
 	installed, _ := llama.GetInstalledVersion()
 	if installed != nil {
 		fmt.Println(ui.LlamaCppCredit(installed.TagName))
 	}
 	fmt.Println(ui.Muted("Press Ctrl+C to stop"))
-
-	// Wait for shutdown signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-
-	fmt.Println()
-	fmt.Println("Shutting down...")
-
-	if err := server.Stop(); err != nil {
-		fmt.Printf("%s Failed to stop server cleanly: %v\n", ui.ErrorMsg("Warning:"), err)
-	}
-
-	proxy.ClearProxyState()
-	fmt.Println("Server stopped")
 }
 
 func startServerDetached() {
@@ -292,6 +321,10 @@ func startServerDetached() {
 	if serverMaxModels != 0 {
 		args = append(args, "--max-models", fmt.Sprintf("%d", serverMaxModels))
 	}
+	if serverInsecure {
+		args = append(args, "--insecure")
+	}
+	args = append(args, verbosityArgs()...)
 
 	// Spawn daemon - it handles its own logging, config loading, etc.
 	cmd := exec.Command(executable, args...)
@@ -304,19 +337,21 @@ func startServerDetached() {
 		ui.Fatal("Failed to start server in background: %v", err)
 	}
 
-	// Poll for state file (up to 5 seconds)
+	// Poll for state file (up to 5 seconds). Messages go to stderr when an
+	// update restarts the server under --progress json.
+	out := humanOut()
 	logPath := logs.ProxyLogPath()
 	deadline := time.Now().Add(5 * time.Second)
 	for time.Now().Before(deadline) {
 		if state := proxy.GetRunningProxyState(); state != nil {
-			fmt.Printf("Server started in background on http://%s:%d (PID %d)\n", state.Host, state.Port, state.PID)
-			fmt.Printf("Web UI available at http://%s:%d\n", state.Host, state.Port)
-			fmt.Printf("Logs: %s\n", ui.Muted(logPath))
+			fmt.Fprintf(out, "Server started in background on http://%s:%d (PID %d)\n", state.Host, state.Port, state.PID)
+			fmt.Fprintf(out, "Web UI available at http://%s:%d\n", state.Host, state.Port)
+			fmt.Fprintf(out, "Logs: %s\n", ui.Muted(logPath))
 			return
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
-	fmt.Printf("%s Server may have failed to start. Check logs: %s\n", ui.ErrorMsg("Warning:"), logPath)
+	fmt.Fprintf(out, "%s Server may have failed to start. Check logs: %s\n", ui.ErrorMsg("Warning:"), logPath)
 }
 
 // internalServeCmd is the daemon process for background serving.
@@ -331,7 +366,7 @@ var internalServeCmd = &cobra.Command{
 			os.Exit(1)
 		}
 		defer logFile.Close()
-		logs.InitLogger(logFile, false)
+		logs.InitLogger(logFile, verbosity)
 
 		// Load config
 		cfg, err := config.Load()
@@ -351,6 +386,9 @@ var internalServeCmd = &cobra.Command{
 		if serverMaxModels != 0 {
 			proxyCfg.MaxModels = serverMaxModels
 		}
+		if serverInsecure {
+			proxyCfg.Insecure = true
+		}
 
 		// Create and start server (handles orphan cleanup and state persistence internally)
 		server := proxy.NewServer(proxyCfg, cfg)
@@ -381,12 +419,16 @@ func init() {
 	serverStartCmd.Flags().IntVarP(&serverPort, "port", "p", 0, "Server port (default from config)")
 	serverStartCmd.Flags().IntVar(&serverMaxModels, "max-models", 0, "Maximum concurrent models (default from config)")
 	serverStartCmd.Flags().BoolVarP(&serverDetach, "detach", "d", false, "Run server in background")
+	serverStartCmd.Flags().BoolVar(&serverInsecure, "insecure", false, "Allow a non-loopback host without API keys")
+	serverStartCmd.Flags().BoolVarP(&serverQuiet, "quiet", "q", false, "Skip the startup banner (useful under systemd)")
 
 	serverRestartCmd.Flags().StringVarP(&serverHost, "host", "H", "", "Server host (default from config)")
 	serverRestartCmd.Flags().IntVarP(&serverPort, "port", "p", 0, "Server port (default from config)")
 	serverRestartCmd.Flags().IntVar(&serverMaxModels, "max-models", 0, "Maximum concurrent models (default from config)")
+	serverRestartCmd.Flags().BoolVar(&serverInsecure, "insecure", false, "Allow a non-loopback host without API keys")
 
 	internalServeCmd.Flags().StringVar(&serverHost, "host", "", "")
 	internalServeCmd.Flags().IntVar(&serverPort, "port", 0, "")
 	internalServeCmd.Flags().IntVar(&serverMaxModels, "max-models", 0, "")
+	internalServeCmd.Flags().BoolVar(&serverInsecure, "insecure", false, "")
 }
@@ -1,25 +1,27 @@
 package cmd
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/nchapman/lleme/internal/process"
 	"github.com/nchapman/lleme/internal/proxy"
 )
 
 func TestStopServerNotRunning(t *testing.T) {
 	// Use temp directory to isolate from real system state
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	// Use a port that's definitely not in use to avoid finding real servers
 	oldPort := serverPort
 	defer func() { serverPort = oldPort }()
 	serverPort = 59999
 
-	// With a temp HOME and unused port, no server should be found
+	// With a temp LLEME_HOME and unused port, no server should be found
 	stopped, err := stopServer()
 	if err != nil {
 		t.Errorf("stopServer() error = %v, want nil", err)
@@ -29,12 +31,96 @@ func TestStopServerNotRunning(t *testing.T) {
 	}
 }
 
+func TestStopServerCorruptState(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	oldPort := serverPort
+	defer func() { serverPort = oldPort }()
+	serverPort = 59999
+
+	// A state file cut off mid-write falls back to the port, then is cleared
+	path := proxy.ProxyStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"pid": 12`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stopped, err := stopServer()
+	if err != nil {
+		t.Errorf("stopServer() error = %v, want nil", err)
+	}
+	if stopped {
+		t.Error("stopServer() returned true with nothing on the port")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("corrupt state file not cleared: %v", err)
+	}
+}
+
+func TestStopServerByPort(t *testing.T) {
+	fake := func(table *process.Table) {
+		old := snapshotProcesses
+		snapshotProcesses = func() (*process.Table, error) { return table, nil }
+		t.Cleanup(func() { snapshotProcesses = old })
+	}
+
+	t.Run("nothing on the port", func(t *testing.T) {
+		fake(&process.Table{Processes: map[int]process.Process{1: {PID: 1, Name: "init"}}})
+		stopped, err := stopServerByPort(11313)
+		if stopped || err != nil {
+			t.Errorf("stopServerByPort() = %v, %v; want false, nil", stopped, err)
+		}
+	})
+
+	t.Run("another program on the port", func(t *testing.T) {
+		fake(&process.Table{
+			Processes: map[int]process.Process{
+				1:   {PID: 1, Name: "init"},
+				200: {PID: 200, PPID: 1, Name: "python3", Args: []string{"python3", "-m", "http.server", "11313"}},
+				201: {PID: 201, PPID: 200, Name: "python3"},
+			},
+			Listeners: []process.Listener{{Port: 11313, PID: 201}, {Port: 11313, PID: 200}},
+		})
+		stopped, err := stopServerByPort(11313)
+		if stopped || err == nil || !strings.Contains(err.Error(), "PID 200") {
+			t.Errorf("stopServerByPort() = %v, %v; want an error naming PID 200", stopped, err)
+		}
+	})
+
+	t.Run("listing fails", func(t *testing.T) {
+		old := snapshotProcesses
+		snapshotProcesses = func() (*process.Table, error) { return nil, process.ErrUnsupported }
+		t.Cleanup(func() { snapshotProcesses = old })
+		if _, err := stopServerByPort(11313); !errors.Is(err, process.ErrUnsupported) {
+			t.Errorf("stopServerByPort() error = %v, want ErrUnsupported", err)
+		}
+	})
+}
+
+func TestIsLlemeProcess(t *testing.T) {
+	tests := []struct {
+		p    process.Process
+		want bool
+	}{
+		{process.Process{Name: "lleme", Args: []string{"/usr/local/bin/lleme", "server", "start"}}, true},
+		{process.Process{Name: "lleme"}, true}, // Windows, where only the name is known
+		{process.Process{Name: "go", Args: []string{"go", "run", "./cmd/LLeme"}}, true},
+		{process.Process{Name: "python3", Args: []string{"python3", "-m", "http.server"}}, false},
+		{process.Process{}, false},
+	}
+	for _, tt := range tests {
+		if got := isLlemeProcess(tt.p); got != tt.want {
+			t.Errorf("isLlemeProcess(%+v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
 func TestStopServerStaleState(t *testing.T) {
 	// Create temp directory for state file
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	// Save state with a PID that doesn't exist
 	state := &proxy.ProxyState{
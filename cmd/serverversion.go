@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// getServerHealth reads the running server's /health. A 503 still carries
+// its versions, so the status code isn't checked.
+func getServerHealth(proxyURL string) (*proxy.HealthReport, error) {
+	resp, err := proxyHTTPClient(2 * time.Second).Get(proxyURL + "/health")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var report proxy.HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// checkServerVersion makes sure the running server speaks this CLI's API.
+// When it doesn't, it offers to restart the server on this binary and
+// returns the new server's URL.
+func checkServerVersion(cfg *config.Config, proxyURL string) (string, error) {
+	report, err := getServerHealth(proxyURL)
+	if err != nil {
+		logs.Debug("Could not check server version", "error", err)
+		return proxyURL, nil // The health check that follows reports it
+	}
+	if proxy.Compatible(report.APIVersion, report.MinClientAPIVersion) {
+		if report.Version != version.Version {
+			logs.Debug("Server version differs", "server", report.Version, "cli", version.Version)
+		}
+		return proxyURL, nil
+	}
+
+	running := report.Version
+	if running == "" {
+		running = i18n.T("an older version")
+	}
+	fmt.Printf("%s %s\n", ui.Warning("!"), i18n.Tf("The running server is lleme %s, which this lleme %s can't work with reliably.", running, version.Version))
+	if !isInteractive() {
+		fmt.Println(ui.Muted(i18n.T("Restart it with 'lleme server restart'.")))
+		return proxyURL, nil
+	}
+	if !ui.PromptYesNo(i18n.T("Restart the server?"), true) {
+		return proxyURL, nil
+	}
+	if _, err := stopServer(); err != nil {
+		return "", fmt.Errorf("failed to stop server: %w", err)
+	}
+	return ensureProxyRunning(cfg)
+}
+
+// checkBinaryUpgrade notices when lleme has been upgraded since the
+// running server started, and offers to restart it on the new binary with
+// the same models loaded. It reports whether the server was restarted and
+// the URL to use.
+func checkBinaryUpgrade(cfg *config.Config, state *proxy.ProxyState) (string, bool, error) {
+	proxyURL := fmt.Sprintf("http://%s:%d", state.Host, state.Port)
+	if !proxy.BinaryChanged(state, version.Version) {
+		return proxyURL, false, nil
+	}
+
+	running := state.Version
+	if running == "" {
+		running = i18n.T("an older version")
+	}
+	fmt.Printf("%s %s\n", ui.Warning("!"), i18n.Tf("lleme was upgraded to %s, but the server is still running %s.", version.Version, running))
+	if !isInteractive() {
+		fmt.Println(ui.Muted(i18n.T("Restart it with 'lleme server restart'.")))
+		return proxyURL, false, nil
+	}
+	prompt := i18n.T("Restart the server?")
+	if len(state.Backends) > 0 {
+		prompt = i18n.T("Restart the server? Loaded models will be loaded again.")
+	}
+	if !ui.PromptYesNo(prompt, true) {
+		return proxyURL, false, nil
+	}
+
+	if err := proxy.SaveRestoreList(state.Backends); err != nil {
+		logs.Warn("Failed to save models to restore", "error", err)
+	}
+	if _, err := stopServer(); err != nil {
+		return "", false, fmt.Errorf("failed to stop server: %w", err)
+	}
+	proxyURL, err := ensureProxyRunning(cfg)
+	return proxyURL, err == nil, err
+}
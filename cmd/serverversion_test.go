@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/proxy"
+)
+
+func TestCheckServerVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		report proxy.HealthReport
+		status int
+	}{
+		{"same API", proxy.HealthReport{Status: "ok", Version: "9.9.9", APIVersion: proxy.APIVersion, MinClientAPIVersion: proxy.MinClientAPIVersion}, http.StatusOK},
+		{"failing but compatible", proxy.HealthReport{Status: "fail", APIVersion: proxy.APIVersion, MinClientAPIVersion: 1}, http.StatusServiceUnavailable},
+		{"server from before the handshake", proxy.HealthReport{Status: "ok"}, http.StatusOK},
+		{"CLI too old", proxy.HealthReport{Status: "ok", APIVersion: proxy.APIVersion + 1, MinClientAPIVersion: proxy.APIVersion + 1}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				json.NewEncoder(w).Encode(tt.report)
+			}))
+			defer srv.Close()
+
+			report, err := getServerHealth(srv.URL)
+			if err != nil || report.APIVersion != tt.report.APIVersion {
+				t.Fatalf("getServerHealth() = %+v, %v", report, err)
+			}
+
+			// Tests aren't interactive, so a mismatch only warns and the
+			// running server is kept
+			got, err := checkServerVersion(config.DefaultConfig(), srv.URL)
+			if err != nil || got != srv.URL {
+				t.Errorf("checkServerVersion() = %q, %v; want %q", got, err, srv.URL)
+			}
+		})
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	if !proxy.Compatible(proxy.APIVersion, proxy.MinClientAPIVersion) {
+		t.Error("a server on this API isn't compatible")
+	}
+	if proxy.Compatible(0, 0) {
+		t.Error("a server without an API version is compatible")
+	}
+	if proxy.Compatible(proxy.APIVersion+1, proxy.APIVersion+1) {
+		t.Error("a server that needs a newer CLI is compatible")
+	}
+}
+
+func TestCheckBinaryUpgradeNonInteractive(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	state := &proxy.ProxyState{
+		Host:       "127.0.0.1",
+		Port:       11313,
+		Version:    "0.0.1",
+		Executable: &proxy.Executable{Path: filepath.Join(t.TempDir(), "gone")},
+		Backends:   []proxy.BackendState{{ModelName: "u/r:Q4_K_M"}},
+	}
+
+	// The binary is gone, but without a terminal to ask on the running
+	// server is kept
+	got, restarted, err := checkBinaryUpgrade(config.DefaultConfig(), state)
+	if err != nil || restarted || got != "http://127.0.0.1:11313" {
+		t.Errorf("checkBinaryUpgrade() = %q, %v, %v", got, restarted, err)
+	}
+}
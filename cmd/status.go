@@ -20,6 +20,15 @@ var statusCmd = &cobra.Command{
 	Short:   "Show server status and loaded models",
 	GroupID: "model",
 	Run: func(cmd *cobra.Command, args []string) {
+		if remote := remoteURL(); remote != "" {
+			status, err := getProxyStatus(remote)
+			if err != nil {
+				ui.Fatal("Failed to get status from %s: %v", remote, err)
+			}
+			printProxyStatus(remote, 0, status)
+			return
+		}
+
 		state := proxy.GetRunningProxyState()
 		if state == nil {
 			fmt.Println(ui.Muted("Server is not running"))
@@ -29,6 +38,21 @@ var statusCmd = &cobra.Command{
 			return
 		}
 
+		// Offer to move the server onto a newly installed binary first
+		cfg, cfgErr := config.Load()
+		if cfgErr == nil {
+			_, restarted, err := checkBinaryUpgrade(cfg, state)
+			if err != nil {
+				ui.Fatal("Failed to restart server: %v", err)
+			}
+			if restarted {
+				fmt.Println()
+				if state = proxy.GetRunningProxyState(); state == nil {
+					ui.Fatal("Server did not come back after restarting")
+				}
+			}
+		}
+
 		// Get detailed status from proxy API
 		proxyURL := fmt.Sprintf("http://%s:%d", state.Host, state.Port)
 		status, err := getProxyStatus(proxyURL)
@@ -43,45 +67,10 @@ var statusCmd = &cobra.Command{
 			return
 		}
 
-		// Pretty print status
-		fmt.Println(ui.Header("Server Status"))
-		fmt.Printf("  %-12s %s\n", "Address", proxyURL)
-		fmt.Printf("  %-12s %d\n", "PID", state.PID)
-		fmt.Printf("  %-12s %s\n", "Uptime", formatUptime(time.Duration(status.UptimeSeconds)*time.Second))
-		fmt.Printf("  %-12s %d\n", "Max models", status.MaxModels)
-		fmt.Println()
-
-		if len(status.Models) == 0 {
-			fmt.Println(ui.Muted("No models loaded"))
-			fmt.Println()
-			fmt.Println("Use 'lleme run <model>' to load a model")
+		if !printProxyStatus(proxyURL, state.PID, status) {
 			return
 		}
 
-		fmt.Println(ui.Header("Loaded Models"))
-		fmt.Println()
-
-		table := ui.NewTable().
-			AddColumn("MODEL", 0, ui.AlignLeft).
-			AddColumn("PORT", 5, ui.AlignRight).
-			AddColumn("STATUS", 0, ui.AlignLeft).
-			AddColumn("UNLOADS", 7, ui.AlignLeft)
-
-		// Calculate idle timeout in minutes for "unload in" display
-		idleTimeoutMins := 10.0 // default
-		if status.IdleTimeout != "" {
-			if d, err := time.ParseDuration(status.IdleTimeout); err == nil {
-				idleTimeoutMins = d.Minutes()
-			}
-		}
-
-		for _, m := range status.Models {
-			unloadIn := formatUnloadTime(m.IdleMinutes, idleTimeoutMins)
-			table.AddRow(m.ModelName, fmt.Sprintf("%d", m.Port), m.Status, unloadIn)
-		}
-
-		fmt.Print(table.Render())
-
 		// Footer with model count and llama.cpp credit
 		fmt.Println()
 		modelWord := "model"
@@ -96,8 +85,7 @@ var statusCmd = &cobra.Command{
 		}
 
 		// Show peer status if enabled
-		cfg, err := config.Load()
-		if err != nil {
+		if cfgErr != nil {
 			fmt.Println(ui.Muted("Note: unable to load config; skipping peer status"))
 		} else if cfg != nil && cfg.Peer.Enabled {
 			showPeerStatus()
@@ -105,16 +93,83 @@ var statusCmd = &cobra.Command{
 	},
 }
 
+// printProxyStatus prints the server summary and its loaded models, and
+// reports whether any are loaded. A zero pid, for a remote server, is left
+// out.
+func printProxyStatus(proxyURL string, pid int, status *proxy.ProxyStatus) bool {
+	fmt.Println(ui.Header("Server Status"))
+	fmt.Printf("  %-12s %s\n", "Address", proxyURL)
+	if pid != 0 {
+		fmt.Printf("  %-12s %d\n", "PID", pid)
+	}
+	fmt.Printf("  %-12s %s\n", "Uptime", formatUptime(time.Duration(status.UptimeSeconds)*time.Second))
+	fmt.Printf("  %-12s %d\n", "Max models", status.MaxModels)
+	if p := status.Power; p != nil {
+		fmt.Printf("  %-12s %s\n", "Power", powerStatusText(p))
+	}
+	if q := status.QuietHours; q != nil {
+		if q.Active {
+			fmt.Printf("  %-12s %s %s\n", "Quiet hours", q.Schedule, ui.Warning(fmt.Sprintf("(now, until %s)", q.Until.Local().Format("15:04"))))
+		} else {
+			fmt.Printf("  %-12s %s\n", "Quiet hours", q.Schedule)
+		}
+	}
+	fmt.Println()
+
+	if len(status.Models) == 0 {
+		fmt.Println(ui.Muted("No models loaded"))
+		fmt.Println()
+		fmt.Println("Use 'lleme run <model>' to load a model")
+		return false
+	}
+
+	fmt.Println(ui.Header("Loaded Models"))
+	fmt.Println()
+
+	table := ui.NewTable().
+		AddColumn("MODEL", 0, ui.AlignLeft).
+		AddColumn("PORT", 5, ui.AlignRight).
+		AddColumn("STATUS", 0, ui.AlignLeft).
+		AddColumn("UNLOADS", 7, ui.AlignLeft)
+
+	// Calculate idle timeout in minutes for "unload in" display
+	idleTimeoutMins := 10.0 // default
+	if status.IdleTimeout != "" {
+		if d, err := time.ParseDuration(status.IdleTimeout); err == nil {
+			idleTimeoutMins = d.Minutes()
+		}
+	}
+
+	for _, m := range status.Models {
+		unloadIn := formatUnloadTime(m.IdleMinutes, idleTimeoutMins)
+		table.AddRow(m.ModelName, fmt.Sprintf("%d", m.Port), m.Status, unloadIn)
+	}
+
+	fmt.Print(table.Render())
+	return true
+}
+
+// powerStatusText describes the power saver for status: whether it's saving
+// and why
+func powerStatusText(p *proxy.PowerStatus) string {
+	if !p.Saving {
+		return fmt.Sprintf("normal (saver: %s)", p.Saver)
+	}
+	if p.Reason != "" {
+		return ui.Warning("saving") + " (" + p.Reason + ")"
+	}
+	return ui.Warning("saving")
+}
+
 func getProxyStatus(proxyURL string) (*proxy.ProxyStatus, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get(proxyURL + "/api/status")
+	resp, err := proxyHTTPClient(5 * time.Second).Get(proxyURL + "/api/status")
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+		return nil, responseError(resp)
 	}
 
 	var status proxy.ProxyStatus
@@ -192,4 +247,5 @@ func showPeerStatus() {
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
+	addRemoteFlag(statusCmd)
 }
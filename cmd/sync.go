@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/peer"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncPush   bool
+	syncPull   bool
+	syncDryRun bool
+)
+
+var syncCmd = &cobra.Command{
+	Use:     "sync <host[:port]>",
+	Short:   "Sync downloaded models with another machine",
+	GroupID: "model",
+	Long: `Compare the model store with another lleme machine and transfer only the
+models that are missing or changed, by file hash. Files the other side
+already has are never sent again.
+
+By default models go both ways, and a model that differs goes from the
+side that pulled it most recently. The other machine must be running
+'lleme server' with peer.enabled and peer.allow_sync set.
+
+Examples:
+  lleme sync 192.168.1.20             # Two-way sync
+  lleme sync workstation.lan --pull   # Only fetch models from it
+  lleme sync 192.168.1.20 --push      # Only send models to it
+  lleme sync 192.168.1.20 --dry-run   # Show the plan without transferring`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if syncPush && syncPull {
+			ui.Fatal("--push and --pull can't be combined (omit both for a two-way sync)")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			ui.Fatal("Failed to load config: %v", err)
+		}
+
+		remote, err := parsePeerAddr(args[0], cfg.Peer.Port)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		client := peer.NewClient(remote)
+
+		remoteModels, err := client.SyncModels()
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		localModels, err := peer.LocalSyncModels()
+		if err != nil {
+			ui.Fatal("Failed to read local models: %v", err)
+		}
+
+		mode := peer.SyncBoth
+		if syncPush {
+			mode = peer.SyncPush
+		} else if syncPull {
+			mode = peer.SyncPull
+		}
+		plan := peer.PlanSync(localModels, remoteModels, mode)
+
+		printSyncPlan(plan, remote)
+		if syncDryRun || len(plan.Actions) == 0 {
+			return
+		}
+		fmt.Println()
+
+		source := peer.NewSource(client, remoteModels)
+		pulled := false
+		var failed int
+		for _, action := range plan.Actions {
+			m := action.Model
+			if action.Push {
+				err = pushWithProgress(client, m)
+			} else {
+				fmt.Printf("Pulling %s (%s)\n", ui.Keyword(m.Name()), ui.FormatBytes(m.Size()))
+				quant := hf.Quantization{Name: m.Quant, Tag: m.Quant}
+				_, err = hf.PullModelWithProgressFactory(source, m.User, m.Repo, quant, nil, newProgressBar)
+				pulled = pulled || err == nil
+			}
+			if err != nil {
+				ui.PrintError("%s: %v", m.Name(), err)
+				failed++
+			}
+		}
+
+		if pulled {
+			if err := peer.RebuildPeerFileIndex(); err != nil {
+				ui.PrintError("Failed to update peer index: %v", err)
+			}
+		}
+
+		if failed > 0 {
+			ui.Fatal("%d of %d transfers failed", failed, len(plan.Actions))
+		}
+		fmt.Printf("Synced %d model(s) with %s\n", len(plan.Actions), remote.Host)
+	},
+}
+
+// parsePeerAddr parses host or host:port, using defaultPort when none is given.
+func parsePeerAddr(addr string, defaultPort int) (*peer.Peer, error) {
+	if defaultPort == 0 {
+		defaultPort = config.DefaultConfig().Peer.Port
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return &peer.Peer{Host: addr, Port: defaultPort}, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("invalid port in %s", addr)
+	}
+	return &peer.Peer{Host: host, Port: port}, nil
+}
+
+func printSyncPlan(plan peer.SyncPlan, remote *peer.Peer) {
+	if len(plan.Actions) == 0 {
+		fmt.Printf("Already in sync with %s\n", remote.Host)
+	}
+
+	var pushSize, pullSize int64
+	var pushes, pulls int
+	for _, action := range plan.Actions {
+		size := action.Model.Size()
+		arrow, verb := ui.Keyword("↑"), "push"
+		if action.Push {
+			pushes++
+			pushSize += size
+		} else {
+			arrow, verb = ui.Keyword("↓"), "pull"
+			pulls++
+			pullSize += size
+		}
+		fmt.Printf("  %s %s  %s  %s\n", arrow, verb, action.Model.Name(),
+			ui.Muted(fmt.Sprintf("%s, %s", ui.FormatBytes(size), action.Reason)))
+	}
+
+	for _, name := range plan.Skipped {
+		fmt.Printf("  %s skip  %s  %s\n", ui.Muted("-"), name, ui.Muted("no SHA256 to transfer by"))
+	}
+
+	if len(plan.Actions) > 0 {
+		fmt.Println()
+		fmt.Printf("%d to push (%s), %d to pull (%s)\n", pushes, ui.FormatBytes(pushSize), pulls, ui.FormatBytes(pullSize))
+	}
+}
+
+func pushWithProgress(client *peer.Client, m peer.SyncModel) error {
+	bar := ui.NewProgressBar()
+	bar.Start(fmt.Sprintf("Pushing %s", ui.Keyword(m.Name())), m.Size())
+	err := client.PushModel(m, bar.Update)
+	if err != nil {
+		bar.Stop()
+		return err
+	}
+	bar.Finish(fmt.Sprintf("Pushed %s", m.Name()))
+	return nil
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncPush, "push", false, "Only send models to the other machine")
+	syncCmd.Flags().BoolVar(&syncPull, "pull", false, "Only fetch models from the other machine")
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Show what would be transferred without doing it")
+	rootCmd.AddCommand(syncCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var tagRemove bool
+
+var tagCmd = &cobra.Command{
+	Use:     "tag <model> [tags]",
+	Short:   "Tag a downloaded model",
+	GroupID: "model",
+	Long: `Add comma-separated tags to a downloaded model, or show its tags.
+Tags apply to every quantization of the model.
+
+Examples:
+  lleme tag llama work,coding          # Add tags
+  lleme tag llama --remove work        # Remove a tag
+  lleme tag llama                      # Show tags
+  lleme list --tag work                # List models with a tag`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		user, repo, err := resolveDownloadedRepo(args[0])
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		meta, err := hf.LoadMetadata(user, repo)
+		if err != nil {
+			ui.Fatal("Failed to load metadata: %v", err)
+		}
+
+		if len(args) == 1 {
+			if len(meta.Tags) == 0 {
+				fmt.Println(ui.Muted("No tags"))
+				return
+			}
+			fmt.Println(strings.Join(meta.Tags, ", "))
+			return
+		}
+
+		tags := parseTags(args[1])
+		if tagRemove {
+			tags = slices.DeleteFunc(slices.Clone(meta.Tags), func(t string) bool {
+				return slices.Contains(tags, t)
+			})
+		} else {
+			tags = parseTags(strings.Join(append(meta.Tags, tags...), ","))
+		}
+
+		if err := hf.SetTags(user, repo, tags); err != nil {
+			ui.Fatal("Failed to save tags: %v", err)
+		}
+
+		if len(tags) == 0 {
+			fmt.Printf("Removed all tags from %s/%s\n", user, repo)
+		} else {
+			fmt.Printf("Tagged %s/%s: %s\n", user, repo, strings.Join(tags, ", "))
+		}
+	},
+}
+
+// parseTags splits a comma-separated list into sorted, lowercase, unique tags.
+func parseTags(s string) []string {
+	var tags []string
+	for tag := range strings.SplitSeq(s, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// resolveDownloadedRepo finds the user/repo of a downloaded model by name.
+func resolveDownloadedRepo(query string) (user, repo string, err error) {
+	result, err := proxy.NewModelResolver().Resolve(query)
+	if err != nil {
+		return "", "", err
+	}
+	if result.Model != nil {
+		return result.Model.User, result.Model.Repo, nil
+	}
+	if len(result.Matches) > 1 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "'%s' matches multiple models:\n\n", query)
+		for _, m := range result.Matches {
+			fmt.Fprintf(&b, "  %s/%s\n", m.User, m.Repo)
+		}
+		b.WriteString("\nSpecify the full model name to continue")
+		return "", "", fmt.Errorf("%s", b.String())
+	}
+	return "", "", modelNotFoundError(query, result.Suggestions)
+}
+
+func init() {
+	tagCmd.Flags().BoolVarP(&tagRemove, "remove", "r", false, "Remove the given tags instead of adding them")
+	rootCmd.AddCommand(tagCmd)
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/telemetry"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/nchapman/lleme/internal/version"
+	"github.com/spf13/cobra"
+)
+
+var telemetryExport string
+
+var telemetryCmd = &cobra.Command{
+	Use:     "telemetry",
+	Short:   "Show or change anonymous usage counting",
+	GroupID: "config",
+	Long: `Show or change anonymous usage counting. It is off until you enable it.
+
+When enabled, each command run is counted by name, along with the platform
+and the lleme and llama.cpp versions. Arguments, model names, prompts and
+paths are never recorded. The counts stay in a JSON file on this machine;
+lleme never sends them anywhere. To gather counts from several machines,
+set an export directory, such as a shared drive, and each machine writes
+its counts there as <id>.json.
+
+Examples:
+  lleme telemetry status
+  lleme telemetry enable --export /mnt/shared/lleme-usage
+  lleme telemetry export usage.json
+  lleme telemetry disable`,
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether usage is counted, and the counts so far",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			ui.Fatal("Failed to load config: %v", err)
+		}
+		report, err := telemetry.Load()
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+
+		state := ui.Muted("disabled")
+		if cfg.Telemetry.Enabled {
+			state = ui.Success("enabled")
+		}
+		fmt.Printf("%s %s (local only, never sent)\n", ui.Muted("Telemetry:"), state)
+		fmt.Printf("%s %s\n", ui.Muted("Kept in:"), telemetry.Path())
+		if cfg.Telemetry.Export != "" {
+			fmt.Printf("%s %s\n", ui.Muted("Exported to:"), cfg.Telemetry.Export)
+		}
+
+		if report == nil || len(report.Commands) == 0 {
+			fmt.Println()
+			fmt.Println(ui.Muted("Nothing counted yet"))
+			return
+		}
+		fmt.Printf("%s %s\n", ui.Muted("ID:"), report.ID)
+		fmt.Printf("%s %s\n", ui.Muted("Since:"), report.Since.Local().Format("2006-01-02"))
+		fmt.Println()
+
+		table := ui.NewTable().
+			AddColumn("COMMAND", 0, ui.AlignLeft).
+			AddColumn("RUNS", 0, ui.AlignRight)
+		for _, name := range commandsByRuns(report.Commands) {
+			table.AddRow(name, strconv.Itoa(report.Commands[name]))
+		}
+		fmt.Print(table.Render())
+	},
+}
+
+var telemetryEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Start counting usage on this machine",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setTelemetry(cmd, true)
+		fmt.Printf("%s Counting usage in %s\n", ui.Success("✓"), telemetry.Path())
+		fmt.Println(ui.Muted("Nothing is sent anywhere. See what's recorded with: lleme telemetry status"))
+	},
+}
+
+var telemetryDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop counting usage",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		setTelemetry(cmd, false)
+		fmt.Printf("%s Stopped counting usage\n", ui.Success("✓"))
+		fmt.Println(ui.Muted("Delete the counts so far with: lleme telemetry reset"))
+	},
+}
+
+var telemetryExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the counts as JSON to a file or stdout",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := telemetry.Load()
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		if report == nil {
+			ui.Fatal("Nothing counted yet; start with: lleme telemetry enable")
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		data = append(data, '\n')
+
+		if len(args) == 0 {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := fileutil.AtomicWriteFile(args[0], data, 0644); err != nil {
+			ui.Fatal("Failed to write %s: %v", args[0], err)
+		}
+		fmt.Printf("%s Wrote %s\n", ui.Success("✓"), args[0])
+	},
+}
+
+var telemetryResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the counts so far",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := telemetry.Reset(); err != nil {
+			ui.Fatal("%v", err)
+		}
+		fmt.Printf("%s Deleted %s\n", ui.Success("✓"), telemetry.Path())
+	},
+}
+
+// setTelemetry turns counting on or off in config, setting the export
+// directory too when --export is given
+func setTelemetry(cmd *cobra.Command, enabled bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		ui.Fatal("Failed to load config: %v", err)
+	}
+	cfg.Telemetry.Enabled = enabled
+	if cmd.Flags().Changed("export") {
+		cfg.Telemetry.Export = telemetryExport
+	}
+	if err := config.Save(cfg); err != nil {
+		ui.Fatal("Failed to save config: %v", err)
+	}
+}
+
+// recordUsage counts cmd when telemetry is enabled. Counting must never get
+// in the way, so failures are only logged.
+func recordUsage(cmd *cobra.Command) {
+	if cmd == telemetryCmd || cmd.Parent() == telemetryCmd {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil || !cfg.Telemetry.Enabled {
+		return
+	}
+
+	name := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	var llamaCpp string
+	if installed, _ := llama.GetInstalledVersion(); installed != nil {
+		llamaCpp = installed.TagName
+	}
+	var sinks []telemetry.Sink
+	if cfg.Telemetry.Export != "" {
+		sinks = append(sinks, telemetry.DirSink(cfg.Telemetry.Export))
+	}
+	if err := telemetry.Record(name, version.Version, llamaCpp, sinks...); err != nil {
+		logs.Debug("Failed to record usage", "error", err)
+	}
+}
+
+// commandsByRuns returns command names, most run first
+func commandsByRuns(counts map[string]int) []string {
+	return slices.SortedFunc(maps.Keys(counts), func(a, b string) int {
+		return cmp.Or(cmp.Compare(counts[b], counts[a]), strings.Compare(a, b))
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(telemetryCmd)
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+	telemetryCmd.AddCommand(telemetryEnableCmd)
+	telemetryCmd.AddCommand(telemetryDisableCmd)
+	telemetryCmd.AddCommand(telemetryExportCmd)
+	telemetryCmd.AddCommand(telemetryResetCmd)
+
+	telemetryEnableCmd.Flags().StringVar(&telemetryExport, "export", "", "Also write the counts as JSON into this directory (empty to stop)")
+}
@@ -9,4 +9,6 @@ type ModelInfo struct {
 	Quant    string
 	Size     int64
 	LastUsed time.Time
+	Tags     []string
+	Note     string
 }
@@ -25,14 +25,16 @@ Examples:
   lleme unload bartowski/Llama-3.2-3B-Instruct-GGUF     # Unload by full name
   lleme unload --all                                    # Unload all models`,
 	Run: func(cmd *cobra.Command, args []string) {
-		state := proxy.GetRunningProxyState()
-		if state == nil {
-			fmt.Println(ui.Muted("Server is not running"))
-			return
+		proxyURL := remoteURL()
+		if proxyURL == "" {
+			state := proxy.GetRunningProxyState()
+			if state == nil {
+				fmt.Println(ui.Muted("Server is not running"))
+				return
+			}
+			proxyURL = fmt.Sprintf("http://%s:%d", state.Host, state.Port)
 		}
 
-		proxyURL := fmt.Sprintf("http://%s:%d", state.Host, state.Port)
-
 		if unloadAll {
 			unloadAllModels(proxyURL)
 			return
@@ -56,7 +58,7 @@ Examples:
 }
 
 func unloadModel(proxyURL, modelName string) {
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := proxyHTTPClient(30 * time.Second)
 
 	reqBody, err := json.Marshal(map[string]string{"model": modelName})
 	if err != nil {
@@ -73,14 +75,14 @@ func unloadModel(proxyURL, modelName string) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		ui.Fatal("Failed to unload model: HTTP %d", resp.StatusCode)
+		ui.Fatal("Failed to unload model: %v", responseError(resp))
 	}
 
 	fmt.Printf("Unloaded %s\n", modelName)
 }
 
 func unloadAllModels(proxyURL string) {
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := proxyHTTPClient(30 * time.Second)
 
 	resp, err := client.Post(proxyURL+"/api/stop-all", "application/json", nil)
 	if err != nil {
@@ -89,7 +91,7 @@ func unloadAllModels(proxyURL string) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		ui.Fatal("Failed to unload models: HTTP %d", resp.StatusCode)
+		ui.Fatal("Failed to unload models: %v", responseError(resp))
 	}
 
 	var result struct {
@@ -113,4 +115,5 @@ func init() {
 	rootCmd.AddCommand(unloadCmd)
 
 	unloadCmd.Flags().BoolVarP(&unloadAll, "all", "a", false, "Unload all loaded models")
+	addRemoteFlag(unloadCmd)
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/nchapman/lleme/internal/i18n"
 	"github.com/nchapman/lleme/internal/llama"
 	"github.com/nchapman/lleme/internal/proxy"
 	"github.com/nchapman/lleme/internal/selfupdate"
@@ -23,25 +25,47 @@ var updateLlamaCmd = &cobra.Command{
 	Run:   runUpdateLlama,
 }
 
+var updateSDCmd = &cobra.Command{
+	Use:     "stable-diffusion",
+	Aliases: []string{"sd"},
+	Short:   "Install or update stable-diffusion.cpp for image generation",
+	Long: `Install or update stable-diffusion.cpp, which serves /v1/images/generations.
+It isn't installed by default because only image generation needs it.`,
+	Run: runUpdateSD,
+}
+
 var updateSelfCmd = &cobra.Command{
 	Use:   "self",
 	Short: "Update lleme to the latest version",
 	Run:   runUpdateSelf,
 }
 
-var forceUpdate bool
+var (
+	forceUpdate  bool
+	checkUpdates bool
+)
+
+// maxChangelogLines is how many notable llama.cpp changes --check lists
+const maxChangelogLines = 20
 
 func init() {
 	updateCmd.PersistentFlags().BoolVarP(&forceUpdate, "force", "f", false, "Skip confirmation")
+	for _, c := range []*cobra.Command{updateCmd, updateLlamaCmd} {
+		c.Flags().BoolVar(&checkUpdates, "check", false, "Show what's new in llama.cpp without updating")
+	}
+	updateCmd.PersistentFlags().StringVar(&progressFormat, "progress", progressFormatBar, progressUsage+" (skips confirmation)")
 
 	rootCmd.AddCommand(updateCmd)
 	updateCmd.AddCommand(updateLlamaCmd)
+	updateCmd.AddCommand(updateSDCmd)
 	updateCmd.AddCommand(updateSelfCmd)
 }
 
 func runUpdateAll(cmd *cobra.Command, args []string) {
-	fmt.Println("Checking for updates...")
-	fmt.Println()
+	initProgress("update", "")
+	out := humanOut()
+	fmt.Fprintln(out, "Checking for updates...")
+	fmt.Fprintln(out)
 
 	// Check lleme version
 	llemeInstalled := selfupdate.GetInstalledVersion()
@@ -66,34 +90,42 @@ func runUpdateAll(cmd *cobra.Command, args []string) {
 		(llamaInstalled == nil || llamaInstalled.TagName != llamaRelease.TagName)
 
 	// Display status
-	fmt.Println("  lleme:")
-	fmt.Printf("    %-12s %s\n", "Installed", llemeInstalled)
+	fmt.Fprintln(out, "  lleme:")
+	fmt.Fprintf(out, "    %-12s %s\n", "Installed", llemeInstalled)
 	if llemeErr != nil {
-		fmt.Printf("    %-12s %s\n", "Available", ui.Muted("Failed to check"))
+		fmt.Fprintf(out, "    %-12s %s\n", "Available", ui.Muted("Failed to check"))
 	} else if llemeNeedsUpdate {
-		fmt.Printf("    %-12s %s\n", "Available", llemeLatest)
+		fmt.Fprintf(out, "    %-12s %s\n", "Available", llemeLatest)
 	} else {
-		fmt.Printf("    %-12s %s %s\n", "Available", llemeLatest, ui.Success(ui.IconCheck))
+		fmt.Fprintf(out, "    %-12s %s %s\n", "Available", llemeLatest, ui.Success(ui.IconCheck))
 	}
-	fmt.Println()
+	fmt.Fprintln(out)
 
-	fmt.Println("  llama.cpp:")
-	fmt.Printf("    %-12s %s\n", "Installed", llamaInstalledStr)
+	fmt.Fprintln(out, "  llama.cpp:")
+	fmt.Fprintf(out, "    %-12s %s\n", "Installed", llamaInstalledStr)
 	if llamaFetchErr != nil {
-		fmt.Printf("    %-12s %s\n", "Available", ui.Muted("Failed to check"))
+		fmt.Fprintf(out, "    %-12s %s\n", "Available", ui.Muted("Failed to check"))
 	} else if llamaNeedsUpdate {
-		fmt.Printf("    %-12s %s\n", "Available", llamaLatestStr)
+		fmt.Fprintf(out, "    %-12s %s\n", "Available", llamaLatestStr)
 	} else {
-		fmt.Printf("    %-12s %s %s\n", "Available", llamaLatestStr, ui.Success(ui.IconCheck))
+		fmt.Fprintf(out, "    %-12s %s %s\n", "Available", llamaLatestStr, ui.Success(ui.IconCheck))
 	}
-	fmt.Println()
+	fmt.Fprintln(out)
 
 	if llamaErr != nil {
 		ui.PrintError("Failed to check llama.cpp installed version: %v", llamaErr)
 	}
 
+	if checkUpdates {
+		if llamaNeedsUpdate && llamaInstalled != nil {
+			printLlamaChangelog(llamaInstalled.TagName, llamaLatestStr)
+		}
+		return
+	}
+
 	if !llemeNeedsUpdate && !llamaNeedsUpdate {
-		fmt.Println("Everything is up to date")
+		fmt.Fprintln(out, "Everything is up to date")
+		progressEvents.Done(progressEvent{UpToDate: true})
 		return
 	}
 
@@ -106,19 +138,19 @@ func runUpdateAll(cmd *cobra.Command, args []string) {
 		updates = append(updates, fmt.Sprintf("llama.cpp to %s", llamaLatestStr))
 	}
 
-	if !forceUpdate {
-		prompt := fmt.Sprintf("Update %s?", joinWithAnd(updates))
+	if !forceUpdate && progressEvents == nil {
+		prompt := i18n.Tf("Update %s?", joinWithAnd(updates))
 		if !ui.PromptYesNo(prompt, false) {
-			fmt.Println(ui.Muted("Cancelled"))
+			fmt.Fprintln(out, ui.Muted(i18n.T("Cancelled")))
 			return
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(out)
 
 	// Update lleme if needed
 	if llemeNeedsUpdate {
-		updateLleme(selfupdate.DetectInstallMethod())
-		fmt.Println()
+		updateLleme(selfupdate.DetectInstallMethod(), llemeLatest)
+		fmt.Fprintln(out)
 	}
 
 	// Update llama.cpp if needed
@@ -130,17 +162,19 @@ func runUpdateAll(cmd *cobra.Command, args []string) {
 }
 
 func runUpdateLlama(cmd *cobra.Command, args []string) {
-	fmt.Println("Checking for llama.cpp updates...")
-	fmt.Println()
+	initProgress("update", "llama.cpp")
+	out := humanOut()
+	fmt.Fprintln(out, "Checking for llama.cpp updates...")
+	fmt.Fprintln(out)
 
 	installed, err := llama.GetInstalledVersion()
 	if err != nil {
-		ui.Fatal("Failed to check installed version: %v", err)
+		progressFatal("Failed to check installed version: %v", err)
 	}
 
 	release, err := llama.GetLatestVersion()
 	if err != nil {
-		ui.Fatal("Failed to get latest release: %v", err)
+		progressFatal("Failed to get latest release: %v", err)
 	}
 
 	currentVersion := "Not installed"
@@ -148,83 +182,221 @@ func runUpdateLlama(cmd *cobra.Command, args []string) {
 		currentVersion = installed.TagName
 	}
 
-	fmt.Printf("  %-12s %s\n", "Installed", currentVersion)
-	fmt.Printf("  %-12s %s\n", "Available", release.TagName)
-	fmt.Println()
+	fmt.Fprintf(out, "  %-12s %s\n", "Installed", currentVersion)
+	fmt.Fprintf(out, "  %-12s %s\n", "Available", release.TagName)
+	fmt.Fprintln(out)
 
+	// The latest release is reinstalled when its files no longer match
+	reinstall := false
 	if installed != nil && installed.TagName == release.TagName {
-		fmt.Println("llama.cpp is already up to date")
+		problems, err := llama.VerifyInstalled()
+		switch {
+		case errors.Is(err, llama.ErrNoChecksums):
+			fmt.Fprintln(out, "No checksums were recorded for the installed llama.cpp")
+			reinstall = true
+		case err == nil && len(problems) > 0:
+			fmt.Fprintln(out, ui.Warning(fmt.Sprintf("%d installed llama.cpp files changed or are missing", len(problems))))
+			reinstall = true
+		default:
+			fmt.Fprintln(out, "llama.cpp is already up to date")
+			progressEvents.Done(progressEvent{Version: release.TagName, UpToDate: true})
+			return
+		}
+	}
+
+	if checkUpdates {
+		if installed != nil && !reinstall {
+			printLlamaChangelog(installed.TagName, release.TagName)
+		}
 		return
 	}
 
-	if !forceUpdate {
-		if !ui.PromptYesNo(fmt.Sprintf("Update to %s?", release.TagName), false) {
-			fmt.Println(ui.Muted("Cancelled"))
+	if !forceUpdate && progressEvents == nil {
+		prompt := i18n.Tf("Update to %s?", release.TagName)
+		if reinstall {
+			prompt = i18n.Tf("Reinstall %s?", release.TagName)
+		}
+		if !ui.PromptYesNo(prompt, false) {
+			fmt.Fprintln(out, ui.Muted(i18n.T("Cancelled")))
 			return
 		}
 	}
 
-	fmt.Println()
+	fmt.Fprintln(out)
 	updateLlamaCpp()
 	restartServerIfRunning()
 }
 
+// printLlamaChangelog summarizes the server and model support changes
+// between two llama.cpp releases, and what updating would interrupt
+func printLlamaChangelog(installed, latest string) {
+	out := humanOut()
+	changelog, err := llama.GetChangelog(installed)
+	if err != nil {
+		ui.PrintError("Failed to get llama.cpp release notes: %v", err)
+		return
+	}
+
+	count := fmt.Sprintf("%d releases", changelog.Releases)
+	if !changelog.Complete {
+		count = fmt.Sprintf("more than %d releases", changelog.Releases)
+	}
+	fmt.Fprintln(out, ui.Header(fmt.Sprintf("llama.cpp %s → %s (%s)", installed, latest, count)))
+	if len(changelog.Notable) == 0 {
+		fmt.Fprintln(out, ui.Muted("  No server or model support changes"))
+	}
+	for i, change := range changelog.Notable {
+		if i == maxChangelogLines {
+			fmt.Fprintln(out, ui.Muted(fmt.Sprintf("  ...and %d more", len(changelog.Notable)-i)))
+			break
+		}
+		fmt.Fprintf(out, "  %-7s %s\n", change.Tag, change.Text)
+	}
+	fmt.Fprintln(out)
+
+	if state := proxy.GetRunningProxyState(); state != nil {
+		status, err := getProxyStatus(fmt.Sprintf("http://%s:%d", state.Host, state.Port))
+		if err == nil && len(status.Models) > 0 {
+			names := make([]string, len(status.Models))
+			for i, m := range status.Models {
+				names[i] = m.ModelName
+			}
+			fmt.Fprintf(out, "Updating restarts the server, unloading %s\n", joinWithAnd(names))
+		}
+	}
+	fmt.Fprintln(out, "Run 'lleme update llama.cpp' to update")
+}
+
+func runUpdateSD(cmd *cobra.Command, args []string) {
+	initProgress("update", "stable-diffusion.cpp")
+	out := humanOut()
+	fmt.Fprintln(out, "Checking for stable-diffusion.cpp updates...")
+	fmt.Fprintln(out)
+
+	installed, err := llama.GetInstalledSDVersion()
+	if err != nil {
+		progressFatal("Failed to check installed version: %v", err)
+	}
+
+	release, err := llama.GetLatestSDVersion()
+	if err != nil {
+		progressFatal("Failed to get latest release: %v", err)
+	}
+
+	currentVersion := "Not installed"
+	if installed != nil {
+		currentVersion = installed.TagName
+	}
+
+	fmt.Fprintf(out, "  %-12s %s\n", "Installed", currentVersion)
+	fmt.Fprintf(out, "  %-12s %s\n", "Available", release.TagName)
+	fmt.Fprintln(out)
+
+	if installed != nil && installed.TagName == release.TagName && llama.IsSDInstalled() {
+		fmt.Fprintln(out, "stable-diffusion.cpp is already up to date")
+		progressEvents.Done(progressEvent{Version: release.TagName, UpToDate: true})
+		return
+	}
+
+	if !forceUpdate && progressEvents == nil {
+		prompt := i18n.Tf("Update %s?", release.TagName)
+		if installed == nil {
+			prompt = i18n.Tf("Install %s?", release.TagName)
+		}
+		if !ui.PromptYesNo(prompt, false) {
+			fmt.Fprintln(out, ui.Muted(i18n.T("Cancelled")))
+			return
+		}
+	}
+
+	fmt.Fprintln(out)
+	progressEvents.Start("stable-diffusion.cpp", "", 0)
+	version, err := llama.InstallLatestSDWithProgress(installStatus, installProgress)
+	if err != nil {
+		progressFatal("Failed to install stable-diffusion.cpp: %v", err)
+	}
+	fmt.Fprintf(out, "Installed stable-diffusion.cpp %s\n", version.TagName)
+	progressEvents.Done(progressEvent{Version: version.TagName, Path: version.BinaryPath})
+}
+
 func runUpdateSelf(cmd *cobra.Command, args []string) {
-	fmt.Println("Checking for lleme updates...")
-	fmt.Println()
+	initProgress("update", "lleme")
+	out := humanOut()
+	fmt.Fprintln(out, "Checking for lleme updates...")
+	fmt.Fprintln(out)
 
 	installed := selfupdate.GetInstalledVersion()
 	latest, err := selfupdate.GetLatestVersion()
 	if err != nil {
-		ui.Fatal("Failed to get latest release: %v", err)
+		progressFatal("Failed to get latest release: %v", err)
 	}
 
-	fmt.Printf("  %-12s %s\n", "Installed", installed)
-	fmt.Printf("  %-12s %s\n", "Available", latest)
-	fmt.Println()
+	fmt.Fprintf(out, "  %-12s %s\n", "Installed", installed)
+	fmt.Fprintf(out, "  %-12s %s\n", "Available", latest)
+	fmt.Fprintln(out)
 
 	if installed == latest {
-		fmt.Println("lleme is already up to date")
+		fmt.Fprintln(out, "lleme is already up to date")
+		progressEvents.Done(progressEvent{Version: latest, UpToDate: true})
 		return
 	}
 
 	method := selfupdate.DetectInstallMethod()
 	if method == selfupdate.InstallUnknown {
-		fmt.Println(selfupdate.ManualUpdateInstructions())
+		fmt.Fprintln(out, selfupdate.ManualUpdateInstructions())
+		progressEvents.Error("could not detect how lleme was installed")
 		return
 	}
 
-	if !forceUpdate {
-		if !ui.PromptYesNo(fmt.Sprintf("Update to %s?", latest), false) {
-			fmt.Println(ui.Muted("Cancelled"))
+	if !forceUpdate && progressEvents == nil {
+		if !ui.PromptYesNo(i18n.Tf("Update to %s?", latest), false) {
+			fmt.Fprintln(out, ui.Muted(i18n.T("Cancelled")))
 			return
 		}
 	}
 
-	fmt.Println()
-	updateLleme(method)
+	fmt.Fprintln(out)
+	updateLleme(method, latest)
 	restartServerIfRunning()
 }
 
-func updateLleme(method selfupdate.InstallMethod) {
+func updateLleme(method selfupdate.InstallMethod, latest string) {
+	out := humanOut()
+	progressEvents.Start("lleme", "", 0)
 	if method == selfupdate.InstallUnknown {
-		fmt.Println(selfupdate.ManualUpdateInstructions())
+		fmt.Fprintln(out, selfupdate.ManualUpdateInstructions())
+		progressEvents.Error("could not detect how lleme was installed")
 		return
 	}
 
-	fmt.Println("Updating lleme...")
-	if err := selfupdate.Update(method); err != nil {
-		ui.Fatal("Failed to update lleme: %v", err)
+	installStatus("Updating lleme...")
+	if err := selfupdate.UpdateWithOutput(method, out); err != nil {
+		progressFatal("Failed to update lleme: %v", err)
 	}
-	fmt.Println("lleme updated successfully")
+	fmt.Fprintln(out, "lleme updated successfully")
+	progressEvents.Done(progressEvent{Version: latest})
 }
 
 func updateLlamaCpp() {
-	version, err := llama.InstallLatest(func(msg string) { fmt.Println(msg) })
+	progressEvents.Start("llama.cpp", "", 0)
+	version, err := llama.InstallLatestWithProgress(installStatus, installProgress)
 	if err != nil {
-		ui.Fatal("Failed to install llama.cpp: %v", err)
+		progressFatal("Failed to install llama.cpp: %v", err)
 	}
-	fmt.Printf("Updated to llama.cpp %s\n", version.TagName)
+	fmt.Fprintf(humanOut(), "Updated to llama.cpp %s\n", version.TagName)
+	progressEvents.Done(progressEvent{Version: version.TagName, Path: version.BinaryPath})
+}
+
+// installStatus prints an installer step and reports it as a status event
+func installStatus(msg string) {
+	fmt.Fprintln(humanOut(), msg)
+	progressEvents.Status(msg)
+}
+
+// installProgress reports archive download progress under --progress json.
+// Bar mode has always shown only the status lines for these small downloads.
+func installProgress(current, total int64) {
+	progressEvents.Progress("download", current, total)
 }
 
 func joinWithAnd(items []string) string {
@@ -244,16 +416,17 @@ func restartServerIfRunning() {
 	if !proxy.IsProxyRunning() {
 		return
 	}
+	out := humanOut()
 
-	fmt.Println()
-	fmt.Println("Restarting server to apply updates...")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Restarting server to apply updates...")
 	stopped, err := stopServer()
 	if err != nil {
 		ui.PrintError("Failed to stop server: %v", err)
 		return
 	}
 	if stopped {
-		fmt.Println("Stopped server")
+		fmt.Fprintln(out, "Stopped server")
 	}
 	// startServerDetached executes the binary from disk, which is now the updated version
 	startServerDetached()
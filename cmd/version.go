@@ -1,42 +1,104 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"runtime"
 
-	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/ui"
 	"github.com/nchapman/lleme/internal/version"
 	"github.com/spf13/cobra"
 )
 
+var versionJSON bool
+
+// versionReport is the machine-readable output of 'lleme version --json'
+type versionReport struct {
+	version.Info
+	LlamaCpp        *backendVersion   `json:"llama_cpp,omitempty"`
+	StableDiffusion *backendVersion   `json:"stable_diffusion,omitempty"`
+	Paths           map[string]string `json:"paths"`
+}
+
+type backendVersion struct {
+	Build   string `json:"build"`
+	Backend string `json:"backend,omitempty"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:     "version",
 	Short:   "Show version information",
 	GroupID: "config",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("lleme %s (%s/%s)\n", version.Version, runtime.GOOS, runtime.GOARCH)
+		info := version.Get()
+
+		if versionJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(buildVersionReport(info)); err != nil {
+				ui.Fatal("Failed to encode version: %v", err)
+			}
+			return
+		}
+
+		fmt.Printf("lleme %s (%s/%s)\n", info.Version, info.OS, info.Arch)
+		if info.Commit != "" {
+			commit := info.ShortCommit()
+			if info.Modified {
+				commit += "-dirty"
+			}
+			fmt.Println(ui.Muted(fmt.Sprintf("commit %s %s", commit, info.Date)))
+		}
 
 		installed, _ := llama.GetInstalledVersion()
 		if installed != nil {
-			backend := "CPU"
-			if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
-				backend = "Metal"
-			}
-			fmt.Printf("%s (%s)\n", ui.LlamaCppCredit(installed.TagName), backend)
+			fmt.Printf("%s (%s)\n", ui.LlamaCppCredit(installed.TagName), llamaBackend())
 		} else {
 			fmt.Println(ui.Muted("llama.cpp not installed"))
 		}
 
 		fmt.Println()
 		fmt.Println(ui.Header("Paths"))
-		fmt.Printf("  %-10s %s\n", "Models", ui.Muted(config.ModelsPath()))
-		fmt.Printf("  %-10s %s\n", "Binaries", ui.Muted(config.BinPath()))
-		fmt.Printf("  %-10s %s\n", "Config", ui.Muted(config.ConfigPath()))
+		fmt.Printf("  %-10s %s\n", "Models", ui.Muted(paths.Models()))
+		fmt.Printf("  %-10s %s\n", "Binaries", ui.Muted(paths.Bin()))
+		fmt.Printf("  %-10s %s\n", "Config", ui.Muted(paths.Config()))
+		fmt.Printf("  %-10s %s\n", "Cache", ui.Muted(paths.Cache()))
+		fmt.Printf("  %-10s %s\n", "Logs", ui.Muted(paths.Logs()))
 	},
 }
 
+func buildVersionReport(info version.Info) versionReport {
+	report := versionReport{
+		Info: info,
+		Paths: map[string]string{
+			"models":   paths.Models(),
+			"binaries": paths.Bin(),
+			"config":   paths.Config(),
+			"cache":    paths.Cache(),
+			"logs":     paths.Logs(),
+		},
+	}
+	if installed, _ := llama.GetInstalledVersion(); installed != nil {
+		report.LlamaCpp = &backendVersion{Build: installed.TagName, Backend: llamaBackend()}
+	}
+	if sd, _ := llama.GetInstalledSDVersion(); sd != nil {
+		report.StableDiffusion = &backendVersion{Build: sd.TagName}
+	}
+	return report
+}
+
+// llamaBackend names the acceleration the installed llama.cpp build uses
+func llamaBackend() string {
+	if runtime.GOOS == "darwin" && runtime.GOARCH == "arm64" {
+		return "Metal"
+	}
+	return "CPU"
+}
+
 func init() {
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, "Output version information as JSON")
 	rootCmd.AddCommand(versionCmd)
 }
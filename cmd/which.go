@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/proxy"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var whichCmd = &cobra.Command{
+	Use:     "which <model|persona>",
+	Short:   "Show how a model name resolves",
+	GroupID: "model",
+	Long: `Show exactly how run and the server interpret a model name: persona and
+registry hits, which matching rule found it, every candidate, the one
+chosen and why, and where its files are.
+
+Rules are tried in order, and the first with any match wins:
+  1. exact name    user/repo:quant
+  2. user/repo     any quant of user/repo
+  3. repo name     repo or repo:quant
+  4. substring     anywhere in the full name
+When nothing matches, the closest names by edit distance are listed.
+
+Examples:
+  lleme which llama               # Which downloaded model is "llama"?
+  lleme which coding-assistant    # Follow a persona to its model
+  lleme which llama3.2            # Resolve a registry short name`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			ui.Fatal("Failed to load config: %v", err)
+		}
+
+		e, err := explainModel(args[0], cfg)
+		if err != nil {
+			ui.Fatal("%v", err)
+		}
+		printExplanation(e, cfg)
+		if !e.resolves() {
+			os.Exit(1)
+		}
+	},
+}
+
+// modelExplanation records each step of resolving a model name the way
+// run does: persona, downloaded models, registry, then Hugging Face.
+type modelExplanation struct {
+	Query string
+
+	Persona      string // Set when the query names a persona
+	PersonaPath  string
+	PersonaModel string // Empty when the persona leaves the model to the command line
+
+	Lookup string // Name given to the resolver for Result
+	Result *proxy.ResolveResult
+
+	ShortName     string // Registry short name from the query
+	Registry      string // user/repo it maps to
+	RegistryQuant string
+	RegistryNamed bool // Quant came from the query rather than the registry default
+
+	Pull  string // Model run would offer to download
+	Fuzzy []proxy.FuzzyScore
+}
+
+func (e *modelExplanation) resolves() bool {
+	return (e.Result != nil && e.Result.Model != nil) || e.Pull != "" || (e.Persona != "" && e.PersonaModel == "")
+}
+
+func explainModel(query string, cfg *config.Config) (*modelExplanation, error) {
+	e := &modelExplanation{Query: query}
+
+	if config.PersonaExists(query) {
+		persona, err := config.LoadPersona(query)
+		if err != nil {
+			return nil, err
+		}
+		e.Persona = query
+		e.PersonaPath = config.PersonaPath(query)
+		e.PersonaModel = persona.Model
+		if persona.Model == "" {
+			return e, nil
+		}
+		query = persona.Model
+	}
+
+	resolver := proxy.NewModelResolver().PreferQuant(cfg.HuggingFace.DefaultQuant)
+	result, err := resolver.Resolve(query)
+	if err != nil {
+		return nil, err
+	}
+	e.Lookup, e.Result = query, result
+	if result.Model != nil || len(result.Matches) > 0 {
+		return e, nil
+	}
+
+	if _, _, _, err := parseModelRef(query); err == nil {
+		e.Pull = query
+		return e, nil
+	}
+
+	if user, repo, quant, explicit, ok := lookupShortName(query); ok {
+		e.ShortName, e.Registry, e.RegistryQuant, e.RegistryNamed = query, user+"/"+repo, quant, explicit
+		ref := e.Registry
+		if explicit {
+			ref += ":" + quant
+		}
+		if local, err := resolver.Resolve(ref); err == nil && local.Model != nil {
+			e.Lookup, e.Result = ref, local
+			return e, nil
+		}
+		e.Pull = e.Registry + ":" + quant
+		return e, nil
+	}
+
+	models, err := resolver.ListDownloadedModels()
+	if err != nil {
+		return nil, err
+	}
+	e.Fuzzy = proxy.FuzzyScores(query, models)
+	return e, nil
+}
+
+func printExplanation(e *modelExplanation, cfg *config.Config) {
+	row := func(label, value string) {
+		fmt.Printf("  %-10s %s\n", label, value)
+	}
+
+	row("Query", e.Query)
+	if e.Persona != "" {
+		model := e.PersonaModel
+		if model == "" {
+			model = ui.Muted("(none, taken from the next argument)")
+		}
+		row("Persona", fmt.Sprintf("%s → %s  %s", e.Persona, model, ui.Muted(e.PersonaPath)))
+		if e.PersonaModel == "" {
+			return
+		}
+	}
+	if e.Registry != "" {
+		source := "registry default"
+		if e.RegistryNamed {
+			source = "from the query"
+		}
+		row("Registry", fmt.Sprintf("%s → %s  %s", e.ShortName, e.Registry, ui.Muted(fmt.Sprintf("(quant %s, %s)", e.RegistryQuant, source))))
+	}
+
+	if e.Result != nil && len(e.Result.Matches) > 0 {
+		row("Rule", fmt.Sprintf("%s, %d candidate(s) for %q", e.Result.Rule, len(e.Result.Matches), e.Lookup))
+		for _, m := range e.Result.Matches {
+			if e.Result.Model != nil && m.FullName == e.Result.Model.FullName {
+				fmt.Printf("  %-10s %s %s\n", "", ui.Keyword(m.FullName), ui.Muted("← "+chosenReason(e.Result, cfg)))
+			} else {
+				fmt.Printf("  %-10s %s\n", "", m.FullName)
+			}
+		}
+	}
+
+	switch {
+	case e.Result != nil && e.Result.Model != nil:
+		m := e.Result.Model
+		manifest := hf.GetManifestFilePath(m.User, m.Repo, m.Quant)
+		if _, err := os.Stat(manifest); err != nil {
+			manifest += ui.Muted(" (missing)")
+		}
+		fmt.Println()
+		row("Model", ui.Keyword(m.FullName))
+		row("File", m.ModelPath)
+		row("Manifest", manifest)
+	case e.Result != nil && len(e.Result.Matches) > 1:
+		fmt.Println()
+		fmt.Println("Ambiguous: the candidates are from different repos. run asks which one in a terminal.")
+	case e.Pull != "":
+		fmt.Println()
+		row("Model", ui.Muted("not downloaded"))
+		fmt.Printf("\nrun would offer to pull %s from Hugging Face\n", ui.Keyword(e.Pull))
+	default:
+		fmt.Println()
+		fmt.Printf("No downloaded model matches %q.\n", e.Query)
+		printFuzzyScores(e.Fuzzy)
+	}
+}
+
+// chosenReason says why Resolve picked its model among the candidates
+func chosenReason(result *proxy.ResolveResult, cfg *config.Config) string {
+	switch {
+	case len(result.Matches) == 1:
+		return "only match"
+	case strings.EqualFold(result.Model.Quant, cfg.HuggingFace.DefaultQuant):
+		return "huggingface.default_quant"
+	default:
+		return fmt.Sprintf("best quant (no %s downloaded)", cfg.HuggingFace.DefaultQuant)
+	}
+}
+
+func printFuzzyScores(scores []proxy.FuzzyScore) {
+	if len(scores) == 0 {
+		fmt.Println(ui.Muted("No models are downloaded."))
+		return
+	}
+
+	fmt.Println()
+	table := ui.NewTable().
+		AddColumn("MODEL", 0, ui.AlignLeft).
+		AddColumn("DISTANCE", 0, ui.AlignRight).
+		AddColumn("", 0, ui.AlignLeft)
+	for i, s := range scores {
+		if i == 5 {
+			break
+		}
+		note := ""
+		if s.Close {
+			note = "suggested"
+		}
+		table.AddRow(s.Model.FullName, fmt.Sprintf("%d", s.Distance), ui.Muted(note))
+	}
+	fmt.Print(table.Render())
+}
+
+func init() {
+	rootCmd.AddCommand(whichCmd)
+}
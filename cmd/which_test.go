@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/proxy"
+)
+
+func TestExplainModel(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+
+	dir := filepath.Join(paths.Models(), "bartowski", "Llama-3.2-1B-Instruct-GGUF")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Q4_K_M", "Q8_0"} {
+		if err := createTestFile(filepath.Join(dir, name+".gguf"), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := config.SavePersona("helper", &config.Persona{Model: "llama-3.2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SavePersona("blank", &config.Persona{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantModel string
+		wantRule  proxy.MatchRule
+		wantPull  string
+		resolves  bool
+	}{
+		{"substring picks default quant", "llama-3.2", "bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M", proxy.MatchContains, "", true},
+		{"exact name", "bartowski/Llama-3.2-1B-Instruct-GGUF:Q8_0", "bartowski/Llama-3.2-1B-Instruct-GGUF:Q8_0", proxy.MatchExact, "", true},
+		{"persona follows its model", "helper", "bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M", proxy.MatchContains, "", true},
+		{"persona without a model", "blank", "", "", "", true},
+		{"registry short name found locally", "llama3.2:1b", "bartowski/Llama-3.2-1B-Instruct-GGUF:Q4_K_M", proxy.MatchRepo, "", true},
+		{"hugging face ref not downloaded", "someone/other-GGUF:Q5_K_M", "", "", "someone/other-GGUF:Q5_K_M", true},
+		{"unknown name", "zzzz", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := explainModel(tt.query, cfg)
+			if err != nil {
+				t.Fatalf("explainModel() error = %v", err)
+			}
+			if e.resolves() != tt.resolves {
+				t.Errorf("resolves() = %v, want %v", e.resolves(), tt.resolves)
+			}
+			model := ""
+			if e.Result != nil && e.Result.Model != nil {
+				model = e.Result.Model.FullName
+			}
+			if model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			if tt.wantModel != "" && e.Result.Rule != tt.wantRule {
+				t.Errorf("rule = %q, want %q", e.Result.Rule, tt.wantRule)
+			}
+			if e.Pull != tt.wantPull {
+				t.Errorf("pull = %q, want %q", e.Pull, tt.wantPull)
+			}
+		})
+	}
+
+	e, _ := explainModel("zzzz", cfg)
+	if len(e.Fuzzy) != 2 {
+		t.Errorf("unknown name should score every model, got %d", len(e.Fuzzy))
+	}
+
+	e, _ = explainModel("llama3.2:1b", cfg)
+	if e.ShortName != "llama3.2:1b" || !strings.HasPrefix(e.Registry, "bartowski/") || e.RegistryNamed {
+		t.Errorf("registry = %q → %q (named %v)", e.ShortName, e.Registry, e.RegistryNamed)
+	}
+}
@@ -0,0 +1,273 @@
+// Package backup archives lleme's settings and model metadata into a single
+// tarball for moving to another machine. Model weights are left out; they
+// can be pulled again on the new machine.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// infoName is the first entry in every backup and describes it
+const infoName = "lleme-backup.json"
+
+// formatVersion is bumped when the archive layout changes incompatibly
+const formatVersion = 1
+
+// maxFileSize bounds each restored file so a bad archive can't fill the disk
+const maxFileSize = 16 << 20
+
+// patterns lists what a backup contains, as paths in the single-directory
+// layout that paths.Resolve maps to where they live. Binaries, caches, and logs are left out along with weights.
+var patterns = []string{
+	"config.yaml",
+	"personas/*.yaml",
+	"prompts/*.md",
+	"models/*/*/metadata.yaml",
+	"models/*/*/*-manifest.json",
+}
+
+// Info describes a backup.
+type Info struct {
+	Version      int       `json:"version"`
+	Created      time.Time `json:"created"`
+	LlemeVersion string    `json:"lleme_version"`
+	Files        []string  `json:"files"`
+	Models       []string  `json:"models,omitempty"` // user/repo:quant for each manifest
+}
+
+// RestoreResult reports what Restore did with each file in a backup.
+type RestoreResult struct {
+	Info      *Info
+	Restored  []string
+	Unchanged []string
+	Skipped   []string // Differ from the local copy, which was kept
+}
+
+// Files returns the files a backup would contain, as slash-separated paths
+// in the single-directory layout (see paths.Resolve).
+func Files() ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(paths.Resolve(pattern))
+		if err != nil {
+			return nil, err
+		}
+		top, _, nested := strings.Cut(pattern, "/")
+		root := paths.Resolve(top)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			if !nested {
+				files = append(files, top)
+				continue
+			}
+			rel, err := filepath.Rel(root, match)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, path.Join(top, filepath.ToSlash(rel)))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Create writes a gzipped tarball of lleme's settings and model metadata
+// to w.
+func Create(w io.Writer) (*Info, error) {
+	files, err := Files()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		Version:      formatVersion,
+		Created:      time.Now().UTC().Truncate(time.Second),
+		LlemeVersion: version.Get().Version,
+		Files:        files,
+		Models:       modelNames(files),
+	}
+	infoData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	if err := writeEntry(tw, infoName, infoData, 0644, info.Created); err != nil {
+		return nil, err
+	}
+	for _, name := range files {
+		src := paths.Resolve(name)
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		stat, err := os.Stat(src)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeEntry(tw, name, data, stat.Mode().Perm(), stat.ModTime()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, mode fs.FileMode, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode),
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Restore extracts a backup into the lleme data directories. Files that exist
+// locally with different contents are kept unless overwrite is set.
+func Restore(r io.Reader, overwrite bool) (*RestoreResult, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a lleme backup: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	result := &RestoreResult{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		if result.Info == nil {
+			if hdr.Name != infoName {
+				return nil, errors.New("not a lleme backup")
+			}
+			info, err := readInfo(tr)
+			if err != nil {
+				return nil, err
+			}
+			result.Info = info
+			continue
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if !allowed(hdr.Name) {
+			return result, fmt.Errorf("unexpected file in backup: %s", hdr.Name)
+		}
+		if hdr.Size > maxFileSize {
+			return result, fmt.Errorf("%s is too large (%d bytes)", hdr.Name, hdr.Size)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxFileSize))
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+
+		dest := paths.Resolve(hdr.Name)
+		if existing, err := os.ReadFile(dest); err == nil {
+			if bytes.Equal(existing, data) {
+				result.Unchanged = append(result.Unchanged, hdr.Name)
+				continue
+			}
+			if !overwrite {
+				result.Skipped = append(result.Skipped, hdr.Name)
+				continue
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return result, err
+		}
+		mode := fs.FileMode(hdr.Mode).Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := fileutil.AtomicWriteFile(dest, data, mode); err != nil {
+			return result, fmt.Errorf("failed to write %s: %w", hdr.Name, err)
+		}
+		result.Restored = append(result.Restored, hdr.Name)
+	}
+
+	if result.Info == nil {
+		return nil, errors.New("not a lleme backup")
+	}
+	return result, nil
+}
+
+func readInfo(r io.Reader) (*Info, error) {
+	var info Info
+	if err := json.NewDecoder(io.LimitReader(r, maxFileSize)).Decode(&info); err != nil {
+		return nil, fmt.Errorf("invalid backup info: %w", err)
+	}
+	if info.Version > formatVersion {
+		return nil, fmt.Errorf("backup format %d is newer than this lleme supports (%d), update lleme first", info.Version, formatVersion)
+	}
+	return &info, nil
+}
+
+// allowed reports whether name is a clean relative path matching one of
+// the backed-up patterns, so an archive can't write anywhere else.
+func allowed(name string) bool {
+	if name != path.Clean(name) || path.IsAbs(name) || strings.Contains(name, "..") {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// modelNames returns user/repo:quant for each manifest in files
+func modelNames(files []string) []string {
+	var models []string
+	for _, name := range files {
+		parts := strings.Split(name, "/")
+		if len(parts) != 4 || parts[0] != "models" || !strings.HasSuffix(parts[3], "-manifest.json") {
+			continue
+		}
+		quant := strings.TrimSuffix(parts[3], "-manifest.json")
+		models = append(models, fmt.Sprintf("%s/%s:%s", parts[1], parts[2], quant))
+	}
+	return models
+}
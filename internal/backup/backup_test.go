@@ -0,0 +1,146 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+func writeFile(t *testing.T, rel, content string) {
+	t.Helper()
+	path := paths.Resolve(rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, rel string) string {
+	t.Helper()
+	data, err := os.ReadFile(paths.Resolve(rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	writeFile(t, "config.yaml", "server:\n  port: 9000\n")
+	writeFile(t, "personas/coder.yaml", "model: user/repo\n")
+	writeFile(t, "prompts/reviewer.md", "You review code.\n")
+	writeFile(t, "models/user/repo/metadata.yaml", "note: fast\n")
+	writeFile(t, "models/user/repo/Q4_K_M-manifest.json", "{}")
+	writeFile(t, "models/user/repo/model-Q4_K_M.gguf", "weights")
+	writeFile(t, "cache/catalog.json", "{}")
+
+	var buf bytes.Buffer
+	info, err := Create(&buf)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	wantFiles := []string{
+		"config.yaml",
+		"models/user/repo/Q4_K_M-manifest.json",
+		"models/user/repo/metadata.yaml",
+		"personas/coder.yaml",
+		"prompts/reviewer.md",
+	}
+	if !reflect.DeepEqual(info.Files, wantFiles) {
+		t.Errorf("Files = %v, want %v", info.Files, wantFiles)
+	}
+	if !reflect.DeepEqual(info.Models, []string{"user/repo:Q4_K_M"}) {
+		t.Errorf("Models = %v", info.Models)
+	}
+
+	// Restore onto a fresh machine
+	t.Setenv("LLEME_HOME", t.TempDir())
+	result, err := Restore(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !reflect.DeepEqual(result.Restored, wantFiles) {
+		t.Errorf("Restored = %v, want %v", result.Restored, wantFiles)
+	}
+	if got := readFile(t, "personas/coder.yaml"); got != "model: user/repo\n" {
+		t.Errorf("persona = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Models(), "user", "repo", "model-Q4_K_M.gguf")); !os.IsNotExist(err) {
+		t.Error("weights should not be restored")
+	}
+
+	// Local changes are kept unless overwriting
+	writeFile(t, "config.yaml", "server:\n  port: 1234\n")
+	result, err = Restore(bytes.NewReader(buf.Bytes()), false)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if len(result.Restored) != 0 || len(result.Unchanged) != 4 || !reflect.DeepEqual(result.Skipped, []string{"config.yaml"}) {
+		t.Errorf("second Restore() = restored %v, unchanged %v, skipped %v", result.Restored, result.Unchanged, result.Skipped)
+	}
+	if got := readFile(t, "config.yaml"); got != "server:\n  port: 1234\n" {
+		t.Errorf("config was overwritten: %q", got)
+	}
+
+	if _, err := Restore(bytes.NewReader(buf.Bytes()), true); err != nil {
+		t.Fatalf("Restore(overwrite) error = %v", err)
+	}
+	if got := readFile(t, "config.yaml"); got != "server:\n  port: 9000\n" {
+		t.Errorf("config after overwrite = %q", got)
+	}
+}
+
+func tarball(t *testing.T, entries map[string]string, order ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range order {
+		data := entries[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(data))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestRestoreRejects(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	info := `{"version":1}`
+
+	tests := []struct {
+		name    string
+		entries map[string]string
+		order   []string
+	}{
+		{"no info", map[string]string{"config.yaml": "x"}, []string{"config.yaml"}},
+		{"newer format", map[string]string{infoName: `{"version":99}`}, []string{infoName}},
+		{"path traversal", map[string]string{infoName: info, "../evil.yaml": "x"}, []string{infoName, "../evil.yaml"}},
+		{"absolute path", map[string]string{infoName: info, "/etc/passwd": "x"}, []string{infoName, "/etc/passwd"}},
+		{"unlisted file", map[string]string{infoName: info, "bin/llama-server": "x"}, []string{infoName, "bin/llama-server"}},
+		{"weights", map[string]string{infoName: info, "models/u/r/model.gguf": "x"}, []string{infoName, "models/u/r/model.gguf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Restore(bytes.NewReader(tarball(t, tt.entries, tt.order...)), true); err == nil {
+				t.Error("Restore() should fail")
+			}
+		})
+	}
+
+	if _, err := Restore(bytes.NewReader([]byte("not gzip")), true); err == nil {
+		t.Error("Restore() of a non-archive should fail")
+	}
+}
@@ -0,0 +1,213 @@
+// Package catalog provides a curated list of models known to work well with lleme,
+// with short names (e.g. "llama3.2") that resolve to Hugging Face repos.
+package catalog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// IndexURL is where the latest catalog is published.
+const IndexURL = "https://raw.githubusercontent.com/nchapman/lleme/main/internal/catalog/catalog.json"
+
+// Use cases a catalog model can be recommended for
+const (
+	UseCaseChat       = "chat"
+	UseCaseCoding     = "coding"
+	UseCaseEmbeddings = "embeddings"
+)
+
+// UseCases lists the valid use cases.
+var UseCases = []string{UseCaseChat, UseCaseCoding, UseCaseEmbeddings}
+
+//go:embed catalog.json
+var bundled []byte
+
+// Catalog is a curated list of models.
+type Catalog struct {
+	Updated  string    `json:"updated"` // YYYY-MM-DD, used to pick the newer of bundled and cached
+	Models   []Model   `json:"models"`
+	Samplers []Sampler `json:"samplers"` // Checked in order; the first match wins
+}
+
+// Sampler is the sampling defaults a model family's authors recommend.
+type Sampler struct {
+	Family  string         `json:"family"`
+	Match   []string       `json:"match"`   // Case-insensitive substrings of the repo name
+	Options map[string]any `json:"options"` // llama-server sampling options
+}
+
+// samplerOptions are the llama-server options a sampler may set. Others are
+// dropped, so a downloaded catalog can only change sampling.
+var samplerOptions = []string{
+	"temp", "top-k", "top-p", "min-p", "typical",
+	"repeat-penalty", "repeat-last-n", "presence-penalty", "frequency-penalty",
+}
+
+// Model is a catalog entry for one Hugging Face repo.
+type Model struct {
+	Name        string   `json:"name"`
+	Repo        string   `json:"repo"`
+	Description string   `json:"description"`
+	UseCases    []string `json:"use_cases"`
+	ParamsB     float64  `json:"params_b"` // Parameter count in billions
+	Quants      []Quant  `json:"quants"`   // Ordered smallest to largest
+}
+
+// Quant is a downloadable quantization of a catalog model.
+type Quant struct {
+	Name   string `json:"name"`
+	SizeMB int64  `json:"size_mb"`
+}
+
+// Size returns the quant's file size in bytes.
+func (q Quant) Size() int64 {
+	return q.SizeMB << 20
+}
+
+// HasUseCase reports whether the model is suited to the given use case.
+func (m *Model) HasUseCase(useCase string) bool {
+	return slices.Contains(m.UseCases, useCase)
+}
+
+// CachePath returns where an updated catalog is stored.
+func CachePath() string {
+	return filepath.Join(paths.Cache(), "catalog.json")
+}
+
+// Load returns the newer of the bundled catalog and the last downloaded one.
+func Load() (*Catalog, error) {
+	cat, err := parse(bundled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse bundled catalog: %w", err)
+	}
+
+	data, err := os.ReadFile(CachePath())
+	if err != nil {
+		return cat, nil
+	}
+	cached, err := parse(data)
+	if err != nil || cached.Updated < cat.Updated {
+		return cat, nil
+	}
+	return cached, nil
+}
+
+// Update downloads the latest catalog from IndexURL and caches it.
+func Update() (*Catalog, error) {
+	req, err := http.NewRequest("GET", IndexURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	client := &http.Client{Transport: logs.Transport(nil), Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch catalog: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	cat, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(CachePath()), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.WriteFile(CachePath(), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save catalog: %w", err)
+	}
+	return cat, nil
+}
+
+func parse(data []byte) (*Catalog, error) {
+	var cat Catalog
+	if err := json.Unmarshal(data, &cat); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %w", err)
+	}
+	if len(cat.Models) == 0 {
+		return nil, fmt.Errorf("invalid catalog: no models")
+	}
+	for i := range cat.Samplers {
+		for key, val := range cat.Samplers[i].Options {
+			if _, ok := val.(float64); !ok || !slices.Contains(samplerOptions, key) {
+				delete(cat.Samplers[i].Options, key)
+			}
+		}
+	}
+	return &cat, nil
+}
+
+// SamplerFor returns the sampling defaults for modelName (user/repo:quant),
+// or nil when its family has none.
+func (c *Catalog) SamplerFor(modelName string) *Sampler {
+	repoRef, _, _ := strings.Cut(modelName, ":")
+	repo := strings.ToLower(repoRef)
+	if _, name, ok := strings.Cut(repo, "/"); ok {
+		repo = name
+	}
+	for i := range c.Samplers {
+		for _, m := range c.Samplers[i].Match {
+			if m != "" && strings.Contains(repo, strings.ToLower(m)) {
+				return &c.Samplers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultQuant returns the quant pulled when a short name doesn't specify one.
+func (m *Model) DefaultQuant() string {
+	if len(m.Quants) == 0 {
+		return ""
+	}
+	return m.Quants[0].Name
+}
+
+// Lookup resolves a short name like "llama3.2" or "llama3.2:Q8_0" to a catalog
+// model and the requested quant ("" if none was given). Names are case-insensitive.
+func (c *Catalog) Lookup(ref string) (*Model, string, bool) {
+	if strings.Contains(ref, "/") {
+		return nil, "", false
+	}
+	if m := c.find(ref); m != nil {
+		return m, "", true
+	}
+	if i := strings.LastIndex(ref, ":"); i > 0 {
+		if m := c.find(ref[:i]); m != nil {
+			return m, ref[i+1:], true
+		}
+	}
+	return nil, "", false
+}
+
+func (c *Catalog) find(name string) *Model {
+	for i := range c.Models {
+		if strings.EqualFold(c.Models[i].Name, name) {
+			return &c.Models[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/hw"
+)
+
+func TestBundledCatalog(t *testing.T) {
+	cat, err := parse(bundled)
+	if err != nil {
+		t.Fatalf("bundled catalog failed to parse: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for _, m := range cat.Models {
+		if names[m.Name] {
+			t.Errorf("duplicate model name %q", m.Name)
+		}
+		names[m.Name] = true
+
+		if user, repo, ok := strings.Cut(m.Repo, "/"); !ok || user == "" || repo == "" || strings.Contains(repo, "/") {
+			t.Errorf("%s: repo %q should be user/repo", m.Name, m.Repo)
+		}
+		if len(m.Quants) == 0 {
+			t.Errorf("%s: no quants", m.Name)
+		}
+		for _, uc := range m.UseCases {
+			if !slices.Contains(UseCases, uc) {
+				t.Errorf("%s: unknown use case %q", m.Name, uc)
+			}
+		}
+	}
+}
+
+func TestBundledSamplers(t *testing.T) {
+	cat, err := parse(bundled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cat.Samplers) == 0 {
+		t.Fatal("bundled catalog has no samplers")
+	}
+	for _, s := range cat.Samplers {
+		if s.Family == "" || len(s.Match) == 0 || len(s.Options) == 0 {
+			t.Errorf("sampler %+v needs a family, matches and options", s)
+		}
+	}
+}
+
+func TestSamplerFor(t *testing.T) {
+	cat, err := parse(bundled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"bartowski/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M", "qwen-coder"},
+		{"bartowski/Qwen2.5-7B-Instruct-GGUF:Q4_K_M", "qwen2.5"},
+		{"unsloth/Qwen3-30B-A3B-GGUF:Q4_K_M", "qwen3"},
+		{"bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M", "llama3"},
+		{"bartowski/gemma-2-9b-it-GGUF", "gemma"},
+		{"llama-3-fans/Phi-4-GGUF:Q4_K_M", ""}, // Only the repo name counts
+		{"microsoft/phi-2-gguf:Q4_0", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if s := cat.SamplerFor(tt.model); s != nil {
+			got = s.Family
+		}
+		if got != tt.want {
+			t.Errorf("SamplerFor(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestParseDropsNonSamplingOptions(t *testing.T) {
+	cat, err := parse([]byte(`{"models":[{"name":"x","repo":"a/b"}],"samplers":[
+		{"family":"f","match":["b"],"options":{"temp":0.5,"model":"/etc/passwd","top-k":"40"}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := cat.Samplers[0].Options
+	if len(opts) != 1 || opts["temp"] != 0.5 {
+		t.Errorf("options = %v, want only temp", opts)
+	}
+}
+
+func TestLoadPrefersNewerCache(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	writeCache := func(content string) {
+		if err := os.MkdirAll(filepath.Dir(CachePath()), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(CachePath(), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		cache      string
+		wantCached bool
+	}{
+		{"newer cache", `{"updated":"9999-01-01","models":[{"name":"x","repo":"a/b"}]}`, true},
+		{"older cache", `{"updated":"2000-01-01","models":[{"name":"x","repo":"a/b"}]}`, false},
+		{"corrupt cache", `not json`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeCache(tt.cache)
+			cat, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if gotCached := cat.Updated == "9999-01-01"; gotCached != tt.wantCached {
+				t.Errorf("Load() used cache = %v, want %v", gotCached, tt.wantCached)
+			}
+		})
+	}
+}
+
+func TestBudgetFor(t *testing.T) {
+	tests := []struct {
+		name string
+		info *hw.Info
+		want Budget
+	}{
+		{
+			name: "dedicated gpu",
+			info: &hw.Info{
+				Memory: hw.MemoryInfo{Total: 64 << 30},
+				GPUs:   []hw.GPU{{Vendor: "nvidia", VRAMTotal: 24 << 30}},
+			},
+			want: Budget{Bytes: 24 << 30, Device: "GPU"},
+		},
+		{
+			name: "apple silicon",
+			info: &hw.Info{
+				Memory: hw.MemoryInfo{Total: 32 << 30, Unified: true},
+				GPUs:   []hw.GPU{{Vendor: "apple", Unified: true}},
+			},
+			want: Budget{Bytes: 24 << 30, Device: "Metal"},
+		},
+		{
+			name: "cpu only",
+			info: &hw.Info{Memory: hw.MemoryInfo{Total: 16 << 30}},
+			want: Budget{Bytes: 12 << 30, Device: "CPU", CPUOnly: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BudgetFor(tt.info); got != tt.want {
+				t.Errorf("BudgetFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	cat := &Catalog{Models: []Model{
+		{Name: "small", Repo: "u/small", UseCases: []string{"chat"}, ParamsB: 3,
+			Quants: []Quant{{"Q4_K_M", 2000}, {"Q8_0", 3400}}},
+		{Name: "medium", Repo: "u/medium", UseCases: []string{"chat"}, ParamsB: 8,
+			Quants: []Quant{{"Q4_K_M", 4700}, {"Q8_0", 8100}}},
+		{Name: "large", Repo: "u/large", UseCases: []string{"chat"}, ParamsB: 32,
+			Quants: []Quant{{"Q4_K_M", 19900}, {"Q8_0", 34800}}},
+		{Name: "coder", Repo: "u/coder", UseCases: []string{"coding"}, ParamsB: 7,
+			Quants: []Quant{{"Q4_K_M", 4700}}},
+	}}
+
+	tests := []struct {
+		name    string
+		useCase string
+		budget  Budget
+		want    []string
+	}{
+		{"gpu picks best quant that fits", "chat", Budget{Bytes: 12 << 30}, []string{"u/medium:Q8_0", "u/small:Q8_0"}},
+		{"large budget", "chat", Budget{Bytes: 48 << 30}, []string{"u/large:Q8_0", "u/medium:Q8_0", "u/small:Q8_0"}},
+		{"cpu prefers small quants and models", "chat", Budget{Bytes: 48 << 30, CPUOnly: true}, []string{"u/medium:Q4_K_M", "u/small:Q4_K_M"}},
+		{"nothing fits", "chat", Budget{Bytes: 1 << 30}, nil},
+		{"filters by use case", "coding", Budget{Bytes: 12 << 30}, []string{"u/coder:Q4_K_M"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range cat.Recommend(tt.useCase, tt.budget) {
+				got = append(got, r.Ref())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Recommend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cat := &Catalog{Models: []Model{
+		{Name: "llama3.2", Repo: "u/Llama-3.2-3B", Quants: []Quant{{"Q4_K_M", 2000}, {"Q8_0", 3400}}},
+		{Name: "llama3.2:1b", Repo: "u/Llama-3.2-1B", Quants: []Quant{{"Q4_K_M", 800}}},
+	}}
+
+	tests := []struct {
+		ref       string
+		wantRepo  string
+		wantQuant string
+		wantOK    bool
+	}{
+		{"llama3.2", "u/Llama-3.2-3B", "", true},
+		{"LLAMA3.2", "u/Llama-3.2-3B", "", true},
+		{"llama3.2:Q8_0", "u/Llama-3.2-3B", "Q8_0", true},
+		{"llama3.2:1b", "u/Llama-3.2-1B", "", true},
+		{"llama3.2:1b:Q8_0", "u/Llama-3.2-1B", "Q8_0", true},
+		{"mistral", "", "", false},
+		{"u/llama3.2", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			m, quant, ok := cat.Lookup(tt.ref)
+			if ok != tt.wantOK {
+				t.Fatalf("Lookup(%q) ok = %v, want %v", tt.ref, ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if m.Repo != tt.wantRepo || quant != tt.wantQuant {
+				t.Errorf("Lookup(%q) = %s, %q; want %s, %q", tt.ref, m.Repo, quant, tt.wantRepo, tt.wantQuant)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/nchapman/lleme/internal/hw"
+)
+
+const (
+	// overheadBytes covers compute buffers and a modest KV cache
+	overheadBytes = 512 << 20
+
+	// maxCPUParamsB caps model size on CPU-only hosts, beyond which
+	// generation is too slow to be pleasant
+	maxCPUParamsB = 8
+)
+
+// Budget is the memory a model can use and what it will run on.
+type Budget struct {
+	Bytes   int64
+	Device  string // "GPU", "Metal", or "CPU"
+	CPUOnly bool
+}
+
+// BudgetFor derives a model memory budget from detected hardware.
+// Dedicated GPUs are judged by VRAM so models run fully offloaded; unified
+// memory and CPU-only hosts keep a quarter of RAM for the OS and other apps.
+func BudgetFor(info *hw.Info) Budget {
+	var vram int64
+	for _, gpu := range info.GPUs {
+		if !gpu.Unified {
+			vram += gpu.VRAMTotal
+		}
+	}
+
+	switch {
+	case vram > 0:
+		return Budget{Bytes: vram, Device: "GPU"}
+	case info.Memory.Unified:
+		return Budget{Bytes: info.Memory.Total * 3 / 4, Device: "Metal"}
+	default:
+		return Budget{Bytes: info.Memory.Total * 3 / 4, Device: "CPU", CPUOnly: true}
+	}
+}
+
+// RequiredMemory estimates the memory needed to run a model file of the given
+// size: the weights, about 20% more for the KV cache, and compute overhead.
+func RequiredMemory(fileSize int64) int64 {
+	return fileSize + fileSize/5 + overheadBytes
+}
+
+// Fits reports whether a model file of the given size fits the budget.
+func (b Budget) Fits(fileSize int64) bool {
+	return RequiredMemory(fileSize) <= b.Bytes
+}
+
+// Recommendation is a catalog model with the quant chosen for a budget.
+type Recommendation struct {
+	Model Model
+	Quant Quant
+}
+
+// Ref returns the user/repo:quant reference to pull.
+func (r Recommendation) Ref() string {
+	return r.Model.Repo + ":" + r.Quant.Name
+}
+
+// Recommend returns models for the use case that fit the budget, largest first.
+// Each gets the highest quality quant that fits, or the smallest on CPU where
+// speed matters more than precision.
+func (c *Catalog) Recommend(useCase string, budget Budget) []Recommendation {
+	var recs []Recommendation
+	for _, m := range c.Models {
+		if !m.HasUseCase(useCase) || (budget.CPUOnly && m.ParamsB > maxCPUParamsB) {
+			continue
+		}
+
+		var best *Quant
+		for i := range m.Quants {
+			q := &m.Quants[i]
+			if !budget.Fits(q.Size()) {
+				continue
+			}
+			if best == nil || (budget.CPUOnly && q.SizeMB < best.SizeMB) || (!budget.CPUOnly && q.SizeMB > best.SizeMB) {
+				best = q
+			}
+		}
+		if best != nil {
+			recs = append(recs, Recommendation{Model: m, Quant: *best})
+		}
+	}
+
+	slices.SortStableFunc(recs, func(a, b Recommendation) int {
+		return cmp.Compare(b.Model.ParamsB, a.Model.ParamsB)
+	})
+	return recs
+}
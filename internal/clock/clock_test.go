@@ -0,0 +1,45 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	if !f.Now().Equal(start) {
+		t.Errorf("Now() = %v, want %v", f.Now(), start)
+	}
+
+	f.Sleep(time.Minute)
+	if got := f.Now().Sub(start); got != time.Minute {
+		t.Errorf("Sleep advanced %v, want 1m", got)
+	}
+
+	soon, later := f.After(time.Second), f.After(time.Hour)
+	f.Advance(2 * time.Second)
+	select {
+	case <-soon:
+	default:
+		t.Error("After(1s) should fire once the clock passes it")
+	}
+	select {
+	case <-later:
+		t.Error("After(1h) fired early")
+	default:
+	}
+
+	f.Set(start.Add(2 * time.Hour))
+	select {
+	case <-later:
+	default:
+		t.Error("After(1h) should fire once the clock is set past it")
+	}
+
+	select {
+	case <-f.After(0):
+	default:
+		t.Error("After(0) should fire immediately")
+	}
+}
@@ -0,0 +1,241 @@
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"br", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate, gzip", "gzip"},
+		{"GZIP", "gzip"},
+		{"x-gzip", "gzip"},
+		{"*", "gzip"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"gzip; q=0.0", ""},
+		{"gzip;q=0.5", "gzip"},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+var bigJSON = `{"models":[` + strings.Repeat(`{"name":"user/repo:Q4_K_M"},`, 100) + `{}]}`
+
+func serve(h http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	JSON(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func jsonHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, bigJSON)
+}
+
+func TestJSONCompresses(t *testing.T) {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			rec := serve(jsonHandler, encoding)
+			if got := rec.Header().Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("Vary = %q", got)
+			}
+			if rec.Body.Len() >= len(bigJSON) {
+				t.Errorf("body is %d bytes, not smaller than %d", rec.Body.Len(), len(bigJSON))
+			}
+
+			var r io.Reader
+			var err error
+			if encoding == "gzip" {
+				r, err = gzip.NewReader(rec.Body)
+			} else {
+				r, err = zlib.NewReader(rec.Body)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != bigJSON {
+				t.Error("decoded body doesn't match")
+			}
+		})
+	}
+}
+
+func TestJSONPassesThrough(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		handler http.HandlerFunc
+	}{
+		{"not accepted", "", jsonHandler},
+		{"stream", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, bigJSON)
+		}},
+		{"ndjson", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			io.WriteString(w, bigJSON)
+		}},
+		{"small", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "2")
+			io.WriteString(w, "{}")
+		}},
+		{"already encoded", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Encoding", "br")
+			io.WriteString(w, bigJSON)
+		}},
+		{"no content", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNoContent)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(tt.handler, tt.accept)
+			if enc := rec.Header().Get("Content-Encoding"); enc == "gzip" {
+				t.Errorf("response was compressed")
+			}
+		})
+	}
+}
+
+func TestJSONFlush(t *testing.T) {
+	srv := httptest.NewServer(JSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"first":true}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The flushed part decodes before the handler returns
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(`{"first":true}`))
+	if _, err := io.ReadFull(zr, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != `{"first":true}` {
+		t.Errorf("got %q", buf)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(JSON(http.HandlerFunc(jsonHandler)))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if !resp.Uncompressed {
+		t.Error("response wasn't compressed on the wire")
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != bigJSON {
+		t.Error("decoded body doesn't match")
+	}
+}
+
+func TestTransportDeflate(t *testing.T) {
+	encoders := map[string]func(io.Writer) io.WriteCloser{
+		"zlib": func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) },
+		"raw": func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		},
+	}
+	for name, newEncoder := range encoders {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			enc := newEncoder(&buf)
+			io.WriteString(enc, bigJSON)
+			enc.Close()
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Accept-Encoding"); !strings.Contains(got, "deflate") {
+					t.Errorf("Accept-Encoding = %q", got)
+				}
+				w.Header().Set("Content-Encoding", "deflate")
+				w.Write(buf.Bytes())
+			}))
+			defer srv.Close()
+
+			resp, err := (&http.Client{Transport: Transport(nil)}).Get(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != bigJSON {
+				t.Error("decoded body doesn't match")
+			}
+		})
+	}
+}
+
+func TestTransportLeavesRangeAlone(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); strings.Contains(got, "deflate") {
+			t.Errorf("Accept-Encoding = %q on a range request", got)
+		}
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Range", "bytes=0-9")
+	resp, err := (&http.Client{Transport: Transport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
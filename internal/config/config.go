@@ -2,9 +2,13 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/nchapman/lleme/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,26 +18,106 @@ func DefaultSystemPrompt() string {
 }
 
 type Config struct {
-	HuggingFace HuggingFace `yaml:"huggingface"`
-	Server      Server      `yaml:"server"`
-	LlamaCpp    LlamaCpp    `yaml:"llamacpp"`
-	Peer        Peer        `yaml:"peer"`
+	Version         int             `yaml:"version"` // Layout version, used to migrate older files on load
+	HuggingFace     HuggingFace     `yaml:"huggingface"`
+	Server          Server          `yaml:"server"`
+	Power           Power           `yaml:"power,omitempty"`
+	LlamaCpp        LlamaCpp        `yaml:"llamacpp"`
+	StableDiffusion StableDiffusion `yaml:"stable_diffusion,omitempty"`
+	Chat            Chat            `yaml:"chat,omitempty"`
+	UI              UI              `yaml:"ui,omitempty"`
+	Peer            Peer            `yaml:"peer"`
+	S3              S3              `yaml:"s3,omitempty"`
+	Telemetry       Telemetry       `yaml:"telemetry,omitempty"`
+	Features        []string        `yaml:"features,omitempty"` // Experimental features to turn on, see 'lleme doctor'
 }
 
 type Peer struct {
-	Enabled     bool     `yaml:"enabled"`      // Enable bidirectional peer-to-peer model sharing (default: false)
-	Port        int      `yaml:"port"`         // Port for peer sharing server (default: 11314)
-	StaticPeers []string `yaml:"static_peers"` // Static peer addresses (host:port) when mDNS discovery fails
+	Enabled     bool     `yaml:"enabled"`              // Enable bidirectional peer-to-peer model sharing (default: false)
+	Port        int      `yaml:"port"`                 // Port for peer sharing server (default: 11314)
+	StaticPeers []string `yaml:"static_peers"`         // Static peer addresses (host:port) when mDNS discovery fails
+	AllowSync   bool     `yaml:"allow_sync,omitempty"` // Let other machines list and push models with 'lleme sync'
+	Allow       []string `yaml:"allow,omitempty"`      // Addresses or CIDR ranges that may download and sync (empty = any)
+	Deny        []string `yaml:"deny,omitempty"`       // Addresses or CIDR ranges refused, even if allowed
+}
+
+// Telemetry configures anonymous usage counts. They stay on this machine:
+// lleme never sends them anywhere.
+type Telemetry struct {
+	Enabled bool   `yaml:"enabled,omitempty"` // Count command usage (default: false)
+	Export  string `yaml:"export,omitempty"`  // Also write the counts as JSON into this directory, such as a shared drive
 }
 
 type HuggingFace struct {
 	Token        string `yaml:"token"`
 	DefaultQuant string `yaml:"default_quant"`
+	Endpoint     string `yaml:"endpoint,omitempty"` // Hub mirror to pull through, e.g. a lleme cache (HF_ENDPOINT overrides)
+}
+
+// S3 configures pulling models from S3-compatible storage. Credentials come
+// from the standard AWS chain (env vars, shared config/credentials, SSO, instance roles).
+type S3 struct {
+	Endpoint  string `yaml:"endpoint,omitempty"`   // Custom endpoint for MinIO and other S3-compatible stores
+	Region    string `yaml:"region,omitempty"`     // Region (default: from AWS config, else us-east-1)
+	PathStyle bool   `yaml:"path_style,omitempty"` // Use path-style URLs (bucket in the path), needed by most MinIO setups
 }
 
 type LlamaCpp struct {
-	ServerPath string         `yaml:"server_path,omitempty"`
-	Options    map[string]any `yaml:"options,omitempty"`
+	ServerPath   string                    `yaml:"server_path,omitempty"`
+	Options      map[string]any            `yaml:"options,omitempty"`
+	ModelOptions map[string]map[string]any `yaml:"model_options,omitempty"` // Per-model overrides keyed by model name
+	Passthrough  map[string]any            `yaml:"passthrough,omitempty"`   // Passed to llama-server without checking
+
+	// SamplerPresets is on (the default) to use the registry's recommended
+	// sampling for each model family where options leave it unset, or off
+	SamplerPresets string `yaml:"sampler_presets,omitempty"`
+}
+
+// StableDiffusion configures the sd-server backend behind /v1/images/generations.
+type StableDiffusion struct {
+	Options map[string]any `yaml:"options,omitempty"` // Passed to sd-server as --key value
+}
+
+// UI configures terminal output for all commands.
+type UI struct {
+	Color      string `yaml:"color,omitempty"`      // auto, always or never (default: auto, which honors NO_COLOR)
+	Accessible bool   `yaml:"accessible,omitempty"` // Screen-reader-friendly linear output, without spinners or repainting
+	// SmoothStream paces bursty replies in the chat UI so they type out
+	// evenly; --no-smooth turns it off for one session
+	SmoothStream bool `yaml:"smooth_stream,omitempty"`
+
+	// Theme is auto, dark, light or solarized (default: auto, which adapts
+	// to the terminal background)
+	Theme string `yaml:"theme,omitempty"`
+	// Colors overrides theme colors by name (primary, secondary, success,
+	// error, warning, accent, muted, border, value) with hex or ANSI codes
+	Colors map[string]string `yaml:"colors,omitempty"`
+}
+
+// Chat configures the run and chat clients.
+type Chat struct {
+	MaxContinues int                 `yaml:"max_continues,omitempty"` // Times to continue a reply cut off by max tokens
+	Keymap       string              `yaml:"keymap,omitempty"`        // default or vim
+	Keys         map[string][]string `yaml:"keys,omitempty"`          // Per-action key overrides
+	Images       string              `yaml:"images,omitempty"`        // auto, kitty, iterm2, sixel, or off
+	HistorySize  int                 `yaml:"history_size,omitempty"`  // Input history kept per model (0 = 1000, -1 = off)
+	RespondIn    string              `yaml:"respond_in,omitempty"`    // Language code replies should be in, like de (a persona's wins)
+
+	// CostRates prices tokens like a hosted API, to show what a session
+	// would have cost there. Keyed like llamacpp.model_options, with
+	// "default" for models that have no entry of their own.
+	CostRates map[string]CostRate `yaml:"cost_rates,omitempty"`
+}
+
+// CostRate is a price in dollars per million tokens.
+type CostRate struct {
+	Input  float64 `yaml:"input"`  // Prompt tokens
+	Output float64 `yaml:"output"` // Completion tokens
+}
+
+// Cost returns the price of promptTokens and completionTokens in dollars.
+func (r CostRate) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*r.Input + float64(completionTokens)*r.Output) / 1e6
 }
 
 type Server struct {
@@ -45,62 +129,98 @@ type Server struct {
 	BackendPortMin  int      `yaml:"backend_port_min"`
 	BackendPortMax  int      `yaml:"backend_port_max"`
 	CORSOrigins     []string `yaml:"cors_origins,omitempty"`
+	MemoryGuard     string   `yaml:"memory_guard,omitempty"`         // refuse, reduce, or off (default: refuse)
+	MinFreeMemoryMB int      `yaml:"min_free_memory_mb,omitempty"`   // Memory to keep free after loading a model
+	GRPCPort        int      `yaml:"grpc_port,omitempty"`            // Port for the gRPC management API (0 = disabled)
+	HFCache         bool     `yaml:"hf_cache,omitempty"`             // Serve a caching Hugging Face mirror at /hf for other lleme clients
+	HFCacheToken    bool     `yaml:"hf_cache_token,omitempty"`       // Let /hf clients without a Hub token use this machine's
+	WarmUp          bool     `yaml:"warmup,omitempty"`               // Send a one-token request after loading so the first real one is fast
+	DefaultModel    string   `yaml:"default_model,omitempty"`        // Model for requests that name none, or a hosted model lleme doesn't have
+	DefaultStrict   bool     `yaml:"default_model_strict,omitempty"` // Only use default_model when the request names no model
+	Clients         []Client `yaml:"clients,omitempty"`              // Per-client limits for a shared server
+	Insecure        bool     `yaml:"insecure,omitempty"`             // Listen on a public address without API keys
+	StatusToken     string   `yaml:"status_token,omitempty"`         // Token for the read-only /status page (empty = disabled)
+	UnloadSchedule  string   `yaml:"unload_schedule,omitempty"`      // Quiet hours like "22:00-07:00" when no models run
+	Eviction        string   `yaml:"eviction,omitempty"`             // Which model to unload for a new one: lru, size, or priority (default: lru)
+	Pprof           bool     `yaml:"pprof,omitempty"`                // Serve Go profiles at /debug/pprof for diagnosing the server
+	JSONRepair      bool     `yaml:"json_repair,omitempty"`          // Fix almost-valid JSON replies to response_format requests
+	MaxOutputTokens int      `yaml:"max_output_tokens,omitempty"`    // Cap on tokens generated per request, for every client (0 = none)
+	DefaultTokens   int      `yaml:"default_max_tokens,omitempty"`   // max_tokens for requests that set no length (0 = until the model stops)
+
+	Priorities ModelPriorities `yaml:"priorities,omitempty"` // Weights for eviction: priority, higher stays loaded longer
 }
 
-const (
-	configDir  = ".lleme"
-	configFile = "config.yaml"
-	modelsDir  = "models"
-	binDir     = "bin"
-	cacheDir   = "cache"
-	logsDir    = "logs"
-	pidsDir    = "pids"
-)
-
-// UserHomeDir returns the user's home directory.
-func UserHomeDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "."
+// ModelPriorities weighs models for the priority eviction policy, keyed like
+// llamacpp.model_options by full name, user/repo, or repo, with "default" for
+// every other model.
+type ModelPriorities map[string]int
+
+// For returns modelName's weight, preferring the full name, then user/repo,
+// then the bare repo name, then "default". Keys are matched
+// case-insensitively, as in matchingKeys; models without one weigh 0.
+func (p ModelPriorities) For(modelName string) int {
+	repoRef, _, _ := strings.Cut(modelName, ":")
+	_, repo, _ := strings.Cut(repoRef, "/")
+
+	for _, candidate := range []string{modelName, repoRef, repo, "default"} {
+		if candidate == "" {
+			continue
+		}
+		if keys := matchingKeys(p, candidate); len(keys) > 0 {
+			return p[keys[len(keys)-1]]
+		}
 	}
-	return home
+	return 0
 }
 
-// BaseDir returns the base directory for all lleme data.
-// Uses LLEME_HOME environment variable if set, otherwise ~/.lleme
-func BaseDir() string {
-	if dir := os.Getenv("LLEME_HOME"); dir != "" {
-		return dir
+// matchingKeys returns m's keys that equal name ignoring case, in the order
+// to apply them: sorted, with the one written exactly like name last so it
+// wins. Keys that differ only in case would otherwise be picked in map order.
+func matchingKeys[V any](m map[string]V, name string) []string {
+	var keys []string
+	for key := range m {
+		if key != name && strings.EqualFold(key, name) {
+			keys = append(keys, key)
+		}
 	}
-	return filepath.Join(UserHomeDir(), configDir)
-}
-
-func ConfigPath() string {
-	return filepath.Join(BaseDir(), configFile)
-}
-
-func ModelsPath() string {
-	return filepath.Join(BaseDir(), modelsDir)
-}
-
-func BinPath() string {
-	return filepath.Join(BaseDir(), binDir)
+	slices.Sort(keys)
+	if _, ok := m[name]; ok {
+		keys = append(keys, name)
+	}
+	return keys
 }
 
-func CachePath() string {
-	return filepath.Join(BaseDir(), cacheDir)
-}
+// Power saver policies (power.saver)
+const (
+	PowerSaverOff    = "off"    // Load and pull the same on battery as on AC power
+	PowerSaverAuto   = "auto"   // Save power on battery, in Low Power Mode, or under thermal pressure
+	PowerSaverAlways = "always" // Always save power
+)
 
-func LogsPath() string {
-	return filepath.Join(BaseDir(), logsDir)
+// Power eases off on laptops so running models doesn't drain or overheat
+// them. Battery, Low Power Mode and thermal pressure are detected on macOS.
+type Power struct {
+	Saver      string `yaml:"saver,omitempty"`       // off, auto, or always (default: off)
+	Threads    int    `yaml:"threads,omitempty"`     // CPU threads for models loaded while saving (0 = half the cores)
+	GPULayers  *int   `yaml:"gpu_layers,omitempty"`  // GPU layers for models loaded while saving (unset = unchanged)
+	DeferPulls bool   `yaml:"defer_pulls,omitempty"` // Hold downloads through the server until power is back
 }
 
-func PidsPath() string {
-	return filepath.Join(BaseDir(), pidsDir)
+// Client restricts what one client of the proxy may do. A client is
+// recognized by its API key, sent as a bearer token or x-api-key, or, when it
+// has no key, by its name in the X-LLeme-Client header.
+type Client struct {
+	Name              string         `yaml:"name"`
+	APIKey            string         `yaml:"api_key,omitempty"`
+	Models            []string       `yaml:"models,omitempty"`              // Allowed models (empty = all)
+	MaxTokens         int            `yaml:"max_tokens,omitempty"`          // Cap on tokens generated per request
+	RequestsPerMinute int            `yaml:"requests_per_minute,omitempty"` // 0 = unlimited
+	Options           map[string]any `yaml:"options,omitempty"`             // Sampling options forced on every request
 }
 
 func DefaultConfig() *Config {
 	return &Config{
+		Version: CurrentVersion,
 		HuggingFace: HuggingFace{
 			Token:        "",
 			DefaultQuant: "Q4_K_M",
@@ -114,6 +234,9 @@ func DefaultConfig() *Config {
 			StartupTimeoutS: 120,
 			BackendPortMin:  49152,
 			BackendPortMax:  49200,
+			MemoryGuard:     "refuse",
+			MinFreeMemoryMB: 1024,
+			Eviction:        "lru",
 			CORSOrigins: []string{
 				"http://localhost",
 				"http://127.0.0.1",
@@ -129,44 +252,142 @@ func DefaultConfig() *Config {
 
 // DefaultConfigTemplate returns a nicely formatted config with comments
 // showing popular llama-server options and their defaults.
-const DefaultConfigTemplate = `# Hugging Face settings
+const DefaultConfigTemplate = `# Config format version, used to upgrade older files (don't change)
+version: 2
+
+# Hugging Face settings
 huggingface:
   # Access token for gated models (or set HF_TOKEN env var)
   token: ""
-  # Default quantization when pulling models
+  # Default quantization when pulling models, and the one run picks
+  # when several quants of a model are downloaded
   default_quant: Q4_K_M
+  # Pull through a Hub mirror, such as another lleme with server.hf_cache
+  # enabled (or set HF_ENDPOINT env var)
+  # endpoint: http://models.office.lan:11313/hf
 
 # lleme server settings
 server:
-  host: 127.0.0.1
+  host: 127.0.0.1            # Other addresses need an api_key under clients, or insecure: true
   port: 11313
   max_models: 3              # Max concurrent models in memory
   idle_timeout_mins: 10      # Unload idle models after this time
   startup_timeout_secs: 120  # Max time to wait for model to load
   backend_port_min: 49152    # Port range for llama-server backends
   backend_port_max: 49200
+  memory_guard: refuse       # Before loading: refuse, reduce (shrink ctx-size), or off
+  min_free_memory_mb: 1024   # Memory to leave free for other apps
+  grpc_port: 0               # gRPC management API on host (0 = disabled)
+  hf_cache: false            # Cache Hugging Face downloads for other lleme clients at /hf
+  hf_cache_token: false      # Let /hf clients without a Hub token use this machine's (needs an API key when public)
+  warmup: false              # Generate one token after loading so the first request doesn't pay setup costs
+  default_model: ""          # Serve requests without a model, or for hosted names like gpt-4o, with this model
+  default_model_strict: false # Only fall back when the request names no model
+  status_token: ""           # Share a read-only page at /status?token=<this> (empty = disabled)
+  # unload_schedule: "22:00-07:00" # Quiet hours: unload all models and refuse loads (local time)
+  eviction: lru              # Model unloaded to make room: lru, size (biggest first), or priority
+  pprof: false               # Serve Go profiles at /debug/pprof, for diagnosing hangs
+  json_repair: false         # Fix trailing commas, unquoted keys and the like in JSON-mode replies
+  max_output_tokens: 0       # Stop any reply after this many tokens (0 = no cap)
+  default_max_tokens: 0      # Length for requests that don't set max_tokens (0 = until the model stops)
+  # priorities:              # Weights for eviction: priority (higher stays loaded; others are 0)
+  #   bartowski/Llama-3.3-70B-Instruct-GGUF: 10
+  #   nomic-embed-text-v1.5-GGUF: 5
   cors_origins:              # Allowed CORS origins
     - http://localhost
     - http://127.0.0.1
     - http://[::1]
+  # Limits for clients sharing this server, matched by API key, or by
+  # X-LLeme-Client header for clients without one
+  # clients:
+  #   - name: ci
+  #     api_key: change-me
+  #     models: [bartowski/Llama-3.2-3B-Instruct-GGUF]
+  #     max_tokens: 1024
+  #     requests_per_minute: 30
+  #     options:
+  #       temp: 0.2
+
+# Go easy on laptops: on battery, in Low Power Mode, or when the Mac is hot
+# power:
+#   saver: auto              # off, auto (when any of those apply), or always
+#   threads: 0               # CPU threads for models loaded while saving (0 = half the cores)
+#   gpu_layers: 0            # GPU layers while saving (leave out to keep the usual)
+#   defer_pulls: true        # Hold downloads through the server until back on power
+
+# Terminal output
+ui:
+  color: auto                # auto (color on terminals, off with NO_COLOR), always, or never
+  accessible: false          # Screen reader mode: linear chat, no spinners, repainting or boxes
+  theme: auto                # auto (adapts to the terminal background), dark, light, or solarized
+  smooth_stream: false       # Even out bursts of tokens in chat replies for easier reading
+  # colors:                  # Override theme colors with hex or ANSI 256 codes
+  #   primary: "#005f87"
+  #   muted: "244"
+
+# Chat settings for run and the chat UI
+chat:
+  max_continues: 0           # Keep going when a reply hits max tokens, up to this many times
+  keymap: default            # Key bindings: default, or vim (esc to scroll, i to type)
+  # keys:                    # Override single actions; /keys in chat lists them all
+  #   send: [ctrl+s]         # ctrl+enter only works in terminals that report it
+  #   newline: [enter]
+  #   clear: []              # An empty list unbinds the action
+  images: auto               # Draw /image attachments: auto, kitty, iterm2, sixel, or off for a placeholder
+  history_size: 1000         # Input history kept per model (-1 to not save any)
+  # respond_in: de           # Always reply in this language, asking again once if a reply drifts
+  # cost_rates:              # Show what a session would cost on a hosted API, in $ per million tokens
+  #   default:
+  #     input: 3.00
+  #     output: 15.00
+  #   bartowski/Llama-3.2-3B-Instruct-GGUF:
+  #     input: 0.06
+  #     output: 0.06
 
 # Peer-to-peer model sharing
 # Share models with other lleme instances on your LAN (uses mDNS discovery)
 peer:
   enabled: false  # Discover peers and share models bidirectionally
   port: 11314     # Port for peer sharing (accessible from other machines)
+  allow_sync: false  # Let 'lleme sync' on other machines list and push models here
+  # allow:           # Only these addresses or CIDR ranges may download or sync (default: any)
+  #   - 192.168.1.0/24
+  # deny:            # Refuse these, even if allowed
+  #   - 192.168.1.66
   # static_peers:  # Manually specify peers if mDNS doesn't work (e.g., across subnets)
   #   - 192.168.1.100:11314
 
+# S3-compatible storage for 'lleme pull s3://bucket/prefix'
+# Credentials come from the AWS chain (AWS_ACCESS_KEY_ID, ~/.aws, SSO, instance roles)
+# s3:
+#   endpoint: http://minio.internal:9000  # Custom endpoint (empty = AWS)
+#   region: us-east-1
+#   path_style: true                      # Needed by most MinIO setups
+
+# Anonymous usage counts (commands run, platform, versions), kept on this machine
+# and never sent anywhere. 'lleme telemetry status' shows what's recorded.
+# telemetry:
+#   enabled: false
+#   export: /mnt/shared/lleme-usage      # Also write the counts here as <id>.json
+
+# Experimental features to turn on for this machine. They're off by default
+# and may change or go away; 'lleme doctor' lists the ones this build has.
+# LLEME_FEATURES=name,-other turns them on or off for one command.
+# features: [background-jobs]
+
 # llama.cpp server settings
-# All options here are passed directly to llama-server.
-# See 'llama-server --help' for the full list.
+# Options are passed to llama-server as --key value.
+# See 'llama-server --help' for what each one does.
 llamacpp:
   # Path to llama-server binary (empty = auto-detect)
   # server_path: ""
 
-  # Any llama-server options can be added here.
-  # Uncomment and modify as needed:
+  # Sampling recommended by each model family's authors (from 'lleme registry
+  # update') fills in whatever the options below leave unset: on or off
+  # sampler_presets: on
+
+  # llama-server options, checked when set with 'lleme config set' and when
+  # sent to /api/run. Uncomment and modify as needed:
   options:
     # --- Performance ---
     # threads: -1              # CPU threads for generation (-1 = auto)
@@ -187,6 +408,19 @@ llamacpp:
     # cache-type-v: f16        # KV cache type for V
     # mlock: false             # Lock model in RAM (prevents swapping)
 
+    # --- Long context (RoPE / YaRN) ---
+    # rope-scaling: yarn       # RoPE scaling method (none, linear, yarn)
+    # rope-freq-base: 0        # RoPE base frequency (0 = from model)
+    # rope-freq-scale: 0.25    # RoPE frequency scale (1/N extends context N times)
+    # yarn-orig-ctx: 0         # Original training context for YaRN (0 = from model)
+    # override-kv:             # Override model metadata (KEY=TYPE:VALUE)
+    #   - tokenizer.ggml.add_bos_token=bool:false
+
+    # --- Context window ---
+    # context-shift: true      # Slide the window when context fills instead of failing
+    # keep: 0                  # Tokens of the prompt to keep when shifting (-1 = all)
+    # cache-reuse: 256         # Min chunk size to reuse from KV cache via shifting (0 = off)
+
     # --- Sampling defaults ---
     # temp: 0.8                # Temperature
     # top-k: 40                # Top-k sampling (0 = disabled)
@@ -196,12 +430,34 @@ llamacpp:
 
     # --- Reasoning models ---
     # reasoning-format: auto   # Thinking token handling (auto, none, deepseek)
+
+  # Per-model options, merged over the options above. Keys match a full model
+  # name (user/repo:quant), a repo (user/repo), or a bare repo name.
+  # model_options:
+  #   bartowski/Llama-3.2-3B-Instruct-GGUF:
+  #     ctx-size: 32768
+  #     context-shift: true
+  #     cache-reuse: 256
+
+  # llama-server flags lleme doesn't know yet, passed along without checking.
+  # Options above win over the same key here.
+  # passthrough:
+  #   some-new-flag: 1
+
+# stable-diffusion.cpp settings for /v1/images/generations.
+# Install it with 'lleme update stable-diffusion'. Options are passed to sd-server.
+# stable_diffusion:
+#   options:
+#     vae: /path/to/ae.safetensors      # Separate VAE weights
+#     clip_l: /path/to/clip_l.safetensors
+#     t5xxl: /path/to/t5xxl.gguf
+#     offload-to-cpu: true              # Keep weights in RAM, move to VRAM as needed
 `
 
 func Load() (*Config, error) {
 	cfg := DefaultConfig()
 
-	configPath := ConfigPath()
+	configPath := paths.Config()
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -210,22 +466,44 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config: %w", err)
 	}
 
+	data, err = migrateFile(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := ValidateMemoryGuard(cfg.Server.MemoryGuard); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return cfg, nil
 }
 
+// MemoryGuards are the server.memory_guard policies
+var MemoryGuards = []string{"refuse", "reduce", "off"}
+
+// ValidateMemoryGuard checks a server.memory_guard value, so a typo isn't
+// quietly read as the default
+func ValidateMemoryGuard(guard string) error {
+	if guard == "" || slices.Contains(MemoryGuards, guard) {
+		return nil
+	}
+	return fmt.Errorf("invalid server.memory_guard %q: expected %s", guard, strings.Join(MemoryGuards, ", "))
+}
+
 func Save(cfg *Config) error {
-	configPath := ConfigPath()
+	configPath := paths.Config()
 	configDir := filepath.Dir(configPath)
 
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := yaml.Marshal(cfg)
+	out := *cfg
+	out.Version = CurrentVersion
+	data, err := yaml.Marshal(&out)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
@@ -240,7 +518,7 @@ func Save(cfg *Config) error {
 // SaveDefault writes the default config template with comments.
 // Use this for initial config creation or reset.
 func SaveDefault() error {
-	configPath := ConfigPath()
+	configPath := paths.Config()
 	configDir := filepath.Dir(configPath)
 
 	if err := os.MkdirAll(configDir, 0755); err != nil {
@@ -290,15 +568,82 @@ func (c *LlamaCpp) GetFloatOption(key string, defaultVal float64) float64 {
 	return defaultVal
 }
 
+// LaunchOptions returns the options every backend starts with: Options
+// over Passthrough.
+func (c *LlamaCpp) LaunchOptions() map[string]any {
+	if len(c.Passthrough) == 0 {
+		return c.Options
+	}
+	merged := maps.Clone(c.Passthrough)
+	maps.Copy(merged, c.Options)
+	return merged
+}
+
+// OptionsForModel returns the global options merged with any per-model overrides
+// for modelName ("user/repo:quant").
+func (c *LlamaCpp) OptionsForModel(modelName string) map[string]any {
+	merged := make(map[string]any, len(c.Options))
+	maps.Copy(merged, c.Options)
+	maps.Copy(merged, c.ModelOverrides(modelName))
+	return merged
+}
+
+// ModelOverrides returns the per-model options that apply to modelName
+// ("user/repo:quant"), or nil when none do. Overrides are applied from least
+// to most specific: bare repo name, then user/repo, then the full name. Keys
+// are matched case-insensitively, as in matchingKeys.
+func (c *LlamaCpp) ModelOverrides(modelName string) map[string]any {
+	if len(c.ModelOptions) == 0 {
+		return nil
+	}
+
+	repoRef, _, _ := strings.Cut(modelName, ":")
+	_, repo, _ := strings.Cut(repoRef, "/")
+	candidates := []string{repo, repoRef, modelName}
+
+	var merged map[string]any
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		for _, key := range matchingKeys(c.ModelOptions, candidate) {
+			if merged == nil {
+				merged = make(map[string]any)
+			}
+			maps.Copy(merged, c.ModelOptions[key])
+		}
+	}
+	return merged
+}
+
+// CostRateForModel returns the cost rate for modelName ("user/repo:quant"),
+// preferring the full name, then user/repo, then the bare repo name, then
+// "default". Keys are matched case-insensitively, as in matchingKeys. It
+// reports false when no rate applies.
+func (c *Chat) CostRateForModel(modelName string) (CostRate, bool) {
+	repoRef, _, _ := strings.Cut(modelName, ":")
+	_, repo, _ := strings.Cut(repoRef, "/")
+
+	for _, candidate := range []string{modelName, repoRef, repo, "default"} {
+		if candidate == "" {
+			continue
+		}
+		if keys := matchingKeys(c.CostRates, candidate); len(keys) > 0 {
+			return c.CostRates[keys[len(keys)-1]], true
+		}
+	}
+	return CostRate{}, false
+}
+
 func EnsureDirectories() error {
 	dirs := []string{
-		ConfigPath(),
-		ModelsPath(),
-		BinPath(),
-		CachePath(),
-		LogsPath(),
-		PidsPath(),
-		PersonasPath(),
+		paths.Config(),
+		paths.Models(),
+		paths.Bin(),
+		paths.Cache(),
+		paths.Logs(),
+		paths.Pids(),
+		paths.Personas(),
 	}
 
 	for _, dir := range dirs {
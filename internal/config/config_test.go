@@ -40,10 +40,7 @@ func TestDefaultConfig(t *testing.T) {
 
 func TestLoad(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	t.Run("returns default config when file does not exist", func(t *testing.T) {
 		cfg, err := Load()
@@ -61,7 +58,7 @@ func TestLoad(t *testing.T) {
 	})
 
 	t.Run("parses valid config file with options", func(t *testing.T) {
-		configDir := filepath.Join(tmpDir, ".lleme")
+		configDir := tmpDir
 		if err := os.MkdirAll(configDir, 0755); err != nil {
 			t.Fatalf("Failed to create test config dir: %v", err)
 		}
@@ -131,8 +128,20 @@ server:
 		}
 	})
 
+	t.Run("rejects an unknown memory guard", func(t *testing.T) {
+		configPath := filepath.Join(tmpDir, "config.yaml")
+		if err := os.WriteFile(configPath, []byte("server:\n  memory_guard: refuze\n"), 0644); err != nil {
+			t.Fatalf("Failed to write test config: %v", err)
+		}
+
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), "refuse, reduce, off") {
+			t.Errorf("Load() error = %v, want one listing the memory guards", err)
+		}
+	})
+
 	t.Run("returns error for invalid YAML", func(t *testing.T) {
-		configDir := filepath.Join(tmpDir, ".lleme")
+		configDir := tmpDir
 		if err := os.MkdirAll(configDir, 0755); err != nil {
 			t.Fatalf("Failed to create test config dir: %v", err)
 		}
@@ -151,17 +160,14 @@ server:
 
 func TestSaveDefault(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	err := SaveDefault()
 	if err != nil {
 		t.Fatalf("Expected no error saving default config, got %v", err)
 	}
 
-	configPath := filepath.Join(tmpDir, ".lleme", "config.yaml")
+	configPath := filepath.Join(tmpDir, "config.yaml")
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		t.Fatalf("Failed to read saved config: %v", err)
@@ -235,19 +241,151 @@ func TestGetOptionHelpers(t *testing.T) {
 	})
 }
 
+func TestOptionsForModel(t *testing.T) {
+	llama := &LlamaCpp{
+		Options: map[string]any{"ctx-size": 4096, "threads": 8},
+		ModelOptions: map[string]map[string]any{
+			"Llama-3.2-3B-Instruct-GGUF":                  {"ctx-size": 8192, "cache-reuse": 256},
+			"bartowski/llama-3.2-3b-instruct-gguf":        {"ctx-size": 16384},
+			"bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M": {"context-shift": true},
+			"unsloth/Qwen3-8B-GGUF":                       {"ctx-size": 32768},
+		},
+	}
+
+	t.Run("most specific override wins", func(t *testing.T) {
+		got := llama.OptionsForModel("bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M")
+		if got["ctx-size"] != 16384 {
+			t.Errorf("Expected ctx-size 16384, got %v", got["ctx-size"])
+		}
+		if got["cache-reuse"] != 256 {
+			t.Errorf("Expected cache-reuse 256, got %v", got["cache-reuse"])
+		}
+		if got["context-shift"] != true {
+			t.Errorf("Expected context-shift true, got %v", got["context-shift"])
+		}
+		if got["threads"] != 8 {
+			t.Errorf("Expected global threads 8, got %v", got["threads"])
+		}
+	})
+
+	t.Run("other quant skips full-name override", func(t *testing.T) {
+		got := llama.OptionsForModel("bartowski/Llama-3.2-3B-Instruct-GGUF:Q8_0")
+		if _, ok := got["context-shift"]; ok {
+			t.Error("Expected no context-shift for a different quant")
+		}
+	})
+
+	t.Run("unmatched model gets global options", func(t *testing.T) {
+		got := llama.OptionsForModel("other/Model-GGUF:Q4_K_M")
+		if got["ctx-size"] != 4096 {
+			t.Errorf("Expected ctx-size 4096, got %v", got["ctx-size"])
+		}
+	})
+
+	t.Run("does not mutate global options", func(t *testing.T) {
+		llama.OptionsForModel("unsloth/Qwen3-8B-GGUF:Q4_K_M")
+		if llama.Options["ctx-size"] != 4096 {
+			t.Errorf("Global options were mutated: %v", llama.Options)
+		}
+	})
+
+	t.Run("keys differing only in case apply in a fixed order", func(t *testing.T) {
+		dup := &LlamaCpp{ModelOptions: map[string]map[string]any{
+			"Qwen3-8B-GGUF": {"ctx-size": 8192},
+			"qwen3-8b-gguf": {"ctx-size": 4096},
+			"QWEN3-8B-GGUF": {"ctx-size": 2048},
+		}}
+		// Map order varies between runs, so check more than once
+		for range 20 {
+			if got := dup.OptionsForModel("unsloth/Qwen3-8B-GGUF:Q4_K_M")["ctx-size"]; got != 8192 {
+				t.Fatalf("Expected the exactly written key's ctx-size 8192, got %v", got)
+			}
+			if got := dup.OptionsForModel("unsloth/qwen3-8B-gguf:Q4_K_M")["ctx-size"]; got != 4096 {
+				t.Fatalf("Expected the last sorted key's ctx-size 4096, got %v", got)
+			}
+		}
+	})
+
+	t.Run("overrides alone leave out global options", func(t *testing.T) {
+		got := llama.ModelOverrides("bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M")
+		if got["ctx-size"] != 16384 || got["cache-reuse"] != 256 {
+			t.Errorf("ModelOverrides() = %v", got)
+		}
+		if _, ok := got["threads"]; ok {
+			t.Error("ModelOverrides() included the global threads")
+		}
+		if got := llama.ModelOverrides("other/Model-GGUF:Q4_K_M"); got != nil {
+			t.Errorf("ModelOverrides() = %v for an unmatched model, want nil", got)
+		}
+	})
+}
+
+func TestCostRateForModel(t *testing.T) {
+	chat := &Chat{CostRates: map[string]CostRate{
+		"default":                    {Input: 3, Output: 15},
+		"Llama-3.2-3B-Instruct-GGUF": {Input: 0.1, Output: 0.1},
+		"bartowski/llama-3.2-3b-instruct-gguf:q8_0": {Input: 0.2, Output: 0.4},
+	}}
+
+	tests := []struct {
+		model string
+		want  CostRate
+	}{
+		{"bartowski/Llama-3.2-3B-Instruct-GGUF:Q8_0", CostRate{Input: 0.2, Output: 0.4}},
+		{"bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M", CostRate{Input: 0.1, Output: 0.1}},
+		{"other/Model-GGUF:Q4_K_M", CostRate{Input: 3, Output: 15}},
+	}
+	for _, tt := range tests {
+		if got, ok := chat.CostRateForModel(tt.model); !ok || got != tt.want {
+			t.Errorf("CostRateForModel(%q) = %v, %v, want %v", tt.model, got, ok, tt.want)
+		}
+	}
+
+	if _, ok := (&Chat{}).CostRateForModel("user/repo:Q4_K_M"); ok {
+		t.Error("CostRateForModel() with no rates reported a rate")
+	}
+
+	if got := (CostRate{Input: 3, Output: 15}).Cost(1000, 2000); got != 0.033 {
+		t.Errorf("Cost() = %v, want 0.033", got)
+	}
+}
+
+func TestModelPriorities(t *testing.T) {
+	priorities := ModelPriorities{
+		"default":                     1,
+		"Llama-3.3-70B-Instruct-GGUF": 10,
+		"nomic-ai/nomic-embed-text-v1.5-GGUF:f16": -5,
+	}
+
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"bartowski/Llama-3.3-70B-Instruct-GGUF:Q4_K_M", 10},
+		{"nomic-ai/nomic-embed-text-v1.5-GGUF:F16", -5},
+		{"nomic-ai/nomic-embed-text-v1.5-GGUF:Q8_0", 1},
+	}
+	for _, tt := range tests {
+		if got := priorities.For(tt.model); got != tt.want {
+			t.Errorf("For(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+
+	if got := ModelPriorities(nil).For("user/repo:Q4_K_M"); got != 0 {
+		t.Errorf("For() with no priorities = %d, want 0", got)
+	}
+}
+
 func TestEnsureDirectories(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
 	err := EnsureDirectories()
 	if err != nil {
 		t.Fatalf("Expected no error creating directories, got %v", err)
 	}
 
-	baseDir := filepath.Join(tmpDir, ".lleme")
+	baseDir := tmpDir
 
 	expectedDirs := []string{
 		baseDir,
@@ -265,40 +403,28 @@ func TestEnsureDirectories(t *testing.T) {
 	}
 }
 
-func TestPathHelpers(t *testing.T) {
-	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+func TestLoadPersonaChecksOptions(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 
-	configPath := ConfigPath()
-	expectedConfigPath := filepath.Join(tmpDir, ".lleme", "config.yaml")
-	if configPath != expectedConfigPath {
-		t.Errorf("Expected ConfigPath %s, got %s", expectedConfigPath, configPath)
+	good := &Persona{
+		Options:     map[string]any{"temp": 0.7, "ctx-size": 8192},
+		Passthrough: map[string]any{"some-new-flag": 1},
 	}
-
-	modelsPath := ModelsPath()
-	expectedModelsPath := filepath.Join(tmpDir, ".lleme", "models")
-	if modelsPath != expectedModelsPath {
-		t.Errorf("Expected ModelsPath %s, got %s", expectedModelsPath, modelsPath)
+	if err := SavePersonaTemplate("good", good); err != nil {
+		t.Fatal(err)
 	}
-
-	binPath := BinPath()
-	expectedBinPath := filepath.Join(tmpDir, ".lleme", "bin")
-	if binPath != expectedBinPath {
-		t.Errorf("Expected BinPath %s, got %s", expectedBinPath, binPath)
+	p, err := LoadPersona("good")
+	if err != nil {
+		t.Fatalf("LoadPersona() error = %v", err)
 	}
-
-	cachePath := CachePath()
-	expectedCachePath := filepath.Join(tmpDir, ".lleme", "cache")
-	if cachePath != expectedCachePath {
-		t.Errorf("Expected CachePath %s, got %s", expectedCachePath, cachePath)
+	if p.GetPassthrough()["some-new-flag"] != 1 || p.Options["ctx-size"] != 8192 {
+		t.Errorf("persona = %+v", p)
 	}
 
-	pidsPath := PidsPath()
-	expectedPidsPath := filepath.Join(tmpDir, ".lleme", "pids")
-	if pidsPath != expectedPidsPath {
-		t.Errorf("Expected PidsPath %s, got %s", expectedPidsPath, pidsPath)
+	if err := SavePersona("bad", &Persona{Options: map[string]any{"some-new-flag": 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPersona("bad"); err == nil || !strings.Contains(err.Error(), "passthrough") {
+		t.Errorf("LoadPersona() error = %v, want the unknown option reported", err)
 	}
 }
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"strings"
+)
+
+// FeaturesEnv lists experimental features to turn on for this process, on
+// top of the config's features, comma-separated. A name starting with "-"
+// turns off one the config turns on.
+const FeaturesEnv = "LLEME_FEATURES"
+
+// Feature is an experimental feature. New subsystems that aren't ready for
+// everyone ship behind one, off until a machine lists it in features or
+// LLEME_FEATURES, and are taken off this list once they're on for all.
+type Feature struct {
+	Name        string
+	Description string
+}
+
+// FeatureBackgroundJobs turns on background jobs: run --detach, lleme jobs
+// and /api/jobs on the server
+const FeatureBackgroundJobs = "background-jobs"
+
+// experimentalFeatures are the features this build knows. To add one, list
+// it here and check Config.FeatureEnabled where it's used.
+var experimentalFeatures = []Feature{
+	{Name: FeatureBackgroundJobs, Description: "Background jobs from run --detach and /api/jobs"},
+}
+
+// ExperimentalFeatures returns the experimental features this build knows
+func ExperimentalFeatures() []Feature {
+	return slices.Clone(experimentalFeatures)
+}
+
+// LookupFeature returns the experimental feature called name
+func LookupFeature(name string) (Feature, bool) {
+	for _, f := range experimentalFeatures {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Feature{}, false
+}
+
+// FeatureEnabled reports whether the named experimental feature is on,
+// from the config's features or LLEME_FEATURES, which wins. A nil config
+// only reads LLEME_FEATURES.
+func (c *Config) FeatureEnabled(name string) bool {
+	return slices.Contains(c.EnabledFeatures(), name)
+}
+
+// EnabledFeatures returns the names turned on in the config and
+// LLEME_FEATURES, sorted, including any this build doesn't know
+func (c *Config) EnabledFeatures() []string {
+	on := make(map[string]bool)
+	if c != nil {
+		for _, name := range c.Features {
+			on[normalizeFeature(name)] = true
+		}
+	}
+	for name := range strings.SplitSeq(os.Getenv(FeaturesEnv), ",") {
+		name = normalizeFeature(name)
+		if off, ok := strings.CutPrefix(name, "-"); ok {
+			on[off] = false
+		} else {
+			on[name] = true
+		}
+	}
+
+	var names []string
+	for name, enabled := range on {
+		if enabled && name != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// UnknownFeatures returns the enabled names this build doesn't know, like
+// typos or features since removed, which do nothing
+func (c *Config) UnknownFeatures() []string {
+	var unknown []string
+	for _, name := range c.EnabledFeatures() {
+		if _, ok := LookupFeature(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// normalizeFeature lowercases a name and accepts _ for -, like option names
+func normalizeFeature(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if rest, ok := strings.CutPrefix(name, "-"); ok {
+		return "-" + strings.ReplaceAll(rest, "_", "-")
+	}
+	return strings.ReplaceAll(name, "_", "-")
+}
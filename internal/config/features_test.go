@@ -0,0 +1,74 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+// useFeatures replaces the features this build knows for a test
+func useFeatures(t *testing.T, features ...Feature) {
+	t.Helper()
+	orig := experimentalFeatures
+	experimentalFeatures = features
+	t.Cleanup(func() { experimentalFeatures = orig })
+}
+
+func TestFeatureEnabled(t *testing.T) {
+	useFeatures(t,
+		Feature{Name: "batch-api", Description: "Batch API"},
+		Feature{Name: "swarm-pull", Description: "Download from several peers at once"},
+	)
+
+	cfg := &Config{Features: []string{"batch-api"}}
+	t.Setenv(FeaturesEnv, "")
+	if !cfg.FeatureEnabled("batch-api") || cfg.FeatureEnabled("swarm-pull") {
+		t.Errorf("EnabledFeatures() = %v, want only batch-api", cfg.EnabledFeatures())
+	}
+
+	// The environment adds to the config, and "-name" turns one off
+	t.Setenv(FeaturesEnv, " Swarm_Pull , -batch-api")
+	if cfg.FeatureEnabled("batch-api") || !cfg.FeatureEnabled("swarm-pull") {
+		t.Errorf("EnabledFeatures() = %v, want only swarm-pull", cfg.EnabledFeatures())
+	}
+
+	// Without a config, only the environment counts
+	var none *Config
+	if !none.FeatureEnabled("swarm-pull") || none.FeatureEnabled("batch-api") {
+		t.Errorf("nil config EnabledFeatures() = %v", none.EnabledFeatures())
+	}
+}
+
+func TestUnknownFeatures(t *testing.T) {
+	useFeatures(t, Feature{Name: "batch-api"})
+	t.Setenv(FeaturesEnv, "zeta,typo-feature")
+
+	cfg := &Config{Features: []string{"batch-api", "old-feature"}}
+	if got, want := cfg.EnabledFeatures(), []string{"batch-api", "old-feature", "typo-feature", "zeta"}; !slices.Equal(got, want) {
+		t.Errorf("EnabledFeatures() = %v, want %v", got, want)
+	}
+	if got, want := cfg.UnknownFeatures(), []string{"old-feature", "typo-feature", "zeta"}; !slices.Equal(got, want) {
+		t.Errorf("UnknownFeatures() = %v, want %v", got, want)
+	}
+	if _, ok := LookupFeature("batch-api"); !ok {
+		t.Error("LookupFeature() didn't find a known feature")
+	}
+}
+
+func TestLoadFeatures(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	t.Setenv(FeaturesEnv, "")
+	useFeatures(t, Feature{Name: "batch-api"})
+
+	cfg := DefaultConfig()
+	cfg.Features = []string{"batch-api"}
+	if err := Save(cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.FeatureEnabled("batch-api") {
+		t.Errorf("Features = %v after loading", loaded.Features)
+	}
+}
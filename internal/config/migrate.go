@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config.yaml layout this build reads and writes.
+// Bump it and add a migration whenever a key is renamed or moved, so
+// settings in older files carry over instead of being silently dropped.
+const CurrentVersion = 2
+
+// migration upgrades a config document from version-1 to version. It edits
+// the YAML tree directly so comments and unrelated keys are kept.
+type migration struct {
+	version int
+	apply   func(root *yaml.Node) error
+}
+
+// migrations must be in version order. Files without a version field are
+// version 0.
+var migrations = []migration{
+	{1, migrateIdleTimeout},
+	{2, migratePassthrough},
+}
+
+// migrateFile upgrades the config file's contents to CurrentVersion. When
+// anything changes, the original is kept next to it as config.yaml.v<N>.bak
+// and the migrated file is written in its place.
+func migrateFile(path string, data []byte) ([]byte, error) {
+	migrated, from, err := migrate(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate config: %w", err)
+	}
+	if from >= CurrentVersion {
+		return data, nil
+	}
+
+	// A read-only config still loads with the migrated settings; it's just
+	// migrated again next time
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backupPath, data, 0644); err == nil {
+		fileutil.AtomicWriteFile(path, migrated, 0644)
+	}
+	return migrated, nil
+}
+
+// migrate applies the migrations newer than the document's version and
+// returns the upgraded document along with the version it started at.
+// Documents from a newer lleme are returned unchanged.
+func migrate(data []byte) ([]byte, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return data, CurrentVersion, nil
+	}
+	root := doc.Content[0]
+
+	from := 0
+	if v := mappingValue(root, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if err != nil || n < 0 {
+			return nil, 0, fmt.Errorf("invalid version %q", v.Value)
+		}
+		from = n
+	}
+	if from >= CurrentVersion {
+		return data, from, nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= from {
+			continue
+		}
+		if err := m.apply(root); err != nil {
+			return nil, 0, fmt.Errorf("version %d: %w", m.version, err)
+		}
+	}
+	setVersion(root, CurrentVersion)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, 0, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), from, nil
+}
+
+// migrateIdleTimeout converts server.idle_timeout, a duration like "10m"
+// that was documented but never read, to server.idle_timeout_mins.
+func migrateIdleTimeout(root *yaml.Node) error {
+	server := mappingValue(root, "server")
+	if server == nil || server.Kind != yaml.MappingNode {
+		return nil
+	}
+	old := mappingValue(server, "idle_timeout")
+	if old == nil {
+		return nil
+	}
+
+	if mappingValue(server, "idle_timeout_mins") == nil {
+		var mins int
+		if d, err := time.ParseDuration(old.Value); err == nil {
+			mins = int(math.Ceil(d.Minutes()))
+		} else if n, err := strconv.Atoi(old.Value); err == nil {
+			mins = n
+		} else {
+			return fmt.Errorf("server.idle_timeout: invalid duration %q", old.Value)
+		}
+		setMappingValue(server, "idle_timeout_mins", &yaml.Node{
+			Kind:        yaml.ScalarNode,
+			Tag:         "!!int",
+			Value:       strconv.Itoa(mins),
+			LineComment: old.LineComment,
+		})
+	}
+	deleteMappingKey(server, "idle_timeout")
+	return nil
+}
+
+// migratePassthrough moves llamacpp.options that aren't in the option
+// catalog to llamacpp.passthrough, where they're still passed to
+// llama-server but no longer checked.
+func migratePassthrough(root *yaml.Node) error {
+	llama := mappingValue(root, "llamacpp")
+	if llama == nil || llama.Kind != yaml.MappingNode {
+		return nil
+	}
+	opts := mappingValue(llama, "options")
+	if opts == nil || opts.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var unknown []*yaml.Node
+	for i := 0; i+1 < len(opts.Content); {
+		if _, ok := LookupServerOption(opts.Content[i].Value); ok {
+			i += 2
+			continue
+		}
+		unknown = append(unknown, opts.Content[i], opts.Content[i+1])
+		opts.Content = append(opts.Content[:i], opts.Content[i+2:]...)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	passthrough := mappingValue(llama, "passthrough")
+	if passthrough == nil || passthrough.Kind != yaml.MappingNode {
+		passthrough = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(llama, "passthrough", passthrough)
+	}
+	for i := 0; i < len(unknown); i += 2 {
+		// A key already in passthrough keeps its value there
+		if mappingValue(passthrough, unknown[i].Value) == nil {
+			passthrough.Content = append(passthrough.Content, unknown[i], unknown[i+1])
+		}
+	}
+	return nil
+}
+
+// setVersion sets the top-level version key, adding it first if missing
+func setVersion(root *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if v := mappingValue(root, "version"); v != nil {
+		*v = *value
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/paths"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantFrom int
+		want     []string // Substrings of the migrated file
+		notWant  []string
+		wantErr  bool
+	}{
+		{
+			name:     "adds version to unversioned file",
+			input:    "server:\n  port: 9000\n",
+			wantFrom: 0,
+			want:     []string{"version: 2\n", "server:\n  port: 9000\n"},
+		},
+		{
+			name:     "converts idle_timeout duration",
+			input:    "# My settings\nserver:\n  idle_timeout: 90s # unload faster\n",
+			wantFrom: 0,
+			want:     []string{"# My settings", "idle_timeout_mins: 2 # unload faster"},
+			notWant:  []string{"idle_timeout:"},
+		},
+		{
+			name:     "keeps explicit idle_timeout_mins",
+			input:    "server:\n  idle_timeout: 1h\n  idle_timeout_mins: 5\n",
+			wantFrom: 0,
+			want:     []string{"idle_timeout_mins: 5"},
+			notWant:  []string{"idle_timeout:", "60"},
+		},
+		{
+			name:    "rejects bad idle_timeout",
+			input:   "server:\n  idle_timeout: soon\n",
+			wantErr: true,
+		},
+		{
+			name:    "rejects bad version",
+			input:   "version: latest\n",
+			wantErr: true,
+		},
+		{
+			name:     "skips applied migrations",
+			input:    "version: 1\nserver:\n  idle_timeout: 10m\n",
+			wantFrom: 1,
+			want:     []string{"version: 2\n", "idle_timeout: 10m"},
+		},
+		{
+			name:     "moves unknown options to passthrough",
+			input:    "version: 1\nllamacpp:\n  options:\n    ctx-size: 8192\n    some-new-flag: 4 # from the changelog\n    temp: 0.7\n",
+			wantFrom: 1,
+			want:     []string{"  options:\n    ctx-size: 8192\n    temp: 0.7\n", "  passthrough:\n    some-new-flag: 4 # from the changelog\n"},
+		},
+		{
+			name:     "keeps existing passthrough values",
+			input:    "version: 1\nllamacpp:\n  options:\n    custom: 1\n  passthrough:\n    custom: 2\n",
+			wantFrom: 1,
+			want:     []string{"passthrough:\n    custom: 2\n"},
+			notWant:  []string{"custom: 1"},
+		},
+		{
+			name:     "leaves known options alone",
+			input:    "version: 1\nllamacpp:\n  options:\n    ctx_size: 8192\n",
+			wantFrom: 1,
+			want:     []string{"ctx_size: 8192"},
+			notWant:  []string{"passthrough"},
+		},
+		{
+			name:     "leaves current file alone",
+			input:    "version: 2\nserver:\n  idle_timeout: 10m\n",
+			wantFrom: 2,
+			want:     []string{"idle_timeout: 10m"},
+		},
+		{
+			name:     "leaves newer file alone",
+			input:    "version: 99\nfuture: true\n",
+			wantFrom: 99,
+			want:     []string{"version: 99\nfuture: true\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, from, err := migrate([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("migrate() should fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrate() error = %v", err)
+			}
+			if from != tt.wantFrom {
+				t.Errorf("from = %d, want %d", from, tt.wantFrom)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(string(out), s) {
+					t.Errorf("migrated file missing %q:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(string(out), s) {
+					t.Errorf("migrated file contains %q:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadMigratesFile(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	old := "server:\n  port: 9000\n  idle_timeout: 30m\n"
+	if err := os.WriteFile(paths.Config(), []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.IdleTimeoutMins != 30 || cfg.Server.Port != 9000 {
+		t.Errorf("Load() idle = %d, port = %d, want 30 and 9000", cfg.Server.IdleTimeoutMins, cfg.Server.Port)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+
+	backup, err := os.ReadFile(filepath.Join(paths.ConfigDir(), "config.yaml.v0.bak"))
+	if err != nil || string(backup) != old {
+		t.Errorf("backup = %q, %v; want the original file", backup, err)
+	}
+	data, _ := os.ReadFile(paths.Config())
+	if !strings.Contains(string(data), "idle_timeout_mins: 30") {
+		t.Errorf("config.yaml was not rewritten:\n%s", data)
+	}
+
+	// Loading again is a no-op
+	os.Remove(filepath.Join(paths.ConfigDir(), "config.yaml.v0.bak"))
+	if _, err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.ConfigDir(), "config.yaml.v0.bak")); !os.IsNotExist(err) {
+		t.Error("an up-to-date config should not be backed up again")
+	}
+}
+
+func TestDefaultConfigTemplateVersion(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(DefaultConfigTemplate), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("DefaultConfigTemplate version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+}
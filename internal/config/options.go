@@ -0,0 +1,251 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OptionType is the kind of value a llama-server option takes.
+type OptionType int
+
+const (
+	OptionBool   OptionType = iota // true or false
+	OptionInt                      // A whole number
+	OptionFloat                    // Any number
+	OptionString                   // Free text, or one of Values
+	OptionList                     // A string, or a list for flags passed once per value
+)
+
+// ServerOption describes a llama-server flag lleme knows how to check.
+type ServerOption struct {
+	Name        string
+	Type        OptionType
+	Min, Max    *float64                         // Bounds for numbers, inclusive; nil for none
+	Values      []string                         // Words accepted; the only values for strings, or as well as the type's
+	Check       func(name string, val any) error // Extra check, after the type's
+	Restart     bool                             // Read at load, so changing it restarts the backend
+	Path        bool                             // Takes a path on the server's machine, so only config can set it
+	Description string
+}
+
+// ServerOptionKeys lists llama-server options that affect model loading.
+// Changing any of these requires the backend to be restarted.
+var ServerOptionKeys = restartOptionKeys()
+
+// RopeScalingTypes are the values accepted by llama-server's --rope-scaling.
+var RopeScalingTypes = []string{"none", "linear", "yarn"}
+
+// overrideKVTypes are the value types accepted by llama-server's --override-kv.
+var overrideKVTypes = []string{"int", "float", "bool", "str"}
+
+// ServerOptions returns the catalog of llama-server options lleme accepts.
+func ServerOptions() []ServerOption {
+	return serverOptions
+}
+
+// LookupServerOption returns the catalog entry for key, which may use
+// underscores or hyphens.
+func LookupServerOption(key string) (ServerOption, bool) {
+	key = strings.ReplaceAll(key, "_", "-")
+	for _, opt := range serverOptions {
+		if opt.Name == key {
+			return opt, true
+		}
+	}
+	return ServerOption{}, false
+}
+
+func restartOptionKeys() []string {
+	var keys []string
+	for _, opt := range serverOptions {
+		if opt.Restart {
+			keys = append(keys, opt.Name)
+		}
+	}
+	return keys
+}
+
+// ValidateServerOptions checks llama-server options against the catalog.
+// Options it doesn't know are rejected; they belong under passthrough.
+func ValidateServerOptions(opts map[string]any) error {
+	for _, key := range slices.Sorted(maps.Keys(opts)) {
+		if err := ValidateServerOption(key, opts[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateServerOption checks a single llama-server option's value.
+func ValidateServerOption(key string, val any) error {
+	opt, ok := LookupServerOption(key)
+	if !ok {
+		return fmt.Errorf("unknown llama-server option %q (put it under passthrough to pass it unchecked)", key)
+	}
+	if !opt.accepts(val) {
+		return fmt.Errorf("invalid %s %v: must be %s", opt.Name, val, opt.describe())
+	}
+	if opt.Check != nil {
+		return opt.Check(opt.Name, val)
+	}
+	return nil
+}
+
+// accepts reports whether val has the option's type and is in range.
+func (o ServerOption) accepts(val any) bool {
+	if s, ok := val.(string); ok && slices.Contains(o.Values, s) {
+		return true
+	}
+	switch o.Type {
+	case OptionBool:
+		_, ok := val.(bool)
+		return ok
+	case OptionInt, OptionFloat:
+		f, ok := numericValue(val)
+		if !ok || (o.Type == OptionInt && f != math.Trunc(f)) {
+			return false
+		}
+		return (o.Min == nil || f >= *o.Min) && (o.Max == nil || f <= *o.Max)
+	case OptionString:
+		_, ok := val.(string)
+		return ok && len(o.Values) == 0
+	case OptionList:
+		_, ok := StringList(val)
+		return ok
+	}
+	return false
+}
+
+// describe says what values the option accepts, for error messages.
+func (o ServerOption) describe() string {
+	var want string
+	switch o.Type {
+	case OptionBool:
+		want = "true or false"
+	case OptionInt, OptionFloat:
+		noun := "a number"
+		if o.Type == OptionInt {
+			noun = "an integer"
+		}
+		switch {
+		case o.Min != nil && o.Max != nil:
+			want = fmt.Sprintf("%s from %g to %g", noun, *o.Min, *o.Max)
+		case o.Min != nil && *o.Min == 0 && o.Type == OptionInt:
+			want = "a non-negative integer"
+		case o.Min != nil:
+			want = fmt.Sprintf("%s >= %g", noun, *o.Min)
+		default:
+			want = noun
+		}
+	case OptionString:
+		if len(o.Values) > 0 {
+			return "one of " + strings.Join(o.Values, ", ")
+		}
+		want = "a string"
+	case OptionList:
+		want = "a string or list of strings"
+	}
+	if len(o.Values) > 0 {
+		want += " or one of " + strings.Join(o.Values, ", ")
+	}
+	return want
+}
+
+// Parse reads a value for the option typed on the command line. Text that
+// isn't of the option's type is returned as a string, for validation to
+// reject or accept as one of Values.
+func (o ServerOption) Parse(s string) any {
+	switch o.Type {
+	case OptionBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case OptionInt:
+		if i, err := strconv.Atoi(s); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f // Rejected as fractional rather than as text
+		}
+	case OptionFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+func bound(f float64) *float64 {
+	return &f
+}
+
+func positive(name string, val any) error {
+	if f, _ := numericValue(val); f <= 0 {
+		return fmt.Errorf("invalid %s %v: must be a positive number", name, val)
+	}
+	return nil
+}
+
+func checkOverrideKV(_ string, val any) error {
+	overrides, _ := StringList(val)
+	for _, o := range overrides {
+		if err := ValidateOverrideKV(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateOverrideKV checks a single --override-kv value in KEY=TYPE:VALUE form.
+func ValidateOverrideKV(s string) error {
+	key, typed, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid override-kv %q: expected KEY=TYPE:VALUE", s)
+	}
+	typ, _, ok := strings.Cut(typed, ":")
+	if !ok {
+		return fmt.Errorf("invalid override-kv %q: expected KEY=TYPE:VALUE", s)
+	}
+	if !slices.Contains(overrideKVTypes, typ) {
+		return fmt.Errorf("invalid override-kv %q: type must be one of %s", s, strings.Join(overrideKVTypes, ", "))
+	}
+	return nil
+}
+
+// StringList returns val as a list of strings. A single string is treated as a
+// one-element list so repeatable options can be written either way in YAML.
+func StringList(val any) ([]string, bool) {
+	switch v := val.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case []any:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, s)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func numericValue(val any) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
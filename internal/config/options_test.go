@@ -0,0 +1,182 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestValidateServerOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    map[string]any
+		wantErr bool
+	}{
+		{"nil options", nil, false},
+		{"unknown option", map[string]any{"custom": "anything"}, true},
+		{"underscore key", map[string]any{"ctx_size": 8192}, false},
+		{"fractional ctx size", map[string]any{"ctx-size": 8192.5}, true},
+		{"whole float ctx size", map[string]any{"ctx-size": float64(8192)}, false},
+		{"negative ctx size", map[string]any{"ctx-size": -1}, true},
+		{"string ctx size", map[string]any{"ctx-size": "8192"}, true},
+		{"gpu layers all", map[string]any{"gpu-layers": -1}, false},
+		{"gpu layers word", map[string]any{"gpu-layers": "auto"}, false},
+		{"gpu layers unknown word", map[string]any{"gpu-layers": "most"}, true},
+		{"flash attn bool", map[string]any{"flash-attn": true}, false},
+		{"flash attn word", map[string]any{"flash-attn": "on"}, false},
+		{"flash attn unknown word", map[string]any{"flash-attn": "yes"}, true},
+		{"valid cache type", map[string]any{"cache-type-k": "q8_0"}, false},
+		{"invalid cache type", map[string]any{"cache-type-v": "q3_k"}, true},
+		{"free text", map[string]any{"tensor-split": "3,1"}, false},
+		{"valid top p", map[string]any{"top-p": 0.9}, false},
+		{"top p above range", map[string]any{"top-p": 1.5}, true},
+		{"integer temp", map[string]any{"temp": 1}, false},
+		{"negative temp", map[string]any{"temp": -0.5}, true},
+		{"mirostat out of range", map[string]any{"mirostat": 3}, true},
+		{"lora list", map[string]any{"lora": []any{"a.gguf", "b.gguf"}}, false},
+		{"valid rope scaling", map[string]any{"rope-scaling": "yarn"}, false},
+		{"invalid rope scaling", map[string]any{"rope-scaling": "cubic"}, true},
+		{"non-string rope scaling", map[string]any{"rope-scaling": 1}, true},
+		{"valid rope freq base", map[string]any{"rope-freq-base": float64(1000000)}, false},
+		{"zero rope freq scale", map[string]any{"rope-freq-scale": 0}, true},
+		{"negative rope freq base", map[string]any{"rope-freq-base": -1.0}, true},
+		{"valid yarn orig ctx", map[string]any{"yarn-orig-ctx": 32768}, false},
+		{"fractional yarn orig ctx", map[string]any{"yarn-orig-ctx": 1.5}, true},
+		{"negative yarn ext factor", map[string]any{"yarn-ext-factor": -1.0}, false},
+		{"string yarn attn factor", map[string]any{"yarn-attn-factor": "high"}, true},
+		{"single override", map[string]any{"override-kv": "llama.context_length=int:65536"}, false},
+		{"override list", map[string]any{"override-kv": []any{"a=int:1", "b=bool:false"}}, false},
+		{"override string slice", map[string]any{"override-kv": []string{"a=str:x"}}, false},
+		{"override missing type", map[string]any{"override-kv": "a=1"}, true},
+		{"override bad type", map[string]any{"override-kv": "a=double:1.0"}, true},
+		{"override missing key", map[string]any{"override-kv": "=int:1"}, true},
+		{"override non-string item", map[string]any{"override-kv": []any{"a=int:1", 2}}, true},
+		{"context shift bool", map[string]any{"context-shift": true}, false},
+		{"context shift string", map[string]any{"context-shift": "yes"}, true},
+		{"valid cache reuse", map[string]any{"cache-reuse": float64(256)}, false},
+		{"negative cache reuse", map[string]any{"cache-reuse": -1}, true},
+		{"keep all", map[string]any{"keep": -1}, false},
+		{"keep below -1", map[string]any{"keep": -2}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateServerOptions(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateServerOptions(%v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerOptionKeys(t *testing.T) {
+	for _, key := range []string{"ctx-size", "gpu-layers", "threads", "rope-scaling", "override-kv", "cache-reuse"} {
+		if !slices.Contains(ServerOptionKeys, key) {
+			t.Errorf("ServerOptionKeys is missing %s", key)
+		}
+	}
+	for _, key := range []string{"temp", "top-p", "seed"} {
+		if slices.Contains(ServerOptionKeys, key) {
+			t.Errorf("ServerOptionKeys has sampling option %s", key)
+		}
+	}
+}
+
+func TestServerOptionCatalog(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, opt := range ServerOptions() {
+		if seen[opt.Name] {
+			t.Errorf("%s is listed twice", opt.Name)
+		}
+		seen[opt.Name] = true
+		if opt.Description == "" {
+			t.Errorf("%s has no description", opt.Name)
+		}
+		if opt.Type == OptionString && len(opt.Values) > 0 {
+			for _, v := range opt.Values {
+				if err := ValidateServerOption(opt.Name, v); err != nil {
+					t.Errorf("%s rejects its own value %q: %v", opt.Name, v, err)
+				}
+			}
+		}
+	}
+}
+
+func TestLookupServerOption(t *testing.T) {
+	if opt, ok := LookupServerOption("cache_type_k"); !ok || opt.Name != "cache-type-k" {
+		t.Errorf("LookupServerOption(cache_type_k) = %v, %v", opt.Name, ok)
+	}
+	if _, ok := LookupServerOption("model"); ok {
+		t.Error("LookupServerOption(model) found a flag lleme sets itself")
+	}
+}
+
+func TestServerOptionParse(t *testing.T) {
+	tests := []struct {
+		key   string
+		input string
+		want  any
+	}{
+		{"ctx-size", "8192", 8192},
+		{"ctx-size", "1.5", 1.5},
+		{"gpu-layers", "auto", "auto"},
+		{"temp", "0.7", 0.7},
+		{"temp", "1", float64(1)},
+		{"mlock", "true", true},
+		{"flash-attn", "on", "on"},
+		{"cache-type-k", "q8_0", "q8_0"},
+	}
+	for _, tt := range tests {
+		opt, _ := LookupServerOption(tt.key)
+		if got := opt.Parse(tt.input); got != tt.want {
+			t.Errorf("%s.Parse(%q) = %v (%T), want %v (%T)", tt.key, tt.input, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+func TestValidateServerOptionMessage(t *testing.T) {
+	tests := []struct {
+		key  string
+		val  any
+		want string
+	}{
+		{"gpu-layers", "most", "invalid gpu-layers most: must be an integer >= -1 or one of auto, all"},
+		{"top-p", 2.0, "invalid top-p 2: must be a number from 0 to 1"},
+		{"cache-reuse", -1, "invalid cache-reuse -1: must be a non-negative integer"},
+		{"split-mode", "cols", "invalid split-mode cols: must be one of none, layer, row"},
+		{"rope-scale", 0, "invalid rope-scale 0: must be a positive number"},
+	}
+	for _, tt := range tests {
+		err := ValidateServerOption(tt.key, tt.val)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("ValidateServerOption(%s, %v) = %v, want %q", tt.key, tt.val, err, tt.want)
+		}
+	}
+}
+
+func TestStringList(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  any
+		want   []string
+		wantOk bool
+	}{
+		{"string", "a", []string{"a"}, true},
+		{"string slice", []string{"a", "b"}, []string{"a", "b"}, true},
+		{"any slice", []any{"a", "b"}, []string{"a", "b"}, true},
+		{"mixed slice", []any{"a", 1}, nil, false},
+		{"int", 1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := StringList(tt.input)
+			if ok != tt.wantOk {
+				t.Fatalf("StringList(%v) ok = %v, want %v", tt.input, ok, tt.wantOk)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StringList(%v) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,14 +6,49 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/nchapman/lleme/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
 // Persona represents a saved model configuration with optional system prompt and options.
 type Persona struct {
-	Model   string         `yaml:"model,omitempty"`
-	System  string         `yaml:"system,omitempty"`
-	Options map[string]any `yaml:"options,omitempty"`
+	Model     string         `yaml:"model,omitempty"`
+	System    string         `yaml:"system,omitempty"`
+	RespondIn string         `yaml:"respond_in,omitempty"` // Language code replies should be in, like de
+	Options   map[string]any `yaml:"options,omitempty"`
+	Filters   *OutputFilters `yaml:"filters,omitempty"`
+
+	// Passthrough holds llama-server options passed on without checking,
+	// for flags the option catalog doesn't know
+	Passthrough map[string]any `yaml:"passthrough,omitempty"`
+}
+
+// OutputFilters post-process a persona's responses before they're displayed.
+// They run in the order the fields are declared.
+type OutputFilters struct {
+	StripThink  bool            `yaml:"strip_think,omitempty"`  // Remove <think>...</think> blocks
+	TrimLeading bool            `yaml:"trim_leading,omitempty"` // Remove leading whitespace
+	Replace     []ReplaceFilter `yaml:"replace,omitempty"`      // Regex replacements, applied per line
+	MaxLength   int             `yaml:"max_length,omitempty"`   // Truncate after this many characters
+}
+
+// ReplaceFilter replaces matches of a regular expression. With may reference
+// capture groups as $1, ${name}, etc.
+type ReplaceFilter struct {
+	Pattern string `yaml:"pattern"`
+	With    string `yaml:"with"`
+}
+
+// ResponseLanguage returns the language code replies should be in: the
+// persona's, or else the one in chat config.
+func (p *Persona) ResponseLanguage(cfg *Config) string {
+	if p != nil && p.RespondIn != "" {
+		return p.RespondIn
+	}
+	if cfg != nil {
+		return cfg.Chat.RespondIn
+	}
+	return ""
 }
 
 // GetFloatOption returns a float option from the persona, with a default if not set.
@@ -55,15 +90,8 @@ func (p *Persona) GetServerOptions() map[string]any {
 		return nil
 	}
 
-	// Server options that affect model loading
-	serverOptionKeys := []string{
-		"ctx-size", "gpu-layers", "threads",
-		"batch-size", "ubatch-size", "flash-attn",
-		"mlock", "cache-type-k", "cache-type-v",
-	}
-
 	result := make(map[string]any)
-	for _, key := range serverOptionKeys {
+	for _, key := range ServerOptionKeys {
 		if val, ok := p.Options[key]; ok {
 			result[key] = val
 		}
@@ -75,33 +103,40 @@ func (p *Persona) GetServerOptions() map[string]any {
 	return result
 }
 
-const personasDir = "personas"
+// GetPassthrough returns the persona's unchecked llama-server options.
+func (p *Persona) GetPassthrough() map[string]any {
+	if p == nil || len(p.Passthrough) == 0 {
+		return nil
+	}
+	return p.Passthrough
+}
 
 // ValidatePersonaName checks if a persona name is valid for use as a filename.
 func ValidatePersonaName(name string) error {
+	return validateName("persona", name)
+}
+
+// validateName checks that a name of the given kind, such as "persona",
+// is safe to use as a filename.
+func validateName(kind, name string) error {
 	if name == "" {
-		return fmt.Errorf("persona name cannot be empty")
+		return fmt.Errorf("%s name cannot be empty", kind)
 	}
 	if strings.ContainsAny(name, `/\:*?"<>|`) {
-		return fmt.Errorf("persona name contains invalid characters")
+		return fmt.Errorf("%s name contains invalid characters", kind)
 	}
 	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
-		return fmt.Errorf("persona name cannot start with '.' or '-'")
+		return fmt.Errorf("%s name cannot start with '.' or '-'", kind)
 	}
 	if strings.Contains(name, "..") {
-		return fmt.Errorf("persona name cannot contain '..'")
+		return fmt.Errorf("%s name cannot contain '..'", kind)
 	}
 	return nil
 }
 
-// PersonasPath returns the path to the personas directory.
-func PersonasPath() string {
-	return filepath.Join(BaseDir(), personasDir)
-}
-
 // PersonaPath returns the path to a specific persona file.
 func PersonaPath(name string) string {
-	return filepath.Join(PersonasPath(), name+".yaml")
+	return filepath.Join(paths.Personas(), name+".yaml")
 }
 
 // LoadPersona loads a persona by name.
@@ -119,13 +154,16 @@ func LoadPersona(name string) (*Persona, error) {
 	if err := yaml.Unmarshal(data, &persona); err != nil {
 		return nil, fmt.Errorf("failed to parse persona: %w", err)
 	}
+	if err := ValidateServerOptions(persona.Options); err != nil {
+		return nil, fmt.Errorf("invalid options in persona '%s': %w", name, err)
+	}
 
 	return &persona, nil
 }
 
 // SavePersona saves a persona to disk.
 func SavePersona(name string, persona *Persona) error {
-	if err := os.MkdirAll(PersonasPath(), 0755); err != nil {
+	if err := os.MkdirAll(paths.Personas(), 0755); err != nil {
 		return fmt.Errorf("failed to create personas directory: %w", err)
 	}
 
@@ -144,7 +182,7 @@ func SavePersona(name string, persona *Persona) error {
 
 // SavePersonaTemplate saves a persona with helpful comments.
 func SavePersonaTemplate(name string, persona *Persona) error {
-	if err := os.MkdirAll(PersonasPath(), 0755); err != nil {
+	if err := os.MkdirAll(paths.Personas(), 0755); err != nil {
 		return fmt.Errorf("failed to create personas directory: %w", err)
 	}
 
@@ -172,6 +210,13 @@ func SavePersonaTemplate(name string, persona *Persona) error {
 		b.WriteString("#   You are a helpful assistant.\n\n")
 	}
 
+	if persona.RespondIn != "" {
+		b.WriteString("respond_in: " + persona.RespondIn + "\n\n")
+	} else {
+		b.WriteString("# Language to always reply in, checked as replies stream\n")
+		b.WriteString("# respond_in: de\n\n")
+	}
+
 	b.WriteString("# llama.cpp options (same as config llamacpp.options)\n")
 	b.WriteString("# options:\n")
 	b.WriteString("#   temp: 0.8\n")
@@ -180,18 +225,31 @@ func SavePersonaTemplate(name string, persona *Persona) error {
 	b.WriteString("#   repeat-penalty: 1.0\n")
 
 	if len(persona.Options) > 0 {
-		b.WriteString("\noptions:\n")
-		optData, err := yaml.Marshal(persona.Options)
-		if err != nil {
-			return fmt.Errorf("failed to marshal options: %w", err)
+		b.WriteString("\n")
+		if err := writeYAMLSection(&b, "options", persona.Options); err != nil {
+			return err
 		}
-		for line := range strings.SplitSeq(string(optData), "\n") {
-			if line != "" {
-				b.WriteString("  " + line + "\n")
-			}
+	}
+
+	b.WriteString("\n# llama-server flags lleme doesn't know, passed along unchecked\n")
+	if len(persona.Passthrough) > 0 {
+		if err := writeYAMLSection(&b, "passthrough", persona.Passthrough); err != nil {
+			return err
 		}
+	} else {
+		b.WriteString("# passthrough:\n")
+		b.WriteString("#   some-new-flag: 1\n")
 	}
 
+	b.WriteString("\n# Response filters, applied before display\n")
+	b.WriteString("# filters:\n")
+	b.WriteString("#   strip_think: true      # Remove leaked <think>...</think> blocks\n")
+	b.WriteString("#   trim_leading: true     # Remove leading whitespace\n")
+	b.WriteString("#   max_length: 2000       # Truncate after this many characters\n")
+	b.WriteString("#   replace:               # Regex replacements, applied per line\n")
+	b.WriteString("#     - pattern: \"^Assistant: \"\n")
+	b.WriteString("#       with: \"\"\n")
+
 	path := PersonaPath(name)
 	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
 		return fmt.Errorf("failed to write persona: %w", err)
@@ -200,6 +258,21 @@ func SavePersonaTemplate(name string, persona *Persona) error {
 	return nil
 }
 
+// writeYAMLSection writes m under key, indented for a persona file.
+func writeYAMLSection(b *strings.Builder, key string, m map[string]any) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	b.WriteString(key + ":\n")
+	for line := range strings.SplitSeq(string(data), "\n") {
+		if line != "" {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	return nil
+}
+
 // DeletePersona removes a persona by name.
 func DeletePersona(name string) error {
 	path := PersonaPath(name)
@@ -222,7 +295,7 @@ type PersonaInfo struct {
 
 // ListPersonas returns all available personas.
 func ListPersonas() ([]PersonaInfo, error) {
-	dir := PersonasPath()
+	dir := paths.Personas()
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// PromptRefPrefix marks a system prompt that names a saved prompt, as in
+// --system @reviewer. A doubled prefix escapes a literal "@".
+const PromptRefPrefix = "@"
+
+// ValidatePromptName checks if a prompt name is valid for use as a filename.
+func ValidatePromptName(name string) error {
+	return validateName("prompt", name)
+}
+
+// PromptPath returns the path to a specific prompt file.
+func PromptPath(name string) string {
+	return filepath.Join(paths.Prompts(), name+".md")
+}
+
+// LoadPrompt loads a saved system prompt by name.
+func LoadPrompt(name string) (string, error) {
+	if err := ValidatePromptName(name); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(PromptPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("prompt '%s' not found", name)
+		}
+		return "", fmt.Errorf("failed to read prompt: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SavePrompt saves a system prompt to disk.
+func SavePrompt(name, text string) error {
+	if err := os.MkdirAll(paths.Prompts(), 0755); err != nil {
+		return fmt.Errorf("failed to create prompts directory: %w", err)
+	}
+	text = strings.TrimSpace(text) + "\n"
+	if err := os.WriteFile(PromptPath(name), []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write prompt: %w", err)
+	}
+	return nil
+}
+
+// DeletePrompt removes a saved prompt by name.
+func DeletePrompt(name string) error {
+	if err := os.Remove(PromptPath(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("prompt '%s' not found", name)
+		}
+		return fmt.Errorf("failed to delete prompt: %w", err)
+	}
+	return nil
+}
+
+// PromptExists checks if a prompt with the given name exists.
+func PromptExists(name string) bool {
+	_, err := os.Stat(PromptPath(name))
+	return err == nil
+}
+
+// PromptInfo holds metadata about a saved prompt.
+type PromptInfo struct {
+	Name    string
+	Path    string
+	Summary string // First non-blank line
+}
+
+// ListPrompts returns all saved prompts.
+func ListPrompts() ([]PromptInfo, error) {
+	dir := paths.Prompts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []PromptInfo{}, nil
+		}
+		return nil, fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+
+	var prompts []PromptInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".md")
+		info := PromptInfo{
+			Name: name,
+			Path: filepath.Join(dir, entry.Name()),
+		}
+		if text, err := LoadPrompt(name); err == nil {
+			info.Summary, _, _ = strings.Cut(text, "\n")
+		}
+
+		prompts = append(prompts, info)
+	}
+
+	return prompts, nil
+}
+
+// ResolveSystemPrompt expands a reference to a saved prompt. "@name" is
+// replaced by the prompt's text, "@@..." is the literal text with one "@"
+// removed, and anything else is returned unchanged.
+func ResolveSystemPrompt(s string) (string, error) {
+	rest, ok := strings.CutPrefix(s, PromptRefPrefix)
+	if !ok {
+		return s, nil
+	}
+	if strings.HasPrefix(rest, PromptRefPrefix) {
+		return rest, nil
+	}
+	return LoadPrompt(rest)
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPrompts(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	if err := SavePrompt("reviewer", "\nYou review Go code.\nBe terse.\n\n"); err != nil {
+		t.Fatalf("SavePrompt() error = %v", err)
+	}
+	text, err := LoadPrompt("reviewer")
+	if err != nil || text != "You review Go code.\nBe terse." {
+		t.Fatalf("LoadPrompt() = %q, %v", text, err)
+	}
+
+	prompts, err := ListPrompts()
+	if err != nil || len(prompts) != 1 {
+		t.Fatalf("ListPrompts() = %v, %v", prompts, err)
+	}
+	if prompts[0].Name != "reviewer" || prompts[0].Summary != "You review Go code." {
+		t.Errorf("ListPrompts()[0] = %+v", prompts[0])
+	}
+
+	if err := DeletePrompt("reviewer"); err != nil {
+		t.Fatalf("DeletePrompt() error = %v", err)
+	}
+	if PromptExists("reviewer") {
+		t.Error("prompt exists after DeletePrompt()")
+	}
+	if _, err := LoadPrompt("reviewer"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("LoadPrompt() of a deleted prompt error = %v", err)
+	}
+}
+
+func TestResolveSystemPrompt(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	SavePrompt("pirate", "Talk like a pirate.")
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"You are helpful.", "You are helpful.", false},
+		{"", "", false},
+		{"@pirate", "Talk like a pirate.", false},
+		{"@@pirate", "@pirate", false},
+		{"@missing", "", true},
+		{"@../config", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ResolveSystemPrompt(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ResolveSystemPrompt(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
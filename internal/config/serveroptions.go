@@ -0,0 +1,112 @@
+package config
+
+// serverOptions is the catalog of llama-server flags lleme accepts in
+// llamacpp.options, personas, /set and /api/run, checked against
+// 'llama-server --help'. Flags lleme sets itself (--model, --host, --port,
+// --chat-template-file and the like) are left out. Anything missing here can
+// still be passed under passthrough.
+var serverOptions = []ServerOption{
+	// Context and batching
+	{Name: "ctx-size", Type: OptionInt, Min: bound(0), Restart: true, Description: "Context size (0 = from model)"},
+	{Name: "batch-size", Type: OptionInt, Min: bound(1), Restart: true, Description: "Logical batch size"},
+	{Name: "ubatch-size", Type: OptionInt, Min: bound(1), Restart: true, Description: "Physical batch size"},
+	{Name: "parallel", Type: OptionInt, Min: bound(-1), Restart: true, Description: "Concurrent requests (-1 = auto)"},
+	{Name: "cont-batching", Type: OptionBool, Restart: true, Description: "Continuous batching"},
+	{Name: "context-shift", Type: OptionBool, Restart: true, Description: "Slide the window when context fills"},
+	{Name: "keep", Type: OptionInt, Min: bound(-1), Restart: true, Description: "Prompt tokens kept when shifting (-1 = all)"},
+	{Name: "cache-reuse", Type: OptionInt, Min: bound(0), Restart: true, Description: "Min chunk size reused from the KV cache"},
+	{Name: "swa-full", Type: OptionBool, Restart: true, Description: "Full-size sliding window attention cache"},
+	{Name: "kv-unified", Type: OptionBool, Restart: true, Description: "One KV cache shared by all sequences"},
+
+	// CPU
+	{Name: "threads", Type: OptionInt, Min: bound(-1), Restart: true, Description: "CPU threads (-1 = auto)"},
+	{Name: "threads-batch", Type: OptionInt, Min: bound(-1), Restart: true, Description: "CPU threads for batches (-1 = same as threads)"},
+	{Name: "numa", Type: OptionString, Values: []string{"distribute", "isolate", "numactl"}, Restart: true, Description: "NUMA placement"},
+	{Name: "cpu-moe", Type: OptionBool, Restart: true, Description: "Keep all MoE expert weights on the CPU"},
+	{Name: "n-cpu-moe", Type: OptionInt, Min: bound(0), Restart: true, Description: "MoE layers whose experts stay on the CPU"},
+
+	// GPU
+	{Name: "gpu-layers", Type: OptionInt, Min: bound(-1), Values: []string{"auto", "all"}, Restart: true, Description: "Layers offloaded to GPU (-1 = all)"},
+	{Name: "split-mode", Type: OptionString, Values: []string{"none", "layer", "row"}, Restart: true, Description: "Multi-GPU split"},
+	{Name: "tensor-split", Type: OptionString, Restart: true, Description: "Share of the model per GPU, like 3,1"},
+	{Name: "main-gpu", Type: OptionInt, Min: bound(0), Restart: true, Description: "Primary GPU index"},
+	{Name: "device", Type: OptionString, Restart: true, Description: "Devices to offload to, comma-separated"},
+	{Name: "flash-attn", Type: OptionBool, Values: []string{"on", "off", "auto"}, Restart: true, Description: "Flash attention"},
+	{Name: "no-kv-offload", Type: OptionBool, Restart: true, Description: "Keep the KV cache in RAM"},
+	{Name: "override-tensor", Type: OptionList, Restart: true, Description: "Buffer type for tensors, PATTERN=TYPE"},
+
+	// Memory
+	{Name: "mlock", Type: OptionBool, Restart: true, Description: "Lock the model in RAM"},
+	{Name: "no-mmap", Type: OptionBool, Restart: true, Description: "Read the model instead of mapping it"},
+	{Name: "cache-type-k", Type: OptionString, Values: cacheTypes, Restart: true, Description: "KV cache type for K"},
+	{Name: "cache-type-v", Type: OptionString, Values: cacheTypes, Restart: true, Description: "KV cache type for V"},
+	{Name: "defrag-thold", Type: OptionFloat, Restart: true, Description: "KV cache defragmentation threshold"},
+
+	// Long context
+	{Name: "rope-scaling", Type: OptionString, Values: RopeScalingTypes, Restart: true, Description: "RoPE scaling method"},
+	{Name: "rope-scale", Type: OptionFloat, Check: positive, Restart: true, Description: "RoPE context scaling factor"},
+	{Name: "rope-freq-base", Type: OptionFloat, Check: positive, Restart: true, Description: "RoPE base frequency"},
+	{Name: "rope-freq-scale", Type: OptionFloat, Check: positive, Restart: true, Description: "RoPE frequency scale"},
+	{Name: "yarn-orig-ctx", Type: OptionInt, Min: bound(0), Restart: true, Description: "Original training context for YaRN"},
+	{Name: "yarn-ext-factor", Type: OptionFloat, Restart: true, Description: "YaRN extrapolation mix factor"},
+	{Name: "yarn-attn-factor", Type: OptionFloat, Restart: true, Description: "YaRN attention magnitude scale"},
+	{Name: "yarn-beta-slow", Type: OptionFloat, Restart: true, Description: "YaRN high correction dim"},
+	{Name: "yarn-beta-fast", Type: OptionFloat, Restart: true, Description: "YaRN low correction dim"},
+	{Name: "override-kv", Type: OptionList, Check: checkOverrideKV, Restart: true, Description: "Override model metadata, KEY=TYPE:VALUE"},
+
+	// Model features
+	{Name: "pooling", Type: OptionString, Values: []string{"none", "mean", "cls", "last", "rank"}, Restart: true, Description: "Embedding pooling"},
+	{Name: "reranking", Type: OptionBool, Restart: true, Description: "Serve the rerank endpoint"},
+	{Name: "jinja", Type: OptionBool, Restart: true, Description: "Jinja chat templates"},
+	{Name: "chat-template", Type: OptionString, Restart: true, Description: "Built-in chat template to use"},
+	{Name: "reasoning-format", Type: OptionString, Values: []string{"auto", "none", "deepseek", "deepseek-legacy"}, Restart: true, Description: "Thinking token handling"},
+	{Name: "reasoning-budget", Type: OptionInt, Min: bound(-1), Restart: true, Description: "Thinking budget (-1 = unlimited, 0 = off)"},
+	{Name: "mmproj", Type: OptionString, Restart: true, Path: true, Description: "Vision projector file"},
+	{Name: "no-mmproj-offload", Type: OptionBool, Restart: true, Description: "Keep the vision projector on the CPU"},
+	{Name: "lora", Type: OptionList, Restart: true, Path: true, Description: "LoRA adapter files"},
+
+	// Speculative decoding
+	{Name: "model-draft", Type: OptionString, Restart: true, Path: true, Description: "Draft model file"},
+	{Name: "ctx-size-draft", Type: OptionInt, Min: bound(0), Restart: true, Description: "Draft model context size"},
+	{Name: "gpu-layers-draft", Type: OptionInt, Min: bound(-1), Values: []string{"auto", "all"}, Restart: true, Description: "Draft model layers offloaded to GPU"},
+	{Name: "draft-max", Type: OptionInt, Min: bound(0), Restart: true, Description: "Most tokens drafted at once"},
+	{Name: "draft-min", Type: OptionInt, Min: bound(0), Restart: true, Description: "Fewest tokens drafted at once"},
+
+	// Server
+	{Name: "alias", Type: OptionString, Restart: true, Description: "Model name reported by the backend"},
+	{Name: "timeout", Type: OptionInt, Min: bound(0), Restart: true, Description: "Backend read/write timeout in seconds"},
+	{Name: "metrics", Type: OptionBool, Restart: true, Description: "Prometheus metrics on the backend"},
+	{Name: "slot-save-path", Type: OptionString, Restart: true, Path: true, Description: "Directory for saved slots"},
+
+	// Sampling defaults, which requests can override
+	{Name: "temp", Type: OptionFloat, Min: bound(0), Description: "Temperature"},
+	{Name: "top-k", Type: OptionInt, Min: bound(0), Description: "Top-K sampling (0 = off)"},
+	{Name: "top-p", Type: OptionFloat, Min: bound(0), Max: bound(1), Description: "Top-P sampling (1.0 = off)"},
+	{Name: "min-p", Type: OptionFloat, Min: bound(0), Max: bound(1), Description: "Min-P sampling (0.0 = off)"},
+	{Name: "typical", Type: OptionFloat, Min: bound(0), Max: bound(1), Description: "Locally typical sampling (1.0 = off)"},
+	{Name: "top-nsigma", Type: OptionFloat, Description: "Top-n-sigma sampling (-1 = off)"},
+	{Name: "repeat-penalty", Type: OptionFloat, Min: bound(0), Description: "Repeat penalty (1.0 = off)"},
+	{Name: "repeat-last-n", Type: OptionInt, Min: bound(-1), Description: "Tokens checked for repeats (-1 = context)"},
+	{Name: "presence-penalty", Type: OptionFloat, Description: "Presence penalty"},
+	{Name: "frequency-penalty", Type: OptionFloat, Description: "Frequency penalty"},
+	{Name: "dry-multiplier", Type: OptionFloat, Min: bound(0), Description: "DRY sampling multiplier (0 = off)"},
+	{Name: "dry-base", Type: OptionFloat, Min: bound(0), Description: "DRY sampling base"},
+	{Name: "dry-allowed-length", Type: OptionInt, Min: bound(0), Description: "DRY allowed repeat length"},
+	{Name: "dry-penalty-last-n", Type: OptionInt, Min: bound(-1), Description: "Tokens checked by DRY (-1 = context)"},
+	{Name: "xtc-probability", Type: OptionFloat, Min: bound(0), Max: bound(1), Description: "XTC probability (0 = off)"},
+	{Name: "xtc-threshold", Type: OptionFloat, Min: bound(0), Max: bound(1), Description: "XTC threshold"},
+	{Name: "dynatemp-range", Type: OptionFloat, Min: bound(0), Description: "Dynamic temperature range (0 = off)"},
+	{Name: "dynatemp-exp", Type: OptionFloat, Min: bound(0), Description: "Dynamic temperature exponent"},
+	{Name: "mirostat", Type: OptionInt, Min: bound(0), Max: bound(2), Description: "Mirostat version (0 = off)"},
+	{Name: "mirostat-lr", Type: OptionFloat, Min: bound(0), Description: "Mirostat learning rate"},
+	{Name: "mirostat-ent", Type: OptionFloat, Min: bound(0), Description: "Mirostat target entropy"},
+	{Name: "samplers", Type: OptionString, Description: "Sampler order, separated by ;"},
+	{Name: "seed", Type: OptionInt, Min: bound(-1), Description: "RNG seed (-1 = random)"},
+	{Name: "n-predict", Type: OptionInt, Min: bound(-1), Description: "Most tokens per reply (-1 = unlimited)"},
+	{Name: "ignore-eos", Type: OptionBool, Description: "Keep generating past end of stream"},
+	{Name: "grammar", Type: OptionString, Description: "BNF grammar replies must follow"},
+	{Name: "json-schema", Type: OptionString, Description: "JSON schema replies must follow"},
+}
+
+// cacheTypes are the values accepted by --cache-type-k and --cache-type-v.
+var cacheTypes = []string{"f32", "f16", "bf16", "q8_0", "q4_0", "q4_1", "iq4_nl", "q5_0", "q5_1"}
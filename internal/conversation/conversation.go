@@ -0,0 +1,220 @@
+// Package conversation saves chats from lleme run so they can be listed and
+// read back later, each under a short title the model writes for it.
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// Conversation is a saved chat
+type Conversation struct {
+	ID       string               `json:"id"`
+	Title    string               `json:"title"`
+	Model    string               `json:"model"`
+	Created  time.Time            `json:"created"`
+	Updated  time.Time            `json:"updated"`
+	Source   string               `json:"source,omitempty"` // Where an imported conversation came from, like chatgpt-export:<id>
+	Messages []server.ChatMessage `json:"messages"`
+}
+
+// New starts a conversation with model, not yet saved
+func New(model string) *Conversation {
+	now := time.Now()
+	return &Conversation{ID: newID(now), Model: model, Created: now, Updated: now}
+}
+
+// newID returns an ID that sorts by creation time, with a random suffix so
+// two chats saved in the same second don't collide
+func newID(t time.Time) string {
+	b := make([]byte, 2)
+	rand.Read(b)
+	return t.Format("20060102-150405") + "-" + hex.EncodeToString(b)
+}
+
+// Path returns the file a conversation is saved in
+func Path(id string) string {
+	return filepath.Join(paths.Conversations(), id+".json")
+}
+
+// Save writes the conversation, readable only by the user since chats may
+// hold anything
+func (c *Conversation) Save() error {
+	c.Updated = time.Now()
+	return c.write()
+}
+
+// write saves the conversation as it is, keeping its times
+func (c *Conversation) write() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal conversation: %w", err)
+	}
+	if err := os.MkdirAll(paths.Conversations(), 0700); err != nil {
+		return fmt.Errorf("failed to create conversations directory: %w", err)
+	}
+	return fileutil.AtomicWriteFile(Path(c.ID), data, 0600)
+}
+
+// Load reads a saved conversation by ID
+func Load(id string) (*Conversation, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("invalid conversation ID '%s'", id)
+	}
+	data, err := os.ReadFile(Path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("conversation '%s' not found", id)
+		}
+		return nil, fmt.Errorf("failed to read conversation: %w", err)
+	}
+	var c Conversation
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse conversation '%s': %w", id, err)
+	}
+	return &c, nil
+}
+
+// Delete removes a saved conversation
+func Delete(id string) error {
+	if _, err := Load(id); err != nil {
+		return err
+	}
+	if err := os.Remove(Path(id)); err != nil {
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved conversations, most recently updated first. Files
+// that can't be read are skipped.
+func List() ([]*Conversation, error) {
+	entries, err := os.ReadDir(paths.Conversations())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read conversations directory: %w", err)
+	}
+
+	var list []*Conversation
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		if c, err := Load(id); err == nil {
+			list = append(list, c)
+		}
+	}
+	slices.SortFunc(list, func(a, b *Conversation) int {
+		return b.Updated.Compare(a.Updated)
+	})
+	return list, nil
+}
+
+// Exchanges returns the number of user messages
+func (c *Conversation) Exchanges() int {
+	n := 0
+	for _, msg := range c.Messages {
+		if msg.Role == "user" {
+			n++
+		}
+	}
+	return n
+}
+
+// titlePrompt is the instruction the web UI also uses to title its chats
+const titlePrompt = "Generate a short title (3-6 words) for this conversation. Reply with only the title, no quotes or punctuation."
+
+const (
+	titleMaxTokens    = 24   // Enough for a title, and keeps the request cheap
+	titleMaxInput     = 4000 // Characters of the conversation sent to the model
+	titleMaxLength    = 80   // Characters kept of whatever the model replies
+	fallbackMaxLength = 50   // Characters of the first message used without one
+)
+
+// GenerateTitle asks model for a short title for messages in a single small
+// request. If the request fails or the reply is empty, the start of the
+// first user message is used instead.
+func GenerateTitle(api *server.APIClient, model string, messages []server.ChatMessage) string {
+	fallback := fallbackTitle(messages)
+	if api == nil {
+		return fallback
+	}
+
+	var sb strings.Builder
+	for _, msg := range messages {
+		switch msg.Role {
+		case "user":
+			sb.WriteString("User: " + msg.Content + "\n")
+		case "assistant":
+			sb.WriteString("Assistant: " + msg.Content + "\n")
+		}
+	}
+	text := sb.String()
+	if len(text) > titleMaxInput {
+		text = strings.ToValidUTF8(text[:titleMaxInput], "")
+	}
+
+	resp, err := api.ChatCompletion(&server.ChatCompletionRequest{
+		Model: model,
+		Messages: []server.ChatMessage{
+			{Role: "system", Content: titlePrompt},
+			{Role: "user", Content: text},
+		},
+		MaxTokens:       titleMaxTokens,
+		ReasoningFormat: "auto",
+	})
+	if err != nil || len(resp.Choices) == 0 {
+		return fallback
+	}
+	if title := cleanTitle(resp.Choices[0].Message.Content); title != "" {
+		return title
+	}
+	return fallback
+}
+
+// cleanTitle keeps the first line of a model's reply, without the quotes,
+// markdown or trailing punctuation models add despite being asked not to
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+	s, _, _ = strings.Cut(s, "\n")
+	s = strings.TrimPrefix(s, "Title:")
+	s = strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || (unicode.IsPunct(r) && r != ')' && r != '?')
+	})
+	return truncate(s, titleMaxLength)
+}
+
+// fallbackTitle is the start of the first user message, on one line
+func fallbackTitle(messages []server.ChatMessage) string {
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			if title := truncate(strings.Join(strings.Fields(msg.Content), " "), fallbackMaxLength); title != "" {
+				return title
+			}
+		}
+	}
+	return "New conversation"
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n]))
+	}
+	return s
+}
@@ -0,0 +1,117 @@
+package conversation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+func TestSaveAndList(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	first := New("user/repo:Q4_K_M")
+	first.Title = "Go generics"
+	first.Messages = []server.ChatMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "what are generics?"},
+		{Role: "assistant", Content: "type parameters"},
+	}
+	if err := first.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	second := New("user/other:Q8_0")
+	second.Title = "Pasta"
+	second.Save()
+
+	if info, err := os.Stat(Path(first.ID)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("conversation file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	// Saving again moves a conversation to the top
+	time.Sleep(10 * time.Millisecond)
+	first.Save()
+	list, err := List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("List() = %d conversations, %v", len(list), err)
+	}
+	if list[0].ID != first.ID || list[0].Title != "Go generics" || list[0].Exchanges() != 1 {
+		t.Errorf("List()[0] = %+v, want the conversation saved last", list[0])
+	}
+
+	loaded, err := Load(first.ID)
+	if err != nil || len(loaded.Messages) != 3 || loaded.Messages[2].Content != "type parameters" {
+		t.Fatalf("Load() = %+v, %v", loaded, err)
+	}
+
+	if err := Delete(second.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := Load(second.ID); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Load() of a deleted conversation error = %v", err)
+	}
+	if _, err := Load("../config"); err == nil {
+		t.Error("Load() accepted a path outside the conversations directory")
+	}
+}
+
+func TestGenerateTitle(t *testing.T) {
+	messages := []server.ChatMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "How do I   make fresh\npasta without a machine? I have flour and eggs."},
+		{Role: "assistant", Content: "Roll it by hand."},
+	}
+
+	var got server.ChatCompletionRequest
+	reply := `"Handmade Pasta Basics."`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(server.ChatCompletionResponse{
+			Choices: []server.Choice{{Message: server.ChatMessage{Role: "assistant", Content: reply}}},
+		})
+	}))
+	defer ts.Close()
+	api := server.NewAPIClientFromURL(ts.URL)
+
+	if title := GenerateTitle(api, "m", messages); title != "Handmade Pasta Basics" {
+		t.Errorf("GenerateTitle() = %q", title)
+	}
+	if got.Stream || got.MaxTokens != titleMaxTokens || len(got.Messages) != 2 {
+		t.Errorf("request = %+v, want one small non-streaming request", got)
+	}
+	if !strings.Contains(got.Messages[1].Content, "User: How do I") || strings.Contains(got.Messages[1].Content, "Be brief") {
+		t.Errorf("request text = %q, want the exchanges without the system prompt", got.Messages[1].Content)
+	}
+
+	// An empty reply falls back to the start of the first message
+	reply = "  "
+	if title := GenerateTitle(api, "m", messages); title != "How do I make fresh pasta without a machine? I hav" {
+		t.Errorf("GenerateTitle() with an empty reply = %q", title)
+	}
+	if title := GenerateTitle(nil, "m", nil); title != "New conversation" {
+		t.Errorf("GenerateTitle() with nothing to go on = %q", title)
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Pasta Basics", "Pasta Basics"},
+		{`"Pasta Basics."`, "Pasta Basics"},
+		{"**Pasta Basics**\n\nThis title fits because", "Pasta Basics"},
+		{"Title: Why Is the Sky Blue?", "Why Is the Sky Blue?"},
+		{"Using fmt.Println (Go)", "Using fmt.Println (Go)"},
+		{strings.Repeat("word ", 30), strings.TrimSpace(strings.Repeat("word ", 16))},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
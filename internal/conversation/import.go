@@ -0,0 +1,445 @@
+package conversation
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// Formats Import reads
+const (
+	FormatOllama    = "ollama"         // Modelfiles from 'ollama show --modelfile' of a session saved with /save
+	FormatOpenWebUI = "openwebui"      // JSON from Open WebUI's chat export
+	FormatChatGPT   = "chatgpt-export" // ChatGPT's data export, as the zip or its conversations.json
+)
+
+// ImportFormats lists the formats Import reads
+var ImportFormats = []string{FormatOllama, FormatOpenWebUI, FormatChatGPT}
+
+// ImportResult describes what an import saved
+type ImportResult struct {
+	Imported []*Conversation
+	Skipped  int // Imported before, so left alone
+	Empty    int // No messages to keep
+}
+
+// Import reads the conversations another tool exported to path and saves
+// them, keeping their titles and times. Conversations imported before are
+// skipped, so running it again after a fresh export only adds new ones.
+func Import(format, path string) (*ImportResult, error) {
+	convs, err := Read(format, path)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := List()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, c := range existing {
+		if c.Source != "" {
+			seen[c.Source] = true
+		}
+	}
+
+	result := &ImportResult{}
+	for _, c := range convs {
+		switch {
+		case len(c.Messages) == 0:
+			result.Empty++
+		case seen[c.Source]:
+			result.Skipped++
+		default:
+			// IDs only have a second's resolution, and exports often hold
+			// several chats started in the same one
+			for exists(Path(c.ID)) {
+				c.ID = newID(c.Created)
+			}
+			if err := c.write(); err != nil {
+				return result, err
+			}
+			seen[c.Source] = true
+			result.Imported = append(result.Imported, c)
+		}
+	}
+	return result, nil
+}
+
+// Read parses the conversations another tool exported to path, without
+// saving them
+func Read(format, path string) ([]*Conversation, error) {
+	switch format {
+	case FormatOllama:
+		return readOllama(path)
+	case FormatOpenWebUI:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		return parseOpenWebUI(data)
+	case FormatChatGPT:
+		data, err := readChatGPTExport(path)
+		if err != nil {
+			return nil, err
+		}
+		return parseChatGPT(data)
+	default:
+		return nil, fmt.Errorf("unknown import format '%s' (use %s)", format, strings.Join(ImportFormats, ", "))
+	}
+}
+
+// imported builds a conversation from another tool's, not yet saved
+func imported(source, title, model string, created, updated time.Time, messages []server.ChatMessage) *Conversation {
+	if created.IsZero() {
+		created = time.Now()
+	}
+	if updated.Before(created) {
+		updated = created
+	}
+	title = truncate(strings.Join(strings.Fields(title), " "), titleMaxLength)
+	if title == "" {
+		title = fallbackTitle(messages)
+	}
+	return &Conversation{
+		ID:       newID(created),
+		Title:    title,
+		Model:    model,
+		Created:  created,
+		Updated:  updated,
+		Source:   source,
+		Messages: messages,
+	}
+}
+
+// appendMessage adds a message unless it's empty or from a role lleme
+// doesn't keep, like tool output
+func appendMessage(messages []server.ChatMessage, role, content string) []server.ChatMessage {
+	content = strings.TrimSpace(content)
+	if content == "" || (role != "system" && role != "user" && role != "assistant") {
+		return messages
+	}
+	return append(messages, server.ChatMessage{Role: role, Content: content})
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// unixSeconds converts an export's Unix time, which may have a fraction
+func unixSeconds(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(sec*float64(time.Second)))
+}
+
+// chatGPTConversation is one conversation in ChatGPT's conversations.json.
+// Messages form a tree, since edits and regenerations branch it; the one
+// shown is the path from current_node back to the root.
+type chatGPTConversation struct {
+	ID               string                 `json:"id"`
+	ConversationID   string                 `json:"conversation_id"`
+	Title            string                 `json:"title"`
+	CreateTime       float64                `json:"create_time"`
+	UpdateTime       float64                `json:"update_time"`
+	CurrentNode      string                 `json:"current_node"`
+	DefaultModelSlug string                 `json:"default_model_slug"`
+	Mapping          map[string]chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		Content struct {
+			ContentType string `json:"content_type"`
+			Parts       []any  `json:"parts"`
+		} `json:"content"`
+		Metadata struct {
+			ModelSlug string `json:"model_slug"`
+			Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+// readChatGPTExport returns conversations.json from ChatGPT's export, given
+// the zip it's emailed as, the unzipped directory, or the file itself
+func readChatGPTExport(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, "conversations.json")
+	} else if strings.EqualFold(filepath.Ext(path), ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open export: %w", err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if filepath.Base(f.Name) != "conversations.json" {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read conversations.json: %w", err)
+			}
+			defer rc.Close()
+			return io.ReadAll(rc)
+		}
+		return nil, fmt.Errorf("no conversations.json in %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	return data, nil
+}
+
+// parseChatGPT reads ChatGPT's conversations.json. Images, files, tool
+// calls and hidden system messages are left out.
+func parseChatGPT(data []byte) ([]*Conversation, error) {
+	var exported []chatGPTConversation
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("failed to parse ChatGPT export: %w", err)
+	}
+
+	var convs []*Conversation
+	for _, e := range exported {
+		// Walk up from the shown message, guarding against a cycle
+		var path []chatGPTNode
+		for id, n := e.CurrentNode, 0; id != "" && n <= len(e.Mapping); n++ {
+			node, ok := e.Mapping[id]
+			if !ok {
+				break
+			}
+			path = append(path, node)
+			id = node.Parent
+		}
+
+		model := e.DefaultModelSlug
+		var messages []server.ChatMessage
+		for i := len(path) - 1; i >= 0; i-- {
+			msg := path[i].Message
+			if msg == nil || msg.Metadata.Hidden {
+				continue
+			}
+			if t := msg.Content.ContentType; t != "text" && t != "multimodal_text" {
+				continue
+			}
+			var parts []string
+			for _, p := range msg.Content.Parts {
+				if s, ok := p.(string); ok {
+					parts = append(parts, s)
+				}
+			}
+			messages = appendMessage(messages, msg.Author.Role, strings.Join(parts, "\n"))
+			if msg.Metadata.ModelSlug != "" {
+				model = msg.Metadata.ModelSlug
+			}
+		}
+
+		id := e.ID
+		if id == "" {
+			id = e.ConversationID
+		}
+		convs = append(convs, imported(FormatChatGPT+":"+id, e.Title, model,
+			unixSeconds(e.CreateTime), unixSeconds(e.UpdateTime), messages))
+	}
+	return convs, nil
+}
+
+// openWebUIChat is one chat in Open WebUI's export. Like ChatGPT's, its
+// messages form a tree under history, with the shown branch ending at
+// currentId; older exports only have the flat messages list.
+type openWebUIChat struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+	Chat      struct {
+		Title    string             `json:"title"`
+		Models   []string           `json:"models"`
+		Messages []openWebUIMessage `json:"messages"`
+		History  struct {
+			CurrentID string                      `json:"currentId"`
+			Messages  map[string]openWebUIMessage `json:"messages"`
+		} `json:"history"`
+	} `json:"chat"`
+}
+
+type openWebUIMessage struct {
+	ParentID  string `json:"parentId"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	Model     string `json:"model"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// parseOpenWebUI reads Open WebUI's export of all chats, or of one
+func parseOpenWebUI(data []byte) ([]*Conversation, error) {
+	var exported []openWebUIChat
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		exported = make([]openWebUIChat, 1)
+		if err := json.Unmarshal(data, &exported[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse Open WebUI export: %w", err)
+		}
+	} else if err := json.Unmarshal(data, &exported); err != nil {
+		return nil, fmt.Errorf("failed to parse Open WebUI export: %w", err)
+	}
+
+	var convs []*Conversation
+	for _, e := range exported {
+		branch := e.Chat.Messages
+		if history := e.Chat.History.Messages; len(history) > 0 {
+			branch = nil
+			for id, n := e.Chat.History.CurrentID, 0; id != "" && n <= len(history); n++ {
+				msg, ok := history[id]
+				if !ok {
+					break
+				}
+				branch = append([]openWebUIMessage{msg}, branch...)
+				id = msg.ParentID
+			}
+		}
+
+		var model string
+		if len(e.Chat.Models) > 0 {
+			model = e.Chat.Models[0]
+		}
+		var messages []server.ChatMessage
+		for _, msg := range branch {
+			messages = appendMessage(messages, msg.Role, msg.Content)
+			if model == "" {
+				model = msg.Model
+			}
+		}
+
+		title := e.Title
+		if title == "" {
+			title = e.Chat.Title
+		}
+		created, updated := unixSeconds(float64(e.CreatedAt)), unixSeconds(float64(e.UpdatedAt))
+		if created.IsZero() && len(branch) > 0 {
+			created = unixSeconds(float64(branch[0].Timestamp))
+		}
+		convs = append(convs, imported(FormatOpenWebUI+":"+e.ID, title, model, created, updated, messages))
+	}
+	return convs, nil
+}
+
+// readOllama reads a Modelfile, or every file in a directory of them.
+// Ollama keeps a session saved with /save as a model, so its conversation
+// is the MESSAGE lines 'ollama show --modelfile <name>' prints.
+func readOllama(path string) ([]*Conversation, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		files = nil
+		for _, entry := range entries {
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	var convs []*Conversation
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read export: %w", err)
+		}
+		abs, _ := filepath.Abs(file)
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		model, messages := parseModelfile(string(data))
+		convs = append(convs, imported(FormatOllama+":"+abs, name, model, info.ModTime(), info.ModTime(), messages))
+	}
+	return convs, nil
+}
+
+// parseModelfile returns the model a Modelfile is built from and its SYSTEM
+// and MESSAGE instructions as a conversation. Values may span lines inside
+// triple quotes.
+func parseModelfile(data string) (string, []server.ChatMessage) {
+	var model, generatedFrom string
+	var messages []server.ChatMessage
+
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		// 'ollama show' comments the model's name above a FROM of its blob
+		if rest, ok := strings.CutPrefix(line, "# FROM "); ok {
+			generatedFrom = strings.TrimSpace(rest)
+			continue
+		}
+		instruction, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+
+		switch strings.ToUpper(instruction) {
+		case "FROM":
+			model = rest
+		case "SYSTEM":
+			var value string
+			value, i = modelfileValue(rest, lines, i)
+			messages = appendMessage(messages, "system", value)
+		case "MESSAGE":
+			role, value, _ := strings.Cut(rest, " ")
+			value, i = modelfileValue(strings.TrimSpace(value), lines, i)
+			messages = appendMessage(messages, strings.ToLower(role), value)
+		}
+	}
+
+	if generatedFrom != "" && (model == "" || filepath.IsAbs(model)) {
+		model = generatedFrom
+	}
+	return model, messages
+}
+
+// modelfileValue returns an instruction's value starting with first on line
+// i, reading on to the closing triple quote when it opens one, and the
+// line it ended on
+func modelfileValue(first string, lines []string, i int) (string, int) {
+	value, ok := strings.CutPrefix(first, `"""`)
+	if !ok {
+		return strings.Trim(first, `"`), i
+	}
+	if before, _, closed := strings.Cut(value, `"""`); closed {
+		return before, i
+	}
+	var sb strings.Builder
+	sb.WriteString(value)
+	for i++; i < len(lines); i++ {
+		sb.WriteString("\n")
+		if before, _, closed := strings.Cut(lines[i], `"""`); closed {
+			sb.WriteString(before)
+			return sb.String(), i
+		}
+		sb.WriteString(lines[i])
+	}
+	return sb.String(), i
+}
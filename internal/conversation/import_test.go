@@ -0,0 +1,212 @@
+package conversation
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const chatGPTExport = `[{
+  "id": "c1", "title": "Sourdough starter", "create_time": 1700000000.5, "update_time": 1700000600,
+  "default_model_slug": "gpt-4o", "current_node": "a2",
+  "mapping": {
+    "root": {"parent": null, "message": null},
+    "s":  {"parent": "root", "message": {"author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]}, "metadata": {"is_visually_hidden_from_conversation": true}}},
+    "u1": {"parent": "s", "message": {"author": {"role": "user"}, "content": {"content_type": "text", "parts": ["How do I feed a starter?"]}, "metadata": {}}},
+    "a1": {"parent": "u1", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["An old answer"]}, "metadata": {}}},
+    "a2": {"parent": "u1", "message": {"author": {"role": "assistant"}, "content": {"content_type": "text", "parts": ["Equal parts flour and water."]}, "metadata": {"model_slug": "gpt-4o-mini"}}},
+    "t":  {"parent": "u1", "message": {"author": {"role": "tool"}, "content": {"content_type": "code", "parts": []}, "metadata": {}}}
+  }
+}, {
+  "id": "c2", "title": "", "create_time": 1700001000, "update_time": 1700001000, "current_node": "x",
+  "mapping": {"x": {"parent": "", "message": {"author": {"role": "user"}, "content": {"content_type": "multimodal_text", "parts": [{"asset_pointer": "file-1"}, "What is in this picture?"]}, "metadata": {}}}}
+}]`
+
+func TestParseChatGPT(t *testing.T) {
+	convs, err := parseChatGPT([]byte(chatGPTExport))
+	if err != nil || len(convs) != 2 {
+		t.Fatalf("parseChatGPT() = %d conversations, %v", len(convs), err)
+	}
+
+	c := convs[0]
+	if c.Title != "Sourdough starter" || c.Model != "gpt-4o-mini" || c.Source != "chatgpt-export:c1" {
+		t.Errorf("conversation = %+v", c)
+	}
+	if !c.Created.Equal(time.Unix(1700000000, 5e8)) || !c.Updated.Equal(time.Unix(1700000600, 0)) {
+		t.Errorf("times = %v, %v", c.Created, c.Updated)
+	}
+	// The shown branch, without the hidden system message or the replaced reply
+	if len(c.Messages) != 2 || c.Messages[0].Content != "How do I feed a starter?" || c.Messages[1].Content != "Equal parts flour and water." {
+		t.Errorf("messages = %+v", c.Messages)
+	}
+
+	// Untitled chats are titled like /save titles them without a model
+	if c := convs[1]; c.Title != "What is in this picture?" || len(c.Messages) != 1 {
+		t.Errorf("untitled conversation = %+v", c)
+	}
+
+	if _, err := parseChatGPT([]byte("{")); err == nil {
+		t.Error("parseChatGPT() accepted invalid JSON")
+	}
+}
+
+func TestReadChatGPTExport(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "export.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("chat.html")
+	w.Write([]byte("<html></html>"))
+	w, _ = zw.Create("conversations.json")
+	w.Write([]byte(chatGPTExport))
+	zw.Close()
+	f.Close()
+
+	os.WriteFile(filepath.Join(dir, "conversations.json"), []byte(chatGPTExport), 0644)
+
+	for _, path := range []string{zipPath, dir, filepath.Join(dir, "conversations.json")} {
+		convs, err := Read(FormatChatGPT, path)
+		if err != nil || len(convs) != 2 {
+			t.Errorf("Read(%s) = %d conversations, %v", filepath.Base(path), len(convs), err)
+		}
+	}
+}
+
+func TestParseOpenWebUI(t *testing.T) {
+	export := `[{
+	  "id": "w1", "title": "Haiku about Go", "created_at": 1700000000, "updated_at": 1700000300,
+	  "chat": {
+	    "models": ["llama3.2:latest"],
+	    "history": {"currentId": "m3", "messages": {
+	      "m1": {"parentId": null, "role": "user", "content": "Write a haiku about Go"},
+	      "m2": {"parentId": "m1", "role": "assistant", "content": "first try"},
+	      "m3": {"parentId": "m1", "role": "assistant", "content": "Goroutines hum"}
+	    }}
+	  }
+	}]`
+	convs, err := parseOpenWebUI([]byte(export))
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("parseOpenWebUI() = %d conversations, %v", len(convs), err)
+	}
+	c := convs[0]
+	if c.Title != "Haiku about Go" || c.Model != "llama3.2:latest" || c.Source != "openwebui:w1" || !c.Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("conversation = %+v", c)
+	}
+	if len(c.Messages) != 2 || c.Messages[1].Content != "Goroutines hum" {
+		t.Errorf("messages = %+v", c.Messages)
+	}
+
+	// A single chat, from older versions without history
+	single := `{"id": "w2", "chat": {"title": "Flat", "messages": [
+	  {"role": "user", "content": "hi", "model": "", "timestamp": 1700000000},
+	  {"role": "assistant", "content": "hello", "model": "qwen3:8b"}
+	]}}`
+	convs, err = parseOpenWebUI([]byte(single))
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("parseOpenWebUI(single) = %d conversations, %v", len(convs), err)
+	}
+	if c := convs[0]; c.Title != "Flat" || c.Model != "qwen3:8b" || len(c.Messages) != 2 || !c.Created.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("conversation = %+v", c)
+	}
+}
+
+func TestParseModelfile(t *testing.T) {
+	modelfile := `# Modelfile generated by "ollama show"
+# To build a new Modelfile based on this, replace FROM with:
+# FROM llama3.2:latest
+
+FROM /home/me/.ollama/models/blobs/sha256-abc
+TEMPLATE """{{ .Prompt }}"""
+SYSTEM You are terse.
+PARAMETER temperature 0.7
+MESSAGE user What's a closure?
+MESSAGE assistant """A function
+that captures "variables"
+from its scope."""
+MESSAGE user "Thanks"
+`
+	model, messages := parseModelfile(modelfile)
+	if model != "llama3.2:latest" {
+		t.Errorf("model = %q, want the name 'ollama show' commented", model)
+	}
+	want := []string{"system:You are terse.", "user:What's a closure?", "assistant:A function\nthat captures \"variables\"\nfrom its scope.", "user:Thanks"}
+	var got []string
+	for _, msg := range messages {
+		got = append(got, msg.Role+":"+msg.Content)
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+
+	if model, _ := parseModelfile("FROM qwen3:8b\nMESSAGE user hi\n"); model != "qwen3:8b" {
+		t.Errorf("model = %q, want qwen3:8b", model)
+	}
+}
+
+func TestImport(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "closures.modelfile"), []byte("FROM qwen3:8b\nMESSAGE user What's a closure?\nMESSAGE assistant A function with state\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "empty"), []byte("FROM qwen3:8b\n"), 0644)
+
+	result, err := Import(FormatOllama, dir)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(result.Imported) != 1 || result.Empty != 1 || result.Skipped != 0 {
+		t.Fatalf("Import() = %+v", result)
+	}
+
+	list, _ := List()
+	if len(list) != 1 || list[0].Title != "closures" || list[0].Model != "qwen3:8b" || list[0].Exchanges() != 1 {
+		t.Fatalf("List() = %+v", list)
+	}
+	// Imported times are kept rather than set to now
+	info, _ := os.Stat(filepath.Join(dir, "closures.modelfile"))
+	if !list[0].Updated.Equal(info.ModTime()) {
+		t.Errorf("Updated = %v, want the file's %v", list[0].Updated, info.ModTime())
+	}
+
+	// Importing again adds nothing
+	result, err = Import(FormatOllama, dir)
+	if err != nil || len(result.Imported) != 0 || result.Skipped != 1 {
+		t.Errorf("second Import() = %+v, %v", result, err)
+	}
+
+	if _, err := Import("slack", dir); err == nil || !strings.Contains(err.Error(), "chatgpt-export") {
+		t.Errorf("Import() of an unknown format error = %v", err)
+	}
+}
+
+func TestImportSameSecond(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	// Many chats started in one second still get their own IDs
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := range 50 {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"id": "w` + strconv.Itoa(i) + `", "created_at": 1700000000, "chat": {"messages": [{"role": "user", "content": "hi"}]}}`)
+	}
+	sb.WriteString("]")
+	path := filepath.Join(t.TempDir(), "export.json")
+	os.WriteFile(path, []byte(sb.String()), 0644)
+
+	result, err := Import(FormatOpenWebUI, path)
+	if err != nil || len(result.Imported) != 50 {
+		t.Fatalf("Import() = %+v, %v", result, err)
+	}
+	if list, _ := List(); len(list) != 50 {
+		t.Errorf("List() = %d conversations, want 50", len(list))
+	}
+}
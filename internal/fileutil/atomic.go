@@ -1,17 +1,52 @@
 package fileutil
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
-// AtomicWriteFile writes data to a temp file then renames it to path.
-// This ensures the file is never partially written on process crash.
+// AtomicWriteFile writes data to a temp file beside path, flushes it to
+// disk, and renames it over path. Readers see the old contents or the new,
+// never a mix, even after a crash or power loss.
 func AtomicWriteFile(path string, data []byte, perm os.FileMode) error {
-	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, data, perm); err != nil {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
 		return err
 	}
-	if err := os.Rename(tmp, path); err != nil {
-		os.Remove(tmp) // Best-effort cleanup
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Without this the rename can reach the disk before the data does,
+	// leaving an empty or truncated file after a power loss
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
 		return err
 	}
+	syncDir(dir)
 	return nil
 }
+
+// syncDir flushes a directory so a rename in it survives a power loss.
+// Best-effort: some platforms can't open or sync directories.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	d.Close()
+}
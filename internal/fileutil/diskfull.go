@@ -0,0 +1,12 @@
+package fileutil
+
+import (
+	"errors"
+	"syscall"
+)
+
+// IsDiskFull reports whether err came from writing to a disk with no space
+// left on it.
+func IsDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
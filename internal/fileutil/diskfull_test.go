@@ -0,0 +1,22 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsDiskFull(t *testing.T) {
+	full := &os.PathError{Op: "write", Path: "model.gguf.partial", Err: syscall.ENOSPC}
+	if !IsDiskFull(full) {
+		t.Error("IsDiskFull(ENOSPC) = false")
+	}
+	if !IsDiskFull(fmt.Errorf("failed to download: %w", full)) {
+		t.Error("IsDiskFull(wrapped ENOSPC) = false")
+	}
+	if IsDiskFull(&os.PathError{Op: "write", Path: "x", Err: syscall.EACCES}) || IsDiskFull(errors.New("HTTP 500")) || IsDiskFull(nil) {
+		t.Error("IsDiskFull() = true for another error")
+	}
+}
@@ -0,0 +1,38 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFS(t *testing.T) {
+	for name, fsys := range map[string]FS{"os": OS, "mem": NewMemFS()} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "state")
+			path := filepath.Join(dir, "state.json")
+
+			if _, err := fsys.ReadFile(path); !os.IsNotExist(err) {
+				t.Errorf("ReadFile() of a missing file = %v, want not exist", err)
+			}
+			if err := fsys.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := fsys.WriteFile(path, []byte("one"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if err := fsys.WriteFile(path, []byte("two"), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			if data, err := fsys.ReadFile(path); err != nil || string(data) != "two" {
+				t.Errorf("ReadFile() = %q, %v, want two", data, err)
+			}
+			if err := fsys.Remove(path); err != nil {
+				t.Fatalf("Remove() error = %v", err)
+			}
+			if err := fsys.Remove(path); !os.IsNotExist(err) {
+				t.Errorf("Remove() of a missing file = %v, want not exist", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package fileutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// FileLock is an exclusive lock on a file. It uses POSIX record locks, which
+// NFS and most other shared filesystems honor across hosts, plus an
+// in-process mutex, since record locks don't exclude a process from itself.
+type FileLock struct {
+	f  *os.File
+	mu *sync.Mutex
+}
+
+var (
+	processLocksMu sync.Mutex
+	processLocks   = map[string]*sync.Mutex{}
+)
+
+// Lock takes an exclusive lock on path, creating the file if needed, and
+// blocks until it's available. If someone else holds it, onWait is called
+// once before blocking. The lock file is left in place after Unlock, since
+// removing it would race with the next holder.
+func Lock(path string, onWait func()) (*FileLock, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	processLocksMu.Lock()
+	mu := processLocks[abs]
+	if mu == nil {
+		mu = &sync.Mutex{}
+		processLocks[abs] = mu
+	}
+	processLocksMu.Unlock()
+
+	waited := false
+	wait := func() {
+		if !waited && onWait != nil {
+			onWait()
+		}
+		waited = true
+	}
+
+	if !mu.TryLock() {
+		wait()
+		mu.Lock()
+	}
+
+	f, err := os.OpenFile(abs, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+
+	lk := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart}
+	err = unix.FcntlFlock(f.Fd(), unix.F_SETLK, &lk)
+	if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+		wait()
+		for {
+			err = unix.FcntlFlock(f.Fd(), unix.F_SETLKW, &lk)
+			if !errors.Is(err, unix.EINTR) {
+				break
+			}
+		}
+	}
+	if err != nil {
+		f.Close()
+		mu.Unlock()
+		return nil, err
+	}
+
+	return &FileLock{f: f, mu: mu}, nil
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	lk := unix.Flock_t{Type: unix.F_UNLCK, Whence: io.SeekStart}
+	err := unix.FcntlFlock(l.f.Fd(), unix.F_SETLK, &lk)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.mu.Unlock()
+	return err
+}
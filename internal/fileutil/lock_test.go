@@ -0,0 +1,49 @@
+package fileutil
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pull.lock")
+
+	first, err := Lock(path, func() { t.Error("first Lock() waited") })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var waited, acquired atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		second, err := Lock(path, func() { waited.Store(true) })
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		acquired.Store(true)
+		second.Unlock()
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if acquired.Load() {
+		t.Fatal("second Lock() acquired a held lock")
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if !waited.Load() || !acquired.Load() {
+		t.Errorf("second Lock() waited = %v, acquired = %v, want both", waited.Load(), acquired.Load())
+	}
+
+	// The lock can be taken again once released
+	again, err := Lock(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Unlock()
+}
@@ -0,0 +1,254 @@
+// Package filter post-processes streamed model responses according to a
+// persona's output filters.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// Filter is a compiled set of output filters. A nil Filter passes text through.
+type Filter struct {
+	cfg      config.OutputFilters
+	replaces []replacement
+}
+
+type replacement struct {
+	re   *regexp.Regexp
+	with string
+}
+
+// Compile validates cfg and returns a Filter, or nil if cfg is nil or empty.
+func Compile(cfg *config.OutputFilters) (*Filter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.MaxLength < 0 {
+		return nil, fmt.Errorf("max_length must not be negative")
+	}
+
+	f := &Filter{cfg: *cfg}
+	for _, r := range cfg.Replace {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replace pattern %q: %w", r.Pattern, err)
+		}
+		f.replaces = append(f.replaces, replacement{re: re, with: r.With})
+	}
+
+	if !cfg.StripThink && !cfg.TrimLeading && cfg.MaxLength == 0 && len(f.replaces) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Stream starts filtering a new response.
+func (f *Filter) Stream() *Stream {
+	if f == nil {
+		return nil
+	}
+
+	s := &Stream{}
+	if f.cfg.StripThink {
+		s.stages = append(s.stages, &stripThink{})
+	}
+	if f.cfg.TrimLeading {
+		s.stages = append(s.stages, &trimLeading{})
+	}
+	if len(f.replaces) > 0 {
+		s.stages = append(s.stages, &replaceLines{replaces: f.replaces})
+	}
+	if f.cfg.MaxLength > 0 {
+		s.stages = append(s.stages, &maxLength{remaining: f.cfg.MaxLength})
+	}
+	return s
+}
+
+// Apply filters a complete response.
+func (f *Filter) Apply(text string) string {
+	s := f.Stream()
+	return s.Write(text) + s.Flush()
+}
+
+// Stream filters one response as it arrives. Text that a filter can't decide
+// on yet, such as a partial tag, is held back until a later Write or Flush.
+// A nil Stream passes text through.
+type Stream struct {
+	stages []stage
+}
+
+type stage interface {
+	write(s string) string
+	flush() string
+}
+
+// Write filters the next chunk and returns the text ready to display.
+func (s *Stream) Write(chunk string) string {
+	if s == nil {
+		return chunk
+	}
+	for _, st := range s.stages {
+		chunk = st.write(chunk)
+	}
+	return chunk
+}
+
+// Flush returns any held-back text once the response is complete.
+func (s *Stream) Flush() string {
+	if s == nil {
+		return ""
+	}
+	var out string
+	for _, st := range s.stages {
+		out = st.write(out) + st.flush()
+	}
+	return out
+}
+
+// stripThink removes <think>...</think> blocks. An unterminated block is
+// dropped to the end of the response.
+type stripThink struct {
+	inThink bool
+	pending string
+}
+
+func (t *stripThink) write(s string) string {
+	buf := t.pending + s
+	t.pending = ""
+
+	var out strings.Builder
+	for {
+		tag := thinkOpen
+		if t.inThink {
+			tag = thinkClose
+		}
+
+		idx := strings.Index(buf, tag)
+		if idx < 0 {
+			// Hold back anything that could be the start of the tag
+			keep := partialSuffix(buf, tag)
+			if !t.inThink {
+				out.WriteString(buf[:len(buf)-keep])
+			}
+			t.pending = buf[len(buf)-keep:]
+			return out.String()
+		}
+
+		if !t.inThink {
+			out.WriteString(buf[:idx])
+		}
+		buf = buf[idx+len(tag):]
+		t.inThink = !t.inThink
+	}
+}
+
+func (t *stripThink) flush() string {
+	pending := t.pending
+	t.pending = ""
+	if t.inThink {
+		return ""
+	}
+	return pending
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialSuffix(s, tag string) int {
+	for n := min(len(s), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// trimLeading drops whitespace before the first visible character.
+type trimLeading struct {
+	started bool
+}
+
+func (t *trimLeading) write(s string) string {
+	if t.started {
+		return s
+	}
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	t.started = s != ""
+	return s
+}
+
+func (t *trimLeading) flush() string { return "" }
+
+// replaceLines applies regex replacements one complete line at a time, so
+// ^ and $ anchor to each line and patterns can't span lines.
+type replaceLines struct {
+	replaces []replacement
+	pending  string
+}
+
+func (r *replaceLines) write(s string) string {
+	r.pending += s
+	idx := strings.LastIndexByte(r.pending, '\n')
+	if idx < 0 {
+		return ""
+	}
+	complete := r.pending[:idx+1]
+	r.pending = r.pending[idx+1:]
+
+	var out strings.Builder
+	for line := range strings.Lines(complete) {
+		out.WriteString(r.apply(strings.TrimSuffix(line, "\n")))
+		out.WriteByte('\n')
+	}
+	return out.String()
+}
+
+func (r *replaceLines) flush() string {
+	pending := r.pending
+	r.pending = ""
+	if pending == "" {
+		return ""
+	}
+	return r.apply(pending)
+}
+
+func (r *replaceLines) apply(line string) string {
+	for _, rep := range r.replaces {
+		line = rep.re.ReplaceAllString(line, rep.with)
+	}
+	return line
+}
+
+// maxLength truncates the response after a number of characters.
+type maxLength struct {
+	remaining int
+}
+
+func (m *maxLength) write(s string) string {
+	if m.remaining <= 0 {
+		return ""
+	}
+	if n := utf8.RuneCountInString(s); n <= m.remaining {
+		m.remaining -= n
+		return s
+	}
+
+	i := 0
+	for range m.remaining {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	m.remaining = 0
+	return s[:i]
+}
+
+func (m *maxLength) flush() string { return "" }
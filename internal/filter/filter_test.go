@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *config.OutputFilters
+		wantNil bool
+		wantErr bool
+	}{
+		{"nil config", nil, true, false},
+		{"empty config", &config.OutputFilters{}, true, false},
+		{"strip think", &config.OutputFilters{StripThink: true}, false, false},
+		{"bad pattern", &config.OutputFilters{Replace: []config.ReplaceFilter{{Pattern: "("}}}, true, true},
+		{"negative max length", &config.OutputFilters{MaxLength: -1}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Compile(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (f == nil) != tt.wantNil {
+				t.Errorf("Compile() = %v, wantNil %v", f, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.OutputFilters
+		input string
+		want  string
+	}{
+		{
+			name:  "strip think block",
+			cfg:   config.OutputFilters{StripThink: true},
+			input: "<think>let me see</think>The answer is 4.",
+			want:  "The answer is 4.",
+		},
+		{
+			name:  "strip several think blocks",
+			cfg:   config.OutputFilters{StripThink: true},
+			input: "a<think>x</think>b<think>y</think>c",
+			want:  "abc",
+		},
+		{
+			name:  "unterminated think block",
+			cfg:   config.OutputFilters{StripThink: true},
+			input: "Hi<think>never closed",
+			want:  "Hi",
+		},
+		{
+			name:  "text resembling a tag is kept",
+			cfg:   config.OutputFilters{StripThink: true},
+			input: "1 <thin 2 <",
+			want:  "1 <thin 2 <",
+		},
+		{
+			name:  "strip think then trim",
+			cfg:   config.OutputFilters{StripThink: true, TrimLeading: true},
+			input: "<think>hmm</think>\n\n  Answer\n",
+			want:  "Answer\n",
+		},
+		{
+			name:  "trim leading only",
+			cfg:   config.OutputFilters{TrimLeading: true},
+			input: "\n\n  Hello  \n world",
+			want:  "Hello  \n world",
+		},
+		{
+			name: "replace per line",
+			cfg: config.OutputFilters{Replace: []config.ReplaceFilter{
+				{Pattern: "^Assistant: ", With: ""},
+				{Pattern: `(\w+)@example\.com`, With: "$1@redacted"},
+			}},
+			input: "Assistant: hi\nAssistant: mail bob@example.com",
+			want:  "hi\nmail bob@redacted",
+		},
+		{
+			name:  "max length counts characters",
+			cfg:   config.OutputFilters{MaxLength: 5},
+			input: "héllo wörld",
+			want:  "héllo",
+		},
+		{
+			name:  "max length after strip think",
+			cfg:   config.OutputFilters{StripThink: true, MaxLength: 3},
+			input: "<think>long reasoning</think>abcdef",
+			want:  "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Compile(&tt.cfg)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+
+			if got := f.Apply(tt.input); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+
+			// Streaming a character at a time must match filtering the whole response
+			s := f.Stream()
+			var b strings.Builder
+			for _, r := range tt.input {
+				b.WriteString(s.Write(string(r)))
+			}
+			b.WriteString(s.Flush())
+			if got := b.String(); got != tt.want {
+				t.Errorf("streamed = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilFilterPassesThrough(t *testing.T) {
+	var f *Filter
+	s := f.Stream()
+	if got := s.Write("hello") + s.Flush(); got != "hello" {
+		t.Errorf("nil filter = %q, want %q", got, "hello")
+	}
+}
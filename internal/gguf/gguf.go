@@ -0,0 +1,491 @@
+// Package gguf reads the metadata of GGUF model files: the header, key-value
+// pairs, and tensor descriptions. It streams through the file and decodes
+// values only when asked, so arrays like a 150k-token vocabulary are skipped
+// rather than loaded.
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+const magic = "GGUF"
+
+// Limits that keep a corrupt or hostile file from triggering huge
+// allocations or deep recursion. Models come from untrusted repos, so a
+// declared length is never trusted further than the data that follows it.
+const (
+	maxStringLen  = 16 << 20
+	maxArrayLen   = 1 << 24
+	maxArrayDepth = 4
+	maxDims       = 8
+
+	// Strings and arrays up to these sizes are allocated at their declared
+	// length; larger ones grow as their data is actually read
+	preallocString = 64 << 10
+	preallocArray  = 1 << 16
+)
+
+// ValueType is the type tag of a metadata value.
+type ValueType uint32
+
+const (
+	TypeUint8 ValueType = iota
+	TypeInt8
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeFloat32
+	TypeBool
+	TypeString
+	TypeArray
+	TypeUint64
+	TypeInt64
+	TypeFloat64
+)
+
+var typeNames = []string{"uint8", "int8", "uint16", "int16", "uint32", "int32", "float32", "bool", "string", "array", "uint64", "int64", "float64"}
+
+func (t ValueType) String() string {
+	if int(t) < len(typeNames) {
+		return typeNames[t]
+	}
+	return fmt.Sprintf("type(%d)", uint32(t))
+}
+
+// size returns the encoded size of a fixed-size type, or 0 for strings and
+// arrays.
+func (t ValueType) size() int64 {
+	switch t {
+	case TypeUint8, TypeInt8, TypeBool:
+		return 1
+	case TypeUint16, TypeInt16:
+		return 2
+	case TypeUint32, TypeInt32, TypeFloat32:
+		return 4
+	case TypeUint64, TypeInt64, TypeFloat64:
+		return 8
+	}
+	return 0
+}
+
+// Header is the fixed part at the start of every GGUF file.
+type Header struct {
+	Version     uint32
+	TensorCount uint64
+	KVCount     uint64
+}
+
+// Array describes an array value without its elements.
+type Array struct {
+	Type ValueType
+	Len  uint64
+}
+
+// TensorInfo describes a tensor. Offset is relative to the start of the
+// tensor data section.
+type TensorInfo struct {
+	Name   string
+	Dims   []uint64
+	Type   uint32 // ggml_type, e.g. 12 for Q4_K
+	Offset uint64
+}
+
+// Elements returns the number of values in the tensor.
+func (t TensorInfo) Elements() uint64 {
+	n := uint64(1)
+	for _, d := range t.Dims {
+		n *= d
+	}
+	return n
+}
+
+// Reader walks a GGUF file's metadata in order: key-value pairs with Next,
+// then tensor descriptions with Tensors.
+type Reader struct {
+	Header Header
+
+	r       *bufio.Reader
+	kvRead  uint64
+	pending *ValueType // Type of the current key's value until it's consumed
+	tensors bool
+	depth   int // Nesting of arrays being skipped
+}
+
+// NewReader reads the header from r. Versions 2 and 3 are supported.
+func NewReader(r io.Reader) (*Reader, error) {
+	gr := &Reader{r: bufio.NewReader(r)}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(gr.r, buf); err != nil {
+		return nil, fmt.Errorf("failed to read magic: %w", err)
+	}
+	if string(buf) != magic {
+		return nil, fmt.Errorf("invalid GGUF magic: %q", string(buf))
+	}
+	if err := binary.Read(gr.r, binary.LittleEndian, &gr.Header.Version); err != nil {
+		return nil, fmt.Errorf("failed to read version: %w", err)
+	}
+	if gr.Header.Version < 2 || gr.Header.Version > 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d (expected 2 or 3)", gr.Header.Version)
+	}
+	if err := binary.Read(gr.r, binary.LittleEndian, &gr.Header.TensorCount); err != nil {
+		return nil, fmt.Errorf("failed to read tensor count: %w", err)
+	}
+	if err := binary.Read(gr.r, binary.LittleEndian, &gr.Header.KVCount); err != nil {
+		return nil, fmt.Errorf("failed to read kv count: %w", err)
+	}
+	return gr, nil
+}
+
+// File is a Reader over an open file.
+type File struct {
+	*Reader
+	f *os.File
+}
+
+// Open opens path and reads its header.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &File{Reader: r, f: f}, nil
+}
+
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// Next advances to the next key-value pair and returns its key and type. The
+// value is skipped unless Value or ReadArray is called before the next call.
+// Next returns io.EOF after the last pair.
+func (r *Reader) Next() (string, ValueType, error) {
+	if err := r.skipPending(); err != nil {
+		return "", 0, err
+	}
+	if r.kvRead >= r.Header.KVCount {
+		return "", 0, io.EOF
+	}
+
+	key, err := r.readString()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read key %d: %w", r.kvRead, err)
+	}
+	var typ ValueType
+	if err := binary.Read(r.r, binary.LittleEndian, &typ); err != nil {
+		return "", 0, fmt.Errorf("failed to read value type for key %q: %w", key, err)
+	}
+	r.kvRead++
+	r.pending = &typ
+	return key, typ, nil
+}
+
+// Value decodes the current value. Scalars come back as their Go types
+// (uint32, float32, bool, string, ...); arrays come back as an Array and
+// their elements are skipped.
+func (r *Reader) Value() (any, error) {
+	if r.pending == nil {
+		return nil, errors.New("no current value")
+	}
+	typ := *r.pending
+	r.pending = nil
+
+	if typ != TypeArray {
+		return r.readScalar(typ)
+	}
+	arr, err := r.readArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if arr.Type == TypeArray {
+		if r.depth >= maxArrayDepth {
+			return nil, errors.New("arrays nested too deeply")
+		}
+		r.depth++
+		defer func() { r.depth-- }()
+	}
+	for i := uint64(0); i < arr.Len && arr.Type.size() == 0; i++ {
+		if err := r.skip(arr.Type); err != nil {
+			return nil, err
+		}
+	}
+	if n := arr.Type.size(); n > 0 {
+		if _, err := r.r.Discard(int(n * int64(arr.Len))); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// ReadArray decodes every element of the current value, which must be an
+// array of scalars or strings.
+func (r *Reader) ReadArray() ([]any, error) {
+	if r.pending == nil || *r.pending != TypeArray {
+		return nil, errors.New("current value is not an array")
+	}
+	r.pending = nil
+
+	arr, err := r.readArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if arr.Type == TypeArray {
+		return nil, errors.New("nested arrays are not supported")
+	}
+	if arr.Len > maxArrayLen {
+		return nil, fmt.Errorf("array too long: %d", arr.Len)
+	}
+	values := make([]any, 0, min(arr.Len, preallocArray))
+	for i := uint64(0); i < arr.Len; i++ {
+		v, err := r.readScalar(arr.Type)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read array element %d of %d: %w", i, arr.Len, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Find reads the values of the given keys, stopping early once all are
+// found. Keys missing from the file are absent from the result.
+func (r *Reader) Find(keys ...string) (map[string]any, error) {
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	found := make(map[string]any, len(keys))
+	for len(found) < len(want) {
+		key, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !want[key] {
+			continue
+		}
+		v, err := r.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value for key %q: %w", key, err)
+		}
+		found[key] = v
+	}
+	return found, nil
+}
+
+// Tensors skips any remaining key-value pairs and reads the tensor
+// descriptions. It can be called once.
+func (r *Reader) Tensors() ([]TensorInfo, error) {
+	if r.tensors {
+		return nil, errors.New("tensors already read")
+	}
+	for {
+		if _, _, err := r.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	r.tensors = true
+
+	var tensors []TensorInfo
+	for i := uint64(0); i < r.Header.TensorCount; i++ {
+		name, err := r.readString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tensor %d name: %w", i, err)
+		}
+		var nDims uint32
+		if err := binary.Read(r.r, binary.LittleEndian, &nDims); err != nil {
+			return nil, fmt.Errorf("failed to read tensor %q: %w", name, err)
+		}
+		if nDims > maxDims {
+			return nil, fmt.Errorf("tensor %q has %d dimensions", name, nDims)
+		}
+		t := TensorInfo{Name: name, Dims: make([]uint64, nDims)}
+		if err := binary.Read(r.r, binary.LittleEndian, t.Dims); err != nil {
+			return nil, fmt.Errorf("failed to read tensor %q: %w", name, err)
+		}
+		if err := binary.Read(r.r, binary.LittleEndian, &t.Type); err != nil {
+			return nil, fmt.Errorf("failed to read tensor %q: %w", name, err)
+		}
+		if err := binary.Read(r.r, binary.LittleEndian, &t.Offset); err != nil {
+			return nil, fmt.Errorf("failed to read tensor %q: %w", name, err)
+		}
+		tensors = append(tensors, t)
+	}
+	return tensors, nil
+}
+
+// AsInt converts an integer value from Value to int64.
+func AsInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+func (r *Reader) skipPending() error {
+	if r.pending == nil {
+		return nil
+	}
+	typ := *r.pending
+	r.pending = nil
+	return r.skip(typ)
+}
+
+func (r *Reader) skip(typ ValueType) error {
+	if n := typ.size(); n > 0 {
+		_, err := r.r.Discard(int(n))
+		return err
+	}
+	switch typ {
+	case TypeString:
+		var length uint64
+		if err := binary.Read(r.r, binary.LittleEndian, &length); err != nil {
+			return err
+		}
+		if length > maxStringLen {
+			return fmt.Errorf("string too long: %d", length)
+		}
+		_, err := r.r.Discard(int(length))
+		return err
+	case TypeArray:
+		r.pending = &typ
+		_, err := r.Value()
+		return err
+	}
+	return fmt.Errorf("unknown GGUF value type: %d", typ)
+}
+
+func (r *Reader) readArrayHeader() (Array, error) {
+	var arr Array
+	if err := binary.Read(r.r, binary.LittleEndian, &arr.Type); err != nil {
+		return arr, err
+	}
+	if err := binary.Read(r.r, binary.LittleEndian, &arr.Len); err != nil {
+		return arr, err
+	}
+	if arr.Type > TypeFloat64 {
+		return arr, fmt.Errorf("unknown GGUF value type: %d", arr.Type)
+	}
+	if n := arr.Type.size(); n > 0 && arr.Len > math.MaxInt32/uint64(n) {
+		return arr, fmt.Errorf("array too long: %d", arr.Len)
+	}
+	return arr, nil
+}
+
+func (r *Reader) readScalar(typ ValueType) (any, error) {
+	var err error
+	switch typ {
+	case TypeUint8:
+		var v uint8
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeInt8:
+		var v int8
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeUint16:
+		var v uint16
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeInt16:
+		var v int16
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeUint32:
+		var v uint32
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeInt32:
+		var v int32
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeFloat32:
+		var v float32
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeBool:
+		var v uint8
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v != 0, err
+	case TypeString:
+		return r.readString()
+	case TypeUint64:
+		var v uint64
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeInt64:
+		var v int64
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	case TypeFloat64:
+		var v float64
+		err = binary.Read(r.r, binary.LittleEndian, &v)
+		return v, err
+	}
+	return nil, fmt.Errorf("unknown GGUF value type: %d", typ)
+}
+
+func (r *Reader) readString() (string, error) {
+	var length uint64
+	if err := binary.Read(r.r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	if length > maxStringLen {
+		return "", fmt.Errorf("string too long: %d", length)
+	}
+	if length > preallocString {
+		var sb strings.Builder
+		if _, err := io.CopyN(&sb, r.r, int64(length)); err != nil {
+			return "", noEOF(err)
+		}
+		return sb.String(), nil
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// noEOF turns io.EOF partway through a value into io.ErrUnexpectedEOF, as
+// io.ReadFull does
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
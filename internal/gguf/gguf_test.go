@@ -0,0 +1,377 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testFile builds a GGUF file in memory.
+type testFile struct {
+	kv      bytes.Buffer
+	kvCount uint64
+	tensors bytes.Buffer
+	tCount  uint64
+}
+
+func (f *testFile) str(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func (f *testFile) add(key string, typ ValueType, value any) {
+	f.str(&f.kv, key)
+	binary.Write(&f.kv, binary.LittleEndian, typ)
+	if s, ok := value.(string); ok {
+		f.str(&f.kv, s)
+	} else {
+		binary.Write(&f.kv, binary.LittleEndian, value)
+	}
+	f.kvCount++
+}
+
+func (f *testFile) addStrings(key string, values ...string) {
+	f.str(&f.kv, key)
+	binary.Write(&f.kv, binary.LittleEndian, TypeArray)
+	binary.Write(&f.kv, binary.LittleEndian, TypeString)
+	binary.Write(&f.kv, binary.LittleEndian, uint64(len(values)))
+	for _, v := range values {
+		f.str(&f.kv, v)
+	}
+	f.kvCount++
+}
+
+func (f *testFile) addTensor(name string, typ uint32, offset uint64, dims ...uint64) {
+	f.str(&f.tensors, name)
+	binary.Write(&f.tensors, binary.LittleEndian, uint32(len(dims)))
+	binary.Write(&f.tensors, binary.LittleEndian, dims)
+	binary.Write(&f.tensors, binary.LittleEndian, typ)
+	binary.Write(&f.tensors, binary.LittleEndian, offset)
+	f.tCount++
+}
+
+func (f *testFile) bytes(version uint32) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("GGUF")
+	binary.Write(&buf, binary.LittleEndian, version)
+	binary.Write(&buf, binary.LittleEndian, f.tCount)
+	binary.Write(&buf, binary.LittleEndian, f.kvCount)
+	buf.Write(f.kv.Bytes())
+	buf.Write(f.tensors.Bytes())
+	return buf.Bytes()
+}
+
+func newTestFile() *testFile {
+	f := &testFile{}
+	f.add("general.architecture", TypeString, "llama")
+	f.addStrings("tokenizer.ggml.tokens", "<s>", "</s>", "hello")
+	f.str(&f.kv, "tokenizer.ggml.scores")
+	binary.Write(&f.kv, binary.LittleEndian, TypeArray)
+	binary.Write(&f.kv, binary.LittleEndian, TypeFloat32)
+	binary.Write(&f.kv, binary.LittleEndian, uint64(3))
+	binary.Write(&f.kv, binary.LittleEndian, []float32{0, 0, -1.5})
+	f.kvCount++
+	f.add("llama.block_count", TypeUint32, uint32(16))
+	f.add("llama.rope.freq_base", TypeFloat32, float32(500000))
+	f.add("general.quantized", TypeBool, uint8(1))
+	f.add("llama.context_length", TypeUint64, uint64(131072))
+	f.addTensor("token_embd.weight", 12, 0, 2048, 128256)
+	f.addTensor("blk.0.attn_q.weight", 12, 1<<20, 2048, 2048)
+	return f
+}
+
+func TestReaderNext(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(newTestFile().bytes(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Version != 3 || r.Header.KVCount != 7 || r.Header.TensorCount != 2 {
+		t.Fatalf("Header = %+v", r.Header)
+	}
+
+	got := make(map[string]any)
+	var keys []string
+	for {
+		key, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		keys = append(keys, key)
+		// Leave one value unread so Next has to skip it
+		if key == "llama.rope.freq_base" {
+			continue
+		}
+		if got[key], err = r.Value(); err != nil {
+			t.Fatalf("Value(%s) error = %v", key, err)
+		}
+	}
+
+	want := map[string]any{
+		"general.architecture":  "llama",
+		"tokenizer.ggml.tokens": Array{Type: TypeString, Len: 3},
+		"tokenizer.ggml.scores": Array{Type: TypeFloat32, Len: 3},
+		"llama.block_count":     uint32(16),
+		"general.quantized":     true,
+		"llama.context_length":  uint64(131072),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %#v, want %#v", got, want)
+	}
+	if len(keys) != 7 {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestReaderFindAndTensors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, newTestFile().bytes(3), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	values, err := f.Find("llama.block_count", "missing.key")
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if n, ok := AsInt(values["llama.block_count"]); !ok || n != 16 {
+		t.Errorf("block_count = %v", values["llama.block_count"])
+	}
+	if _, ok := values["missing.key"]; ok {
+		t.Error("missing key should be absent")
+	}
+
+	tensors, err := f.Tensors()
+	if err != nil {
+		t.Fatalf("Tensors() error = %v", err)
+	}
+	if len(tensors) != 2 || tensors[1].Name != "blk.0.attn_q.weight" || tensors[1].Offset != 1<<20 {
+		t.Fatalf("Tensors() = %+v", tensors)
+	}
+	if tensors[0].Elements() != 2048*128256 {
+		t.Errorf("Elements() = %d", tensors[0].Elements())
+	}
+}
+
+func TestReadArray(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(newTestFile().bytes(2)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		key, _, err := r.Next()
+		if err != nil {
+			t.Fatalf("tokens not found: %v", err)
+		}
+		if key == "tokenizer.ggml.tokens" {
+			break
+		}
+	}
+	tokens, err := r.ReadArray()
+	if err != nil {
+		t.Fatalf("ReadArray() error = %v", err)
+	}
+	if !reflect.DeepEqual(tokens, []any{"<s>", "</s>", "hello"}) {
+		t.Errorf("ReadArray() = %v", tokens)
+	}
+
+	// The reader carries on after the array
+	key, _, err := r.Next()
+	if err != nil || key != "tokenizer.ggml.scores" {
+		t.Errorf("Next() = %q, %v", key, err)
+	}
+}
+
+func TestNewReaderRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad magic", []byte("GGML\x03\x00\x00\x00")},
+		{"version 1", newTestFile().bytes(1)},
+		{"truncated", newTestFile().bytes(3)[:10]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReader(bytes.NewReader(tt.data)); err == nil {
+				t.Error("NewReader() should fail")
+			}
+		})
+	}
+
+	// A string length beyond the limit fails instead of allocating
+	f := &testFile{}
+	binary.Write(&f.kv, binary.LittleEndian, uint64(1<<40))
+	f.kvCount = 1
+	r, err := NewReader(bytes.NewReader(f.bytes(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Next(); err == nil {
+		t.Error("Next() should fail for an oversized key")
+	}
+}
+
+// newBenchFile is shaped like a real model's header: a 128k-token
+// vocabulary ahead of the keys callers want, and a few hundred tensors.
+func newBenchFile() []byte {
+	f := &testFile{}
+	f.add("general.architecture", TypeString, "llama")
+	tokens := make([]string, 128256)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("token%d", i)
+	}
+	f.addStrings("tokenizer.ggml.tokens", tokens...)
+	f.add("llama.block_count", TypeUint32, uint32(32))
+	f.add("llama.context_length", TypeUint64, uint64(131072))
+	for blk := range 32 {
+		for _, name := range []string{"attn_q", "attn_k", "attn_v", "attn_output", "ffn_gate", "ffn_up", "ffn_down", "attn_norm", "ffn_norm"} {
+			f.addTensor(fmt.Sprintf("blk.%d.%s.weight", blk, name), 12, 0, 4096, 4096)
+		}
+	}
+	return f.bytes(3)
+}
+
+func BenchmarkFind(b *testing.B) {
+	data := newBenchFile()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.Find("llama.block_count", "llama.context_length"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadArray(b *testing.B) {
+	data := newBenchFile()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		for {
+			key, _, err := r.Next()
+			if err != nil {
+				b.Fatal(err)
+			}
+			if key == "tokenizer.ggml.tokens" {
+				break
+			}
+		}
+		if _, err := r.ReadArray(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTensors(b *testing.B) {
+	data := newBenchFile()
+	b.SetBytes(int64(len(data)))
+	for b.Loop() {
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := r.Tensors(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestNestedArrayDepth(t *testing.T) {
+	f := &testFile{}
+	f.str(&f.kv, "deep")
+	binary.Write(&f.kv, binary.LittleEndian, TypeArray)
+	for range maxArrayDepth + 2 {
+		binary.Write(&f.kv, binary.LittleEndian, TypeArray)
+		binary.Write(&f.kv, binary.LittleEndian, uint64(1))
+	}
+	f.kvCount = 1
+	r, err := NewReader(bytes.NewReader(f.bytes(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Value(); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("Value() error = %v, want nesting error", err)
+	}
+}
+
+func TestTruncatedArray(t *testing.T) {
+	// An array that claims far more elements than follow fails without
+	// allocating for all of them
+	f := &testFile{}
+	f.str(&f.kv, "tokens")
+	binary.Write(&f.kv, binary.LittleEndian, TypeArray)
+	binary.Write(&f.kv, binary.LittleEndian, TypeString)
+	binary.Write(&f.kv, binary.LittleEndian, uint64(maxArrayLen))
+	f.str(&f.kv, "only one")
+	f.kvCount = 1
+	r, err := NewReader(bytes.NewReader(f.bytes(3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = r.ReadArray()
+	runtime.ReadMemStats(&after)
+	if !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadArray() error = %v, want EOF", err)
+	}
+	if grew := after.TotalAlloc - before.TotalAlloc; grew > 16<<20 {
+		t.Errorf("ReadArray() allocated %d bytes for a truncated array", grew)
+	}
+}
+
+// FuzzReader walks arbitrary input the way callers do. It must return
+// errors, never panic or allocate without bound.
+func FuzzReader(f *testing.F) {
+	f.Add(newTestFile().bytes(3))
+	f.Add(newTestFile().bytes(2))
+	f.Add(newTestFile().bytes(3)[:40])
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+		for {
+			_, typ, err := r.Next()
+			if err != nil {
+				break
+			}
+			if typ == TypeArray {
+				if _, err := r.ReadArray(); err != nil {
+					break
+				}
+			} else if _, err := r.Value(); err != nil {
+				break
+			}
+		}
+		r.Tensors()
+	})
+}
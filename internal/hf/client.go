@@ -11,21 +11,26 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nchapman/lleme/internal/compress"
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/version"
 )
 
 const (
-	baseURL    = "https://huggingface.co"
-	apiBase    = "https://huggingface.co/api"
-	maxRetries = 3
-	retryDelay = 1 * time.Second
+	// DefaultEndpoint is the Hugging Face Hub, used unless the config or
+	// HF_ENDPOINT points at a mirror such as a lleme cache
+	DefaultEndpoint = "https://huggingface.co"
+	maxRetries      = 3
+	retryDelay      = 1 * time.Second
 )
 
 type Client struct {
 	httpClient     *http.Client
 	downloadClient *http.Client
 	token          string
+	endpoint       string
 }
 
 type ModelInfo struct {
@@ -110,6 +115,8 @@ type ManifestFile struct {
 	RFilename string       `json:"rfilename"`
 	Size      int64        `json:"size"`
 	LFS       *ManifestLFS `json:"lfs"`
+	ETag      string       `json:"etag,omitempty"` // Object version from sources without SHA256 hashes (local augmentation)
+	MD5       string       `json:"md5,omitempty"`  // Content MD5 when the source has no SHA256 (local augmentation)
 }
 
 // Manifest represents the HuggingFace manifest API response.
@@ -123,23 +130,41 @@ type Manifest struct {
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Transport:     logs.Transport(compress.Transport(nil)),
+			Timeout:       30 * time.Second,
+			CheckRedirect: checkRedirect,
 		},
 		downloadClient: &http.Client{
-			Transport: &http.Transport{
+			Transport: logs.Transport(&http.Transport{
 				ResponseHeaderTimeout: 30 * time.Second,
-			},
+			}),
+			CheckRedirect: checkRedirect,
 		},
-		token: getToken(cfg),
+		token:    Token(cfg),
+		endpoint: Endpoint(cfg),
 	}
 }
 
-func getToken(cfg *config.Config) string {
+// Endpoint returns the Hub URL to use: HF_ENDPOINT, then the config, then
+// the public Hub.
+func Endpoint(cfg *config.Config) string {
+	if endpoint := os.Getenv("HF_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+	if cfg != nil && cfg.HuggingFace.Endpoint != "" {
+		return strings.TrimSuffix(cfg.HuggingFace.Endpoint, "/")
+	}
+	return DefaultEndpoint
+}
+
+// Token returns the Hugging Face token from HF_TOKEN, the huggingface-cli
+// token file, or the config, in that order.
+func Token(cfg *config.Config) string {
 	if token := os.Getenv("HF_TOKEN"); token != "" {
 		return token
 	}
 
-	tokenPath := filepath.Join(config.UserHomeDir(), ".cache", "huggingface", "token")
+	tokenPath := filepath.Join(paths.Home(), ".cache", "huggingface", "token")
 	if data, err := os.ReadFile(tokenPath); err == nil {
 		return strings.TrimSpace(string(data))
 	}
@@ -149,7 +174,7 @@ func getToken(cfg *config.Config) string {
 
 // HasToken returns true if a HuggingFace token is available from any source.
 func HasToken(cfg *config.Config) bool {
-	return getToken(cfg) != ""
+	return Token(cfg) != ""
 }
 
 func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
@@ -180,7 +205,7 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 }
 
 func (c *Client) GetModel(user, repo string) (*ModelInfo, error) {
-	url := fmt.Sprintf("%s/models/%s/%s", apiBase, user, repo)
+	url := fmt.Sprintf("%s/api/models/%s/%s", c.endpoint, user, repo)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -213,9 +238,9 @@ func (c *Client) ListFiles(user, repo, branch string) ([]FileTree, error) {
 func (c *Client) ListFilesInPath(user, repo, branch, path string) ([]FileTree, error) {
 	var urlStr string
 	if path == "" {
-		urlStr = fmt.Sprintf("%s/models/%s/%s/tree/%s", apiBase, user, repo, branch)
+		urlStr = fmt.Sprintf("%s/api/models/%s/%s/tree/%s", c.endpoint, user, repo, branch)
 	} else {
-		urlStr = fmt.Sprintf("%s/models/%s/%s/tree/%s/%s", apiBase, user, repo, branch, path)
+		urlStr = fmt.Sprintf("%s/api/models/%s/%s/tree/%s/%s", c.endpoint, user, repo, branch, path)
 	}
 	req, err := http.NewRequest("GET", urlStr, nil)
 	if err != nil {
@@ -243,7 +268,7 @@ func (c *Client) ListFilesInPath(user, repo, branch, path string) ([]FileTree, e
 
 // GetFileSize returns the size of a file in a repository using a HEAD request.
 func (c *Client) GetFileSize(user, repo, branch, filename string) (int64, error) {
-	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", baseURL, user, repo, branch, filename)
+	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", c.endpoint, user, repo, branch, filename)
 	req, err := http.NewRequest("HEAD", url, nil)
 	if err != nil {
 		return 0, err
@@ -264,7 +289,7 @@ func (c *Client) GetFileSize(user, repo, branch, filename string) (int64, error)
 
 func (c *Client) SearchModels(query string, limit int) ([]SearchResult, error) {
 	// Use models-json endpoint with apps=llama.cpp filter for llama.cpp compatible models
-	searchURL := fmt.Sprintf("%s/models-json?apps=llama.cpp&pipeline_tag=text-generation&sort=trending", baseURL)
+	searchURL := fmt.Sprintf("%s/models-json?apps=llama.cpp&pipeline_tag=text-generation&sort=trending", c.endpoint)
 	if query != "" {
 		searchURL += "&search=" + url.QueryEscape(query)
 	}
@@ -299,7 +324,7 @@ func (c *Client) SearchModels(query string, limit int) ([]SearchResult, error) {
 }
 
 func (c *Client) DownloadFile(user, repo, branch, filename string, progress func(int64, int64)) (string, error) {
-	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", baseURL, user, repo, branch, filename)
+	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", c.endpoint, user, repo, branch, filename)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return "", err
@@ -315,7 +340,7 @@ func (c *Client) DownloadFile(user, repo, branch, filename string, progress func
 		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
-	tmpPath := filepath.Join(config.BinPath(), filename+".partial")
+	tmpPath := filepath.Join(paths.Bin(), filename+".partial")
 	out, err := os.Create(tmpPath)
 	if err != nil {
 		return "", err
@@ -346,7 +371,7 @@ func (c *Client) DownloadFile(user, repo, branch, filename string, progress func
 		}
 	}
 
-	finalPath := filepath.Join(config.BinPath(), filename)
+	finalPath := filepath.Join(paths.Bin(), filename)
 	if err := os.Rename(tmpPath, finalPath); err != nil {
 		return "", err
 	}
@@ -359,7 +384,7 @@ func (c *Client) DownloadFile(user, repo, branch, filename string, progress func
 // The tag parameter is typically a quantization level like "Q4_K_M".
 // Returns both the parsed manifest and the raw JSON bytes for saving to disk.
 func (c *Client) GetManifest(user, repo, tag string) (*Manifest, []byte, error) {
-	url := fmt.Sprintf("%s/v2/%s/%s/manifests/%s", baseURL, user, repo, tag)
+	url := fmt.Sprintf("%s/v2/%s/%s/manifests/%s", c.endpoint, user, repo, tag)
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return nil, nil, err
@@ -380,17 +405,19 @@ func (c *Client) GetManifest(user, repo, tag string) (*Manifest, []byte, error)
 		return nil, nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
 	}
 
-	rawJSON, err := io.ReadAll(resp.Body)
+	// Read one byte past the limit so an oversized manifest is rejected
+	// rather than cut off
+	rawJSON, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize+1))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	var manifest Manifest
-	if err := json.Unmarshal(rawJSON, &manifest); err != nil {
+	manifest, err := ParseManifest(rawJSON)
+	if err != nil {
 		return nil, nil, err
 	}
 
-	return &manifest, rawJSON, nil
+	return manifest, rawJSON, nil
 }
 
 // FetchFolderQuantSizes fills in Size for folder-style quantizations by
@@ -0,0 +1,73 @@
+package hf
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/ui"
+)
+
+// freeDisk reports free bytes on a path's disk; tests replace it
+var freeDisk = hw.FreeDisk
+
+// DiskFullError is returned when a pull won't fit on disk, either before it
+// starts or when a write runs out of space partway through.
+type DiskFullError struct {
+	Model  string
+	Path   string // Directory the model is downloaded to
+	Needed int64  // Bytes still to download
+	Free   int64  // Bytes free on Path's disk
+}
+
+func (e *DiskFullError) Error() string {
+	return fmt.Sprintf("not enough disk space to pull '%s': needs %s more but only %s is free in %s (free up space or remove unused models with 'lleme rm', then pull again)",
+		e.Model, ui.FormatBytes(e.Needed), ui.FormatBytes(e.Free), e.Path)
+}
+
+// checkDiskSpace returns a DiskFullError when files won't fit in dir. The
+// pull goes ahead when free space can't be read.
+func checkDiskSpace(model, dir string, files []fileDownload, keepComplete bool) error {
+	free, err := freeDisk(dir)
+	if err != nil {
+		return nil
+	}
+	if needed := bytesNeeded(files, keepComplete); needed > free {
+		return &DiskFullError{Model: model, Path: dir, Needed: needed, Free: free}
+	}
+	return nil
+}
+
+// bytesNeeded returns how many bytes are left to write for files, counting
+// partial downloads as written and skipping files the pull will keep.
+func bytesNeeded(files []fileDownload, keepComplete bool) int64 {
+	var needed int64
+	for _, fd := range files {
+		if fd.file.LFS != nil && fd.file.LFS.SHA256 != "" && isVerified(fd.destPath, fd.file.LFS.SHA256) {
+			continue
+		}
+		if keepComplete {
+			if info, err := os.Stat(fd.destPath); err == nil && info.Size() == fd.file.Size {
+				continue
+			}
+		}
+		needed += fd.file.Size
+		if info, err := os.Stat(fd.destPath + ".partial"); err == nil && info.Size() <= fd.file.Size {
+			needed -= info.Size()
+		}
+	}
+	return needed
+}
+
+// diskFullError removes the partial downloads a write ran out of space on,
+// so the disk isn't left full, and describes what the pull still needs.
+func diskFullError(model, dir string, files []fileDownload, keepComplete bool) *DiskFullError {
+	for _, fd := range files {
+		os.Remove(fd.destPath + ".partial")
+	}
+	e := &DiskFullError{Model: model, Path: dir, Needed: bytesNeeded(files, keepComplete)}
+	e.Free, _ = freeDisk(dir)
+	logs.Warn("Disk full during pull", "model", model, "path", dir, "needed", e.Needed, "free", e.Free)
+	return e
+}
@@ -0,0 +1,103 @@
+package hf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// fullDiskSource writes part of each file, then fails like a full disk
+type fullDiskSource struct {
+	*memorySource
+}
+
+func (s fullDiskSource) Download(user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
+	partialPath := destPath + ".partial"
+	if err := os.WriteFile(partialPath, s.files[file.RFilename][:4], 0644); err != nil {
+		return err
+	}
+	return &os.PathError{Op: "write", Path: partialPath, Err: syscall.ENOSPC}
+}
+
+func useFreeDisk(t *testing.T, free int64) {
+	t.Helper()
+	orig := freeDisk
+	freeDisk = func(string) (int64, error) { return free, nil }
+	t.Cleanup(func() { freeDisk = orig })
+}
+
+func TestPullModelChecksDiskSpace(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	useFreeDisk(t, 4)
+
+	source := newMemorySource(map[string][]byte{"model-Q4_K_M.gguf": []byte("gguf data")}, "model-Q4_K_M.gguf")
+	_, err := PullModel(source, "user", "repo", Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}, nil, nil)
+
+	var diskErr *DiskFullError
+	if !errors.As(err, &diskErr) {
+		t.Fatalf("PullModel() error = %v, want DiskFullError", err)
+	}
+	if diskErr.Needed != 9 || diskErr.Free != 4 || diskErr.Path != GetModelPath("user", "repo") {
+		t.Errorf("DiskFullError = %+v", diskErr)
+	}
+	if source.downloads != 0 {
+		t.Error("PullModel() downloaded without enough space")
+	}
+	if !strings.Contains(err.Error(), "user/repo:Q4_K_M") || !strings.Contains(err.Error(), diskErr.Path) {
+		t.Errorf("Error() = %q, want the model and path", err)
+	}
+}
+
+func TestPullModelDiskFull(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	useFreeDisk(t, 1<<30)
+
+	source := fullDiskSource{newMemorySource(map[string][]byte{"model-Q4_K_M.gguf": []byte("gguf data")}, "model-Q4_K_M.gguf")}
+	_, err := PullModel(source, "user", "repo", Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}, nil, nil)
+
+	var diskErr *DiskFullError
+	if !errors.As(err, &diskErr) {
+		t.Fatalf("PullModel() error = %v, want DiskFullError", err)
+	}
+	if diskErr.Needed != 9 || diskErr.Free != 1<<30 {
+		t.Errorf("DiskFullError = %+v", diskErr)
+	}
+	if _, err := os.Stat(GetModelFilePath("user", "repo", "Q4_K_M") + ".partial"); !os.IsNotExist(err) {
+		t.Error("partial download left behind on a full disk")
+	}
+}
+
+func TestBytesNeeded(t *testing.T) {
+	dir := t.TempDir()
+	complete := filepath.Join(dir, "complete.gguf")
+	partial := filepath.Join(dir, "partial.gguf")
+	os.WriteFile(complete, make([]byte, 100), 0644)
+	os.WriteFile(partial+".partial", make([]byte, 30), 0644)
+
+	files := []fileDownload{
+		{file: &ManifestFile{Size: 100}, destPath: complete},
+		{file: &ManifestFile{Size: 100}, destPath: partial},
+		{file: &ManifestFile{Size: 100}, destPath: filepath.Join(dir, "missing.gguf")},
+	}
+	if got := bytesNeeded(files, false); got != 270 {
+		t.Errorf("bytesNeeded() = %d, want 270", got)
+	}
+	// A pull that waited on another keeps its complete files
+	if got := bytesNeeded(files, true); got != 170 {
+		t.Errorf("bytesNeeded(keepComplete) = %d, want 170", got)
+	}
+}
+
+func TestCheckDiskSpaceUnknown(t *testing.T) {
+	orig := freeDisk
+	freeDisk = func(string) (int64, error) { return 0, errors.ErrUnsupported }
+	t.Cleanup(func() { freeDisk = orig })
+
+	files := []fileDownload{{file: &ManifestFile{Size: 100}, destPath: filepath.Join(t.TempDir(), "model.gguf")}}
+	if err := checkDiskSpace("user/repo:Q4_K_M", t.TempDir(), files, false); err != nil {
+		t.Errorf("checkDiskSpace() = %v, want nil when free space is unknown", err)
+	}
+}
@@ -11,7 +11,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/version"
 	"gopkg.in/yaml.v3"
 )
@@ -47,7 +47,7 @@ func NewDownloaderWithProgress(client *Client, progress ProgressCallback) *Downl
 }
 
 func (d *Downloader) DownloadModel(user, repo, branch, filename string, destPath string) (*DownloadProgress, error) {
-	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", baseURL, user, repo, branch, filename)
+	url := fmt.Sprintf("%s/%s/%s/resolve/%s/%s", d.client.endpoint, user, repo, branch, filename)
 
 	partialPath := destPath + ".partial"
 	fileSize := int64(0)
@@ -165,8 +165,16 @@ func CalculateSHA256(filePath string) (string, error) {
 	return CalculateSHA256WithProgress(filePath, nil)
 }
 
+// hashBlockSize is the read size for hashing. Large reads keep the disk
+// streaming while the previous block is hashed.
+const hashBlockSize = 1 << 20
+
 // CalculateSHA256WithProgress computes sha256 hash with optional progress callback.
 // The callback receives bytes processed and total size.
+//
+// SHA-256 is sequential and the expected hashes cover whole files, so one
+// file can't be split across cores. Instead the next block is read while the
+// current one is hashed, overlapping disk and CPU.
 func CalculateSHA256WithProgress(filePath string, progress func(processed, total int64)) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -179,26 +187,47 @@ func CalculateSHA256WithProgress(filePath string, progress func(processed, total
 		return "", err
 	}
 	totalSize := info.Size()
+	adviseSequential(file)
+
+	// Two buffers circulate: one being filled, one being hashed
+	free := make(chan []byte, 2)
+	free <- make([]byte, hashBlockSize)
+	free <- make([]byte, hashBlockSize)
+	blocks := make(chan []byte, 1)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(blocks)
+		for {
+			buf := <-free
+			n, err := io.ReadFull(file, buf)
+			if n > 0 {
+				blocks <- buf[:n]
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				readErr <- nil
+				return
+			}
+			if err != nil {
+				readErr <- err
+				return
+			}
+		}
+	}()
 
 	hash := sha256.New()
-	buf := make([]byte, 32*1024)
 	processed := int64(0)
-
-	for {
-		n, err := file.Read(buf)
-		if n > 0 {
-			hash.Write(buf[:n])
-			processed += int64(n)
-			if progress != nil {
-				progress(processed, totalSize)
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
+	for block := range blocks {
+		hash.Write(block)
+		dropCached(file, processed, int64(len(block)))
+		processed += int64(len(block))
+		if progress != nil {
+			progress(processed, totalSize)
 		}
+		free <- block[:cap(block)]
+	}
+	if err := <-readErr; err != nil {
+		return "", err
 	}
 
 	return hex.EncodeToString(hash.Sum(nil)), nil
@@ -214,7 +243,7 @@ func VerifySHA256(filePath, expectedHash string) (bool, error) {
 }
 
 func GetModelPath(user, repo string) string {
-	return filepath.Join(config.ModelsPath(), user, repo)
+	return filepath.Join(paths.Models(), user, repo)
 }
 
 func GetModelFilePath(user, repo, quant string) string {
@@ -259,8 +288,56 @@ func GetManifestFilePath(user, repo, quant string) string {
 	return filepath.Join(modelDir, quant+"-manifest.json")
 }
 
+// GetPullLockPath returns the lock file held while a quant is being pulled.
+func GetPullLockPath(user, repo, quant string) string {
+	return filepath.Join(GetModelPath(user, repo), "."+quant+".lock")
+}
+
+// RemoveModel deletes a downloaded quant: its GGUF file or split directory
+// and the manifest, mmproj and pull lock beside it. The repo and user
+// directories go too once they're empty.
+func RemoveModel(user, repo, quant string) error {
+	splitDir := GetSplitModelDir(user, repo, quant)
+	if info, err := os.Stat(splitDir); err == nil && info.IsDir() {
+		if err := os.RemoveAll(splitDir); err != nil {
+			return err
+		}
+	} else {
+		modelPath := FindModelFile(user, repo, quant)
+		if modelPath == "" {
+			modelPath = GetModelFilePath(user, repo, quant)
+		}
+		if err := os.Remove(modelPath); err != nil {
+			return err
+		}
+	}
+
+	// These may not exist; errors are expected and safe to ignore
+	os.Remove(GetManifestFilePath(user, repo, quant))
+	os.Remove(GetMMProjFilePath(user, repo, quant))
+	os.Remove(GetPullLockPath(user, repo, quant))
+
+	modelDir := GetModelPath(user, repo)
+	removeEmptyDir(modelDir)
+	removeEmptyDir(filepath.Dir(modelDir))
+	return nil
+}
+
+// removeEmptyDir removes a directory if it's empty
+func removeEmptyDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	if len(entries) == 0 {
+		os.Remove(dir)
+	}
+}
+
 // ModelMetadata stores metadata for a downloaded model repository.
 type ModelMetadata struct {
+	Tags   []string                 `yaml:"tags,omitempty"`
+	Note   string                   `yaml:"note,omitempty"`
 	Quants map[string]QuantMetadata `yaml:"quants"`
 }
 
@@ -268,6 +345,7 @@ type ModelMetadata struct {
 type QuantMetadata struct {
 	LastUsed     time.Time `yaml:"last_used,omitempty"`
 	DownloadedAt time.Time `yaml:"downloaded_at,omitempty"`
+	GPULayers    *int      `yaml:"gpu_layers,omitempty"` // Most layers found to fit in VRAM
 }
 
 // GetMetadataPath returns the path to the metadata.yaml file for a model repo.
@@ -329,6 +407,50 @@ func GetLastUsed(user, repo, quant string) time.Time {
 	return meta.Quants[quant].LastUsed
 }
 
+// GetGPULayers returns the gpu-layers recorded as fitting in VRAM for a
+// model, if a load has had to find it.
+func GetGPULayers(user, repo, quant string) (int, bool) {
+	meta, err := LoadMetadata(user, repo)
+	if err != nil || meta.Quants[quant].GPULayers == nil {
+		return 0, false
+	}
+	return *meta.Quants[quant].GPULayers, true
+}
+
+// SetGPULayers records the gpu-layers that fit in VRAM for a model.
+func SetGPULayers(user, repo, quant string, layers int) error {
+	meta, err := LoadMetadata(user, repo)
+	if err != nil {
+		return err
+	}
+
+	q := meta.Quants[quant]
+	q.GPULayers = &layers
+	meta.Quants[quant] = q
+
+	return SaveMetadata(user, repo, meta)
+}
+
+// SetTags replaces the tags for a model repo.
+func SetTags(user, repo string, tags []string) error {
+	meta, err := LoadMetadata(user, repo)
+	if err != nil {
+		return err
+	}
+	meta.Tags = tags
+	return SaveMetadata(user, repo, meta)
+}
+
+// SetNote replaces the note for a model repo. An empty note removes it.
+func SetNote(user, repo, note string) error {
+	meta, err := LoadMetadata(user, repo)
+	if err != nil {
+		return err
+	}
+	meta.Note = note
+	return SaveMetadata(user, repo, meta)
+}
+
 // FindFirstSplitFile finds the first split file (-00001-of-NNNNN) in a directory.
 // Returns empty string if no split file is found.
 func FindFirstSplitFile(dir string) string {
@@ -359,8 +481,8 @@ func FindMMProjFile(user, repo, quant string) string {
 }
 
 func CleanupPartialFiles() (int, error) {
-	binDir := config.BinPath()
-	modelsDir := config.ModelsPath()
+	binDir := paths.Bin()
+	modelsDir := paths.Models()
 
 	dirs := []string{binDir, modelsDir}
 	count := 0
@@ -1,8 +1,14 @@
 package hf
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -128,6 +134,37 @@ func TestCalculateSHA256(t *testing.T) {
 	}
 }
 
+func TestCalculateSHA256MultipleBlocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "big.bin")
+
+	// Not a multiple of the block size, so the last read is short
+	content := bytes.Repeat([]byte("0123456789abcdef"), (2*hashBlockSize+12345)/16)
+	if err := os.WriteFile(testFile, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last, calls int64
+	hash, err := CalculateSHA256WithProgress(testFile, func(processed, total int64) {
+		if processed < last || total != int64(len(content)) {
+			t.Errorf("progress(%d, %d) after %d", processed, total, last)
+		}
+		last = processed
+		calls++
+	})
+	if err != nil {
+		t.Fatalf("CalculateSHA256WithProgress() error = %v", err)
+	}
+
+	sum := sha256.Sum256(content)
+	if want := hex.EncodeToString(sum[:]); hash != want {
+		t.Errorf("CalculateSHA256WithProgress() = %v, want %v", hash, want)
+	}
+	if last != int64(len(content)) || calls != 3 {
+		t.Errorf("progress ended at %d after %d calls, want %d after 3", last, calls, len(content))
+	}
+}
+
 func TestVerifySHA256(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile := filepath.Join(tmpDir, "test.txt")
@@ -412,3 +449,186 @@ func TestCleanupPartialFilesEmptyDirs(t *testing.T) {
 		t.Errorf("CleanupPartialFiles() count = %d, want 0", count)
 	}
 }
+
+func TestSetTagsAndNote(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	if err := os.MkdirAll(GetModelPath("user", "repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := TouchLastUsed("user", "repo", "Q4_K_M"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetTags("user", "repo", []string{"coding", "work"}); err != nil {
+		t.Fatalf("SetTags() error = %v", err)
+	}
+	if err := SetNote("user", "repo", "great for SQL"); err != nil {
+		t.Fatalf("SetNote() error = %v", err)
+	}
+
+	meta, err := LoadMetadata("user", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(meta.Tags, []string{"coding", "work"}) {
+		t.Errorf("Tags = %v, want [coding work]", meta.Tags)
+	}
+	if meta.Note != "great for SQL" {
+		t.Errorf("Note = %q, want %q", meta.Note, "great for SQL")
+	}
+	if meta.Quants["Q4_K_M"].LastUsed.IsZero() {
+		t.Error("SetTags/SetNote should preserve quant metadata")
+	}
+}
+
+func TestGPULayers(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	if err := os.MkdirAll(GetModelPath("user", "repo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetGPULayers("user", "repo", "Q4_K_M"); ok {
+		t.Error("GetGPULayers() should be unset for a new model")
+	}
+	if err := TouchLastUsed("user", "repo", "Q4_K_M"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Zero, CPU only, is a value worth keeping
+	if err := SetGPULayers("user", "repo", "Q4_K_M", 0); err != nil {
+		t.Fatalf("SetGPULayers() error = %v", err)
+	}
+	if layers, ok := GetGPULayers("user", "repo", "Q4_K_M"); !ok || layers != 0 {
+		t.Errorf("GetGPULayers() = %d, %v, want 0, true", layers, ok)
+	}
+	if err := SetGPULayers("user", "repo", "Q4_K_M", 20); err != nil {
+		t.Fatal(err)
+	}
+	if layers, _ := GetGPULayers("user", "repo", "Q4_K_M"); layers != 20 {
+		t.Errorf("GetGPULayers() = %d, want 20", layers)
+	}
+	if GetLastUsed("user", "repo", "Q4_K_M").IsZero() {
+		t.Error("SetGPULayers should preserve quant metadata")
+	}
+	if _, ok := GetGPULayers("user", "repo", "Q8_0"); ok {
+		t.Error("GetGPULayers() should be per quant")
+	}
+}
+
+func TestRemoveModel(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	modelDir := GetModelPath("user", "repo")
+	splitDir := GetSplitModelDir("user", "repo", "Q8_0")
+	if err := os.MkdirAll(splitDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		GetModelFilePath("user", "repo", "Q4_K_M"),
+		GetManifestFilePath("user", "repo", "Q4_K_M"),
+		GetMMProjFilePath("user", "repo", "Q4_K_M"),
+		filepath.Join(splitDir, "model-00001-of-00002.gguf"),
+		filepath.Join(splitDir, "model-00002-of-00002.gguf"),
+	} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RemoveModel("user", "repo", "Q4_K_M"); err != nil {
+		t.Fatalf("RemoveModel() error = %v", err)
+	}
+	for _, path := range []string{
+		GetModelFilePath("user", "repo", "Q4_K_M"),
+		GetManifestFilePath("user", "repo", "Q4_K_M"),
+		GetMMProjFilePath("user", "repo", "Q4_K_M"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists", filepath.Base(path))
+		}
+	}
+	if _, err := os.Stat(splitDir); err != nil {
+		t.Error("RemoveModel() removed another quant")
+	}
+
+	// Removing the last quant cleans up the empty repo and user directories
+	if err := RemoveModel("user", "repo", "Q8_0"); err != nil {
+		t.Fatalf("RemoveModel() split error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(modelDir)); !os.IsNotExist(err) {
+		t.Error("RemoveModel() left the empty user directory")
+	}
+
+	if err := RemoveModel("user", "repo", "Q4_K_M"); err == nil {
+		t.Error("RemoveModel() of a missing model should fail")
+	}
+}
+
+func TestRemoveEmptyDir(t *testing.T) {
+	t.Run("removes empty directory", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		emptyDir := filepath.Join(tmpDir, "empty")
+		if err := os.Mkdir(emptyDir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+
+		removeEmptyDir(emptyDir)
+
+		if _, err := os.Stat(emptyDir); !os.IsNotExist(err) {
+			t.Error("removeEmptyDir() did not remove empty directory")
+		}
+	})
+
+	t.Run("keeps non-empty directory", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		nonEmptyDir := filepath.Join(tmpDir, "nonempty")
+		if err := os.Mkdir(nonEmptyDir, 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(nonEmptyDir, "file.txt"), []byte("data"), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+
+		removeEmptyDir(nonEmptyDir)
+
+		if _, err := os.Stat(nonEmptyDir); os.IsNotExist(err) {
+			t.Error("removeEmptyDir() removed non-empty directory")
+		}
+	})
+
+	t.Run("handles non-existent directory", func(t *testing.T) {
+		// Should not panic
+		removeEmptyDir("/nonexistent/path/that/does/not/exist")
+	})
+}
+
+// benchFileSize is large enough that per-call setup doesn't dominate
+const benchFileSize = 64 << 20
+
+func BenchmarkDownloadModel(b *testing.B) {
+	content := bytes.Repeat([]byte("gguf"), benchFileSize/4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "model.gguf", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	d := NewDownloader(&Client{endpoint: srv.URL, downloadClient: srv.Client()})
+	dest := filepath.Join(b.TempDir(), "model.gguf")
+	b.SetBytes(benchFileSize)
+	for b.Loop() {
+		if _, err := d.DownloadModel("user", "repo", "main", "model.gguf", dest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCalculateSHA256(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("gguf"), benchFileSize/4), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(benchFileSize)
+	for b.Loop() {
+		if _, err := CalculateSHA256(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package hf
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// adviseSequential tells the kernel f will be read once, front to back, so
+// it reads ahead aggressively.
+func adviseSequential(f *os.File) {
+	unix.Fadvise(int(f.Fd()), 0, 0, unix.FADV_SEQUENTIAL)
+}
+
+// dropCached releases a range of f that has been hashed from the page cache,
+// so verifying a 50GB model doesn't evict everything else cached.
+func dropCached(f *os.File, offset, length int64) {
+	unix.Fadvise(int(f.Fd()), offset, length, unix.FADV_DONTNEED)
+}
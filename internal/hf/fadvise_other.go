@@ -0,0 +1,11 @@
+//go:build !linux
+
+package hf
+
+import "os"
+
+// adviseSequential is a no-op where posix_fadvise isn't available.
+func adviseSequential(f *os.File) {}
+
+// dropCached is a no-op where posix_fadvise isn't available.
+func dropCached(f *os.File, offset, length int64) {}
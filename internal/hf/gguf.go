@@ -1,37 +1,19 @@
 package hf
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
-)
 
-// GGUF format constants
-const (
-	ggufMagic = "GGUF"
-
-	// GGUF value types
-	ggufTypeUint8   = 0
-	ggufTypeInt8    = 1
-	ggufTypeUint16  = 2
-	ggufTypeInt16   = 3
-	ggufTypeUint32  = 4
-	ggufTypeInt32   = 5
-	ggufTypeFloat32 = 6
-	ggufTypeBool    = 7
-	ggufTypeString  = 8
-	ggufTypeArray   = 9
-	ggufTypeUint64  = 10
-	ggufTypeInt64   = 11
-	ggufTypeFloat64 = 12
-
-	// Key for split count
-	keySplitCount = "split.count"
+	"github.com/nchapman/lleme/internal/gguf"
 )
 
+// Key for split count
+const keySplitCount = "split.count"
+
 // SplitFilePattern matches split GGUF files like "model-00001-of-00002.gguf"
 var SplitFilePattern = regexp.MustCompile(`-(\d{5})-of-(\d{5})\.gguf$`)
 
@@ -56,133 +38,25 @@ func ReadGGUFHeader(path string) (*GGUFHeader, error) {
 }
 
 func readGGUFHeader(r io.Reader) (*GGUFHeader, error) {
-	// Read and verify magic
-	magic := make([]byte, 4)
-	if _, err := io.ReadFull(r, magic); err != nil {
-		return nil, fmt.Errorf("failed to read magic: %w", err)
-	}
-	if string(magic) != ggufMagic {
-		return nil, fmt.Errorf("invalid GGUF magic: %q", string(magic))
-	}
-
-	// Read version
-	var version uint32
-	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
-		return nil, fmt.Errorf("failed to read version: %w", err)
-	}
-
-	// Read tensor count
-	var tensorCnt int64
-	if err := binary.Read(r, binary.LittleEndian, &tensorCnt); err != nil {
-		return nil, fmt.Errorf("failed to read tensor count: %w", err)
-	}
-
-	// Read KV count
-	var kvCnt int64
-	if err := binary.Read(r, binary.LittleEndian, &kvCnt); err != nil {
-		return nil, fmt.Errorf("failed to read kv count: %w", err)
+	gr, err := gguf.NewReader(r)
+	if err != nil {
+		return nil, err
 	}
 
 	header := &GGUFHeader{
-		Version:   version,
-		TensorCnt: tensorCnt,
-		KVCnt:     kvCnt,
-	}
-
-	// Read KV pairs to find split.count
-	for i := int64(0); i < kvCnt; i++ {
-		key, err := readGGUFString(r)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read key %d: %w", i, err)
-		}
-
-		var valType int32
-		if err := binary.Read(r, binary.LittleEndian, &valType); err != nil {
-			return nil, fmt.Errorf("failed to read value type for key %q: %w", key, err)
-		}
-
-		// If this is the split.count key, read it as uint16
-		if key == keySplitCount && valType == ggufTypeUint16 {
-			var splitCount uint16
-			if err := binary.Read(r, binary.LittleEndian, &splitCount); err != nil {
-				return nil, fmt.Errorf("failed to read split.count: %w", err)
-			}
-			header.SplitCount = int(splitCount)
-			// We found what we need, no need to continue
-			return header, nil
-		}
-
-		// Skip the value
-		if err := skipGGUFValue(r, valType); err != nil {
-			return nil, fmt.Errorf("failed to skip value for key %q: %w", key, err)
-		}
+		Version:   gr.Header.Version,
+		TensorCnt: int64(gr.Header.TensorCount),
+		KVCnt:     int64(gr.Header.KVCount),
 	}
 
-	return header, nil
-}
-
-func readGGUFString(r io.Reader) (string, error) {
-	var length uint64
-	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
-		return "", err
-	}
-
-	if length > 1024*1024 { // Sanity check: 1MB max string length
-		return "", fmt.Errorf("string too long: %d", length)
+	values, err := gr.Find(keySplitCount)
+	if err != nil {
+		return nil, err
 	}
-
-	data := make([]byte, length)
-	if _, err := io.ReadFull(r, data); err != nil {
-		return "", err
-	}
-
-	return string(data), nil
-}
-
-func skipGGUFValue(r io.Reader, valType int32) error {
-	switch valType {
-	case ggufTypeUint8, ggufTypeInt8, ggufTypeBool:
-		_, err := io.CopyN(io.Discard, r, 1)
-		return err
-	case ggufTypeUint16, ggufTypeInt16:
-		_, err := io.CopyN(io.Discard, r, 2)
-		return err
-	case ggufTypeUint32, ggufTypeInt32, ggufTypeFloat32:
-		_, err := io.CopyN(io.Discard, r, 4)
-		return err
-	case ggufTypeUint64, ggufTypeInt64, ggufTypeFloat64:
-		_, err := io.CopyN(io.Discard, r, 8)
-		return err
-	case ggufTypeString:
-		_, err := readGGUFString(r)
-		return err
-	case ggufTypeArray:
-		// Read array type
-		var arrType int32
-		if err := binary.Read(r, binary.LittleEndian, &arrType); err != nil {
-			return err
-		}
-
-		// Read array length
-		var arrLen uint64
-		if err := binary.Read(r, binary.LittleEndian, &arrLen); err != nil {
-			return err
-		}
-
-		if arrLen > 1024*1024 { // Sanity check: 1M elements max
-			return fmt.Errorf("array too long: %d", arrLen)
-		}
-
-		// Skip array elements
-		for i := uint64(0); i < arrLen; i++ {
-			if err := skipGGUFValue(r, arrType); err != nil {
-				return err
-			}
-		}
-		return nil
-	default:
-		return fmt.Errorf("unknown GGUF value type: %d", valType)
+	if n, ok := gguf.AsInt(values[keySplitCount]); ok {
+		header.SplitCount = int(n)
 	}
+	return header, nil
 }
 
 // SplitInfo contains parsed information from a split filename.
@@ -236,3 +110,154 @@ func SplitPrefix(path string, splitNo, splitCount int) string {
 	}
 	return ""
 }
+
+// GGUFModelParams holds the architecture metadata needed to estimate how much
+// memory a model needs at a given context size.
+type GGUFModelParams struct {
+	Architecture    string
+	BlockCount      int
+	ContextLength   int
+	EmbeddingLength int
+	HeadCount       int
+	HeadCountKV     int
+	KeyLength       int
+	ValueLength     int
+	PoolingType     int // PoolingUnspecified if the model doesn't declare one
+}
+
+// PoolingUnspecified marks a model without a pooling_type key.
+const PoolingUnspecified = -1
+
+// embeddingArchitectures are architectures that only produce embeddings.
+// GTE and BGE models convert to bert.
+var embeddingArchitectures = []string{
+	"bert",
+	"nomic-bert",
+	"nomic-bert-moe",
+	"jina-bert-v2",
+	"jina-bert-v3",
+	"modern-bert",
+	"neo-bert",
+	"t5encoder",
+}
+
+// IsEmbedding reports whether the model is an embedding model rather than a
+// chat model, either by architecture or because it declares a pooling type.
+func (p *GGUFModelParams) IsEmbedding() bool {
+	return slices.Contains(embeddingArchitectures, p.Architecture) || p.PoolingType > 0
+}
+
+// KVEmbeddingSizes returns the per-layer K and V embedding sizes, falling back
+// to llama.cpp's defaults when the model doesn't specify them.
+func (p *GGUFModelParams) KVEmbeddingSizes() (k, v int) {
+	headCountKV := p.HeadCountKV
+	if headCountKV == 0 {
+		headCountKV = p.HeadCount
+	}
+	headDim := 0
+	if p.HeadCount > 0 {
+		headDim = p.EmbeddingLength / p.HeadCount
+	}
+	keyLength, valueLength := p.KeyLength, p.ValueLength
+	if keyLength == 0 {
+		keyLength = headDim
+	}
+	if valueLength == 0 {
+		valueLength = headDim
+	}
+	return headCountKV * keyLength, headCountKV * valueLength
+}
+
+// ReadGGUFModelParams reads architecture metadata from a GGUF file.
+func ReadGGUFModelParams(path string) (*GGUFModelParams, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readGGUFModelParams(f)
+}
+
+func readGGUFModelParams(r io.Reader) (*GGUFModelParams, error) {
+	gr, err := gguf.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Architecture keys are prefixed with the architecture name, which may not
+	// be known yet when they're read, so collect all numeric values first.
+	params := &GGUFModelParams{}
+	numbers := make(map[string]int)
+
+	for {
+		key, typ, err := gr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if typ == gguf.TypeArray || (typ == gguf.TypeString && key != "general.architecture") {
+			continue
+		}
+
+		v, err := gr.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read value for key %q: %w", key, err)
+		}
+		if key == "general.architecture" {
+			params.Architecture, _ = v.(string)
+		} else if n, ok := gguf.AsInt(v); ok {
+			numbers[key] = int(n)
+		}
+	}
+
+	if params.Architecture == "" {
+		return nil, fmt.Errorf("missing general.architecture")
+	}
+
+	prefix := params.Architecture + "."
+	params.BlockCount = numbers[prefix+"block_count"]
+	params.ContextLength = numbers[prefix+"context_length"]
+	params.EmbeddingLength = numbers[prefix+"embedding_length"]
+	params.HeadCount = numbers[prefix+"attention.head_count"]
+	params.HeadCountKV = numbers[prefix+"attention.head_count_kv"]
+	params.KeyLength = numbers[prefix+"attention.key_length"]
+	params.ValueLength = numbers[prefix+"attention.value_length"]
+	params.PoolingType = PoolingUnspecified
+	if n, ok := numbers[prefix+"pooling_type"]; ok {
+		params.PoolingType = n
+	}
+
+	return params, nil
+}
+
+// ReadGGUFParameterCount returns the number of model parameters, summed over
+// the tensors of every part of a split model.
+func ReadGGUFParameterCount(path string) (int64, error) {
+	paths := []string{path}
+	if split := ParseSplitFilename(path); split != nil {
+		paths = paths[:0]
+		for i := range split.SplitCount {
+			paths = append(paths, SplitPath(split.Prefix, i, split.SplitCount))
+		}
+	}
+
+	var total int64
+	for _, p := range paths {
+		f, err := gguf.Open(p)
+		if err != nil {
+			return 0, err
+		}
+		tensors, err := f.Tensors()
+		f.Close()
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range tensors {
+			total += int64(t.Elements())
+		}
+	}
+	return total, nil
+}
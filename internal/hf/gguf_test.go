@@ -3,7 +3,12 @@ package hf
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/nchapman/lleme/internal/gguf"
 )
 
 func TestReadGGUFHeader(t *testing.T) {
@@ -309,3 +314,134 @@ func TestSplitPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestReadGGUFModelParams(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("GGUF")
+	binary.Write(buf, binary.LittleEndian, uint32(3))
+	binary.Write(buf, binary.LittleEndian, int64(0))
+	binary.Write(buf, binary.LittleEndian, int64(6))
+
+	writeKey := func(key string, valType int32) {
+		binary.Write(buf, binary.LittleEndian, uint64(len(key)))
+		buf.WriteString(key)
+		binary.Write(buf, binary.LittleEndian, valType)
+	}
+
+	// Numeric keys before the architecture must still be picked up
+	writeKey("qwen2.block_count", int32(gguf.TypeUint32))
+	binary.Write(buf, binary.LittleEndian, uint32(28))
+
+	writeKey("general.architecture", int32(gguf.TypeString))
+	binary.Write(buf, binary.LittleEndian, uint64(5))
+	buf.WriteString("qwen2")
+
+	writeKey("qwen2.context_length", int32(gguf.TypeUint64))
+	binary.Write(buf, binary.LittleEndian, uint64(32768))
+
+	writeKey("qwen2.embedding_length", int32(gguf.TypeInt32))
+	binary.Write(buf, binary.LittleEndian, int32(3584))
+
+	writeKey("qwen2.attention.head_count", int32(gguf.TypeUint32))
+	binary.Write(buf, binary.LittleEndian, uint32(28))
+
+	writeKey("qwen2.rope.freq_base", int32(gguf.TypeFloat32))
+	binary.Write(buf, binary.LittleEndian, float32(1000000))
+
+	params, err := readGGUFModelParams(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readGGUFModelParams() error = %v", err)
+	}
+
+	if params.Architecture != "qwen2" {
+		t.Errorf("Architecture = %q, want qwen2", params.Architecture)
+	}
+	if params.BlockCount != 28 {
+		t.Errorf("BlockCount = %d, want 28", params.BlockCount)
+	}
+	if params.ContextLength != 32768 {
+		t.Errorf("ContextLength = %d, want 32768", params.ContextLength)
+	}
+
+	// No head_count_kv: falls back to head_count (no GQA)
+	k, v := params.KVEmbeddingSizes()
+	if k != 3584 || v != 3584 {
+		t.Errorf("KVEmbeddingSizes() = %d, %d, want 3584, 3584", k, v)
+	}
+	if params.PoolingType != PoolingUnspecified {
+		t.Errorf("PoolingType = %d, want PoolingUnspecified", params.PoolingType)
+	}
+	if params.IsEmbedding() {
+		t.Error("IsEmbedding() = true for a chat model")
+	}
+}
+
+func TestIsEmbedding(t *testing.T) {
+	tests := []struct {
+		name   string
+		params GGUFModelParams
+		want   bool
+	}{
+		{"bert", GGUFModelParams{Architecture: "bert", PoolingType: PoolingUnspecified}, true},
+		{"nomic-bert", GGUFModelParams{Architecture: "nomic-bert", PoolingType: 1}, true},
+		{"qwen3 with pooling", GGUFModelParams{Architecture: "qwen3", PoolingType: 3}, true},
+		{"qwen3 chat", GGUFModelParams{Architecture: "qwen3", PoolingType: PoolingUnspecified}, false},
+		{"pooling none", GGUFModelParams{Architecture: "llama", PoolingType: 0}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.params.IsEmbedding(); got != tt.want {
+				t.Errorf("IsEmbedding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGGUFModelParamsMissingArchitecture(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("GGUF")
+	binary.Write(buf, binary.LittleEndian, uint32(3))
+	binary.Write(buf, binary.LittleEndian, int64(0))
+	binary.Write(buf, binary.LittleEndian, int64(0))
+
+	if _, err := readGGUFModelParams(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("readGGUFModelParams() should fail without general.architecture")
+	}
+}
+
+func TestReadGGUFParameterCount(t *testing.T) {
+	writePart := func(path string, dims ...[]uint64) {
+		buf := &bytes.Buffer{}
+		buf.WriteString("GGUF")
+		binary.Write(buf, binary.LittleEndian, uint32(3))
+		binary.Write(buf, binary.LittleEndian, uint64(len(dims)))
+		binary.Write(buf, binary.LittleEndian, uint64(0))
+		for i, d := range dims {
+			name := fmt.Sprintf("blk.%d.weight", i)
+			binary.Write(buf, binary.LittleEndian, uint64(len(name)))
+			buf.WriteString(name)
+			binary.Write(buf, binary.LittleEndian, uint32(len(d)))
+			binary.Write(buf, binary.LittleEndian, d)
+			binary.Write(buf, binary.LittleEndian, uint32(0))
+			binary.Write(buf, binary.LittleEndian, uint64(0))
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	single := filepath.Join(dir, "model.gguf")
+	writePart(single, []uint64{4, 8}, []uint64{8})
+	if n, err := ReadGGUFParameterCount(single); err != nil || n != 40 {
+		t.Errorf("single file = %d, %v; want 40", n, err)
+	}
+
+	prefix := filepath.Join(dir, "big")
+	writePart(SplitPath(prefix, 0, 2), []uint64{10, 10})
+	writePart(SplitPath(prefix, 1, 2), []uint64{5})
+	if n, err := ReadGGUFParameterCount(SplitPath(prefix, 0, 2)); err != nil || n != 105 {
+		t.Errorf("split model = %d, %v; want 105", n, err)
+	}
+}
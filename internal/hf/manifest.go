@@ -0,0 +1,85 @@
+package hf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits on manifests, which come from whatever repo or peer a model is
+// pulled from
+const (
+	maxManifestSize = 1 << 20
+	maxSplitFiles   = 1024
+)
+
+// ParseManifest decodes a manifest and checks it's safe to act on: it names
+// a GGUF file, and every file has a relative name, a sane size, and a
+// well-formed hash.
+func ParseManifest(data []byte) (*Manifest, error) {
+	if len(data) > maxManifestSize {
+		return nil, fmt.Errorf("manifest too large: %d bytes", len(data))
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// Validate checks the fields pulls rely on. See ParseManifest.
+func (m *Manifest) Validate() error {
+	if m.GGUFFile == nil {
+		return errors.New("manifest does not contain a GGUF file")
+	}
+	if err := m.GGUFFile.validate(); err != nil {
+		return fmt.Errorf("manifest GGUF file: %w", err)
+	}
+	if m.MMProjFile != nil {
+		if err := m.MMProjFile.validate(); err != nil {
+			return fmt.Errorf("manifest mmproj file: %w", err)
+		}
+	}
+	if len(m.SplitFiles) > maxSplitFiles {
+		return fmt.Errorf("manifest lists %d split files", len(m.SplitFiles))
+	}
+	for i, sf := range m.SplitFiles {
+		if sf == nil {
+			return fmt.Errorf("manifest split file %d is empty", i+1)
+		}
+		if err := sf.validate(); err != nil {
+			return fmt.Errorf("manifest split file %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (f *ManifestFile) validate() error {
+	name := f.RFilename
+	switch {
+	case name == "":
+		return errors.New("no file name")
+	case strings.ContainsAny(name, "\\\x00"), path.IsAbs(name), path.Clean(name) != name, name == "..", strings.HasPrefix(name, "../"):
+		return fmt.Errorf("unsafe file name %q", name)
+	case f.Size < 0:
+		return fmt.Errorf("%s has negative size %d", name, f.Size)
+	}
+	if f.LFS != nil && f.LFS.SHA256 != "" {
+		if b, err := hex.DecodeString(f.LFS.SHA256); err != nil || len(b) != 32 {
+			return fmt.Errorf("%s has malformed sha256 %q", name, f.LFS.SHA256)
+		}
+	}
+	return nil
+}
+
+// insideDir reports whether path is below dir
+func insideDir(dir, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), filepath.Clean(dir)+string(filepath.Separator))
+}
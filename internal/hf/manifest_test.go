@@ -0,0 +1,73 @@
+package hf
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSHA256 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func TestParseManifestValidation(t *testing.T) {
+	valid := `{"ggufFile":{"rfilename":"Q4_K_M/model-00001-of-00002.gguf","size":10,"lfs":{"sha256":"` + testSHA256 + `","size":10}},` +
+		`"mmprojFile":{"rfilename":"mmproj.gguf","size":5},"splitFiles":[{"rfilename":"Q4_K_M/model-00002-of-00002.gguf","size":10}]}`
+	m, err := ParseManifest([]byte(valid))
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if m.GGUFFile.LFS.SHA256 != testSHA256 || m.MMProjFile == nil || len(m.SplitFiles) != 1 {
+		t.Errorf("ParseManifest() = %+v", m)
+	}
+
+	tests := map[string]string{
+		"not json":           `{"ggufFile":`,
+		"no GGUF file":       `{"mmprojFile":{"rfilename":"mmproj.gguf"}}`,
+		"no file name":       `{"ggufFile":{"size":10}}`,
+		"parent directory":   `{"ggufFile":{"rfilename":"../../.ssh/authorized_keys"}}`,
+		"absolute path":      `{"ggufFile":{"rfilename":"/etc/passwd"}}`,
+		"unclean path":       `{"ggufFile":{"rfilename":"a/../../b.gguf"}}`,
+		"backslash":          `{"ggufFile":{"rfilename":"..\\b.gguf"}}`,
+		"negative size":      `{"ggufFile":{"rfilename":"a.gguf","size":-1}}`,
+		"short sha256":       `{"ggufFile":{"rfilename":"a.gguf","lfs":{"sha256":"abcd"}}}`,
+		"non-hex sha256":     `{"ggufFile":{"rfilename":"a.gguf","lfs":{"sha256":"` + strings.Repeat("z", 64) + `"}}}`,
+		"null split file":    `{"ggufFile":{"rfilename":"a.gguf"},"splitFiles":[null]}`,
+		"unsafe split file":  `{"ggufFile":{"rfilename":"a.gguf"},"splitFiles":[{"rfilename":"../b.gguf"}]}`,
+		"unsafe mmproj file": `{"ggufFile":{"rfilename":"a.gguf"},"mmprojFile":{"rfilename":"/b.gguf"}}`,
+		"too many splits":    `{"ggufFile":{"rfilename":"a.gguf"},"splitFiles":[` + strings.TrimSuffix(strings.Repeat(`{"rfilename":"b.gguf"},`, maxSplitFiles+1), ",") + `]}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseManifest([]byte(data)); err == nil {
+				t.Error("ParseManifest() should fail")
+			}
+		})
+	}
+
+	if _, err := ParseManifest(make([]byte, maxManifestSize+1)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("ParseManifest() of an oversized manifest error = %v", err)
+	}
+}
+
+// FuzzParseManifest checks that any manifest ParseManifest accepts is safe
+// to build local paths and requests from.
+func FuzzParseManifest(f *testing.F) {
+	f.Add([]byte(`{"ggufFile":{"rfilename":"model.gguf","size":10,"lfs":{"sha256":"` + testSHA256 + `"}}}`))
+	f.Add([]byte(`{"ggufFile":{"rfilename":"../x"},"splitFiles":[null]}`))
+	f.Add([]byte(`{"ggufFile":{"rfilename":"Q4_K_M/model-00001-of-00002.gguf"},"mmprojFile":{"rfilename":"mmproj.gguf"}}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m, err := ParseManifest(data)
+		if err != nil {
+			return
+		}
+		files := []*ManifestFile{m.GGUFFile}
+		if m.MMProjFile != nil {
+			files = append(files, m.MMProjFile)
+		}
+		files = append(files, m.SplitFiles...)
+		for _, file := range files {
+			if strings.HasPrefix(file.RFilename, "/") || strings.Contains("/"+file.RFilename+"/", "/../") {
+				t.Errorf("accepted unsafe name %q", file.RFilename)
+			}
+		}
+		ManifestLocalFiles("user", "repo", "Q4_K_M", m)
+	})
+}
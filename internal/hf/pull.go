@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
-	"time"
+	"sync"
 
+	"github.com/nchapman/lleme/internal/fileutil"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 // PullResult contains the result of a model pull operation.
@@ -38,14 +41,10 @@ type ManifestInfo struct {
 
 // GetManifestInfo fetches the manifest and returns size information for display.
 // Returns the manifest data so it can be passed to PullModel to avoid re-fetching.
-func GetManifestInfo(client *Client, user, repo string, quant Quantization) (*ManifestInfo, *Manifest, []byte, error) {
-	manifest, manifestJSON, err := client.GetManifest(user, repo, quant.Tag)
+func GetManifestInfo(source ModelSource, user, repo string, quant Quantization) (*ManifestInfo, *Manifest, []byte, error) {
+	manifest, manifestJSON, err := fetchManifest(source, user, repo, quant)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to get manifest: %w", err)
-	}
-
-	if manifest.GGUFFile == nil {
-		return nil, nil, nil, fmt.Errorf("manifest does not contain a GGUF file")
+		return nil, nil, nil, err
 	}
 
 	info := &ManifestInfo{
@@ -73,7 +72,7 @@ type PullOptions struct {
 	ManifestJSON []byte
 
 	// PeerDownload is an optional function to try downloading from peers first.
-	// If provided and returns (true, nil), the HuggingFace download is skipped.
+	// If provided and returns (true, nil), the source download is skipped.
 	PeerDownload PeerDownloadFunc
 }
 
@@ -84,11 +83,11 @@ type fileDownload struct {
 	fromPeer bool // true if downloaded from peer (needs verification with fallback)
 }
 
-// PullModel downloads a model from HuggingFace using the manifest API.
+// PullModel downloads a model from a source using its manifest.
 // It handles downloading the GGUF file, optional mmproj for vision models,
 // split GGUF files, hash verification, and saving the manifest for future reference.
-func PullModel(client *Client, user, repo string, quant Quantization, opts *PullOptions, progress func(PullProgress)) (*PullResult, error) {
-	manifest, manifestJSON, err := getOrFetchManifest(client, user, repo, quant, opts)
+func PullModel(source ModelSource, user, repo string, quant Quantization, opts *PullOptions, progress func(PullProgress)) (*PullResult, error) {
+	manifest, manifestJSON, err := getOrFetchManifest(source, user, repo, quant, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -97,14 +96,8 @@ func PullModel(client *Client, user, repo string, quant Quantization, opts *Pull
 	if splitInfo != nil && splitInfo.SplitNo != 0 {
 		return nil, fmt.Errorf("manifest references split %d, expected first split", splitInfo.SplitNo+1)
 	}
-
-	// Fetch split file info if needed
-	if splitInfo != nil {
-		splitFiles, err := fetchSplitFileInfo(client, user, repo, splitInfo)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch split file info: %w", err)
-		}
-		manifest.SplitFiles = splitFiles
+	if splitInfo != nil && len(manifest.SplitFiles) != splitInfo.SplitCount-1 {
+		return nil, fmt.Errorf("manifest lists %d of %d split files", len(manifest.SplitFiles)+1, splitInfo.SplitCount)
 	}
 
 	result := calculateResultSizes(manifest, splitInfo)
@@ -115,12 +108,30 @@ func PullModel(client *Client, user, repo string, quant Quantization, opts *Pull
 		return nil, fmt.Errorf("failed to create model directory: %w", err)
 	}
 
+	// Only one pull of a quant at a time, even from another host sharing
+	// the model store. A pull that waited finds the files already there.
+	waited := false
+	lock, err := fileutil.Lock(GetPullLockPath(user, repo, quant.Name), func() {
+		logs.Info("Waiting for another pull of this model to finish", "model", user+"/"+repo+":"+quant.Name)
+		waited = true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock model for pull: %w", err)
+	}
+	defer lock.Unlock()
+
 	// Build list of files to download
 	files, err := buildFileList(user, repo, quant, manifest, splitInfo, result)
 	if err != nil {
 		return nil, err
 	}
 
+	// Refuse up front rather than fill the disk partway through
+	modelName := user + "/" + repo + ":" + quant.Name
+	if err := checkDiskSpace(modelName, modelDir, files, waited); err != nil {
+		return nil, err
+	}
+
 	// Get peer download function
 	var peerDownload PeerDownloadFunc
 	if opts != nil {
@@ -128,13 +139,16 @@ func PullModel(client *Client, user, repo string, quant Quantization, opts *Pull
 	}
 
 	// Download all files
-	if err := downloadAllFiles(client, user, repo, files, peerDownload, result.TotalSize, progress); err != nil {
+	if err := downloadAllFiles(source, user, repo, files, peerDownload, waited, result.TotalSize, progress); err != nil {
 		cleanupFiles(files, splitInfo, user, repo, quant)
+		if fileutil.IsDiskFull(err) {
+			return nil, diskFullError(modelName, modelDir, files, waited)
+		}
 		return nil, err
 	}
 
 	// Verify all files (with fallback for peer downloads)
-	if err := verifyAllFiles(client, user, repo, files, result.TotalSize, progress); err != nil {
+	if err := verifyAllFiles(source, user, repo, files, result.TotalSize, progress); err != nil {
 		cleanupFiles(files, splitInfo, user, repo, quant)
 		return nil, err
 	}
@@ -148,24 +162,33 @@ func PullModel(client *Client, user, repo string, quant Quantization, opts *Pull
 }
 
 // getOrFetchManifest returns the manifest from opts or fetches it.
-func getOrFetchManifest(client *Client, user, repo string, quant Quantization, opts *PullOptions) (*Manifest, []byte, error) {
+func getOrFetchManifest(source ModelSource, user, repo string, quant Quantization, opts *PullOptions) (*Manifest, []byte, error) {
 	if opts != nil && opts.Manifest != nil {
+		if err := opts.Manifest.Validate(); err != nil {
+			return nil, nil, err
+		}
 		return opts.Manifest, opts.ManifestJSON, nil
 	}
 
-	if client == nil {
-		return nil, nil, fmt.Errorf("HuggingFace client is required")
+	if source == nil {
+		return nil, nil, fmt.Errorf("model source is required")
 	}
 
-	manifest, manifestJSON, err := client.GetManifest(user, repo, quant.Tag)
+	return fetchManifest(source, user, repo, quant)
+}
+
+// fetchManifest gets a manifest from source and validates it. Sources fill
+// in parts themselves, such as split files found by listing the repo, so
+// the whole manifest is checked here before any of it becomes a request
+// or a path.
+func fetchManifest(source ModelSource, user, repo string, quant Quantization) (*Manifest, []byte, error) {
+	manifest, manifestJSON, err := source.GetManifest(user, repo, quant)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get manifest: %w", err)
+		return nil, nil, err
 	}
-
-	if manifest.GGUFFile == nil {
-		return nil, nil, fmt.Errorf("manifest does not contain a GGUF file")
+	if err := manifest.Validate(); err != nil {
+		return nil, nil, err
 	}
-
 	return manifest, manifestJSON, nil
 }
 
@@ -197,41 +220,68 @@ func calculateResultSizes(manifest *Manifest, splitInfo *SplitInfo) *PullResult
 	return result
 }
 
+// LocalFile is where a manifest file lives in the model store.
+type LocalFile struct {
+	File *ManifestFile
+	Path string
+}
+
+// ManifestLocalFiles returns where each file in a manifest is stored locally:
+// the model (or its splits), then the mmproj for vision models.
+func ManifestLocalFiles(user, repo, quant string, manifest *Manifest) []LocalFile {
+	var files []LocalFile
+
+	if splitInfo := ParseSplitFilename(manifest.GGUFFile.RFilename); splitInfo != nil {
+		splitDir := GetSplitModelDir(user, repo, quant)
+		files = append(files, LocalFile{File: manifest.GGUFFile, Path: filepath.Join(splitDir, filepath.Base(manifest.GGUFFile.RFilename))})
+		for _, sf := range manifest.SplitFiles {
+			files = append(files, LocalFile{File: sf, Path: filepath.Join(splitDir, filepath.Base(sf.RFilename))})
+		}
+	} else {
+		files = append(files, LocalFile{File: manifest.GGUFFile, Path: GetModelFilePath(user, repo, quant)})
+	}
+
+	if manifest.MMProjFile != nil {
+		files = append(files, LocalFile{File: manifest.MMProjFile, Path: GetMMProjFilePath(user, repo, quant)})
+	}
+
+	return files
+}
+
 // buildFileList creates the list of files to download.
 func buildFileList(user, repo string, quant Quantization, manifest *Manifest, splitInfo *SplitInfo, result *PullResult) ([]fileDownload, error) {
-	var files []fileDownload
+	// The model name and file names come from the user and the repo, so
+	// make sure none of them lead out of the models directory
+	modelsDir := paths.Models()
+	for _, lf := range ManifestLocalFiles(user, repo, quant.Name, manifest) {
+		if !insideDir(modelsDir, lf.Path) {
+			return nil, fmt.Errorf("refusing to write %s outside the models directory", lf.Path)
+		}
+	}
 
 	if splitInfo != nil {
-		splitDir := GetSplitModelDir(user, repo, quant.Name)
-		if err := os.MkdirAll(splitDir, 0755); err != nil {
+		if err := os.MkdirAll(GetSplitModelDir(user, repo, quant.Name), 0755); err != nil {
 			return nil, fmt.Errorf("failed to create split directory: %w", err)
 		}
+	}
 
-		// First split
-		firstPath := filepath.Join(splitDir, filepath.Base(manifest.GGUFFile.RFilename))
-		result.ModelPath = firstPath
-		files = append(files, fileDownload{file: manifest.GGUFFile, destPath: firstPath})
-
-		// Remaining splits
-		for _, sf := range manifest.SplitFiles {
-			path := filepath.Join(splitDir, filepath.Base(sf.RFilename))
-			files = append(files, fileDownload{file: sf, destPath: path})
-		}
-	} else {
-		result.ModelPath = GetModelFilePath(user, repo, quant.Name)
-		files = append(files, fileDownload{file: manifest.GGUFFile, destPath: result.ModelPath})
+	var files []fileDownload
+	for _, lf := range ManifestLocalFiles(user, repo, quant.Name, manifest) {
+		files = append(files, fileDownload{file: lf.File, destPath: lf.Path})
 	}
 
+	result.ModelPath = files[0].destPath
 	if result.IsVision {
-		result.MMProjPath = GetMMProjFilePath(user, repo, quant.Name)
-		files = append(files, fileDownload{file: manifest.MMProjFile, destPath: result.MMProjPath})
+		result.MMProjPath = files[len(files)-1].destPath
 	}
 
 	return files, nil
 }
 
-// downloadAllFiles downloads all files, trying peer first then HuggingFace.
-func downloadAllFiles(client *Client, user, repo string, files []fileDownload, peerDownload PeerDownloadFunc, totalSize int64, progress func(PullProgress)) error {
+// downloadAllFiles downloads all files, trying peer first then the source.
+// With keepComplete, files already at their full size are left for
+// verification instead of being fetched again.
+func downloadAllFiles(source ModelSource, user, repo string, files []fileDownload, peerDownload PeerDownloadFunc, keepComplete bool, totalSize int64, progress func(PullProgress)) error {
 	downloaded := int64(0)
 
 	for i := range files {
@@ -247,7 +297,24 @@ func downloadAllFiles(client *Client, user, repo string, files []fileDownload, p
 			}
 		}
 
-		fromPeer, err := downloadFile(client, user, repo, fd.file, fd.destPath, peerDownload, progressFn)
+		// A file already verified against this hash, like an unchanged
+		// mmproj when the model is updated, is kept as is
+		if fd.file.LFS != nil && fd.file.LFS.SHA256 != "" && isVerified(fd.destPath, fd.file.LFS.SHA256) {
+			logs.Debug("Keeping verified file", "path", fd.destPath)
+			progressFn(fd.file.Size, fd.file.Size)
+			downloaded += fd.file.Size
+			continue
+		}
+		if keepComplete {
+			if info, err := os.Stat(fd.destPath); err == nil && info.Size() == fd.file.Size {
+				logs.Debug("Keeping file from concurrent pull", "path", fd.destPath)
+				progressFn(fd.file.Size, fd.file.Size)
+				downloaded += fd.file.Size
+				continue
+			}
+		}
+
+		fromPeer, err := downloadFile(source, user, repo, fd.file, fd.destPath, peerDownload, progressFn)
 		if err != nil {
 			return err
 		}
@@ -258,105 +325,140 @@ func downloadAllFiles(client *Client, user, repo string, files []fileDownload, p
 	return nil
 }
 
-// downloadFile tries peer download first, falls back to HuggingFace.
+// downloadFile tries peer download first, falls back to the source.
 // Returns (fromPeer, error). Does NOT verify - that's handled separately.
-func downloadFile(client *Client, user, repo string, file *ManifestFile, destPath string, peerDownload PeerDownloadFunc, progress func(current, total int64)) (bool, error) {
+func downloadFile(source ModelSource, user, repo string, file *ManifestFile, destPath string, peerDownload PeerDownloadFunc, progress func(current, total int64)) (bool, error) {
 	// Try peer first if available
 	if peerDownload != nil && file.LFS != nil && file.LFS.SHA256 != "" {
 		downloaded, err := peerDownload(file.LFS.SHA256, destPath, file.Size, progress)
 		if err != nil {
-			logs.Debug("peer download failed, falling back to HuggingFace", "file", file.RFilename, "error", err)
+			logs.Debug("peer download failed, falling back to source", "file", file.RFilename, "error", err)
 		}
 		if downloaded {
 			return true, nil
 		}
 	}
 
-	// Fall back to HuggingFace
-	if err := downloadFromHF(client, user, repo, file, destPath, progress); err != nil {
+	// Fall back to the source
+	if err := downloadFromSource(source, user, repo, file, destPath, progress); err != nil {
 		return false, err
 	}
 
 	return false, nil
 }
 
-// downloadFromHF downloads a file from HuggingFace.
-func downloadFromHF(client *Client, user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
-	if client == nil {
-		return fmt.Errorf("HuggingFace client is required")
+// downloadFromSource downloads a file from the model source.
+func downloadFromSource(source ModelSource, user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
+	if source == nil {
+		return fmt.Errorf("model source is required")
 	}
-
-	downloader := NewDownloaderWithProgress(client, func(current, total int64, speed float64, eta time.Duration) {
-		if progress != nil {
-			progress(current, total)
-		}
-	})
-
-	_, err := downloader.DownloadModel(user, repo, "main", file.RFilename, destPath)
-	return err
+	return source.Download(user, repo, file, destPath, progress)
 }
 
-// verifyAllFiles verifies all downloaded files. If a peer-downloaded file fails,
-// retries from HuggingFace. HuggingFace download failures are fatal.
-func verifyAllFiles(client *Client, user, repo string, files []fileDownload, totalSize int64, progress func(PullProgress)) error {
+// maxVerifyWorkers caps how many files are hashed at once. Split parts and
+// the mmproj hash on separate cores; beyond a few, the disk is the limit.
+const maxVerifyWorkers = 4
+
+// verifyAllFiles verifies all downloaded files, hashing several at once. If a
+// peer-downloaded file fails, retries from the source. Source download
+// failures are fatal.
+func verifyAllFiles(source ModelSource, user, repo string, files []fileDownload, totalSize int64, progress func(PullProgress)) error {
+	var mu sync.Mutex
 	verified := int64(0)
+	report := func(delta int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		verified += delta
+		if progress != nil {
+			progress(PullProgress{
+				Phase:   "verify",
+				Current: verified,
+				Total:   totalSize,
+			})
+		}
+	}
+	// verify hashes one file, taking its bytes back out of the total if it fails
+	verify := func(fd *fileDownload) error {
+		var done int64
+		err := verifyFile(fd.destPath, fd.file.LFS.SHA256, func(current, total int64) {
+			report(current - done)
+			done = current
+		})
+		if err != nil {
+			report(-done)
+			os.Remove(fd.destPath)
+		}
+		return err
+	}
 
+	errs := make([]error, len(files))
+	sem := make(chan struct{}, min(maxVerifyWorkers, runtime.NumCPU()))
+	var wg sync.WaitGroup
 	for i := range files {
 		fd := &files[i]
 
 		// Skip if no hash to verify
 		if fd.file.LFS == nil || fd.file.LFS.SHA256 == "" {
+			mu.Lock()
 			verified += fd.file.Size
+			mu.Unlock()
 			continue
 		}
 
-		progressFn := func(current, total int64) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = verify(fd)
+		}()
+	}
+	wg.Wait()
+
+	// Failed peer downloads are fetched again from the source one at a time,
+	// after the rest are verified, so the phases don't interleave
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		fd := &files[i]
+		if !fd.fromPeer {
+			return fmt.Errorf("verification failed for %s: %w", filepath.Base(fd.destPath), err)
+		}
+
+		downloadProgressFn := func(current, total int64) {
 			if progress != nil {
 				progress(PullProgress{
-					Phase:   "verify",
-					Current: verified + current,
-					Total:   totalSize,
+					Phase:   "download",
+					Current: current,
+					Total:   fd.file.Size,
 				})
 			}
 		}
 
-		if err := verifyFile(fd.destPath, fd.file.LFS.SHA256, progressFn); err != nil {
-			os.Remove(fd.destPath)
-
-			// If peer download failed verification, retry from HuggingFace
-			if fd.fromPeer {
-				downloadProgressFn := func(current, total int64) {
-					if progress != nil {
-						progress(PullProgress{
-							Phase:   "download",
-							Current: current,
-							Total:   fd.file.Size,
-						})
-					}
-				}
-
-				if err := downloadFromHF(client, user, repo, fd.file, fd.destPath, downloadProgressFn); err != nil {
-					return fmt.Errorf("failed to download %s from HuggingFace: %w", filepath.Base(fd.destPath), err)
-				}
-
-				// Verify the HF download
-				if err := verifyFile(fd.destPath, fd.file.LFS.SHA256, progressFn); err != nil {
-					os.Remove(fd.destPath)
-					return fmt.Errorf("verification failed for %s: %w", filepath.Base(fd.destPath), err)
-				}
-			} else {
-				return fmt.Errorf("verification failed for %s: %w", filepath.Base(fd.destPath), err)
-			}
+		if err := downloadFromSource(source, user, repo, fd.file, fd.destPath, downloadProgressFn); err != nil {
+			return fmt.Errorf("failed to re-download %s: %w", filepath.Base(fd.destPath), err)
 		}
 
-		verified += fd.file.Size
+		// Verify the source download
+		if err := verify(fd); err != nil {
+			return fmt.Errorf("verification failed for %s: %w", filepath.Base(fd.destPath), err)
+		}
 	}
 
 	return nil
 }
 
-// verifyFile checks a file's SHA256 hash.
+// verifyFile checks a file's SHA256 hash, skipping files that were verified
+// before and haven't changed since.
 func verifyFile(path, expectedHash string, progress func(current, total int64)) error {
+	if isVerified(path, expectedHash) {
+		if info, err := os.Stat(path); err == nil && progress != nil {
+			progress(info.Size(), info.Size())
+		}
+		return nil
+	}
+
 	hash, err := CalculateSHA256WithProgress(path, progress)
 	if err != nil {
 		return fmt.Errorf("failed to calculate hash: %w", err)
@@ -364,6 +466,7 @@ func verifyFile(path, expectedHash string, progress func(current, total int64))
 	if !strings.EqualFold(hash, expectedHash) {
 		return fmt.Errorf("hash mismatch")
 	}
+	recordVerified(path, expectedHash)
 	return nil
 }
 
@@ -393,7 +496,7 @@ func saveManifest(user, repo, quant string, manifest *Manifest, manifestJSON []b
 	}
 
 	manifestPath := GetManifestFilePath(user, repo, quant)
-	if err := os.WriteFile(manifestPath, manifestData, 0644); err != nil {
+	if err := fileutil.AtomicWriteFile(manifestPath, manifestData, 0644); err != nil {
 		return fmt.Errorf("failed to save manifest: %w", err)
 	}
 
@@ -450,14 +553,10 @@ func fetchSplitFileInfo(client *Client, user, repo string, splitInfo *SplitInfo)
 }
 
 // CheckForUpdates checks if a local model is up to date with the remote manifest.
-func CheckForUpdates(client *Client, user, repo string, quant Quantization) (bool, bool, *Manifest, []byte, error) {
-	manifest, manifestJSON, err := client.GetManifest(user, repo, quant.Tag)
+func CheckForUpdates(source ModelSource, user, repo string, quant Quantization) (bool, bool, *Manifest, []byte, error) {
+	manifest, manifestJSON, err := fetchManifest(source, user, repo, quant)
 	if err != nil {
-		return false, false, nil, nil, fmt.Errorf("failed to get manifest: %w", err)
-	}
-
-	if manifest.GGUFFile == nil {
-		return false, false, nil, nil, fmt.Errorf("manifest does not contain a GGUF file")
+		return false, false, nil, nil, err
 	}
 
 	upToDate, saveManifest := isUpToDate(user, repo, quant.Name, manifest)
@@ -486,8 +585,8 @@ func isUpToDate(user, repo, quant string, remote *Manifest) (bool, bool) {
 		return false, false
 	}
 
-	var local Manifest
-	if err := json.Unmarshal(manifestData, &local); err != nil {
+	local, err := ParseManifest(manifestData)
+	if err != nil {
 		return false, false
 	}
 
@@ -517,6 +616,9 @@ func hashesMatch(local, remote *ManifestFile) bool {
 		return local == nil && remote == nil
 	}
 	if local.LFS == nil || remote.LFS == nil {
+		if local.ETag != "" && remote.ETag != "" {
+			return local.ETag == remote.ETag
+		}
 		return local.Size == remote.Size
 	}
 	return local.LFS.SHA256 == remote.LFS.SHA256
@@ -534,16 +636,16 @@ type ProgressDisplay interface {
 type ProgressDisplayFactory func() ProgressDisplay
 
 // PullModelWithProgress downloads a model with progress bar display.
-func PullModelWithProgress(client *Client, user, repo string, quant Quantization, opts *PullOptions) (*PullResult, error) {
-	return PullModelWithProgressFactory(client, user, repo, quant, opts, nil)
+func PullModelWithProgress(source ModelSource, user, repo string, quant Quantization, opts *PullOptions) (*PullResult, error) {
+	return PullModelWithProgressFactory(source, user, repo, quant, opts, nil)
 }
 
 // PullModelWithProgressFactory downloads a model with customizable progress display.
-func PullModelWithProgressFactory(client *Client, user, repo string, quant Quantization, opts *PullOptions, factory ProgressDisplayFactory) (*PullResult, error) {
+func PullModelWithProgressFactory(source ModelSource, user, repo string, quant Quantization, opts *PullOptions, factory ProgressDisplayFactory) (*PullResult, error) {
 	var progressBar ProgressDisplay
 	var currentPhase string
 
-	result, err := PullModel(client, user, repo, quant, opts, func(p PullProgress) {
+	result, err := PullModel(source, user, repo, quant, opts, func(p PullProgress) {
 		if factory == nil {
 			return
 		}
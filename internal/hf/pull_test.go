@@ -1,11 +1,14 @@
 package hf
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -509,7 +512,7 @@ func TestDownloadAllFilesWithPeer(t *testing.T) {
 	}
 
 	var progressCalls int
-	err := downloadAllFiles(nil, "user", "repo", files, peerDownload, 100, func(p PullProgress) {
+	err := downloadAllFiles(nil, "user", "repo", files, peerDownload, false, 100, func(p PullProgress) {
 		progressCalls++
 	})
 
@@ -524,7 +527,41 @@ func TestDownloadAllFilesWithPeer(t *testing.T) {
 	}
 }
 
+func TestDownloadAllFilesKeepComplete(t *testing.T) {
+	tmpDir := t.TempDir()
+	complete := filepath.Join(tmpDir, "complete.gguf")
+	if err := os.WriteFile(complete, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fetched []string
+	peerDownload := func(hash, destPath string, size int64, progress func(int64, int64)) (bool, error) {
+		fetched = append(fetched, filepath.Base(destPath))
+		return true, nil
+	}
+	files := []fileDownload{
+		{file: &ManifestFile{RFilename: "complete.gguf", Size: 100, LFS: &ManifestLFS{SHA256: "a"}}, destPath: complete},
+		{file: &ManifestFile{RFilename: "missing.gguf", Size: 100, LFS: &ManifestLFS{SHA256: "b"}}, destPath: filepath.Join(tmpDir, "missing.gguf")},
+	}
+
+	if err := downloadAllFiles(nil, "user", "repo", files, peerDownload, true, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 1 || fetched[0] != "missing.gguf" {
+		t.Errorf("fetched %v, want only missing.gguf", fetched)
+	}
+
+	fetched = nil
+	if err := downloadAllFiles(nil, "user", "repo", files, peerDownload, false, 200, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 2 {
+		t.Errorf("fetched %v without keepComplete, want both files", fetched)
+	}
+}
+
 func TestVerifyAllFilesSuccess(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 	tmpDir := t.TempDir()
 
 	content := []byte("test content")
@@ -549,6 +586,7 @@ func TestVerifyAllFilesSuccess(t *testing.T) {
 }
 
 func TestVerifyAllFilesHashMismatch(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 	tmpDir := t.TempDir()
 
 	badFile := filepath.Join(tmpDir, "bad.gguf")
@@ -574,6 +612,7 @@ func TestVerifyAllFilesHashMismatch(t *testing.T) {
 }
 
 func TestVerifyAllFilesSkipsWithoutHash(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 	tmpDir := t.TempDir()
 
 	noHashFile := filepath.Join(tmpDir, "nohash.gguf")
@@ -593,6 +632,48 @@ func TestVerifyAllFilesSkipsWithoutHash(t *testing.T) {
 	}
 }
 
+func TestVerifyAllFilesConcurrent(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+
+	var files []fileDownload
+	var total int64
+	for i := range 6 {
+		content := bytes.Repeat([]byte{byte('a' + i)}, 1000*(i+1))
+		h := sha256.Sum256(content)
+		path := filepath.Join(tmpDir, fmt.Sprintf("part-%d.gguf", i))
+		os.WriteFile(path, content, 0644)
+		files = append(files, fileDownload{
+			file:     &ManifestFile{RFilename: filepath.Base(path), Size: int64(len(content)), LFS: &ManifestLFS{SHA256: hex.EncodeToString(h[:])}},
+			destPath: path,
+		})
+		total += int64(len(content))
+	}
+
+	var last int64
+	err := verifyAllFiles(nil, "user", "repo", files, total, func(p PullProgress) {
+		if p.Phase != "verify" || p.Current < last || p.Current > total {
+			t.Errorf("progress %+v after %d", p, last)
+		}
+		last = p.Current
+	})
+	if err != nil {
+		t.Fatalf("verifyAllFiles() error = %v", err)
+	}
+	if last != total {
+		t.Errorf("progress ended at %d, want %d", last, total)
+	}
+
+	// One bad part fails the whole set and is removed
+	files[3].file.LFS.SHA256 = "wrong_hash"
+	if err := verifyAllFiles(nil, "user", "repo", files, total, nil); err == nil || !strings.Contains(err.Error(), "part-3.gguf") {
+		t.Errorf("verifyAllFiles() error = %v, want failure for part-3.gguf", err)
+	}
+	if _, err := os.Stat(files[3].destPath); !os.IsNotExist(err) {
+		t.Error("bad part should be deleted after verification failure")
+	}
+}
+
 func TestGetOrFetchManifestUsesProvided(t *testing.T) {
 	manifest := &Manifest{
 		GGUFFile: &ManifestFile{RFilename: "model.gguf"},
@@ -3,6 +3,7 @@ package hf
 import (
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -193,3 +194,33 @@ func FormatModelName(user, repo, quant string) string {
 	}
 	return user + "/" + repo + ":" + quant
 }
+
+// quantBitsPattern extracts the bit width from quant names like Q4_K_M or IQ3_XXS
+var quantBitsPattern = regexp.MustCompile(`^(?:UD-)?[IT]?Q([0-9]+)`)
+
+// QuantQualityTier returns a rough quality label for a quantization,
+// from "full" (unquantized) down to "poor" (2 bits and below).
+func QuantQualityTier(quant string) string {
+	quant = strings.ToUpper(quant)
+	switch quant {
+	case "F32", "FP32", "F16", "FP16", "BF16":
+		return "full"
+	}
+
+	m := quantBitsPattern.FindStringSubmatch(quant)
+	if m == nil {
+		return "unknown"
+	}
+	switch bits, _ := strconv.Atoi(m[1]); {
+	case bits >= 8:
+		return "excellent"
+	case bits >= 5:
+		return "high"
+	case bits == 4:
+		return "good"
+	case bits == 3:
+		return "low"
+	default:
+		return "poor"
+	}
+}
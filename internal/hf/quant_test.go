@@ -534,3 +534,30 @@ func TestIsGGUFFile(t *testing.T) {
 		})
 	}
 }
+
+func TestQuantQualityTier(t *testing.T) {
+	tests := []struct {
+		quant string
+		want  string
+	}{
+		{"F16", "full"},
+		{"bf16", "full"},
+		{"Q8_0", "excellent"},
+		{"Q6_K", "high"},
+		{"UD-Q5_K_XL", "high"},
+		{"Q4_K_M", "good"},
+		{"IQ4_XS", "good"},
+		{"Q3_K_M", "low"},
+		{"UD-IQ2_XXS", "poor"},
+		{"TQ1_0", "poor"},
+		{"default", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.quant, func(t *testing.T) {
+			if got := QuantQualityTier(tt.quant); got != tt.want {
+				t.Errorf("QuantQualityTier(%q) = %q, want %q", tt.quant, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package hf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// maxRedirects matches net/http's own limit
+const maxRedirects = 10
+
+// lookupHost resolves redirect targets. Tests replace it.
+var lookupHost = net.DefaultResolver.LookupNetIP
+
+// checkRedirect vets where a Hub request is redirected. The Hub sends
+// downloads on to its CDN, which is fine, but a hostile repo or mirror
+// shouldn't be able to bounce lleme onto services on the local network or
+// from HTTPS down to plain HTTP. An endpoint that is itself on the local
+// network, such as another lleme's /hf mirror, may redirect within it.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	from, to := via[0].URL, req.URL
+	if to.Scheme != "https" && to.Scheme != "http" {
+		return fmt.Errorf("refusing redirect to %s URL", to.Scheme)
+	}
+	if from.Scheme == "https" && to.Scheme != "https" {
+		return fmt.Errorf("refusing redirect from HTTPS to %s", to.Redacted())
+	}
+	ctx := req.Context()
+	if isLocalHost(ctx, to.Hostname()) && !isLocalHost(ctx, from.Hostname()) {
+		return fmt.Errorf("refusing redirect to local address %s", to.Host)
+	}
+	return nil
+}
+
+// isLocalHost reports whether host is, or resolves to, a loopback,
+// private, or link-local address. Hosts that don't resolve aren't local;
+// the request fails on its own.
+func isLocalHost(ctx context.Context, host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return isLocalAddr(addr)
+	}
+	addrs, err := lookupHost(ctx, "ip", host)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if isLocalAddr(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLocalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified()
+}
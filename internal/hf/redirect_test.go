@@ -0,0 +1,59 @@
+package hf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"testing"
+)
+
+func TestCheckRedirect(t *testing.T) {
+	oldLookup := lookupHost
+	defer func() { lookupHost = oldLookup }()
+	lookupHost = func(ctx context.Context, network, host string) ([]netip.Addr, error) {
+		switch host {
+		case "cdn.example.com", "huggingface.co":
+			return []netip.Addr{netip.MustParseAddr("203.0.113.7")}, nil
+		case "internal.example.com":
+			return []netip.Addr{netip.MustParseAddr("10.0.0.5")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	tests := []struct {
+		from, to string
+		ok       bool
+	}{
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://cdn.example.com/a.gguf", true},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "http://cdn.example.com/a.gguf", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "ftp://cdn.example.com/a.gguf", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://127.0.0.1:11313/api/stop-all", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://169.254.169.254/latest/meta-data/", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://[::ffff:192.168.1.1]/", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://localhost/", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://internal.example.com/", false},
+		{"https://huggingface.co/u/r/resolve/main/a.gguf", "https://unresolvable.example.com/", true},
+		// A mirror on the local network may redirect within it
+		{"http://192.168.1.20:11313/hf/u/r/resolve/main/a.gguf", "http://192.168.1.20:11313/hf/blob", true},
+		{"http://192.168.1.20:11313/hf/u/r/resolve/main/a.gguf", "https://cdn.example.com/a.gguf", true},
+	}
+	for _, tt := range tests {
+		from, _ := http.NewRequest("GET", tt.from, nil)
+		to, _ := http.NewRequest("GET", tt.to, nil)
+		err := checkRedirect(to, []*http.Request{from})
+		if (err == nil) != tt.ok {
+			t.Errorf("redirect %s -> %s: error = %v, want ok %v", tt.from, tt.to, err, tt.ok)
+		}
+	}
+
+	from, _ := http.NewRequest("GET", "https://huggingface.co/a", nil)
+	to, _ := http.NewRequest("GET", "https://cdn.example.com/a", nil)
+	via := make([]*http.Request, maxRedirects)
+	for i := range via {
+		via[i] = from
+	}
+	if err := checkRedirect(to, via); err == nil {
+		t.Error("checkRedirect() allowed too many redirects")
+	}
+}
@@ -0,0 +1,444 @@
+package hf
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// s3DefaultRegion is used when neither the config nor the AWS chain sets a
+// region. S3-compatible stores like MinIO accept any region.
+const s3DefaultRegion = "us-east-1"
+
+// S3Ref is a model in S3-compatible storage. A key prefix plays the part of
+// a Hugging Face repo: its GGUF files, or quant directories of split files,
+// are the quantizations.
+type S3Ref struct {
+	Bucket string
+	Prefix string // Key prefix without a trailing slash, empty for the bucket root
+	Quant  string // Requested quantization, empty for the default
+}
+
+// IsS3Ref reports whether ref is an s3:// URL.
+func IsS3Ref(ref string) bool {
+	return strings.HasPrefix(ref, "s3://")
+}
+
+// ParseS3Ref parses s3://bucket/prefix[:quant] or s3://bucket/prefix/file.gguf.
+func ParseS3Ref(ref string) (S3Ref, error) {
+	rest, ok := strings.CutPrefix(ref, "s3://")
+	if !ok {
+		return S3Ref{}, fmt.Errorf("not an s3:// URL: %s", ref)
+	}
+
+	bucket, key, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return S3Ref{}, fmt.Errorf("missing bucket in %s", ref)
+	}
+	r := S3Ref{Bucket: bucket}
+
+	if IsGGUFFile(key) {
+		dir := path.Dir(key)
+		r.Quant = s3QuantName(path.Base(key))
+		if quantDirPattern.MatchString(path.Base(dir)) {
+			r.Quant = strings.ToUpper(path.Base(dir))
+			dir = path.Dir(dir)
+		}
+		if dir != "." {
+			r.Prefix = dir
+		}
+		return r, nil
+	}
+
+	key = strings.TrimSuffix(key, "/")
+	if i := strings.LastIndex(key, ":"); i > strings.LastIndex(key, "/") {
+		key, r.Quant = key[:i], key[i+1:]
+	}
+	r.Prefix = key
+	return r, nil
+}
+
+// LocalName returns the user and repo the model is stored under locally:
+// the bucket, and the last element of the prefix.
+func (r S3Ref) LocalName() (user, repo string) {
+	if r.Prefix == "" {
+		return r.Bucket, r.Bucket
+	}
+	return r.Bucket, path.Base(r.Prefix)
+}
+
+func (r S3Ref) String() string {
+	return "s3://" + path.Join(r.Bucket, r.Prefix)
+}
+
+// S3Source pulls models from a prefix in S3 or an S3-compatible store such
+// as MinIO. Files are verified against their SHA256 when the object carries
+// one (an S3 full-object checksum or x-amz-meta-sha256), otherwise against
+// an MD5 ETag.
+type S3Source struct {
+	client *s3.Client
+	ref    S3Ref
+}
+
+// NewS3Source returns a source for ref, using the AWS credentials chain and
+// the endpoint, region and addressing style from cfg.
+func NewS3Source(cfg *config.Config, ref S3Ref) (*S3Source, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.S3.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.S3.Region))
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if awsCfg.Region == "" {
+		awsCfg.Region = s3DefaultRegion
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.S3.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.S3.Endpoint)
+		}
+		o.UsePathStyle = cfg.S3.PathStyle
+		o.HTTPClient = &http.Client{Transport: logs.Transport(nil)}
+		// Checksums are checked by the pull, and many S3-compatible
+		// stores don't support the newer checksum headers
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+
+	return &S3Source{client: client, ref: ref}, nil
+}
+
+func (s *S3Source) Name() string {
+	return s.ref.String()
+}
+
+// ListQuantizations lists the quantizations under the source's prefix. The
+// user and repo are only used locally, so they are ignored here.
+func (s *S3Source) ListQuantizations(user, repo string) ([]Quantization, error) {
+	objects, err := s.listGGUF()
+	if err != nil {
+		return nil, err
+	}
+
+	var quants []Quantization
+	index := make(map[string]int)
+	for _, obj := range objects {
+		if isMMProj(obj.rel) {
+			continue
+		}
+		name := s3QuantName(obj.rel)
+		if i, ok := index[name]; ok {
+			quants[i].Size += obj.size
+			continue
+		}
+		index[name] = len(quants)
+		quants = append(quants, Quantization{Name: name, Tag: name, File: obj.rel, Size: obj.size})
+	}
+	return quants, nil
+}
+
+func (s *S3Source) GetManifest(user, repo string, quant Quantization) (*Manifest, []byte, error) {
+	objects, err := s.listGGUF()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files, mmprojs []string
+	for _, obj := range objects {
+		if isMMProj(obj.rel) {
+			mmprojs = append(mmprojs, obj.rel)
+		} else if strings.EqualFold(s3QuantName(obj.rel), quant.Name) {
+			files = append(files, obj.rel)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil, fmt.Errorf("quantization %s not found in %s", quant.Name, s.ref)
+	}
+	sort.Strings(files)
+	if len(files) > 1 && ParseSplitFilename(files[0]) == nil {
+		return nil, nil, fmt.Errorf("found %d GGUF files for %s in %s, expected one or a set of splits", len(files), quant.Name, s.ref)
+	}
+
+	manifest := &Manifest{}
+	for i, rel := range files {
+		mf, err := s.manifestFile(rel)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			manifest.GGUFFile = mf
+		} else {
+			manifest.SplitFiles = append(manifest.SplitFiles, mf)
+		}
+	}
+
+	// Prefer a projector next to the model files over one at the prefix root
+	if rel := pickMMProj(mmprojs, path.Dir(files[0])); rel != "" {
+		if manifest.MMProjFile, err = s.manifestFile(rel); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, manifestJSON, nil
+}
+
+// Download fetches an object with ranged GETs, resuming from a .partial file.
+// If-Match on the ETag keeps a resumed download from mixing two versions.
+func (s *S3Source) Download(user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
+	partialPath := destPath + ".partial"
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil && info.Size() <= file.Size {
+		offset = info.Size()
+	}
+
+	if offset < file.Size || file.Size == 0 {
+		if err := s.downloadRange(file, partialPath, offset, progress); err != nil {
+			return err
+		}
+	}
+
+	if file.LFS == nil && file.MD5 != "" {
+		if err := verifyMD5(partialPath, file.MD5); err != nil {
+			os.Remove(partialPath)
+			return fmt.Errorf("%s: %w", file.RFilename, err)
+		}
+	}
+	return os.Rename(partialPath, destPath)
+}
+
+func (s *S3Source) downloadRange(file *ManifestFile, partialPath string, offset int64, progress func(current, total int64)) error {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.ref.Bucket),
+		Key:    aws.String(s.key(file.RFilename)),
+	}
+	if file.ETag != "" {
+		input.IfMatch = aws.String(file.ETag)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	out, err := s.client.GetObject(context.Background(), input)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed" {
+			os.Remove(partialPath)
+			return fmt.Errorf("%s changed during download, pull again", file.RFilename)
+		}
+		return fmt.Errorf("failed to download %s: %w", file.RFilename, err)
+	}
+	defer out.Body.Close()
+
+	f, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	written := offset
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := out.Body.Read(buf)
+		if n > 0 {
+			if _, werr := f.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			written += int64(n)
+			if progress != nil {
+				progress(written, file.Size)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", file.RFilename, err)
+		}
+	}
+
+	if written != file.Size {
+		return fmt.Errorf("%s: downloaded %d of %d bytes", file.RFilename, written, file.Size)
+	}
+	return f.Close()
+}
+
+// manifestFile describes one object, picking up whatever hashes it carries.
+func (s *S3Source) manifestFile(rel string) (*ManifestFile, error) {
+	out, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket:       aws.String(s.ref.Bucket),
+		Key:          aws.String(s.key(rel)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", s.key(rel), err)
+	}
+
+	mf := &ManifestFile{
+		RFilename: rel,
+		Size:      aws.ToInt64(out.ContentLength),
+		ETag:      aws.ToString(out.ETag),
+	}
+	if sum := objectSHA256(out); sum != "" {
+		mf.LFS = &ManifestLFS{SHA256: sum, Size: mf.Size}
+	}
+	if md5sum := strings.Trim(mf.ETag, `"`); isHex(md5sum, md5.Size) && !isKMSEncrypted(out.ServerSideEncryption) {
+		mf.MD5 = md5sum
+	}
+	return mf, nil
+}
+
+type s3Object struct {
+	rel  string // Key relative to the prefix
+	size int64
+}
+
+// listGGUF lists GGUF objects directly under the prefix or one directory
+// below it, where quant directories of split files live.
+func (s *S3Source) listGGUF() ([]s3Object, error) {
+	prefix := ""
+	if s.ref.Prefix != "" {
+		prefix = s.ref.Prefix + "/"
+	}
+
+	var objects []s3Object
+	pager := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.ref.Bucket),
+		Prefix: aws.String(prefix),
+	})
+	for pager.HasMorePages() {
+		page, err := pager.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", s.ref, err)
+		}
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(aws.ToString(obj.Key), prefix)
+			if !IsGGUFFile(rel) || strings.Count(rel, "/") > 1 {
+				continue
+			}
+			objects = append(objects, s3Object{rel: rel, size: aws.ToInt64(obj.Size)})
+		}
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no GGUF files found in %s", s.ref)
+	}
+	return objects, nil
+}
+
+func (s *S3Source) key(rel string) string {
+	if s.ref.Prefix == "" {
+		return rel
+	}
+	return s.ref.Prefix + "/" + rel
+}
+
+// s3QuantName returns the quantization a GGUF file belongs to, following
+// Hugging Face conventions: a quant directory, or a quant suffix before any
+// split suffix.
+func s3QuantName(rel string) string {
+	dir, name := path.Split(rel)
+	if dir = strings.TrimSuffix(dir, "/"); dir != "" && quantDirPattern.MatchString(path.Base(dir)) {
+		return strings.ToUpper(path.Base(dir))
+	}
+	if info := ParseSplitFilename(name); info != nil {
+		name = SplitPrefix(name, info.SplitNo, info.SplitCount) + ".gguf"
+	}
+	if q := ParseQuantization(name); q != "" {
+		return q
+	}
+	return "default"
+}
+
+func isMMProj(rel string) bool {
+	return strings.Contains(strings.ToLower(path.Base(rel)), "mmproj")
+}
+
+// pickMMProj returns the first projector in dir, else the first at the root.
+func pickMMProj(mmprojs []string, dir string) string {
+	sort.Strings(mmprojs)
+	for _, rel := range mmprojs {
+		if path.Dir(rel) == dir {
+			return rel
+		}
+	}
+	for _, rel := range mmprojs {
+		if path.Dir(rel) == "." {
+			return rel
+		}
+	}
+	return ""
+}
+
+// objectSHA256 returns the object's SHA256 as hex, from a full-object
+// checksum or x-amz-meta-sha256. Composite (multipart) checksums don't hash
+// the content, so they're ignored.
+func objectSHA256(out *s3.HeadObjectOutput) string {
+	if sum := aws.ToString(out.ChecksumSHA256); sum != "" && out.ChecksumType != types.ChecksumTypeComposite && !strings.Contains(sum, "-") {
+		if raw, err := base64.StdEncoding.DecodeString(sum); err == nil && len(raw) == 32 {
+			return hex.EncodeToString(raw)
+		}
+	}
+	if sum := strings.ToLower(out.Metadata["sha256"]); isHex(sum, 32) {
+		return sum
+	}
+	return ""
+}
+
+// isKMSEncrypted reports whether the ETag is something other than an MD5
+// of the content, as it is for SSE-KMS objects.
+func isKMSEncrypted(sse types.ServerSideEncryption) bool {
+	return strings.HasPrefix(string(sse), "aws:kms")
+}
+
+func isHex(s string, bytes int) bool {
+	if len(s) != bytes*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func verifyMD5(filePath, expected string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("MD5 mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package hf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestParseS3Ref(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want S3Ref
+		user string
+		repo string
+	}{
+		{"s3://models/llama", S3Ref{Bucket: "models", Prefix: "llama"}, "models", "llama"},
+		{"s3://models/llama/", S3Ref{Bucket: "models", Prefix: "llama"}, "models", "llama"},
+		{"s3://models/team/llama:Q8_0", S3Ref{Bucket: "models", Prefix: "team/llama", Quant: "Q8_0"}, "models", "llama"},
+		{"s3://models/llama/model-Q4_K_M.gguf", S3Ref{Bucket: "models", Prefix: "llama", Quant: "Q4_K_M"}, "models", "llama"},
+		{"s3://models/llama/Q8_0/model-Q8_0-00001-of-00002.gguf", S3Ref{Bucket: "models", Prefix: "llama", Quant: "Q8_0"}, "models", "llama"},
+		{"s3://models/model-Q4_K_M.gguf", S3Ref{Bucket: "models", Quant: "Q4_K_M"}, "models", "models"},
+		{"s3://models", S3Ref{Bucket: "models"}, "models", "models"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseS3Ref(tt.ref)
+			if err != nil {
+				t.Fatalf("ParseS3Ref() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseS3Ref() = %+v, want %+v", got, tt.want)
+			}
+			user, repo := got.LocalName()
+			if user != tt.user || repo != tt.repo {
+				t.Errorf("LocalName() = %s/%s, want %s/%s", user, repo, tt.user, tt.repo)
+			}
+		})
+	}
+
+	for _, ref := range []string{"s3:///prefix", "https://models/llama"} {
+		if _, err := ParseS3Ref(ref); err == nil {
+			t.Errorf("ParseS3Ref(%q) should fail", ref)
+		}
+	}
+}
+
+func TestS3QuantName(t *testing.T) {
+	tests := map[string]string{
+		"model-Q4_K_M.gguf":                    "Q4_K_M",
+		"model-Q8_0-00002-of-00003.gguf":       "Q8_0",
+		"Q5_K_M/model-00001-of-00002.gguf":     "Q5_K_M",
+		"ud-q4_k_xl/model-00001-of-00002.gguf": "UD-Q4_K_XL",
+		"model.gguf":                           "default",
+	}
+	for rel, want := range tests {
+		if got := s3QuantName(rel); got != want {
+			t.Errorf("s3QuantName(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
+
+// fakeS3 serves a bucket over the S3 REST API with path-style addressing
+type fakeS3 struct {
+	bucket  string
+	objects map[string][]byte
+	meta    map[string]map[string]string
+	ranges  []string
+}
+
+func (f *fakeS3) etag(key string) string {
+	sum := md5.Sum(f.objects[key])
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	key = strings.TrimPrefix(key, "/")
+
+	if key == "" && r.URL.Query().Get("list-type") == "2" {
+		type content struct {
+			Key  string
+			Size int64
+			ETag string
+		}
+		var result struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			IsTruncated bool
+			Contents    []content
+		}
+		result.Name = f.bucket
+		var keys []string
+		for k := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			result.Contents = append(result.Contents, content{Key: k, Size: int64(len(f.objects[k])), ETag: f.etag(k)})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+		return
+	}
+
+	data, ok := f.objects[key]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	for k, v := range f.meta[key] {
+		w.Header().Set("x-amz-meta-"+k, v)
+	}
+	if rng := r.Header.Get("Range"); rng != "" {
+		f.ranges = append(f.ranges, rng)
+	}
+	w.Header().Set("ETag", f.etag(key))
+	http.ServeContent(w, r, key, time.Time{}, bytes.NewReader(data))
+}
+
+func newTestS3Source(t *testing.T, fake *fakeS3, ref string) *S3Source {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	// Static credentials through the default chain, isolated from the host
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "")
+
+	cfg := config.DefaultConfig()
+	cfg.S3 = config.S3{Endpoint: srv.URL, PathStyle: true}
+
+	s3Ref, err := ParseS3Ref(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewS3Source(cfg, s3Ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestS3SourcePull(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	q4 := []byte("q4 model data")
+	sum := sha256.Sum256(q4)
+	fake := &fakeS3{
+		bucket: "models",
+		objects: map[string][]byte{
+			"llama/model-Q4_K_M.gguf":                   q4,
+			"llama/Q8_0/model-Q8_0-00001-of-00002.gguf": []byte("first half"),
+			"llama/Q8_0/model-Q8_0-00002-of-00002.gguf": []byte("second half"),
+			"llama/mmproj-F16.gguf":                     []byte("projector"),
+			"llama/notes.txt":                           []byte("ignored"),
+			"other/model-Q4_K_M.gguf":                   []byte("other"),
+		},
+		meta: map[string]map[string]string{
+			"llama/model-Q4_K_M.gguf": {"sha256": hex.EncodeToString(sum[:])},
+		},
+	}
+	source := newTestS3Source(t, fake, "s3://models/llama")
+
+	quants, err := source.ListQuantizations("", "")
+	if err != nil {
+		t.Fatalf("ListQuantizations() error = %v", err)
+	}
+	if len(quants) != 2 {
+		t.Fatalf("ListQuantizations() = %+v, want Q4_K_M and Q8_0", quants)
+	}
+	q8, ok := FindQuantization(quants, "Q8_0")
+	if !ok || q8.Size != int64(len("first half")+len("second half")) {
+		t.Errorf("Q8_0 = %+v", q8)
+	}
+
+	q4Quant, _ := FindQuantization(quants, "Q4_K_M")
+	manifest, _, err := source.GetManifest("models", "llama", q4Quant)
+	if err != nil {
+		t.Fatalf("GetManifest() error = %v", err)
+	}
+	if manifest.GGUFFile.LFS == nil || manifest.GGUFFile.LFS.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("GGUFFile.LFS = %+v, want sha256 from metadata", manifest.GGUFFile.LFS)
+	}
+	if manifest.MMProjFile == nil || manifest.MMProjFile.RFilename != "mmproj-F16.gguf" {
+		t.Errorf("MMProjFile = %+v", manifest.MMProjFile)
+	}
+
+	if _, err := PullModel(source, "models", "llama", q4Quant, nil, nil); err != nil {
+		t.Fatalf("PullModel(Q4_K_M) error = %v", err)
+	}
+	if data, _ := os.ReadFile(GetModelFilePath("models", "llama", "Q4_K_M")); string(data) != string(q4) {
+		t.Errorf("model file = %q", data)
+	}
+
+	// Split files without a sha256 are verified against their MD5 ETags
+	result, err := PullModel(source, "models", "llama", q8, nil, nil)
+	if err != nil {
+		t.Fatalf("PullModel(Q8_0) error = %v", err)
+	}
+	if result.TotalSize == 0 {
+		t.Error("TotalSize should include split files")
+	}
+	if FindModelFile("models", "llama", "Q8_0") == "" {
+		t.Error("split model should be found after pull")
+	}
+
+	upToDate, _, _, _, err := CheckForUpdates(source, "models", "llama", q8)
+	if err != nil || !upToDate {
+		t.Errorf("CheckForUpdates() = %v, %v, want up to date", upToDate, err)
+	}
+	fake.objects["llama/Q8_0/model-Q8_0-00001-of-00002.gguf"] = []byte("first half, v2")
+	upToDate, _, _, _, err = CheckForUpdates(source, "models", "llama", q8)
+	if err != nil || upToDate {
+		t.Errorf("CheckForUpdates() = %v, %v, want out of date after the object changed", upToDate, err)
+	}
+}
+
+func TestS3SourceDownloadResumes(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	fake := &fakeS3{bucket: "models", objects: map[string][]byte{"llama/model-Q4_K_M.gguf": data}}
+	source := newTestS3Source(t, fake, "s3://models/llama")
+
+	file, err := source.manifestFile("model-Q4_K_M.gguf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(dest+".partial", data[:6], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var last int64
+	err = source.Download("models", "llama", file, dest, func(current, total int64) { last = current })
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != string(data) {
+		t.Errorf("downloaded %q, want %q", got, data)
+	}
+	if len(fake.ranges) != 1 || fake.ranges[0] != "bytes=6-" {
+		t.Errorf("ranges = %v, want [bytes=6-]", fake.ranges)
+	}
+	if last != int64(len(data)) {
+		t.Errorf("last progress = %d, want %d", last, len(data))
+	}
+
+	// A changed object fails the If-Match instead of mixing versions
+	if err := os.WriteFile(dest+".partial", data[:6], 0644); err != nil {
+		t.Fatal(err)
+	}
+	fake.objects["llama/model-Q4_K_M.gguf"] = []byte("a different model")
+	err = source.Download("models", "llama", file, dest, nil)
+	if err == nil || !strings.Contains(err.Error(), "changed") {
+		t.Errorf("Download() error = %v, want changed object error", err)
+	}
+	if _, statErr := os.Stat(dest + ".partial"); !os.IsNotExist(statErr) {
+		t.Error("stale partial file should be removed")
+	}
+}
+
+func TestS3SourceMD5Mismatch(t *testing.T) {
+	fake := &fakeS3{bucket: "models", objects: map[string][]byte{"model-Q4_K_M.gguf": []byte("model")}}
+	source := newTestS3Source(t, fake, "s3://models")
+
+	file, err := source.manifestFile("model-Q4_K_M.gguf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.MD5 = fmt.Sprintf("%x", md5.Sum([]byte("something else")))
+
+	dest := filepath.Join(t.TempDir(), "model.gguf")
+	if err := source.Download("models", "models", file, dest, nil); err == nil || !strings.Contains(err.Error(), "MD5 mismatch") {
+		t.Errorf("Download() error = %v, want MD5 mismatch", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("mismatched file should not be kept")
+	}
+}
@@ -0,0 +1,85 @@
+package hf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// ModelSource is somewhere models can be pulled from. The pull logic only
+// talks to a ModelSource, so buckets, artifact registries and other indexes
+// can be added without changing it. Models are always stored locally under
+// user/repo/quant, whatever the source calls them.
+type ModelSource interface {
+	// Name identifies the source in messages, e.g. "Hugging Face".
+	Name() string
+
+	// ListQuantizations returns the quantizations available for user/repo.
+	ListQuantizations(user, repo string) ([]Quantization, error)
+
+	// GetManifest describes the files that make up a quantization, including
+	// any split files, and returns the raw manifest to save alongside them.
+	GetManifest(user, repo string, quant Quantization) (*Manifest, []byte, error)
+
+	// Download fetches one manifest file to destPath, resuming a partial
+	// download when possible. SHA256 verification is left to the caller;
+	// a source may check other hashes it has.
+	Download(user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error
+}
+
+// HFSource pulls models from Hugging Face.
+type HFSource struct {
+	client *Client
+}
+
+// NewHFSource returns a ModelSource backed by client.
+func NewHFSource(client *Client) *HFSource {
+	return &HFSource{client: client}
+}
+
+func (s *HFSource) Name() string {
+	return "Hugging Face"
+}
+
+func (s *HFSource) ListQuantizations(user, repo string) ([]Quantization, error) {
+	files, err := s.client.ListFiles(user, repo, "main")
+	if err != nil {
+		return nil, err
+	}
+	return ExtractQuantizations(files), nil
+}
+
+func (s *HFSource) GetManifest(user, repo string, quant Quantization) (*Manifest, []byte, error) {
+	manifest, manifestJSON, err := s.client.GetManifest(user, repo, quant.Tag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	// The manifest only names the first split, so look up the rest
+	if splitInfo := ParseSplitFilename(manifest.GGUFFile.RFilename); splitInfo != nil && splitInfo.SplitNo == 0 {
+		splitFiles, err := fetchSplitFileInfo(s.client, user, repo, splitInfo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to fetch split file info: %w", err)
+		}
+		manifest.SplitFiles = splitFiles
+	}
+
+	return manifest, manifestJSON, nil
+}
+
+func (s *HFSource) Download(user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
+	downloader := NewDownloaderWithProgress(s.client, func(current, total int64, speed float64, eta time.Duration) {
+		if progress != nil {
+			progress(current, total)
+		}
+	})
+
+	_, err := downloader.DownloadModel(user, repo, "main", file.RFilename, destPath)
+	return err
+}
+
+// NewSource returns the source to pull models from.
+func NewSource(cfg *config.Config) ModelSource {
+	return NewHFSource(NewClient(cfg))
+}
@@ -0,0 +1,154 @@
+package hf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// memorySource serves models from memory
+type memorySource struct {
+	files     map[string][]byte
+	manifest  *Manifest
+	downloads int
+}
+
+func newMemorySource(files map[string][]byte, names ...string) *memorySource {
+	s := &memorySource{files: files, manifest: &Manifest{}}
+	for i, name := range names {
+		sum := sha256.Sum256(files[name])
+		mf := &ManifestFile{
+			RFilename: name,
+			Size:      int64(len(files[name])),
+			LFS:       &ManifestLFS{SHA256: hex.EncodeToString(sum[:]), Size: int64(len(files[name]))},
+		}
+		if i == 0 {
+			s.manifest.GGUFFile = mf
+		} else {
+			s.manifest.SplitFiles = append(s.manifest.SplitFiles, mf)
+		}
+	}
+	return s
+}
+
+func (s *memorySource) Name() string { return "memory" }
+
+func (s *memorySource) ListQuantizations(user, repo string) ([]Quantization, error) {
+	return []Quantization{{Name: "Q4_K_M", Tag: "Q4_K_M"}}, nil
+}
+
+func (s *memorySource) GetManifest(user, repo string, quant Quantization) (*Manifest, []byte, error) {
+	data, err := json.Marshal(s.manifest)
+	return s.manifest, data, err
+}
+
+func (s *memorySource) Download(user, repo string, file *ManifestFile, destPath string, progress func(current, total int64)) error {
+	s.downloads++
+	data, ok := s.files[file.RFilename]
+	if !ok {
+		return fmt.Errorf("%s not found", file.RFilename)
+	}
+	if progress != nil {
+		progress(int64(len(data)), int64(len(data)))
+	}
+	return os.WriteFile(destPath, data, 0644)
+}
+
+func TestPullModelFromSource(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	source := newMemorySource(map[string][]byte{"model-Q4_K_M.gguf": []byte("gguf data")}, "model-Q4_K_M.gguf")
+	quant := Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}
+
+	result, err := PullModel(source, "user", "repo", quant, nil, nil)
+	if err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+	if result.TotalSize != int64(len("gguf data")) {
+		t.Errorf("TotalSize = %d, want %d", result.TotalSize, len("gguf data"))
+	}
+
+	data, err := os.ReadFile(GetModelFilePath("user", "repo", "Q4_K_M"))
+	if err != nil || string(data) != "gguf data" {
+		t.Errorf("model file = %q, %v", data, err)
+	}
+	if _, err := os.Stat(GetManifestFilePath("user", "repo", "Q4_K_M")); err != nil {
+		t.Errorf("manifest not saved: %v", err)
+	}
+
+	upToDate, _, _, _, err := CheckForUpdates(source, "user", "repo", quant)
+	if err != nil {
+		t.Fatalf("CheckForUpdates() error = %v", err)
+	}
+	if !upToDate {
+		t.Error("CheckForUpdates() should report the pulled model as up to date")
+	}
+}
+
+func TestPullModelFromSourceSplit(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	files := map[string][]byte{
+		"model-00001-of-00002.gguf": []byte("first"),
+		"model-00002-of-00002.gguf": []byte("second"),
+	}
+	source := newMemorySource(files, "model-00001-of-00002.gguf", "model-00002-of-00002.gguf")
+	quant := Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}
+
+	if _, err := PullModel(source, "user", "repo", quant, nil, nil); err != nil {
+		t.Fatalf("PullModel() error = %v", err)
+	}
+	if source.downloads != 2 {
+		t.Errorf("downloads = %d, want 2", source.downloads)
+	}
+
+	// A source that omits split files is rejected before downloading
+	source.manifest.SplitFiles = nil
+	source.downloads = 0
+	if _, err := PullModel(source, "user", "other", quant, nil, nil); err == nil {
+		t.Error("PullModel() should fail when split files are missing from the manifest")
+	}
+	if source.downloads != 0 {
+		t.Errorf("downloads = %d, want 0", source.downloads)
+	}
+}
+
+func TestPullModelFromSourceHashMismatch(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	source := newMemorySource(map[string][]byte{"model.gguf": []byte("good")}, "model.gguf")
+	source.files["model.gguf"] = []byte("corrupt")
+	quant := Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}
+
+	if _, err := PullModel(source, "user", "repo", quant, nil, nil); err == nil {
+		t.Fatal("PullModel() should fail on hash mismatch")
+	}
+	if _, err := os.Stat(GetModelFilePath("user", "repo", "Q4_K_M")); !os.IsNotExist(err) {
+		t.Error("corrupt model file should be cleaned up")
+	}
+}
+
+func TestPullModelRejectsTraversal(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	quant := Quantization{Name: "Q4_K_M", Tag: "Q4_K_M"}
+
+	// A split file the source added after its manifest was parsed
+	source := newMemorySource(map[string][]byte{"model-00001-of-00002.gguf": []byte("a")}, "model-00001-of-00002.gguf")
+	source.manifest.SplitFiles = []*ManifestFile{{RFilename: "../../../.bashrc"}}
+	if _, err := PullModel(source, "user", "repo", quant, nil, nil); err == nil || !strings.Contains(err.Error(), "unsafe file name") {
+		t.Errorf("PullModel() with a traversing split file error = %v", err)
+	}
+
+	// A model name that leads out of the models directory
+	source = newMemorySource(map[string][]byte{"model.gguf": []byte("a")}, "model.gguf")
+	if _, err := PullModel(source, "..", "..", quant, nil, nil); err == nil || !strings.Contains(err.Error(), "outside the models directory") {
+		t.Errorf("PullModel() with a traversing model name error = %v", err)
+	}
+	if source.downloads != 0 {
+		t.Errorf("%d files downloaded", source.downloads)
+	}
+}
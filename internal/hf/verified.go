@@ -0,0 +1,84 @@
+package hf
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// verifiedEntry fingerprints a file whose SHA256 has been checked. A file
+// with the same size and modification time is assumed unchanged, so it
+// isn't downloaded or hashed again.
+type verifiedEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+var verifiedMu sync.Mutex
+
+func verifiedCachePath() string {
+	return filepath.Join(paths.Cache(), "verified.json")
+}
+
+func loadVerified() map[string]verifiedEntry {
+	entries := make(map[string]verifiedEntry)
+	data, err := os.ReadFile(verifiedCachePath())
+	if err != nil {
+		return entries
+	}
+	json.Unmarshal(data, &entries)
+	return entries
+}
+
+// isVerified reports whether path was verified against sha256 and hasn't
+// changed since.
+func isVerified(path, sha256 string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	verifiedMu.Lock()
+	entry, ok := loadVerified()[path]
+	verifiedMu.Unlock()
+
+	return ok && entry.Size == info.Size() && entry.ModTime.Equal(info.ModTime()) &&
+		strings.EqualFold(entry.SHA256, sha256)
+}
+
+// recordVerified fingerprints path after its hash checked out. Entries for
+// files that no longer exist are dropped. The cache is only an optimization,
+// so failures are ignored.
+func recordVerified(path, sha256 string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	verifiedMu.Lock()
+	defer verifiedMu.Unlock()
+
+	entries := loadVerified()
+	for p := range entries {
+		if _, err := os.Stat(p); err != nil {
+			delete(entries, p)
+		}
+	}
+	entries[path] = verifiedEntry{Size: info.Size(), ModTime: info.ModTime(), SHA256: sha256}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(paths.Cache(), 0755); err != nil {
+		return
+	}
+	fileutil.AtomicWriteFile(verifiedCachePath(), data, 0644)
+}
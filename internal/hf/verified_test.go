@@ -0,0 +1,77 @@
+package hf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVerifiedCache(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	content := []byte("model weights")
+	os.WriteFile(path, content, 0644)
+	h := sha256.Sum256(content)
+	hash := hex.EncodeToString(h[:])
+
+	if isVerified(path, hash) {
+		t.Fatal("file should not be verified before recordVerified")
+	}
+	if err := verifyFile(path, hash, nil); err != nil {
+		t.Fatalf("verifyFile() error = %v", err)
+	}
+	if !isVerified(path, hash) {
+		t.Fatal("file should be verified after verifyFile")
+	}
+	if isVerified(path, "other") {
+		t.Error("a different expected hash should not match")
+	}
+
+	// A changed file has to be hashed again
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(path, later, later)
+	if isVerified(path, hash) {
+		t.Error("a file with a new modification time should not match")
+	}
+
+	// Entries for deleted files are dropped on the next write
+	other := filepath.Join(t.TempDir(), "other.gguf")
+	os.WriteFile(other, content, 0644)
+	os.Remove(path)
+	recordVerified(other, hash)
+	if entries := loadVerified(); len(entries) != 1 {
+		t.Errorf("cache has %d entries, want 1", len(entries))
+	}
+}
+
+func TestDownloadAllFilesKeepsVerified(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "mmproj.gguf")
+	content := []byte("projector")
+	os.WriteFile(path, content, 0644)
+	h := sha256.Sum256(content)
+	hash := hex.EncodeToString(h[:])
+	recordVerified(path, hash)
+
+	files := []fileDownload{{
+		file:     &ManifestFile{RFilename: "mmproj.gguf", Size: int64(len(content)), LFS: &ManifestLFS{SHA256: hash}},
+		destPath: path,
+	}}
+
+	// With no source, anything but the verified file would fail to download
+	var last int64
+	err := downloadAllFiles(nil, "user", "repo", files, nil, false, int64(len(content)), func(p PullProgress) {
+		last = p.Current
+	})
+	if err != nil {
+		t.Fatalf("downloadAllFiles() error = %v", err)
+	}
+	if last != int64(len(content)) {
+		t.Errorf("progress = %d, want %d", last, len(content))
+	}
+}
@@ -0,0 +1,109 @@
+package hw
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// cpuFeatures are the SIMD extensions llama.cpp's CPU backend takes advantage of,
+// in display order. /proc/cpuinfo reports NEON as "asimd" on arm64.
+var cpuFeatures = []string{
+	"avx", "avx2", "avx512f", "avx512_vnni", "avx_vnni", "fma", "f16c",
+	"neon", "sve", "sve2", "dotprod", "i8mm",
+}
+
+// darwinCPUFeatures maps sysctl hw.optional keys to feature names.
+var darwinCPUFeatures = map[string]string{
+	"hw.optional.avx1_0":           "avx",
+	"hw.optional.avx2_0":           "avx2",
+	"hw.optional.avx512f":          "avx512f",
+	"hw.optional.fma":              "fma",
+	"hw.optional.neon":             "neon",
+	"hw.optional.arm.FEAT_DotProd": "dotprod",
+	"hw.optional.arm.FEAT_I8MM":    "i8mm",
+}
+
+// DetectCPU returns the CPU model, core count, and supported SIMD features.
+func DetectCPU() CPUInfo {
+	info := CPUInfo{Cores: runtime.NumCPU()}
+
+	switch runtime.GOOS {
+	case "linux":
+		if f, err := os.Open("/proc/cpuinfo"); err == nil {
+			info.Model, info.Features = parseCPUInfo(f)
+			f.Close()
+		}
+	case "darwin":
+		if out, err := runCommand("sysctl", "-n", "machdep.cpu.brand_string"); err == nil {
+			info.Model = strings.TrimSpace(string(out))
+		}
+		info.Features = darwinFeatures()
+	}
+
+	// NEON is mandatory on arm64 even when the OS doesn't list it
+	if runtime.GOARCH == "arm64" && !slices.Contains(info.Features, "neon") {
+		info.Features = append([]string{"neon"}, info.Features...)
+	}
+	return info
+}
+
+// parseCPUInfo extracts the model name and known features from /proc/cpuinfo.
+// Only the first processor entry is read; features are uniform across cores.
+func parseCPUInfo(r io.Reader) (string, []string) {
+	var model string
+	flags := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "model name", "Model":
+			if model == "" {
+				model = value
+			}
+		case "flags", "Features":
+			if len(flags) > 0 {
+				continue
+			}
+			for _, flag := range strings.Fields(value) {
+				if flag == "asimd" {
+					flag = "neon"
+				} else if flag == "asimddp" {
+					flag = "dotprod"
+				}
+				flags[flag] = true
+			}
+		}
+	}
+	return model, knownFeatures(flags)
+}
+
+func darwinFeatures() []string {
+	flags := make(map[string]bool)
+	for key, feature := range darwinCPUFeatures {
+		if out, err := runCommand("sysctl", "-n", key); err == nil && strings.TrimSpace(string(out)) == "1" {
+			flags[feature] = true
+		}
+	}
+	return knownFeatures(flags)
+}
+
+func knownFeatures(flags map[string]bool) []string {
+	var features []string
+	for _, f := range cpuFeatures {
+		if flags[f] {
+			features = append(features, f)
+		}
+	}
+	return features
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package hw
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// FreeDisk is not supported here.
+func FreeDisk(path string) (int64, error) {
+	return 0, fmt.Errorf("disk space detection not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package hw
+
+import "golang.org/x/sys/unix"
+
+// FreeDisk returns the bytes available to this user on the filesystem
+// holding path.
+func FreeDisk(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
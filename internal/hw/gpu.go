@@ -0,0 +1,175 @@
+package hw
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// amdVendorID is the PCI vendor ID for AMD GPUs in sysfs
+const amdVendorID = "0x1002"
+
+// drmPath is where Linux exposes GPU devices. Variable for tests.
+var drmPath = "/sys/class/drm"
+
+// DetectGPUs returns the GPUs it can find. Detection failures are not errors;
+// the host simply reports fewer GPUs.
+func DetectGPUs() []GPU {
+	var gpus []GPU
+	switch runtime.GOOS {
+	case "darwin":
+		gpus = append(gpus, detectAppleGPUs()...)
+	case "linux":
+		gpus = append(gpus, detectNvidiaGPUs()...)
+		gpus = append(gpus, detectAMDGPUs()...)
+	case "windows":
+		gpus = append(gpus, detectNvidiaGPUs()...)
+	}
+	return gpus
+}
+
+func detectNvidiaGPUs() []GPU {
+	output, err := runCommand("nvidia-smi", "--query-gpu=name,memory.total,memory.free", "--format=csv,noheader,nounits")
+	if err != nil {
+		return nil
+	}
+	return parseNvidiaSMI(string(output))
+}
+
+// parseNvidiaSMI parses nvidia-smi CSV output with name, total and free MiB.
+func parseNvidiaSMI(output string) []GPU {
+	var gpus []GPU
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			continue
+		}
+		total, err1 := strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64)
+		free, err2 := strconv.ParseInt(strings.TrimSpace(fields[2]), 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		gpus = append(gpus, GPU{
+			Vendor:    "nvidia",
+			Name:      strings.TrimSpace(fields[0]),
+			VRAMTotal: total << 20,
+			VRAMFree:  free << 20,
+		})
+	}
+	return gpus
+}
+
+// detectAMDGPUs reads VRAM usage that the amdgpu driver exposes in sysfs.
+func detectAMDGPUs() []GPU {
+	cards, _ := filepath.Glob(filepath.Join(drmPath, "card[0-9]*"))
+
+	var gpus []GPU
+	for _, card := range cards {
+		device := filepath.Join(card, "device")
+		if strings.TrimSpace(readFile(filepath.Join(device, "vendor"))) != amdVendorID {
+			continue
+		}
+		total, err := strconv.ParseInt(strings.TrimSpace(readFile(filepath.Join(device, "mem_info_vram_total"))), 10, 64)
+		if err != nil || total == 0 {
+			continue
+		}
+		used, _ := strconv.ParseInt(strings.TrimSpace(readFile(filepath.Join(device, "mem_info_vram_used"))), 10, 64)
+
+		name := strings.TrimSpace(readFile(filepath.Join(device, "product_name")))
+		if name == "" {
+			name = "AMD GPU (" + filepath.Base(card) + ")"
+		}
+		gpus = append(gpus, GPU{
+			Vendor:    "amd",
+			Name:      name,
+			VRAMTotal: total,
+			VRAMFree:  max(total-used, 0),
+		})
+	}
+	return gpus
+}
+
+func detectAppleGPUs() []GPU {
+	output, err := runCommand("system_profiler", "SPDisplaysDataType", "-json")
+	if err != nil {
+		return nil
+	}
+	gpus, err := parseSystemProfiler(output)
+	if err != nil {
+		return nil
+	}
+	return gpus
+}
+
+type systemProfilerDisplays struct {
+	Displays []struct {
+		Model  string `json:"sppci_model"`
+		Cores  string `json:"sppci_cores"`
+		Vendor string `json:"spdisplays_vendor"`
+		VRAM   string `json:"spdisplays_vram"`
+	} `json:"SPDisplaysDataType"`
+}
+
+// parseSystemProfiler parses `system_profiler SPDisplaysDataType -json`.
+// Apple GPUs report a core count and share system memory.
+func parseSystemProfiler(output []byte) ([]GPU, error) {
+	var data systemProfilerDisplays
+	if err := json.Unmarshal(output, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse system_profiler output: %w", err)
+	}
+
+	var gpus []GPU
+	for _, d := range data.Displays {
+		gpu := GPU{Name: d.Model}
+		gpu.Cores, _ = strconv.Atoi(d.Cores)
+
+		vendor := strings.ToLower(d.Vendor)
+		switch {
+		case strings.Contains(vendor, "apple"):
+			gpu.Vendor = "apple"
+			gpu.Unified = true
+		case strings.Contains(vendor, "nvidia"):
+			gpu.Vendor = "nvidia"
+		case strings.Contains(vendor, "amd"):
+			gpu.Vendor = "amd"
+		default:
+			gpu.Vendor = vendor
+		}
+		if !gpu.Unified {
+			gpu.VRAMTotal = parseVRAMString(d.VRAM)
+		}
+		gpus = append(gpus, gpu)
+	}
+	return gpus, nil
+}
+
+// parseVRAMString parses sizes like "8 GB" or "1536 MB".
+func parseVRAMString(s string) int64 {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0
+	}
+	n, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "GB":
+		return n << 30
+	case "MB":
+		return n << 20
+	}
+	return 0
+}
+
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,97 @@
+// Package hw detects the host's CPU, memory, and GPUs.
+package hw
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds external probes like nvidia-smi and system_profiler
+const commandTimeout = 5 * time.Second
+
+// Info describes the host hardware relevant to running models.
+type Info struct {
+	OS     string
+	Arch   string
+	CPU    CPUInfo
+	Memory MemoryInfo
+	GPUs   []GPU
+}
+
+// CPUInfo describes the host CPU.
+type CPUInfo struct {
+	Model    string
+	Cores    int
+	Features []string // SIMD features llama.cpp can use (avx2, neon, ...)
+}
+
+// MemoryInfo describes system memory in bytes.
+type MemoryInfo struct {
+	Total     int64
+	Available int64
+	Unified   bool // GPU shares system memory (Apple Silicon)
+}
+
+// GPU describes a single graphics device.
+type GPU struct {
+	Vendor    string // "apple", "nvidia", or "amd"
+	Name      string
+	VRAMTotal int64 // Dedicated memory in bytes; 0 for unified memory
+	VRAMFree  int64
+	Cores     int  // GPU cores, when reported (Apple Silicon)
+	Unified   bool // Shares system memory rather than having its own
+}
+
+// Detect gathers everything that can be determined about the host.
+// Missing tools or unsupported platforms leave the corresponding fields empty.
+func Detect() *Info {
+	info := &Info{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+		CPU:  DetectCPU(),
+		GPUs: DetectGPUs(),
+	}
+
+	info.Memory.Total, _ = TotalMemory()
+	info.Memory.Available, _ = AvailableMemory()
+	for _, gpu := range info.GPUs {
+		if gpu.Unified {
+			info.Memory.Unified = true
+		}
+	}
+	return info
+}
+
+// DedicatedVRAMFree returns the free memory across GPUs with their own VRAM.
+func (i *Info) DedicatedVRAMFree() int64 {
+	var total int64
+	for _, gpu := range i.GPUs {
+		if !gpu.Unified {
+			total += gpu.VRAMFree
+		}
+	}
+	return total
+}
+
+// IsAppleSilicon reports whether the host is an Apple Silicon Mac, including
+// when this binary is an x86_64 build running under Rosetta.
+func IsAppleSilicon() bool {
+	if runtime.GOOS != "darwin" {
+		return false
+	}
+	if runtime.GOARCH == "arm64" {
+		return true
+	}
+	out, err := runCommand("sysctl", "-n", "sysctl.proc_translated")
+	return err == nil && strings.TrimSpace(string(out)) == "1"
+}
+
+// runCommand runs an external probe, giving up after commandTimeout.
+var runCommand = func(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	return exec.CommandContext(ctx, name, args...).Output()
+}
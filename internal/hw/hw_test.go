@@ -0,0 +1,303 @@
+package hw
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	meminfo := `MemTotal:       32768000 kB
+MemFree:         1024000 kB
+MemAvailable:   16384000 kB
+`
+	tests := []struct {
+		field   string
+		want    int64
+		wantErr bool
+	}{
+		{"MemTotal", 32768000 * 1024, false},
+		{"MemAvailable", 16384000 * 1024, false},
+		{"SwapTotal", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := parseMeminfo(strings.NewReader(meminfo), tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMeminfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMeminfo() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVMStat(t *testing.T) {
+	output := `Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                               10000.
+Pages active:                            500000.
+Pages inactive:                           20000.
+Pages speculative:                         3000.
+Pages throttled:                              0.
+Pages wired down:                        100000.
+Pages purgeable:                           1000.
+`
+	got, err := parseVMStat(output)
+	if err != nil {
+		t.Fatalf("parseVMStat() error = %v", err)
+	}
+	if want := int64(34000 * 16384); got != want {
+		t.Errorf("parseVMStat() = %d, want %d", got, want)
+	}
+
+	if _, err := parseVMStat("garbage"); err == nil {
+		t.Error("parseVMStat() should fail without page size")
+	}
+}
+
+func TestParseNvidiaSMI(t *testing.T) {
+	output := `NVIDIA GeForce RTX 4090, 24564, 23012
+NVIDIA RTX A4000, 16376, 1024
+garbage line
+`
+	got := parseNvidiaSMI(output)
+	want := []GPU{
+		{Vendor: "nvidia", Name: "NVIDIA GeForce RTX 4090", VRAMTotal: 24564 << 20, VRAMFree: 23012 << 20},
+		{Vendor: "nvidia", Name: "NVIDIA RTX A4000", VRAMTotal: 16376 << 20, VRAMFree: 1024 << 20},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseNvidiaSMI() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSystemProfiler(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []GPU
+	}{
+		{
+			name: "apple silicon",
+			output: `{"SPDisplaysDataType":[{"sppci_model":"Apple M2 Max","sppci_cores":"38",
+				"spdisplays_vendor":"sppci_vendor_Apple"}]}`,
+			want: []GPU{{Vendor: "apple", Name: "Apple M2 Max", Cores: 38, Unified: true}},
+		},
+		{
+			name: "intel mac with discrete amd",
+			output: `{"SPDisplaysDataType":[{"sppci_model":"AMD Radeon Pro 5500M",
+				"spdisplays_vendor":"sppci_vendor_amd","spdisplays_vram":"8 GB"}]}`,
+			want: []GPU{{Vendor: "amd", Name: "AMD Radeon Pro 5500M", VRAMTotal: 8 << 30}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSystemProfiler([]byte(tt.output))
+			if err != nil {
+				t.Fatalf("parseSystemProfiler() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parseSystemProfiler() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseSystemProfiler([]byte("not json")); err == nil {
+		t.Error("parseSystemProfiler() should fail on invalid JSON")
+	}
+}
+
+func TestDetectAMDGPUs(t *testing.T) {
+	origPath := drmPath
+	defer func() { drmPath = origPath }()
+	drmPath = t.TempDir()
+
+	writeCard := func(card string, files map[string]string) {
+		dir := filepath.Join(drmPath, card, "device")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeCard("card0", map[string]string{
+		"vendor":              "0x1002",
+		"product_name":        "Radeon RX 7900 XTX",
+		"mem_info_vram_total": "25753026560",
+		"mem_info_vram_used":  "1073741824",
+	})
+	writeCard("card1", map[string]string{"vendor": "0x8086"})
+
+	got := detectAMDGPUs()
+	want := []GPU{{
+		Vendor:    "amd",
+		Name:      "Radeon RX 7900 XTX",
+		VRAMTotal: 25753026560,
+		VRAMFree:  25753026560 - 1073741824,
+	}}
+	if !slices.Equal(got, want) {
+		t.Errorf("detectAMDGPUs() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseCPUInfo(t *testing.T) {
+	tests := []struct {
+		name         string
+		cpuinfo      string
+		wantModel    string
+		wantFeatures []string
+	}{
+		{
+			name: "x86",
+			cpuinfo: `processor	: 0
+model name	: AMD Ryzen 9 7950X 16-Core Processor
+flags		: fpu sse sse2 avx avx2 fma f16c avx512f
+processor	: 1
+model name	: AMD Ryzen 9 7950X 16-Core Processor
+flags		: fpu sse sse2 avx avx2 fma f16c avx512f
+`,
+			wantModel:    "AMD Ryzen 9 7950X 16-Core Processor",
+			wantFeatures: []string{"avx", "avx2", "avx512f", "fma", "f16c"},
+		},
+		{
+			name: "arm64",
+			cpuinfo: `processor	: 0
+Features	: fp asimd evtstrm aes crc32 asimddp sve i8mm
+`,
+			wantFeatures: []string{"neon", "sve", "dotprod", "i8mm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, features := parseCPUInfo(strings.NewReader(tt.cpuinfo))
+			if model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			if !slices.Equal(features, tt.wantFeatures) {
+				t.Errorf("features = %v, want %v", features, tt.wantFeatures)
+			}
+		})
+	}
+}
+
+func TestDedicatedVRAMFree(t *testing.T) {
+	info := &Info{GPUs: []GPU{
+		{Vendor: "apple", Unified: true, VRAMFree: 1 << 30},
+		{Vendor: "nvidia", VRAMFree: 8 << 30},
+		{Vendor: "amd", VRAMFree: 4 << 30},
+	}}
+	if got, want := info.DedicatedVRAMFree(), int64(12<<30); got != want {
+		t.Errorf("DedicatedVRAMFree() = %d, want %d", got, want)
+	}
+}
+
+func TestParseProcStat(t *testing.T) {
+	// The command name may hold spaces and parentheses
+	stat := "4242 (llama server (x)) S 1 4242 4242 0 -1 4194560 9000 0 12 0 1250 375 0 0 20 0 9 0 100 1000000 2000 18446744073709551615"
+	got, err := parseProcStat(stat)
+	if err != nil {
+		t.Fatalf("parseProcStat() error = %v", err)
+	}
+	if want := 16250 * time.Millisecond; got != want {
+		t.Errorf("parseProcStat() = %v, want %v", got, want)
+	}
+
+	if _, err := parseProcStat("4242 (short) S 1"); err == nil {
+		t.Error("parseProcStat() should fail on a truncated line")
+	}
+}
+
+func TestParsePSTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"0:01.50", 1500 * time.Millisecond, false},
+		{"12:34.00", 12*time.Minute + 34*time.Second, false},
+		{"1:02:03.00", time.Hour + 2*time.Minute + 3*time.Second, false},
+		{"2-01:00:00", 49 * time.Hour, false},
+		{"", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parsePSTime(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePSTime(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePSTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseNvidiaPmon(t *testing.T) {
+	output := `# gpu        pid  type    sm   mem   enc   dec   command
+# Idx          #   C/G     %     %     %     %   name
+    0       4242     C    45    20     -     -   llama-server
+    1       4242     C    30    10     -     -   llama-server
+    0       5151     C     -     -     -     -   llama-server
+    0          -     -     -     -     -     -   -
+`
+	got := parseNvidiaPmon(output)
+	if len(got) != 2 || got[4242] != 75 || got[5151] != 0 {
+		t.Errorf("parseNvidiaPmon() = %v", got)
+	}
+}
+
+func TestParsePmset(t *testing.T) {
+	if !parsePmsetBattery("Now drawing from 'Battery Power'\n -InternalBattery-0 (id=1234)\t82%; discharging;\n") {
+		t.Error("battery power not detected")
+	}
+	if parsePmsetBattery("Now drawing from 'AC Power'\n -InternalBattery-0 (id=1234)\t100%; charged;\n") {
+		t.Error("AC power read as battery")
+	}
+
+	settings := "System-wide power settings:\nCurrently in use:\n standby              1\n lowpowermode         %s\n sleep                1\n"
+	if !parsePmsetLowPower(strings.ReplaceAll(settings, "%s", "1")) {
+		t.Error("lowpowermode 1 not detected")
+	}
+	if parsePmsetLowPower(strings.ReplaceAll(settings, "%s", "0")) {
+		t.Error("lowpowermode 0 read as on")
+	}
+	if !parsePmsetLowPower(" powermode            1\n") || parsePmsetLowPower(" powermode            2\n") {
+		t.Error("powermode not read as 1 = low")
+	}
+
+	tests := []struct {
+		name   string
+		output string
+		want   bool
+	}{
+		{"nothing recorded", "Note: No thermal warning level has been recorded\nNote: No performance warning level has been recorded\nNote: No CPU power status has been recorded\n", false},
+		{"full speed", "CPU_Scheduler_Limit \t= 100\nCPU_Available_CPUs \t= 8\nCPU_Speed_Limit \t= 100\n", false},
+		{"throttled", "CPU_Scheduler_Limit \t= 100\nCPU_Available_CPUs \t= 8\nCPU_Speed_Limit \t= 63\n", true},
+		{"warning", "Thermal warning level set to 2.\n", true},
+		{"warning cleared", "Thermal warning level set to 0.\n", false},
+	}
+	for _, tt := range tests {
+		if got := parsePmsetTherm(tt.output); got != tt.want {
+			t.Errorf("parsePmsetTherm(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPowerStateReason(t *testing.T) {
+	if (PowerState{}).Constrained() || (PowerState{}).Reason() != "" {
+		t.Error("zero state should not be constrained")
+	}
+	p := PowerState{OnBattery: true, ThermalPressure: true}
+	if !p.Constrained() || p.Reason() != "thermal pressure, on battery" {
+		t.Errorf("Reason() = %q", p.Reason())
+	}
+}
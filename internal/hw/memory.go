@@ -0,0 +1,96 @@
+package hw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AvailableMemory returns system memory that can be allocated without swapping.
+func AvailableMemory() (int64, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readMeminfo("MemAvailable")
+	case "darwin":
+		output, err := runCommand("vm_stat")
+		if err != nil {
+			return 0, fmt.Errorf("failed to run vm_stat: %w", err)
+		}
+		return parseVMStat(string(output))
+	default:
+		return 0, fmt.Errorf("memory detection not supported on %s", runtime.GOOS)
+	}
+}
+
+// TotalMemory returns the total physical memory.
+func TotalMemory() (int64, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readMeminfo("MemTotal")
+	case "darwin":
+		output, err := runCommand("sysctl", "-n", "hw.memsize")
+		if err != nil {
+			return 0, fmt.Errorf("failed to run sysctl: %w", err)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse hw.memsize: %w", err)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("memory detection not supported on %s", runtime.GOOS)
+	}
+}
+
+func readMeminfo(field string) (int64, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read meminfo: %w", err)
+	}
+	defer f.Close()
+	return parseMeminfo(f, field)
+}
+
+// parseMeminfo returns a field from /proc/meminfo in bytes.
+func parseMeminfo(r io.Reader, field string) (int64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == field+":" {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse %s: %w", field, err)
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("%s not found in /proc/meminfo", field)
+}
+
+var (
+	vmStatPageSize = regexp.MustCompile(`page size of (\d+) bytes`)
+	vmStatPages    = regexp.MustCompile(`^Pages (free|inactive|speculative|purgeable):\s+(\d+)\.`)
+)
+
+// parseVMStat sums reclaimable pages from macOS vm_stat output.
+func parseVMStat(output string) (int64, error) {
+	match := vmStatPageSize.FindStringSubmatch(output)
+	if match == nil {
+		return 0, fmt.Errorf("page size not found in vm_stat output")
+	}
+	pageSize, _ := strconv.ParseInt(match[1], 10, 64)
+
+	var pages int64
+	for line := range strings.SplitSeq(output, "\n") {
+		if m := vmStatPages.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.ParseInt(m[2], 10, 64)
+			pages += n
+		}
+	}
+	return pages * pageSize, nil
+}
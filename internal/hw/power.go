@@ -0,0 +1,96 @@
+package hw
+
+import (
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// PowerState describes whether the host should go easy on power. Only macOS
+// reports it; elsewhere every field is false.
+type PowerState struct {
+	OnBattery       bool // Running from the battery rather than AC power
+	LowPowerMode    bool // Low Power Mode is on
+	ThermalPressure bool // The CPU is being throttled, or a thermal warning is active
+}
+
+// Constrained reports whether any condition calls for saving power.
+func (p PowerState) Constrained() bool {
+	return p.OnBattery || p.LowPowerMode || p.ThermalPressure
+}
+
+// Reason describes the conditions that are active, most pressing first, or
+// "" when none are.
+func (p PowerState) Reason() string {
+	var reasons []string
+	if p.ThermalPressure {
+		reasons = append(reasons, "thermal pressure")
+	}
+	if p.LowPowerMode {
+		reasons = append(reasons, "low power mode")
+	}
+	if p.OnBattery {
+		reasons = append(reasons, "on battery")
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// DetectPower reads the power source, Low Power Mode and thermal state from
+// pmset on macOS. Probes that fail leave their fields false.
+func DetectPower() PowerState {
+	var p PowerState
+	if runtime.GOOS != "darwin" {
+		return p
+	}
+	if out, err := runCommand("pmset", "-g", "batt"); err == nil {
+		p.OnBattery = parsePmsetBattery(string(out))
+	}
+	if out, err := runCommand("pmset", "-g"); err == nil {
+		p.LowPowerMode = parsePmsetLowPower(string(out))
+	}
+	if out, err := runCommand("pmset", "-g", "therm"); err == nil {
+		p.ThermalPressure = parsePmsetTherm(string(out))
+	}
+	return p
+}
+
+// parsePmsetBattery reads the power source from `pmset -g batt`, whose first
+// line is "Now drawing from 'Battery Power'" or "... 'AC Power'".
+func parsePmsetBattery(output string) bool {
+	first, _, _ := strings.Cut(output, "\n")
+	return strings.Contains(first, "'Battery Power'")
+}
+
+// parsePmsetLowPower reads Low Power Mode from `pmset -g` settings, listed
+// as lowpowermode, or as powermode (1 = low) on newer releases.
+func parsePmsetLowPower(output string) bool {
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && (fields[0] == "lowpowermode" || fields[0] == "powermode") {
+			return fields[1] == "1"
+		}
+	}
+	return false
+}
+
+var (
+	cpuSpeedLimitRe = regexp.MustCompile(`CPU_Speed_Limit\s*=\s*(\d+)`)
+	warningLevelRe  = regexp.MustCompile(`(?i)(thermal|performance) warning level set to (\d+)`)
+)
+
+// parsePmsetTherm reads `pmset -g therm`: a CPU speed limit under 100% or a
+// nonzero thermal or performance warning level means the Mac is too hot.
+func parsePmsetTherm(output string) bool {
+	if m := cpuSpeedLimitRe.FindStringSubmatch(output); m != nil {
+		if limit, err := strconv.Atoi(m[1]); err == nil && limit < 100 {
+			return true
+		}
+	}
+	for _, m := range warningLevelRe.FindAllStringSubmatch(output, -1) {
+		if level, err := strconv.Atoi(m[2]); err == nil && level > 0 {
+			return true
+		}
+	}
+	return false
+}
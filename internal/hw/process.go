@@ -0,0 +1,113 @@
+package hw
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is the unit of CPU times in /proc/<pid>/stat (USER_HZ), which
+// is 100 on every Linux architecture llama.cpp runs on
+const clockTicks = 100
+
+// ProcessCPUTime returns the CPU time, user and system, a process has used
+// since it started.
+func ProcessCPUTime(pid int) (time.Duration, error) {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			return 0, err
+		}
+		return parseProcStat(string(data))
+	case "darwin":
+		output, err := runCommand("ps", "-o", "time=", "-p", strconv.Itoa(pid))
+		if err != nil {
+			return 0, fmt.Errorf("failed to run ps: %w", err)
+		}
+		return parsePSTime(strings.TrimSpace(string(output)))
+	default:
+		return 0, fmt.Errorf("process CPU time not supported on %s", runtime.GOOS)
+	}
+}
+
+// parseProcStat reads utime and stime from /proc/<pid>/stat. The command name
+// in parentheses may contain spaces, so fields are counted after its end.
+func parseProcStat(stat string) (time.Duration, error) {
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, fmt.Errorf("malformed stat")
+	}
+	// Fields from state (3rd) on; utime and stime are the 14th and 15th
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 13 {
+		return 0, fmt.Errorf("malformed stat")
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("malformed stat")
+	}
+	return time.Duration(utime+stime) * time.Second / clockTicks, nil
+}
+
+// parsePSTime parses the [[dd-]hh:]mm:ss.ss CPU time ps prints.
+func parsePSTime(s string) (time.Duration, error) {
+	var days int64
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.ParseInt(d, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", s)
+		}
+		days, s = n, rest
+	}
+
+	var seconds float64
+	for part := range strings.SplitSeq(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", s)
+		}
+		seconds = seconds*60 + n
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(seconds*float64(time.Second)), nil
+}
+
+// GPUProcessUtilization returns the GPU utilization (SM %) of each process
+// using an NVIDIA GPU, summed across GPUs. It returns nil where per-process
+// utilization can't be read, which is everywhere nvidia-smi isn't available.
+func GPUProcessUtilization() map[int]float64 {
+	if runtime.GOOS != "linux" && runtime.GOOS != "windows" {
+		return nil
+	}
+	output, err := runCommand("nvidia-smi", "pmon", "-c", "1", "-s", "u")
+	if err != nil {
+		return nil
+	}
+	return parseNvidiaPmon(string(output))
+}
+
+// parseNvidiaPmon parses `nvidia-smi pmon -s u` output: comment lines, then
+// gpu, pid, type, sm%, mem%, ... per process, with "-" for no sample.
+func parseNvidiaPmon(output string) map[int]float64 {
+	util := make(map[int]float64)
+	for line := range strings.SplitSeq(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		sm, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			sm = 0 // "-": in use, but idle during the sample
+		}
+		util[pid] += sm
+	}
+	return util
+}
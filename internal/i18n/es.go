@@ -0,0 +1,167 @@
+package i18n
+
+// es is the Spanish catalog
+var es = map[string]string{
+	// Prompts
+	"[y/N]":                         "[s/N]",
+	"[Y/n]":                         "[S/n]",
+	"y":                             "s",
+	"yes":                           "sí",
+	"Cancelled":                     "Cancelado",
+	"Remove %s (%s)?":               "¿Eliminar %s (%s)?",
+	"Remove %d model(s), %s total?": "¿Eliminar %d modelo(s), %s en total?",
+	"Remove persona '%s'?":          "¿Eliminar la persona '%s'?",
+	"Remove prompt '%s'?":           "¿Eliminar el prompt '%s'?",
+	"Delete conversation '%s'?":     "¿Eliminar la conversación '%s'?",
+	"Update %s?":                    "¿Actualizar %s?",
+	"Update to %s?":                 "¿Actualizar a %s?",
+	"Install %s?":                   "¿Instalar %s?",
+	"Reinstall %s?":                 "¿Reinstalar %s?",
+	"Restart the server?":           "¿Reiniciar el servidor?",
+	"Restart the server? Loaded models will be loaded again.": "¿Reiniciar el servidor? Los modelos cargados se volverán a cargar.",
+
+	// Errors
+	"Error:":                                                 "Error:",
+	"Failed to load config: %v":                              "No se pudo cargar la configuración: %v",
+	"Failed to convert config: %v":                           "No se pudo convertir la configuración: %v",
+	"Failed to update peer index: %v":                        "No se pudo actualizar el índice de pares: %v",
+	"Failed to unload model: %v":                             "No se pudo descargar el modelo de memoria: %v",
+	"Failed to unload models: %v":                            "No se pudieron descargar los modelos de memoria: %v",
+	"Failed to save note: %v":                                "No se pudo guardar la nota: %v",
+	"Failed to load metadata: %v":                            "No se pudieron cargar los metadatos: %v",
+	"Failed to create backup: %v":                            "No se pudo crear la copia de seguridad: %v",
+	"Failed to stop server: %v":                              "No se pudo detener el servidor: %v",
+	"Failed to remove %s: %v":                                "No se pudo eliminar %s: %v",
+	"Persona '%s' not found":                                 "No se encontró la persona '%s'",
+	"Persona '%s' already exists. Use --force to overwrite.": "La persona '%s' ya existe. Usa --force para sobrescribirla.",
+	"Model '%s' is not loaded":                               "El modelo '%s' no está cargado",
+	"Quantization '%s' not found":                            "No se encontró la cuantización '%s'",
+	"No GGUF files found":                                    "No se encontraron archivos GGUF",
+	"Authentication required":                                "Se requiere autenticación",
+	"Server already running on http://%s:%d (PID %d)":        "El servidor ya está en ejecución en http://%s:%d (PID %d)",
+	"Proxy health check failed: %v":                          "Falló la comprobación de estado del proxy: %v",
+	"Input history unavailable: %v":                          "Historial de entrada no disponible: %v",
+	"TUI error: %v":                                          "Error de la interfaz: %v",
+	"The running server is lleme %s, which this lleme %s can't work with reliably.": "El servidor en ejecución es lleme %s, con el que este lleme %s no puede funcionar de forma fiable.",
+	"an older version": "una versión anterior",
+	"lleme was upgraded to %s, but the server is still running %s.": "lleme se actualizó a %s, pero el servidor sigue ejecutando %s.",
+	"Restart it with 'lleme server restart'.":                       "Reinícialo con 'lleme server restart'.",
+
+	// Chat
+	"Show help":          "Mostrar la ayuda",
+	"Clear conversation": "Borrar la conversación",
+	"Show/set system prompt (@name for a saved one)":            "Ver o cambiar el prompt de sistema (@nombre para uno guardado)",
+	"Save the conversation under a title":                       "Guardar la conversación con un título",
+	"Change a setting":                                          "Cambiar un ajuste",
+	"Show current settings":                                     "Mostrar los ajustes actuales",
+	"Reload model":                                              "Recargar el modelo",
+	"Append responses to a file":                                "Añadir las respuestas a un archivo",
+	"Exit chat":                                                 "Salir del chat",
+	"Attach an image to the next message":                       "Adjuntar una imagen al siguiente mensaje",
+	"No images attached\nUsage: /image <file> or /image clear":  "No hay imágenes adjuntas\nUso: /image <archivo> o /image clear",
+	"Attached to your next message: %s":                         "Adjuntas a tu siguiente mensaje: %s",
+	"Removed attached images":                                   "Se quitaron las imágenes adjuntas",
+	"Attached %s, sent with your next message":                  "Adjuntada %s, se enviará con tu siguiente mensaje",
+	"Remove the last exchange":                                  "Quitar el último intercambio",
+	"Nothing to undo":                                           "No hay nada que deshacer",
+	"Continue a cancelled reply":                                "Continuar una respuesta cancelada",
+	"No cancelled reply to continue":                            "No hay ninguna respuesta cancelada que continuar",
+	"Nothing to save yet":                                       "Todavía no hay nada que guardar",
+	"Failed to save conversation: %v":                           "No se pudo guardar la conversación: %v",
+	"Saved \"%s\" (%s)":                                         "Guardada «%s» (%s)",
+	"Removed the last exchange":                                 "Se quitó el último intercambio",
+	"Show key bindings":                                         "Mostrar los atajos de teclado",
+	"Key bindings (%s):":                                        "Atajos de teclado (%s):",
+	"(unbound)":                                                 "(sin asignar)",
+	"Change them with chat.keymap and chat.keys in config.yaml": "Cámbialos con chat.keymap y chat.keys en config.yaml",
+	"Temperature (0.0-2.0)":                                     "Temperatura (0.0-2.0)",
+	"Top-P sampling (0.0-1.0)":                                  "Muestreo Top-P (0.0-1.0)",
+	"Top-K sampling (integer)":                                  "Muestreo Top-K (entero)",
+	"Min-P sampling (0.0-1.0)":                                  "Muestreo Min-P (0.0-1.0)",
+	"Repeat penalty (0.0-2.0)":                                  "Penalización por repetición (0.0-2.0)",
+	"Context size (requires /reload)":                           "Tamaño de contexto (requiere /reload)",
+	"GPU layers (requires /reload)":                             "Capas en GPU (requiere /reload)",
+	"CPU threads (requires /reload)":                            "Hilos de CPU (requiere /reload)",
+	"Commands:":                                                 "Comandos:",
+	"Options for /set:":                                         "Opciones de /set:",
+	"(* require /reload)":                                       "(* requieren /reload)",
+	"Other llama-server load options, like cache-type-k* or rope-scaling*": "Otras opciones de carga de llama-server, como cache-type-k* o rope-scaling*",
+	"Goodbye!":             "¡Hasta luego!",
+	"Conversation cleared": "Conversación borrada",
+	"System prompt:":       "Prompt de sistema:",
+	"No system prompt set": "No hay prompt de sistema",
+	"System prompt updated, conversation cleared": "Prompt de sistema actualizado, conversación borrada",
+	"Usage: /set <option> <value>\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads, or another llama-server load option": "Uso: /set <opción> <valor>\nOpciones: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads u otra opción de carga de llama-server",
+	"Unknown command: %s (type /? for help)":     "Comando desconocido: %s (escribe /? para ver la ayuda)",
+	"Invalid value for temp: %s":                 "Valor no válido para temp: %s",
+	"Invalid value for top-p: %s":                "Valor no válido para top-p: %s",
+	"Invalid value for top-k: %s":                "Valor no válido para top-k: %s",
+	"Invalid value for repeat-penalty: %s":       "Valor no válido para repeat-penalty: %s",
+	"Invalid value for min-p: %s":                "Valor no válido para min-p: %s",
+	"Invalid value for ctx-size: %s":             "Valor no válido para ctx-size: %s",
+	"Invalid value for gpu-layers: %s":           "Valor no válido para gpu-layers: %s",
+	"Invalid value for threads: %s":              "Valor no válido para threads: %s",
+	"Set ctx-size = %d (use /reload to apply)":   "ctx-size = %d (usa /reload para aplicarlo)",
+	"Set gpu-layers = %d (use /reload to apply)": "gpu-layers = %d (usa /reload para aplicarlo)",
+	"Set threads = %d (use /reload to apply)":    "threads = %d (usa /reload para aplicarlo)",
+	"Set %s = %v (use /reload to apply)":         "%s = %v (usa /reload para aplicarlo)",
+	"%s (requires /reload)":                      "%s (requiere /reload)",
+	"Unknown option: %s\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads, or another llama-server load option": "Opción desconocida: %s\nOpciones: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads u otra opción de carga de llama-server",
+	"No pending server option changes to apply": "No hay cambios pendientes en las opciones del servidor",
+	"Failed to stop model: %v":                  "No se pudo detener el modelo: %v",
+	"Failed to reload model: %v":                "No se pudo recargar el modelo: %v",
+	"Model reloaded":                            "Modelo recargado",
+	"Current Settings":                          "Ajustes actuales",
+	"  Model: %s\n\n":                           "  Modelo: %s\n\n",
+	"  System: %s\n\n":                          "  Sistema: %s\n\n",
+	"  Sampling:\n":                             "  Muestreo:\n",
+	"  Server:\n":                               "  Servidor:\n",
+	"  Session:\n":                              "  Sesión:\n",
+	"    tokens = %d prompt + %d completion (%d total)\n":   "    tokens = %d de entrada + %d de salida (%d en total)\n",
+	"    API cost = %s (at $%g / $%g per million tokens)\n": "    coste en API = %s (a $%g / $%g por millón de tokens)\n",
+	"%d tokens":                        "%d tokens",
+	"%d prompt + %d completion tokens": "%d tokens de entrada + %d de salida",
+	"    %s = %s (session)\n":          "    %s = %s (sesión)\n",
+	"    %s = %s (config)\n":           "    %s = %s (configuración)\n",
+	"    %s = %s (persona)\n":          "    %s = %s (persona)\n",
+	"    %s = %s (model options)\n":    "    %s = %s (opciones del modelo)\n",
+	"    %s = default\n":               "    %s = predeterminado\n",
+	"Not writing responses to a file":  "No se están guardando las respuestas en un archivo",
+	"Not writing responses to a file\nUsage: /tee <file> or /tee off": "No se están guardando las respuestas en un archivo\nUso: /tee <archivo> o /tee off",
+	"Appending responses to %s":                                       "Añadiendo las respuestas a %s",
+	"Stopped writing to %s":                                           "Se dejó de escribir en %s",
+	"Failed to close %s: %v":                                          "No se pudo cerrar %s: %v",
+	"Chatting with %s. Type /? for help or /bye to exit.":             "Chateando con %s. Escribe /? para ver la ayuda o /bye para salir.",
+	"Did you mean:":                                                   "¿Quisiste decir?",
+	"Thinking:":                                                       "Pensando:",
+	"Initializing...":                                                 "Iniciando...",
+	"Type a message...":                                               "Escribe un mensaje...",
+	"Streaming":                                                       "Generando",
+	"Error occurred":                                                  "Se produjo un error",
+	"send":                                                            "enviar",
+	"commands":                                                        "comandos",
+	"clear":                                                           "borrar",
+	"quit":                                                            "salir",
+	"cancel":                                                          "cancelar",
+	"help":                                                            "ayuda",
+	"scroll":                                                          "desplazar",
+	"retry":                                                           "reintentar",
+	"previous input":                                                  "entrada anterior",
+	"next input":                                                      "entrada siguiente",
+	"search history":                                                  "buscar en el historial",
+	"history search: %s":                                              "búsqueda en el historial: %s",
+	"new line":                                                        "nueva línea",
+	"switch pane":                                                     "cambiar de panel",
+	"back to input":                                                   "volver a la entrada",
+	"to messages":                                                     "ir a los mensajes",
+	"insert":                                                          "insertar",
+	"normal mode":                                                     "modo normal",
+	"scroll up":                                                       "subir",
+	"scroll down":                                                     "bajar",
+	"page up":                                                         "página arriba",
+	"page down":                                                       "página abajo",
+	"half page up":                                                    "media página arriba",
+	"half page down":                                                  "media página abajo",
+	"top":                                                             "inicio",
+	"bottom":                                                          "final",
+}
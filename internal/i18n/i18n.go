@@ -0,0 +1,106 @@
+// Package i18n translates user-facing strings.
+//
+// Messages are looked up by their English text, gettext style, so code reads
+// the same as untranslated code and a missing translation falls back to
+// English. The language comes from LC_ALL, LC_MESSAGES or LANG, in that
+// order, as with other Unix tools.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// catalogs maps a language code to translations keyed by English text
+var catalogs = map[string]map[string]string{
+	"es": es,
+}
+
+var (
+	mu       sync.RWMutex
+	lang     string
+	detected sync.Once
+)
+
+// T returns msg translated into the current language
+func T(msg string) string {
+	if s, ok := catalogs[current()][msg]; ok {
+		return s
+	}
+	return msg
+}
+
+// Tf translates format and then formats it like fmt.Sprintf
+func Tf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// SetLanguage overrides the detected language. An unsupported language
+// means English.
+func SetLanguage(l string) {
+	detected.Do(func() {})
+	mu.Lock()
+	defer mu.Unlock()
+	lang = l
+}
+
+// Language returns the language in use
+func Language() string {
+	l := current()
+	if _, ok := catalogs[l]; !ok {
+		return "en"
+	}
+	return l
+}
+
+// current returns the language set by SetLanguage or else detected from the
+// environment on first use
+func current() string {
+	detected.Do(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if lang == "" {
+			lang = Detect()
+		}
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	return lang
+}
+
+// Languages returns the supported language codes, English included
+func Languages() []string {
+	langs := []string{"en"}
+	for l := range catalogs {
+		langs = append(langs, l)
+	}
+	slices.Sort(langs[1:])
+	return langs
+}
+
+// Detect returns the language requested by the locale environment, such as
+// "es" for LANG=es_ES.UTF-8
+func Detect() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(key); v != "" {
+			return parseLocale(v)
+		}
+	}
+	return "en"
+}
+
+// parseLocale reduces a POSIX locale like "pt_BR.UTF-8@euro" to its language
+func parseLocale(locale string) string {
+	l, _, _ := strings.Cut(locale, ".")
+	l, _, _ = strings.Cut(l, "@")
+	l, _, _ = strings.Cut(l, "_")
+	l, _, _ = strings.Cut(l, "-")
+	l = strings.ToLower(l)
+	if l == "" || l == "c" || l == "posix" {
+		return "en"
+	}
+	return l
+}
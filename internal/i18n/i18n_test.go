@@ -0,0 +1,107 @@
+package i18n
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"es_ES.UTF-8", "es"},
+		{"es", "es"},
+		{"pt_BR.UTF-8@euro", "pt"},
+		{"de-DE", "de"},
+		{"FR_fr", "fr"},
+		{"C", "en"},
+		{"C.UTF-8", "en"},
+		{"POSIX", "en"},
+		{"", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			if got := parseLocale(tt.locale); got != tt.want {
+				t.Errorf("parseLocale(%q) = %q, want %q", tt.locale, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name                     string
+		lcAll, lcMessages, langV string
+		want                     string
+	}{
+		{"LANG", "", "", "es_ES.UTF-8", "es"},
+		{"LC_MESSAGES beats LANG", "", "en_US.UTF-8", "es_ES.UTF-8", "en"},
+		{"LC_ALL beats everything", "es_MX", "en_US", "en_US", "es"},
+		{"unset", "", "", "", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tt.lcAll)
+			t.Setenv("LC_MESSAGES", tt.lcMessages)
+			t.Setenv("LANG", tt.langV)
+			if got := Detect(); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	t.Cleanup(func() { SetLanguage("en") })
+
+	SetLanguage("es")
+	if got := T("Goodbye!"); got != "¡Hasta luego!" {
+		t.Errorf("T() = %q, want the Spanish translation", got)
+	}
+	if got := Tf("Unknown command: %s (type /? for help)", "/foo"); got != "Comando desconocido: /foo (escribe /? para ver la ayuda)" {
+		t.Errorf("Tf() = %q", got)
+	}
+	if got := T("Not in any catalog"); got != "Not in any catalog" {
+		t.Errorf("T() of an untranslated message = %q, want it unchanged", got)
+	}
+	if got := Language(); got != "es" {
+		t.Errorf("Language() = %q, want es", got)
+	}
+
+	SetLanguage("xx")
+	if got := T("Goodbye!"); got != "Goodbye!" {
+		t.Errorf("T() in an unsupported language = %q, want English", got)
+	}
+	if got := Language(); got != "en" {
+		t.Errorf("Language() = %q, want en for an unsupported language", got)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	got := Languages()
+	if got[0] != "en" || !slices.Contains(got, "es") {
+		t.Errorf("Languages() = %v, want en first and es included", got)
+	}
+}
+
+// Translations must take the same arguments in the same order, or Tf
+// produces %!v(MISSING) and friends
+func TestCatalogVerbs(t *testing.T) {
+	verb := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	for lang, catalog := range catalogs {
+		for msg, translated := range catalog {
+			if translated == "" {
+				t.Errorf("%s: empty translation for %q", lang, msg)
+			}
+			want := verb.FindAllString(msg, -1)
+			got := verb.FindAllString(translated, -1)
+			if !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, want %v to match %q", lang, translated, got, want, msg)
+			}
+		}
+	}
+}
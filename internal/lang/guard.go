@@ -0,0 +1,110 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Guard thresholds: a reply is judged after this many sentences, or after
+// this many bytes if it has no sentence breaks
+const (
+	guardSentences = 2
+	guardMaxBytes  = 600
+)
+
+// Guard holds back the start of a streamed reply until it can tell whether
+// the reply is in the wanted language, so a reply in the wrong one can be
+// dropped before anyone reads it. Fenced code is ignored when judging.
+type Guard struct {
+	want    string
+	held    strings.Builder
+	decided bool
+}
+
+// NewGuard returns a guard for replies in the language with the code, or
+// nil, which passes everything through, when the code is empty or unknown
+func NewGuard(code string) *Guard {
+	if code == "" || !Known(code) {
+		return nil
+	}
+	return &Guard{want: code}
+}
+
+// Write takes the next piece of the reply and returns what can be shown so
+// far. It returns false if the reply has turned out to be in another
+// language, in which case nothing held back should be shown.
+func (g *Guard) Write(s string) (string, bool) {
+	if g == nil || g.decided {
+		return s, true
+	}
+	g.held.WriteString(s)
+	text := g.held.String()
+	sample := stripCode(text)
+	if sentences(sample) < guardSentences && len(text) < guardMaxBytes {
+		return "", true
+	}
+
+	g.decided = true
+	g.held.Reset()
+	if !Matches(sample, g.want) {
+		return "", false
+	}
+	return text, true
+}
+
+// Flush returns whatever is still held back, for a reply that ended before
+// it could be judged, and false if that is in another language
+func (g *Guard) Flush() (string, bool) {
+	if g == nil || g.decided {
+		return "", true
+	}
+	g.decided = true
+	text := g.held.String()
+	g.held.Reset()
+	return text, Matches(stripCode(text), g.want)
+}
+
+// Instruction returns a system prompt line asking for replies in the
+// language with the code. A value that isn't a known code, such as
+// "Swahili", is used as the language's name.
+func Instruction(code string) string {
+	name := Name(code)
+	if name == "" {
+		name = code
+	}
+	return "Always reply in " + name + ", whatever language the user writes in."
+}
+
+// sentences counts the sentence breaks in s
+func sentences(s string) int {
+	n := 0
+	prev := ' '
+	for _, r := range s {
+		switch {
+		case r == '。' || r == '！' || r == '？':
+			n++
+		case unicode.IsSpace(r) && (prev == '.' || prev == '!' || prev == '?'):
+			n++
+		}
+		prev = r
+	}
+	return n
+}
+
+// stripCode removes fenced code blocks, including one still open at the
+// end, since code reads as English whatever language the prose is in
+func stripCode(s string) string {
+	var b strings.Builder
+	for {
+		before, after, found := strings.Cut(s, "```")
+		b.WriteString(before)
+		if !found {
+			return b.String()
+		}
+		_, rest, closed := strings.Cut(after, "```")
+		if !closed {
+			return b.String()
+		}
+		s = rest
+	}
+}
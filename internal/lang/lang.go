@@ -0,0 +1,243 @@
+// Package lang guesses which natural language a piece of text is written in.
+//
+// It is meant for the first sentences of a model's reply, not for
+// classifying arbitrary documents: languages with their own script are told
+// apart by script, and Latin-script languages by counting common function
+// words. When the text is too short or too mixed to tell, it says so rather
+// than guess.
+package lang
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// script is a writing system, as far as telling languages apart needs
+type script int
+
+const (
+	scriptUnknown script = iota
+	scriptLatin
+	scriptCyrillic
+	scriptGreek
+	scriptArabic
+	scriptHebrew
+	scriptDevanagari
+	scriptThai
+	scriptHan
+	scriptKana
+	scriptHangul
+)
+
+// language describes one language the detector knows
+type language struct {
+	name      string
+	scripts   []script // Scripts its text is written in
+	stopwords string   // Common words that mark it apart from other Latin-script languages
+}
+
+var languages = map[string]language{
+	"en": {name: "English", scripts: []script{scriptLatin},
+		stopwords: "the and is are of to in that it with for this was you not be have on as they"},
+	"de": {name: "German", scripts: []script{scriptLatin},
+		stopwords: "der die das und ist nicht ein eine mit sich auf für den dem des zu von sie es ich wir auch"},
+	"es": {name: "Spanish", scripts: []script{scriptLatin},
+		stopwords: "el la los las y es en que un una por con para del se no lo como pero su al"},
+	"fr": {name: "French", scripts: []script{scriptLatin},
+		stopwords: "le la les et est un une des du que qui dans pour pas sur avec ce il vous nous au"},
+	"it": {name: "Italian", scripts: []script{scriptLatin},
+		stopwords: "il lo la gli le e è un una di che per non con del della sono si ma anche"},
+	"pt": {name: "Portuguese", scripts: []script{scriptLatin},
+		stopwords: "o a os as e é um uma de que não com para do da em se por mas você são"},
+	"nl": {name: "Dutch", scripts: []script{scriptLatin},
+		stopwords: "de het een en is van dat niet op te zijn met voor ik je er maar ook wat"},
+	"sv": {name: "Swedish", scripts: []script{scriptLatin},
+		stopwords: "och är att det som en ett på för med inte av jag den har till om"},
+	"pl": {name: "Polish", scripts: []script{scriptLatin},
+		stopwords: "i w nie na się z że do jest to jak ale co tak po od"},
+	"tr": {name: "Turkish", scripts: []script{scriptLatin},
+		stopwords: "ve bir bu da de için ile ne çok daha gibi olarak var değil ben sen"},
+	"ru": {name: "Russian", scripts: []script{scriptCyrillic}},
+	"uk": {name: "Ukrainian", scripts: []script{scriptCyrillic}},
+	"el": {name: "Greek", scripts: []script{scriptGreek}},
+	"ar": {name: "Arabic", scripts: []script{scriptArabic}},
+	"fa": {name: "Persian", scripts: []script{scriptArabic}},
+	"he": {name: "Hebrew", scripts: []script{scriptHebrew}},
+	"hi": {name: "Hindi", scripts: []script{scriptDevanagari}},
+	"th": {name: "Thai", scripts: []script{scriptThai}},
+	"zh": {name: "Chinese", scripts: []script{scriptHan}},
+	"ja": {name: "Japanese", scripts: []script{scriptKana, scriptHan}},
+	"ko": {name: "Korean", scripts: []script{scriptHangul, scriptHan}},
+}
+
+// stopwordSets holds each Latin-script language's stopwords as a set
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool)
+	for code, l := range languages {
+		if l.stopwords == "" {
+			continue
+		}
+		set := make(map[string]bool)
+		for _, w := range strings.Fields(l.stopwords) {
+			set[w] = true
+		}
+		sets[code] = set
+	}
+	return sets
+}()
+
+// minLetters is how many letters it takes before a guess is worth making
+const minLetters = 20
+
+// Name returns the English name of the language with the ISO 639-1 code,
+// such as "German" for "de", or "" if it isn't known
+func Name(code string) string {
+	return languages[normalize(code)].name
+}
+
+// Known reports whether the language with the code can be detected
+func Known(code string) bool {
+	_, ok := languages[normalize(code)]
+	return ok
+}
+
+// Detect returns the ISO 639-1 code of the language text is written in, or
+// "" if it can't tell. Languages that share a script and have no word list,
+// like Russian and Ukrainian, are never told apart.
+func Detect(text string) string {
+	s := dominantScript(text)
+	switch s {
+	case scriptUnknown:
+		return ""
+	case scriptLatin:
+		return detectLatin(text)
+	}
+	var found string
+	for code, l := range languages {
+		if l.scripts[0] != s {
+			continue
+		}
+		if found != "" {
+			return "" // Several languages share it
+		}
+		found = code
+	}
+	return found
+}
+
+// Matches reports whether text could be in the language with the code. It
+// is false only when the text is clearly in another language, so short or
+// ambiguous text matches, as does any text for a language it doesn't know.
+func Matches(text, code string) bool {
+	l, ok := languages[normalize(code)]
+	if !ok {
+		return true
+	}
+	s := dominantScript(text)
+	if s == scriptUnknown {
+		return true
+	}
+	if !slices.Contains(l.scripts, s) {
+		return false
+	}
+	if s != scriptLatin {
+		return true
+	}
+	detected := detectLatin(text)
+	return detected == "" || detected == normalize(code)
+}
+
+// detectLatin picks the Latin-script language whose common words appear
+// most often, if one clearly leads
+func detectLatin(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]int)
+	for _, w := range words {
+		for code, set := range stopwordSets {
+			if set[w] {
+				scores[code]++
+			}
+		}
+	}
+
+	best, bestScore, second := "", 0, 0
+	for code, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, second = code, score, bestScore
+		case score > second:
+			second = score
+		}
+	}
+	// A couple of hits could be loanwords or names; a narrow lead could be
+	// either of two close languages
+	if bestScore < 3 || bestScore < second*3/2+1 {
+		return ""
+	}
+	return best
+}
+
+// dominantScript returns the script most of text's letters are in, or
+// scriptUnknown when there are too few letters or no clear majority
+func dominantScript(text string) script {
+	counts := make(map[script]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		counts[scriptOf(r)]++
+	}
+	// Han and kana count for Japanese when both appear
+	if counts[scriptKana] > 0 {
+		counts[scriptKana] += counts[scriptHan]
+		counts[scriptHan] = 0
+	}
+	// A Han character carries a word, so fewer of them make a sentence
+	if letters < minLetters && counts[scriptHan] < minLetters/4 {
+		return scriptUnknown
+	}
+	for s, n := range counts {
+		if s != scriptUnknown && n*2 > letters {
+			return s
+		}
+	}
+	return scriptUnknown
+}
+
+func scriptOf(r rune) script {
+	switch {
+	case unicode.Is(unicode.Latin, r):
+		return scriptLatin
+	case unicode.Is(unicode.Cyrillic, r):
+		return scriptCyrillic
+	case unicode.Is(unicode.Greek, r):
+		return scriptGreek
+	case unicode.Is(unicode.Arabic, r):
+		return scriptArabic
+	case unicode.Is(unicode.Hebrew, r):
+		return scriptHebrew
+	case unicode.Is(unicode.Devanagari, r):
+		return scriptDevanagari
+	case unicode.Is(unicode.Thai, r):
+		return scriptThai
+	case unicode.Is(unicode.Han, r):
+		return scriptHan
+	case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+		return scriptKana
+	case unicode.Is(unicode.Hangul, r):
+		return scriptHangul
+	}
+	return scriptUnknown
+}
+
+// normalize reduces a code like "pt-BR" or "DE" to its language
+func normalize(code string) string {
+	code, _, _ = strings.Cut(code, "-")
+	code, _, _ = strings.Cut(code, "_")
+	return strings.ToLower(code)
+}
@@ -0,0 +1,132 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "The weather is nice today, and it is a good day for a walk in the park.", "en"},
+		{"german", "Das Wetter ist heute schön, und es ist ein guter Tag für einen Spaziergang.", "de"},
+		{"spanish", "El tiempo es bueno hoy, y es un buen día para dar un paseo por el parque.", "es"},
+		{"french", "Le temps est beau aujourd'hui, et c'est une bonne journée pour une promenade dans le parc.", "fr"},
+		{"japanese", "今日はいい天気ですね。公園を散歩するのにぴったりの日です。", "ja"},
+		{"chinese", "今天天气很好，是去公园散步的好日子。", "zh"},
+		{"korean", "오늘은 날씨가 좋네요. 공원에서 산책하기 좋은 날입니다.", "ko"},
+		{"greek", "Ο καιρός είναι ωραίος σήμερα και είναι καλή μέρα για βόλτα.", "el"},
+		{"cyrillic is ambiguous", "Сегодня хорошая погода, и это отличный день для прогулки.", ""},
+		{"too short", "Ja, gut.", ""},
+		{"no words", "1234 5678 !!!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		code string
+		want bool
+	}{
+		{"same language", "Das Wetter ist heute schön, und es ist ein guter Tag für einen Spaziergang.", "de", true},
+		{"region suffix", "O tempo está bom hoje, e é um dia ótimo para um passeio no parque com a família.", "pt-BR", true},
+		{"other latin language", "The weather is nice today, and it is a good day for a walk in the park.", "de", false},
+		{"other script", "Сегодня хорошая погода, и это отличный день для прогулки.", "de", false},
+		{"shared script", "Сьогодні гарна погода, і це чудовий день для прогулянки.", "ru", true},
+		{"kanji in japanese", "今日はいい天気ですね。公園を散歩するのにぴったりの日です。", "ja", true},
+		{"latin for japanese", "The weather is nice today, and it is a good day for a walk in the park.", "ja", false},
+		{"too short to tell", "OK, sure.", "de", true},
+		{"unknown language", "The weather is nice today, and it is a good day for a walk in the park.", "sw", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.text, tt.code); got != tt.want {
+				t.Errorf("Matches(%q, %q) = %v, want %v", tt.text, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuard(t *testing.T) {
+	t.Run("releases matching reply", func(t *testing.T) {
+		g := NewGuard("de")
+		var shown string
+		for _, chunk := range []string{"Das Wetter ist heute ", "schön. Es ist ein guter ", "Tag für einen Spaziergang. ", "Viel Spaß!"} {
+			out, ok := g.Write(chunk)
+			if !ok {
+				t.Fatalf("Write(%q) reported drift", chunk)
+			}
+			shown += out
+		}
+		rest, _ := g.Flush()
+		shown += rest
+		if want := "Das Wetter ist heute schön. Es ist ein guter Tag für einen Spaziergang. Viel Spaß!"; shown != want {
+			t.Errorf("shown = %q, want %q", shown, want)
+		}
+	})
+
+	t.Run("holds back until judged", func(t *testing.T) {
+		g := NewGuard("de")
+		if out, _ := g.Write("Das Wetter ist heute schön."); out != "" {
+			t.Errorf("first sentence released early: %q", out)
+		}
+		if out, ok := g.Flush(); !ok || out != "Das Wetter ist heute schön." {
+			t.Errorf("Flush() = %q, %v", out, ok)
+		}
+	})
+
+	t.Run("judges short reply on flush", func(t *testing.T) {
+		g := NewGuard("de")
+		g.Write("The weather is nice today, and it is a good day for a walk.")
+		if _, ok := g.Flush(); ok {
+			t.Error("Flush() accepted a reply in English")
+		}
+	})
+
+	t.Run("reports drift", func(t *testing.T) {
+		g := NewGuard("de")
+		g.Write("The weather is nice today. ")
+		out, ok := g.Write("It is a good day for a walk in the park. ")
+		if ok || out != "" {
+			t.Errorf("Write() = %q, %v, want drift", out, ok)
+		}
+	})
+
+	t.Run("ignores code", func(t *testing.T) {
+		g := NewGuard("de")
+		reply := "```python\nfor item in items:\n    if item is not None and item in the_list:\n        print(item)\n```\nDas ist die Schleife. Sie gibt jedes Element aus, das nicht leer ist. "
+		out, ok := g.Write(reply)
+		if !ok || out != reply {
+			t.Errorf("Write() = %q, %v, want the reply released", out, ok)
+		}
+	})
+
+	t.Run("nil passes through", func(t *testing.T) {
+		g := NewGuard("")
+		if out, ok := g.Write("anything"); !ok || out != "anything" {
+			t.Errorf("Write() = %q, %v", out, ok)
+		}
+		if out, ok := g.Flush(); !ok || out != "" {
+			t.Error("nil guard held content")
+		}
+	})
+}
+
+func TestInstruction(t *testing.T) {
+	if got := Instruction("de"); got != "Always reply in German, whatever language the user writes in." {
+		t.Errorf("Instruction(de) = %q", got)
+	}
+	if got := Instruction("Swahili"); got != "Always reply in Swahili, whatever language the user writes in." {
+		t.Errorf("Instruction(Swahili) = %q", got)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 	"github.com/nchapman/lleme/internal/version"
 )
 
@@ -70,6 +72,7 @@ const (
 type Release struct {
 	TagName string  `json:"tag_name"`
 	Name    string  `json:"name"`
+	Body    string  `json:"body,omitempty"`
 	Assets  []Asset `json:"assets"`
 }
 
@@ -77,16 +80,26 @@ type Asset struct {
 	Name               string `json:"name"`
 	Size               int64  `json:"size"`
 	BrowserDownloadUrl string `json:"browser_download_url"`
+	Digest             string `json:"digest,omitempty"` // "sha256:<hex>", on releases since mid-2025
 }
 
 type VersionInfo struct {
-	TagName     string `json:"tag_name"`
-	BinaryPath  string `json:"binary_path"`
-	InstalledAt string `json:"installed_at"`
+	TagName       string            `json:"tag_name"`
+	BinaryPath    string            `json:"binary_path"`
+	InstalledAt   string            `json:"installed_at"`
+	ArchiveSHA256 string            `json:"archive_sha256,omitempty"` // Of the downloaded release archive
+	Files         map[string]string `json:"files,omitempty"`          // SHA-256 of each installed file, by relative path
 }
 
 type VersionFile struct {
 	Llama *VersionInfo `json:"llama,omitempty"`
+	SD    *VersionInfo `json:"sd,omitempty"`
+}
+
+// Platform returns the llama.cpp release build selected for this host,
+// or "" if llama.cpp doesn't ship one.
+func Platform() string {
+	return getPlatform()
 }
 
 func getPlatform() string {
@@ -95,7 +108,8 @@ func getPlatform() string {
 
 	switch osName {
 	case "darwin":
-		if arch == "arm64" {
+		// An x86_64 lleme under Rosetta should still get the native Metal build
+		if hw.IsAppleSilicon() {
 			return "macos-arm64"
 		}
 		return "macos-x64"
@@ -135,7 +149,7 @@ func GetLatestVersion() (*Release, error) {
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", version.UserAgent())
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Transport: logs.Transport(nil), Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -156,18 +170,27 @@ func GetLatestVersion() (*Release, error) {
 }
 
 func FindAssetForPlatform(release *Release) (string, string, error) {
+	asset, err := findAsset(release)
+	if err != nil {
+		return "", "", err
+	}
+	return asset.BrowserDownloadUrl, asset.Name, nil
+}
+
+// findAsset returns the release archive for this platform
+func findAsset(release *Release) (*Asset, error) {
 	binaryPattern := getBinaryPattern(release)
 	if binaryPattern == "" {
-		return "", "", fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
+		return nil, fmt.Errorf("unsupported platform: %s/%s", runtime.GOOS, runtime.GOARCH)
 	}
 
-	for _, asset := range release.Assets {
-		if asset.Name == binaryPattern {
-			return asset.BrowserDownloadUrl, asset.Name, nil
+	for i := range release.Assets {
+		if release.Assets[i].Name == binaryPattern {
+			return &release.Assets[i], nil
 		}
 	}
 
-	return "", "", fmt.Errorf("could not find binary for platform %s", binaryPattern)
+	return nil, fmt.Errorf("could not find binary for platform %s", binaryPattern)
 }
 
 func DownloadBinary(downloadURL, destPath string, progress func(int64, int64)) error {
@@ -182,7 +205,7 @@ func DownloadBinary(downloadURL, destPath string, progress func(int64, int64)) e
 	transport := &http.Transport{
 		ResponseHeaderTimeout: 30 * time.Second,
 	}
-	client := &http.Client{Transport: transport}
+	client := &http.Client{Transport: logs.Transport(transport)}
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -271,22 +294,28 @@ func extractTarGz(archivePath, destDir string) error {
 type StatusFunc func(message string)
 
 func InstallLatest(status StatusFunc) (*VersionInfo, error) {
+	return InstallLatestWithProgress(status, nil)
+}
+
+// InstallLatestWithProgress is InstallLatest with byte-level download
+// progress reported to progress.
+func InstallLatestWithProgress(status StatusFunc, progress func(current, total int64)) (*VersionInfo, error) {
 	release, err := GetLatestVersion()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest release: %w", err)
 	}
 
-	downloadURL, binaryName, err := FindAssetForPlatform(release)
+	asset, err := findAsset(release)
 	if err != nil {
 		return nil, err
 	}
 
-	binDir := config.BinPath()
+	binDir := paths.Bin()
 	if err := os.MkdirAll(binDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	archivePath := filepath.Join(binDir, binaryName)
+	archivePath := filepath.Join(binDir, asset.Name)
 
 	if status != nil {
 		msg := fmt.Sprintf("Downloading llama.cpp %s", release.TagName)
@@ -296,10 +325,16 @@ func InstallLatest(status StatusFunc) (*VersionInfo, error) {
 		status(msg)
 	}
 
-	if err := DownloadBinary(downloadURL, archivePath, nil); err != nil {
+	if err := DownloadBinary(asset.BrowserDownloadUrl, archivePath, progress); err != nil {
 		return nil, fmt.Errorf("failed to download binary: %w", err)
 	}
 
+	archiveSum, err := verifyArchive(archivePath, asset.Digest)
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, err
+	}
+
 	if status != nil {
 		status("Extracting...")
 	}
@@ -311,11 +346,19 @@ func InstallLatest(status StatusFunc) (*VersionInfo, error) {
 	// Clean up tarball after successful extraction
 	os.Remove(archivePath)
 
+	// Record what was installed so `lleme doctor` can tell if it changes
+	files, err := hashInstall(installDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash installed files: %w", err)
+	}
+
 	cliPath := filepath.Join(binDir, "llama-current", "llama-cli")
 	versionInfo := &VersionInfo{
-		TagName:     release.TagName,
-		BinaryPath:  cliPath,
-		InstalledAt: time.Now().Format(time.RFC3339),
+		TagName:       release.TagName,
+		BinaryPath:    cliPath,
+		InstalledAt:   time.Now().Format(time.RFC3339),
+		ArchiveSHA256: archiveSum,
+		Files:         files,
 	}
 
 	if err := SaveVersionInfo(versionInfo); err != nil {
@@ -326,12 +369,32 @@ func InstallLatest(status StatusFunc) (*VersionInfo, error) {
 }
 
 func GetInstalledVersion() (*VersionInfo, error) {
-	versionPath := filepath.Join(config.BinPath(), "version.json")
+	file, err := readVersionFile()
+	if err != nil {
+		return nil, err
+	}
+	return file.Llama, nil
+}
 
-	data, err := os.ReadFile(versionPath)
+func SaveVersionInfo(version *VersionInfo) error {
+	file, err := readVersionFile()
+	if err != nil {
+		return err
+	}
+	file.Llama = version
+	return writeVersionFile(file)
+}
+
+func versionFilePath() string {
+	return filepath.Join(paths.Bin(), "version.json")
+}
+
+// readVersionFile loads version.json, returning an empty file if it doesn't exist.
+func readVersionFile() (*VersionFile, error) {
+	data, err := os.ReadFile(versionFilePath())
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, nil
+			return &VersionFile{}, nil
 		}
 		return nil, err
 	}
@@ -340,18 +403,16 @@ func GetInstalledVersion() (*VersionInfo, error) {
 	if err := json.Unmarshal(data, &file); err != nil {
 		return nil, err
 	}
-
-	return file.Llama, nil
+	return &file, nil
 }
 
-func SaveVersionInfo(version *VersionInfo) error {
-	versionPath := filepath.Join(config.BinPath(), "version.json")
+func writeVersionFile(file *VersionFile) error {
+	versionPath := versionFilePath()
 
 	if err := os.MkdirAll(filepath.Dir(versionPath), 0755); err != nil {
 		return fmt.Errorf("failed to create version directory: %w", err)
 	}
 
-	file := VersionFile{Llama: version}
 	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal version: %w", err)
@@ -361,15 +422,15 @@ func SaveVersionInfo(version *VersionInfo) error {
 }
 
 func BinaryPath() string {
-	return filepath.Join(config.BinPath(), "llama-current", "llama-cli")
+	return filepath.Join(paths.Bin(), "llama-current", "llama-cli")
 }
 
 func ServerPath() string {
-	return filepath.Join(config.BinPath(), "llama-current", "llama-server")
+	return filepath.Join(paths.Bin(), "llama-current", "llama-server")
 }
 
 func IsInstalled() bool {
-	cliPath := filepath.Join(config.BinPath(), "llama-current", "llama-cli")
+	cliPath := filepath.Join(paths.Bin(), "llama-current", "llama-cli")
 	if _, err := os.Stat(cliPath); err != nil {
 		return false
 	}
@@ -7,6 +7,8 @@ import (
 	"runtime"
 	"slices"
 	"testing"
+
+	"github.com/nchapman/lleme/internal/hw"
 )
 
 func TestGetPlatform(t *testing.T) {
@@ -21,7 +23,10 @@ func TestGetPlatform(t *testing.T) {
 		if runtime.GOARCH == "arm64" && result != "macos-arm64" {
 			t.Errorf("Expected platform macos-arm64, got %s", result)
 		}
-		if runtime.GOARCH == "amd64" && result != "macos-x64" {
+		if runtime.GOARCH == "amd64" && hw.IsAppleSilicon() && result != "macos-arm64" {
+			t.Errorf("Expected platform macos-arm64 under Rosetta, got %s", result)
+		}
+		if runtime.GOARCH == "amd64" && !hw.IsAppleSilicon() && result != "macos-x64" {
 			t.Errorf("Expected platform macos-x64, got %s", result)
 		}
 	case "linux":
@@ -73,12 +78,9 @@ func TestGetBinaryPattern(t *testing.T) {
 
 func TestBinaryPath(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
-	expectedPath := filepath.Join(tmpDir, ".lleme", "bin", "llama-current", "llama-cli")
+	expectedPath := filepath.Join(tmpDir, "bin", "llama-current", "llama-cli")
 	actualPath := BinaryPath()
 
 	if actualPath != expectedPath {
@@ -88,12 +90,9 @@ func TestBinaryPath(t *testing.T) {
 
 func TestServerPath(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
+	t.Setenv("LLEME_HOME", tmpDir)
 
-	os.Setenv("HOME", tmpDir)
-
-	expectedPath := filepath.Join(tmpDir, ".lleme", "bin", "llama-current", "llama-server")
+	expectedPath := filepath.Join(tmpDir, "bin", "llama-current", "llama-server")
 	actualPath := ServerPath()
 
 	if actualPath != expectedPath {
@@ -103,12 +102,9 @@ func TestServerPath(t *testing.T) {
 
 func TestIsInstalled(t *testing.T) {
 	tmpDir := t.TempDir()
-	oldHome := os.Getenv("HOME")
-	defer os.Setenv("HOME", oldHome)
-
-	os.Setenv("HOME", tmpDir)
+	t.Setenv("LLEME_HOME", tmpDir)
 
-	binDir := filepath.Join(tmpDir, ".lleme", "bin")
+	binDir := filepath.Join(tmpDir, "bin")
 	if err := os.MkdirAll(binDir, 0755); err != nil {
 		t.Fatalf("Failed to create bin dir: %v", err)
 	}
@@ -0,0 +1,130 @@
+package llama
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+const (
+	// releasesPerPage is the most releases GitHub returns per page
+	releasesPerPage = 100
+
+	// maxReleasePages caps how far back the changelog looks. llama.cpp tags a
+	// release per commit, so this covers several weeks.
+	maxReleasePages = 5
+)
+
+// Change is a notable line from a release's notes
+type Change struct {
+	Tag  string
+	Text string
+}
+
+// Changelog summarizes the releases between two llama.cpp builds.
+type Changelog struct {
+	Releases int      // Releases after the installed one
+	Complete bool     // Whether the installed release was reached
+	Notable  []Change // Server and model support changes, newest first
+}
+
+var (
+	// notableRe matches release note lines about llama-server or model
+	// support, which llama.cpp prefixes with the area they touch
+	notableRe = regexp.MustCompile(`(?i)^(server|llama-server|model|models|mtmd)\b[^:]{0,20}:|\bsupport(s|ed)? for\b|\badds? support\b`)
+
+	htmlTagRe = regexp.MustCompile(`<[^>]+>`)
+)
+
+// GetChangelog fetches the releases after installedTag, up to the latest,
+// and picks out their notable changes.
+func GetChangelog(installedTag string) (*Changelog, error) {
+	var releases []Release
+	for page := 1; page <= maxReleasePages; page++ {
+		var batch []Release
+		url := fmt.Sprintf("%s/releases?per_page=%d&page=%d", apiBase, releasesPerPage, page)
+		if err := getGitHubJSON(url, &batch); err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		if len(batch) < releasesPerPage || containsRelease(batch, installedTag) {
+			break
+		}
+	}
+	return summarizeReleases(releases, installedTag), nil
+}
+
+// summarizeReleases builds the changelog from releases newest first,
+// stopping at installedTag
+func summarizeReleases(releases []Release, installedTag string) *Changelog {
+	log := &Changelog{}
+	for _, release := range releases {
+		if release.TagName == installedTag {
+			log.Complete = true
+			break
+		}
+		log.Releases++
+		for _, text := range notableLines(release.Body) {
+			log.Notable = append(log.Notable, Change{Tag: release.TagName, Text: text})
+		}
+	}
+	return log
+}
+
+// notableLines returns the server and model support lines of release notes,
+// skipping markup and the download links llama.cpp lists under each release
+func notableLines(body string) []string {
+	var lines []string
+	for line := range strings.SplitSeq(body, "\n") {
+		line = strings.TrimSpace(htmlTagRe.ReplaceAllString(line, ""))
+		line = strings.TrimSpace(strings.TrimLeft(line, "-*"))
+		if line == "" || strings.Contains(line, "](") {
+			continue
+		}
+		if notableRe.MatchString(line) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func containsRelease(releases []Release, tag string) bool {
+	for _, r := range releases {
+		if r.TagName == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// getGitHubJSON decodes a GitHub API response into v
+func getGitHubJSON(url string, v any) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	client := &http.Client{Transport: logs.Transport(nil), Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
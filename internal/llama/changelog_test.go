@@ -0,0 +1,66 @@
+package llama
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNotableLines(t *testing.T) {
+	body := `<details open>
+
+server : add support for reasoning budget (#15100)
+
+</details>
+
+**macOS/iOS:**
+- [macOS Apple Silicon (arm64)](https://github.com/ggml-org/llama.cpp/releases/download/b6100/llama-b6100-bin-macos-arm64.zip)
+`
+	if got := notableLines(body); !slices.Equal(got, []string{"server : add support for reasoning budget (#15100)"}) {
+		t.Errorf("notableLines() = %q", got)
+	}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"model : add gpt-oss (#15091)", true},
+		{"llama-server: fix slot save path", true},
+		{"mtmd : add Gemma 3n vision", true},
+		{"vulkan: add support for MXFP4", true},
+		{"ggml : fix build on FreeBSD", false},
+		{"ci : bump actions", false},
+		{"docs : mention the server in the README", false},
+	}
+	for _, tt := range tests {
+		if got := len(notableLines(tt.line)) == 1; got != tt.want {
+			t.Errorf("notableLines(%q) notable = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeReleases(t *testing.T) {
+	releases := []Release{
+		{TagName: "b6103", Body: "server : fix crash with empty prompt"},
+		{TagName: "b6102", Body: "ggml : speed up q4_0"},
+		{TagName: "b6101", Body: "model : add support for Foo"},
+		{TagName: "b6100", Body: "server : old change"},
+		{TagName: "b6099", Body: "server : older change"},
+	}
+
+	log := summarizeReleases(releases, "b6100")
+	if log.Releases != 3 || !log.Complete {
+		t.Errorf("Releases = %d, Complete = %v, want 3, true", log.Releases, log.Complete)
+	}
+	want := []Change{
+		{Tag: "b6103", Text: "server : fix crash with empty prompt"},
+		{Tag: "b6101", Text: "model : add support for Foo"},
+	}
+	if !slices.Equal(log.Notable, want) {
+		t.Errorf("Notable = %+v, want %+v", log.Notable, want)
+	}
+
+	// An installed release older than those fetched
+	if log := summarizeReleases(releases, "b5000"); log.Complete || log.Releases != 5 {
+		t.Errorf("Releases = %d, Complete = %v, want 5, false", log.Releases, log.Complete)
+	}
+}
@@ -0,0 +1,39 @@
+package llama
+
+import (
+	"strconv"
+	"strings"
+)
+
+// archBuilds maps GGUF architectures to the first llama.cpp build that can
+// load them. Add an entry when a release brings support for a new
+// architecture; ones supported before these are left out and never flagged.
+var archBuilds = map[string]int{
+	"gemma3":   4875,
+	"llama4":   5074,
+	"qwen3":    5092,
+	"qwen3moe": 5092,
+	"gemma3n":  5757,
+	"glm4moe":  6085,
+	"gpt-oss":  6096,
+}
+
+// RequiredBuild returns the first llama.cpp build that supports a GGUF
+// architecture, if it's one known to need a recent build.
+func RequiredBuild(arch string) (int, bool) {
+	build, ok := archBuilds[arch]
+	return build, ok
+}
+
+// ParseBuild returns the build number of a release tag such as "b6096".
+func ParseBuild(tag string) (int, bool) {
+	n, ok := strings.CutPrefix(tag, "b")
+	if !ok {
+		return 0, false
+	}
+	build, err := strconv.Atoi(n)
+	if err != nil || build <= 0 {
+		return 0, false
+	}
+	return build, true
+}
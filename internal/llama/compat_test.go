@@ -0,0 +1,34 @@
+package llama
+
+import "testing"
+
+func TestParseBuild(t *testing.T) {
+	tests := []struct {
+		tag   string
+		build int
+		ok    bool
+	}{
+		{"b6096", 6096, true},
+		{"b1", 1, true},
+		{"6096", 0, false},
+		{"b", 0, false},
+		{"b60x", 0, false},
+		{"master-abc123", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		build, ok := ParseBuild(tt.tag)
+		if build != tt.build || ok != tt.ok {
+			t.Errorf("ParseBuild(%q) = %d, %v, want %d, %v", tt.tag, build, ok, tt.build, tt.ok)
+		}
+	}
+}
+
+func TestRequiredBuild(t *testing.T) {
+	if build, ok := RequiredBuild("gpt-oss"); !ok || build != 6096 {
+		t.Errorf("RequiredBuild(gpt-oss) = %d, %v", build, ok)
+	}
+	if _, ok := RequiredBuild("llama"); ok {
+		t.Error("RequiredBuild(llama) should be unknown, as it's always been supported")
+	}
+}
@@ -0,0 +1,126 @@
+package llama
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// ErrNoChecksums is returned when verifying an install made before lleme
+// recorded checksums.
+var ErrNoChecksums = errors.New("no checksums recorded for this install, run 'lleme update llama.cpp' to reinstall")
+
+// fileSHA256 returns the hex SHA-256 of a file.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyArchive checks a downloaded archive against the digest GitHub
+// publishes for the release asset ("sha256:<hex>"), returning its SHA-256.
+// Older releases have no digest, in which case the archive is only hashed.
+func verifyArchive(path, digest string) (string, error) {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return "", err
+	}
+
+	want, ok := strings.CutPrefix(digest, "sha256:")
+	if !ok {
+		logs.Debug("No published digest to verify download against", "archive", filepath.Base(path), "digest", digest)
+		return sum, nil
+	}
+	if !strings.EqualFold(want, sum) {
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s (the download is truncated or was tampered with)",
+			filepath.Base(path), want, sum)
+	}
+	return sum, nil
+}
+
+// hashInstall returns the SHA-256 of every regular file under dir, keyed by
+// slash-separated path relative to it.
+func hashInstall(dir string) (map[string]string, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// compareInstall lists the recorded files that are missing from dir or no
+// longer match their checksums, sorted by path.
+func compareInstall(dir string, recorded map[string]string) ([]string, error) {
+	current, err := hashInstall(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for path, want := range recorded {
+		got, ok := current[path]
+		switch {
+		case !ok:
+			problems = append(problems, path+" is missing")
+		case got != want:
+			problems = append(problems, path+" has changed")
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// VerifyInstalled re-hashes the installed llama.cpp binaries and libraries
+// and lists any that are missing or differ from when they were installed.
+func VerifyInstalled() ([]string, error) {
+	installed, err := GetInstalledVersion()
+	if err != nil {
+		return nil, err
+	}
+	if installed == nil || len(installed.Files) == 0 {
+		return nil, ErrNoChecksums
+	}
+	return compareInstall(installDir(), installed.Files)
+}
+
+// installDir is the directory llama-current points at
+func installDir() string {
+	return filepath.Dir(ServerPath())
+}
@@ -0,0 +1,96 @@
+package llama
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+func TestVerifyArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "llama-b6096-bin-macos-arm64.tar.gz")
+	if err := os.WriteFile(path, []byte("archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("archive"))
+	want := hex.EncodeToString(sum[:])
+
+	if got, err := verifyArchive(path, "sha256:"+want); err != nil || got != want {
+		t.Errorf("verifyArchive() = %q, %v, want %q", got, err, want)
+	}
+	if got, err := verifyArchive(path, "sha256:"+strings.ToUpper(want)); err != nil || got != want {
+		t.Errorf("verifyArchive() with an uppercase digest = %q, %v", got, err)
+	}
+
+	// Releases from before GitHub published digests are only hashed
+	if got, err := verifyArchive(path, ""); err != nil || got != want {
+		t.Errorf("verifyArchive() without a digest = %q, %v, want %q", got, err, want)
+	}
+
+	_, err := verifyArchive(path, "sha256:"+strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("verifyArchive() with the wrong digest = %v, want a checksum mismatch", err)
+	}
+}
+
+func TestVerifyInstalled(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	if _, err := VerifyInstalled(); !errors.Is(err, ErrNoChecksums) {
+		t.Errorf("VerifyInstalled() without an install = %v, want ErrNoChecksums", err)
+	}
+
+	// Installs live in a versioned directory behind the llama-current link
+	binDir := paths.Bin()
+	versionDir := filepath.Join(binDir, "llama-b6096")
+	if err := os.MkdirAll(filepath.Join(versionDir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"llama-server": "server", "llama-cli": "cli", "lib/libggml.so": "ggml"} {
+		if err := os.WriteFile(filepath.Join(versionDir, name), []byte(data), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("libggml.so", filepath.Join(versionDir, "lib", "libggml.so.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("llama-b6096", filepath.Join(binDir, "llama-current")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := hashInstall(installDir())
+	if err != nil {
+		t.Fatalf("hashInstall() error = %v", err)
+	}
+	if len(files) != 3 || files["lib/libggml.so"] == "" {
+		t.Fatalf("hashInstall() = %v, want the 3 regular files", files)
+	}
+	if err := SaveVersionInfo(&VersionInfo{TagName: "b6096", Files: files}); err != nil {
+		t.Fatal(err)
+	}
+
+	if problems, err := VerifyInstalled(); err != nil || len(problems) != 0 {
+		t.Errorf("VerifyInstalled() = %v, %v, want no problems", problems, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(versionDir, "llama-server"), []byte("tampered"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(versionDir, "lib", "libggml.so")); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := VerifyInstalled()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lib/libggml.so is missing", "llama-server has changed"}
+	if !slices.Equal(problems, want) {
+		t.Errorf("VerifyInstalled() = %q, want %q", problems, want)
+	}
+}
@@ -0,0 +1,277 @@
+package llama
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+const (
+	sdRepo    = "leejet/stable-diffusion.cpp"
+	sdAPIBase = "https://api.github.com/repos/" + sdRepo
+	sdServer  = "sd-server"
+)
+
+// GetLatestSDVersion returns the latest stable-diffusion.cpp release.
+func GetLatestSDVersion() (*Release, error) {
+	req, err := http.NewRequest("GET", sdAPIBase+"/releases/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	client := &http.Client{Transport: logs.Transport(nil), Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+
+	return &release, nil
+}
+
+// findSDAsset picks the release archive for a platform. stable-diffusion.cpp
+// names its builds like sd-master-abc123-bin-Linux-Ubuntu-24.04-x86_64.zip,
+// with GPU variants carrying a suffix such as -vulkan or -cuda12.
+func findSDAsset(release *Release, goos, goarch string, vulkan bool) (*Asset, error) {
+	var osToken, archToken string
+	switch {
+	case goos == "darwin" && goarch == "arm64":
+		osToken, archToken = "-Darwin-", "arm64"
+	case goos == "linux" && goarch == "amd64":
+		osToken, archToken = "-Linux-", "x86_64"
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s/%s", goos, goarch)
+	}
+
+	var cpu, gpu *Asset
+	for i, asset := range release.Assets {
+		name := strings.TrimSuffix(asset.Name, ".zip")
+		if name == asset.Name || !strings.Contains(name, osToken) {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, archToken):
+			cpu = &release.Assets[i]
+		case strings.HasSuffix(name, archToken+"-vulkan"):
+			gpu = &release.Assets[i]
+		}
+	}
+
+	if vulkan && gpu != nil {
+		return gpu, nil
+	}
+	if cpu == nil {
+		return nil, fmt.Errorf("could not find stable-diffusion.cpp build for %s/%s", goos, goarch)
+	}
+	return cpu, nil
+}
+
+// InstallLatestSD downloads the latest stable-diffusion.cpp release into the
+// bin directory alongside llama.cpp.
+func InstallLatestSD(status StatusFunc) (*VersionInfo, error) {
+	return InstallLatestSDWithProgress(status, nil)
+}
+
+// InstallLatestSDWithProgress is InstallLatestSD with byte-level download
+// progress reported to progress.
+func InstallLatestSDWithProgress(status StatusFunc, progress func(current, total int64)) (*VersionInfo, error) {
+	release, err := GetLatestSDVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest release: %w", err)
+	}
+
+	asset, err := findSDAsset(release, runtime.GOOS, runtime.GOARCH, HasVulkanSupport())
+	if err != nil {
+		return nil, err
+	}
+
+	binDir := paths.Bin()
+	if err := os.MkdirAll(binDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create bin directory: %w", err)
+	}
+
+	if status != nil {
+		status(fmt.Sprintf("Downloading stable-diffusion.cpp %s", release.TagName))
+	}
+
+	archivePath := filepath.Join(binDir, asset.Name)
+	if err := DownloadBinary(asset.BrowserDownloadUrl, archivePath, progress); err != nil {
+		return nil, fmt.Errorf("failed to download binary: %w", err)
+	}
+	defer os.Remove(archivePath)
+
+	archiveSum, err := verifyArchive(archivePath, asset.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != nil {
+		status("Extracting...")
+	}
+
+	versionDir := "sd-" + release.TagName
+	if err := extractZip(archivePath, filepath.Join(binDir, versionDir)); err != nil {
+		return nil, fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	serverDir, err := findFileDir(filepath.Join(binDir, versionDir), sdServer)
+	if err != nil {
+		return nil, err
+	}
+	// Not every archive preserves the executable bit
+	if err := os.Chmod(filepath.Join(serverDir, sdServer), 0755); err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(binDir, serverDir)
+	if err != nil {
+		return nil, err
+	}
+
+	currentLink := filepath.Join(binDir, "sd-current")
+	if err := os.RemoveAll(currentLink); err != nil {
+		return nil, fmt.Errorf("failed to remove existing sd-current: %w", err)
+	}
+	if err := os.Symlink(rel, currentLink); err != nil {
+		return nil, fmt.Errorf("failed to create sd-current symlink: %w", err)
+	}
+
+	versionInfo := &VersionInfo{
+		TagName:       release.TagName,
+		BinaryPath:    SDServerPath(),
+		InstalledAt:   time.Now().Format(time.RFC3339),
+		ArchiveSHA256: archiveSum,
+	}
+
+	file, err := readVersionFile()
+	if err != nil {
+		return nil, err
+	}
+	file.SD = versionInfo
+	if err := writeVersionFile(file); err != nil {
+		return nil, fmt.Errorf("failed to save version info: %w", err)
+	}
+
+	return versionInfo, nil
+}
+
+// extractZip unpacks a zip archive into destDir, refusing entries that would
+// escape it.
+func extractZip(archivePath, destDir string) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		path := filepath.Join(destDir, f.Name)
+		if !strings.HasPrefix(path, filepath.Clean(destDir)+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in archive: %s", f.Name)
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := extractZipFile(f, path); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func extractZipFile(f *zip.File, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, f.Mode().Perm()|0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// findFileDir returns the directory under root that contains name.
+func findFileDir(root, name string) (string, error) {
+	var found string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && d.Name() == name {
+			found = filepath.Dir(path)
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if found == "" {
+		return "", fmt.Errorf("could not find %s in archive", name)
+	}
+	return found, nil
+}
+
+// GetInstalledSDVersion returns the installed stable-diffusion.cpp version,
+// or nil if it isn't installed.
+func GetInstalledSDVersion() (*VersionInfo, error) {
+	file, err := readVersionFile()
+	if err != nil {
+		return nil, err
+	}
+	return file.SD, nil
+}
+
+func SDServerPath() string {
+	return filepath.Join(paths.Bin(), "sd-current", sdServer)
+}
+
+func IsSDInstalled() bool {
+	_, err := os.Stat(SDServerPath())
+	return err == nil
+}
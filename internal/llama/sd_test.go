@@ -0,0 +1,127 @@
+package llama
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindSDAsset(t *testing.T) {
+	release := &Release{
+		TagName: "master-abc123",
+		Assets: []Asset{
+			{Name: "sd-master-abc123-bin-Darwin-macOS-15.6-arm64.zip"},
+			{Name: "sd-master-abc123-bin-Linux-Ubuntu-24.04-x86_64.zip"},
+			{Name: "sd-master-abc123-bin-Linux-Ubuntu-24.04-x86_64-vulkan.zip"},
+			{Name: "sd-master-abc123-bin-win-cuda12-x64.zip"},
+			{Name: "sd-master-abc123-src.tar.gz"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		goos    string
+		goarch  string
+		vulkan  bool
+		want    string
+		wantErr bool
+	}{
+		{"macOS arm64", "darwin", "arm64", false, "sd-master-abc123-bin-Darwin-macOS-15.6-arm64.zip", false},
+		{"linux cpu", "linux", "amd64", false, "sd-master-abc123-bin-Linux-Ubuntu-24.04-x86_64.zip", false},
+		{"linux vulkan", "linux", "amd64", true, "sd-master-abc123-bin-Linux-Ubuntu-24.04-x86_64-vulkan.zip", false},
+		{"linux arm64", "linux", "arm64", false, "", true},
+		{"macOS intel", "darwin", "amd64", false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asset, err := findSDAsset(release, tt.goos, tt.goarch, tt.vulkan)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("findSDAsset() = %s, want error", asset.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("findSDAsset() error = %v", err)
+			}
+			if asset.Name != tt.want {
+				t.Errorf("findSDAsset() = %s, want %s", asset.Name, tt.want)
+			}
+		})
+	}
+}
+
+func writeTestZip(t *testing.T, files map[string]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractZip(t *testing.T) {
+	t.Run("extracts nested files", func(t *testing.T) {
+		archive := writeTestZip(t, map[string]string{
+			"build/bin/sd-server": "binary",
+			"build/bin/sd-cli":    "binary",
+		})
+		dest := filepath.Join(t.TempDir(), "sd")
+
+		if err := extractZip(archive, dest); err != nil {
+			t.Fatalf("extractZip() error = %v", err)
+		}
+
+		dir, err := findFileDir(dest, sdServer)
+		if err != nil {
+			t.Fatalf("findFileDir() error = %v", err)
+		}
+		if want := filepath.Join(dest, "build", "bin"); dir != want {
+			t.Errorf("findFileDir() = %s, want %s", dir, want)
+		}
+	})
+
+	t.Run("rejects path traversal", func(t *testing.T) {
+		archive := writeTestZip(t, map[string]string{"../evil": "x"})
+		dest := filepath.Join(t.TempDir(), "sd")
+
+		if err := extractZip(archive, dest); err == nil {
+			t.Error("extractZip() should reject entries outside the destination")
+		}
+	})
+}
+
+func TestSaveVersionInfoKeepsSD(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := writeVersionFile(&VersionFile{SD: &VersionInfo{TagName: "master-abc123"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveVersionInfo(&VersionInfo{TagName: "b7751"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sd, err := GetInstalledSDVersion()
+	if err != nil {
+		t.Fatalf("GetInstalledSDVersion() error = %v", err)
+	}
+	if sd == nil || sd.TagName != "master-abc123" {
+		t.Errorf("GetInstalledSDVersion() = %+v, want master-abc123", sd)
+	}
+}
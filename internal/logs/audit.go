@@ -0,0 +1,86 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// AuditEntry records one administrative action taken through the server
+type AuditEntry struct {
+	Time   time.Time      `json:"time"`
+	Action string         `json:"action"`           // e.g. "run", "stop", "stop-all"
+	Client string         `json:"client,omitempty"` // server.clients name, if the caller had a profile
+	Remote string         `json:"remote,omitempty"` // Caller's address
+	Via    string         `json:"via"`              // "http" or "grpc"
+	Params map[string]any `json:"params,omitempty"`
+	Error  string         `json:"error,omitempty"` // Empty when the action succeeded
+}
+
+var auditMu sync.Mutex
+
+// AuditLogPath returns the path of the audit log. Unlike other logs it is
+// never rotated or truncated.
+func AuditLogPath() string {
+	return filepath.Join(paths.Logs(), "audit.log")
+}
+
+// Audit appends an entry to the audit log as one line of JSON. A zero Time
+// is set to now.
+func Audit(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	path := AuditLogPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReadAudit returns the audit log's entries, oldest first. Lines that can't
+// be parsed, such as one cut short by a crash, are skipped. A missing log
+// has no entries.
+func ReadAudit() ([]AuditEntry, error) {
+	f, err := os.Open(AuditLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,62 @@
+package logs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	if entries, err := ReadAudit(); err != nil || entries != nil {
+		t.Fatalf("ReadAudit() without a log = %v, %v", entries, err)
+	}
+
+	if err := Audit(AuditEntry{Action: "run", Client: "ci", Via: "http", Params: map[string]any{"model": "m"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Audit(AuditEntry{Action: "stop", Via: "grpc", Error: errors.New("not loaded").Error()}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A line cut short by a crash is skipped, and later entries still append
+	f, err := os.OpenFile(AuditLogPath(), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"action":"ru` + "\n")
+	f.Close()
+	if err := Audit(AuditEntry{Action: "stop-all", Via: "http"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ReadAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+		if e.Time.IsZero() {
+			t.Errorf("entry %q has no time", e.Action)
+		}
+	}
+	if len(actions) != 3 || actions[0] != "run" || actions[1] != "stop" || actions[2] != "stop-all" {
+		t.Errorf("actions = %v, want [run stop stop-all]", actions)
+	}
+	if entries[0].Client != "ci" || entries[0].Params["model"] != "m" {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	if entries[1].Error != "not loaded" {
+		t.Errorf("error = %q, want %q", entries[1].Error, "not loaded")
+	}
+
+	info, err := os.Stat(AuditLogPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("audit log mode = %v, want 0600", info.Mode().Perm())
+	}
+}
@@ -5,26 +5,76 @@ import (
 	"os"
 
 	"github.com/charmbracelet/log"
+	"github.com/muesli/termenv"
 )
 
+// traceLevel sits below debug, for request headers and other detail shown with -vv
+const traceLevel = log.DebugLevel - 4
+
 var logger *log.Logger
 
+// output is where the logger writes
+var output io.Writer = os.Stderr
+
+// verbosity is the level InitLogger was called with, for passing on to
+// child processes
+var verbosity int
+
 // InitLogger initializes the global logger. If w is nil, logs to stderr.
-func InitLogger(w io.Writer, verbose bool) {
+// Verbosity 0 logs info and above, 1 (-v) adds debug messages, and 2 (-vv)
+// adds trace messages.
+func InitLogger(w io.Writer, v int) {
 	if w == nil {
 		w = os.Stderr
 	}
+	output = w
 	logger = log.NewWithOptions(w, log.Options{
 		Level:           log.InfoLevel,
 		ReportTimestamp: false,
 		ReportCaller:    false,
 	})
+	styles := log.DefaultStyles()
+	styles.Levels[traceLevel] = styles.Levels[log.DebugLevel].SetString("TRCE")
+	logger.SetStyles(styles)
 
-	if verbose {
+	verbosity = v
+	switch {
+	case v >= 2:
+		logger.SetLevel(traceLevel)
+	case v == 1:
 		logger.SetLevel(log.DebugLevel)
 	}
 }
 
+// SetColorProfile overrides the color profile detected for the log output,
+// for the ui.color setting
+func SetColorProfile(p termenv.Profile) {
+	if logger != nil {
+		logger.SetColorProfile(p)
+	}
+}
+
+// Writer returns where log messages go, for output too long to log line
+// by line
+func Writer() io.Writer {
+	return output
+}
+
+// Verbosity returns the verbosity the logger was initialized with.
+func Verbosity() int {
+	return verbosity
+}
+
+func debugEnabled() bool {
+	return logger != nil && logger.GetLevel() <= log.DebugLevel
+}
+
+func Trace(msg string, args ...any) {
+	if logger != nil {
+		logger.Log(traceLevel, msg, args...)
+	}
+}
+
 func Debug(msg string, args ...any) {
 	if logger != nil {
 		logger.Debug(msg, args...)
@@ -8,8 +8,11 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 const (
@@ -19,6 +22,10 @@ const (
 	MaxFileSize = 10 * 1024 * 1024
 )
 
+// diskFullRetry is how long a writer drops output after the disk fills up
+// before trying to write again
+var diskFullRetry = 30 * time.Second
+
 // Pre-compiled regexes for model name sanitization
 var (
 	ggufSuffixRe      = regexp.MustCompile(`(?i)-gguf(:|$)`)
@@ -59,12 +66,12 @@ func SanitizeModelName(fullName string) string {
 // BackendLogPath returns the log file path for a backend with the given model name.
 func BackendLogPath(modelName string) string {
 	sanitized := SanitizeModelName(modelName)
-	return filepath.Join(config.LogsPath(), sanitized+".log")
+	return filepath.Join(paths.Logs(), sanitized+".log")
 }
 
 // ProxyLogPath returns the log file path for the proxy.
 func ProxyLogPath() string {
-	return filepath.Join(config.LogsPath(), "proxy.log")
+	return filepath.Join(paths.Logs(), "proxy.log")
 }
 
 // rotateLogs rotates log files: .log -> .log.1 -> .log.2
@@ -99,6 +106,8 @@ type RotatingWriter struct {
 	basePath     string
 	file         *os.File
 	bytesWritten int64
+	pausedAt     time.Time // When the disk filled up; zero while writing
+	dropped      int64     // Bytes dropped since pausedAt
 }
 
 // NewRotatingWriter creates a new rotating writer for the given base path.
@@ -127,23 +136,74 @@ func NewRotatingWriter(basePath string) (*RotatingWriter, error) {
 	}, nil
 }
 
-// Write writes data to the log file, rotating if necessary.
+// Write writes data to the log file, rotating if necessary. When the disk
+// fills up, output is dropped instead of failing, so a backend writing its
+// output here doesn't stall, and writing resumes once there is space again.
 func (w *RotatingWriter) Write(p []byte) (n int, err error) {
 	w.mu.Lock()
-	defer w.mu.Unlock()
+	n, err = w.writeUnlocked(p)
+	paused := false
+	if fileutil.IsDiskFull(err) {
+		paused = w.pausedAt.IsZero()
+		w.pausedAt = time.Now()
+		w.dropped += int64(len(p) - n)
+		n, err = len(p), nil
+	}
+	w.mu.Unlock()
+
+	// Warned after unlocking, since the logger may write here
+	if paused {
+		free, _ := hw.FreeDisk(filepath.Dir(w.basePath))
+		Warn("Disk full, dropping log output until space is freed", "path", w.basePath, "needed", len(p), "free", free)
+	}
+	return n, err
+}
+
+// writeUnlocked writes p, dropping it while paused on a full disk.
+// Caller must hold w.mu.
+func (w *RotatingWriter) writeUnlocked(p []byte) (int, error) {
+	if !w.pausedAt.IsZero() {
+		if time.Since(w.pausedAt) < diskFullRetry {
+			w.dropped += int64(len(p))
+			return len(p), nil
+		}
+		if err := w.resumeUnlocked(); err != nil {
+			return 0, err
+		}
+	}
 
 	// Check if we need to rotate before writing
-	if w.bytesWritten+int64(len(p)) > MaxFileSize {
+	if w.file == nil || w.bytesWritten+int64(len(p)) > MaxFileSize {
 		if err := w.rotateUnlocked(); err != nil {
 			return 0, err
 		}
 	}
 
-	n, err = w.file.Write(p)
+	n, err := w.file.Write(p)
 	w.bytesWritten += int64(n)
 	return n, err
 }
 
+// resumeUnlocked notes how much output was dropped while the disk was full,
+// so a gap in the log isn't mistaken for a quiet backend.
+// Caller must hold w.mu.
+func (w *RotatingWriter) resumeUnlocked() error {
+	if w.file == nil {
+		if err := w.rotateUnlocked(); err != nil {
+			return err
+		}
+	}
+	note := fmt.Sprintf("[lleme] %d bytes of output dropped while the disk was full\n", w.dropped)
+	n, err := w.file.WriteString(note)
+	w.bytesWritten += int64(n)
+	if err != nil {
+		return err
+	}
+	w.pausedAt = time.Time{}
+	w.dropped = 0
+	return nil
+}
+
 // Close closes the underlying file.
 func (w *RotatingWriter) Close() error {
 	w.mu.Lock()
@@ -161,6 +221,7 @@ func (w *RotatingWriter) rotateUnlocked() error {
 	// Close current file
 	if w.file != nil {
 		w.file.Close()
+		w.file = nil
 	}
 
 	// Rotate files
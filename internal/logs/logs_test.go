@@ -6,7 +6,7 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 func TestSanitizeModelName(t *testing.T) {
@@ -263,7 +263,7 @@ func TestNewRotatingWriterRotatesExisting(t *testing.T) {
 
 func TestBackendLogPath(t *testing.T) {
 	path := BackendLogPath("bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M")
-	expected := filepath.Join(config.LogsPath(), "llama-3.2-3b-instruct-q4_k_m.log")
+	expected := filepath.Join(paths.Logs(), "llama-3.2-3b-instruct-q4_k_m.log")
 	if path != expected {
 		t.Errorf("BackendLogPath() = %q, want %q", path, expected)
 	}
@@ -271,8 +271,51 @@ func TestBackendLogPath(t *testing.T) {
 
 func TestProxyLogPath(t *testing.T) {
 	path := ProxyLogPath()
-	expected := filepath.Join(config.LogsPath(), "proxy.log")
+	expected := filepath.Join(paths.Logs(), "proxy.log")
 	if path != expected {
 		t.Errorf("ProxyLogPath() = %q, want %q", path, expected)
 	}
 }
+
+func TestRotatingWriterDiskFull(t *testing.T) {
+	// Writes to /dev/full fail with ENOSPC
+	full, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip("no /dev/full")
+	}
+	basePath := filepath.Join(t.TempDir(), "test.log")
+	writer := &RotatingWriter{basePath: basePath, file: full}
+
+	// Output is dropped rather than failing, so backends don't stall
+	for _, line := range []string{"first\n", "second\n"} {
+		if n, err := writer.Write([]byte(line)); err != nil || n != len(line) {
+			t.Fatalf("Write() on a full disk = %d, %v; want %d, nil", n, err, len(line))
+		}
+	}
+	if writer.pausedAt.IsZero() || writer.dropped != int64(len("first\nsecond\n")) {
+		t.Errorf("pausedAt = %v, dropped = %d", writer.pausedAt, writer.dropped)
+	}
+
+	// Once space is back, the next write resumes after a note about the gap
+	orig := diskFullRetry
+	diskFullRetry = 0
+	t.Cleanup(func() { diskFullRetry = orig })
+	full.Close()
+	writer.file, err = os.Create(basePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Write() after space was freed: %v", err)
+	}
+	writer.Close()
+
+	content, _ := os.ReadFile(basePath)
+	want := "[lleme] 13 bytes of output dropped while the disk was full\nthird\n"
+	if string(content) != want {
+		t.Errorf("log = %q, want %q", content, want)
+	}
+	if !writer.pausedAt.IsZero() || writer.dropped != 0 {
+		t.Error("writer still paused after resuming")
+	}
+}
@@ -0,0 +1,78 @@
+package logs
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sensitiveParams are query parameters whose values are redacted, such as
+// the signatures in presigned S3 and CDN URLs
+var sensitiveParams = []string{"signature", "token", "credential", "key", "auth"}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+// Transport wraps base (http.DefaultTransport if nil) to log each request
+// at debug level and its headers at trace level. It costs nothing when
+// neither -v nor -vv is set. Credentials are redacted.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !debugEnabled() {
+		return t.base.RoundTrip(req)
+	}
+
+	target := RedactURL(req.URL)
+	Trace("HTTP request", "method", req.Method, "url", target, "headers", redactHeaders(req.Header))
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		Debug("HTTP request failed", "method", req.Method, "url", target, "duration", elapsed, "error", err)
+		return nil, err
+	}
+
+	Debug("HTTP", "method", req.Method, "url", target, "status", resp.StatusCode, "duration", elapsed)
+	Trace("HTTP response", "url", target, "headers", redactHeaders(resp.Header))
+	return resp, nil
+}
+
+// RedactURL returns u as a string with credential-like query values hidden.
+func RedactURL(u *url.URL) string {
+	if u.RawQuery == "" && u.User == nil {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = nil
+	query := redacted.Query()
+	for name := range query {
+		lower := strings.ToLower(name)
+		for _, s := range sensitiveParams {
+			if strings.Contains(lower, s) {
+				query.Set(name, "REDACTED")
+				break
+			}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range []string{"Authorization", "Cookie", "Set-Cookie", "X-Amz-Security-Token"} {
+		if out.Get(name) != "" {
+			out.Set(name, "REDACTED")
+		}
+	}
+	return out
+}
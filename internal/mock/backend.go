@@ -0,0 +1,301 @@
+// Package mock provides a deterministic stand-in for llama-server so the
+// proxy, TUI and CLI can be exercised without real models or llama.cpp.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvVar enables the mock backend when set to "1".
+const EnvVar = "LLEME_MOCK_BACKEND"
+
+// embeddingSize is the length of vectors returned by /v1/embeddings
+const embeddingSize = 8
+
+// Enabled reports whether backends should be replaced by the mock.
+func Enabled() bool {
+	return os.Getenv(EnvVar) == "1"
+}
+
+// Reply returns the canned response for a prompt. It is exported so tests
+// can assert on the exact text a mock backend streams.
+func Reply(prompt string) string {
+	return "Mock response to: " + prompt
+}
+
+// Backend is a running mock server.
+type Backend struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// Start serves the mock on addr until Close is called.
+func Start(addr, model string) (*Backend, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	b := &Backend{
+		server:   &http.Server{Handler: Handler(model)},
+		listener: ln,
+	}
+	go b.server.Serve(ln)
+	return b, nil
+}
+
+// Addr returns the address the mock is listening on.
+func (b *Backend) Addr() string {
+	return b.listener.Addr().String()
+}
+
+// Close stops the mock server.
+func (b *Backend) Close() error {
+	return b.server.Close()
+}
+
+// Handler returns the mock's routes for the given model name.
+func Handler(model string) http.Handler {
+	h := &handler{model: model}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", h.health)
+	mux.HandleFunc("GET /props", h.props)
+	mux.HandleFunc("GET /v1/models", h.models)
+	mux.HandleFunc("POST /v1/chat/completions", h.chatCompletions)
+	mux.HandleFunc("POST /v1/completions", h.completions)
+	mux.HandleFunc("POST /v1/embeddings", h.embeddings)
+	return mux
+}
+
+type handler struct {
+	model string
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type completionRequest struct {
+	Messages  []message `json:"messages"`
+	Prompt    any       `json:"prompt"`
+	Stream    bool      `json:"stream"`
+	MaxTokens int       `json:"max_tokens"`
+}
+
+func (h *handler) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// props reports llama-server's default of four parallel slots
+func (h *handler) props(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{"total_slots": 4})
+}
+
+func (h *handler) models(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{
+		"object": "list",
+		"data":   []map[string]any{{"id": h.model, "object": "model", "owned_by": "lleme"}},
+	})
+}
+
+func (h *handler) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body: "+err.Error())
+		return
+	}
+
+	var prompt string
+	for _, msg := range req.Messages {
+		if msg.Role == "user" {
+			prompt = contentText(msg.Content)
+		}
+	}
+
+	// A trailing assistant message is continued rather than answered
+	var prefix string
+	if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == "assistant" {
+		prefix = contentText(req.Messages[n-1].Content)
+	}
+	tokens, finish := replyTokens(prompt, prefix, req.MaxTokens)
+
+	id := "chatcmpl-mock"
+	created := time.Now().Unix()
+	usage := map[string]int{
+		"prompt_tokens":     len(strings.Fields(prompt)),
+		"completion_tokens": len(tokens),
+		"total_tokens":      len(strings.Fields(prompt)) + len(tokens),
+	}
+
+	if !req.Stream {
+		writeJSON(w, map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   h.model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": strings.Join(tokens, "")},
+				"finish_reason": finish,
+			}},
+			"usage": usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(delta map[string]string, finishReason any, extra map[string]any) {
+		chunk := map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   h.model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+		for k, v := range extra {
+			chunk[k] = v
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(map[string]string{"role": "assistant"}, nil, nil)
+	for _, tok := range tokens {
+		send(map[string]string{"content": tok}, nil, nil)
+	}
+	send(map[string]string{}, finish, map[string]any{
+		"usage": usage,
+		"timings": map[string]any{
+			"prompt_n":             usage["prompt_tokens"],
+			"prompt_per_second":    1000.0,
+			"predicted_n":          len(tokens),
+			"predicted_per_second": 100.0,
+		},
+	})
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func (h *handler) completions(w http.ResponseWriter, r *http.Request) {
+	var req completionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body: "+err.Error())
+		return
+	}
+	if req.Stream {
+		writeError(w, "streaming completions are not supported by the mock backend")
+		return
+	}
+
+	prompt := contentText(req.Prompt)
+	tokens, finish := replyTokens(prompt, "", req.MaxTokens)
+	writeJSON(w, map[string]any{
+		"id":      "cmpl-mock",
+		"object":  "text_completion",
+		"created": time.Now().Unix(),
+		"model":   h.model,
+		"choices": []map[string]any{{"index": 0, "text": strings.Join(tokens, ""), "finish_reason": finish}},
+	})
+}
+
+func (h *handler) embeddings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Input any `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid request body: "+err.Error())
+		return
+	}
+
+	var inputs []string
+	switch v := req.Input.(type) {
+	case string:
+		inputs = []string{v}
+	case []any:
+		for _, item := range v {
+			inputs = append(inputs, contentText(item))
+		}
+	}
+
+	data := make([]map[string]any, len(inputs))
+	for i, input := range inputs {
+		data[i] = map[string]any{"object": "embedding", "index": i, "embedding": Embedding(input)}
+	}
+	writeJSON(w, map[string]any{"object": "list", "model": h.model, "data": data})
+}
+
+// Embedding returns the deterministic vector the mock assigns to text.
+func Embedding(text string) []float64 {
+	vec := make([]float64, embeddingSize)
+	for i := range vec {
+		hash := fnv.New32a()
+		fmt.Fprintf(hash, "%d:%s", i, text)
+		vec[i] = float64(hash.Sum32())/float64(^uint32(0))*2 - 1
+	}
+	return vec
+}
+
+// replyTokens splits the canned reply into word tokens, skipping any already
+// given in prefix and truncating to maxTokens with finish_reason "length" so
+// clients can exercise continuation.
+func replyTokens(prompt, prefix string, maxTokens int) ([]string, string) {
+	tokens := strings.SplitAfter(Reply(prompt), " ")
+	for len(tokens) > 0 && strings.HasPrefix(prefix, tokens[0]) {
+		prefix = prefix[len(tokens[0]):]
+		tokens = tokens[1:]
+	}
+	if maxTokens > 0 && len(tokens) > maxTokens {
+		return tokens[:maxTokens], "length"
+	}
+	return tokens, "stop"
+}
+
+// contentText flattens string or content-part message content to text.
+func contentText(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		var parts []string
+		for _, part := range v {
+			if m, ok := part.(map[string]any); ok {
+				if text, ok := m["text"].(string); ok {
+					parts = append(parts, text)
+				}
+			} else if s, ok := part.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, "")
+	}
+	return ""
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": http.StatusBadRequest, "message": msg, "type": "invalid_request_error"},
+	})
+}
@@ -0,0 +1,146 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+func TestEnabled(t *testing.T) {
+	t.Setenv(EnvVar, "")
+	if Enabled() {
+		t.Error("Enabled() = true with variable unset")
+	}
+	t.Setenv(EnvVar, "1")
+	if !Enabled() {
+		t.Error("Enabled() = false with variable set to 1")
+	}
+}
+
+func TestStartServesHealth(t *testing.T) {
+	b, err := Start("127.0.0.1:0", "test/model:Q4_K_M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	if err := server.NewAPIClientFromURL("http://" + b.Addr()).Health(); err != nil {
+		t.Errorf("Health() error = %v", err)
+	}
+}
+
+func TestChatCompletion(t *testing.T) {
+	srv := httptest.NewServer(Handler("test/model:Q4_K_M"))
+	defer srv.Close()
+	api := server.NewAPIClientFromURL(srv.URL)
+
+	req := &server.ChatCompletionRequest{
+		Model:    "test/model:Q4_K_M",
+		Messages: []server.ChatMessage{{Role: "user", Content: "hello there"}},
+	}
+
+	resp, err := api.ChatCompletion(req)
+	if err != nil {
+		t.Fatalf("ChatCompletion() error = %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != Reply("hello there") {
+		t.Errorf("ChatCompletion() = %q, want %q", got, Reply("hello there"))
+	}
+
+	req.Stream = true
+	var streamed strings.Builder
+	err = api.StreamChatCompletion(context.Background(), req, server.StreamCallback{
+		ContentCallback: func(s string) { streamed.WriteString(s) },
+	})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion() error = %v", err)
+	}
+	if streamed.String() != Reply("hello there") {
+		t.Errorf("streamed = %q, want %q", streamed.String(), Reply("hello there"))
+	}
+}
+
+func TestChatCompletionContinued(t *testing.T) {
+	srv := httptest.NewServer(Handler("test/model:Q4_K_M"))
+	defer srv.Close()
+	api := server.NewAPIClientFromURL(srv.URL)
+
+	// Two tokens per request forces several continuations
+	req := &server.ChatCompletionRequest{
+		Messages:  []server.ChatMessage{{Role: "user", Content: "a b c"}},
+		Stream:    true,
+		MaxTokens: 2,
+	}
+
+	var streamed strings.Builder
+	err := api.StreamChatCompletionContinued(context.Background(), req, server.StreamCallback{
+		ContentCallback: func(s string) { streamed.WriteString(s) },
+	}, 5)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionContinued() error = %v", err)
+	}
+	if streamed.String() != Reply("a b c") {
+		t.Errorf("streamed = %q, want %q", streamed.String(), Reply("a b c"))
+	}
+}
+
+func TestReplyTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		maxTokens  int
+		want       []string
+		wantFinish string
+	}{
+		{"full reply", "", 0, []string{"Mock ", "response ", "to: ", "hi"}, "stop"},
+		{"truncated", "", 2, []string{"Mock ", "response "}, "length"},
+		{"continued", "Mock response ", 0, []string{"to: ", "hi"}, "stop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, finish := replyTokens("hi", tt.prefix, tt.maxTokens)
+			if !slices.Equal(got, tt.want) || finish != tt.wantFinish {
+				t.Errorf("replyTokens() = %q, %s, want %q, %s", got, finish, tt.want, tt.wantFinish)
+			}
+		})
+	}
+}
+
+func TestEmbeddings(t *testing.T) {
+	srv := httptest.NewServer(Handler("test/embed:Q8_0"))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v1/embeddings", "application/json",
+		bytes.NewBufferString(`{"input": ["one", "two"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data []struct {
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Data) != 2 {
+		t.Fatalf("got %d embeddings, want 2", len(body.Data))
+	}
+	if !slices.Equal(body.Data[0].Embedding, Embedding("one")) {
+		t.Errorf("embedding = %v, want %v", body.Data[0].Embedding, Embedding("one"))
+	}
+	if slices.Equal(Embedding("one"), Embedding("two")) {
+		t.Error("different inputs should have different embeddings")
+	}
+}
@@ -0,0 +1,173 @@
+// Package options decides which value of each llama-server option wins.
+//
+// Options can be set in many places: registry sampler presets, llamacpp.options
+// in config, per-model overrides in llamacpp.model_options, personas, command
+// line flags and /set, API requests, and adjustments the server makes at load
+// time. Resolve merges them in one fixed order, lowest priority first, and
+// records where each value came from so it can be shown to the user.
+package options
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Source is where a resolved option's value came from. Later sources take
+// precedence over earlier ones.
+type Source int
+
+const (
+	SourceDefault Source = iota // Not set anywhere; llama-server's default applies
+	SourcePreset                // Sampler preset for the model's family
+	SourceConfig                // llamacpp.options
+	SourceModel                 // llamacpp.model_options for this model
+	SourcePersona               // The persona's options
+	SourceFlag                  // Command-line flags, or /set in chat
+	SourceRequest               // Options sent with an API request, already merged by the client
+	SourceServer                // Set by the server at load: power saver, memory guard, GPU layer fitting
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourcePreset:
+		return "preset"
+	case SourceConfig:
+		return "config"
+	case SourceModel:
+		return "model"
+	case SourcePersona:
+		return "persona"
+	case SourceFlag:
+		return "flag"
+	case SourceRequest:
+		return "request"
+	case SourceServer:
+		return "server"
+	default:
+		return "default"
+	}
+}
+
+// Layers holds the options from each source. Nil or missing layers are
+// skipped; keys may use underscores or hyphens.
+type Layers struct {
+	Preset  map[string]any
+	Config  map[string]any
+	Model   map[string]any
+	Persona map[string]any
+	Flags   map[string]any
+	Request map[string]any
+	Server  map[string]any
+}
+
+// Value is one resolved option
+type Value struct {
+	Key    string
+	Value  any
+	Source Source
+}
+
+// Resolved is the result of merging layers: each option's winning value
+type Resolved struct {
+	values map[string]Value
+}
+
+// Resolve merges the layers, each overriding the ones before it
+func Resolve(l Layers) Resolved {
+	r := Resolved{values: make(map[string]Value)}
+	for _, layer := range []struct {
+		options map[string]any
+		source  Source
+	}{
+		{l.Preset, SourcePreset},
+		{l.Config, SourceConfig},
+		{l.Model, SourceModel},
+		{l.Persona, SourcePersona},
+		{l.Flags, SourceFlag},
+		{l.Request, SourceRequest},
+		{l.Server, SourceServer},
+	} {
+		for key, val := range layer.options {
+			key = NormalizeKey(key)
+			r.values[key] = Value{Key: key, Value: val, Source: layer.source}
+		}
+	}
+	return r
+}
+
+// NormalizeKey puts an option name in llama-server's command-line form,
+// with hyphens rather than underscores
+func NormalizeKey(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// Get returns the resolved option for key
+func (r Resolved) Get(key string) (Value, bool) {
+	v, ok := r.values[NormalizeKey(key)]
+	return v, ok
+}
+
+// Source returns where key's value came from, SourceDefault when unset
+func (r Resolved) Source(key string) Source {
+	return r.values[NormalizeKey(key)].Source
+}
+
+// Int returns key's value as an int, or 0 when unset or not a number
+func (r Resolved) Int(key string) int {
+	switch v := r.values[NormalizeKey(key)].Value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// Float returns key's value as a float64, or 0 when unset or not a number
+func (r Resolved) Float(key string) float64 {
+	switch v := r.values[NormalizeKey(key)].Value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// Map returns the resolved values by key, ready to pass to llama-server
+func (r Resolved) Map() map[string]any {
+	m := make(map[string]any, len(r.values))
+	for key, v := range r.values {
+		m[key] = v.Value
+	}
+	return m
+}
+
+// Sources returns where each resolved value came from, by key
+func (r Resolved) Sources() map[string]string {
+	m := make(map[string]string, len(r.values))
+	for key, v := range r.values {
+		m[key] = v.Source.String()
+	}
+	return m
+}
+
+// Values returns every resolved option, sorted by key
+func (r Resolved) Values() []Value {
+	keys := slices.Sorted(maps.Keys(r.values))
+	values := make([]Value, len(keys))
+	for i, key := range keys {
+		values[i] = r.values[key]
+	}
+	return values
+}
+
+// Len returns how many options are set
+func (r Resolved) Len() int {
+	return len(r.values)
+}
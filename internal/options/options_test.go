@@ -0,0 +1,223 @@
+package options
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// layerSetters set one layer of Layers, in priority order
+var layerSetters = []struct {
+	source Source
+	set    func(l *Layers, m map[string]any)
+}{
+	{SourcePreset, func(l *Layers, m map[string]any) { l.Preset = m }},
+	{SourceConfig, func(l *Layers, m map[string]any) { l.Config = m }},
+	{SourceModel, func(l *Layers, m map[string]any) { l.Model = m }},
+	{SourcePersona, func(l *Layers, m map[string]any) { l.Persona = m }},
+	{SourceFlag, func(l *Layers, m map[string]any) { l.Flags = m }},
+	{SourceRequest, func(l *Layers, m map[string]any) { l.Request = m }},
+	{SourceServer, func(l *Layers, m map[string]any) { l.Server = m }},
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	// Every pair of layers: the later one wins
+	for i, low := range layerSetters {
+		for _, high := range layerSetters[i+1:] {
+			t.Run(low.source.String()+" under "+high.source.String(), func(t *testing.T) {
+				var l Layers
+				low.set(&l, map[string]any{"ctx-size": 1024, "threads": 4})
+				high.set(&l, map[string]any{"ctx-size": 8192})
+				r := Resolve(l)
+
+				if v, _ := r.Get("ctx-size"); v.Value != 8192 || v.Source != high.source {
+					t.Errorf("ctx-size = %+v, want 8192 from %s", v, high.source)
+				}
+				// Keys the higher layer doesn't set come from the lower one
+				if v, _ := r.Get("threads"); v.Value != 4 || v.Source != low.source {
+					t.Errorf("threads = %+v, want 4 from %s", v, low.source)
+				}
+			})
+		}
+	}
+}
+
+func TestResolveAllLayers(t *testing.T) {
+	l := Layers{
+		Preset:  map[string]any{"temp": 0.6, "top-k": 20, "top-p": 0.95, "min-p": 0.0, "ctx-size": 1, "threads": 1, "gpu-layers": 1},
+		Config:  map[string]any{"top-k": 40, "top-p": 0.9, "min-p": 0.05, "ctx-size": 2, "threads": 2, "gpu-layers": 2},
+		Model:   map[string]any{"top-p": 0.8, "min-p": 0.1, "ctx-size": 3, "threads": 3, "gpu-layers": 3},
+		Persona: map[string]any{"min-p": 0.2, "ctx-size": 4, "threads": 4, "gpu-layers": 4},
+		Flags:   map[string]any{"ctx-size": 5, "threads": 5, "gpu-layers": 5},
+		Request: map[string]any{"threads": 6, "gpu-layers": 6},
+		Server:  map[string]any{"gpu-layers": 7},
+	}
+	r := Resolve(l)
+
+	tests := []struct {
+		key    string
+		value  any
+		source Source
+	}{
+		{"temp", 0.6, SourcePreset},
+		{"top-k", 40, SourceConfig},
+		{"top-p", 0.8, SourceModel},
+		{"min-p", 0.2, SourcePersona},
+		{"ctx-size", 5, SourceFlag},
+		{"threads", 6, SourceRequest},
+		{"gpu-layers", 7, SourceServer},
+	}
+	for _, tt := range tests {
+		v, ok := r.Get(tt.key)
+		if !ok || v.Value != tt.value || v.Source != tt.source {
+			t.Errorf("%s = %+v, want %v from %s", tt.key, v, tt.value, tt.source)
+		}
+	}
+	if r.Len() != len(tests) {
+		t.Errorf("Len() = %d, want %d", r.Len(), len(tests))
+	}
+
+	// Layers are read, never changed
+	if l.Config["top-k"] != 40 || len(l.Server) != 1 {
+		t.Error("Resolve changed its layers")
+	}
+}
+
+func TestResolveEmpty(t *testing.T) {
+	r := Resolve(Layers{Config: map[string]any{}})
+	if r.Len() != 0 || len(r.Map()) != 0 || len(r.Values()) != 0 {
+		t.Errorf("Resolve(empty) = %v", r.Map())
+	}
+	if _, ok := r.Get("temp"); ok {
+		t.Error("Get() found an unset option")
+	}
+	if r.Source("temp") != SourceDefault {
+		t.Errorf("Source() = %s, want default", r.Source("temp"))
+	}
+}
+
+func TestResolveNormalizesKeys(t *testing.T) {
+	r := Resolve(Layers{
+		Config:  map[string]any{"ctx_size": 2048, "cache-type-k": "f16"},
+		Persona: map[string]any{"ctx-size": 4096, "cache_type_k": "q8_0"},
+	})
+
+	if v, _ := r.Get("ctx-size"); v.Value != 4096 || v.Source != SourcePersona {
+		t.Errorf("ctx-size = %+v", v)
+	}
+	if v, _ := r.Get("cache_type_k"); v.Value != "q8_0" || v.Key != "cache-type-k" {
+		t.Errorf("cache_type_k = %+v", v)
+	}
+	want := map[string]any{"ctx-size": 4096, "cache-type-k": "q8_0"}
+	if got := r.Map(); !maps.Equal(got, want) {
+		t.Errorf("Map() = %v, want %v", got, want)
+	}
+}
+
+func TestResolvedConversions(t *testing.T) {
+	r := Resolve(Layers{Config: map[string]any{
+		"ctx-size":   8192,
+		"threads":    float64(6), // JSON numbers
+		"batch-size": int64(512),
+		"temp":       0.7,
+		"top-k":      40,
+		"flash-attn": "on",
+	}})
+
+	ints := map[string]int{"ctx-size": 8192, "threads": 6, "batch-size": 512, "temp": 0, "flash-attn": 0, "unset": 0}
+	for key, want := range ints {
+		if got := r.Int(key); got != want {
+			t.Errorf("Int(%s) = %d, want %d", key, got, want)
+		}
+	}
+	floats := map[string]float64{"temp": 0.7, "top-k": 40, "batch-size": 512, "flash-attn": 0, "unset": 0}
+	for key, want := range floats {
+		if got := r.Float(key); got != want {
+			t.Errorf("Float(%s) = %g, want %g", key, got, want)
+		}
+	}
+}
+
+func TestResolvedValuesAndSources(t *testing.T) {
+	r := Resolve(Layers{
+		Preset: map[string]any{"top-k": 20},
+		Config: map[string]any{"temp": 0.7},
+		Flags:  map[string]any{"ctx-size": 4096},
+	})
+
+	values := r.Values()
+	var keys []string
+	for _, v := range values {
+		keys = append(keys, v.Key)
+	}
+	if len(keys) != 3 || keys[0] != "ctx-size" || keys[1] != "temp" || keys[2] != "top-k" {
+		t.Errorf("Values() keys = %v, want sorted", keys)
+	}
+
+	want := map[string]string{"ctx-size": "flag", "temp": "config", "top-k": "preset"}
+	if got := r.Sources(); !maps.Equal(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+}
+
+func TestSourceString(t *testing.T) {
+	want := map[Source]string{
+		SourceDefault: "default",
+		SourcePreset:  "preset",
+		SourceConfig:  "config",
+		SourceModel:   "model",
+		SourcePersona: "persona",
+		SourceFlag:    "flag",
+		SourceRequest: "request",
+		SourceServer:  "server",
+		Source(99):    "default",
+	}
+	for s, name := range want {
+		if s.String() != name {
+			t.Errorf("Source(%d).String() = %q, want %q", s, s.String(), name)
+		}
+	}
+}
+
+func TestResolverWithModel(t *testing.T) {
+	cfg := &config.Config{LlamaCpp: config.LlamaCpp{
+		Options: map[string]any{"temp": 0.9, "top-k": 40},
+		ModelOptions: map[string]map[string]any{
+			"Qwen3-8B-GGUF": {"temp": 0.6},
+		},
+	}}
+	persona := &config.Persona{Options: map[string]any{"top-k": 20}}
+
+	r := NewResolver(persona, cfg)
+	if got := r.ResolveFloat(0, "temp"); got != 0.9 {
+		t.Errorf("without a model, temp = %g, want the config's 0.9", got)
+	}
+
+	r = r.WithModel("unsloth/Qwen3-8B-GGUF:Q4_K_M")
+	if got := r.ResolveFloat(0, "temp"); got != 0.6 {
+		t.Errorf("temp = %g, want the per-model 0.6", got)
+	}
+	if got := r.ResolveFloat(0.3, "temp"); got != 0.3 {
+		t.Errorf("temp = %g, want the session's 0.3", got)
+	}
+	if got := r.ResolveInt(0, "top-k"); got != 20 {
+		t.Errorf("top-k = %d, want the persona's 20", got)
+	}
+
+	resolved := r.Resolve(map[string]any{"ctx-size": 4096})
+	want := map[string]string{"temp": "model", "top-k": "persona", "ctx-size": "flag"}
+	if got := resolved.Sources(); !maps.Equal(got, want) {
+		t.Errorf("Sources() = %v, want %v", got, want)
+	}
+}
+
+func TestResolverNilConfig(t *testing.T) {
+	r := NewResolver(nil, nil).WithModel("user/repo:Q4_K_M")
+	if got := r.Resolve(map[string]any{"temp": 0.5}).Float("temp"); got != 0.5 {
+		t.Errorf("temp = %g, want 0.5", got)
+	}
+	if got := r.GetConfigInt("ctx-size"); got != 0 {
+		t.Errorf("ctx-size = %d, want 0", got)
+	}
+}
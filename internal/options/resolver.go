@@ -2,10 +2,12 @@ package options
 
 import "github.com/nchapman/lleme/internal/config"
 
-// Resolver resolves option values with priority: session > persona > config.
+// Resolver resolves option values for a client building requests, with
+// priority: session > persona > per-model config > config.
 type Resolver struct {
 	Persona *config.Persona
 	Config  *config.Config
+	Model   string // Full model name, for llamacpp.model_options; empty to skip them
 }
 
 // NewResolver creates a new option resolver.
@@ -16,21 +18,42 @@ func NewResolver(persona *config.Persona, cfg *config.Config) *Resolver {
 	}
 }
 
-// ResolveFloat returns the first non-zero value from: sessionVal, persona, config.
+// WithModel returns a copy of the resolver that also applies the config's
+// per-model options for modelName.
+func (r *Resolver) WithModel(modelName string) *Resolver {
+	withModel := *r
+	withModel.Model = modelName
+	return &withModel
+}
+
+// Resolve merges session options, such as flags and /set values, over the
+// persona's and the config's.
+func (r *Resolver) Resolve(session map[string]any) Resolved {
+	l := Layers{Flags: session}
+	if r.Config != nil {
+		l.Config = r.Config.LlamaCpp.Options
+		if r.Model != "" {
+			l.Model = r.Config.LlamaCpp.ModelOverrides(r.Model)
+		}
+	}
+	if r.Persona != nil {
+		l.Persona = r.Persona.Options
+	}
+	return Resolve(l)
+}
+
+// ResolveFloat returns sessionVal if it's set, and otherwise the persona's
+// or config's value.
 // Note: Zero is treated as "not set", not as an explicit value.
 func (r *Resolver) ResolveFloat(sessionVal float64, key string) float64 {
 	if sessionVal != 0 {
 		return sessionVal
 	}
-	if r.Persona != nil {
-		if v := r.Persona.GetFloatOption(key, 0); v != 0 {
-			return v
-		}
-	}
-	return r.Config.LlamaCpp.GetFloatOption(key, 0)
+	return r.GetConfigFloat(key)
 }
 
-// ResolveInt returns the first non-zero value from: sessionVal, persona, config.
+// ResolveInt returns sessionVal if it's set, and otherwise the persona's or
+// config's value.
 func (r *Resolver) ResolveInt(sessionVal int, key string) int {
 	if sessionVal != 0 {
 		return sessionVal
@@ -38,27 +61,12 @@ func (r *Resolver) ResolveInt(sessionVal int, key string) int {
 	return r.GetConfigInt(key)
 }
 
-// GetConfigInt returns the first non-zero value from: persona, config.
+// GetConfigInt returns the persona's or config's value, ignoring the session.
 func (r *Resolver) GetConfigInt(key string) int {
-	if r.Persona != nil {
-		if val, ok := r.Persona.Options[key]; ok {
-			switch v := val.(type) {
-			case int:
-				return v
-			case float64:
-				return int(v)
-			}
-		}
-	}
-	return r.Config.LlamaCpp.GetIntOption(key, 0)
+	return r.Resolve(nil).Int(key)
 }
 
-// GetConfigFloat returns the first non-zero value from: persona, config.
+// GetConfigFloat returns the persona's or config's value, ignoring the session.
 func (r *Resolver) GetConfigFloat(key string) float64 {
-	if r.Persona != nil {
-		if v := r.Persona.GetFloatOption(key, 0); v != 0 {
-			return v
-		}
-	}
-	return r.Config.LlamaCpp.GetFloatOption(key, 0)
+	return r.Resolve(nil).Float(key)
 }
@@ -0,0 +1,59 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Migrate moves an existing ~/.lleme into the XDG directories, so later
+// runs pick up the XDG layout. It does nothing when LLEME_HOME is set or
+// there's no ~/.lleme, and reports whether anything moved.
+//
+// Entries are renamed, not copied, so models aren't duplicated. If any
+// move fails, those already made are put back and ~/.lleme stays in use.
+func Migrate() (bool, error) {
+	if os.Getenv("LLEME_HOME") != "" || !isDir(Legacy()) {
+		return false, nil
+	}
+	legacy := singleLayout(Legacy())
+	dest := xdgLayout()
+
+	entries, err := os.ReadDir(Legacy())
+	if err != nil {
+		return false, err
+	}
+
+	type move struct{ from, to string }
+	var moved []move
+	rollback := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			_ = os.Rename(moved[i].to, moved[i].from)
+		}
+	}
+
+	for _, entry := range entries {
+		from := legacy.resolve(entry.Name())
+		to := dest.resolve(entry.Name())
+		if _, err := os.Lstat(to); err == nil {
+			rollback()
+			return false, fmt.Errorf("can't move %s: %s already exists", from, to)
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			rollback()
+			return false, err
+		}
+		if err := os.Rename(from, to); err != nil {
+			rollback()
+			return false, fmt.Errorf("failed to move %s: %w", from, err)
+		}
+		moved = append(moved, move{from, to})
+	}
+
+	if err := os.Remove(Legacy()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		rollback()
+		return false, err
+	}
+	return len(moved) > 0, nil
+}
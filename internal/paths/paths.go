@@ -0,0 +1,179 @@
+// Package paths resolves where lleme keeps its files on disk.
+//
+// Setting LLEME_HOME puts everything in that one directory. Otherwise
+// files are split the XDG way: settings under XDG_CONFIG_HOME, models,
+// binaries, and saved personas under XDG_DATA_HOME, caches under
+// XDG_CACHE_HOME, and logs, pid files, and history under XDG_STATE_HOME,
+// each in a "lleme" subdirectory. An existing ~/.lleme from older
+// releases keeps being used until Migrate moves it.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	appName    = "lleme"
+	legacyName = ".lleme"
+	configFile = "config.yaml"
+
+	modelsDir        = "models"
+	binDir           = "bin"
+	cacheDir         = "cache"
+	logsDir          = "logs"
+	pidsDir          = "pids"
+	historyDir       = "history"
+	conversationsDir = "conversations"
+	personasDir      = "personas"
+	promptsDir       = "prompts"
+)
+
+// Home returns the user's home directory.
+func Home() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "."
+	}
+	return home
+}
+
+// Legacy returns ~/.lleme, where releases before the XDG layout kept
+// everything.
+func Legacy() string {
+	return filepath.Join(Home(), legacyName)
+}
+
+// layout is where each kind of file goes.
+type layout struct {
+	config, data, cache, state string
+}
+
+// singleLayout keeps everything in dir, the way ~/.lleme always has.
+func singleLayout(dir string) layout {
+	return layout{config: dir, data: dir, cache: filepath.Join(dir, cacheDir), state: dir}
+}
+
+// xdgLayout splits files across the XDG base directories.
+func xdgLayout() layout {
+	return layout{
+		config: xdg("XDG_CONFIG_HOME", ".config"),
+		data:   xdg("XDG_DATA_HOME", filepath.Join(".local", "share")),
+		cache:  xdg("XDG_CACHE_HOME", ".cache"),
+		state:  xdg("XDG_STATE_HOME", filepath.Join(".local", "state")),
+	}
+}
+
+// xdg returns $env/lleme, falling back to fallback (relative to the home
+// directory) when the variable is unset or not absolute, as the spec asks.
+func xdg(env, fallback string) string {
+	dir := os.Getenv(env)
+	if dir == "" || !filepath.IsAbs(dir) {
+		dir = filepath.Join(Home(), fallback)
+	}
+	return filepath.Join(dir, appName)
+}
+
+// current returns the layout in use: LLEME_HOME if set, then ~/.lleme if
+// it's still around, then XDG.
+func current() layout {
+	if dir := os.Getenv("LLEME_HOME"); dir != "" {
+		return singleLayout(dir)
+	}
+	if isDir(Legacy()) {
+		return singleLayout(Legacy())
+	}
+	return xdgLayout()
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// resolve maps a slash-separated path in the single-directory layout to
+// where it goes in l.
+func (l layout) resolve(rel string) string {
+	top, rest, _ := strings.Cut(rel, "/")
+	var dir string
+	switch {
+	case top == cacheDir:
+		return filepath.Join(l.cache, filepath.FromSlash(rest))
+	case top == logsDir, top == pidsDir, top == historyDir:
+		dir = l.state
+	case strings.HasPrefix(top, configFile):
+		dir = l.config // config.yaml and the backups migrations leave beside it
+	default:
+		dir = l.data
+	}
+	return filepath.Join(dir, filepath.FromSlash(rel))
+}
+
+// Resolve maps a slash-separated path in the single-directory layout, such
+// as "models/user/repo/metadata.yaml", to where it lives now.
+func Resolve(rel string) string {
+	return current().resolve(rel)
+}
+
+// ConfigDir returns the directory holding config.yaml.
+func ConfigDir() string {
+	return current().config
+}
+
+// DataDir returns the directory models, binaries, and saved personas,
+// prompts, and conversations live under.
+func DataDir() string {
+	return current().data
+}
+
+// StateDir returns the directory logs, pid files, and input history live
+// under.
+func StateDir() string {
+	return current().state
+}
+
+func Config() string {
+	return filepath.Join(ConfigDir(), configFile)
+}
+
+func Models() string {
+	return filepath.Join(DataDir(), modelsDir)
+}
+
+func Bin() string {
+	return filepath.Join(DataDir(), binDir)
+}
+
+// Cache returns the directory for files that can be rebuilt or downloaded
+// again.
+func Cache() string {
+	return current().cache
+}
+
+func Logs() string {
+	return filepath.Join(StateDir(), logsDir)
+}
+
+func Pids() string {
+	return filepath.Join(StateDir(), pidsDir)
+}
+
+func History() string {
+	return filepath.Join(StateDir(), historyDir)
+}
+
+// Conversations returns the directory chats saved with /save are kept in.
+func Conversations() string {
+	return filepath.Join(DataDir(), conversationsDir)
+}
+
+// Personas returns the path to the personas directory.
+func Personas() string {
+	return filepath.Join(DataDir(), personasDir)
+}
+
+// Prompts returns the path to the saved system prompts directory.
+func Prompts() string {
+	return filepath.Join(DataDir(), promptsDir)
+}
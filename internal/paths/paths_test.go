@@ -0,0 +1,201 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useHome points the home directory at a temp dir and clears every
+// variable that picks a layout.
+func useHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range []string{"LLEME_HOME", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(env, "")
+	}
+	return home
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLlemeHome(t *testing.T) {
+	useHome(t)
+	dir := t.TempDir()
+	t.Setenv("LLEME_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", t.TempDir()) // LLEME_HOME wins
+
+	tests := map[string]string{
+		Config():        filepath.Join(dir, "config.yaml"),
+		Models():        filepath.Join(dir, "models"),
+		Bin():           filepath.Join(dir, "bin"),
+		Cache():         filepath.Join(dir, "cache"),
+		Logs():          filepath.Join(dir, "logs"),
+		Pids():          filepath.Join(dir, "pids"),
+		History():       filepath.Join(dir, "history"),
+		Conversations(): filepath.Join(dir, "conversations"),
+		Personas():      filepath.Join(dir, "personas"),
+		Prompts():       filepath.Join(dir, "prompts"),
+	}
+	for got, want := range tests {
+		if got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestLegacyHome(t *testing.T) {
+	home := useHome(t)
+	if err := os.Mkdir(filepath.Join(home, ".lleme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := Config(), filepath.Join(home, ".lleme", "config.yaml"); got != want {
+		t.Errorf("Config() = %s, want %s", got, want)
+	}
+	if got, want := Cache(), filepath.Join(home, ".lleme", "cache"); got != want {
+		t.Errorf("Cache() = %s, want %s", got, want)
+	}
+}
+
+func TestXDGLayout(t *testing.T) {
+	home := useHome(t)
+
+	t.Run("defaults", func(t *testing.T) {
+		tests := map[string]string{
+			Config(): filepath.Join(home, ".config", "lleme", "config.yaml"),
+			Models(): filepath.Join(home, ".local", "share", "lleme", "models"),
+			Cache():  filepath.Join(home, ".cache", "lleme"),
+			Logs():   filepath.Join(home, ".local", "state", "lleme", "logs"),
+		}
+		for got, want := range tests {
+			if got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		}
+	})
+
+	t.Run("variables", func(t *testing.T) {
+		root := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+		t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+		t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "cache"))
+		t.Setenv("XDG_STATE_HOME", "relative") // Ignored, as the spec asks
+
+		tests := map[string]string{
+			Config(): filepath.Join(root, "config", "lleme", "config.yaml"),
+			Bin():    filepath.Join(root, "data", "lleme", "bin"),
+			Cache():  filepath.Join(root, "cache", "lleme"),
+			Pids():   filepath.Join(home, ".local", "state", "lleme", "pids"),
+		}
+		for got, want := range tests {
+			if got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		}
+	})
+}
+
+func TestResolve(t *testing.T) {
+	home := useHome(t)
+
+	tests := map[string]string{
+		"config.yaml":                 filepath.Join(home, ".config", "lleme", "config.yaml"),
+		"config.yaml.v0.bak":          filepath.Join(home, ".config", "lleme", "config.yaml.v0.bak"),
+		"models/u/r/metadata.yaml":    filepath.Join(home, ".local", "share", "lleme", "models", "u", "r", "metadata.yaml"),
+		"cache/catalog.json":          filepath.Join(home, ".cache", "lleme", "catalog.json"),
+		"cache":                       filepath.Join(home, ".cache", "lleme"),
+		"history/model":               filepath.Join(home, ".local", "state", "lleme", "history", "model"),
+		"personas/reviewer.yaml":      filepath.Join(home, ".local", "share", "lleme", "personas", "reviewer.yaml"),
+		"something-new/whatever.json": filepath.Join(home, ".local", "share", "lleme", "something-new", "whatever.json"),
+	}
+	for rel, want := range tests {
+		if got := Resolve(rel); got != want {
+			t.Errorf("Resolve(%q) = %s, want %s", rel, got, want)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	home := useHome(t)
+	legacy := filepath.Join(home, ".lleme")
+	for _, rel := range []string{"config.yaml", "models/u/r/Q4_K_M.gguf", "bin/version.json", "cache/catalog.json", "logs/proxy.log", "pids/proxy.json"} {
+		writeFile(t, filepath.Join(legacy, filepath.FromSlash(rel)))
+	}
+
+	moved, err := Migrate()
+	if err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if !moved {
+		t.Error("Migrate() = false, want true")
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("~/.lleme still exists after migrating: %v", err)
+	}
+
+	for _, path := range []string{
+		Config(),
+		filepath.Join(Models(), "u", "r", "Q4_K_M.gguf"),
+		filepath.Join(Bin(), "version.json"),
+		filepath.Join(Cache(), "catalog.json"),
+		filepath.Join(Logs(), "proxy.log"),
+		filepath.Join(Pids(), "proxy.json"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s after migrating: %v", path, err)
+		}
+	}
+	if got, want := Config(), filepath.Join(home, ".config", "lleme", "config.yaml"); got != want {
+		t.Errorf("Config() = %s, want %s", got, want)
+	}
+
+	moved, err = Migrate()
+	if err != nil || moved {
+		t.Errorf("second Migrate() = %v, %v; want false, nil", moved, err)
+	}
+}
+
+func TestMigrateConflictRollsBack(t *testing.T) {
+	home := useHome(t)
+	legacy := filepath.Join(home, ".lleme")
+	writeFile(t, filepath.Join(legacy, "bin", "version.json"))
+	writeFile(t, filepath.Join(legacy, "config.yaml"))
+	writeFile(t, filepath.Join(legacy, "models", "u", "r", "Q4_K_M.gguf"))
+	writeFile(t, filepath.Join(home, ".local", "share", "lleme", "models", "other"))
+
+	if _, err := Migrate(); err == nil {
+		t.Fatal("Migrate() succeeded with an existing destination")
+	}
+	for _, rel := range []string{"bin/version.json", "config.yaml", "models/u/r/Q4_K_M.gguf"} {
+		if _, err := os.Stat(filepath.Join(legacy, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("%s not restored to ~/.lleme: %v", rel, err)
+		}
+	}
+	if got, want := Config(), filepath.Join(legacy, "config.yaml"); got != want {
+		t.Errorf("Config() = %s, want %s", got, want)
+	}
+}
+
+func TestMigrateSkipsLlemeHome(t *testing.T) {
+	home := useHome(t)
+	writeFile(t, filepath.Join(home, ".lleme", "config.yaml"))
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	moved, err := Migrate()
+	if err != nil || moved {
+		t.Errorf("Migrate() = %v, %v; want false, nil", moved, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".lleme", "config.yaml")); err != nil {
+		t.Errorf("~/.lleme was touched: %v", err)
+	}
+}
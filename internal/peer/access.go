@@ -0,0 +1,99 @@
+package peer
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// AccessList decides which machines may use the peer server, by address.
+// Deny entries win over allow entries, and an empty allow list admits
+// everyone not denied.
+type AccessList struct {
+	allow, deny []netip.Prefix
+}
+
+// ParseAccessList builds an access list from peer.allow and peer.deny,
+// whose entries are addresses ("192.168.1.20") or CIDR ranges
+// ("192.168.1.0/24").
+func ParseAccessList(allow, deny []string) (*AccessList, error) {
+	a := &AccessList{}
+	var err error
+	if a.allow, err = parsePrefixes("peer.allow", allow); err != nil {
+		return nil, err
+	}
+	if a.deny, err = parsePrefixes("peer.deny", deny); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func parsePrefixes(key string, entries []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s entry %q: %w", key, entry, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: want an IP address or CIDR range", key, entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Allowed reports whether addr may use the peer server
+func (a *AccessList) Allowed(addr netip.Addr) bool {
+	if a == nil {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, p := range a.deny {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	if len(a.allow) == 0 {
+		return true
+	}
+	for _, p := range a.allow {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Restrict limits the peer server to the machines access allows. Call
+// before Start.
+func (s *Server) Restrict(access *AccessList) {
+	s.access = access
+}
+
+// checkAccess refuses requests from machines the access list doesn't allow
+func (s *Server) checkAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.access != nil {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			addr, perr := netip.ParseAddr(host)
+			if err != nil || perr != nil || !s.access.Allowed(addr) {
+				logs.Debug("Refused peer request", "remote", r.RemoteAddr, "path", r.URL.Path)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -7,7 +7,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/clock"
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
@@ -31,17 +33,29 @@ type CachedPeer struct {
 type PeerCache struct {
 	mu    sync.RWMutex
 	peers map[string]*CachedPeer // key: "host:port"
+	path  string
+	fs    fileutil.FS
+	clock clock.Clock
 }
 
 // CacheFilePath returns the path to the peer cache file
 func CacheFilePath() string {
-	return filepath.Join(config.CachePath(), "peers.yaml")
+	return filepath.Join(paths.Cache(), "peers.yaml")
 }
 
 // NewPeerCache creates a new peer cache
 func NewPeerCache() *PeerCache {
+	return NewPeerCacheWith(CacheFilePath(), fileutil.OS, clock.System)
+}
+
+// NewPeerCacheWith creates a peer cache kept at path on fsys, which ages
+// peers by clk
+func NewPeerCacheWith(path string, fsys fileutil.FS, clk clock.Clock) *PeerCache {
 	return &PeerCache{
 		peers: make(map[string]*CachedPeer),
+		path:  path,
+		fs:    fsys,
+		clock: clk,
 	}
 }
 
@@ -50,7 +64,7 @@ func (c *PeerCache) Load() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	data, err := os.ReadFile(CacheFilePath())
+	data, err := c.fs.ReadFile(c.path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			c.peers = make(map[string]*CachedPeer)
@@ -73,17 +87,10 @@ func (c *PeerCache) Save() error {
 	}
 
 	// Ensure cache directory exists
-	cachePath := CacheFilePath()
-	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
-		return err
-	}
-
-	// Atomic write
-	tmpPath := cachePath + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	if err := c.fs.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
 		return err
 	}
-	return os.Rename(tmpPath, cachePath)
+	return c.fs.WriteFile(c.path, data, 0644)
 }
 
 // Update adds or updates peers in the cache
@@ -91,7 +98,7 @@ func (c *PeerCache) Update(peers []*Peer) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	for _, p := range peers {
 		key := peerKey(p.Host, p.Port)
 		c.peers[key] = &CachedPeer{
@@ -108,7 +115,7 @@ func (c *PeerCache) GetFresh() []*Peer {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	cutoff := time.Now().Add(-PeerTTL)
+	cutoff := c.clock.Now().Add(-PeerTTL)
 	var peers []*Peer
 
 	for _, cp := range c.peers {
@@ -130,7 +137,7 @@ func (c *PeerCache) Cleanup() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	cutoff := time.Now().Add(-PeerTTL)
+	cutoff := c.clock.Now().Add(-PeerTTL)
 	for key, cp := range c.peers {
 		if cp.LastSeen.Before(cutoff) {
 			delete(c.peers, key)
@@ -1,12 +1,11 @@
 package peer
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/nchapman/lleme/internal/clock"
+	"github.com/nchapman/lleme/internal/fileutil"
 )
 
 func TestNewPeerCache(t *testing.T) {
@@ -48,53 +47,35 @@ func TestPeerCacheUpdate(t *testing.T) {
 }
 
 func TestPeerCacheGetFresh(t *testing.T) {
-	cache := NewPeerCache()
+	clk := clock.NewFake(time.Now())
+	cache := NewPeerCacheWith("/cache/peers.yaml", fileutil.NewMemFS(), clk)
 
-	// Add fresh peer
 	cache.Update([]*Peer{
 		{Host: "192.168.1.100", Port: 11313, Version: "0.1.0"},
 	})
-
-	fresh := cache.GetFresh()
-	if len(fresh) != 1 {
-		t.Errorf("expected 1 fresh peer, got %d", len(fresh))
-	}
-
-	// Manually inject stale peer (past TTL)
-	cache.mu.Lock()
-	cache.peers["192.168.1.200:11313"] = &CachedPeer{
-		Host:     "192.168.1.200",
-		Port:     11313,
-		Version:  "0.1.0",
-		LastSeen: time.Now().Add(-PeerTTL - time.Minute),
+	clk.Advance(PeerTTL - time.Minute)
+	cache.Update([]*Peer{
+		{Host: "192.168.1.101", Port: 11313, Version: "0.1.0"},
+	})
+	if fresh := cache.GetFresh(); len(fresh) != 2 {
+		t.Errorf("expected 2 fresh peers within the TTL, got %d", len(fresh))
 	}
-	cache.mu.Unlock()
 
-	// GetFresh should return only non-stale
-	fresh = cache.GetFresh()
-	if len(fresh) != 1 {
-		t.Errorf("expected 1 fresh peer after adding stale, got %d", len(fresh))
+	// The first peer goes stale once the TTL has passed since it was seen
+	clk.Advance(2 * time.Minute)
+	fresh := cache.GetFresh()
+	if len(fresh) != 1 || fresh[0].Host != "192.168.1.101" {
+		t.Errorf("expected only the recent peer to be fresh, got %v", fresh)
 	}
 }
 
 func TestPeerCacheCleanup(t *testing.T) {
-	cache := NewPeerCache()
+	clk := clock.NewFake(time.Now())
+	cache := NewPeerCacheWith("/cache/peers.yaml", fileutil.NewMemFS(), clk)
 
-	// Add fresh and stale peers
-	cache.mu.Lock()
-	cache.peers["192.168.1.100:11313"] = &CachedPeer{
-		Host:     "192.168.1.100",
-		Port:     11313,
-		Version:  "0.1.0",
-		LastSeen: time.Now(),
-	}
-	cache.peers["192.168.1.200:11313"] = &CachedPeer{
-		Host:     "192.168.1.200",
-		Port:     11313,
-		Version:  "0.1.0",
-		LastSeen: time.Now().Add(-PeerTTL - time.Minute),
-	}
-	cache.mu.Unlock()
+	cache.Update([]*Peer{{Host: "192.168.1.200", Port: 11313, Version: "0.1.0"}})
+	clk.Advance(PeerTTL + time.Minute)
+	cache.Update([]*Peer{{Host: "192.168.1.100", Port: 11313, Version: "0.1.0"}})
 
 	if len(cache.peers) != 2 {
 		t.Errorf("expected 2 peers before cleanup, got %d", len(cache.peers))
@@ -108,40 +89,30 @@ func TestPeerCacheCleanup(t *testing.T) {
 }
 
 func TestPeerCacheSaveLoad(t *testing.T) {
-	// Use a temp directory
-	tmpDir := t.TempDir()
-	tmpFile := filepath.Join(tmpDir, "peers.json")
+	fsys := fileutil.NewMemFS()
+	clk := clock.NewFake(time.Now())
 
-	// Create cache and add peers
-	cache := NewPeerCache()
+	cache := NewPeerCacheWith("/cache/peers.yaml", fsys, clk)
 	cache.Update([]*Peer{
 		{Host: "192.168.1.100", Port: 11313, Version: "0.1.0"},
 	})
-
-	// Save to temp file manually (since we can't override CacheFilePath)
-	cache.mu.RLock()
-	data, err := encodeCache(cache.peers)
-	cache.mu.RUnlock()
-	if err != nil {
-		t.Fatalf("failed to encode: %v", err)
-	}
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		t.Fatalf("failed to write: %v", err)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
 	}
 
-	// Read back and verify
-	data2, err := os.ReadFile(tmpFile)
-	if err != nil {
-		t.Fatalf("failed to read: %v", err)
+	loaded := NewPeerCacheWith("/cache/peers.yaml", fsys, clk)
+	if err := loaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
 	}
-
-	cache2 := NewPeerCache()
-	if err := decodeCache(data2, &cache2.peers); err != nil {
-		t.Fatalf("failed to decode: %v", err)
+	fresh := loaded.GetFresh()
+	if len(fresh) != 1 || fresh[0].Host != "192.168.1.100" || fresh[0].Version != "0.1.0" {
+		t.Errorf("expected the saved peer after load, got %v", fresh)
 	}
 
-	if len(cache2.peers) != 1 {
-		t.Errorf("expected 1 peer after load, got %d", len(cache2.peers))
+	// A missing cache file loads as empty
+	empty := NewPeerCacheWith("/elsewhere/peers.yaml", fsys, clk)
+	if err := empty.Load(); err != nil || len(empty.GetFresh()) != 0 {
+		t.Errorf("Load() of a missing file = %v with %d peers", err, len(empty.GetFresh()))
 	}
 }
 
@@ -163,12 +134,3 @@ func TestPeerKey(t *testing.T) {
 		}
 	}
 }
-
-// Helper functions for testing (avoid changing CacheFilePath)
-func encodeCache(peers map[string]*CachedPeer) ([]byte, error) {
-	return yaml.Marshal(peers)
-}
-
-func decodeCache(data []byte, peers *map[string]*CachedPeer) error {
-	return yaml.Unmarshal(data, peers)
-}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/nchapman/lleme/internal/compress"
+	"github.com/nchapman/lleme/internal/logs"
 	"github.com/nchapman/lleme/internal/version"
 )
 
@@ -29,7 +31,8 @@ func NewClient(peer *Peer) *Client {
 	return &Client{
 		peer: peer,
 		httpClient: &http.Client{
-			Timeout: ClientTimeout,
+			Transport: logs.Transport(compress.Transport(nil)),
+			Timeout:   ClientTimeout,
 		},
 	}
 }
@@ -67,9 +70,9 @@ func (c *Client) DownloadHash(hash, destPath string, progress DownloadProgressCa
 
 	// Use a client with no timeout for large downloads
 	downloadClient := &http.Client{
-		Transport: &http.Transport{
+		Transport: logs.Transport(&http.Transport{
 			ResponseHeaderTimeout: ClientTimeout,
-		},
+		}),
 	}
 
 	partialPath := destPath + ".partial"
@@ -97,6 +97,24 @@ func (d *Discovery) Start() error {
 	return nil
 }
 
+// DiscoveryStatus summarizes peer discovery for health checks
+type DiscoveryStatus struct {
+	Enabled     bool `json:"enabled"`
+	Advertising bool `json:"advertising"` // Registered over mDNS
+	Peers       int  `json:"peers"`
+}
+
+// Status reports whether discovery is running and how many peers it sees
+func (d *Discovery) Status() DiscoveryStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return DiscoveryStatus{
+		Enabled:     d.enabled,
+		Advertising: d.server != nil,
+		Peers:       len(d.peers),
+	}
+}
+
 // Stop shuts down mDNS registration and discovery
 func (d *Discovery) Stop() {
 	d.stopOnce.Do(func() {
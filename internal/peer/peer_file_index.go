@@ -7,15 +7,15 @@ import (
 	"strings"
 	"sync"
 
-	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/hf"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 	"gopkg.in/yaml.v3"
 )
 
 // PeerFileIndexPath returns the path to the persisted peer file index.
 func PeerFileIndexPath() string {
-	return filepath.Join(config.CachePath(), "peer_file_index.yaml")
+	return filepath.Join(paths.Cache(), "peer_file_index.yaml")
 }
 
 // PeerFileIndex maps SHA256 hashes to local file paths for peer sharing.
@@ -78,7 +78,7 @@ func (h *PeerFileIndex) Entries() map[string]string {
 // Call this after pulling or deleting models.
 func RebuildPeerFileIndex() error {
 	index := make(map[string]string)
-	modelsDir := config.ModelsPath()
+	modelsDir := paths.Models()
 
 	// Use WalkDir for better performance (avoids stat on every file)
 	err := filepath.WalkDir(modelsDir, func(path string, d os.DirEntry, err error) error {
@@ -103,7 +103,7 @@ func RebuildPeerFileIndex() error {
 		}
 
 		// Extract user/repo/quant from manifest path
-		// Path format: ~/.lleme/models/{user}/{repo}/{quant}-manifest.json
+		// Path format: <models>/{user}/{repo}/{quant}-manifest.json
 		rel, err := filepath.Rel(modelsDir, path)
 		if err != nil {
 			return nil
@@ -10,36 +10,46 @@ import (
 	"strings"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/compress"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 // Server handles peer-to-peer model sharing HTTP endpoints.
 // Uses hash-based requests for privacy - peers cannot list available models.
 type Server struct {
 	httpServer    *http.Server
+	mux           *http.ServeMux
 	port          int
 	peerFileIndex *PeerFileIndex
+	access        *AccessList // Machines allowed in; nil for all
 }
 
 // NewServer creates a new peer sharing server.
 func NewServer(port int) *Server {
 	s := &Server{
+		mux:           http.NewServeMux(),
 		port:          port,
 		peerFileIndex: NewPeerFileIndex(),
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/peer/sha256/", s.handleHashDownload)
+	s.mux.HandleFunc("/api/peer/sha256/", s.handleHashDownload)
 
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf("0.0.0.0:%d", port),
-		Handler: mux,
+		Handler: s.checkAccess(compress.JSON(s.mux)),
 	}
 
 	return s
 }
 
+// AllowSync lets 'lleme sync' on other machines list this machine's models
+// and push models to it. Call before Start.
+func (s *Server) AllowSync() {
+	s.mux.HandleFunc("/api/peer/sync/models", s.handleSyncModels)
+	s.mux.HandleFunc("/api/peer/sync/sha256/", s.handleSyncUpload)
+}
+
 // Start starts the peer server and loads the peer file index.
 func (s *Server) Start() error {
 	// Rebuild index if file doesn't exist, then load it
@@ -114,28 +124,30 @@ func (s *Server) handleHashDownload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify path is under models directory (defense in depth)
-	absFilePath, err := filepath.Abs(filePath)
+	// Verify path is under models directory (defense in depth), after
+	// symlinks, so a link in the store can't expose files elsewhere
+	resolved, err := filepath.EvalSymlinks(filePath)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
-	absModelsDir, err := filepath.Abs(config.ModelsPath())
-	if err != nil {
-		http.NotFound(w, r)
-		return
-	}
-	if !strings.HasPrefix(absFilePath, absModelsDir+string(filepath.Separator)) {
+	if !inModelStore(resolved) {
 		http.Error(w, "Invalid file path", http.StatusBadRequest)
 		return
 	}
 
-	// Get file info
-	info, err := os.Stat(filePath)
+	// Only regular files are served; never a directory listing
+	file, err := os.Open(resolved)
 	if err != nil {
 		http.NotFound(w, r)
 		return
 	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
 
 	// Set headers
 	w.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
@@ -147,5 +159,23 @@ func (s *Server) handleHashDownload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Serve the file with range support
-	http.ServeFile(w, r, filePath)
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// inModelStore reports whether resolved, a path with symlinks already
+// resolved, is inside the models directory
+func inModelStore(resolved string) bool {
+	modelsDir, err := filepath.EvalSymlinks(paths.Models())
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(resolved)
+	if err != nil {
+		return false
+	}
+	absModelsDir, err := filepath.Abs(modelsDir)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(absPath, absModelsDir+string(filepath.Separator))
 }
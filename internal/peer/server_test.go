@@ -3,11 +3,26 @@ package peer
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/nchapman/lleme/internal/paths"
 )
 
+// testModelsDir points the model store at a temp dir and returns it
+func testModelsDir(t *testing.T) string {
+	t.Helper()
+	t.Setenv("LLEME_HOME", t.TempDir())
+	modelsDir := paths.Models()
+	if err := os.MkdirAll(modelsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return modelsDir
+}
+
 func TestNewServer(t *testing.T) {
 	s := NewServer(11314)
 	if s == nil {
@@ -100,11 +115,7 @@ func TestHandleHashDownloadNotFound(t *testing.T) {
 }
 
 func TestHandleHashDownloadHEAD(t *testing.T) {
-	// Skip if models directory doesn't exist (can't test path traversal validation)
-	modelsDir := os.ExpandEnv("$HOME/.lleme/models")
-	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
-		t.Skip("No models directory, skipping integration test")
-	}
+	modelsDir := testModelsDir(t)
 
 	s := NewServer(11314)
 
@@ -147,11 +158,7 @@ func TestHandleHashDownloadHEAD(t *testing.T) {
 }
 
 func TestHandleHashDownloadGET(t *testing.T) {
-	// Skip if models directory doesn't exist
-	modelsDir := os.ExpandEnv("$HOME/.lleme/models")
-	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
-		t.Skip("No models directory, skipping integration test")
-	}
+	modelsDir := testModelsDir(t)
 
 	s := NewServer(11314)
 
@@ -183,11 +190,7 @@ func TestHandleHashDownloadGET(t *testing.T) {
 }
 
 func TestHandleHashDownloadCaseNormalization(t *testing.T) {
-	// Skip if models directory doesn't exist
-	modelsDir := os.ExpandEnv("$HOME/.lleme/models")
-	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
-		t.Skip("No models directory, skipping integration test")
-	}
+	modelsDir := testModelsDir(t)
 
 	s := NewServer(11314)
 
@@ -226,11 +229,7 @@ func TestHandleHashDownloadCaseNormalization(t *testing.T) {
 }
 
 func TestHandleHashDownloadFileNotExists(t *testing.T) {
-	// Skip if models directory doesn't exist
-	modelsDir := os.ExpandEnv("$HOME/.lleme/models")
-	if _, err := os.Stat(modelsDir); os.IsNotExist(err) {
-		t.Skip("No models directory, skipping integration test")
-	}
+	modelsDir := testModelsDir(t)
 
 	s := NewServer(11314)
 
@@ -265,3 +264,97 @@ func TestHandleHashDownloadPathTraversal(t *testing.T) {
 		t.Errorf("expected status 400 or 404 for path traversal attempt, got %d", w.Code)
 	}
 }
+
+func TestHandleHashDownloadOnlyRegularFilesInStore(t *testing.T) {
+	modelsDir := testModelsDir(t)
+	s := NewServer(11314)
+
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(modelsDir, "link.gguf")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	subdir := filepath.Join(modelsDir, "user", "repo")
+	if err := os.MkdirAll(subdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		path string
+		want int
+	}{
+		"symlink out of the store": {link, http.StatusBadRequest},
+		"directory":                {subdir, http.StatusNotFound},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			hash := strings.Repeat("3", 64)
+			s.peerFileIndex.index[hash] = tt.path
+			w := httptest.NewRecorder()
+			s.handleHashDownload(w, httptest.NewRequest(http.MethodGet, "/api/peer/sha256/"+hash, nil))
+			if w.Code != tt.want || strings.Contains(w.Body.String(), "secret") {
+				t.Errorf("got %d %q, want %d", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessList(t *testing.T) {
+	a, err := ParseAccessList([]string{"192.168.1.0/24", "10.0.0.5", "fd00::/8"}, []string{"192.168.1.66"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{
+		"192.168.1.20":        true,
+		"::ffff:192.168.1.20": true,
+		"192.168.1.66":        false, // Denied inside an allowed range
+		"10.0.0.5":            true,
+		"10.0.0.6":            false,
+		"fd12::1":             true,
+		"203.0.113.7":         false,
+	}
+	for addr, want := range tests {
+		if got := a.Allowed(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("Allowed(%s) = %v, want %v", addr, got, want)
+		}
+	}
+
+	denyOnly, _ := ParseAccessList(nil, []string{"10.0.0.0/8"})
+	if !denyOnly.Allowed(netip.MustParseAddr("192.168.1.20")) || denyOnly.Allowed(netip.MustParseAddr("10.1.2.3")) {
+		t.Error("a deny list alone should admit everyone else")
+	}
+
+	for _, bad := range []string{"192.168.1.0/33", "peer.lan", ""} {
+		if _, err := ParseAccessList([]string{bad}, nil); err == nil {
+			t.Errorf("ParseAccessList(%q) should fail", bad)
+		}
+	}
+}
+
+func TestServerRestrict(t *testing.T) {
+	testModelsDir(t)
+	s := NewServer(11314)
+	s.AllowSync()
+	access, _ := ParseAccessList([]string{"192.168.1.0/24"}, nil)
+	s.Restrict(access)
+
+	for _, path := range []string{"/api/peer/sha256/" + strings.Repeat("a", 64), "/api/peer/sync/models"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "203.0.113.7:51000"
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("GET %s from outside the allow list = %d, want 403", path, w.Code)
+		}
+
+		req.RemoteAddr = "192.168.1.20:51000"
+		w = httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, req)
+		if w.Code == http.StatusForbidden {
+			t.Errorf("GET %s from an allowed peer was refused", path)
+		}
+	}
+}
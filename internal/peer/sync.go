@@ -0,0 +1,597 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// freeDisk reports free space. Overridden in tests.
+var freeDisk = hw.FreeDisk
+
+// SyncModel is one downloaded model quantization, as exchanged by 'lleme sync'.
+type SyncModel struct {
+	User     string          `json:"user"`
+	Repo     string          `json:"repo"`
+	Quant    string          `json:"quant"`
+	Modified time.Time       `json:"modified"` // When the manifest was last written
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// Name returns the model's full name, e.g. "user/repo:Q4_K_M".
+func (m SyncModel) Name() string {
+	return hf.FormatModelName(m.User, m.Repo, m.Quant)
+}
+
+func (m SyncModel) parse() (*hf.Manifest, error) {
+	manifest, err := hf.ParseManifest(m.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Name(), err)
+	}
+	return manifest, nil
+}
+
+// Size returns the total size of the model's files.
+func (m SyncModel) Size() int64 {
+	manifest, err := m.parse()
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, lf := range hf.ManifestLocalFiles(m.User, m.Repo, m.Quant, manifest) {
+		size += lf.File.Size
+	}
+	return size
+}
+
+// fingerprint identifies the model's content by its file hashes. It returns
+// false when a file has no SHA256, since such models can't be transferred by hash.
+func (m SyncModel) fingerprint() (string, bool) {
+	manifest, err := m.parse()
+	if err != nil {
+		return "", false
+	}
+	var hashes []string
+	for _, lf := range hf.ManifestLocalFiles(m.User, m.Repo, m.Quant, manifest) {
+		if lf.File.LFS == nil || lf.File.LFS.SHA256 == "" {
+			return "", false
+		}
+		hashes = append(hashes, strings.ToLower(lf.File.LFS.SHA256))
+	}
+	return strings.Join(hashes, ","), true
+}
+
+// LocalSyncModels lists the models in the local store whose files are all present.
+func LocalSyncModels() ([]SyncModel, error) {
+	modelsDir := paths.Models()
+	manifests, err := filepath.Glob(filepath.Join(modelsDir, "*", "*", "*-manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var models []SyncModel
+	for _, path := range manifests {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		rel, _ := filepath.Rel(modelsDir, path)
+		parts := strings.Split(rel, string(filepath.Separator))
+		m := SyncModel{
+			User:     parts[0],
+			Repo:     parts[1],
+			Quant:    strings.TrimSuffix(parts[2], "-manifest.json"),
+			Modified: info.ModTime().UTC(),
+			Manifest: data,
+		}
+
+		manifest, err := m.parse()
+		if err != nil {
+			continue
+		}
+		complete := true
+		for _, lf := range hf.ManifestLocalFiles(m.User, m.Repo, m.Quant, manifest) {
+			if _, err := os.Stat(lf.Path); err != nil {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			models = append(models, m)
+		}
+	}
+	return models, nil
+}
+
+// SyncMode chooses which way models are transferred.
+type SyncMode int
+
+const (
+	SyncBoth SyncMode = iota // Transfer both ways; changed models go from the newer side
+	SyncPush                 // Only send models to the remote
+	SyncPull                 // Only fetch models from the remote
+)
+
+// SyncAction is one model transfer in a sync plan.
+type SyncAction struct {
+	Model  SyncModel
+	Push   bool   // Send to the remote, otherwise fetch from it
+	Reason string // "missing" or "changed"
+}
+
+// SyncPlan lists the transfers needed to bring two stores in line.
+type SyncPlan struct {
+	Actions []SyncAction
+	Skipped []string // Models with files that have no SHA256 to transfer by
+}
+
+// PlanSync compares two stores and works out what to transfer. Models are
+// the same when their file hashes match, so unchanged models are never sent.
+func PlanSync(local, remote []SyncModel, mode SyncMode) SyncPlan {
+	byName := func(models []SyncModel) map[string]SyncModel {
+		m := make(map[string]SyncModel, len(models))
+		for _, model := range models {
+			m[model.Name()] = model
+		}
+		return m
+	}
+	localByName, remoteByName := byName(local), byName(remote)
+
+	var names []string
+	for name := range localByName {
+		names = append(names, name)
+	}
+	for name := range remoteByName {
+		if _, ok := localByName[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var plan SyncPlan
+	for _, name := range names {
+		l, haveLocal := localByName[name]
+		r, haveRemote := remoteByName[name]
+
+		switch {
+		case haveLocal && !haveRemote:
+			if mode == SyncPull {
+				continue
+			}
+			if _, ok := l.fingerprint(); !ok {
+				plan.Skipped = append(plan.Skipped, name)
+				continue
+			}
+			plan.Actions = append(plan.Actions, SyncAction{Model: l, Push: true, Reason: "missing"})
+
+		case haveRemote && !haveLocal:
+			if mode == SyncPush {
+				continue
+			}
+			if _, ok := r.fingerprint(); !ok {
+				plan.Skipped = append(plan.Skipped, name)
+				continue
+			}
+			plan.Actions = append(plan.Actions, SyncAction{Model: r, Reason: "missing"})
+
+		default:
+			lf, lok := l.fingerprint()
+			rf, rok := r.fingerprint()
+			if !lok || !rok {
+				plan.Skipped = append(plan.Skipped, name)
+				continue
+			}
+			if lf == rf {
+				continue
+			}
+			push := mode == SyncPush || (mode == SyncBoth && l.Modified.After(r.Modified))
+			if push {
+				plan.Actions = append(plan.Actions, SyncAction{Model: l, Push: true, Reason: "changed"})
+			} else {
+				plan.Actions = append(plan.Actions, SyncAction{Model: r, Reason: "changed"})
+			}
+		}
+	}
+	return plan
+}
+
+// syncStagingDir is where pushed files wait until their model is committed.
+func syncStagingDir() string {
+	return filepath.Join(paths.Cache(), "sync")
+}
+
+func syncStagingPath(hash string) string {
+	return filepath.Join(syncStagingDir(), hash)
+}
+
+// handleSyncModels lists the local models (GET) or commits a pushed model (POST).
+// Endpoint: /api/peer/sync/models
+func (s *Server) handleSyncModels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		models, err := LocalSyncModels()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models)
+
+	case http.MethodPost:
+		var m SyncModel
+		if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&m); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := s.commitModel(m); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSyncUpload receives a pushed file, keeping it only if it matches its hash.
+// Endpoint: PUT /api/peer/sync/sha256/{hash}
+func (s *Server) handleSyncUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hash := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/api/peer/sync/sha256/"))
+	if !isSHA256(hash) {
+		http.Error(w, "Invalid hash", http.StatusBadRequest)
+		return
+	}
+
+	// The size is needed up front to check it fits before writing anything
+	if r.ContentLength < 0 {
+		http.Error(w, "Content-Length is required", http.StatusLengthRequired)
+		return
+	}
+
+	dest := syncStagingPath(hash)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if free, err := freeDisk(filepath.Dir(dest)); err != nil {
+		logs.Debug("Could not check free space for sync upload", "error", err)
+	} else if r.ContentLength > free {
+		http.Error(w, fmt.Sprintf("not enough disk space: %s needed, %s free", ui.FormatBytes(r.ContentLength), ui.FormatBytes(free)), http.StatusInsufficientStorage)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, r.ContentLength)
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), hash+".*.partial")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		http.Error(w, fmt.Sprintf("hash mismatch: got %s", got), http.StatusBadRequest)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// commitModel moves a pushed model's files into the store and writes its
+// manifest. Files come from the staging area or, when unchanged, from
+// models already stored here.
+func (s *Server) commitModel(m SyncModel) error {
+	for _, part := range []string{m.User, m.Repo, m.Quant} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return fmt.Errorf("invalid model name %s", m.Name())
+		}
+	}
+	manifest, err := m.parse()
+	if err != nil {
+		return err
+	}
+
+	files := hf.ManifestLocalFiles(m.User, m.Repo, m.Quant, manifest)
+	sources := make([]string, len(files))
+	for i, lf := range files {
+		if lf.File.LFS == nil || !isSHA256(strings.ToLower(lf.File.LFS.SHA256)) {
+			return fmt.Errorf("%s has no SHA256", lf.File.RFilename)
+		}
+		hash := strings.ToLower(lf.File.LFS.SHA256)
+		if _, err := os.Stat(syncStagingPath(hash)); err == nil {
+			sources[i] = syncStagingPath(hash)
+		} else if path := s.peerFileIndex.Lookup(hash); path != "" {
+			sources[i] = path
+		} else {
+			return fmt.Errorf("%s has not been uploaded", lf.File.RFilename)
+		}
+	}
+
+	for i, lf := range files {
+		if sources[i] == lf.Path {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(lf.Path), 0755); err != nil {
+			return err
+		}
+		if filepath.Dir(sources[i]) == syncStagingDir() {
+			err = os.Rename(sources[i], lf.Path)
+		} else {
+			err = linkOrCopy(sources[i], lf.Path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to store %s: %w", lf.File.RFilename, err)
+		}
+	}
+
+	// Written last and whole, so a model is only listed once its files are in
+	if err := fileutil.AtomicWriteFile(hf.GetManifestFilePath(m.User, m.Repo, m.Quant), m.Manifest, 0644); err != nil {
+		return fmt.Errorf("failed to save manifest: %w", err)
+	}
+
+	if err := RebuildPeerFileIndex(); err != nil {
+		logs.Warn("Failed to update peer index", "error", err)
+	}
+	if err := s.peerFileIndex.Load(); err != nil {
+		logs.Warn("Failed to load peer file index", "error", err)
+	}
+	logs.Info("Model synced from peer", "model", m.Name())
+	return nil
+}
+
+// linkOrCopy hard links src to dst, copying when links aren't possible.
+func linkOrCopy(src, dst string) error {
+	os.Remove(dst)
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+func isSHA256(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// SyncModels lists the peer's models. It fails unless the peer allows sync.
+func (c *Client) SyncModels() ([]SyncModel, error) {
+	url := fmt.Sprintf("http://%s:%d/api/peer/sync/models", c.peer.Host, c.peer.Port)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact peer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("peer %s doesn't allow sync (set peer.allow_sync: true there)", c.peer.Host)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer returned HTTP %d", resp.StatusCode)
+	}
+
+	var models []SyncModel
+	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+		return nil, fmt.Errorf("invalid response from peer: %w", err)
+	}
+	return models, nil
+}
+
+// PushModel sends a local model to the peer, uploading only the files it
+// doesn't already have, then commits it to the peer's store.
+func (c *Client) PushModel(m SyncModel, progress DownloadProgressCallback) error {
+	manifest, err := m.parse()
+	if err != nil {
+		return err
+	}
+
+	files := hf.ManifestLocalFiles(m.User, m.Repo, m.Quant, manifest)
+	var total, sent int64
+	for _, lf := range files {
+		total += lf.File.Size
+	}
+
+	for _, lf := range files {
+		if lf.File.LFS == nil {
+			return fmt.Errorf("%s has no SHA256", lf.File.RFilename)
+		}
+		hash := strings.ToLower(lf.File.LFS.SHA256)
+		if _, ok := c.HasHash(hash); !ok {
+			base := sent
+			err := c.uploadHash(hash, lf.Path, func(n int64) {
+				if progress != nil {
+					progress(base+n, total)
+				}
+			})
+			if err != nil {
+				return fmt.Errorf("failed to upload %s: %w", lf.File.RFilename, err)
+			}
+		}
+		sent += lf.File.Size
+		if progress != nil {
+			progress(sent, total)
+		}
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("http://%s:%d/api/peer/sync/models", c.peer.Host, c.peer.Port)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to contact peer: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("peer rejected %s: %s", m.Name(), strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (c *Client) uploadHash(hash, path string, progress func(sent int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s:%d/api/peer/sync/sha256/%s", c.peer.Host, c.peer.Port, hash)
+	req, err := http.NewRequest(http.MethodPut, url, &progressReader{r: f, progress: progress})
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", version.UserAgent())
+
+	// No overall timeout for large uploads
+	resp, err := (&http.Client{Transport: logs.Transport(nil)}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+type progressReader struct {
+	r        io.Reader
+	n        int64
+	progress func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if p.progress != nil {
+		p.progress(p.n)
+	}
+	return n, err
+}
+
+// Source pulls models from a peer by hash, for use with hf.PullModel.
+type Source struct {
+	client *Client
+	models map[string]SyncModel
+}
+
+// NewSource returns a model source serving the given models from the peer.
+func NewSource(client *Client, models []SyncModel) *Source {
+	s := &Source{client: client, models: make(map[string]SyncModel, len(models))}
+	for _, m := range models {
+		s.models[m.Name()] = m
+	}
+	return s
+}
+
+func (s *Source) Name() string {
+	return "peer " + s.client.peer.Host
+}
+
+func (s *Source) ListQuantizations(user, repo string) ([]hf.Quantization, error) {
+	var quants []hf.Quantization
+	for _, m := range s.models {
+		if m.User == user && m.Repo == repo {
+			quants = append(quants, hf.Quantization{Name: m.Quant, Tag: m.Quant, Size: m.Size()})
+		}
+	}
+	return quants, nil
+}
+
+func (s *Source) GetManifest(user, repo string, quant hf.Quantization) (*hf.Manifest, []byte, error) {
+	m, ok := s.models[hf.FormatModelName(user, repo, quant.Name)]
+	if !ok {
+		return nil, nil, fmt.Errorf("peer %s doesn't have %s", s.client.peer.Host, hf.FormatModelName(user, repo, quant.Name))
+	}
+	manifest, err := m.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, m.Manifest, nil
+}
+
+func (s *Source) Download(user, repo string, file *hf.ManifestFile, destPath string, progress func(current, total int64)) error {
+	if file.LFS == nil || file.LFS.SHA256 == "" {
+		return fmt.Errorf("%s has no SHA256 to fetch it by", file.RFilename)
+	}
+	return s.client.DownloadHash(strings.ToLower(file.LFS.SHA256), destPath, progress)
+}
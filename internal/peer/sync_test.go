@@ -0,0 +1,269 @@
+package peer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+// syncModel builds a model whose files have the given hashes. Short hashes
+// like "aaaa" are repeated out to a full SHA256.
+func syncModel(name, quant string, modified time.Time, hashes ...string) SyncModel {
+	manifest := hf.Manifest{}
+	for i, hash := range hashes {
+		mf := &hf.ManifestFile{RFilename: "model.gguf", Size: 10}
+		if hash != "" {
+			mf.LFS = &hf.ManifestLFS{SHA256: strings.Repeat(hash, 64/len(hash))}
+		}
+		if i == 0 {
+			manifest.GGUFFile = mf
+		} else {
+			manifest.MMProjFile = mf
+		}
+	}
+	data, _ := json.Marshal(manifest)
+	return SyncModel{User: "user", Repo: name, Quant: quant, Modified: modified, Manifest: data}
+}
+
+func TestPlanSync(t *testing.T) {
+	older := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	local := []SyncModel{
+		syncModel("only-local", "Q4_K_M", older, "aaaa"),
+		syncModel("same", "Q4_K_M", older, "bbbb"),
+		syncModel("changed-local-newer", "Q4_K_M", newer, "cccc"),
+		syncModel("changed-remote-newer", "Q4_K_M", older, "dddd"),
+		syncModel("no-hash", "Q4_K_M", older, ""),
+	}
+	remote := []SyncModel{
+		syncModel("only-remote", "Q8_0", older, "eeee"),
+		syncModel("same", "Q4_K_M", newer, "bbbb"),
+		syncModel("changed-local-newer", "Q4_K_M", older, "cccc", "ffff"),
+		syncModel("changed-remote-newer", "Q4_K_M", newer, "9999"),
+	}
+
+	type step struct {
+		name string
+		push bool
+	}
+	tests := []struct {
+		mode SyncMode
+		want []step
+	}{
+		{SyncBoth, []step{
+			{"user/changed-local-newer:Q4_K_M", true},
+			{"user/changed-remote-newer:Q4_K_M", false},
+			{"user/only-local:Q4_K_M", true},
+			{"user/only-remote:Q8_0", false},
+		}},
+		{SyncPush, []step{
+			{"user/changed-local-newer:Q4_K_M", true},
+			{"user/changed-remote-newer:Q4_K_M", true},
+			{"user/only-local:Q4_K_M", true},
+		}},
+		{SyncPull, []step{
+			{"user/changed-local-newer:Q4_K_M", false},
+			{"user/changed-remote-newer:Q4_K_M", false},
+			{"user/only-remote:Q8_0", false},
+		}},
+	}
+
+	for _, tt := range tests {
+		plan := PlanSync(local, remote, tt.mode)
+		if len(plan.Actions) != len(tt.want) {
+			t.Fatalf("mode %d: got %d actions, want %d: %+v", tt.mode, len(plan.Actions), len(tt.want), plan.Actions)
+		}
+		for i, want := range tt.want {
+			got := plan.Actions[i]
+			if got.Model.Name() != want.name || got.Push != want.push {
+				t.Errorf("mode %d action %d = %s push=%v, want %s push=%v", tt.mode, i, got.Model.Name(), got.Push, want.name, want.push)
+			}
+		}
+	}
+
+	plan := PlanSync(local, remote, SyncBoth)
+	if len(plan.Skipped) != 1 || plan.Skipped[0] != "user/no-hash:Q4_K_M" {
+		t.Errorf("Skipped = %v, want the model without a hash", plan.Skipped)
+	}
+}
+
+// writeLocalModel puts a single-file model and its manifest in the store
+func writeLocalModel(t *testing.T, user, repo, quant string, content []byte) SyncModel {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	manifest := hf.Manifest{GGUFFile: &hf.ManifestFile{
+		RFilename: "model-" + quant + ".gguf",
+		Size:      int64(len(content)),
+		LFS:       &hf.ManifestLFS{SHA256: hex.EncodeToString(sum[:]), Size: int64(len(content))},
+	}}
+	data, _ := json.Marshal(manifest)
+
+	if err := os.MkdirAll(hf.GetModelPath(user, repo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hf.GetModelFilePath(user, repo, quant), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hf.GetManifestFilePath(user, repo, quant), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return SyncModel{User: user, Repo: repo, Quant: quant, Manifest: data}
+}
+
+func newSyncTestServer(t *testing.T) (*Server, *Client) {
+	t.Helper()
+	s := NewServer(0)
+	s.AllowSync()
+	srv := httptest.NewServer(s.httpServer.Handler)
+	t.Cleanup(srv.Close)
+
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return s, NewClient(&Peer{Host: u.Hostname(), Port: port})
+}
+
+func TestSyncPushAndList(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	_, client := newSyncTestServer(t)
+
+	m := writeLocalModel(t, "user", "repo", "Q4_K_M", []byte("model weights"))
+
+	var last int64
+	if err := client.PushModel(m, func(current, total int64) { last = current }); err != nil {
+		t.Fatalf("PushModel() error = %v", err)
+	}
+	if last != int64(len("model weights")) {
+		t.Errorf("progress ended at %d", last)
+	}
+
+	models, err := client.SyncModels()
+	if err != nil {
+		t.Fatalf("SyncModels() error = %v", err)
+	}
+	if len(models) != 1 || models[0].Name() != "user/repo:Q4_K_M" {
+		t.Errorf("SyncModels() = %+v", models)
+	}
+
+	entries, _ := os.ReadDir(syncStagingDir())
+	if len(entries) != 0 {
+		t.Errorf("staging has %d files after commit, want 0", len(entries))
+	}
+
+	// A second copy under a new name reuses the stored file instead of uploading
+	copied := m
+	copied.Repo = "copy"
+	if err := client.PushModel(copied, nil); err != nil {
+		t.Fatalf("PushModel(copy) error = %v", err)
+	}
+	if data, _ := os.ReadFile(hf.GetModelFilePath("user", "copy", "Q4_K_M")); string(data) != "model weights" {
+		t.Errorf("copied model = %q", data)
+	}
+}
+
+func TestSyncModelsCompressed(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	_, client := newSyncTestServer(t)
+	writeLocalModel(t, "user", "repo", "Q4_K_M", []byte("model weights"))
+
+	url := fmt.Sprintf("http://%s:%d/api/peer/sync/models", client.peer.Host, client.peer.Port)
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", got)
+	}
+
+	models, err := client.SyncModels()
+	if err != nil {
+		t.Fatalf("SyncModels() error = %v", err)
+	}
+	if len(models) != 1 {
+		t.Errorf("SyncModels() = %+v", models)
+	}
+}
+
+func TestSyncRejects(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	s, _ := newSyncTestServer(t)
+
+	put := func(hash string, body []byte) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/peer/sync/sha256/"+hash, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	sum := sha256.Sum256([]byte("right"))
+	if code := put(hex.EncodeToString(sum[:]), []byte("wrong")); code != http.StatusBadRequest {
+		t.Errorf("upload with wrong content = %d, want 400", code)
+	}
+	if code := put("not-a-hash", nil); code != http.StatusBadRequest {
+		t.Errorf("upload with bad hash = %d, want 400", code)
+	}
+
+	// Uploads must say how big they are, and fit
+	req := httptest.NewRequest(http.MethodPut, "/api/peer/sync/sha256/"+hex.EncodeToString(sum[:]), strings.NewReader("right"))
+	req.ContentLength = -1
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusLengthRequired {
+		t.Errorf("upload without length = %d, want 411", w.Code)
+	}
+	orig := freeDisk
+	freeDisk = func(string) (int64, error) { return 3, nil }
+	code := put(hex.EncodeToString(sum[:]), []byte("right"))
+	freeDisk = orig
+	if code != http.StatusInsufficientStorage {
+		t.Errorf("upload larger than free space = %d, want 507", code)
+	}
+	if entries, _ := os.ReadDir(syncStagingDir()); len(entries) != 0 {
+		t.Errorf("refused uploads left %d files in staging", len(entries))
+	}
+
+	commit := func(m SyncModel) int {
+		body, _ := json.Marshal(m)
+		req := httptest.NewRequest(http.MethodPost, "/api/peer/sync/models", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		s.mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	missing := syncModel("repo", "Q4_K_M", time.Now(), hex.EncodeToString(sum[:]))
+	if code := commit(missing); code != http.StatusConflict {
+		t.Errorf("commit without upload = %d, want 409", code)
+	}
+	traversal := missing
+	traversal.Repo = ".."
+	if code := commit(traversal); code != http.StatusConflict {
+		t.Errorf("commit with path traversal = %d, want 409", code)
+	}
+	if _, err := os.Stat(filepath.Join(hf.GetModelPath("user", "repo"))); !os.IsNotExist(err) {
+		t.Error("rejected commits should not create model files")
+	}
+}
+
+func TestSyncDisabledByDefault(t *testing.T) {
+	s := NewServer(0)
+	req := httptest.NewRequest(http.MethodGet, "/api/peer/sync/models", nil)
+	w := httptest.NewRecorder()
+	s.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("sync endpoint without AllowSync = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,33 @@
+package process
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseLsof reads the listening sockets from `lsof -Fpn` output, where a
+// "p<pid>" line starts each process and "n<addr>:<port>" names a socket.
+func parseLsof(output string) []Listener {
+	var listeners []Listener
+	pid := 0
+	for line := range strings.SplitSeq(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case 'p':
+			pid, _ = strconv.Atoi(line[1:])
+		case 'n':
+			i := strings.LastIndexByte(line, ':')
+			if i < 0 || pid <= 0 {
+				continue
+			}
+			port, err := strconv.Atoi(line[i+1:])
+			if err != nil {
+				continue
+			}
+			listeners = append(listeners, Listener{Port: port, PID: pid})
+		}
+	}
+	return listeners
+}
@@ -0,0 +1,91 @@
+// Package process lists running processes and the TCP ports they listen
+// on, without shelling out to ps or lsof. Linux reads /proc, macOS uses
+// sysctl (and lsof, which it always ships, for sockets), and Windows uses
+// the Toolhelp and IP Helper APIs.
+package process
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrUnsupported is returned by Snapshot where there's no way to inspect
+// processes.
+var ErrUnsupported = errors.New("process inspection is not supported on this platform")
+
+// Process is one running process.
+type Process struct {
+	PID  int
+	PPID int
+	Name string   // Executable name, without its directory
+	Args []string // Command line, when it can be read
+}
+
+// CommandLine returns the process's arguments joined with spaces, or its
+// name if they couldn't be read.
+func (p Process) CommandLine() string {
+	if len(p.Args) == 0 {
+		return p.Name
+	}
+	return strings.Join(p.Args, " ")
+}
+
+// Listener is a process listening on a TCP port. A socket shared by a
+// parent and its children shows up once for each of them.
+type Listener struct {
+	Port int
+	PID  int
+}
+
+// Table is a snapshot of the processes running and the ports they listen
+// on. Tests build their own to fake a process tree.
+type Table struct {
+	Processes map[int]Process
+	Listeners []Listener
+}
+
+// Snapshot lists the processes running now.
+func Snapshot() (*Table, error) {
+	return snapshot()
+}
+
+// Get returns the process with the given PID.
+func (t *Table) Get(pid int) (Process, bool) {
+	p, ok := t.Processes[pid]
+	return p, ok
+}
+
+// ListeningOn returns the PIDs listening on port, lowest first.
+func (t *Table) ListeningOn(port int) []int {
+	var pids []int
+	for _, l := range t.Listeners {
+		if l.Port == port && !slices.Contains(pids, l.PID) {
+			pids = append(pids, l.PID)
+		}
+	}
+	slices.Sort(pids)
+	return pids
+}
+
+// Owner returns the process listening on port that the others sharing the
+// socket descend from: the server itself rather than a child it forked.
+func (t *Table) Owner(port int) (Process, bool) {
+	pids := t.ListeningOn(port)
+	for _, pid := range pids {
+		if !slices.Contains(pids, t.Processes[pid].PPID) {
+			if p, ok := t.Processes[pid]; ok {
+				return p, true
+			}
+			return Process{PID: pid}, true
+		}
+	}
+	return Process{}, false
+}
+
+// baseName strips the directory and, on Windows, the .exe from a path.
+func baseName(path string) string {
+	path = strings.ReplaceAll(path, `\`, "/")
+	return strings.TrimSuffix(filepath.Base(path), ".exe")
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os/exec"
+
+	"golang.org/x/sys/unix"
+)
+
+func snapshot() (*Table, error) {
+	procs, err := unix.SysctlKinfoProcSlice("kern.proc.all")
+	if err != nil {
+		return nil, err
+	}
+
+	table := &Table{Processes: make(map[int]Process, len(procs))}
+	for _, kp := range procs {
+		pid := int(kp.Proc.P_pid)
+		p := Process{
+			PID:  pid,
+			PPID: int(kp.Eproc.Ppid),
+			Name: unix.ByteSliceToString(kp.Proc.P_comm[:]),
+		}
+		if args := procArgs(pid); len(args) > 0 {
+			p.Args = args
+			p.Name = baseName(args[0])
+		}
+		table.Processes[pid] = p
+	}
+
+	// Sockets can only be tied to processes through libproc, which needs
+	// cgo; lsof is part of every macOS install
+	if out, err := exec.Command("lsof", "-nP", "-iTCP", "-sTCP:LISTEN", "-Fpn").Output(); err == nil {
+		table.Listeners = parseLsof(string(out))
+	}
+	return table, nil
+}
+
+// procArgs reads a process's arguments from kern.procargs2: argc, the
+// executable path, NUL padding, then argc NUL-terminated arguments. It
+// returns nil for other users' processes.
+func procArgs(pid int) []string {
+	buf, err := unix.SysctlRaw("kern.procargs2", pid)
+	if err != nil || len(buf) < 4 {
+		return nil
+	}
+	argc := int(binary.LittleEndian.Uint32(buf))
+	buf = buf[4:]
+
+	// Skip the executable path and the padding after it
+	end := bytes.IndexByte(buf, 0)
+	if end < 0 {
+		return nil
+	}
+	buf = bytes.TrimLeft(buf[end:], "\x00")
+
+	args := make([]string, 0, argc)
+	for len(args) < argc && len(buf) > 0 {
+		end := bytes.IndexByte(buf, 0)
+		if end < 0 {
+			end = len(buf)
+		}
+		args = append(args, string(buf[:end]))
+		buf = buf[min(end+1, len(buf)):]
+	}
+	return args
+}
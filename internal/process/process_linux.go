@@ -0,0 +1,5 @@
+package process
+
+func snapshot() (*Table, error) {
+	return readProcfs("/proc")
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !windows
+
+package process
+
+func snapshot() (*Table, error) {
+	return nil, ErrUnsupported
+}
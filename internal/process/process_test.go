@@ -0,0 +1,194 @@
+package process
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// fakeProc lays out a procfs under a temp dir: init, a lleme server and a
+// child it forked that shares the listening socket, and an unrelated
+// server on another port.
+func fakeProc(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(rel, data string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	socket := func(pid, fd, inode string) {
+		dir := filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("socket:["+inode+"]", filepath.Join(dir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("net/tcp", `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:2C31 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 555 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:2C31 0100007F:9C40 01 00000000:00000000 00:00000000 00000000  1000        0 556 1 0000000000000000 20 4 30 10 -1
+`)
+	write("net/tcp6", `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:1F90 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 777 1 0000000000000000 100 0 0 10 0
+`)
+	write("1/stat", "1 (systemd) S 0 1 1 0 -1 4194560")
+	write("1/cmdline", "/sbin/init\x00")
+	write("100/stat", "100 (lleme) S 1 100 100 0 -1 4194560")
+	write("100/cmdline", "/usr/local/bin/lleme\x00server\x00start\x00")
+	socket("100", "3", "555")
+	socket("100", "4", "556") // Connected, not listening
+	write("101/stat", "101 (lleme) S 100 100 100 0 -1 4194560")
+	write("101/cmdline", "/usr/local/bin/lleme\x00server\x00start\x00")
+	socket("101", "3", "555")
+	write("200/stat", "200 (python3 (x)) S 1 200 200 0 -1 4194560")
+	write("200/cmdline", "python3\x00-m\x00http.server\x008080\x00")
+	socket("200", "5", "777")
+	write("300/stat", "300 (kworker/0:1) I 2 0 0 0 -1 69238880")
+	write("300/cmdline", "")
+	write("self", "not a pid")
+	return root
+}
+
+func TestReadProcfs(t *testing.T) {
+	table, err := readProcfs(fakeProc(t))
+	if err != nil {
+		t.Fatalf("readProcfs() error = %v", err)
+	}
+
+	if len(table.Processes) != 5 {
+		t.Errorf("got %d processes, want 5", len(table.Processes))
+	}
+	want := Process{PID: 100, PPID: 1, Name: "lleme", Args: []string{"/usr/local/bin/lleme", "server", "start"}}
+	if got, _ := table.Get(100); !reflect.DeepEqual(got, want) {
+		t.Errorf("Get(100) = %+v, want %+v", got, want)
+	}
+	if got, _ := table.Get(200); got.PPID != 1 || got.Name != "python3" {
+		t.Errorf("Get(200) = %+v", got)
+	}
+	if got, _ := table.Get(300); got.Name != "kworker/0:1" || got.Args != nil {
+		t.Errorf("Get(300) = %+v, want the stat name and no args", got)
+	}
+
+	if got := table.ListeningOn(11313); !reflect.DeepEqual(got, []int{100, 101}) {
+		t.Errorf("ListeningOn(11313) = %v, want [100 101]", got)
+	}
+	if got := table.ListeningOn(8080); !reflect.DeepEqual(got, []int{200}) {
+		t.Errorf("ListeningOn(8080) = %v, want [200]", got)
+	}
+	if got := table.ListeningOn(40000); got != nil {
+		t.Errorf("ListeningOn(40000) = %v, want none", got)
+	}
+
+	owner, ok := table.Owner(11313)
+	if !ok || owner.PID != 100 {
+		t.Errorf("Owner(11313) = %+v, %v, want PID 100", owner, ok)
+	}
+}
+
+func TestOwner(t *testing.T) {
+	t.Run("child listed first", func(t *testing.T) {
+		table := &Table{
+			Processes: map[int]Process{
+				50: {PID: 50, PPID: 900, Name: "lleme"},
+				10: {PID: 10, PPID: 50, Name: "lleme"},
+			},
+			Listeners: []Listener{{Port: 80, PID: 10}, {Port: 80, PID: 50}},
+		}
+		if owner, _ := table.Owner(80); owner.PID != 50 {
+			t.Errorf("Owner() = %d, want the parent, 50", owner.PID)
+		}
+	})
+
+	t.Run("process not in table", func(t *testing.T) {
+		table := &Table{Listeners: []Listener{{Port: 80, PID: 7}}}
+		owner, ok := table.Owner(80)
+		if !ok || owner.PID != 7 || owner.CommandLine() != "" {
+			t.Errorf("Owner() = %+v, %v, want a bare PID 7", owner, ok)
+		}
+	})
+
+	t.Run("nothing listening", func(t *testing.T) {
+		if _, ok := (&Table{}).Owner(80); ok {
+			t.Error("Owner() found a process on an unused port")
+		}
+	})
+}
+
+func TestParseStat(t *testing.T) {
+	tests := []struct {
+		stat string
+		name string
+		ppid int
+		ok   bool
+	}{
+		{"42 (bash) S 1 42 42", "bash", 1, true},
+		{"42 (a) b (c)) R 7 42 42", "a) b (c)", 7, true},
+		{"42 bash S 1", "", 0, false},
+		{"42 (bash)", "", 0, false},
+		{"42 (bash) S x", "", 0, false},
+	}
+	for _, tt := range tests {
+		name, ppid, ok := parseStat(tt.stat)
+		if name != tt.name || ppid != tt.ppid || ok != tt.ok {
+			t.Errorf("parseStat(%q) = %q, %d, %v; want %q, %d, %v", tt.stat, name, ppid, ok, tt.name, tt.ppid, tt.ok)
+		}
+	}
+}
+
+func TestParseLsof(t *testing.T) {
+	output := "p100\nf3\nn*:11313\nf4\nn[::1]:11313\np200\nf5\nn127.0.0.1:8080\nnbad\n"
+	want := []Listener{{Port: 11313, PID: 100}, {Port: 11313, PID: 100}, {Port: 8080, PID: 200}}
+	if got := parseLsof(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLsof() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	if got := (Process{Name: "lleme.exe"}).CommandLine(); got != "lleme.exe" {
+		t.Errorf("CommandLine() = %q, want the name", got)
+	}
+	if got := (Process{Name: "lleme", Args: []string{"lleme", "serve"}}).CommandLine(); got != "lleme serve" {
+		t.Errorf("CommandLine() = %q, want the args", got)
+	}
+	if got := baseName(`C:\Program Files\lleme\lleme.exe`); got != "lleme" {
+		t.Errorf("baseName() = %q, want lleme", got)
+	}
+}
+
+func TestSnapshotFindsListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("can't listen:", err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	table, err := Snapshot()
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if _, ok := table.Get(os.Getpid()); !ok {
+		t.Error("Snapshot() is missing this process")
+	}
+	owner, ok := table.Owner(port)
+	if runtime.GOOS == "darwin" && !ok {
+		t.Skip("lsof not available")
+	}
+	if !ok || owner.PID != os.Getpid() {
+		t.Errorf("Owner(%d) = %+v, %v, want this process", port, owner, ok)
+	}
+}
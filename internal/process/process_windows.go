@@ -0,0 +1,91 @@
+package process
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	iphlpapi                = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetExtendedTcpTable = iphlpapi.NewProc("GetExtendedTcpTable")
+)
+
+const tcpTableOwnerPIDListener = 3 // TCP_TABLE_OWNER_PID_LISTENER
+
+func snapshot() (*Table, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snap)
+
+	table := &Table{Processes: make(map[int]Process)}
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		pid := int(entry.ProcessID)
+		table.Processes[pid] = Process{
+			PID:  pid,
+			PPID: int(entry.ParentProcessID),
+			Name: baseName(windows.UTF16ToString(entry.ExeFile[:])),
+		}
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return nil, err
+	}
+
+	for _, family := range []uint32{windows.AF_INET, windows.AF_INET6} {
+		listeners, err := tcpListeners(family)
+		if err != nil {
+			return nil, err
+		}
+		table.Listeners = append(table.Listeners, listeners...)
+	}
+	return table, nil
+}
+
+// tcpListeners reads the listening sockets of one address family from
+// GetExtendedTcpTable.
+func tcpListeners(family uint32) ([]Listener, error) {
+	var size uint32
+	var buf []byte
+	for {
+		var ptr uintptr
+		if len(buf) > 0 {
+			ptr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		ret, _, _ := procGetExtendedTcpTable.Call(ptr, uintptr(unsafe.Pointer(&size)), 0,
+			uintptr(family), tcpTableOwnerPIDListener, 0)
+		if ret == 0 {
+			break
+		}
+		if windows.Errno(ret) != windows.ERROR_INSUFFICIENT_BUFFER {
+			return nil, windows.Errno(ret)
+		}
+		buf = make([]byte, size)
+	}
+	if len(buf) < 4 {
+		return nil, nil
+	}
+
+	// MIB_TCPROW_OWNER_PID is six DWORDs; the IPv6 row adds two 16-byte
+	// addresses and two scope IDs. Ports are in network byte order.
+	rowSize, portOffset, pidOffset := 24, 8, 20
+	if family == windows.AF_INET6 {
+		rowSize, portOffset, pidOffset = 56, 20, 52
+	}
+	count := int(binary.LittleEndian.Uint32(buf))
+	rows := buf[4:]
+	listeners := make([]Listener, 0, count)
+	for i := 0; i < count && (i+1)*rowSize <= len(rows); i++ {
+		row := rows[i*rowSize:]
+		listeners = append(listeners, Listener{
+			Port: int(binary.BigEndian.Uint16(row[portOffset:])),
+			PID:  int(binary.LittleEndian.Uint32(row[pidOffset:])),
+		})
+	}
+	return listeners, nil
+}
@@ -0,0 +1,141 @@
+package process
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListen is the st column of /proc/net/tcp for a listening socket
+const tcpListen = "0A"
+
+// readProcfs builds a table from a procfs mounted at root. Processes that
+// exit or can't be read partway through are skipped.
+func readProcfs(root string) (*Table, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ports := make(map[string]int) // socket inode -> port
+	for _, name := range []string{"tcp", "tcp6"} {
+		readProcNetTCP(filepath.Join(root, "net", name), ports)
+	}
+
+	table := &Table{Processes: make(map[int]Process)}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid <= 0 {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		p, ok := readProcess(dir, pid)
+		if !ok {
+			continue
+		}
+		table.Processes[pid] = p
+
+		if len(ports) == 0 {
+			continue
+		}
+		fds, err := os.ReadDir(filepath.Join(dir, "fd"))
+		if err != nil {
+			continue // Another user's process
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if err != nil {
+				continue
+			}
+			inode, ok := strings.CutPrefix(link, "socket:[")
+			if !ok {
+				continue
+			}
+			if port, ok := ports[strings.TrimSuffix(inode, "]")]; ok {
+				table.Listeners = append(table.Listeners, Listener{Port: port, PID: pid})
+			}
+		}
+	}
+	return table, nil
+}
+
+// readProcess reads a process's parent, name, and command line from its
+// /proc directory.
+func readProcess(dir string, pid int) (Process, bool) {
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Process{}, false
+	}
+	name, ppid, ok := parseStat(string(stat))
+	if !ok {
+		return Process{}, false
+	}
+	p := Process{PID: pid, PPID: ppid, Name: name}
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		p.Args = parseCmdline(cmdline)
+	}
+	if len(p.Args) > 0 {
+		p.Name = baseName(p.Args[0]) // stat truncates names to 15 bytes
+	}
+	return p, true
+}
+
+// parseStat returns the command name and parent PID from /proc/<pid>/stat.
+// The name is in parentheses and may itself contain spaces or parentheses,
+// so fields are counted from the last ')'.
+func parseStat(stat string) (name string, ppid int, ok bool) {
+	start := strings.IndexByte(stat, '(')
+	end := strings.LastIndexByte(stat, ')')
+	if start < 0 || end < start {
+		return "", 0, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return stat[start+1 : end], ppid, true
+}
+
+// parseCmdline splits the NUL-separated arguments in /proc/<pid>/cmdline.
+func parseCmdline(data []byte) []string {
+	s := strings.TrimRight(string(data), "\x00")
+	if s == "" {
+		return nil // Kernel threads and zombies
+	}
+	return strings.Split(s, "\x00")
+}
+
+// readProcNetTCP adds the listening sockets in a /proc/net/tcp file to
+// ports, keyed by inode.
+func readProcNetTCP(path string, ports map[string]int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil || fields[9] == "0" {
+			continue
+		}
+		ports[fields[9]] = int(port)
+	}
+}
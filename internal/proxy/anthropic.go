@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// anthropicVersions are the anthropic-version values we accept. The header
+// is optional; clients that omit it get the current behavior.
+var anthropicVersions = map[string]bool{
+	"2023-01-01": true,
+	"2023-06-01": true,
+}
+
+// anthropicError is a request llama-server can't serve as asked
+type anthropicError struct {
+	errType AnthropicErrorType
+	message string
+}
+
+func (e *anthropicError) Error() string { return e.message }
+
+// prepareAnthropicRequest checks the version and beta headers and rewrites
+// body to what llama-server understands. The beta header never reaches the
+// backend: prompt caching becomes a no-op, since llama-server already reuses
+// matching prompt prefixes, and other betas only change defaults or
+// response streaming that we don't emulate. Features whose meaning we can't
+// honor, like PDF documents, are rejected rather than silently dropped.
+func prepareAnthropicRequest(r *http.Request, body []byte) ([]byte, error) {
+	if v := r.Header.Get("anthropic-version"); v != "" && !anthropicVersions[v] {
+		return nil, &anthropicError{AnthropicInvalidRequest, fmt.Sprintf("anthropic-version: %q is not a supported version", v)}
+	}
+
+	if betas := r.Header.Get("anthropic-beta"); betas != "" {
+		logs.Debug("Ignoring Anthropic beta features", "betas", betas)
+		r.Header.Del("anthropic-beta")
+	}
+
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, &anthropicError{AnthropicInvalidRequest, "Failed to parse request body as JSON"}
+	}
+
+	if hasContentBlock(req["messages"], "document") {
+		return nil, &anthropicError{AnthropicInvalidRequest, "document content blocks (PDFs) are not supported by local models"}
+	}
+
+	if !stripCacheControl(req) {
+		return body, nil
+	}
+	return json.Marshal(req)
+}
+
+// hasContentBlock reports whether any message has a content block of typ
+func hasContentBlock(messages any, typ string) bool {
+	list, _ := messages.([]any)
+	for _, m := range list {
+		msg, _ := m.(map[string]any)
+		blocks, _ := msg["content"].([]any)
+		for _, b := range blocks {
+			if block, ok := b.(map[string]any); ok && block["type"] == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stripCacheControl removes prompt-caching markers from the system prompt,
+// message content and tools, and reports whether it found any. Tool input
+// schemas are left alone, since a property may be named cache_control.
+func stripCacheControl(req map[string]any) bool {
+	found := false
+	strip := func(v any) {
+		list, _ := v.([]any)
+		for _, item := range list {
+			if m, ok := item.(map[string]any); ok {
+				if _, ok := m["cache_control"]; ok {
+					delete(m, "cache_control")
+					found = true
+				}
+			}
+		}
+	}
+
+	strip(req["system"])
+	strip(req["tools"])
+	messages, _ := req["messages"].([]any)
+	for _, m := range messages {
+		if msg, ok := m.(map[string]any); ok {
+			strip(msg["content"])
+		}
+	}
+	return found
+}
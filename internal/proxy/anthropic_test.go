@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrepareAnthropicRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		beta    string
+		body    string
+		wantErr string
+		want    string // expected body, or "" for unchanged
+	}{
+		{
+			name: "no headers",
+			body: `{"model":"m","messages":[{"role":"user","content":"hi"}]}`,
+		},
+		{
+			name:    "supported version and betas",
+			version: "2023-06-01",
+			beta:    "interleaved-thinking-2025-05-14,fine-grained-tool-streaming-2025-05-14",
+			body:    `{"model":"m","messages":[]}`,
+		},
+		{
+			name:    "unsupported version",
+			version: "2099-01-01",
+			body:    `{"model":"m"}`,
+			wantErr: `anthropic-version: "2099-01-01" is not a supported version`,
+		},
+		{
+			name: "cache control stripped",
+			beta: "prompt-caching-2024-07-31",
+			body: `{"model":"m","system":[{"type":"text","text":"s","cache_control":{"type":"ephemeral"}}],` +
+				`"tools":[{"name":"t","input_schema":{"properties":{"cache_control":{"type":"string"}}},"cache_control":{"type":"ephemeral"}}],` +
+				`"messages":[{"role":"user","content":[{"type":"text","text":"hi","cache_control":{"type":"ephemeral"}}]}]}`,
+			want: `{"messages":[{"content":[{"text":"hi","type":"text"}],"role":"user"}],"model":"m",` +
+				`"system":[{"text":"s","type":"text"}],` +
+				`"tools":[{"input_schema":{"properties":{"cache_control":{"type":"string"}}},"name":"t"}]}`,
+		},
+		{
+			name:    "pdf documents",
+			beta:    "pdfs-2024-09-25",
+			body:    `{"model":"m","messages":[{"role":"user","content":[{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"JVBE"}}]}]}`,
+			wantErr: "document content blocks (PDFs) are not supported by local models",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+			if tt.version != "" {
+				r.Header.Set("anthropic-version", tt.version)
+			}
+			if tt.beta != "" {
+				r.Header.Set("anthropic-beta", tt.beta)
+			}
+
+			got, err := prepareAnthropicRequest(r, []byte(tt.body))
+			if tt.wantErr != "" {
+				var ae *anthropicError
+				if !errors.As(err, &ae) || ae.errType != AnthropicInvalidRequest || ae.message != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			want := tt.want
+			if want == "" {
+				want = tt.body
+			}
+			if string(got) != want {
+				t.Errorf("body =\n%s\nwant\n%s", got, want)
+			}
+			if r.Header.Get("anthropic-beta") != "" {
+				t.Error("anthropic-beta should not be forwarded")
+			}
+		})
+	}
+}
+
+func TestAnthropicUnsupportedVersionResponse(t *testing.T) {
+	s := &Server{config: DefaultConfig()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewBufferString(`{"model":"m"}`))
+	req.Header.Set("anthropic-version", "2001-01-01")
+	w := httptest.NewRecorder()
+
+	s.handleAnthropicMessages(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	var resp AnthropicError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Error.Type != AnthropicInvalidRequest || !strings.Contains(resp.Error.Message, "anthropic-version") {
+		t.Errorf("error = %+v", resp.Error)
+	}
+}
@@ -0,0 +1,20 @@
+package proxy
+
+// APIVersion is the version of the HTTP API the proxy serves. Bump it when
+// a change would break a CLI built against the previous version, and raise
+// MinClientAPIVersion or MinServerAPIVersion to match.
+const APIVersion = 1
+
+// MinClientAPIVersion is the oldest CLI this proxy still serves correctly.
+// /health reports it so a CLI can tell it's too old for the running server.
+const MinClientAPIVersion = 1
+
+// MinServerAPIVersion is the oldest running proxy this CLI works with.
+// Servers from before the handshake report no API version at all.
+const MinServerAPIVersion = 1
+
+// Compatible reports whether a CLI and a running proxy can talk, given
+// what the proxy's /health reported
+func Compatible(serverAPI, serverMinClient int) bool {
+	return serverAPI >= MinServerAPIVersion && APIVersion >= serverMinClient
+}
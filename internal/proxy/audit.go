@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/nchapman/lleme/internal/logs"
+	grpcpeer "google.golang.org/grpc/peer"
+)
+
+// audit records an administrative action made over HTTP in the audit log
+func (s *Server) audit(r *http.Request, action string, params map[string]any, err error) {
+	writeAudit(logs.AuditEntry{
+		Action: action,
+		Client: clientName(s.matchClient(r)),
+		Remote: r.RemoteAddr,
+		Via:    "http",
+		Params: params,
+	}, err)
+}
+
+// auditGRPC records an administrative action made over gRPC in the audit log
+func auditGRPC(ctx context.Context, action string, params map[string]any, err error) {
+	entry := logs.AuditEntry{Action: action, Client: clientName(grpcClient(ctx)), Via: "grpc", Params: params}
+	if p, ok := grpcpeer.FromContext(ctx); ok {
+		entry.Remote = p.Addr.String()
+	}
+	writeAudit(entry, err)
+}
+
+func writeAudit(entry logs.AuditEntry, err error) {
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if werr := logs.Audit(entry); werr != nil {
+		logs.Warn("Failed to write audit log", "action", entry.Action, "error", werr)
+	}
+}
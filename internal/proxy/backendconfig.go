@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/options"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// LaunchConfig records exactly how a backend's server process was started,
+// so differences between machines can be inspected remotely
+type LaunchConfig struct {
+	Path       string            `json:"path"`                    // Server binary
+	Args       []string          `json:"args"`                    // Arguments after the binary
+	Env        []string          `json:"env"`                     // Environment variables that affect inference
+	Dir        string            `json:"dir"`                     // Working directory
+	Template   string            `json:"chat_template,omitempty"` // Patched chat template from the template cache
+	MMProj     string            `json:"mmproj,omitempty"`        // Vision projector
+	Options    map[string]any    `json:"options"`                 // Options after config, per-model overrides, load options and presets
+	Sources    map[string]string `json:"sources"`                 // Where each option came from, such as "config" or "preset"
+	LaunchedAt time.Time         `json:"launched_at,omitzero"`
+}
+
+// launchEnvPrefixes are the environment variables that change how llama.cpp
+// and the GPU runtimes behave. Anything else is left out of LaunchConfig.Env.
+var launchEnvPrefixes = []string{
+	"LLAMA_", "GGML_", "CUDA_", "HIP_", "ROCR_", "HSA_", "VK_", "OMP_", "MTL_",
+	"LD_LIBRARY_PATH=", "DYLD_",
+}
+
+// launchEnv returns the variables from environ that matter to a backend, with
+// anything that looks like a credential redacted
+func launchEnv(environ []string) []string {
+	var env []string
+	for _, kv := range environ {
+		if !slices.ContainsFunc(launchEnvPrefixes, func(p string) bool { return strings.HasPrefix(kv, p) }) {
+			continue
+		}
+		name, _, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		if strings.Contains(upper, "TOKEN") || strings.Contains(upper, "KEY") ||
+			strings.Contains(upper, "SECRET") || strings.Contains(upper, "PASSWORD") {
+			kv = name + "=REDACTED"
+		}
+		env = append(env, kv)
+	}
+	slices.Sort(env)
+	return env
+}
+
+// argValue returns the value following flag in args, or empty string
+func argValue(args []string, flag string) string {
+	if i := slices.Index(args, flag); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
+
+// launchConfig works out the command that starts backend's server
+func (m *ModelManager) launchConfig(backend *Backend) *LaunchConfig {
+	lc := &LaunchConfig{Path: llama.ServerPath(), Env: launchEnv(os.Environ()), Dir: paths.Bin()}
+	var resolved options.Resolved
+	if backend.Kind == BackendImage {
+		lc.Path = llama.SDServerPath()
+		lc.Args = m.buildImageArgs(backend)
+		if m.appConfig != nil {
+			resolved = options.Resolve(options.Layers{Config: m.appConfig.StableDiffusion.Options})
+		}
+	} else {
+		lc.Args, resolved = m.buildArgs(backend)
+	}
+	lc.Template = argValue(lc.Args, "--chat-template-file")
+	lc.MMProj = argValue(lc.Args, "--mmproj")
+	lc.Options = resolved.Map()
+	lc.Sources = resolved.Sources()
+	return lc
+}
+
+// PlanLaunch works out how a llama-server backend for the model would be
+// started with opts, going through the same resolution, memory check,
+// chat template patching and option merging as a real load, without
+// starting anything. It returns the model's full name and the command.
+func (m *ModelManager) PlanLaunch(modelQuery string, opts map[string]any) (string, *LaunchConfig, error) {
+	model, err := m.resolveModel(modelQuery)
+	if err != nil {
+		return "", nil, err
+	}
+	if !mock.Enabled() {
+		if err := m.checkCompatibility(model.FullName, model.ModelPath); err != nil {
+			return "", nil, err
+		}
+	}
+
+	snap := m.takeMemorySnapshot(model.FullName, model.ModelPath)
+	opts, adjusted, err := m.adjustOptions(model.FullName, snap, opts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Show a port that's free now; a real load allocates its own
+	port, err := m.portAllocator.Allocate()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to allocate port: %w", err)
+	}
+	defer m.portAllocator.Release(port)
+
+	backend := &Backend{
+		Kind:      BackendLlama,
+		ModelName: model.FullName,
+		ModelPath: model.ModelPath,
+		Port:      port,
+		Options:   opts,
+		adjusted:  adjusted,
+	}
+	m.useRecordedGPULayers(backend)
+	return model.FullName, m.launchConfig(backend), nil
+}
+
+// LaunchConfig returns how the backend's process was started, or nil for a
+// backend that hasn't launched one, such as a mock
+func (b *Backend) LaunchConfig() *LaunchConfig {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.launched
+}
+
+func (b *Backend) setLaunchConfig(lc *LaunchConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.launched = lc
+}
+
+// handleBackend routes /api/backends/{model}/config, which returns the
+// command line, environment, chat template and options a loaded model's
+// server was started with
+func (s *Server) handleBackend(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/backends/")
+	name, ok := strings.CutSuffix(name, "/config")
+	if !ok || name == "" {
+		s.writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	backend := s.loadedBackend(name)
+	if backend == nil {
+		s.writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Model '%s' is not loaded", name))
+		return
+	}
+	lc := backend.LaunchConfig()
+	if lc == nil {
+		s.writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("Model '%s' has no server process", backend.ModelName))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, struct {
+		Model string `json:"model"`
+		Kind  string `json:"kind"`
+		Port  int    `json:"port"`
+		*LaunchConfig
+	}{backend.ModelName, backend.Kind.String(), backend.Port, lc})
+}
+
+// loadedBackend finds the ready backend for name, which may be the full
+// model name or anything the resolver matches to one
+func (s *Server) loadedBackend(name string) *Backend {
+	if backend := s.manager.GetBackend(name); backend != nil {
+		return backend
+	}
+	result, err := s.manager.Resolver().Resolve(name)
+	if err != nil || result.Model == nil {
+		return nil
+	}
+	return s.manager.GetBackend(result.Model.FullName)
+}
@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestLaunchEnv(t *testing.T) {
+	got := launchEnv([]string{
+		"PATH=/usr/bin",
+		"HOME=/home/me",
+		"GGML_CUDA_ENABLE_UNIFIED_MEMORY=1",
+		"CUDA_VISIBLE_DEVICES=0,1",
+		"LD_LIBRARY_PATH=/opt/rocm/lib",
+		"LLAMA_API_KEY=secret",
+		"HF_TOKEN=hf_secret",
+	})
+	want := []string{
+		"CUDA_VISIBLE_DEVICES=0,1",
+		"GGML_CUDA_ENABLE_UNIFIED_MEMORY=1",
+		"LD_LIBRARY_PATH=/opt/rocm/lib",
+		"LLAMA_API_KEY=REDACTED",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("launchEnv() = %v, want %v", got, want)
+	}
+}
+
+func TestHandleBackendConfig(t *testing.T) {
+	useTestHome(t)
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), nil)}
+
+	launched := &Backend{ModelName: "user/repo:Q4_K_M", Port: 49152, Status: BackendReady}
+	launched.setLaunchConfig(&LaunchConfig{
+		Path:     "/bin/llama-server",
+		Args:     []string{"--model", "/models/repo.gguf", "--chat-template-file", "/cache/templates/abc.jinja", "--ctx-size", "8192"},
+		Template: "/cache/templates/abc.jinja",
+		Options:  map[string]any{"ctx-size": 8192},
+	})
+	s.manager.backends[launched.ModelName] = launched
+	s.manager.backends["user/mock:Q4_K_M"] = &Backend{ModelName: "user/mock:Q4_K_M", Status: BackendReady}
+	s.manager.backends["user/loading:Q4_K_M"] = &Backend{ModelName: "user/loading:Q4_K_M", Status: BackendStarting}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"launched", http.MethodGet, "/api/backends/user/repo:Q4_K_M/config", http.StatusOK},
+		{"wrong method", http.MethodPost, "/api/backends/user/repo:Q4_K_M/config", http.StatusMethodNotAllowed},
+		{"no config suffix", http.MethodGet, "/api/backends/user/repo:Q4_K_M", http.StatusNotFound},
+		{"not loaded", http.MethodGet, "/api/backends/user/other:Q4_K_M/config", http.StatusNotFound},
+		{"still loading", http.MethodGet, "/api/backends/user/loading:Q4_K_M/config", http.StatusNotFound},
+		{"no process", http.MethodGet, "/api/backends/user/mock:Q4_K_M/config", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleBackend(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	s.handleBackend(w, httptest.NewRequest(http.MethodGet, "/api/backends/user/repo:Q4_K_M/config", nil))
+	var resp struct {
+		Model    string         `json:"model"`
+		Kind     string         `json:"kind"`
+		Port     int            `json:"port"`
+		Path     string         `json:"path"`
+		Args     []string       `json:"args"`
+		Template string         `json:"chat_template"`
+		Options  map[string]any `json:"options"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "user/repo:Q4_K_M" || resp.Kind != "llama" || resp.Port != 49152 || resp.Path != "/bin/llama-server" {
+		t.Errorf("response = %+v", resp)
+	}
+	if len(resp.Args) != 6 || resp.Template != "/cache/templates/abc.jinja" || resp.Options["ctx-size"] != float64(8192) {
+		t.Errorf("launch = %+v", resp)
+	}
+}
+
+func TestArgValue(t *testing.T) {
+	args := []string{"--model", "m.gguf", "--mmproj", "p.gguf", "--jinja"}
+	if got := argValue(args, "--mmproj"); got != "p.gguf" {
+		t.Errorf("argValue(--mmproj) = %q", got)
+	}
+	if got := argValue(args, "--jinja"); got != "" {
+		t.Errorf("argValue(--jinja) = %q, want empty", got)
+	}
+	if got := argValue(args, "--chat-template-file"); got != "" {
+		t.Errorf("argValue(missing) = %q, want empty", got)
+	}
+}
+
+func TestHandleRunDryRun(t *testing.T) {
+	useTestHome(t)
+	writeDownloadedModel(t, "user", "repo", "Q4_K_M")
+
+	appCfg := config.DefaultConfig()
+	appCfg.LlamaCpp.Options = map[string]any{"ctx-size": 2048, "threads": 4}
+	cfg := DefaultConfig()
+	cfg.MemoryGuard = MemoryGuardOff
+	s := &Server{config: cfg, manager: NewModelManager(cfg, appCfg)}
+
+	body := `{"model": "repo", "ctx_size": 8192, "options": {"cache_type_k": "q8_0"}, "dry_run": true}`
+	w := httptest.NewRecorder()
+	s.handleRun(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "user/repo:Q4_K_M" || resp.Status != "dry_run" || resp.Launch == nil {
+		t.Fatalf("response = %+v", resp)
+	}
+	args := strings.Join(resp.Launch.Args, " ")
+	for _, want := range []string{"--model " + hf.GetModelFilePath("user", "repo", "Q4_K_M"), "--ctx-size 8192", "--threads 4", "--cache-type-k q8_0"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("args missing %q: %s", want, args)
+		}
+	}
+	if resp.Launch.Options["ctx-size"] != float64(8192) {
+		t.Errorf("options = %v", resp.Launch.Options)
+	}
+
+	// Nothing was started
+	if len(s.manager.backends) != 0 || s.manager.portAllocator.AllocatedCount() != 0 {
+		t.Error("dry run started a backend")
+	}
+
+	w = httptest.NewRecorder()
+	s.handleRun(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`{"model": "missing", "dry_run": true}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing model status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleRunCheckedOptions(t *testing.T) {
+	useTestHome(t)
+	writeDownloadedModel(t, "user", "repo", "Q4_K_M")
+
+	appCfg := config.DefaultConfig()
+	appCfg.LlamaCpp.Passthrough = map[string]any{"config-flag": "x"}
+	cfg := DefaultConfig()
+	cfg.MemoryGuard = MemoryGuardOff
+	s := &Server{config: cfg, manager: NewModelManager(cfg, appCfg)}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantArgs []string
+	}{
+		{"unknown option", `{"model": "repo", "options": {"new-flag": 1}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"out of range", `{"model": "repo", "options": {"top-p": 2}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"passthrough", `{"model": "repo", "passthrough": {"cache_ram": 2048}, "dry_run": true}`, http.StatusOK, []string{"--cache-ram 2048", "--config-flag x"}},
+		{"passthrough flag not allowed in requests", `{"model": "repo", "passthrough": {"new_flag": 1}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"remote fetch in passthrough", `{"model": "repo", "passthrough": {"hf-repo": "someone/model"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"rpc in passthrough", `{"model": "repo", "passthrough": {"rpc": "10.0.0.5:50052"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"path option", `{"model": "repo", "options": {"mmproj": "/etc/passwd"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"path list option", `{"model": "repo", "options": {"lora": ["/tmp/a.gguf"]}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"path option in passthrough", `{"model": "repo", "passthrough": {"model_draft": "/tmp/d.gguf"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"options win over passthrough", `{"model": "repo", "options": {"ctx-size": 4096}, "passthrough": {"ctx-size": "huge"}, "dry_run": true}`, http.StatusOK, []string{"--ctx-size 4096"}},
+		{"managed flag in passthrough", `{"model": "repo", "passthrough": {"log_file": "/etc/cron.d/x"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"managed model flag", `{"model": "repo", "passthrough": {"model": "/tmp/other.gguf"}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"dashed passthrough key", `{"model": "repo", "passthrough": {"--port": 1}, "dry_run": true}`, http.StatusBadRequest, nil},
+		{"passthrough key with value", `{"model": "repo", "passthrough": {"host=0.0.0.0": true}, "dry_run": true}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleRun(w, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(tt.body)))
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantArgs == nil {
+				return
+			}
+			var resp RunResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Join(resp.Launch.Args, " ")
+			for _, want := range tt.wantArgs {
+				if !strings.Contains(args, want) {
+					t.Errorf("args missing %q: %s", want, args)
+				}
+			}
+		})
+	}
+}
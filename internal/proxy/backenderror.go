@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBackendErrorSize bounds how much of an error body is buffered for
+// rewriting; anything larger is passed through untouched
+const maxBackendErrorSize = 64 << 10
+
+// llamaError is llama-server's error body. Code repeats the HTTP status.
+type llamaError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// openAIErrorMapping is how a llama-server error type appears to OpenAI
+// clients. SDKs retry on 429 and 5xx, so the status decides whether a
+// request is retried.
+type openAIErrorMapping struct {
+	status  int
+	errType string
+	code    string
+	param   string
+}
+
+var llamaErrorMappings = map[string]openAIErrorMapping{
+	"exceed_context_size_error": {http.StatusBadRequest, "invalid_request_error", "context_length_exceeded", "messages"},
+	"invalid_request_error":     {http.StatusBadRequest, "invalid_request_error", "", ""},
+	"not_supported_error":       {http.StatusBadRequest, "invalid_request_error", "not_supported", ""},
+	"authentication_error":      {http.StatusUnauthorized, "authentication_error", "invalid_api_key", ""},
+	"permission_error":          {http.StatusForbidden, "permission_error", "", ""},
+	"not_found_error":           {http.StatusNotFound, "invalid_request_error", "not_found", ""},
+	"unavailable_error":         {http.StatusServiceUnavailable, "server_error", "service_unavailable", ""},
+	"server_error":              {http.StatusInternalServerError, "server_error", "", ""},
+}
+
+// mapBackendError rewrites a llama-server error response into an OpenAI
+// error object with a spec status, type, code and param. Responses that
+// aren't llama-server errors pass through unchanged.
+func mapBackendError(resp *http.Response) error {
+	if resp.StatusCode < 400 || resp.Header.Get("Content-Encoding") != "" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBackendErrorSize+1))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	restore := func() error {
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		return nil
+	}
+
+	var le llamaError
+	if len(data) > maxBackendErrorSize || json.Unmarshal(data, &le) != nil || le.Error.Message == "" {
+		return restore()
+	}
+
+	m, ok := llamaErrorMappings[le.Error.Type]
+	if !ok {
+		m = openAIErrorMapping{status: resp.StatusCode, errType: "invalid_request_error"}
+		if resp.StatusCode >= 500 {
+			m.errType = "server_error"
+		}
+	}
+
+	oe := OpenAIError{
+		Error: OpenAIErrorDetail{
+			Message: le.Error.Message,
+			Type:    m.errType,
+			Code:    m.code,
+			Param:   m.param,
+		},
+		RequestID: resp.Request.Header.Get(requestIDHeader),
+	}
+	data, err = json.Marshal(oe)
+	if err != nil {
+		return err
+	}
+
+	if m.status == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") == "" {
+		// The model is still loading; SDKs back off by this much
+		resp.Header.Set("Retry-After", "1")
+	}
+	resp.StatusCode = m.status
+	resp.Status = strconv.Itoa(m.status) + " " + http.StatusText(m.status)
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return restore()
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMapBackendError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantStatus  int
+		want        *OpenAIErrorDetail // nil when the body passes through
+	}{
+		{
+			name:        "context overflow",
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body:        `{"error":{"code":400,"message":"request exceeds the available context size","type":"exceed_context_size_error","n_prompt_tokens":9000,"n_ctx":4096}}`,
+			wantStatus:  http.StatusBadRequest,
+			want:        &OpenAIErrorDetail{Message: "request exceeds the available context size", Type: "invalid_request_error", Code: "context_length_exceeded", Param: "messages"},
+		},
+		{
+			name:        "loading",
+			status:      http.StatusServiceUnavailable,
+			contentType: "application/json",
+			body:        `{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`,
+			wantStatus:  http.StatusServiceUnavailable,
+			want:        &OpenAIErrorDetail{Message: "Loading model", Type: "server_error", Code: "service_unavailable"},
+		},
+		{
+			name:        "not found",
+			status:      http.StatusNotFound,
+			contentType: "application/json",
+			body:        `{"error":{"code":404,"message":"File Not Found","type":"not_found_error"}}`,
+			wantStatus:  http.StatusNotFound,
+			want:        &OpenAIErrorDetail{Message: "File Not Found", Type: "invalid_request_error", Code: "not_found"},
+		},
+		{
+			name:        "unknown type keeps status",
+			status:      http.StatusBadGateway,
+			contentType: "application/json",
+			body:        `{"error":{"code":502,"message":"upstream","type":"weird_error"}}`,
+			wantStatus:  http.StatusBadGateway,
+			want:        &OpenAIErrorDetail{Message: "upstream", Type: "server_error"},
+		},
+		{
+			name:        "not json",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain",
+			body:        "boom",
+			wantStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:        "success",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        `{"error":{"message":"not really","type":"server_error"}}`,
+			wantStatus:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "http://backend/v1/chat/completions", nil)
+			req.Header.Set(requestIDHeader, "req_1")
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{"Content-Type": {tt.contentType}},
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+				Request:    req,
+			}
+
+			if err := mapBackendError(resp); err != nil {
+				t.Fatalf("mapBackendError() error = %v", err)
+			}
+			data, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			if tt.want == nil {
+				if string(data) != tt.body {
+					t.Errorf("body = %s, want unchanged", data)
+				}
+				return
+			}
+			var got OpenAIError
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("body %s: %v", data, err)
+			}
+			if got.Error != *tt.want || got.RequestID != "req_1" {
+				t.Errorf("error = %+v, want %+v", got, *tt.want)
+			}
+			if resp.Header.Get("Content-Length") != strconv.Itoa(len(data)) {
+				t.Errorf("Content-Length = %s, body is %d bytes", resp.Header.Get("Content-Length"), len(data))
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && resp.Header.Get("Retry-After") == "" {
+				t.Error("503 should carry Retry-After")
+			}
+		})
+	}
+}
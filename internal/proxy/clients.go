@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// clientHeader names the calling client when it has no API key
+const clientHeader = "X-LLeme-Client"
+
+// matchClient returns the server.clients profile for a request, or nil for
+// clients without one. A bearer token or x-api-key that matches no profile
+// isn't an error, since many clients send a placeholder key.
+func (s *Server) matchClient(r *http.Request) *config.Client {
+	key := r.Header.Get("x-api-key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if c := s.config.apiKeyClient(key); c != nil {
+		return c
+	}
+	return s.config.namedClient(r.Header.Get(clientHeader))
+}
+
+// namedClient returns the server.clients profile without an API key called
+// name, or nil
+func (c *Config) namedClient(name string) *config.Client {
+	if name == "" {
+		return nil
+	}
+	for i := range c.Clients {
+		client := &c.Clients[i]
+		if client.APIKey == "" && client.Name == name {
+			return client
+		}
+	}
+	return nil
+}
+
+// clientName names c in logs
+func clientName(c *config.Client) string {
+	if c == nil {
+		return ""
+	}
+	return c.Name
+}
+
+// clientLimiter enforces requests_per_minute with a token bucket per client,
+// so short bursts up to the limit are allowed.
+type clientLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow takes one request from the client's bucket. When it's empty, it
+// returns how long until the next request is allowed.
+func (l *clientLimiter) allow(c *config.Client, now time.Time) (bool, time.Duration) {
+	if c == nil || c.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := float64(c.RequestsPerMinute)
+	perSecond := limit / 60
+	b := l.buckets[c.Name]
+	if b == nil {
+		if l.buckets == nil {
+			l.buckets = make(map[string]*tokenBucket)
+		}
+		b = &tokenBucket{tokens: limit, last: now}
+		l.buckets[c.Name] = b
+	}
+	b.tokens = min(limit, b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// retryAfterSeconds formats a wait for the Retry-After header, rounding up
+func retryAfterSeconds(wait time.Duration) string {
+	return fmt.Sprint(int(math.Ceil(wait.Seconds())))
+}
+
+// ModelNotAllowedError is returned when a client asks for a model outside
+// its profile's models list
+type ModelNotAllowedError struct {
+	Client string
+	Model  string
+}
+
+func (e *ModelNotAllowedError) Error() string {
+	return fmt.Sprintf("client '%s' may not use model '%s'", e.Client, e.Model)
+}
+
+// clientAllowsModel reports whether c may use the model named name. Entries
+// match the full name, or the repository for any of its quants.
+func clientAllowsModel(c *config.Client, name string) bool {
+	if c == nil || len(c.Models) == 0 {
+		return true
+	}
+	repo, _, _ := strings.Cut(name, ":")
+	for _, m := range c.Models {
+		if strings.EqualFold(m, name) || strings.EqualFold(m, repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictModels wraps load so that it refuses models c may not use. A name
+// that resolves is checked before anything is loaded; anything else, like a
+// default model fallback, is checked once load has picked the model.
+func (s *Server) restrictModels(c *config.Client, load func(model string) (*Backend, error)) func(model string) (*Backend, error) {
+	if c == nil || len(c.Models) == 0 {
+		return load
+	}
+	return func(model string) (*Backend, error) {
+		if result, err := s.manager.Resolver().Resolve(model); err == nil && result.Model != nil {
+			if !clientAllowsModel(c, result.Model.FullName) {
+				return nil, &ModelNotAllowedError{Client: c.Name, Model: result.Model.FullName}
+			}
+		}
+		backend, err := load(model)
+		if err != nil {
+			return nil, err
+		}
+		if !clientAllowsModel(c, backend.ModelName) {
+			return nil, &ModelNotAllowedError{Client: c.Name, Model: backend.ModelName}
+		}
+		return backend, nil
+	}
+}
+
+// maxTokenFields are the request fields that bound generation length
+var maxTokenFields = []string{"max_tokens", "max_completion_tokens", "n_predict"}
+
+// applyClientLimits caps a generation request's length at the client's
+// max_tokens and overwrites sampling fields with its forced options.
+func applyClientLimits(c *config.Client, body []byte) ([]byte, error) {
+	if c == nil || (c.MaxTokens <= 0 && len(c.Options) == 0) {
+		return body, nil
+	}
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	if c.MaxTokens > 0 {
+		capMaxTokens(req, c.MaxTokens)
+	}
+
+	for key, field := range personaSamplingFields {
+		if val, ok := c.Options[key]; ok {
+			req[field] = val
+		}
+	}
+
+	return json.Marshal(req)
+}
+
+// capMaxTokens lowers each length field of req to limit, adding max_tokens
+// when the request sets none
+func capMaxTokens(req map[string]any, limit int) {
+	capped := false
+	for _, field := range maxTokenFields {
+		n, ok := req[field].(float64)
+		if !ok {
+			continue
+		}
+		// llama-server reads -1 as "until the context is full"
+		if n < 0 || n > float64(limit) {
+			req[field] = limit
+		}
+		capped = true
+	}
+	if !capped {
+		req["max_tokens"] = limit
+	}
+}
+
+// applyOutputLimits bounds a generation request's length for every client:
+// a request that sets no length gets defaultTokens, and none may exceed
+// maxTokens. Either is off at 0.
+func applyOutputLimits(body []byte, maxTokens, defaultTokens int) ([]byte, error) {
+	if maxTokens <= 0 && defaultTokens <= 0 {
+		return body, nil
+	}
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+
+	if defaultTokens > 0 && !slices.ContainsFunc(maxTokenFields, func(field string) bool { return req[field] != nil }) {
+		// As decoded JSON, so the cap below compares it like any other length
+		req["max_tokens"] = float64(defaultTokens)
+	}
+	if maxTokens > 0 {
+		capMaxTokens(req, maxTokens)
+	}
+	return json.Marshal(req)
+}
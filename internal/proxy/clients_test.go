@@ -0,0 +1,267 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestMatchClient(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Clients = []config.Client{
+		{Name: "ci", APIKey: "secret"},
+		{Name: "notebook"},
+	}
+	s := &Server{config: cfg}
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{"bearer key", map[string]string{"Authorization": "Bearer secret"}, "ci"},
+		{"anthropic key", map[string]string{"x-api-key": "secret"}, "ci"},
+		{"wrong key", map[string]string{"Authorization": "Bearer sk-placeholder"}, ""},
+		{"header", map[string]string{clientHeader: "notebook"}, "notebook"},
+		{"header can't claim keyed client", map[string]string{clientHeader: "ci"}, ""},
+		{"anonymous", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := clientName(s.matchClient(r)); got != tt.want {
+				t.Errorf("matchClient() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientLimiter(t *testing.T) {
+	var l clientLimiter
+	c := &config.Client{Name: "ci", RequestsPerMinute: 2}
+	now := time.Now()
+
+	for i := range 2 {
+		if ok, _ := l.allow(c, now); !ok {
+			t.Fatalf("request %d refused within the limit", i+1)
+		}
+	}
+	ok, wait := l.allow(c, now)
+	if ok {
+		t.Fatal("request over the limit allowed")
+	}
+	if wait <= 0 || wait > 30*time.Second {
+		t.Errorf("wait = %v, want up to 30s", wait)
+	}
+	if ok, _ := l.allow(c, now.Add(30*time.Second)); !ok {
+		t.Error("request refused after the bucket refilled")
+	}
+
+	if ok, _ := l.allow(nil, now); !ok {
+		t.Error("anonymous request refused")
+	}
+}
+
+func TestClientAllowsModel(t *testing.T) {
+	c := &config.Client{Models: []string{"bartowski/Llama-3.2-3B-Instruct-GGUF", "microsoft/phi-2-gguf:Q4_0"}}
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"bartowski/Llama-3.2-3B-Instruct-GGUF:Q8_0", true},
+		{"microsoft/phi-2-gguf:Q4_0", true},
+		{"microsoft/phi-2-gguf:Q8_0", false},
+		{"bartowski/Mistral-7B-Instruct-v0.3-GGUF:Q4_K_M", false},
+	}
+	for _, tt := range tests {
+		if got := clientAllowsModel(c, tt.model); got != tt.want {
+			t.Errorf("clientAllowsModel(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+	if !clientAllowsModel(&config.Client{}, "any/model:Q4_0") {
+		t.Error("client without a models list was restricted")
+	}
+}
+
+func TestRestrictModels(t *testing.T) {
+	useTestHome(t)
+	m := NewModelManager(DefaultConfig(), nil)
+	m.resolver = setupTestModels(t)
+	s := &Server{config: DefaultConfig(), manager: m}
+	c := &config.Client{Name: "ci", Models: []string{"microsoft/phi-2-gguf"}}
+
+	loaded := ""
+	load := s.restrictModels(c, func(model string) (*Backend, error) {
+		loaded = model
+		return &Backend{ModelName: "microsoft/phi-2-gguf:Q4_0"}, nil
+	})
+
+	var notAllowed *ModelNotAllowedError
+	if _, err := load("mistral-7b-instruct-v0.3"); !errors.As(err, &notAllowed) {
+		t.Fatalf("load(disallowed) error = %v, want ModelNotAllowedError", err)
+	}
+	if loaded != "" {
+		t.Error("disallowed model was loaded")
+	}
+	if _, err := load("phi-2"); err != nil {
+		t.Errorf("load(allowed) error = %v", err)
+	}
+}
+
+func TestApplyClientLimits(t *testing.T) {
+	c := &config.Client{MaxTokens: 100, Options: map[string]any{"temp": 0.1}}
+
+	tests := []struct {
+		name string
+		body string
+		want map[string]any
+	}{
+		{"adds cap", `{"temperature":1.5}`, map[string]any{"max_tokens": 100.0, "temperature": 0.1}},
+		{"lowers max_tokens", `{"max_tokens":4096}`, map[string]any{"max_tokens": 100.0}},
+		{"keeps smaller max_tokens", `{"max_tokens":10}`, map[string]any{"max_tokens": 10.0}},
+		{"caps unlimited", `{"max_completion_tokens":-1}`, map[string]any{"max_completion_tokens": 100.0, "max_tokens": nil}},
+		{"caps n_predict", `{"n_predict":500}`, map[string]any{"n_predict": 100.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := applyClientLimits(c, []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var req map[string]any
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if req[k] != want {
+					t.Errorf("%s = %v, want %v", k, req[k], want)
+				}
+			}
+		})
+	}
+
+	body := []byte(`{"max_tokens":4096}`)
+	if got, _ := applyClientLimits(nil, body); string(got) != string(body) {
+		t.Error("request without a client was modified")
+	}
+}
+
+func TestApplyOutputLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxTokens  int
+		defaultTok int
+		body       string
+		want       map[string]any
+	}{
+		{"default fills in", 0, 512, `{}`, map[string]any{"max_tokens": 512.0}},
+		{"default keeps request", 0, 512, `{"max_tokens":2000}`, map[string]any{"max_tokens": 2000.0}},
+		{"default respects other fields", 0, 512, `{"n_predict":50}`, map[string]any{"n_predict": 50.0, "max_tokens": nil}},
+		{"cap adds max_tokens", 4096, 0, `{}`, map[string]any{"max_tokens": 4096.0}},
+		{"cap lowers request", 4096, 0, `{"max_tokens":100000}`, map[string]any{"max_tokens": 4096.0}},
+		{"cap lowers unlimited", 4096, 0, `{"max_tokens":-1}`, map[string]any{"max_tokens": 4096.0}},
+		{"cap lowers default", 256, 512, `{}`, map[string]any{"max_tokens": 256.0}},
+		{"both under cap", 4096, 512, `{}`, map[string]any{"max_tokens": 512.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := applyOutputLimits([]byte(tt.body), tt.maxTokens, tt.defaultTok)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var req map[string]any
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if req[k] != want {
+					t.Errorf("%s = %v, want %v", k, req[k], want)
+				}
+			}
+		})
+	}
+
+	body := []byte(`{"max_tokens":100000}`)
+	if got, _ := applyOutputLimits(body, 0, 0); string(got) != string(body) {
+		t.Error("request was modified with no limits set")
+	}
+}
+
+func TestClientModelsOnManagementEndpoints(t *testing.T) {
+	useTestHome(t)
+	writeDownloadedModel(t, "user", "allowed", "Q4_K_M")
+	writeDownloadedModel(t, "user", "other", "Q4_K_M")
+
+	cfg := DefaultConfig()
+	cfg.MemoryGuard = MemoryGuardOff
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "secret", Models: []string{"user/allowed"}}}
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil)}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		handler    http.HandlerFunc
+		wantStatus int
+	}{
+		{"run allowed", http.MethodPost, "/api/run", `{"model": "user/allowed", "dry_run": true}`, s.handleRun, http.StatusOK},
+		{"run other", http.MethodPost, "/api/run", `{"model": "user/other", "dry_run": true}`, s.handleRun, http.StatusForbidden},
+		{"stop other", http.MethodPost, "/api/stop", `{"model": "user/other"}`, s.handleStopModel, http.StatusForbidden},
+		{"stop all", http.MethodPost, "/api/stop-all", ``, s.handleStopAll, http.StatusForbidden},
+		{"pull other", http.MethodPost, "/api/pull", `{"model": "user/other:Q8_0"}`, s.handlePull, http.StatusForbidden},
+		{"remove other", http.MethodDelete, "/api/models/user/other", ``, s.handleManageModel, http.StatusForbidden},
+		{"update other", http.MethodPost, "/api/models/user/other/update", ``, s.handleManageModel, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+
+	if _, err := os.Stat(hf.GetModelFilePath("user", "other", "Q4_K_M")); err != nil {
+		t.Errorf("disallowed model was removed: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	s.handleModels(w, r)
+	var resp OpenAIModelsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 1 || resp.Data[0].ID != "user/allowed:Q4_K_M" {
+		t.Errorf("/v1/models for a restricted client = %+v, want only user/allowed:Q4_K_M", resp.Data)
+	}
+
+	// Unrestricted callers still see everything
+	w = httptest.NewRecorder()
+	s.handleModels(w, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 2 {
+		t.Errorf("/v1/models without a client lists %d models, want 2", len(resp.Data))
+	}
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/llama"
+)
+
+// installedLlamaVersion reads the installed llama.cpp release. Overridden in
+// tests.
+var installedLlamaVersion = llama.GetInstalledVersion
+
+// IncompatibleModelError is returned when a model's architecture is newer
+// than the installed llama.cpp build
+type IncompatibleModelError struct {
+	Model        string
+	Architecture string
+	Required     int    // First build that supports Architecture
+	Installed    string // Installed release tag
+}
+
+func (e *IncompatibleModelError) Error() string {
+	return fmt.Sprintf("'%s' uses the %s architecture, which needs llama.cpp ≥ b%d (installed: %s), run 'lleme update llama.cpp'",
+		e.Model, e.Architecture, e.Required, e.Installed)
+}
+
+// checkCompatibility refuses to launch a model whose architecture the
+// installed llama.cpp can't load, rather than let llama-server fail with an
+// unknown architecture. Anything that can't be read is left to llama-server.
+func (m *ModelManager) checkCompatibility(modelName, modelPath string) error {
+	params, err := hf.ReadGGUFModelParams(modelPath)
+	if err != nil {
+		return nil
+	}
+	required, ok := llama.RequiredBuild(params.Architecture)
+	if !ok {
+		return nil
+	}
+	installed, err := installedLlamaVersion()
+	if err != nil || installed == nil {
+		return nil
+	}
+	build, ok := llama.ParseBuild(installed.TagName)
+	if !ok || build >= required {
+		return nil
+	}
+	return &IncompatibleModelError{
+		Model:        modelName,
+		Architecture: params.Architecture,
+		Required:     required,
+		Installed:    installed.TagName,
+	}
+}
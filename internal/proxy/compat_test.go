@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/llama"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	installed := &llama.VersionInfo{TagName: "b5000"}
+	var installedErr error
+	orig := installedLlamaVersion
+	installedLlamaVersion = func() (*llama.VersionInfo, error) { return installed, installedErr }
+	t.Cleanup(func() { installedLlamaVersion = orig })
+
+	gptOSS := writeTestModel(t, &hf.GGUFModelParams{Architecture: "gpt-oss", BlockCount: 24}, 1024)
+	llamaModel := writeTestModel(t, llama3B, 1024)
+	m := NewModelManager(DefaultConfig(), nil)
+
+	err := m.checkCompatibility("openai/gpt-oss-20b-GGUF:MXFP4", gptOSS)
+	var incompatible *IncompatibleModelError
+	if !errors.As(err, &incompatible) {
+		t.Fatalf("checkCompatibility() = %v, want IncompatibleModelError", err)
+	}
+	if incompatible.Required != 6096 || incompatible.Installed != "b5000" {
+		t.Errorf("error = %+v", incompatible)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "llama.cpp ≥ b6096") || !strings.Contains(msg, "lleme update llama.cpp") {
+		t.Errorf("message = %q", msg)
+	}
+
+	s := &Server{config: DefaultConfig(), manager: m}
+	w := httptest.NewRecorder()
+	s.handleModelError(w, err)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "backend_outdated") {
+		t.Errorf("response = %d %s", w.Code, w.Body)
+	}
+
+	if err := m.checkCompatibility("user/repo:Q4_K_M", llamaModel); err != nil {
+		t.Errorf("checkCompatibility() for an old architecture = %v", err)
+	}
+
+	installed = &llama.VersionInfo{TagName: "b6096"}
+	if err := m.checkCompatibility("openai/gpt-oss-20b-GGUF:MXFP4", gptOSS); err != nil {
+		t.Errorf("checkCompatibility() on a new enough build = %v", err)
+	}
+
+	// Builds that can't be identified are left to llama-server
+	installed = &llama.VersionInfo{TagName: "custom"}
+	if err := m.checkCompatibility("openai/gpt-oss-20b-GGUF:MXFP4", gptOSS); err != nil {
+		t.Errorf("checkCompatibility() with an unknown build = %v", err)
+	}
+	installed, installedErr = nil, errors.New("unreadable")
+	if err := m.checkCompatibility("openai/gpt-oss-20b-GGUF:MXFP4", gptOSS); err != nil {
+		t.Errorf("checkCompatibility() without version info = %v", err)
+	}
+}
@@ -13,6 +13,7 @@ func stripCORSHeaders(resp *http.Response) error {
 	resp.Header.Del("Access-Control-Allow-Headers")
 	resp.Header.Del("Access-Control-Max-Age")
 	resp.Header.Del("Access-Control-Allow-Credentials")
+	resp.Header.Del("Access-Control-Expose-Headers")
 	return nil
 }
 
@@ -27,7 +28,8 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 			if origin != "" && isAllowedOrigin(origin, allowedOrigins) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-Requested-With")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, X-Requested-With, X-Request-ID, X-LLeme-Persona, X-LLeme-Client, X-LLeme-Session, X-LLeme-JSON-Repair, x-api-key")
+				w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, request-id, X-LLeme-JSON-Repaired")
 				w.Header().Set("Access-Control-Max-Age", "86400")
 			}
 
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// registerPprof serves the Go profiler under /debug/pprof. Profiles show
+// prompts in memory and command lines, so this is off unless configured,
+// and only answers this machine unless remote is set, which it is when
+// requireAPIKey checks other machines' keys.
+func registerPprof(mux *http.ServeMux, remote bool) {
+	handle := func(pattern string, h http.HandlerFunc) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if !remote && !isLocalRequest(r) {
+				http.Error(w, "The profiler only answers requests from this machine unless API keys are configured", http.StatusForbidden)
+				return
+			}
+			h(w, r)
+		})
+	}
+	handle("/debug/pprof/", pprof.Index)
+	handle("/debug/pprof/cmdline", pprof.Cmdline)
+	handle("/debug/pprof/profile", pprof.Profile)
+	handle("/debug/pprof/symbol", pprof.Symbol)
+	handle("/debug/pprof/trace", pprof.Trace)
+}
+
+// DumpState writes what a hung server is doing: requests in flight,
+// background jobs, each backend's state, and every goroutine's stack.
+func (s *Server) DumpState(w io.Writer) {
+	fmt.Fprintf(w, "=== lleme %s state dump at %s\n", version.Version, time.Now().Format(time.RFC3339))
+	fmt.Fprintf(w, "uptime: %s\n", time.Since(s.startedAt).Round(time.Second))
+	fmt.Fprintf(w, "requests in flight: %d\n", s.active.Load())
+	fmt.Fprintf(w, "goroutines: %d\n", runtime.NumGoroutine())
+
+	running, finished := 0, 0
+	for _, j := range s.jobs.list(nil) {
+		if j.Status == JobRunning {
+			running++
+		} else {
+			finished++
+		}
+	}
+	fmt.Fprintf(w, "jobs: %d running, %d finished\n", running, finished)
+
+	backends := s.manager.ListBackends()
+	fmt.Fprintf(w, "backends: %d\n", len(backends))
+	if len(backends) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  MODEL\tSTATUS\tPID\tPORT\tIDLE")
+		for _, b := range backends {
+			idle := time.Duration(b.IdleMinutes * float64(time.Minute)).Round(time.Second)
+			fmt.Fprintf(tw, "  %s\t%s\t%d\t%d\t%s\n", b.ModelName, b.Status, b.PID, b.Port, idle)
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w, "=== goroutines")
+	runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+	fmt.Fprintln(w, "=== end of state dump")
+}
+
+// dumpStateToLog writes DumpState to the server log
+func (s *Server) dumpStateToLog() {
+	logs.Info("Dumping server state")
+	s.DumpState(logs.Writer())
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package proxy
+
+// watchDumpSignal does nothing where there's no SIGUSR1. Use /debug/pprof
+// instead.
+func (s *Server) watchDumpSignal() {}
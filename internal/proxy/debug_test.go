@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the dump goroutine while
+// a test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func newDumpServer() *Server {
+	cfg := DefaultConfig()
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil), startedAt: time.Now(), shutdownChan: make(chan struct{})}
+	s.manager.backends["user/repo:Q4_K_M"] = &Backend{ModelName: "user/repo:Q4_K_M", Port: 49152, Status: BackendStarting}
+	s.active.Add(2)
+	s.jobs.add(&job{id: "job-1", status: JobRunning, changed: make(chan struct{})})
+	return s
+}
+
+func TestDumpState(t *testing.T) {
+	var buf bytes.Buffer
+	newDumpServer().DumpState(&buf)
+	out := buf.String()
+
+	for _, want := range []string{
+		"requests in flight: 2",
+		"jobs: 1 running, 0 finished",
+		"backends: 1",
+		"user/repo:Q4_K_M  starting",
+		"=== goroutines",
+		"TestDumpState", // This goroutine's own stack
+		"=== end of state dump",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump is missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegisterPprof(t *testing.T) {
+	mux := http.NewServeMux()
+	registerPprof(mux, false)
+	rec := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil)
+	r.RemoteAddr = "127.0.0.1:5000"
+	mux.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine profile") {
+		t.Errorf("GET /debug/pprof/goroutine = %d %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+package proxy
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchDumpSignal dumps the server's state to its log each time the
+// process gets SIGUSR1, until the server stops.
+func (s *Server) watchDumpSignal() {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(sig)
+		for {
+			select {
+			case <-sig:
+				s.dumpStateToLog()
+			case <-s.shutdownChan:
+				return
+			}
+		}
+	}()
+}
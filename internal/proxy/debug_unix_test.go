@@ -0,0 +1,36 @@
+//go:build unix
+
+package proxy
+
+import (
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+func TestDumpSignal(t *testing.T) {
+	var buf syncBuffer
+	logs.InitLogger(&buf, 0)
+	defer logs.InitLogger(nil, 0)
+
+	s := newDumpServer()
+	s.watchDumpSignal()
+	defer close(s.shutdownChan)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "=== end of state dump") {
+		if time.Now().After(deadline) {
+			t.Fatalf("no state dump in the log after SIGUSR1:\n%s", buf.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "user/repo:Q4_K_M") {
+		t.Errorf("dump is missing the backend:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// hostedModelPrefixes match model names of hosted APIs that clients often
+// hard-code. Each also matches the bare name without its trailing dash.
+var hostedModelPrefixes = []string{
+	"gpt-", "chatgpt-", "o1-", "o3-", "o4-",
+	"text-", "davinci-", "babbage-",
+	"claude-", "gemini-",
+}
+
+// isHostedModelName reports whether name looks like a hosted API's model
+// rather than a local one, e.g. "gpt-3.5-turbo" or "claude-sonnet-4".
+func isHostedModelName(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range hostedModelPrefixes {
+		if strings.HasPrefix(name, p) || name == strings.TrimSuffix(p, "-") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWithDefault loads model with load, falling back to server.default_model
+// when the request names no model, or, outside strict mode, names a hosted
+// model that isn't downloaded. Other unknown names still fail so typos
+// aren't hidden.
+func (s *Server) loadWithDefault(model string, load func(model string) (*Backend, error)) (*Backend, error) {
+	def := s.config.DefaultModel
+	if model == "" && def != "" {
+		return load(def)
+	}
+
+	backend, err := load(model)
+	var notFound *ModelNotFoundError
+	if errors.As(err, &notFound) && def != "" && !s.config.DefaultStrict && isHostedModelName(model) {
+		logs.Debug("Using default model", "requested", model, "model", def)
+		return load(def)
+	}
+	return backend, err
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsHostedModelName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"gpt-3.5-turbo", true},
+		{"GPT-4o", true},
+		{"o1", true},
+		{"o3-mini", true},
+		{"text-embedding-3-small", true},
+		{"claude-sonnet-4-20250514", true},
+		{"llama3", false},
+		{"bartowski/Llama-3.2-3B-Instruct-GGUF", false},
+		{"o1x", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isHostedModelName(tt.name); got != tt.want {
+			t.Errorf("isHostedModelName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadWithDefault(t *testing.T) {
+	// load knows only "local" and "fallback"
+	load := func(model string) (*Backend, error) {
+		if model == "local" || model == "fallback" {
+			return &Backend{ModelName: model}, nil
+		}
+		return nil, &ModelNotFoundError{Query: model}
+	}
+
+	tests := []struct {
+		name         string
+		defaultModel string
+		strict       bool
+		requested    string
+		want         string // loaded model, "" for not found
+	}{
+		{"no default, empty", "", false, "", ""},
+		{"no default, hosted", "", false, "gpt-4o", ""},
+		{"empty uses default", "fallback", false, "", "fallback"},
+		{"hosted uses default", "fallback", false, "gpt-3.5-turbo", "fallback"},
+		{"typo still fails", "fallback", false, "lama3", ""},
+		{"local model wins", "fallback", false, "local", "local"},
+		{"strict, empty uses default", "fallback", true, "", "fallback"},
+		{"strict, hosted fails", "fallback", true, "gpt-4o", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.DefaultModel = tt.defaultModel
+			cfg.DefaultStrict = tt.strict
+			s := &Server{config: cfg}
+
+			backend, err := s.loadWithDefault(tt.requested, load)
+			if tt.want == "" {
+				var notFound *ModelNotFoundError
+				if !errors.As(err, &notFound) {
+					t.Fatalf("loadWithDefault(%q) error = %v, want ModelNotFoundError", tt.requested, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadWithDefault(%q) error = %v", tt.requested, err)
+			}
+			if backend.ModelName != tt.want {
+				t.Errorf("loadWithDefault(%q) loaded %q, want %q", tt.requested, backend.ModelName, tt.want)
+			}
+		})
+	}
+}
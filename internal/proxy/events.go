@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies a backend lifecycle change
+type EventType string
+
+const (
+	EventLoading    EventType = "loading"
+	EventLoaded     EventType = "loaded"
+	EventLoadFailed EventType = "load_failed"
+	EventUnloaded   EventType = "unloaded"
+)
+
+// Event describes a change in a backend's lifecycle
+type Event struct {
+	Type  EventType
+	Model string
+	Time  time.Time
+}
+
+// eventBufferSize is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const eventBufferSize = 64
+
+// eventBus fans out events to subscribers without blocking the publisher
+type eventBus struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func (b *eventBus) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = make(map[chan Event]struct{})
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+func (b *eventBus) publish(eventType EventType, model string) {
+	e := Event{Type: eventType, Model: model, Time: time.Now()}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
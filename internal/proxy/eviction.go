@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// Eviction policies (server.eviction): which model is unloaded to make room
+// for another when max_models are loaded
+const (
+	EvictionLRU      = "lru"      // The least recently used
+	EvictionSize     = "size"     // The biggest, freeing the most memory
+	EvictionPriority = "priority" // The lowest weighted in server.priorities
+)
+
+// checkEviction rejects an unknown server.eviction
+func (c *Config) checkEviction() error {
+	switch c.Eviction {
+	case "", EvictionLRU, EvictionSize, EvictionPriority:
+		return nil
+	}
+	return fmt.Errorf("invalid eviction %q: expected lru, size, or priority", c.Eviction)
+}
+
+// evictionCandidate returns the model to unload for a new one under the
+// configured policy, or "" when none is loaded. Ties, and unknown policies,
+// fall back to least recently used. Caller must hold m.mu.
+func (m *ModelManager) evictionCandidate() string {
+	var better func(a, b string) bool // Whether a should go before b
+	switch m.config.Eviction {
+	case EvictionSize:
+		sizes := make(map[string]int64, len(m.lruOrder))
+		for _, name := range m.lruOrder {
+			sizes[name] = m.backendSize(name)
+		}
+		better = func(a, b string) bool { return sizes[a] > sizes[b] }
+	case EvictionPriority:
+		better = func(a, b string) bool {
+			return m.config.Priorities.For(a) < m.config.Priorities.For(b)
+		}
+	default:
+		return m.getLRUModel()
+	}
+
+	// Walk from least to most recently used, so the first of equals wins
+	candidate := ""
+	for i := len(m.lruOrder) - 1; i >= 0; i-- {
+		name := m.lruOrder[i]
+		if candidate == "" || better(name, candidate) {
+			candidate = name
+		}
+	}
+	return candidate
+}
+
+// evict unloads victim to make room for another model. The caller holds
+// m.mu, which is released while the backend stops and held again on return.
+func (m *ModelManager) evict(victim string) error {
+	// Mark as stopping to prevent concurrent eviction race
+	if backend := m.backends[victim]; backend != nil {
+		backend.SetStatus(BackendStopping)
+	}
+	m.mu.Unlock()
+	err := m.StopBackend(victim)
+	m.mu.Lock()
+	if err != nil {
+		return fmt.Errorf("failed to evict model: %w", err)
+	}
+	return nil
+}
+
+// backendSize returns the size of a loaded model's file, or 0 if it can't
+// be read
+func (m *ModelManager) backendSize(modelName string) int64 {
+	backend := m.backends[modelName]
+	if backend == nil || backend.ModelPath == "" {
+		return 0
+	}
+	info, err := os.Stat(backend.ModelPath)
+	if err != nil {
+		logs.Debug("Failed to read model size for eviction", "model", modelName, "error", err)
+		return 0
+	}
+	return info.Size()
+}
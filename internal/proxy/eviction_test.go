@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestEvictionCandidate(t *testing.T) {
+	dir := t.TempDir()
+	sizes := map[string]int{"a/small": 10, "b/big": 300, "c/medium": 200, "d/big-too": 300}
+
+	newManager := func(policy string, priorities config.ModelPriorities) *ModelManager {
+		cfg := DefaultConfig()
+		cfg.Eviction = policy
+		cfg.Priorities = priorities
+		m := NewModelManager(cfg, nil)
+		for name, size := range sizes {
+			path := filepath.Join(dir, filepath.Base(name)+".gguf")
+			if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+				t.Fatal(err)
+			}
+			m.backends[name] = &Backend{ModelName: name, ModelPath: path}
+		}
+		// Most recently used first
+		m.lruOrder = []string{"a/small", "b/big", "c/medium", "d/big-too"}
+		return m
+	}
+
+	tests := []struct {
+		name       string
+		policy     string
+		priorities config.ModelPriorities
+		want       string
+	}{
+		{"lru", EvictionLRU, nil, "d/big-too"},
+		{"default is lru", "", nil, "d/big-too"},
+		{"size ties go to lru", EvictionSize, nil, "d/big-too"},
+		{"priority", EvictionPriority, config.ModelPriorities{"d/big-too": 5, "c/medium": 5, "b/big": 1, "default": 3}, "b/big"},
+		{"priority ties go to lru", EvictionPriority, nil, "d/big-too"},
+		{"priority by repo", EvictionPriority, config.ModelPriorities{"default": 5, "small": 1}, "a/small"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newManager(tt.policy, tt.priorities)
+			if got := m.evictionCandidate(); got != tt.want {
+				t.Errorf("evictionCandidate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("size picks the biggest", func(t *testing.T) {
+		m := newManager(EvictionSize, nil)
+		m.lruOrder = []string{"d/big-too", "a/small", "b/big", "c/medium"}
+		if got := m.evictionCandidate(); got != "b/big" {
+			t.Errorf("evictionCandidate() = %q, want b/big", got)
+		}
+	})
+
+	t.Run("nothing loaded", func(t *testing.T) {
+		m := NewModelManager(DefaultConfig(), nil)
+		m.config.Eviction = EvictionSize
+		if got := m.evictionCandidate(); got != "" {
+			t.Errorf("evictionCandidate() = %q, want empty", got)
+		}
+	})
+}
+
+func TestCheckEviction(t *testing.T) {
+	for _, policy := range []string{"", EvictionLRU, EvictionSize, EvictionPriority} {
+		if err := (&Config{Eviction: policy}).checkEviction(); err != nil {
+			t.Errorf("checkEviction(%q) = %v", policy, err)
+		}
+	}
+	if err := (&Config{Eviction: "random"}).checkEviction(); err == nil {
+		t.Error("checkEviction(random) should fail")
+	}
+}
@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// vramErrors are lowercased log lines llama.cpp's GPU backends print when
+// they run out of device memory allocating the model or its KV cache
+var vramErrors = []string{
+	"cudamalloc failed: out of memory",
+	"hipmalloc failed: out of memory",
+	"erroroutofdevicememory", // Vulkan
+	"kiogpucommandbuffercallbackerroroutofmemory",
+	"ggml_metal_buffer_init: error: failed to allocate buffer",
+}
+
+// vramBufferRe matches allocation failures of a GPU buffer ("unable to
+// allocate CUDA0 buffer"), but not of a CPU one, which fewer GPU layers
+// would only make worse
+var vramBufferRe = regexp.MustCompile(`(unable|failed) to allocate (cuda|rocm|vulkan|metal|sycl)\d* buffer`)
+
+// errNoGPULayersFit is returned when a model runs out of VRAM even with no
+// layers offloaded, leaving only its KV cache and compute buffers on the GPU
+var errNoGPULayersFit = errors.New("out of VRAM even without GPU layers")
+
+// isVRAMError reports whether a log line is a GPU out of memory error
+func isVRAMError(line string) bool {
+	line = strings.ToLower(line)
+	for _, e := range vramErrors {
+		if strings.Contains(line, e) {
+			return true
+		}
+	}
+	return vramBufferRe.MatchString(line)
+}
+
+// ranOutOfVRAM reports whether a backend's log shows it failed to start
+// for lack of VRAM
+func ranOutOfVRAM(logFile string) bool {
+	file, err := os.Open(logFile)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if isVRAMError(scanner.Text()) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseModelRef splits a "user/repo:quant" model name into its parts
+func parseModelRef(modelName string) (user, repo, quant string, ok bool) {
+	repoRef, quant, ok := strings.Cut(modelName, ":")
+	if !ok {
+		return "", "", "", false
+	}
+	user, repo, ok = strings.Cut(repoRef, "/")
+	if !ok || strings.Contains(repo, "/") {
+		return "", "", "", false
+	}
+	return user, repo, quant, true
+}
+
+// canTuneGPULayers reports whether a backend's GPU layers are lleme's to
+// choose: a llama-server whose load request didn't ask for a number
+func canTuneGPULayers(backend *Backend) bool {
+	_, set := backend.Options["gpu-layers"]
+	return backend.Kind == BackendLlama && !set
+}
+
+// useRecordedGPULayers offloads the number of layers an earlier load found
+// fit in VRAM, when lleme chooses them
+func (m *ModelManager) useRecordedGPULayers(backend *Backend) {
+	if !canTuneGPULayers(backend) {
+		return
+	}
+	user, repo, quant, ok := parseModelRef(backend.ModelName)
+	if !ok {
+		return
+	}
+	if layers, ok := hf.GetGPULayers(user, repo, quant); ok {
+		logs.Debug("Using recorded GPU layers", "model", backend.ModelName, "gpu_layers", layers)
+		backend.gpuLayers = &layers
+	}
+}
+
+// offloadedLayers returns how many layers the failed load tried to put on
+// the GPU, with auto, all, and values past the model's layer count as every
+// layer plus the output layer
+func (m *ModelManager) offloadedLayers(backend *Backend) (int, error) {
+	if backend.gpuLayers != nil {
+		return *backend.gpuLayers, nil
+	}
+	params, err := hf.ReadGGUFModelParams(backend.ModelPath)
+	if err != nil {
+		return 0, err
+	}
+	all := params.BlockCount + 1
+
+	var layers int
+	switch v := m.resolveOptions(backend, false).Map()["gpu-layers"].(type) {
+	case int:
+		layers = v
+	case float64:
+		layers = int(v)
+	case string:
+		if layers, err = strconv.Atoi(v); err != nil {
+			return all, nil
+		}
+	default:
+		return all, nil
+	}
+	if layers < 0 || layers > all {
+		return all, nil
+	}
+	return layers, nil
+}
+
+// tuneGPULayers restarts a backend that ran out of VRAM with fewer GPU
+// layers, searching for the most that fit, and records them for the next
+// load. The backend is left running on success.
+func (m *ModelManager) tuneGPULayers(backend *Backend) error {
+	failed, err := m.offloadedLayers(backend)
+	if err != nil {
+		return fmt.Errorf("failed to read model layers: %w", err)
+	}
+	if failed == 0 {
+		return errNoGPULayersFit
+	}
+	logs.Info("Model ran out of VRAM, searching for GPU layers that fit", "model", backend.ModelName, "gpu_layers", failed)
+
+	try := func(layers int) (bool, error) {
+		if backend.GetStatus() != BackendStarting {
+			return false, errors.New("backend stopped while searching for GPU layers")
+		}
+		backend.gpuLayers = &layers
+		select {
+		case backend.retrying <- struct{}{}:
+		default:
+		}
+		logs.Debug("Retrying with fewer GPU layers", "model", backend.ModelName, "gpu_layers", layers)
+		if err := m.launch(backend); err != nil {
+			if ranOutOfVRAM(logs.BackendLogPath(backend.ModelName)) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	layers, err := searchGPULayers(failed, try, func() { m.killProcess(backend) })
+	if err != nil {
+		return err
+	}
+
+	logs.Info("Found GPU layers that fit in VRAM", "model", backend.ModelName, "gpu_layers", layers)
+	if user, repo, quant, ok := parseModelRef(backend.ModelName); ok {
+		if err := hf.SetGPULayers(user, repo, quant, layers); err != nil {
+			logs.Warn("Failed to record GPU layers", "model", backend.ModelName, "error", err)
+		}
+	}
+	return nil
+}
+
+// searchGPULayers bisects for the most GPU layers under failed, a count that
+// ran out of VRAM, that a model loads with. try loads the model with the
+// given layers and reports whether it fit, leaving it running if it did;
+// any error ends the search. release stops a load that fit so a bigger one
+// can be tried. The load of the returned count is left running.
+func searchGPULayers(failed int, try func(layers int) (bool, error), release func()) (int, error) {
+	lo, hi := 0, failed // hi doesn't fit; lo is assumed to until tried
+	running := false    // Whether the load of lo is running
+	for hi-lo > 1 {
+		if running {
+			release()
+			running = false
+		}
+		mid := (lo + hi) / 2
+		fits, err := try(mid)
+		if err != nil {
+			return 0, err
+		}
+		if fits {
+			lo, running = mid, true
+		} else {
+			hi = mid
+		}
+	}
+	if running {
+		return lo, nil
+	}
+
+	fits, err := try(lo)
+	if err != nil {
+		return 0, err
+	}
+	if !fits {
+		return 0, errNoGPULayersFit
+	}
+	return lo, nil
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestIsVRAMError(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"ggml_backend_cuda_buffer_type_alloc_buffer: allocating 4096.00 MiB on device 0: cudaMalloc failed: out of memory", true},
+		{"llama_model_load: error loading model: unable to allocate CUDA0 buffer", true},
+		{"ggml_backend_alloc_ctx_tensors_from_buft: failed to allocate ROCm0 buffer", true},
+		{"ggml_vulkan: Device memory allocation of size 1073741824 failed. vk::Device::allocateMemory: ErrorOutOfDeviceMemory", true},
+		{"ggml_metal_buffer_init: error: failed to allocate buffer, size = 8192.00 MiB", true},
+		{"error: insufficient memory (00000008:kIOGPUCommandBufferCallbackErrorOutOfMemory)", true},
+		{"llama_model_load: error loading model: unable to allocate CPU buffer", false},
+		{"llama_model_load: error loading model: failed to open model.gguf", false},
+		{"load_tensors: offloaded 33/33 layers to GPU", false},
+	}
+	for _, tt := range tests {
+		if got := isVRAMError(tt.line); got != tt.want {
+			t.Errorf("isVRAMError(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSearchGPULayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		failed int
+		fit    int // Most layers that fit; -1 for none
+		want   int
+	}{
+		{"most fit", 33, 32, 32},
+		{"half fit", 33, 16, 16},
+		{"few fit", 33, 3, 3},
+		{"only cpu", 33, 0, 0},
+		{"one layer", 1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running := -1
+			tries := 0
+			try := func(layers int) (bool, error) {
+				if running >= 0 {
+					t.Fatalf("tried %d layers with %d still running", layers, running)
+				}
+				tries++
+				if layers > tt.fit {
+					return false, nil
+				}
+				running = layers
+				return true, nil
+			}
+			release := func() { running = -1 }
+
+			got, err := searchGPULayers(tt.failed, try, release)
+			if err != nil {
+				t.Fatalf("searchGPULayers() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("searchGPULayers() = %d, want %d", got, tt.want)
+			}
+			if running != got {
+				t.Errorf("left %d layers running, want %d", running, got)
+			}
+			if tries > 7 {
+				t.Errorf("took %d tries, want a bisection", tries)
+			}
+		})
+	}
+
+	t.Run("nothing fits", func(t *testing.T) {
+		_, err := searchGPULayers(33, func(int) (bool, error) { return false, nil }, func() {})
+		if !errors.Is(err, errNoGPULayersFit) {
+			t.Errorf("searchGPULayers() error = %v, want errNoGPULayersFit", err)
+		}
+	})
+
+	t.Run("other failure", func(t *testing.T) {
+		boom := errors.New("boom")
+		_, err := searchGPULayers(33, func(int) (bool, error) { return false, boom }, func() {})
+		if !errors.Is(err, boom) {
+			t.Errorf("searchGPULayers() error = %v, want boom", err)
+		}
+	})
+}
+
+func TestOffloadedLayers(t *testing.T) {
+	modelPath := writeTestModel(t, llama3B, 1024) // 28 blocks
+
+	tests := []struct {
+		name     string
+		config   any
+		options  map[string]any
+		recorded *int
+		want     int
+	}{
+		{"unset offloads all", nil, nil, nil, 29},
+		{"auto", "auto", nil, nil, 29},
+		{"all", "all", nil, nil, 29},
+		{"too many", 999, nil, nil, 29},
+		{"negative", -1, nil, nil, 29},
+		{"config", 20, nil, nil, 20},
+		{"yaml number", float64(12), nil, nil, 12},
+		{"string number", "8", nil, nil, 8},
+		{"request overrides config", 20, map[string]any{"gpu-layers": 10}, nil, 10},
+		{"recorded", nil, nil, new(int), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appCfg := config.DefaultConfig()
+			if tt.config != nil {
+				appCfg.LlamaCpp.Options = map[string]any{"gpu-layers": tt.config}
+			}
+			m := NewModelManager(DefaultConfig(), appCfg)
+			backend := &Backend{ModelName: "user/repo:Q4_K_M", ModelPath: modelPath, Options: tt.options, gpuLayers: tt.recorded}
+
+			got, err := m.offloadedLayers(backend)
+			if err != nil {
+				t.Fatalf("offloadedLayers() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("offloadedLayers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanTuneGPULayers(t *testing.T) {
+	if !canTuneGPULayers(&Backend{Kind: BackendLlama}) {
+		t.Error("should tune a llama backend without gpu-layers")
+	}
+	if canTuneGPULayers(&Backend{Kind: BackendLlama, Options: map[string]any{"gpu-layers": 10}}) {
+		t.Error("should keep gpu-layers the request set")
+	}
+	if canTuneGPULayers(&Backend{Kind: BackendImage}) {
+		t.Error("should only tune llama backends")
+	}
+}
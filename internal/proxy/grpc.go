@@ -0,0 +1,236 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	llemev1 "github.com/nchapman/lleme/api/lleme/v1"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// pullProgressInterval limits how often PullModel streams progress updates
+const pullProgressInterval = 250 * time.Millisecond
+
+// grpcService implements the gRPC management API on top of the proxy
+type grpcService struct {
+	llemev1.UnimplementedManagementServiceServer
+	server *Server
+}
+
+// startGRPC serves the management API on the configured gRPC port
+func (s *Server) startGRPC() error {
+	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.GRPCPort)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s.grpcServer = grpc.NewServer(s.grpcAuth()...)
+	llemev1.RegisterManagementServiceServer(s.grpcServer, &grpcService{server: s})
+
+	go func() {
+		if err := s.grpcServer.Serve(ln); err != nil {
+			logs.Warn("gRPC server error", "error", err)
+		}
+	}()
+	return nil
+}
+
+func (g *grpcService) LoadModel(ctx context.Context, req *llemev1.LoadModelRequest) (*llemev1.LoadModelResponse, error) {
+	if req.GetModel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "model is required")
+	}
+
+	requested := make(map[string]any, len(req.GetOptions()))
+	for k, v := range req.GetOptions() {
+		requested[k] = v.AsInterface()
+	}
+	options, err := requestLaunchOptions(requested, nil)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	backend, err := g.server.restrictModels(grpcClient(ctx), func(model string) (*Backend, error) {
+		return g.server.manager.GetOrLoadBackend(model, options)
+	})(req.GetModel())
+	auditGRPC(ctx, "run", map[string]any{"model": req.GetModel(), "options": options}, err)
+	if err != nil {
+		return nil, grpcModelError(err)
+	}
+
+	for _, info := range g.server.manager.ListBackends() {
+		if info.ModelName == backend.ModelName {
+			return &llemev1.LoadModelResponse{Backend: backendToProto(info)}, nil
+		}
+	}
+	return nil, status.Errorf(codes.Internal, "model %s unloaded while loading", backend.ModelName)
+}
+
+func (g *grpcService) UnloadModel(ctx context.Context, req *llemev1.UnloadModelRequest) (*llemev1.UnloadModelResponse, error) {
+	manager := g.server.manager
+
+	client := grpcClient(ctx)
+	if req.GetAll() {
+		// A client limited to some models can't stop everyone else's
+		if client != nil && len(client.Models) > 0 {
+			return nil, status.Error(codes.PermissionDenied, "this client may only stop the models it's allowed to use")
+		}
+		var names []string
+		for _, info := range manager.ListBackends() {
+			names = append(names, info.ModelName)
+		}
+		err := manager.StopAllBackends()
+		auditGRPC(ctx, "stop-all", map[string]any{"stopped": len(names)}, err)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &llemev1.UnloadModelResponse{Models: names}, nil
+	}
+
+	if req.GetModel() == "" {
+		return nil, status.Error(codes.InvalidArgument, "model is required unless all is set")
+	}
+
+	result, err := manager.Resolver().Resolve(req.GetModel())
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if result.Model != nil && !clientAllowsModel(client, result.Model.FullName) {
+		return nil, grpcModelError(&ModelNotAllowedError{Client: client.Name, Model: result.Model.FullName})
+	}
+	if result.Model == nil || manager.GetBackend(result.Model.FullName) == nil {
+		return nil, status.Errorf(codes.NotFound, "model '%s' is not loaded", req.GetModel())
+	}
+
+	err = manager.StopBackend(result.Model.FullName)
+	auditGRPC(ctx, "stop", map[string]any{"model": result.Model.FullName}, err)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &llemev1.UnloadModelResponse{Models: []string{result.Model.FullName}}, nil
+}
+
+func (g *grpcService) ListBackends(ctx context.Context, req *llemev1.ListBackendsRequest) (*llemev1.ListBackendsResponse, error) {
+	resp := &llemev1.ListBackendsResponse{}
+	for _, info := range g.server.manager.ListBackends() {
+		resp.Backends = append(resp.Backends, backendToProto(info))
+	}
+	return resp, nil
+}
+
+func (g *grpcService) StreamEvents(req *llemev1.StreamEventsRequest, stream grpc.ServerStreamingServer[llemev1.StreamEventsResponse]) error {
+	events, cancel := g.server.manager.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case e := <-events:
+			err := stream.Send(&llemev1.StreamEventsResponse{
+				Type:  eventTypeToProto(e.Type),
+				Model: e.Model,
+				Time:  timestamppb.New(e.Time),
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-g.server.shutdownChan:
+			return nil
+		}
+	}
+}
+
+func (g *grpcService) PullModel(req *llemev1.PullModelRequest, stream grpc.ServerStreamingServer[llemev1.PullModelResponse]) error {
+	if user, repo, ok := splitModelName(req.GetModel()); !ok || user == "" || repo == "" {
+		return status.Error(codes.InvalidArgument, "model must be user/repo or user/repo:quant")
+	}
+	if client := grpcClient(stream.Context()); !clientAllowsModel(client, req.GetModel()) {
+		return grpcModelError(&ModelNotAllowedError{Client: client.Name, Model: req.GetModel()})
+	}
+
+	var lastPhase string
+	var lastSent time.Time
+	progress := func(p hf.PullProgress) {
+		done := p.Total > 0 && p.Current >= p.Total
+		if p.Phase == lastPhase && !done && time.Since(lastSent) < pullProgressInterval {
+			return
+		}
+		lastPhase, lastSent = p.Phase, time.Now()
+		// Keep downloading even if the client went away
+		stream.Send(&llemev1.PullModelResponse{Phase: p.Phase, Completed: p.Current, Total: p.Total})
+	}
+
+	if err := g.server.manager.waitForPower(stream.Context(), func(reason string) {
+		stream.Send(&llemev1.PullModelResponse{Phase: "deferred"})
+	}); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	modelName, err := g.server.manager.PullModel(req.GetModel(), progress)
+	if err != nil {
+		var diskErr *hf.DiskFullError
+		if errors.As(err, &diskErr) {
+			return status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return stream.Send(&llemev1.PullModelResponse{Phase: "done", Model: modelName})
+}
+
+// grpcModelError maps model loading errors to gRPC status codes
+func grpcModelError(err error) error {
+	if errors.Is(err, ErrSDNotInstalled) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	switch err.(type) {
+	case *AmbiguousModelError:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case *ModelNotFoundError:
+		return status.Error(codes.NotFound, err.Error())
+	case *InsufficientMemoryError:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case *QuietHoursError:
+		return status.Error(codes.Unavailable, err.Error())
+	case *ModelNotAllowedError:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case *IncompatibleModelError, *ModelBusyError:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func backendToProto(info BackendInfo) *llemev1.Backend {
+	return &llemev1.Backend{
+		Name:         info.ModelName,
+		Status:       info.Status,
+		Port:         int32(info.Port),
+		Pid:          int32(info.PID),
+		StartedAt:    timestamppb.New(info.StartedAt),
+		LastActivity: timestamppb.New(info.LastActivity),
+	}
+}
+
+func eventTypeToProto(t EventType) llemev1.EventType {
+	switch t {
+	case EventLoading:
+		return llemev1.EventType_EVENT_TYPE_LOADING
+	case EventLoaded:
+		return llemev1.EventType_EVENT_TYPE_LOADED
+	case EventLoadFailed:
+		return llemev1.EventType_EVENT_TYPE_LOAD_FAILED
+	case EventUnloaded:
+		return llemev1.EventType_EVENT_TYPE_UNLOADED
+	default:
+		return llemev1.EventType_EVENT_TYPE_UNSPECIFIED
+	}
+}
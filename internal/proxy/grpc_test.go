@@ -0,0 +1,292 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	llemev1 "github.com/nchapman/lleme/api/lleme/v1"
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/paths"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// newTestGRPCClient serves the management API over an in-memory listener
+func newTestGRPCClient(t *testing.T, s *Server, opts ...grpc.ServerOption) llemev1.ManagementServiceClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	llemev1.RegisterManagementServiceServer(srv, &grpcService{server: s})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return llemev1.NewManagementServiceClient(conn)
+}
+
+func TestGRPCManagement(t *testing.T) {
+	useTestHome(t)
+	t.Setenv(mock.EnvVar, "1")
+
+	dir := filepath.Join(paths.Models(), "test", "tiny-GGUF")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Q4_K_M.gguf"), []byte("fake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.StartupTimeout = 5 * time.Second
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil), shutdownChan: make(chan struct{})}
+	defer s.manager.StopAllBackends()
+	client := newTestGRPCClient(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events, err := client.StreamEvents(ctx, &llemev1.StreamEventsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait until the stream is subscribed before loading
+	for range 100 {
+		s.manager.events.mu.Lock()
+		n := len(s.manager.events.subs)
+		s.manager.events.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	loaded, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{Model: "tiny"})
+	if err != nil {
+		t.Fatalf("LoadModel() error = %v", err)
+	}
+	if loaded.GetBackend().GetName() != "test/tiny-GGUF:Q4_K_M" || loaded.GetBackend().GetStatus() != "ready" {
+		t.Errorf("LoadModel() = %v", loaded.GetBackend())
+	}
+
+	list, err := client.ListBackends(ctx, &llemev1.ListBackendsRequest{})
+	if err != nil {
+		t.Fatalf("ListBackends() error = %v", err)
+	}
+	if len(list.GetBackends()) != 1 {
+		t.Errorf("ListBackends() returned %d backends, want 1", len(list.GetBackends()))
+	}
+
+	unloaded, err := client.UnloadModel(ctx, &llemev1.UnloadModelRequest{Model: "tiny"})
+	if err != nil {
+		t.Fatalf("UnloadModel() error = %v", err)
+	}
+	if len(unloaded.GetModels()) != 1 {
+		t.Errorf("UnloadModel() = %v", unloaded.GetModels())
+	}
+
+	audit, err := logs.ReadAudit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(audit) != 2 || audit[0].Action != "run" || audit[1].Action != "stop" || audit[1].Via != "grpc" {
+		t.Errorf("audit log = %+v, want run and stop over gRPC", audit)
+	}
+
+	want := []llemev1.EventType{
+		llemev1.EventType_EVENT_TYPE_LOADING,
+		llemev1.EventType_EVENT_TYPE_LOADED,
+		llemev1.EventType_EVENT_TYPE_UNLOADED,
+	}
+	for _, wantType := range want {
+		e, err := events.Recv()
+		if err != nil {
+			t.Fatalf("StreamEvents() error = %v", err)
+		}
+		if e.GetType() != wantType || e.GetModel() != "test/tiny-GGUF:Q4_K_M" {
+			t.Errorf("event = %v %s, want %v", e.GetType(), e.GetModel(), wantType)
+		}
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	useTestHome(t)
+	if err := os.MkdirAll(paths.Models(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), nil), shutdownChan: make(chan struct{})}
+	client := newTestGRPCClient(t, s)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"load without model", func() error {
+			_, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"load with a path option", func() error {
+			_, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{Model: "nope", Options: map[string]*structpb.Value{
+				"slot_save_path": structpb.NewStringValue("/etc"),
+			}})
+			return err
+		}, codes.InvalidArgument},
+		{"load unknown model", func() error {
+			_, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{Model: "nope"})
+			return err
+		}, codes.NotFound},
+		{"unload model not loaded", func() error {
+			_, err := client.UnloadModel(ctx, &llemev1.UnloadModelRequest{Model: "nope"})
+			return err
+		}, codes.NotFound},
+		{"pull invalid reference", func() error {
+			stream, err := client.PullModel(ctx, &llemev1.PullModelRequest{Model: "no-slash"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(tt.call()); got != tt.want {
+				t.Errorf("code = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGRPCRequiresAPIKey(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Host = "0.0.0.0"
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "secret"}, {Name: "notebook"}}
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil), shutdownChan: make(chan struct{})}
+	// bufconn's peer isn't loopback, so these calls count as remote
+	client := newTestGRPCClient(t, s, s.grpcAuth()...)
+
+	tests := []struct {
+		name     string
+		md       []string
+		wantCode codes.Code
+	}{
+		{"no key", nil, codes.Unauthenticated},
+		{"wrong key", []string{"authorization", "Bearer nope"}, codes.Unauthenticated},
+		{"header-only client", []string{"x-lleme-client", "notebook"}, codes.Unauthenticated},
+		{"bearer key", []string{"authorization", "Bearer secret"}, codes.OK},
+		{"x-api-key", []string{"x-api-key", "secret"}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), tt.md...)
+			_, err := client.ListBackends(ctx, &llemev1.ListBackendsRequest{})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("ListBackends code = %v, want %v (%v)", got, tt.wantCode, err)
+			}
+
+			// Streams are checked too
+			stream, err := client.StreamEvents(ctx, &llemev1.StreamEventsRequest{})
+			if err == nil && tt.wantCode != codes.OK {
+				_, err = stream.Recv()
+				if got := status.Code(err); got != tt.wantCode {
+					t.Errorf("StreamEvents code = %v, want %v (%v)", got, tt.wantCode, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCAuthLoopback(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Host = "0.0.0.0"
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "secret"}}
+	s := &Server{config: cfg}
+
+	local := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}})
+	if _, err := s.authorizeGRPC(local, "/test"); err != nil {
+		t.Errorf("loopback call rejected: %v", err)
+	}
+	remote := grpcpeer.NewContext(context.Background(), &grpcpeer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(192, 168, 1, 5), Port: 5000}})
+	if _, err := s.authorizeGRPC(remote, "/test"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("remote call without key: got %v, want Unauthenticated", err)
+	}
+
+	// Loopback and keyless binds don't need a key
+	for _, c := range []*Config{{Host: "127.0.0.1", Clients: cfg.Clients}, {Host: "0.0.0.0"}} {
+		if _, err := (&Server{config: c}).authorizeGRPC(remote, "/test"); err != nil {
+			t.Errorf("remote call to host %q with %d clients rejected: %v", c.Host, len(c.Clients), err)
+		}
+	}
+}
+
+func TestGRPCClientModels(t *testing.T) {
+	useTestHome(t)
+	t.Setenv(mock.EnvVar, "1")
+	writeDownloadedModel(t, "test", "tiny-GGUF", "Q4_K_M")
+	writeDownloadedModel(t, "test", "other-GGUF", "Q4_K_M")
+
+	cfg := DefaultConfig()
+	cfg.StartupTimeout = 5 * time.Second
+	cfg.MemoryGuard = MemoryGuardOff
+	cfg.Clients = []config.Client{
+		{Name: "ci", APIKey: "secret", Models: []string{"test/tiny-GGUF"}},
+		{Name: "notebook", Models: []string{"test/tiny-GGUF"}},
+	}
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil), shutdownChan: make(chan struct{})}
+	defer s.manager.StopAllBackends()
+	client := newTestGRPCClient(t, s, s.grpcAuth()...)
+
+	for _, md := range [][]string{{"authorization", "Bearer secret"}, {"x-lleme-client", "notebook"}} {
+		ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(context.Background(), md...), 10*time.Second)
+		defer cancel()
+
+		if _, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{Model: "test/other-GGUF"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: LoadModel(other) = %v, want PermissionDenied", md[1], err)
+		}
+		if _, err := client.LoadModel(ctx, &llemev1.LoadModelRequest{Model: "test/tiny-GGUF"}); err != nil {
+			t.Errorf("%s: LoadModel(allowed) error = %v", md[1], err)
+		}
+		if _, err := client.UnloadModel(ctx, &llemev1.UnloadModelRequest{Model: "test/other-GGUF"}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: UnloadModel(other) = %v, want PermissionDenied", md[1], err)
+		}
+		if _, err := client.UnloadModel(ctx, &llemev1.UnloadModelRequest{All: true}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: UnloadModel(all) = %v, want PermissionDenied", md[1], err)
+		}
+		stream, err := client.PullModel(ctx, &llemev1.PullModelRequest{Model: "someone/else-GGUF"})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("%s: PullModel(other) = %v, want PermissionDenied", md[1], err)
+		}
+	}
+
+	// Calls without a profile aren't restricted
+	if _, err := client.UnloadModel(context.Background(), &llemev1.UnloadModelRequest{All: true}); err != nil {
+		t.Errorf("UnloadModel(all) without a client error = %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/ui"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// Health check results, from best to worst. A failed check makes /health
+// answer 503 so load balancers stop sending traffic; a warning doesn't.
+const (
+	HealthOK   = "ok"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// lowDiskBytes is the free space on the model store below which the disk
+// check warns; a pull of one mid-sized model needs about this much
+const lowDiskBytes = 5 << 30
+
+// freeDisk reports free space. Overridden in tests.
+var freeDisk = hw.FreeDisk
+
+// HealthCheck is the result of checking one dependency
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HealthReport is the body of /health. Checks are listed with ?verbose=1.
+// The versions let a CLI tell whether it can work with this server.
+type HealthReport struct {
+	Status              string        `json:"status"`
+	Version             string        `json:"version,omitempty"`
+	APIVersion          int           `json:"api_version,omitempty"`
+	MinClientAPIVersion int           `json:"min_client_api_version,omitempty"`
+	Checks              []HealthCheck `json:"checks,omitempty"`
+}
+
+// handleHealth reports readiness: 200 while every dependency is usable,
+// 503 once one has failed
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.health()
+	if verbose, _ := strconv.ParseBool(r.URL.Query().Get("verbose")); !verbose {
+		report.Checks = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == HealthFail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	writeJSON(w, report)
+}
+
+// health runs every check. The overall status is the worst of them.
+func (s *Server) health() HealthReport {
+	models := paths.Models()
+	checks := []HealthCheck{
+		{Name: "proxy", Status: HealthOK, Detail: "up " + time.Since(s.startedAt).Round(time.Second).String()},
+		checkLlamaCpp(),
+		checkModelStore(models),
+		checkDiskSpace(models),
+		s.checkBackends(),
+	}
+	if s.discovery != nil {
+		checks = append(checks, s.checkPeerDiscovery())
+	}
+
+	report := HealthReport{
+		Status:              HealthOK,
+		Version:             version.Version,
+		APIVersion:          APIVersion,
+		MinClientAPIVersion: MinClientAPIVersion,
+		Checks:              checks,
+	}
+	for _, c := range checks {
+		if healthRank(c.Status) > healthRank(report.Status) {
+			report.Status = c.Status
+		}
+	}
+	return report
+}
+
+func healthRank(status string) int {
+	switch status {
+	case HealthWarn:
+		return 1
+	case HealthFail:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// checkLlamaCpp fails when no llama.cpp build is installed, since no model
+// can be loaded without one
+func checkLlamaCpp() HealthCheck {
+	c := HealthCheck{Name: "llama.cpp", Status: HealthOK}
+	if mock.Enabled() {
+		c.Detail = "mock backend"
+		return c
+	}
+	installed, err := installedLlamaVersion()
+	if err != nil || installed == nil {
+		c.Status = HealthFail
+		c.Detail = "not installed, run 'lleme update llama.cpp'"
+		return c
+	}
+	c.Detail = installed.TagName
+	return c
+}
+
+// checkModelStore fails when models can't be written to dir, which stops
+// pulls and the metadata recorded beside each model
+func checkModelStore(dir string) HealthCheck {
+	c := HealthCheck{Name: "model_store", Status: HealthOK, Detail: "writable"}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		c.Status, c.Detail = HealthFail, "can't be created"
+		return c
+	}
+	f, err := os.CreateTemp(dir, ".health-*")
+	if err != nil {
+		c.Status, c.Detail = HealthFail, "not writable" // The path isn't shown to remote callers
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return c
+}
+
+// checkDiskSpace warns when the model store's disk is nearly full
+func checkDiskSpace(dir string) HealthCheck {
+	c := HealthCheck{Name: "disk", Status: HealthOK}
+	free, err := freeDisk(dir)
+	if err != nil {
+		c.Detail = "unknown"
+		return c
+	}
+	c.Detail = ui.FormatBytes(free) + " free"
+	if free < lowDiskBytes {
+		c.Status = HealthWarn
+	}
+	return c
+}
+
+// checkBackends counts loaded models, warning when one has failed
+func (s *Server) checkBackends() HealthCheck {
+	c := HealthCheck{Name: "backends", Status: HealthOK}
+	healthy, failed := 0, 0
+	for _, b := range s.manager.ListBackends() {
+		switch b.Status {
+		case BackendReady.String():
+			healthy++
+		case BackendFailed.String():
+			failed++
+		}
+	}
+	c.Detail = fmt.Sprintf("%d healthy", healthy)
+	if failed > 0 {
+		c.Status = HealthWarn
+		c.Detail += fmt.Sprintf(", %d failed", failed)
+	}
+	return c
+}
+
+// checkPeerDiscovery warns when discovery is on but couldn't advertise
+// this machine over mDNS
+func (s *Server) checkPeerDiscovery() HealthCheck {
+	c := HealthCheck{Name: "peer_discovery", Status: HealthOK}
+	status := s.discovery.Status()
+	switch {
+	case !status.Enabled:
+		c.Detail = "disabled"
+	case !status.Advertising:
+		c.Status = HealthWarn
+		c.Detail = fmt.Sprintf("not advertising over mDNS, %d peers found", status.Peers)
+	default:
+		c.Detail = fmt.Sprintf("%d peers found", status.Peers)
+	}
+	return c
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/llama"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/peer"
+)
+
+// fakeHealthDeps stubs the installed llama.cpp build and free disk space
+func fakeHealthDeps(t *testing.T, installed *llama.VersionInfo, free int64) {
+	t.Helper()
+	useTestHome(t)
+	t.Setenv("LLEME_MOCK_BACKEND", "")
+	origVersion, origDisk := installedLlamaVersion, freeDisk
+	installedLlamaVersion = func() (*llama.VersionInfo, error) {
+		if installed == nil {
+			return nil, errors.New("not installed")
+		}
+		return installed, nil
+	}
+	freeDisk = func(string) (int64, error) { return free, nil }
+	t.Cleanup(func() { installedLlamaVersion, freeDisk = origVersion, origDisk })
+}
+
+func getHealth(t *testing.T, s *Server, query string) (int, HealthReport) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.handleHealth(w, httptest.NewRequest(http.MethodGet, "/health"+query, nil))
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v: %s", err, w.Body)
+	}
+	return w.Code, report
+}
+
+func checkNamed(report HealthReport, name string) HealthCheck {
+	for _, c := range report.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return HealthCheck{}
+}
+
+func TestHealth(t *testing.T) {
+	newServer := func() *Server {
+		cfg := DefaultConfig()
+		return &Server{config: cfg, manager: NewModelManager(cfg, nil), startedAt: time.Now()}
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		fakeHealthDeps(t, &llama.VersionInfo{TagName: "b6100"}, 100<<30)
+		code, report := getHealth(t, newServer(), "")
+		if code != http.StatusOK || report.Status != HealthOK {
+			t.Errorf("got %d %q, want 200 ok", code, report.Status)
+		}
+		if report.Checks != nil {
+			t.Errorf("terse report has checks: %+v", report)
+		}
+		if report.APIVersion != APIVersion || report.MinClientAPIVersion != MinClientAPIVersion || report.Version == "" {
+			t.Errorf("report is missing versions: %+v", report)
+		}
+
+		code, report = getHealth(t, newServer(), "?verbose=1")
+		if code != http.StatusOK || report.Status != HealthOK {
+			t.Errorf("verbose got %d %q, want 200 ok", code, report.Status)
+		}
+		for _, name := range []string{"proxy", "llama.cpp", "model_store", "disk", "backends"} {
+			if c := checkNamed(report, name); c.Status != HealthOK {
+				t.Errorf("check %s = %+v, want ok", name, c)
+			}
+		}
+		if c := checkNamed(report, "llama.cpp"); c.Detail != "b6100" {
+			t.Errorf("llama.cpp detail = %q, want b6100", c.Detail)
+		}
+		if c := checkNamed(report, "backends"); c.Detail != "0 healthy" {
+			t.Errorf("backends detail = %q", c.Detail)
+		}
+	})
+
+	t.Run("low disk warns", func(t *testing.T) {
+		fakeHealthDeps(t, &llama.VersionInfo{TagName: "b6100"}, 1<<30)
+		code, report := getHealth(t, newServer(), "?verbose=true")
+		if code != http.StatusOK || report.Status != HealthWarn {
+			t.Errorf("got %d %q, want 200 warn", code, report.Status)
+		}
+		if c := checkNamed(report, "disk"); c.Status != HealthWarn {
+			t.Errorf("disk check = %+v, want warn", c)
+		}
+	})
+
+	t.Run("llama.cpp missing fails", func(t *testing.T) {
+		fakeHealthDeps(t, nil, 100<<30)
+		code, report := getHealth(t, newServer(), "")
+		if code != http.StatusServiceUnavailable || report.Status != HealthFail {
+			t.Errorf("got %d %q, want 503 fail", code, report.Status)
+		}
+	})
+
+	t.Run("mock backend needs no llama.cpp", func(t *testing.T) {
+		fakeHealthDeps(t, nil, 100<<30)
+		t.Setenv("LLEME_MOCK_BACKEND", "1")
+		if code, _ := getHealth(t, newServer(), ""); code != http.StatusOK {
+			t.Errorf("got %d, want 200", code)
+		}
+	})
+
+	t.Run("read-only model store fails", func(t *testing.T) {
+		if os.Geteuid() == 0 {
+			t.Skip("root can write anywhere")
+		}
+		fakeHealthDeps(t, &llama.VersionInfo{TagName: "b6100"}, 100<<30)
+		if err := os.MkdirAll(paths.Models(), 0555); err != nil {
+			t.Fatal(err)
+		}
+		code, report := getHealth(t, newServer(), "?verbose=1")
+		if code != http.StatusServiceUnavailable || checkNamed(report, "model_store").Status != HealthFail {
+			t.Errorf("got %d %+v, want 503 with model_store failing", code, report.Checks)
+		}
+	})
+
+	t.Run("peer discovery not advertising warns", func(t *testing.T) {
+		fakeHealthDeps(t, &llama.VersionInfo{TagName: "b6100"}, 100<<30)
+		s := newServer()
+		s.discovery = peer.NewDiscovery(11314, "test", true) // Never started
+		_, report := getHealth(t, s, "?verbose=1")
+		if c := checkNamed(report, "peer_discovery"); c.Status != HealthWarn {
+			t.Errorf("peer_discovery = %+v, want warn", c)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// hfCachePrefix is where the Hugging Face mirror is served
+const hfCachePrefix = "/hf"
+
+// hfCache is a Hugging Face mirror for other lleme clients, which point
+// huggingface.endpoint at it. API requests pass through to the upstream Hub.
+// LFS files are fetched once, cached by SHA256, and then served locally.
+type hfCache struct {
+	upstream string
+	token    string
+	lend     func(r *http.Request) bool // Whether a request without a token may use ours; nil for never
+	dir      string
+	proxy    *httputil.ReverseProxy
+	client   *http.Client // Follows redirects to the CDN for blob downloads
+	head     *http.Client // Stops at the first response to read the LFS headers
+
+	mu       sync.Mutex
+	inflight map[string]bool // Blobs being written to the cache
+}
+
+func newHFCache(appCfg *config.Config, lend func(r *http.Request) bool) *hfCache {
+	c := &hfCache{
+		upstream: hf.Endpoint(appCfg),
+		token:    hf.Token(appCfg),
+		lend:     lend,
+		dir:      filepath.Join(paths.Cache(), "hf"),
+		client: &http.Client{
+			Transport: logs.Transport(&http.Transport{ResponseHeaderTimeout: 30 * time.Second}),
+		},
+		head: &http.Client{
+			Transport: logs.Transport(nil),
+			Timeout:   30 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		inflight: make(map[string]bool),
+	}
+
+	upstream, _ := url.Parse(c.upstream)
+	c.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstream)
+			c.authorize(r.Out, r.In)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Keep same-host redirects (renamed repos) on the mirror
+			if loc := resp.Header.Get("Location"); strings.HasPrefix(loc, "/") {
+				resp.Header.Set("Location", hfCachePrefix+loc)
+			}
+			return nil
+		},
+	}
+	return c
+}
+
+func (c *hfCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only file downloads are cached: /{user}/{repo}/resolve/{revision}/{path}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 5)
+	if len(parts) < 5 || parts[2] != "resolve" {
+		c.proxy.ServeHTTP(w, r)
+		return
+	}
+	for _, segment := range strings.Split(r.URL.Path[1:], "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			http.Error(w, "Invalid path", http.StatusBadRequest)
+			return
+		}
+	}
+
+	c.serveFile(w, r, parts[0]+"/"+parts[1], parts[3], parts[4])
+}
+
+// serveFile serves an LFS file from the cache, filling the cache on a miss.
+// Non-LFS files and requests that can't be cached go straight upstream.
+func (c *hfCache) serveFile(w http.ResponseWriter, r *http.Request, repo, revision, file string) {
+	refPath := filepath.Join(c.dir, "refs", filepath.FromSlash(repo), revision, filepath.FromSlash(file))
+
+	hash, size, err := c.lookupBlob(r)
+	if err != nil {
+		// Serve what we have when the upstream is unreachable
+		data, readErr := os.ReadFile(refPath)
+		if readErr != nil {
+			http.Error(w, fmt.Sprintf("upstream unavailable: %v", err), http.StatusBadGateway)
+			return
+		}
+		hash = strings.TrimSpace(string(data))
+	}
+	if hash == "" {
+		c.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err == nil {
+		os.WriteFile(refPath, []byte(hash+"\n"), 0644)
+	}
+
+	blobPath := filepath.Join(c.dir, "blobs", hash)
+	w.Header().Set("X-Linked-Etag", `"`+hash+`"`)
+	if f, err := os.Open(blobPath); err == nil {
+		defer f.Close()
+		w.Header().Set("ETag", `"`+hash+`"`)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("X-Cache", "HIT")
+		http.ServeContent(w, r, "", time.Time{}, f)
+		return
+	}
+
+	// Ranged and HEAD requests on a miss, and requests for a blob that is
+	// already being cached, are passed through rather than cached
+	if r.Method != http.MethodGet || r.Header.Get("Range") != "" || !c.claim(hash) {
+		w.Header().Set("X-Cache", "MISS")
+		c.proxy.ServeHTTP(w, r)
+		return
+	}
+	defer c.release(hash)
+
+	c.fill(w, r, hash, size, blobPath)
+}
+
+// lookupBlob asks the upstream for a file's LFS hash and size. The hash is
+// empty for files stored in git rather than LFS.
+func (c *hfCache) lookupBlob(r *http.Request) (string, int64, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodHead, c.upstream+r.URL.EscapedPath(), nil)
+	if err != nil {
+		return "", 0, err
+	}
+	c.authorize(req, r)
+
+	resp, err := c.head.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return "", 0, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	hash := strings.ToLower(strings.Trim(resp.Header.Get("X-Linked-Etag"), `"`))
+	if len(hash) != sha256.Size*2 {
+		return "", 0, nil
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", 0, nil
+	}
+
+	var size int64
+	fmt.Sscan(resp.Header.Get("X-Linked-Size"), &size)
+	return hash, size, nil
+}
+
+// fill streams a blob from upstream to the client while writing it to the
+// cache. The download finishes even if the client goes away, so a retry
+// is served from the cache.
+func (c *hfCache) fill(w http.ResponseWriter, r *http.Request, hash string, size int64, blobPath string) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, c.upstream+r.URL.EscapedPath(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.authorize(req, r)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("upstream unavailable: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(blobPath), hash+".*.partial")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w.Header().Set("ETag", `"`+hash+`"`)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Cache", "MISS")
+	if resp.ContentLength >= 0 {
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", resp.ContentLength))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	h := sha256.New()
+	clientGone := false
+	buf := make([]byte, 256*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := tmp.Write(buf[:n]); err != nil {
+				logs.Warn("Failed to write Hugging Face cache", "blob", hash, "error", err)
+				return
+			}
+			h.Write(buf[:n])
+			if !clientGone {
+				if _, err := w.Write(buf[:n]); err != nil {
+					clientGone = true
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			logs.Warn("Hugging Face download failed", "blob", hash, "error", readErr)
+			return
+		}
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != hash {
+		logs.Warn("Hugging Face blob hash mismatch, not cached", "blob", hash, "got", got)
+		return
+	}
+	if size > 0 {
+		if info, err := tmp.Stat(); err != nil || info.Size() != size {
+			return
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	if err := os.Rename(tmp.Name(), blobPath); err != nil {
+		logs.Warn("Failed to cache Hugging Face blob", "blob", hash, "error", err)
+		return
+	}
+	logs.Info("Cached Hugging Face blob", "path", r.URL.Path, "blob", hash)
+}
+
+// authorize forwards the client's token. Clients without one only get ours
+// when lend allows it, since it opens whatever our account can reach.
+func (c *hfCache) authorize(out, in *http.Request) {
+	out.Header.Set("User-Agent", version.UserAgent())
+	if auth := in.Header.Get("Authorization"); auth != "" {
+		out.Header.Set("Authorization", auth)
+	} else if c.token != "" && c.lend != nil && c.lend(in) {
+		out.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+func (c *hfCache) claim(hash string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight[hash] {
+		return false
+	}
+	c.inflight[hash] = true
+	return true
+}
+
+func (c *hfCache) release(hash string) {
+	c.mu.Lock()
+	delete(c.inflight, hash)
+	c.mu.Unlock()
+}
@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// fakeHub mimics the Hugging Face endpoints the cache talks to: LFS files
+// redirect to a CDN with their hash in X-Linked-Etag, git files are served
+// directly, and API calls return JSON.
+type fakeHub struct {
+	blob      []byte
+	cdnHits   atomic.Int32
+	authSeen  atomic.Value
+	apiCalled atomic.Int32
+}
+
+func (f *fakeHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sum := sha256.Sum256(f.blob)
+	hash := hex.EncodeToString(sum[:])
+
+	switch r.URL.Path {
+	case "/user/repo/resolve/main/model-Q4_K_M.gguf":
+		f.authSeen.Store(r.Header.Get("Authorization"))
+		w.Header().Set("X-Linked-Etag", `"`+hash+`"`)
+		w.Header().Set("X-Linked-Size", fmt.Sprint(len(f.blob)))
+		http.Redirect(w, r, "/cdn/"+hash, http.StatusFound)
+	case "/cdn/" + hash:
+		f.cdnHits.Add(1)
+		w.Write(f.blob)
+	case "/user/repo/resolve/main/README.md":
+		w.Header().Set("ETag", `"0123456789abcdef0123456789abcdef01234567"`)
+		w.Write([]byte("# readme"))
+	case "/api/models/user/repo/tree/main":
+		f.authSeen.Store(r.Header.Get("Authorization"))
+		f.apiCalled.Add(1)
+		fmt.Fprintf(w, `[{"path":"model-Q4_K_M.gguf","type":"file","size":%d}]`, len(f.blob))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestHFCache(t *testing.T, lend func(*http.Request) bool) (*fakeHub, *httptest.Server, *httptest.Server) {
+	t.Helper()
+	useTestHome(t)
+	t.Setenv("HF_TOKEN", "")
+	t.Setenv("HOME", t.TempDir())
+
+	hub := &fakeHub{blob: []byte("gguf blob contents")}
+	upstream := httptest.NewServer(hub)
+	t.Cleanup(upstream.Close)
+
+	appCfg := config.DefaultConfig()
+	appCfg.HuggingFace.Endpoint = upstream.URL
+	appCfg.HuggingFace.Token = "server-token"
+
+	mux := http.NewServeMux()
+	mux.Handle(hfCachePrefix+"/", http.StripPrefix(hfCachePrefix, newHFCache(appCfg, lend)))
+	cache := httptest.NewServer(mux)
+	t.Cleanup(cache.Close)
+
+	return hub, upstream, cache
+}
+
+func TestHFCacheServesBlobsOnce(t *testing.T) {
+	hub, upstream, cache := newTestHFCache(t, nil)
+
+	// Clients pull through the cache like any Hub mirror
+	clientCfg := config.DefaultConfig()
+	clientCfg.HuggingFace.Endpoint = cache.URL + hfCachePrefix
+	client := hf.NewClient(clientCfg)
+
+	files, err := client.ListFiles("user", "repo", "main")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ListFiles() = %v, %v", files, err)
+	}
+	if hub.apiCalled.Load() != 1 {
+		t.Error("API requests should pass through to the upstream")
+	}
+
+	dir := t.TempDir()
+	for i := range 2 {
+		dest := filepath.Join(dir, fmt.Sprintf("model-%d.gguf", i))
+		if _, err := hf.NewDownloader(client).DownloadModel("user", "repo", "main", "model-Q4_K_M.gguf", dest); err != nil {
+			t.Fatalf("DownloadModel() error = %v", err)
+		}
+		if data, _ := os.ReadFile(dest); string(data) != string(hub.blob) {
+			t.Errorf("download %d = %q", i, data)
+		}
+	}
+	if got := hub.cdnHits.Load(); got != 1 {
+		t.Errorf("upstream blob fetched %d times, want 1", got)
+	}
+	if auth, _ := hub.authSeen.Load().(string); auth != "" {
+		t.Errorf("upstream Authorization = %q, want none without hf_cache_token", auth)
+	}
+
+	// Ranged requests are served from the cache too
+	req, _ := http.NewRequest("GET", cache.URL+"/hf/user/repo/resolve/main/model-Q4_K_M.gguf", nil)
+	req.Header.Set("Range", "bytes=5-")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("ranged request = %d %s, want 206 from cache", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+
+	// Cached blobs survive the upstream going away
+	upstream.Close()
+	dest := filepath.Join(dir, "offline.gguf")
+	if _, err := hf.NewDownloader(client).DownloadModel("user", "repo", "main", "model-Q4_K_M.gguf", dest); err != nil {
+		t.Fatalf("DownloadModel() with upstream down error = %v", err)
+	}
+}
+
+func TestHFCachePassesThroughGitFiles(t *testing.T) {
+	_, _, cache := newTestHFCache(t, nil)
+
+	resp, err := http.Get(cache.URL + "/hf/user/repo/resolve/main/README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Cache") != "" {
+		t.Errorf("README = %d %q, want an uncached 200", resp.StatusCode, resp.Header.Get("X-Cache"))
+	}
+
+	resp, err = http.Get(cache.URL + "/hf/user/repo/resolve/main/missing.gguf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing file = %d, want 404 from upstream", resp.StatusCode)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(paths.Cache(), "hf", "blobs"))
+	if len(entries) != 0 {
+		t.Errorf("cache has %d blobs, want none", len(entries))
+	}
+}
+
+func TestHFCacheLendsToken(t *testing.T) {
+	lend := func(r *http.Request) bool { return r.Header.Get("x-api-key") == "secret" }
+	hub, _, cache := newTestHFCache(t, lend)
+
+	get := func(headers map[string]string) string {
+		t.Helper()
+		req, _ := http.NewRequest("GET", cache.URL+"/hf/api/models/user/repo/tree/main", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		auth, _ := hub.authSeen.Load().(string)
+		return auth
+	}
+
+	if auth := get(nil); auth != "" {
+		t.Errorf("upstream Authorization = %q, want none when lend refuses", auth)
+	}
+	if auth := get(map[string]string{"x-api-key": "secret"}); auth != "Bearer server-token" {
+		t.Errorf("upstream Authorization = %q, want the server's token", auth)
+	}
+	if auth := get(map[string]string{"x-api-key": "secret", "Authorization": "Bearer own-token"}); auth != "Bearer own-token" {
+		t.Errorf("upstream Authorization = %q, want the client's own token", auth)
+	}
+}
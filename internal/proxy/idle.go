@@ -49,6 +49,7 @@ func (m *IdleMonitor) run() {
 			return
 		case <-ticker.C:
 			m.checkAndEvict()
+			m.manager.stopForQuietHours()
 		}
 	}
 }
@@ -0,0 +1,420 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// maxFinishedJobs caps how many finished jobs are kept for their output
+const maxFinishedJobs = 100
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobFailed    = "error"
+	JobCancelled = "cancelled"
+)
+
+// job is a chat completion run in the background for a client that doesn't
+// wait on it. Its reply is collected as it streams so it can be read while
+// running and after.
+type job struct {
+	id      string
+	model   string
+	client  string // server.clients profile that started it, if any
+	created time.Time
+	cancel  context.CancelFunc
+
+	mu       sync.Mutex
+	status   string
+	output   strings.Builder
+	err      string
+	finished time.Time
+	changed  chan struct{} // Closed and replaced whenever output or status change
+}
+
+// info returns the job's state, with its output when withOutput is set
+func (j *job) info(withOutput bool) JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := JobInfo{
+		ID:       j.id,
+		Model:    j.model,
+		Client:   j.client,
+		Status:   j.status,
+		Created:  j.created,
+		Finished: j.finished,
+		Error:    j.err,
+	}
+	if withOutput {
+		info.Output = j.output.String()
+	}
+	return info
+}
+
+// append adds reply text and wakes followers
+func (j *job) append(s string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.output.WriteString(s)
+	j.notify()
+}
+
+// finish records the job's end and wakes followers
+func (j *job) finish(status, errMsg string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status, j.err, j.finished = status, errMsg, time.Now()
+	j.notify()
+}
+
+// visibleTo reports whether c may see and cancel the job: a client only
+// sees its own jobs, and requests without a profile, like the lleme CLI on
+// the server's machine, see them all
+func (j *job) visibleTo(c *config.Client) bool {
+	return c == nil || j.client == c.Name
+}
+
+// notify wakes followers; callers hold j.mu
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// since returns the output after offset, whether the job has finished, and
+// a channel closed on the next change
+func (j *job) since(offset int) (string, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	out := j.output.String()
+	return out[min(offset, len(out)):], j.status != JobRunning, j.changed
+}
+
+// jobStore holds background jobs. The zero value is ready to use.
+type jobStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*job
+	order []string // Oldest first
+}
+
+// start runs a chat completion request through handler in the background
+// and returns the new job, owned by the named client. The request is made
+// to stream so the reply can be followed; headers such as the API key are
+// kept from r.
+func (js *jobStore) start(r *http.Request, client string, body []byte, handler http.Handler) (*job, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	req["stream"] = true
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	model, _ := req["model"].(string)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:      newJobID(),
+		model:   model,
+		client:  client,
+		created: time.Now(),
+		cancel:  cancel,
+		status:  JobRunning,
+		changed: make(chan struct{}),
+	}
+
+	jr, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	jr.Header = r.Header.Clone()
+	jr.Header.Del("X-Request-ID")
+	jr.RemoteAddr = r.RemoteAddr
+
+	js.add(j)
+	logs.Info("Job started", "job", j.id, "model", model)
+	go func() {
+		defer cancel()
+		w := &jobWriter{job: j, header: http.Header{}}
+		handler.ServeHTTP(w, jr)
+		status, errMsg := w.result(ctx)
+		j.finish(status, errMsg)
+		logs.Info("Job finished", "job", j.id, "status", status, "error", errMsg)
+	}()
+	return j, nil
+}
+
+// add stores a job, dropping the oldest finished jobs past the cap
+func (js *jobStore) add(j *job) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.jobs == nil {
+		js.jobs = make(map[string]*job)
+	}
+	js.jobs[j.id] = j
+	js.order = append(js.order, j.id)
+
+	finished := 0
+	for _, id := range js.order {
+		if js.jobs[id].info(false).Status != JobRunning {
+			finished++
+		}
+	}
+	kept := js.order[:0]
+	for _, id := range js.order {
+		if finished > maxFinishedJobs && js.jobs[id].info(false).Status != JobRunning {
+			delete(js.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	js.order = kept
+}
+
+// get returns a job by ID
+func (js *jobStore) get(id string) (*job, bool) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	j, ok := js.jobs[id]
+	return j, ok
+}
+
+// remove forgets a job
+func (js *jobStore) remove(id string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	delete(js.jobs, id)
+	for i, o := range js.order {
+		if o == id {
+			js.order = append(js.order[:i], js.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// list returns the jobs c may see, newest first
+func (js *jobStore) list(c *config.Client) []JobInfo {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	infos := make([]JobInfo, 0, len(js.order))
+	for i := len(js.order) - 1; i >= 0; i-- {
+		if j := js.jobs[js.order[i]]; j.visibleTo(c) {
+			infos = append(infos, j.info(false))
+		}
+	}
+	return infos
+}
+
+func newJobID() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return "job_" + hex.EncodeToString(b)
+}
+
+// jobWriter is the response writer a job's request is served to. It keeps
+// the content of each streamed chunk as the job's output, and the body of an
+// error response to report it.
+type jobWriter struct {
+	job     *job
+	header  http.Header
+	status  int
+	partial []byte       // Start of a line not yet complete
+	errBody bytes.Buffer // Body of an error response
+	errMsg  string       // Error sent within the stream
+}
+
+func (w *jobWriter) Header() http.Header { return w.header }
+
+func (w *jobWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *jobWriter) Flush() {}
+
+func (w *jobWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.status != http.StatusOK {
+		return w.errBody.Write(p)
+	}
+
+	data := append(w.partial, p...)
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		w.partial = data
+		return len(p), nil
+	}
+	w.partial = append([]byte(nil), data[end+1:]...)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data[:end]))
+	scanner.Buffer(make([]byte, 64*1024), len(data))
+	for scanner.Scan() {
+		payload, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || payload == "[DONE]" {
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *OpenAIErrorDetail `json:"error"`
+		}
+		if json.Unmarshal([]byte(payload), &chunk) != nil {
+			continue
+		}
+		if chunk.Error != nil {
+			w.errMsg = chunk.Error.Message
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			w.job.append(chunk.Choices[0].Delta.Content)
+		}
+	}
+	return len(p), nil
+}
+
+// result returns the job's final status and error once the handler is done
+func (w *jobWriter) result(ctx context.Context) (string, string) {
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return JobCancelled, ""
+	case w.status != 0 && w.status != http.StatusOK:
+		var resp OpenAIError
+		if json.Unmarshal(w.errBody.Bytes(), &resp) == nil && resp.Error.Message != "" {
+			return JobFailed, resp.Error.Message
+		}
+		return JobFailed, strings.TrimSpace(w.errBody.String())
+	case w.errMsg != "":
+		return JobFailed, w.errMsg
+	}
+	return JobDone, ""
+}
+
+// handleJobs routes /api/jobs: POST submits a chat completion request to run
+// in the background and returns its job at once, and GET lists jobs.
+func (s *Server) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, map[string]any{"jobs": s.jobs.list(s.matchClient(r))})
+
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+			return
+		}
+		// A job submitted during quiet hours waits for them to end
+		run := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.manager.waitForQuietHours(r.Context()) == nil {
+				s.handleChatCompletions(w, r)
+			}
+		})
+		j, err := s.jobs.start(r, clientName(s.matchClient(r)), body, run)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		writeJSON(w, j.info(false))
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET and POST are allowed")
+	}
+}
+
+// handleJobsOff answers /api/jobs while the background-jobs feature is off,
+// saying how to turn it on
+func (s *Server) handleJobsOff(w http.ResponseWriter, r *http.Request) {
+	s.writeError(w, http.StatusNotFound, "not_found",
+		"Background jobs are an experimental feature; add "+config.FeatureBackgroundJobs+" to features in config and restart the server")
+}
+
+// handleJob routes /api/jobs/{id}: GET returns the job with its output so
+// far, DELETE cancels a running job or forgets a finished one, and
+// GET /api/jobs/{id}/logs streams the output as plain text, following it
+// until the job ends when ?follow=true.
+func (s *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	id, logsPath := strings.CutSuffix(id, "/logs")
+	// Another client's job is reported missing, so IDs can't be probed
+	j, ok := s.jobs.get(id)
+	if !ok || !j.visibleTo(s.matchClient(r)) {
+		s.writeError(w, http.StatusNotFound, "not_found", "Job '"+id+"' not found")
+		return
+	}
+
+	switch {
+	case logsPath && r.Method == http.MethodGet:
+		s.streamJobOutput(w, r, j, r.URL.Query().Get("follow") == "true")
+
+	case !logsPath && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, j.info(true))
+
+	case !logsPath && r.Method == http.MethodDelete:
+		if j.info(false).Status == JobRunning {
+			j.cancel()
+		} else {
+			s.jobs.remove(id)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, map[string]any{"success": true})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+// streamJobOutput writes a job's output, and with follow, keeps writing
+// what's added until the job ends or the client goes away
+func (s *Server) streamJobOutput(w http.ResponseWriter, r *http.Request, j *job, follow bool) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	offset := 0
+	for {
+		out, finished, changed := j.since(offset)
+		if out != "" {
+			if _, err := w.Write([]byte(out)); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			offset += len(out)
+		}
+		if !follow || finished {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// sseHandler streams each part as a chunk of content, waiting on release
+// (when set) before the last one
+func sseHandler(t *testing.T, release <-chan struct{}, parts ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]any
+		if err := json.Unmarshal(body, &req); err != nil || req["stream"] != true {
+			t.Errorf("job request = %s, want stream forced on", body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, p := range parts {
+			if i == len(parts)-1 && release != nil {
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+			}
+			data, _ := json.Marshal(map[string]any{"choices": []any{map[string]any{"delta": map[string]any{"content": p}}}})
+			// Split each event across writes, as a proxied stream may be
+			half := len(data) / 2
+			io.WriteString(w, "data: "+string(data[:half]))
+			io.WriteString(w, string(data[half:])+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, "data: [DONE]\n\n")
+	})
+}
+
+// waitJob waits for a job to leave the running state
+func waitJob(t *testing.T, j *job) JobInfo {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if info := j.info(true); info.Status != JobRunning {
+			return info
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("job did not finish")
+	return JobInfo{}
+}
+
+func newJobRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/jobs", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer key")
+	return r
+}
+
+func TestJobStoreStart(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		wantStatus string
+		wantOutput string
+		wantError  string
+	}{
+		{
+			name:       "collects streamed content",
+			handler:    sseHandler(t, nil, "Once ", "upon ", "a time"),
+			wantStatus: JobDone,
+			wantOutput: "Once upon a time",
+		},
+		{
+			name: "reports an error response",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				writeJSON(w, OpenAIError{Error: OpenAIErrorDetail{Message: "Model not found", Type: "not_found"}})
+			}),
+			wantStatus: JobFailed,
+			wantError:  "Model not found",
+		},
+		{
+			name: "reports an error sent in the stream",
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, `data: {"choices":[{"delta":{"content":"Hal"}}]}`+"\n\n")
+				io.WriteString(w, `data: {"error":{"message":"backend crashed","type":"server_error"}}`+"\n\n")
+			}),
+			wantStatus: JobFailed,
+			wantOutput: "Hal",
+			wantError:  "backend crashed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var js jobStore
+			j, err := js.start(newJobRequest(`{"model":"test/model","messages":[]}`), "", []byte(`{"model":"test/model","messages":[]}`), tt.handler)
+			if err != nil {
+				t.Fatalf("start: %v", err)
+			}
+			if j.model != "test/model" || !strings.HasPrefix(j.id, "job_") {
+				t.Errorf("job = %s %s", j.id, j.model)
+			}
+
+			info := waitJob(t, j)
+			if info.Status != tt.wantStatus || info.Output != tt.wantOutput || info.Error != tt.wantError {
+				t.Errorf("job = %q %q %q, want %q %q %q", info.Status, info.Output, info.Error, tt.wantStatus, tt.wantOutput, tt.wantError)
+			}
+			if info.Finished.IsZero() {
+				t.Error("finished time not set")
+			}
+		})
+	}
+}
+
+func TestJobStoreStartInvalidBody(t *testing.T) {
+	var js jobStore
+	if _, err := js.start(newJobRequest("nope"), "", []byte("nope"), sseHandler(t, nil)); err == nil {
+		t.Error("expected an error for a body that isn't JSON")
+	}
+	if len(js.list(nil)) != 0 {
+		t.Error("a job was kept for an invalid request")
+	}
+}
+
+func TestJobStoreKeepsHeaders(t *testing.T) {
+	var js jobStore
+	got := make(chan string, 1)
+	j, err := js.start(newJobRequest("{}"), "", []byte("{}"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("Authorization")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+	if auth := <-got; auth != "Bearer key" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestJobStorePrunesFinished(t *testing.T) {
+	var js jobStore
+	for range maxFinishedJobs + 5 {
+		j := &job{id: newJobID(), status: JobDone, changed: make(chan struct{})}
+		js.add(j)
+	}
+	running := &job{id: "job_running", status: JobRunning, changed: make(chan struct{})}
+	js.add(running)
+
+	list := js.list(nil)
+	if len(list) != maxFinishedJobs+1 {
+		t.Errorf("kept %d jobs, want %d", len(list), maxFinishedJobs+1)
+	}
+	if list[0].ID != "job_running" {
+		t.Errorf("newest job = %s, want job_running", list[0].ID)
+	}
+}
+
+func TestJobEndpoints(t *testing.T) {
+	release := make(chan struct{})
+	s := &Server{config: DefaultConfig(), shutdownChan: make(chan struct{})}
+	j, err := s.jobs.start(newJobRequest("{}"), "", []byte(`{"model":"m"}`), sseHandler(t, release, "first ", "second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the first part, so following starts mid-reply
+	for j.info(true).Output == "" {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleJobs(w, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+		var resp struct{ Jobs []JobInfo }
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Jobs) != 1 || resp.Jobs[0].ID != j.id || resp.Jobs[0].Status != JobRunning || resp.Jobs[0].Output != "" {
+			t.Errorf("jobs = %+v", resp.Jobs)
+		}
+	})
+
+	t.Run("logs so far", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleJob(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+j.id+"/logs", nil))
+		if w.Body.String() != "first " {
+			t.Errorf("logs = %q", w.Body.String())
+		}
+	})
+
+	t.Run("follow logs", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			s.handleJob(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+j.id+"/logs?follow=true", nil))
+			close(done)
+		}()
+		close(release)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("following did not end with the job")
+		}
+		if w.Body.String() != "first second" {
+			t.Errorf("followed logs = %q", w.Body.String())
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleJob(w, httptest.NewRequest(http.MethodGet, "/api/jobs/"+j.id, nil))
+		var info JobInfo
+		if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+			t.Fatal(err)
+		}
+		if info.Status != JobDone || info.Output != "first second" {
+			t.Errorf("job = %+v", info)
+		}
+	})
+
+	t.Run("delete finished", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleJob(w, httptest.NewRequest(http.MethodDelete, "/api/jobs/"+j.id, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d", w.Code)
+		}
+		if _, ok := s.jobs.get(j.id); ok {
+			t.Error("finished job not removed")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		s.handleJob(w, httptest.NewRequest(http.MethodGet, "/api/jobs/job_missing/logs", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", w.Code)
+		}
+	})
+}
+
+func TestJobCancel(t *testing.T) {
+	s := &Server{config: DefaultConfig(), shutdownChan: make(chan struct{})}
+	j, err := s.jobs.start(newJobRequest("{}"), "", []byte(`{"model":"m"}`), sseHandler(t, make(chan struct{}), "never"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	s.handleJob(w, httptest.NewRequest(http.MethodDelete, "/api/jobs/"+j.id, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if info := waitJob(t, j); info.Status != JobCancelled {
+		t.Errorf("status = %q, want cancelled", info.Status)
+	}
+	if _, ok := s.jobs.get(j.id); !ok {
+		t.Error("cancelled job should be kept until deleted again")
+	}
+}
+
+func TestJobsScopedToClient(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "ci-key"}, {Name: "notebook", APIKey: "nb-key"}}
+	s := &Server{config: cfg, shutdownChan: make(chan struct{})}
+	j, err := s.jobs.start(newJobRequest("{}"), "ci", []byte(`{"model":"m"}`), sseHandler(t, nil, "done"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitJob(t, j)
+
+	request := func(method, path, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		if path == "/api/jobs" {
+			s.handleJobs(w, r)
+		} else {
+			s.handleJob(w, r)
+		}
+		return w
+	}
+	listed := func(key string) int {
+		var resp struct{ Jobs []JobInfo }
+		if err := json.NewDecoder(request(http.MethodGet, "/api/jobs", key).Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return len(resp.Jobs)
+	}
+
+	if n := listed("ci-key"); n != 1 {
+		t.Errorf("owner lists %d jobs, want 1", n)
+	}
+	if n := listed("nb-key"); n != 0 {
+		t.Errorf("other client lists %d jobs, want 0", n)
+	}
+	if n := listed(""); n != 1 {
+		t.Errorf("request without a client lists %d jobs, want 1", n)
+	}
+
+	for _, path := range []string{"/api/jobs/" + j.id, "/api/jobs/" + j.id + "/logs"} {
+		if w := request(http.MethodGet, path, "nb-key"); w.Code != http.StatusNotFound {
+			t.Errorf("other client GET %s = %d, want 404", path, w.Code)
+		}
+	}
+	if w := request(http.MethodDelete, "/api/jobs/"+j.id, "nb-key"); w.Code != http.StatusNotFound {
+		t.Errorf("other client DELETE = %d, want 404", w.Code)
+	}
+	if _, ok := s.jobs.get(j.id); !ok {
+		t.Fatal("other client removed the job")
+	}
+
+	w := request(http.MethodGet, "/api/jobs/"+j.id, "ci-key")
+	var info JobInfo
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Client != "ci" || info.Output != "done" {
+		t.Errorf("owner's job = %+v", info)
+	}
+}
+
+func TestJobsFeature(t *testing.T) {
+	for _, on := range []bool{false, true} {
+		useTestHome(t)
+		t.Setenv(config.FeaturesEnv, "")
+		appCfg := config.DefaultConfig()
+		if on {
+			appCfg.Features = []string{config.FeatureBackgroundJobs}
+		}
+		s := NewServer(DefaultConfig(), appCfg)
+
+		w := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+		if on && w.Code != http.StatusOK {
+			t.Errorf("with %s on, GET /api/jobs = %d: %s", config.FeatureBackgroundJobs, w.Code, w.Body)
+		}
+		if !on && (w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), config.FeatureBackgroundJobs)) {
+			t.Errorf("with %s off, GET /api/jobs = %d: %s", config.FeatureBackgroundJobs, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// jsonRepairHeader turns JSON repair on or off for one request, overriding
+// server.json_repair. "off" returns the model's raw output.
+const jsonRepairHeader = "X-LLeme-JSON-Repair"
+
+// jsonRepairedHeader marks a response whose content was repaired
+const jsonRepairedHeader = "X-LLeme-JSON-Repaired"
+
+// wantsJSONRepair reports whether a chat request's reply should be
+// repaired: it asks for JSON through response_format, isn't streamed, and
+// repair is enabled for it.
+func wantsJSONRepair(r *http.Request, body []byte, enabled bool) bool {
+	switch strings.ToLower(r.Header.Get(jsonRepairHeader)) {
+	case "on", "true", "1":
+		enabled = true
+	case "off", "false", "0":
+		enabled = false
+	}
+	if !enabled {
+		return false
+	}
+
+	var req struct {
+		Stream         bool `json:"stream"`
+		ResponseFormat struct {
+			Type string `json:"type"`
+		} `json:"response_format"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Stream {
+		return false
+	}
+	return req.ResponseFormat.Type == "json_object" || req.ResponseFormat.Type == "json_schema"
+}
+
+// repairChoices repairs the content of each choice's message. It reports
+// whether anything changed.
+func repairChoices(resp map[string]any) bool {
+	choices, _ := resp["choices"].([]any)
+	changed := false
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		msg, _ := choice["message"].(map[string]any)
+		content, ok := msg["content"].(string)
+		if !ok {
+			continue
+		}
+		if fixed, ok := repairJSON(content); ok {
+			msg["content"] = fixed
+			changed = true
+		}
+	}
+	return changed
+}
+
+// repairJSON fixes the mistakes weaker models make when asked for JSON: a
+// markdown code fence around it, trailing commas, unquoted or single-quoted
+// keys and strings, and Python's True, False and None. It returns the
+// repaired text and true only when s was invalid and the result is valid.
+func repairJSON(s string) (string, bool) {
+	if json.Valid([]byte(s)) {
+		return s, false
+	}
+	fixed := repairTokens(stripCodeFence(s))
+	if !json.Valid([]byte(fixed)) {
+		return s, false
+	}
+	return fixed, true
+}
+
+// stripCodeFence removes a ```json ... ``` fence around s
+func stripCodeFence(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "```") || !strings.HasSuffix(trimmed, "```") || len(trimmed) < 6 {
+		return s
+	}
+	inner := trimmed[3 : len(trimmed)-3]
+	if nl := strings.IndexByte(inner, '\n'); nl >= 0 && !strings.ContainsAny(inner[:nl], "{[\"") {
+		inner = inner[nl+1:] // Language tag
+	}
+	return strings.TrimSpace(inner)
+}
+
+var pythonLiterals = map[string]string{"True": "true", "False": "false", "None": "null"}
+
+// repairTokens rewrites s outside of strings, leaving valid JSON as it is
+func repairTokens(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			i = copyString(&b, runes, i)
+		case r == ',':
+			// Drop a comma that only precedes a closing bracket
+			next := skipSpace(runes, i+1)
+			if next < len(runes) && (runes[next] == '}' || runes[next] == ']') {
+				continue
+			}
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			end := i
+			for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_' || runes[end] == '$' || runes[end] == '-') {
+				end++
+			}
+			word := string(runes[i:end])
+			next := skipSpace(runes, end)
+			switch {
+			case next < len(runes) && runes[next] == ':':
+				b.WriteString(quoteJSON(word))
+			case pythonLiterals[word] != "":
+				b.WriteString(pythonLiterals[word])
+			default:
+				b.WriteString(word)
+			}
+			i = end - 1
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// copyString writes the string starting at runes[start] as a JSON string,
+// converting single quotes, and returns the index of its closing quote
+func copyString(b *strings.Builder, runes []rune, start int) int {
+	quote := runes[start]
+	b.WriteByte('"')
+	for i := start + 1; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			if quote == '\'' && runes[i+1] == '\'' {
+				b.WriteRune('\'')
+			} else {
+				b.WriteRune(r)
+				b.WriteRune(runes[i+1])
+			}
+			i++
+		case r == quote:
+			b.WriteByte('"')
+			return i
+		case r == '"':
+			b.WriteString(`\"`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return len(runes)
+}
+
+func skipSpace(runes []rune, i int) int {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+func quoteJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
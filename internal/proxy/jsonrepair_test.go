@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		changed bool
+	}{
+		{"valid", `{"a": 1}`, `{"a": 1}`, false},
+		{"trailing comma in object", `{"a": 1,}`, `{"a": 1}`, true},
+		{"trailing comma in array", `{"a": [1, 2, ]}`, `{"a": [1, 2 ]}`, true},
+		{"unquoted keys", `{name: "Ada", born_year: 1815}`, `{"name": "Ada", "born_year": 1815}`, true},
+		{"single quotes", `{'name': 'Ada\'s "notes"'}`, `{"name": "Ada's \"notes\""}`, true},
+		{"python literals", `{"ok": True, "err": None, "retry": False}`, `{"ok": true, "err": null, "retry": false}`, true},
+		{"code fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`, true},
+		{"code fence without tag", "```\n[1, 2,]\n```", `[1, 2]`, true},
+		{"newline in string", "{\"a\": \"one\ntwo\"}", `{"a": "one\ntwo"}`, true},
+		{"commas and colons in strings kept", `{a: "x,}", b: "k: v",}`, `{"a": "x,}", "b": "k: v"}`, true},
+		{"exponent", `{n: 1e5,}`, `{"n": 1e5}`, true},
+		{"truncated", `{"a": [1, 2`, `{"a": [1, 2`, false},
+		{"prose", `Sure! Here is the JSON`, `Sure! Here is the JSON`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := repairJSON(tt.in)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("repairJSON(%q) = %q, %v; want %q, %v", tt.in, got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestWantsJSONRepair(t *testing.T) {
+	jsonMode := `{"response_format": {"type": "json_object"}}`
+	tests := []struct {
+		name    string
+		body    string
+		header  string
+		enabled bool
+		want    bool
+	}{
+		{"enabled", jsonMode, "", true, true},
+		{"disabled", jsonMode, "", false, false},
+		{"header turns on", jsonMode, "on", false, true},
+		{"header asks for raw", jsonMode, "off", true, false},
+		{"schema", `{"response_format": {"type": "json_schema"}}`, "", true, true},
+		{"text", `{"response_format": {"type": "text"}}`, "", true, false},
+		{"no format", `{}`, "", true, false},
+		{"streamed", `{"stream": true, "response_format": {"type": "json_object"}}`, "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set(jsonRepairHeader, tt.header)
+			}
+			if got := wantsJSONRepair(r, []byte(tt.body), tt.enabled); got != tt.want {
+				t.Errorf("wantsJSONRepair() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairChoices(t *testing.T) {
+	resp := map[string]any{
+		"choices": []any{
+			map[string]any{"message": map[string]any{"content": `{a: 1,}`}},
+			map[string]any{"message": map[string]any{"content": `{"b": 2}`}},
+			map[string]any{"message": map[string]any{"content": nil}},
+		},
+	}
+	if !repairChoices(resp) {
+		t.Fatal("repairChoices() = false, want true")
+	}
+	choices := resp["choices"].([]any)
+	if got := choices[0].(map[string]any)["message"].(map[string]any)["content"]; got != `{"a": 1}` {
+		t.Errorf("content = %q", got)
+	}
+	if got := choices[1].(map[string]any)["message"].(map[string]any)["content"]; got != `{"b": 2}` {
+		t.Errorf("valid content changed to %q", got)
+	}
+}
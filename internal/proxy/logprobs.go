@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// maxTopLogprobs is OpenAI's limit on alternatives per token
+const maxTopLogprobs = 20
+
+// logprobsRequest is what a client asked for, so the backend's
+// probabilities can be shaped to match. top is the number of alternatives
+// per token.
+type logprobsRequest struct {
+	chat bool
+	top  int
+}
+
+// mapLogprobsRequest translates OpenAI's logprobs fields into llama-server's
+// n_probs. Chat takes logprobs: true plus top_logprobs; completions take
+// logprobs as the number of alternatives. It returns nil when the client
+// didn't ask for logprobs.
+func mapLogprobsRequest(path string, body []byte) ([]byte, *logprobsRequest) {
+	var req map[string]any
+	if json.Unmarshal(body, &req) != nil {
+		return body, nil
+	}
+
+	lp := &logprobsRequest{chat: path == "/v1/chat/completions"}
+	if lp.chat {
+		if on, _ := req["logprobs"].(bool); !on {
+			return body, nil
+		}
+		top, _ := req["top_logprobs"].(float64)
+		lp.top = int(top)
+	} else {
+		top, ok := req["logprobs"].(float64)
+		if !ok {
+			return body, nil
+		}
+		lp.top = int(top)
+	}
+
+	// llama-server reports nothing with n_probs 0
+	req["n_probs"] = max(lp.top, 1)
+	data, err := json.Marshal(req)
+	if err != nil {
+		return body, nil
+	}
+	return data, lp
+}
+
+// tokenLogprob is one generated token with its most likely alternatives
+type tokenLogprob struct {
+	Token   string       `json:"token"`
+	Logprob float64      `json:"logprob"`
+	Bytes   []int        `json:"bytes"`
+	Top     []topLogprob `json:"top_logprobs"`
+}
+
+type topLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
+}
+
+// convertLogprobs rewrites the probability data in a response or stream
+// chunk into OpenAI's logprobs schema for the first choice. llama-server
+// reports it as completion_probabilities (older builds: probabilities
+// rather than log probabilities) or as choices[].logprobs.content.
+// It reports whether anything changed.
+func (lp *logprobsRequest) convertLogprobs(resp map[string]any) bool {
+	choices, _ := resp["choices"].([]any)
+	if len(choices) == 0 {
+		return false
+	}
+	choice, _ := choices[0].(map[string]any)
+	if choice == nil {
+		return false
+	}
+
+	raw, ok := resp["completion_probabilities"].([]any)
+	delete(resp, "completion_probabilities")
+	if !ok {
+		existing, _ := choice["logprobs"].(map[string]any)
+		if raw, ok = existing["content"].([]any); !ok {
+			return false
+		}
+	}
+
+	tokens := make([]tokenLogprob, 0, len(raw))
+	for _, e := range raw {
+		if entry, ok := e.(map[string]any); ok {
+			tokens = append(tokens, parseTokenLogprob(entry, lp.top))
+		}
+	}
+
+	if lp.chat {
+		choice["logprobs"] = map[string]any{"content": tokens}
+	} else {
+		choice["logprobs"] = completionLogprobs(tokens)
+	}
+	return true
+}
+
+// parseTokenLogprob reads one entry in any of llama-server's formats,
+// keeping at most top alternatives
+func parseTokenLogprob(entry map[string]any, top int) tokenLogprob {
+	t := tokenLogprob{Token: firstString(entry, "token", "content", "tok_str")}
+	t.Logprob = entryLogprob(entry)
+	t.Bytes = tokenBytes(entry, t.Token)
+	chosenFound := t.Logprob != minLogprob
+
+	var alts []any
+	for _, key := range []string{"top_logprobs", "top_probs", "probs"} {
+		if list, ok := entry[key].([]any); ok {
+			alts = list
+			break
+		}
+	}
+	t.Top = []topLogprob{}
+	for _, a := range alts {
+		if len(t.Top) == top {
+			break
+		}
+		if alt, ok := a.(map[string]any); ok {
+			token := firstString(alt, "token", "tok_str")
+			t.Top = append(t.Top, topLogprob{Token: token, Logprob: entryLogprob(alt), Bytes: tokenBytes(alt, token)})
+		}
+	}
+
+	// Older builds only give the chosen token's probability among the
+	// alternatives
+	if !chosenFound {
+		for _, a := range alts {
+			if alt, ok := a.(map[string]any); ok && firstString(alt, "token", "tok_str") == t.Token {
+				t.Logprob = entryLogprob(alt)
+				break
+			}
+		}
+	}
+	return t
+}
+
+// completionLogprobs lays tokens out in the legacy completions format
+func completionLogprobs(tokens []tokenLogprob) map[string]any {
+	out := map[string]any{
+		"tokens":         []string{},
+		"token_logprobs": []float64{},
+		"top_logprobs":   []map[string]float64{},
+		"text_offset":    []int{},
+	}
+	offset := 0
+	for _, t := range tokens {
+		top := make(map[string]float64, len(t.Top))
+		for _, alt := range t.Top {
+			top[alt.Token] = alt.Logprob
+		}
+		out["tokens"] = append(out["tokens"].([]string), t.Token)
+		out["token_logprobs"] = append(out["token_logprobs"].([]float64), t.Logprob)
+		out["top_logprobs"] = append(out["top_logprobs"].([]map[string]float64), top)
+		out["text_offset"] = append(out["text_offset"].([]int), offset)
+		offset += len(t.Token)
+	}
+	return out
+}
+
+func firstString(m map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := m[k].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// minLogprob stands in for an unknown or zero probability, as OpenAI does,
+// since JSON can't carry -Inf
+const minLogprob = -9999.0
+
+// entryLogprob returns an entry's log probability, converting from a plain
+// probability when that's all it has
+func entryLogprob(m map[string]any) float64 {
+	if v, ok := m["logprob"].(float64); ok {
+		return v
+	}
+	if p, ok := m["prob"].(float64); ok && p > 0 {
+		return math.Log(p)
+	}
+	return minLogprob
+}
+
+func tokenBytes(m map[string]any, token string) []int {
+	if list, ok := m["bytes"].([]any); ok {
+		b := make([]int, 0, len(list))
+		for _, v := range list {
+			n, _ := v.(float64)
+			b = append(b, int(n))
+		}
+		return b
+	}
+	b := make([]int, len(token))
+	for i := range len(token) {
+		b[i] = int(token[i])
+	}
+	return b
+}
+
+// convertLogprobsResponse applies convertLogprobs to a successful backend
+// response, either a JSON body or each event of an SSE stream
+func (lp *logprobsRequest) convertLogprobsResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		resp.Body = lp.convertStream(resp.Body)
+		resp.ContentLength = -1
+		resp.Header.Del("Content-Length")
+	case strings.HasPrefix(contentType, "application/json"):
+		return rewriteJSONResponse(resp, lp.convertLogprobs)
+	}
+	return nil
+}
+
+// convertStream rewrites the data lines of an SSE stream as they arrive
+func (lp *logprobsRequest) convertStream(body io.ReadCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		r := bufio.NewReader(body)
+		for {
+			line, err := r.ReadBytes('\n')
+			if len(line) > 0 {
+				if _, werr := pw.Write(lp.convertEvent(line)); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				pw.CloseWithError(err)
+				return
+			}
+		}
+	}()
+	return pr
+}
+
+func (lp *logprobsRequest) convertEvent(line []byte) []byte {
+	payload, ok := bytes.CutPrefix(line, []byte("data: "))
+	if !ok {
+		return line
+	}
+	var chunk map[string]any
+	if json.Unmarshal(bytes.TrimSpace(payload), &chunk) != nil || !lp.convertLogprobs(chunk) {
+		return line
+	}
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return line
+	}
+	return append(append([]byte("data: "), data...), '\n')
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMapLogprobsRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		want      *logprobsRequest
+		wantNProb float64
+	}{
+		{"chat off", "/v1/chat/completions", `{"messages":[]}`, nil, 0},
+		{"chat false", "/v1/chat/completions", `{"logprobs":false}`, nil, 0},
+		{"chat", "/v1/chat/completions", `{"logprobs":true,"top_logprobs":3}`, &logprobsRequest{chat: true, top: 3}, 3},
+		{"chat no alternatives", "/v1/chat/completions", `{"logprobs":true}`, &logprobsRequest{chat: true}, 1},
+		{"completions", "/v1/completions", `{"prompt":"x","logprobs":2}`, &logprobsRequest{top: 2}, 2},
+		{"completions off", "/v1/completions", `{"prompt":"x"}`, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, got := mapLogprobsRequest(tt.path, []byte(tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("request = %+v, want %+v", got, tt.want)
+			}
+			if tt.want == nil {
+				if string(body) != tt.body {
+					t.Errorf("body changed to %s", body)
+				}
+				return
+			}
+			var req map[string]any
+			json.Unmarshal(body, &req)
+			if req["n_probs"] != tt.wantNProb {
+				t.Errorf("n_probs = %v, want %v", req["n_probs"], tt.wantNProb)
+			}
+		})
+	}
+}
+
+func TestConvertLogprobs(t *testing.T) {
+	// Older llama-server builds report probabilities, not log probabilities
+	native := `{"choices":[{"index":0,"text":"Hi!"}],"completion_probabilities":[
+		{"content":"Hi","probs":[{"tok_str":"Hi","prob":0.5},{"tok_str":"Hello","prob":0.25}]},
+		{"content":"!","probs":[{"tok_str":"!","prob":1}]}]}`
+	// Newer builds use OpenAI's chat shape everywhere
+	openai := `{"choices":[{"index":0,"logprobs":{"content":[
+		{"id":1,"token":"Hi","logprob":-0.1,"bytes":[72,105],"top_logprobs":[{"id":1,"token":"Hi","logprob":-0.1,"bytes":[72,105]},{"id":2,"token":"Yo","logprob":-2.5,"bytes":[89,111]}]}]}}]}`
+
+	t.Run("chat from probabilities", func(t *testing.T) {
+		var resp map[string]any
+		json.Unmarshal([]byte(native), &resp)
+		lp := &logprobsRequest{chat: true, top: 1}
+		if !lp.convertLogprobs(resp) {
+			t.Fatal("convertLogprobs() = false")
+		}
+		if _, ok := resp["completion_probabilities"]; ok {
+			t.Error("completion_probabilities should be removed")
+		}
+		data, _ := json.Marshal(resp["choices"].([]any)[0].(map[string]any)["logprobs"])
+		var got struct{ Content []tokenLogprob }
+		json.Unmarshal(data, &got)
+		want := []tokenLogprob{
+			{Token: "Hi", Logprob: math.Log(0.5), Bytes: []int{72, 105}, Top: []topLogprob{{Token: "Hi", Logprob: math.Log(0.5), Bytes: []int{72, 105}}}},
+			{Token: "!", Logprob: 0, Bytes: []int{33}, Top: []topLogprob{{Token: "!", Logprob: 0, Bytes: []int{33}}}},
+		}
+		if !reflect.DeepEqual(got.Content, want) {
+			t.Errorf("content = %+v\nwant %+v", got.Content, want)
+		}
+	})
+
+	t.Run("completions format", func(t *testing.T) {
+		var resp map[string]any
+		json.Unmarshal([]byte(openai), &resp)
+		lp := &logprobsRequest{top: 2}
+		lp.convertLogprobs(resp)
+		data, _ := json.Marshal(resp["choices"].([]any)[0].(map[string]any)["logprobs"])
+		want := `{"text_offset":[0],"token_logprobs":[-0.1],"tokens":["Hi"],"top_logprobs":[{"Hi":-0.1,"Yo":-2.5}]}`
+		if string(data) != want {
+			t.Errorf("logprobs = %s\nwant %s", data, want)
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		resp := map[string]any{"choices": []any{map[string]any{"index": 0}}}
+		if (&logprobsRequest{chat: true}).convertLogprobs(resp) {
+			t.Error("convertLogprobs() = true without probabilities")
+		}
+	})
+}
+
+func TestConvertLogprobsStream(t *testing.T) {
+	stream := "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"Hi\"}}],\"completion_probabilities\":[{\"token\":\"Hi\",\"logprob\":-0.5,\"top_logprobs\":[]}]}\n\n" +
+		"data: [DONE]\n\n"
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(stream)),
+	}
+
+	lp := &logprobsRequest{chat: true}
+	if err := lp.convertLogprobsResponse(resp); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(string(data), "\n")
+	if len(lines) != 5 || lines[2] != "data: [DONE]" {
+		t.Fatalf("stream = %q", data)
+	}
+	want := `data: {"choices":[{"delta":{"content":"Hi"},"index":0,"logprobs":{"content":[{"token":"Hi","logprob":-0.5,"bytes":[72,105],"top_logprobs":[]}]}}]}`
+	if lines[0] != want {
+		t.Errorf("event = %s\nwant %s", lines[0], want)
+	}
+}
@@ -2,20 +2,27 @@ package proxy
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"maps"
 	"net/http"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/nchapman/lleme/internal/clock"
 	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/hf"
 	"github.com/nchapman/lleme/internal/llama"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/options"
 )
 
 // ModelManager manages the lifecycle of llama-server backend instances
@@ -26,19 +33,41 @@ type ModelManager struct {
 	portAllocator *PortAllocator
 	resolver      *ModelResolver
 	config        *Config
+	events        eventBus
+	pulls         pullGroup
+	changing      map[string]string // Models whose files are being removed or updated
+	power         powerMonitor
+	quietHours    *quietHours // nil without server.unload_schedule
+	clock         clock.Clock
 	appConfig     *config.Config
 	onStateChange func() // called after backend start/stop to persist state
 }
 
 // NewModelManager creates a new model manager
 func NewModelManager(cfg *Config, appCfg *config.Config) *ModelManager {
+	return NewModelManagerWithClock(cfg, appCfg, clock.System)
+}
+
+// NewModelManagerWithClock creates a model manager that tells time by clk,
+// for idle timeouts, quiet hours, and activity tracking
+func NewModelManagerWithClock(cfg *Config, appCfg *config.Config, clk clock.Clock) *ModelManager {
+	resolver := NewModelResolver()
+	if appCfg != nil {
+		resolver.PreferQuant(appCfg.HuggingFace.DefaultQuant)
+	}
+
+	// An invalid schedule is reported when the server starts
+	quiet, _ := parseQuietHours(cfg.UnloadSchedule)
+
 	return &ModelManager{
 		backends:      make(map[string]*Backend),
 		lruOrder:      make([]string, 0),
 		portAllocator: NewPortAllocator(cfg.BackendPortMin, cfg.BackendPortMax),
-		resolver:      NewModelResolver(),
+		resolver:      resolver,
 		config:        cfg,
 		appConfig:     appCfg,
+		quietHours:    quiet,
+		clock:         clk,
 	}
 }
 
@@ -50,10 +79,30 @@ func (m *ModelManager) SetStateChangeCallback(fn func()) {
 	m.onStateChange = fn
 }
 
+// Subscribe returns a channel of backend lifecycle events and a function
+// that ends the subscription. Events are dropped if the reader falls behind.
+func (m *ModelManager) Subscribe() (<-chan Event, func()) {
+	return m.events.subscribe()
+}
+
 // GetOrLoadBackend returns a backend for the given model, loading it if necessary.
 // Options override config defaults for this specific load (ctx-size, gpu-layers, etc.).
 func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]any) (*Backend, error) {
-	// First, resolve the model name
+	return m.getOrLoad(modelQuery, options, BackendLlama)
+}
+
+// GetOrLoadImageBackend returns a stable-diffusion.cpp backend for the given
+// model, loading it if necessary.
+func (m *ModelManager) GetOrLoadImageBackend(modelQuery string) (*Backend, error) {
+	if !llama.IsSDInstalled() {
+		return nil, ErrSDNotInstalled
+	}
+	return m.getOrLoad(modelQuery, nil, BackendImage)
+}
+
+// resolveModel resolves a query to one downloaded model, returning an
+// AmbiguousModelError or ModelNotFoundError when it can't
+func (m *ModelManager) resolveModel(modelQuery string) (*DownloadedModel, error) {
 	result, err := m.resolver.Resolve(modelQuery)
 	if err != nil {
 		return nil, err
@@ -83,11 +132,27 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 		}
 	}
 
-	modelName := result.Model.FullName
-	modelPath := result.Model.ModelPath
+	return result.Model, nil
+}
+
+func (m *ModelManager) getOrLoad(modelQuery string, options map[string]any, kind BackendKind) (*Backend, error) {
+	return m.getOrLoadWith(modelQuery, options, kind, nil)
+}
+
+// getOrLoadWith is getOrLoad with the model's memory snapshot, once one has
+// been taken. Without one, a llama-server load unlocks to take it and
+// starts over, so the manager isn't locked while it's read.
+func (m *ModelManager) getOrLoadWith(modelQuery string, options map[string]any, kind BackendKind, snap *memorySnapshot) (*Backend, error) {
+	model, err := m.resolveModel(modelQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	modelName := model.FullName
+	modelPath := model.ModelPath
 
 	// Track model usage for cleanup purposes (non-critical)
-	if err := hf.TouchLastUsed(result.Model.User, result.Model.Repo, result.Model.Quant); err != nil {
+	if err := hf.TouchLastUsed(model.User, model.Repo, model.Quant); err != nil {
 		logs.Debug("failed to update last used timestamp", "model", modelName, "error", err)
 	}
 
@@ -97,8 +162,8 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 	if exists {
 		switch status := backend.GetStatus(); status {
 		case BackendReady:
-			// Check if options changed - if so, reload the model
-			if optionsChanged(backend.Options, options) {
+			// Check if options or server changed - if so, reload the model
+			if backend.Kind != kind || optionsChanged(backend.Options, options) {
 				// Mark as stopping to prevent race conditions
 				backend.SetStatus(BackendStopping)
 				m.mu.Unlock()
@@ -120,11 +185,11 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 			<-readyChan
 			if backend.GetStatus() == BackendReady {
 				// Check options after it's ready
-				if optionsChanged(backend.Options, options) {
+				if backend.Kind != kind || optionsChanged(backend.Options, options) {
 					// Need to reload with different options
 					m.StopBackend(modelName)
 					// Recursively call to load with new options
-					return m.GetOrLoadBackend(modelQuery, options)
+					return m.getOrLoad(modelQuery, options, kind)
 				}
 				backend.UpdateActivity()
 				return backend, nil
@@ -133,24 +198,63 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 		}
 	}
 
-	// Need to start a new backend
+	if kind == BackendLlama && snap == nil && m.config.MemoryGuard != MemoryGuardOff {
+		m.mu.Unlock()
+		return m.getOrLoadWith(modelQuery, options, kind, m.takeMemorySnapshot(modelName, modelPath))
+	}
+
+	// Need to start a new backend, unless its files are changing or it's
+	// quiet hours
+	if err := m.checkChanging(modelName); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	if err := m.checkQuietHours(modelName); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	// Don't evict anything for a model the installed llama.cpp can't load
+	if kind == BackendLlama && !mock.Enabled() {
+		if err := m.checkCompatibility(modelName, modelPath); err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+	}
+
 	// Check if we need to evict
 	if m.config.MaxModels > 0 && len(m.backends) >= m.config.MaxModels {
-		lruModel := m.getLRUModel()
-		if lruModel == "" {
+		victim := m.evictionCandidate()
+		if victim == "" {
 			m.mu.Unlock()
 			return nil, fmt.Errorf("failed to evict model: no models to evict")
 		}
-		// Mark as stopping to prevent concurrent eviction race
-		if lruBackend := m.backends[lruModel]; lruBackend != nil {
-			lruBackend.SetStatus(BackendStopping)
+		logs.Info("Evicting model to free slot", "model", victim, "policy", m.config.Eviction)
+		if err := m.evict(victim); err != nil {
+			m.mu.Unlock()
+			return nil, err
 		}
-		m.mu.Unlock()
-		logs.Info("Evicting model to free slot", "model", lruModel)
-		if err := m.StopBackend(lruModel); err != nil {
-			return nil, fmt.Errorf("failed to evict model: %w", err)
+
+		// A removal or update may have started while the lock was released
+		if err := m.checkChanging(modelName); err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+	}
+
+	// Go easy on a laptop that's saving power, then make sure the model fits
+	// before launching it (may shrink ctx-size or unload other models)
+	var adjusted map[string]any
+	var memory int64
+	if kind == BackendLlama {
+		options, adjusted, err = m.fitInMemory(modelName, snap, options)
+		if err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		if estimate := m.estimate(modelName, snap, options); estimate != nil {
+			memory = estimate.Total()
 		}
-		m.mu.Lock()
 	}
 
 	// Allocate port
@@ -162,14 +266,19 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 
 	// Create backend entry
 	backend = &Backend{
+		Kind:         kind,
 		ModelName:    modelName,
 		ModelPath:    modelPath,
 		Port:         port,
 		Status:       BackendStarting,
-		StartedAt:    time.Now(),
-		LastActivity: time.Now(),
+		StartedAt:    m.clock.Now(),
+		LastActivity: m.clock.Now(),
 		ReadyChan:    make(chan struct{}),
 		Options:      options,
+		adjusted:     adjusted,
+		memory:       memory,
+		retrying:     make(chan struct{}, 1),
+		clock:        m.clock,
 	}
 	m.backends[modelName] = backend
 	m.lruOrder = append([]string{modelName}, m.lruOrder...)
@@ -180,20 +289,27 @@ func (m *ModelManager) GetOrLoadBackend(modelQuery string, options map[string]an
 	if callback != nil {
 		callback()
 	}
+	m.events.publish(EventLoading, modelName)
 
 	// Start the backend in background
 	go m.startBackend(backend)
 
-	// Wait for ready
-	select {
-	case <-backend.ReadyChan:
-		if backend.GetStatus() == BackendReady {
-			return backend, nil
+	// Wait for ready, allowing each retry with fewer GPU layers its own timeout
+	timeout := time.NewTimer(m.config.StartupTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case <-backend.ReadyChan:
+			if backend.GetStatus() == BackendReady {
+				return backend, nil
+			}
+			return nil, fmt.Errorf("backend failed to start")
+		case <-backend.retrying:
+			timeout.Reset(m.config.StartupTimeout)
+		case <-timeout.C:
+			m.StopBackend(modelName)
+			return nil, fmt.Errorf("backend startup timeout after %v", m.config.StartupTimeout)
 		}
-		return nil, fmt.Errorf("backend failed to start")
-	case <-time.After(m.config.StartupTimeout):
-		m.StopBackend(modelName)
-		return nil, fmt.Errorf("backend startup timeout after %v", m.config.StartupTimeout)
 	}
 }
 
@@ -233,11 +349,25 @@ func (m *ModelManager) ListBackends() []BackendInfo {
 			StartedAt:    backend.StartedAt,
 			LastActivity: backend.GetLastActivity(),
 			IdleMinutes:  backend.IdleDuration().Minutes(),
+			Usage:        backend.Usage(),
 		})
 	}
 	return infos
 }
 
+// loadSpec returns how a loaded model was loaded: its kind of backend and
+// the options it was given
+func (m *ModelManager) loadSpec(modelName string) (BackendKind, map[string]any) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	backend, ok := m.backends[modelName]
+	if !ok {
+		return BackendLlama, nil
+	}
+	return backend.Kind, maps.Clone(backend.Options)
+}
+
 // StopBackend stops a specific backend
 func (m *ModelManager) StopBackend(modelName string) error {
 	m.mu.Lock()
@@ -255,41 +385,62 @@ func (m *ModelManager) StopBackend(modelName string) error {
 		backend.Process.Signal(syscall.SIGTERM)
 
 		// Wait for graceful exit (up to 5 seconds)
-		done := make(chan struct{})
-		go func() {
-			backend.Process.Wait()
-			close(done)
-		}()
-
 		select {
-		case <-done:
+		case <-backend.exited:
 			// Process exited gracefully
 		case <-time.After(5 * time.Second):
 			// Force kill
 			backend.Process.Kill()
-			backend.Process.Wait()
+			<-backend.exited
 		}
 	}
+	if backend.mock != nil {
+		backend.mock.Close()
+	}
 
-	// Cleanup
 	m.mu.Lock()
-	backend.SetStatus(BackendStopped)
+	m.removeBackend(backend, BackendStopped)
+	return nil
+}
+
+// removeBackend drops a backend whose process is gone and frees its port.
+// The caller holds m.mu, which is released.
+func (m *ModelManager) removeBackend(backend *Backend, status BackendStatus) {
+	modelName := backend.ModelName
+	backend.SetStatus(status)
 	backend.CloseReadyChan()
 	if backend.LogWriter != nil {
 		backend.LogWriter.Close()
 	}
 	m.portAllocator.Release(backend.Port)
+	// The port may go to the next backend, so don't reuse connections to it
+	backendTransport.CloseIdleConnections()
 	delete(m.backends, modelName)
 	m.removeLRU(modelName)
 	callback := m.onStateChange
 	m.mu.Unlock()
 
+	m.events.publish(EventUnloaded, modelName)
+
 	// Notify state change for persistence
 	if callback != nil {
 		callback()
 	}
+}
 
-	return nil
+// watchExit takes a ready backend out of rotation the moment its process
+// dies, so requests load a fresh one instead of hitting a dead port.
+func (m *ModelManager) watchExit(backend *Backend) {
+	<-backend.exited
+
+	m.mu.Lock()
+	if m.backends[backend.ModelName] != backend || backend.GetStatus() != BackendReady {
+		// Stopped on purpose
+		m.mu.Unlock()
+		return
+	}
+	logs.Warn("Backend exited unexpectedly", "model", backend.ModelName, "pid", backend.Process.Pid, "error", backend.exitErr)
+	m.removeBackend(backend, BackendFailed)
 }
 
 // StopAllBackends stops all running backends
@@ -337,27 +488,54 @@ func (m *ModelManager) Resolver() *ModelResolver {
 	return m.resolver
 }
 
-// startBackend starts the llama-server process for a backend
+// startBackend starts the server process for a backend
 func (m *ModelManager) startBackend(backend *Backend) {
 	defer func() {
 		// Ensure ReadyChan is closed even on error
 		if backend.GetStatus() != BackendReady {
 			backend.CloseReadyChan()
+			m.events.publish(EventLoadFailed, backend.ModelName)
 		}
 	}()
 
-	serverPath := llama.ServerPath()
-	args := m.buildArgs(backend)
+	if mock.Enabled() {
+		m.startMockBackend(backend)
+		return
+	}
+
+	m.useRecordedGPULayers(backend)
+	err := m.launch(backend)
+	if err != nil && canTuneGPULayers(backend) && ranOutOfVRAM(logs.BackendLogPath(backend.ModelName)) {
+		err = m.tuneGPULayers(backend)
+	}
+	if err != nil {
+		logs.Debug("Backend failed to start", "model", backend.ModelName, "error", err)
+		backend.SetStatus(BackendStopped)
+		return
+	}
+
+	m.warmUp(backend)
+	m.markReady(backend)
+	go m.watchExit(backend)
+}
 
-	cmd := exec.Command(serverPath, args...)
+// launch runs the server process for a backend and waits for it to be
+// ready. A process that doesn't get there is killed.
+func (m *ModelManager) launch(backend *Backend) error {
+	lc := m.launchConfig(backend)
+	logs.Debug("Starting backend", "model", backend.ModelName, "port", backend.Port, "path", lc.Path, "args", strings.Join(lc.Args, " "))
+
+	cmd := exec.Command(lc.Path, lc.Args...)
 	cmd.Env = os.Environ()
-	cmd.Dir = config.BinPath()
+	cmd.Dir = lc.Dir
+
+	lc.LaunchedAt = time.Now()
+	backend.setLaunchConfig(lc)
 
 	// Create rotating log writer for this backend
 	logWriter, err := logs.NewRotatingWriter(logs.BackendLogPath(backend.ModelName))
 	if err != nil {
-		backend.SetStatus(BackendStopped)
-		return
+		return err
 	}
 	backend.LogWriter = logWriter
 
@@ -366,22 +544,93 @@ func (m *ModelManager) startBackend(backend *Backend) {
 
 	if err := cmd.Start(); err != nil {
 		logWriter.Close()
-		backend.SetStatus(BackendStopped)
-		return
+		return err
 	}
 
 	backend.Process = cmd.Process
+	backend.exited = make(chan struct{})
+	go func() {
+		backend.exitErr = cmd.Wait()
+		close(backend.exited)
+	}()
 
 	// Wait for server to be ready
 	if err := m.waitForReady(backend); err != nil {
+		m.killProcess(backend)
+		return err
+	}
+	return nil
+}
+
+// killProcess kills a backend's server process, waiting for it to exit so
+// its memory is free, and closes its log
+func (m *ModelManager) killProcess(backend *Backend) {
+	backend.Process.Kill()
+	<-backend.exited
+	backend.LogWriter.Close()
+}
+
+// startMockBackend serves canned responses in-process instead of running
+// llama-server, for tests that set LLEME_MOCK_BACKEND=1.
+func (m *ModelManager) startMockBackend(backend *Backend) {
+	addr := fmt.Sprintf("%s:%d", m.config.Host, backend.Port)
+	server, err := mock.Start(addr, backend.ModelName)
+	if err != nil {
 		backend.SetStatus(BackendStopped)
-		cmd.Process.Kill()
-		logWriter.Close()
+		return
+	}
+	backend.mock = server
+
+	if err := m.waitForReady(backend); err != nil {
+		backend.SetStatus(BackendStopped)
+		server.Close()
+		return
+	}
+
+	m.warmUp(backend)
+	m.markReady(backend)
+}
+
+// warmUp sends a one-token completion when server.warmup is on, so graph
+// setup and Metal shader compilation happen during the load rather than on
+// the first real request. Failures are logged and otherwise ignored.
+func (m *ModelManager) warmUp(backend *Backend) {
+	if !m.config.WarmUp || backend.Kind != BackendLlama {
+		return
+	}
+	if params, err := hf.ReadGGUFModelParams(backend.ModelPath); err == nil && params.IsEmbedding() {
 		return
 	}
 
+	start := time.Now()
+	if err := m.sendWarmUp(backend); err != nil {
+		logs.Debug("Warm-up failed", "model", backend.ModelName, "duration", time.Since(start), "error", err)
+		return
+	}
+	logs.Debug("Warmed up backend", "model", backend.ModelName, "duration", time.Since(start))
+}
+
+func (m *ModelManager) sendWarmUp(backend *Backend) error {
+	url := fmt.Sprintf("http://%s:%d/v1/completions", m.config.Host, backend.Port)
+	body := strings.NewReader(`{"prompt":"Hi","max_tokens":1}`)
+	client := backendClient(m.config.StartupTimeout)
+	resp, err := client.Post(url, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// markReady flags a started backend as ready and wakes waiting requests
+func (m *ModelManager) markReady(backend *Backend) {
 	backend.SetStatus(BackendReady)
 	backend.CloseReadyChan()
+	m.events.publish(EventLoaded, backend.ModelName)
 
 	logs.Info("Model loaded", "model", backend.ModelName, "port", backend.Port)
 
@@ -394,7 +643,9 @@ func (m *ModelManager) startBackend(backend *Backend) {
 	}
 }
 
-func (m *ModelManager) buildArgs(backend *Backend) []string {
+// buildArgs builds the llama-server command line, returning it with the
+// options it was built from
+func (m *ModelManager) buildArgs(backend *Backend) ([]string, options.Resolved) {
 	args := []string{
 		"--model", backend.ModelPath,
 		"--host", m.config.Host,
@@ -408,61 +659,138 @@ func (m *ModelManager) buildArgs(backend *Backend) []string {
 		args = append(args, "--mmproj", mmprojPath)
 	}
 
+	// Embedding models have no chat template and need a pooling strategy
+	params, _ := hf.ReadGGUFModelParams(backend.ModelPath)
+	embedding := params != nil && params.IsEmbedding()
+
 	// Apply template patches to work around llama-server issues.
 	// See template.go for the patch registry and documentation.
-	if templatePath, err := ExtractAndPatchTemplate(backend.ModelPath); err == nil && templatePath != "" {
-		args = append(args, "--chat-template-file", templatePath)
+	if !embedding {
+		if templatePath, err := ExtractAndPatchTemplate(backend.ModelPath); err == nil && templatePath != "" {
+			args = append(args, "--chat-template-file", templatePath)
+		}
 	}
 
-	// Merge config options with backend-specific options (backend overrides config)
-	mergedOptions := make(map[string]any)
-	maps.Copy(mergedOptions, m.appConfig.LlamaCpp.Options)
-	maps.Copy(mergedOptions, backend.Options)
+	// Embedding models take no sampler preset, and need a pooling strategy
+	presets := !embedding && (m.appConfig == nil || m.appConfig.LlamaCpp.SamplerPresets != "off")
+	resolved := m.resolveOptions(backend, presets)
+	mergedOptions := resolved.Map()
+	if embedding {
+		args = append(args, poolingArgs(params, mergedOptions)...)
+	}
 
 	// Pass through all llama-server options
 	args = append(args, buildLlamaServerArgs(mergedOptions)...)
 
-	return args
+	return args, resolved
 }
 
-// findMMProjForModel parses the model name and checks if an mmproj file exists.
-// ModelName format: "user/repo:quant" (e.g., "ggml-org/gemma-3-4b-it-GGUF:Q4_K_M")
-func findMMProjForModel(modelName string) string {
-	parts := strings.Split(modelName, ":")
-	if len(parts) != 2 {
-		return ""
+// resolveOptions merges the options backend's server starts with. From
+// lowest priority: its family's sampler preset when presets is set,
+// llamacpp.options, per-model options, the options it was loaded with, and
+// what the server changed at load, including GPU layers found to fit.
+func (m *ModelManager) resolveOptions(backend *Backend, presets bool) options.Resolved {
+	var l options.Layers
+	if m.appConfig != nil {
+		l.Config = m.appConfig.LlamaCpp.LaunchOptions()
+		l.Model = m.appConfig.LlamaCpp.ModelOverrides(backend.ModelName)
+	}
+	if presets {
+		l.Preset = samplerPreset(backend.ModelName)
+	}
+	l.Request = backend.Options
+	l.Server = maps.Clone(backend.adjusted)
+	if backend.gpuLayers != nil {
+		if l.Server == nil {
+			l.Server = make(map[string]any)
+		}
+		l.Server["gpu-layers"] = *backend.gpuLayers
 	}
+	return options.Resolve(l)
+}
 
-	repoRef := parts[0]
-	quant := parts[1]
+// adjustOptions applies the power saver and memory guard to the options a
+// model was requested with, returning the options to load with and the
+// ones that were changed
+func (m *ModelManager) adjustOptions(modelName string, snap *memorySnapshot, requested map[string]any) (map[string]any, map[string]any, error) {
+	opts := m.applyPowerSaver(modelName, requested)
+	opts, err := m.checkMemory(modelName, snap, opts)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	repoParts := strings.Split(repoRef, "/")
-	if len(repoParts) != 2 {
-		return ""
+	var adjusted map[string]any
+	for key, val := range opts {
+		if old, ok := requested[key]; !ok || !optionValuesEqual(old, val) {
+			if adjusted == nil {
+				adjusted = make(map[string]any)
+			}
+			adjusted[key] = val
+		}
 	}
+	return opts, adjusted, nil
+}
 
-	user := repoParts[0]
-	repo := repoParts[1]
+// buildImageArgs builds the sd-server command line. Options come from the
+// stable_diffusion config section rather than llamacpp.
+func (m *ModelManager) buildImageArgs(backend *Backend) []string {
+	args := []string{
+		"--model", backend.ModelPath,
+		"--listen-ip", m.config.Host,
+		"--listen-port", fmt.Sprintf("%d", backend.Port),
+	}
+	if m.appConfig != nil {
+		args = append(args, buildLlamaServerArgs(m.appConfig.StableDiffusion.Options)...)
+	}
+	return args
+}
+
+// poolingArgs defaults embedding models to mean pooling unless the model
+// declares its own pooling type or the user configured one.
+func poolingArgs(params *hf.GGUFModelParams, options map[string]any) []string {
+	if params.PoolingType != hf.PoolingUnspecified {
+		return nil
+	}
+	if _, ok := options["pooling"]; ok {
+		return nil
+	}
+	return []string{"--pooling", "mean"}
+}
 
+// findMMProjForModel parses the model name and checks if an mmproj file exists.
+// ModelName format: "user/repo:quant" (e.g., "ggml-org/gemma-3-4b-it-GGUF:Q4_K_M")
+func findMMProjForModel(modelName string) string {
+	user, repo, quant, ok := parseModelRef(modelName)
+	if !ok {
+		return ""
+	}
 	return hf.FindMMProjFile(user, repo, quant)
 }
 
+// negatableFlags are boolean options whose llama-server default may be on, so
+// an explicit false is passed as --no-<flag> rather than omitted.
+var negatableFlags = map[string]bool{
+	"context-shift": true,
+}
+
 // buildLlamaServerArgs converts the llama_server config map to command-line arguments.
-func buildLlamaServerArgs(config map[string]any) []string {
-	if config == nil {
+func buildLlamaServerArgs(opts map[string]any) []string {
+	if opts == nil {
 		return nil
 	}
 
 	var args []string
-	for key, value := range config {
+	for key, value := range opts {
 		flag := "--" + key
 
 		switch v := value.(type) {
 		case bool:
 			if v {
 				args = append(args, flag)
+			} else if negatableFlags[key] {
+				args = append(args, "--no-"+key)
 			}
-			// false booleans are omitted (use default)
+			// other false booleans are omitted (use default)
 		case int:
 			args = append(args, flag, fmt.Sprintf("%d", v))
 		case float64:
@@ -476,27 +804,61 @@ func buildLlamaServerArgs(config map[string]any) []string {
 			if v != "" {
 				args = append(args, flag, v)
 			}
+		case []string, []any:
+			// Repeatable flags (e.g., override-kv) are passed once per value
+			if values, ok := config.StringList(v); ok {
+				for _, s := range values {
+					args = append(args, flag, s)
+				}
+			}
 		}
 	}
 
 	return args
 }
 
+// Health polling starts fast, since small models load in well under a
+// second, and backs off to a steady interval for large ones.
+const (
+	healthPollMin = 25 * time.Millisecond
+	healthPollMax = 500 * time.Millisecond
+)
+
 func (m *ModelManager) waitForReady(backend *Backend) error {
 	healthURL := fmt.Sprintf("http://%s:%d/health", m.config.Host, backend.Port)
-	client := &http.Client{Timeout: 2 * time.Second}
+	if backend.Kind == BackendImage {
+		// sd-server has no health route, and loads the model before it
+		// starts listening, so its index page answering is enough
+		healthURL = fmt.Sprintf("http://%s:%d/", m.config.Host, backend.Port)
+	}
+	client := backendClient(2 * time.Second)
 
 	logPath := logs.BackendLogPath(backend.ModelName)
-	deadline := time.Now().Add(m.config.StartupTimeout)
+	start := time.Now()
+	deadline := start.Add(m.config.StartupTimeout)
+	interval := healthPollMin
+	state := ""
 
 	for time.Now().Before(deadline) {
 		// Try health check
+		next := "starting"
 		resp, err := client.Get(healthURL)
 		if err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
+			ready := resp.StatusCode == http.StatusOK
+			if backend.Kind == BackendImage {
+				// Anything else may be another process that took the port
+				ready = resp.StatusCode >= 200 && resp.StatusCode < 300
+			}
+			if ready {
+				resp.Body.Close()
 				return nil
 			}
+			next = healthState(resp)
+			resp.Body.Close()
+		}
+		if next != state {
+			state = next
+			logs.Debug("Backend starting", "model", backend.ModelName, "state", state, "elapsed", time.Since(start).Round(time.Millisecond))
 		}
 
 		// Check log for errors
@@ -504,12 +866,45 @@ func (m *ModelManager) waitForReady(backend *Backend) error {
 			return fmt.Errorf("server startup failed (check %s)", logPath)
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		// A nil exited (mock backends) never fires
+		select {
+		case <-backend.exited:
+			return fmt.Errorf("server exited during startup (check %s)", logPath)
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, healthPollMax)
 	}
 
 	return fmt.Errorf("server did not become ready within %v", m.config.StartupTimeout)
 }
 
+// healthState describes a health response that isn't ready yet. While it
+// reads the weights, llama-server answers 503 with an error message such as
+// "Loading model", plus a progress fraction on builds that report one.
+func healthState(resp *http.Response) string {
+	var body struct {
+		Status string `json:"status"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Progress *float64 `json:"progress"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body)
+
+	state := body.Error.Message
+	if state == "" {
+		state = body.Status
+	}
+	if state == "" {
+		return fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	state = strings.ToLower(state)
+	if body.Progress != nil {
+		state += fmt.Sprintf(" (%.0f%%)", *body.Progress*100)
+	}
+	return state
+}
+
 func hasStartupError(logFile string) bool {
 	file, err := os.Open(logFile)
 	if err != nil {
@@ -554,6 +949,10 @@ func (m *ModelManager) getLRUModel() string {
 	return m.lruOrder[len(m.lruOrder)-1]
 }
 
+// ErrSDNotInstalled is returned when an image request arrives before
+// stable-diffusion.cpp has been installed.
+var ErrSDNotInstalled = errors.New("stable-diffusion.cpp is not installed, run 'lleme update stable-diffusion'")
+
 // AmbiguousModelError is returned when a query matches multiple models
 type AmbiguousModelError struct {
 	Query   string
@@ -592,9 +991,7 @@ func optionsChanged(current, new map[string]any) bool {
 	}
 
 	// Compare the options that matter for model loading
-	serverOptions := []string{"ctx-size", "gpu-layers", "threads", "batch-size", "ubatch-size", "flash-attn", "mlock", "cache-type-k", "cache-type-v"}
-
-	for _, key := range serverOptions {
+	for _, key := range config.ServerOptionKeys {
 		newVal, newExists := new[key]
 		curVal, curExists := current[key]
 
@@ -619,6 +1016,12 @@ func optionValuesEqual(a, b any) bool {
 		return aNum == bNum
 	}
 
+	// Repeatable options (e.g., override-kv) may be a string or a list
+	if aList, ok := config.StringList(a); ok {
+		bList, ok := config.StringList(b)
+		return ok && slices.Equal(aList, bList)
+	}
+
 	// Fall back to direct comparison for non-numeric types (strings, bools)
 	return a == b
 }
@@ -1,9 +1,23 @@
 package proxy
 
 import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/clock"
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/options"
 )
 
 func TestBuildLlamaServerArgs(t *testing.T) {
@@ -71,6 +85,13 @@ func TestBuildLlamaServerArgs(t *testing.T) {
 			},
 			expected: map[string]string{},
 		},
+		{
+			name: "negatable boolean false",
+			config: map[string]any{
+				"context-shift": false,
+			},
+			expected: map[string]string{"no-context-shift": ""},
+		},
 		{
 			name: "multiple options",
 			config: map[string]any{
@@ -98,6 +119,16 @@ func TestBuildLlamaServerArgs(t *testing.T) {
 	}
 }
 
+func TestBuildLlamaServerArgsRepeatedFlag(t *testing.T) {
+	args := buildLlamaServerArgs(map[string]any{
+		"override-kv": []any{"a=int:1", "b=bool:false"},
+	})
+	expected := []string{"--override-kv", "a=int:1", "--override-kv", "b=bool:false"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("buildLlamaServerArgs() = %v, want %v", args, expected)
+	}
+}
+
 // parseArgsToMap converts ["--flag", "value", "--bool"] to {"flag": "value", "bool": ""}
 func parseArgsToMap(args []string) map[string]string {
 	m := make(map[string]string)
@@ -191,6 +222,18 @@ func TestOptionsChanged(t *testing.T) {
 			new:      map[string]any{"ctx-size": 4096, "gpu-layers": 35},
 			expected: true,
 		},
+		{
+			name:     "override-kv changed",
+			current:  map[string]any{"override-kv": []any{"a=int:1"}},
+			new:      map[string]any{"override-kv": []any{"a=int:2"}},
+			expected: true,
+		},
+		{
+			name:     "rope scaling added",
+			current:  map[string]any{"ctx-size": 4096},
+			new:      map[string]any{"ctx-size": 4096, "rope-scaling": "yarn"},
+			expected: true,
+		},
 		{
 			name:     "non-server option ignored",
 			current:  map[string]any{"ctx-size": 4096},
@@ -224,6 +267,9 @@ func TestOptionValuesEqual(t *testing.T) {
 		{"string not equal", "test", "other", false},
 		{"bool equal", true, true, true},
 		{"bool not equal", true, false, false},
+		{"list equal", []any{"a=int:1"}, []string{"a=int:1"}, true},
+		{"list not equal", []any{"a=int:1"}, []any{"a=int:2"}, false},
+		{"string vs list", "a=int:1", []any{"a=int:1"}, true},
 	}
 
 	for _, tt := range tests {
@@ -263,3 +309,287 @@ func TestToFloat64(t *testing.T) {
 		})
 	}
 }
+
+func TestPoolingArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		poolingType int
+		options     map[string]any
+		want        []string
+	}{
+		{"defaults to mean", hf.PoolingUnspecified, nil, []string{"--pooling", "mean"}},
+		{"model declares pooling", 2, nil, nil},
+		{"user configured pooling", hf.PoolingUnspecified, map[string]any{"pooling": "cls"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &hf.GGUFModelParams{Architecture: "bert", PoolingType: tt.poolingType}
+			if got := poolingArgs(params, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("poolingArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOptions(t *testing.T) {
+	useTestHome(t)
+
+	cfg := config.DefaultConfig()
+	cfg.LlamaCpp.Options = map[string]any{"ctx-size": 4096, "threads": 4, "temp": 0.8}
+	cfg.LlamaCpp.ModelOptions = map[string]map[string]any{
+		"Qwen2.5-Coder-7B-Instruct-GGUF": {"threads": 8},
+	}
+	m := NewModelManager(DefaultConfig(), cfg)
+
+	layers := 20
+	backend := &Backend{
+		ModelName: "bartowski/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M",
+		Options:   map[string]any{"ctx_size": 16384, "gpu-layers": 99},
+		adjusted:  map[string]any{"ctx-size": 8192},
+		gpuLayers: &layers,
+	}
+
+	resolved := m.resolveOptions(backend, true)
+	want := map[string]struct {
+		value  any
+		source options.Source
+	}{
+		"ctx-size":   {8192, options.SourceServer},
+		"gpu-layers": {20, options.SourceServer},
+		"threads":    {8, options.SourceModel},
+		"temp":       {0.8, options.SourceConfig},
+		"top-k":      {float64(20), options.SourcePreset},
+	}
+	for key, w := range want {
+		if v, _ := resolved.Get(key); v.Value != w.value || v.Source != w.source {
+			t.Errorf("%s = %+v, want %v from %s", key, v, w.value, w.source)
+		}
+	}
+	if backend.adjusted["gpu-layers"] != nil {
+		t.Error("resolveOptions() changed the backend's adjusted options")
+	}
+
+	if _, ok := m.resolveOptions(backend, false).Get("top-k"); ok {
+		t.Error("sampler preset applied with presets off")
+	}
+}
+
+func TestBuildImageArgs(t *testing.T) {
+	appCfg := config.DefaultConfig()
+	appCfg.StableDiffusion.Options = map[string]any{"vae": "/models/ae.safetensors"}
+	m := NewModelManager(DefaultConfig(), appCfg)
+
+	backend := &Backend{Kind: BackendImage, ModelPath: "/models/sd.gguf", Port: 49153}
+	got := m.buildImageArgs(backend)
+	want := []string{
+		"--model", "/models/sd.gguf",
+		"--listen-ip", "127.0.0.1",
+		"--listen-port", "49153",
+		"--vae", "/models/ae.safetensors",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildImageArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/completions" {
+			requests.Add(1)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	tests := []struct {
+		name    string
+		enabled bool
+		kind    BackendKind
+		want    int32
+	}{
+		{"disabled", false, BackendLlama, 0},
+		{"llama", true, BackendLlama, 1},
+		{"image backends skip it", true, BackendImage, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			cfg := DefaultConfig()
+			cfg.Host = u.Hostname()
+			cfg.WarmUp = tt.enabled
+			m := NewModelManager(cfg, nil)
+
+			m.warmUp(&Backend{Kind: tt.kind, ModelName: "test", ModelPath: "/nonexistent.gguf", Port: port})
+			if got := requests.Load(); got != tt.want {
+				t.Errorf("warm-up requests = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	var polls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if polls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	cfg := DefaultConfig()
+	cfg.Host = u.Hostname()
+	cfg.StartupTimeout = 5 * time.Second
+	m := NewModelManager(cfg, nil)
+
+	start := time.Now()
+	if err := m.waitForReady(&Backend{Kind: BackendLlama, ModelName: "test", Port: port}); err != nil {
+		t.Fatalf("waitForReady() error = %v", err)
+	}
+	// Backoff starts well below the steady interval
+	if elapsed := time.Since(start); elapsed >= healthPollMax {
+		t.Errorf("ready after %v, want under %v", elapsed, healthPollMax)
+	}
+
+	// An exited process ends the wait without running out the timeout
+	exited := make(chan struct{})
+	close(exited)
+	srv.Close()
+	start = time.Now()
+	err := m.waitForReady(&Backend{Kind: BackendLlama, ModelName: "test", Port: port, exited: exited})
+	if err == nil || !strings.Contains(err.Error(), "exited") {
+		t.Errorf("waitForReady() error = %v, want exit error", err)
+	}
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("exit detected after %v", elapsed)
+	}
+}
+
+func TestWaitForReadyImage(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	var status atomic.Int32
+	status.Store(http.StatusNotFound)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	cfg := DefaultConfig()
+	cfg.Host = u.Hostname()
+	cfg.StartupTimeout = 300 * time.Millisecond
+	m := NewModelManager(cfg, nil)
+
+	// An error page isn't sd-server being ready
+	backend := &Backend{Kind: BackendImage, ModelName: "test", Port: port}
+	if err := m.waitForReady(backend); err == nil {
+		t.Error("waitForReady() should not accept a 404 from an image backend")
+	}
+
+	status.Store(http.StatusOK)
+	if err := m.waitForReady(backend); err != nil {
+		t.Errorf("waitForReady() error = %v", err)
+	}
+}
+
+func TestHealthState(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`, "loading model"},
+		{`{"error":{"message":"Loading model"},"progress":0.42}`, "loading model (42%)"},
+		{`{"status":"no slot available"}`, "no slot available"},
+		{`not json`, "HTTP 503"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(tt.body))}
+		if got := healthState(resp); got != tt.want {
+			t.Errorf("healthState(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestWatchExit(t *testing.T) {
+	m := NewModelManager(DefaultConfig(), nil)
+	events, cancel := m.Subscribe()
+	defer cancel()
+
+	port, err := m.portAllocator.Allocate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	backend := &Backend{
+		ModelName: "user/repo:Q4_K_M",
+		Port:      port,
+		Process:   &os.Process{Pid: 12345},
+		Status:    BackendReady,
+		ReadyChan: make(chan struct{}),
+		exited:    exited,
+	}
+	m.backends[backend.ModelName] = backend
+	m.lruOrder = []string{backend.ModelName}
+
+	done := make(chan struct{})
+	go func() {
+		m.watchExit(backend)
+		close(done)
+	}()
+	backend.exitErr = errors.New("signal: segmentation fault")
+	close(exited)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watchExit did not return after the process exited")
+	}
+
+	if got := backend.GetStatus(); got != BackendFailed {
+		t.Errorf("status = %v, want failed", got)
+	}
+	if m.GetBackend(backend.ModelName) != nil || m.LoadedCount() != 0 || len(m.lruOrder) != 0 {
+		t.Error("failed backend should be removed")
+	}
+	if m.portAllocator.IsAllocated(port) {
+		t.Error("port should be released")
+	}
+	if ev := <-events; ev.Type != EventUnloaded || ev.Model != backend.ModelName {
+		t.Errorf("event = %+v, want unloaded", ev)
+	}
+}
+
+func TestGetIdleBackendsUsesClock(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	m := NewModelManagerWithClock(DefaultConfig(), nil, clk)
+	backend := &Backend{ModelName: "user/repo:Q4_K_M", Status: BackendReady, clock: clk}
+	backend.UpdateActivity()
+	m.backends[backend.ModelName] = backend
+
+	clk.Advance(5 * time.Minute)
+	if idle := m.GetIdleBackends(10 * time.Minute); len(idle) != 0 {
+		t.Errorf("idle after 5m = %d backends, want 0", len(idle))
+	}
+	clk.Advance(6 * time.Minute)
+	if idle := m.GetIdleBackends(10 * time.Minute); len(idle) != 1 {
+		t.Errorf("idle after 11m = %d backends, want 1", len(idle))
+	}
+
+	backend.UpdateActivity()
+	if got := backend.IdleDuration(); got != 0 {
+		t.Errorf("IdleDuration() after activity = %v, want 0", got)
+	}
+}
@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/ui"
+)
+
+// Memory guard policies (server.memory_guard)
+const (
+	MemoryGuardRefuse = "refuse" // Refuse to load models that won't fit
+	MemoryGuardReduce = "reduce" // Shrink ctx-size to fit, refuse if that's not enough
+	MemoryGuardOff    = "off"    // Load without checking
+)
+
+const (
+	// defaultCtxSize is llama-server's context size when ctx-size isn't set
+	defaultCtxSize = 4096
+
+	// minReducedCtxSize is the smallest context the reduce policy will shrink to
+	minReducedCtxSize = 2048
+
+	// computeOverheadBytes approximates llama.cpp's compute buffers and runtime
+	computeOverheadBytes = 512 << 20
+)
+
+// cacheTypeBytes is the size in bytes of one KV cache element by cache type.
+// Block-quantized types include their per-block scale overhead.
+var cacheTypeBytes = map[string]float64{
+	"f32":    4,
+	"f16":    2,
+	"bf16":   2,
+	"q8_0":   34.0 / 32,
+	"q5_1":   24.0 / 32,
+	"q5_0":   22.0 / 32,
+	"q4_1":   20.0 / 32,
+	"q4_0":   18.0 / 32,
+	"iq4_nl": 18.0 / 32,
+}
+
+// availableMemory reports memory free for model loading. Overridden in tests.
+var availableMemory = systemAvailableMemory
+
+// MemoryEstimate is the estimated memory needed to run a model.
+type MemoryEstimate struct {
+	WeightsBytes    int64   // Model (and mmproj) file sizes
+	KVBytesPerToken float64 // KV cache cost of each token of context
+	CtxSize         int     // Context size the estimate was made for
+}
+
+// KVCacheBytes returns the KV cache size at the estimated context size.
+func (e *MemoryEstimate) KVCacheBytes() int64 {
+	return int64(e.KVBytesPerToken * float64(e.CtxSize))
+}
+
+// Total returns the total estimated memory in bytes.
+func (e *MemoryEstimate) Total() int64 {
+	return e.WeightsBytes + e.KVCacheBytes() + computeOverheadBytes
+}
+
+// MaxCtxSize returns the largest context size (a multiple of 256) that fits
+// within budget bytes, or 0 if the weights alone don't fit.
+func (e *MemoryEstimate) MaxCtxSize(budget int64) int {
+	free := budget - e.WeightsBytes - computeOverheadBytes
+	if free <= 0 || e.KVBytesPerToken <= 0 {
+		return 0
+	}
+	ctx := int(float64(free) / e.KVBytesPerToken)
+	return ctx / 256 * 256
+}
+
+// InsufficientMemoryError is returned when a model is estimated not to fit in free memory
+type InsufficientMemoryError struct {
+	Model     string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientMemoryError) Error() string {
+	return fmt.Sprintf("not enough memory to load '%s': needs about %s but only %s is available (try a smaller ctx-size or quantization)",
+		e.Model, ui.FormatBytes(e.Required), ui.FormatBytes(e.Available))
+}
+
+// memorySnapshot is what the memory check reads from outside the manager:
+// the model's metadata and file sizes, and the memory free. It's taken
+// before m.mu is locked, since finding free VRAM runs nvidia-smi or
+// system_profiler.
+type memorySnapshot struct {
+	params    *hf.GGUFModelParams
+	weights   int64 // Model and mmproj file sizes
+	available int64
+	err       error // Why the check is skipped, if it is
+}
+
+// takeMemorySnapshot reads what checkMemory needs for a model. It returns
+// nil when the memory guard is off.
+func (m *ModelManager) takeMemorySnapshot(modelName, modelPath string) *memorySnapshot {
+	if m.config.MemoryGuard == MemoryGuardOff {
+		return nil
+	}
+	snap := &memorySnapshot{}
+	snap.params, snap.weights, snap.err = readModelMemory(modelPath, findMMProjForModel(modelName))
+	if snap.err == nil {
+		snap.available, snap.err = availableMemory()
+	}
+	return snap
+}
+
+// estimate returns the model's memory estimate with options, or nil when
+// the snapshot couldn't be taken
+func (m *ModelManager) estimate(modelName string, snap *memorySnapshot, options map[string]any) *MemoryEstimate {
+	if snap == nil || snap.err != nil {
+		return nil
+	}
+	merged := m.resolveOptions(&Backend{ModelName: modelName, Options: options}, false).Map()
+	return newMemoryEstimate(snap.params, snap.weights, merged)
+}
+
+// checkMemory verifies a model fits in free memory before it's launched.
+// With the reduce policy it may return options with a smaller ctx-size.
+func (m *ModelManager) checkMemory(modelName string, snap *memorySnapshot, options map[string]any) (map[string]any, error) {
+	if m.config.MemoryGuard == MemoryGuardOff {
+		return options, nil
+	}
+	estimate := m.estimate(modelName, snap, options)
+	if estimate == nil {
+		if snap != nil {
+			logs.Debug("Skipping memory check", "model", modelName, "error", snap.err)
+		}
+		return options, nil
+	}
+
+	budget := snap.available - m.config.MinFreeMemory
+	if estimate.Total() <= budget {
+		return options, nil
+	}
+
+	if m.config.MemoryGuard == MemoryGuardReduce {
+		if ctx := estimate.MaxCtxSize(budget); ctx >= minReducedCtxSize {
+			logs.Warn("Reducing context size to fit in memory", "model", modelName, "from", estimate.CtxSize, "to", ctx)
+			reduced := make(map[string]any, len(options)+1)
+			maps.Copy(reduced, options)
+			reduced["ctx-size"] = ctx
+			return reduced, nil
+		}
+	}
+
+	return nil, &InsufficientMemoryError{
+		Model:     modelName,
+		Required:  estimate.Total(),
+		Available: max(budget, 0),
+	}
+}
+
+// fitInMemory adjusts a model's options like adjustOptions, and when it
+// still doesn't fit, unloads other models by the eviction policy until it
+// does, since max_models only counts models. A model that wouldn't fit with
+// every other one unloaded fails without unloading any. The caller holds
+// m.mu, which is released while each model stops and memory is measured
+// again.
+func (m *ModelManager) fitInMemory(modelName string, snap *memorySnapshot, requested map[string]any) (map[string]any, map[string]any, error) {
+	evicted := false
+	for {
+		opts, adjusted, err := m.adjustOptions(modelName, snap, requested)
+		var memErr *InsufficientMemoryError
+		if !errors.As(err, &memErr) {
+			return opts, adjusted, err
+		}
+		if !evicted {
+			freed := *snap
+			freed.available += m.evictableMemory()
+			if _, _, err := m.adjustOptions(modelName, &freed, requested); err != nil {
+				return nil, nil, memErr
+			}
+		}
+		victim := m.evictionCandidate()
+		if victim == "" {
+			return nil, nil, err
+		}
+		logs.Info("Evicting model to free memory", "model", victim, "for", modelName, "policy", m.config.Eviction)
+		if err := m.evict(victim); err != nil {
+			return nil, nil, err
+		}
+		evicted = true
+
+		m.mu.Unlock()
+		snap.available, snap.err = availableMemory()
+		m.mu.Lock()
+		// A removal or update may have started while the lock was released
+		if err := m.checkChanging(modelName); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// evictableMemory estimates what unloading every loaded model would free:
+// what each was estimated to use when loaded, or its file size when that
+// wasn't estimated. The caller holds m.mu.
+func (m *ModelManager) evictableMemory() int64 {
+	var total int64
+	for _, name := range m.lruOrder {
+		if b := m.backends[name]; b != nil && b.memory > 0 {
+			total += b.memory
+		} else {
+			total += m.backendSize(name)
+		}
+	}
+	return total
+}
+
+// readModelMemory reads a model's metadata and the size of its files,
+// with the mmproj's.
+func readModelMemory(modelPath, mmprojPath string) (*hf.GGUFModelParams, int64, error) {
+	params, err := hf.ReadGGUFModelParams(modelPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read model metadata: %w", err)
+	}
+
+	weights := modelFilesSize(modelPath)
+	if mmprojPath != "" {
+		if info, err := os.Stat(mmprojPath); err == nil {
+			weights += info.Size()
+		}
+	}
+	return params, weights, nil
+}
+
+// newMemoryEstimate builds an estimate from model metadata and load options.
+func newMemoryEstimate(params *hf.GGUFModelParams, weightsBytes int64, options map[string]any) *MemoryEstimate {
+	kEmb, vEmb := params.KVEmbeddingSizes()
+	kBytes := cacheTypeSize(options["cache-type-k"])
+	vBytes := cacheTypeSize(options["cache-type-v"])
+
+	return &MemoryEstimate{
+		WeightsBytes:    weightsBytes,
+		KVBytesPerToken: float64(params.BlockCount) * (float64(kEmb)*kBytes + float64(vEmb)*vBytes),
+		CtxSize:         resolveCtxSize(options["ctx-size"], params.ContextLength),
+	}
+}
+
+// resolveCtxSize returns the context size llama-server will use.
+// Unset means llama-server's default; 0 means the model's training context.
+func resolveCtxSize(val any, modelCtx int) int {
+	if val == nil {
+		if modelCtx > 0 && modelCtx < defaultCtxSize {
+			return modelCtx
+		}
+		return defaultCtxSize
+	}
+	if n, ok := toFloat64(val); ok && n > 0 {
+		return int(n)
+	}
+	if modelCtx > 0 {
+		return modelCtx
+	}
+	return defaultCtxSize
+}
+
+func cacheTypeSize(val any) float64 {
+	if s, ok := val.(string); ok {
+		if size, ok := cacheTypeBytes[strings.ToLower(s)]; ok {
+			return size
+		}
+	}
+	return cacheTypeBytes["f16"]
+}
+
+// modelFilesSize returns the size of a model file, summing all parts of split models.
+func modelFilesSize(modelPath string) int64 {
+	split := hf.ParseSplitFilename(modelPath)
+	if split == nil {
+		if info, err := os.Stat(modelPath); err == nil {
+			return info.Size()
+		}
+		return 0
+	}
+
+	var total int64
+	for i := range split.SplitCount {
+		if info, err := os.Stat(hf.SplitPath(split.Prefix, i, split.SplitCount)); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// systemAvailableMemory returns free system memory plus free VRAM on GPUs that
+// have their own. Unified memory GPUs are already counted in system memory.
+func systemAvailableMemory() (int64, error) {
+	available, err := hw.AvailableMemory()
+	if err != nil {
+		return 0, err
+	}
+	info := hw.Info{GPUs: hw.DetectGPUs()}
+	return available + info.DedicatedVRAMFree(), nil
+}
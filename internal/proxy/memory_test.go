@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+// llama3B mirrors Llama 3.2 3B: 28 layers, 3072 embd, 24 heads, 8 KV heads.
+var llama3B = &hf.GGUFModelParams{
+	Architecture:    "llama",
+	BlockCount:      28,
+	ContextLength:   131072,
+	EmbeddingLength: 3072,
+	HeadCount:       24,
+	HeadCountKV:     8,
+}
+
+func TestNewMemoryEstimate(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     map[string]any
+		wantCtx     int
+		wantPerTokB float64
+	}{
+		{
+			name:        "default context f16",
+			options:     map[string]any{},
+			wantCtx:     4096,
+			wantPerTokB: 28 * (1024*2 + 1024*2),
+		},
+		{
+			name:        "explicit context",
+			options:     map[string]any{"ctx-size": float64(8192)},
+			wantCtx:     8192,
+			wantPerTokB: 28 * (1024*2 + 1024*2),
+		},
+		{
+			name:        "zero context uses model length",
+			options:     map[string]any{"ctx-size": 0},
+			wantCtx:     131072,
+			wantPerTokB: 28 * (1024*2 + 1024*2),
+		},
+		{
+			name:        "quantized cache",
+			options:     map[string]any{"cache-type-k": "q8_0", "cache-type-v": "Q4_0"},
+			wantCtx:     4096,
+			wantPerTokB: 28 * (1024*34.0/32 + 1024*18.0/32),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			est := newMemoryEstimate(llama3B, 2<<30, tt.options)
+			if est.CtxSize != tt.wantCtx {
+				t.Errorf("CtxSize = %d, want %d", est.CtxSize, tt.wantCtx)
+			}
+			if est.KVBytesPerToken != tt.wantPerTokB {
+				t.Errorf("KVBytesPerToken = %g, want %g", est.KVBytesPerToken, tt.wantPerTokB)
+			}
+			want := int64(2<<30) + int64(tt.wantPerTokB*float64(tt.wantCtx)) + computeOverheadBytes
+			if est.Total() != want {
+				t.Errorf("Total() = %d, want %d", est.Total(), want)
+			}
+		})
+	}
+}
+
+func TestMemoryEstimateMaxCtxSize(t *testing.T) {
+	est := &MemoryEstimate{WeightsBytes: 1 << 30, KVBytesPerToken: 1024, CtxSize: 4096}
+
+	if got := est.MaxCtxSize(1 << 30); got != 0 {
+		t.Errorf("MaxCtxSize() with no room = %d, want 0", got)
+	}
+
+	budget := int64(1<<30) + computeOverheadBytes + 1024*10000
+	if got := est.MaxCtxSize(budget); got != 9984 {
+		t.Errorf("MaxCtxSize() = %d, want 9984", got)
+	}
+}
+
+func TestCheckMemory(t *testing.T) {
+	origAvailable := availableMemory
+	defer func() { availableMemory = origAvailable }()
+
+	modelPath := writeTestModel(t, llama3B, 64<<20)
+
+	tests := []struct {
+		name      string
+		guard     string
+		available int64
+		options   map[string]any
+		wantErr   bool
+		wantCtx   any
+	}{
+		{"fits", MemoryGuardRefuse, 8 << 30, nil, false, nil},
+		{"refused", MemoryGuardRefuse, 1 << 30, map[string]any{"ctx-size": 131072}, true, nil},
+		{"off skips check", MemoryGuardOff, 0, map[string]any{"ctx-size": 131072}, false, 131072},
+		{"reduced", MemoryGuardReduce, 2 << 30, map[string]any{"ctx-size": 131072}, false, 13312},
+		{"too small to reduce", MemoryGuardReduce, 600 << 20, map[string]any{"ctx-size": 131072}, true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			availableMemory = func() (int64, error) { return tt.available, nil }
+
+			cfg := DefaultConfig()
+			cfg.MemoryGuard = tt.guard
+			cfg.MinFreeMemory = 0
+			m := NewModelManager(cfg, nil)
+
+			snap := m.takeMemorySnapshot("user/repo:Q4_K_M", modelPath)
+			opts, err := m.checkMemory("user/repo:Q4_K_M", snap, tt.options)
+			if tt.wantErr {
+				var memErr *InsufficientMemoryError
+				if !errors.As(err, &memErr) {
+					t.Fatalf("checkMemory() error = %v, want InsufficientMemoryError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("checkMemory() error = %v", err)
+			}
+			if tt.wantCtx != nil && opts["ctx-size"] != tt.wantCtx {
+				t.Errorf("ctx-size = %v, want %v", opts["ctx-size"], tt.wantCtx)
+			}
+		})
+	}
+}
+
+func TestFitInMemoryEvicts(t *testing.T) {
+	useTestHome(t)
+	origAvailable := availableMemory
+	defer func() { availableMemory = origAvailable }()
+
+	modelPath := writeTestModel(t, llama3B, 64<<20)
+	opts := map[string]any{"ctx-size": 32768}
+
+	cfg := DefaultConfig()
+	cfg.MemoryGuard = MemoryGuardRefuse
+	cfg.MinFreeMemory = 0
+	m := NewModelManager(cfg, nil)
+	for _, name := range []string{"user/old:Q4_K_M", "user/recent:Q4_K_M"} {
+		m.backends[name] = &Backend{ModelName: name, Status: BackendReady, ReadyChan: make(chan struct{}), memory: 4 << 30}
+		m.lruOrder = append([]string{name}, m.lruOrder...)
+	}
+
+	// Each loaded model holds 4 GiB of the 9 GiB there is; the new one needs
+	// a little over 4 GiB at this context
+	availableMemory = func() (int64, error) { return 9<<30 - int64(len(m.backends))*4<<30, nil }
+
+	snap := m.takeMemorySnapshot("user/repo:Q4_K_M", modelPath)
+	m.mu.Lock()
+	_, _, err := m.fitInMemory("user/repo:Q4_K_M", snap, opts)
+	m.mu.Unlock()
+	if err != nil {
+		t.Fatalf("fitInMemory() error = %v", err)
+	}
+	if _, ok := m.backends["user/old:Q4_K_M"]; ok {
+		t.Error("least recently used model wasn't evicted")
+	}
+	if _, ok := m.backends["user/recent:Q4_K_M"]; !ok {
+		t.Error("evicted more models than needed")
+	}
+
+	// Too big even with everything unloaded: fail without unloading anything
+	availableMemory = func() (int64, error) { return 0, nil }
+	snap = m.takeMemorySnapshot("user/repo:Q4_K_M", modelPath)
+	m.mu.Lock()
+	_, _, err = m.fitInMemory("user/repo:Q4_K_M", snap, opts)
+	m.mu.Unlock()
+	var memErr *InsufficientMemoryError
+	if !errors.As(err, &memErr) {
+		t.Errorf("fitInMemory() too big to fit error = %v, want InsufficientMemoryError", err)
+	}
+	if _, ok := m.backends["user/recent:Q4_K_M"]; !ok {
+		t.Error("evicted a model for one that couldn't fit anyway")
+	}
+}
+
+func TestCheckMemoryUnknownAvailable(t *testing.T) {
+	origAvailable := availableMemory
+	defer func() { availableMemory = origAvailable }()
+	availableMemory = func() (int64, error) { return 0, errors.New("unsupported") }
+
+	m := NewModelManager(DefaultConfig(), nil)
+	modelPath := writeTestModel(t, llama3B, 1024)
+	if _, err := m.checkMemory("user/repo:Q4_K_M", m.takeMemorySnapshot("user/repo:Q4_K_M", modelPath), nil); err != nil {
+		t.Errorf("checkMemory() should skip when memory is unknown, got %v", err)
+	}
+}
+
+// writeTestModel writes a minimal GGUF file with llama architecture metadata,
+// padded to size bytes.
+func writeTestModel(t *testing.T, params *hf.GGUFModelParams, size int64) string {
+	t.Helper()
+
+	numbers := []struct {
+		key   string
+		value int
+	}{
+		{"llama.block_count", params.BlockCount},
+		{"llama.context_length", params.ContextLength},
+		{"llama.embedding_length", params.EmbeddingLength},
+		{"llama.attention.head_count", params.HeadCount},
+		{"llama.attention.head_count_kv", params.HeadCountKV},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("GGUF")
+	binary.Write(&buf, binary.LittleEndian, uint32(3))
+	binary.Write(&buf, binary.LittleEndian, uint64(0))
+	binary.Write(&buf, binary.LittleEndian, uint64(len(numbers)+1))
+
+	writeKVKey(&buf, "general.architecture")
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	binary.Write(&buf, binary.LittleEndian, uint64(len(params.Architecture)))
+	buf.WriteString(params.Architecture)
+
+	for _, n := range numbers {
+		writeKVKey(&buf, n.key)
+		binary.Write(&buf, binary.LittleEndian, uint32(4)) // u32
+		binary.Write(&buf, binary.LittleEndian, uint32(n.value))
+	}
+
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write test GGUF: %v", err)
+	}
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("Failed to pad test GGUF: %v", err)
+	}
+	return path
+}
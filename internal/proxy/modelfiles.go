@@ -0,0 +1,212 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/peer"
+)
+
+// handleManageModel routes /api/models/{name}: DELETE removes a downloaded
+// model like `lleme remove`, and POST /api/models/{name}/update pulls its
+// latest version like re-running `lleme pull`.
+func (s *Server) handleManageModel(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/models/")
+	if name, ok := strings.CutSuffix(name, "/update"); ok {
+		s.handleUpdateModel(w, r, name)
+		return
+	}
+	s.handleRemoveModel(w, r, name)
+}
+
+// handleRemoveModel deletes every downloaded quant the name matches
+func (s *Server) handleRemoveModel(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodDelete {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only DELETE is allowed")
+		return
+	}
+
+	models, release, ok := s.downloadedForChange(w, r, name, changeRemove)
+	if !ok {
+		return
+	}
+	defer release()
+
+	var removed []string
+	var err error
+	for _, m := range models {
+		if err = hf.RemoveModel(m.User, m.Repo, m.Quant); err != nil {
+			err = fmt.Errorf("failed to remove %s: %w", m.FullName, err)
+			break
+		}
+		removed = append(removed, m.FullName)
+	}
+	if len(removed) > 0 {
+		if err := peer.RebuildPeerFileIndex(); err != nil {
+			logs.Warn("Failed to update peer index", "error", err)
+		}
+	}
+	s.audit(r, "remove", map[string]any{"model": name, "removed": removed}, err)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, map[string]any{
+		"success": true,
+		"models":  removed,
+	})
+}
+
+// handleUpdateModel pulls the latest version of every downloaded quant the
+// name matches, streaming progress like /api/pull. Quants that are already
+// up to date finish without downloading anything.
+func (s *Server) handleUpdateModel(w http.ResponseWriter, r *http.Request, name string) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
+		return
+	}
+
+	models, release, ok := s.downloadedForChange(w, r, name, changeUpdate)
+	if !ok {
+		return
+	}
+	defer release()
+
+	stream := newPullStream(w)
+	if err := s.manager.waitForPower(r.Context(), stream.deferred); err != nil {
+		return
+	}
+	for _, m := range models {
+		_, err := s.manager.PullModel(m.FullName, stream.progress)
+		s.audit(r, "update", map[string]any{"model": m.FullName}, err)
+		stream.finish(m.FullName, err)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// downloadedForChange returns the downloaded quants a remove or update
+// names: one for user/repo:quant, all of them for user/repo. Names aren't
+// matched fuzzily as they are for completions, so a typo can't delete the
+// wrong model. Quants the request's client may not use are refused, as are
+// ones whose files are in use; the rest are reserved for the change until
+// release is called. On failure the error response has been written.
+func (s *Server) downloadedForChange(w http.ResponseWriter, r *http.Request, name, change string) (models []DownloadedModel, release func(), ok bool) {
+	if name == "" {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Model name is required")
+		return nil, nil, false
+	}
+
+	downloaded, err := s.manager.Resolver().ListDownloadedModels()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return nil, nil, false
+	}
+	for _, d := range downloaded {
+		if strings.EqualFold(d.FullName, name) || strings.EqualFold(d.User+"/"+d.Repo, name) {
+			models = append(models, d)
+		}
+	}
+	if len(models) == 0 {
+		s.writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("No downloaded model named '%s'", name))
+		return nil, nil, false
+	}
+
+	client := s.matchClient(r)
+	for _, m := range models {
+		if !clientAllowsModel(client, m.FullName) {
+			s.handleModelError(w, &ModelNotAllowedError{Client: client.Name, Model: m.FullName})
+			return nil, nil, false
+		}
+	}
+
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = m.FullName
+	}
+	release, err = s.manager.reserveForChange(names, change)
+	if err != nil {
+		s.handleModelError(w, err)
+		return nil, nil, false
+	}
+	return models, release, true
+}
+
+// Changes to a model's files that keep it from being used meanwhile
+const (
+	changeRemove = "removed"
+	changeUpdate = "updated"
+)
+
+// ModelBusyError is returned when a model's files are in use: it can't be
+// removed or updated while loaded or downloading, or loaded while being
+// removed or updated
+type ModelBusyError struct {
+	Model  string
+	Reason string // Why, following the model's name, like "is loaded; stop it first"
+}
+
+func (e *ModelBusyError) Error() string {
+	return fmt.Sprintf("model '%s' %s", e.Model, e.Reason)
+}
+
+// reserveForChange marks models whose files are about to change, so they
+// aren't loaded until release is called, nor pulled while being removed.
+// Models that are loaded or starting, downloading, or already changing are
+// refused. Checking and marking under m.mu keeps a load from slipping in
+// between.
+func (m *ModelManager) reserveForChange(names []string, change string) (release func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range names {
+		// Starting backends count too: they're reading the files
+		if _, ok := m.backends[name]; ok {
+			return nil, &ModelBusyError{Model: name, Reason: "is loaded; stop it first"}
+		}
+		if m.pulls.running(name) {
+			return nil, &ModelBusyError{Model: name, Reason: "is being downloaded"}
+		}
+		if err := m.checkChanging(name); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.changing == nil {
+		m.changing = make(map[string]string)
+	}
+	for _, name := range names {
+		m.changing[name] = change
+	}
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, name := range names {
+			delete(m.changing, name)
+		}
+	}, nil
+}
+
+// checkChanging refuses a model whose files are being removed or updated.
+// Callers hold m.mu.
+func (m *ModelManager) checkChanging(name string) error {
+	if change, ok := m.changing[name]; ok {
+		return &ModelBusyError{Model: name, Reason: "is being " + change}
+	}
+	return nil
+}
+
+// checkRemoving refuses to pull a model that's being removed
+func (m *ModelManager) checkRemoving(name string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.changing[name] == changeRemove {
+		return &ModelBusyError{Model: name, Reason: "is being removed"}
+	}
+	return nil
+}
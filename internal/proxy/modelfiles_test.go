@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestHandleRemoveModel(t *testing.T) {
+	useTestHome(t)
+	for _, quant := range []string{"Q4_K_M", "Q8_0"} {
+		writeDownloadedModel(t, "user", "repo", quant)
+	}
+	writeDownloadedModel(t, "user", "other", "Q4_K_M")
+
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), nil)}
+	s.manager.backends["user/other:Q4_K_M"] = &Backend{ModelName: "user/other:Q4_K_M", Status: BackendStarting}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{"wrong method", http.MethodPost, "/api/models/user/repo:Q4_K_M", http.StatusMethodNotAllowed},
+		{"no name", http.MethodDelete, "/api/models/", http.StatusBadRequest},
+		{"not fuzzy", http.MethodDelete, "/api/models/repo", http.StatusNotFound},
+		{"loaded", http.MethodDelete, "/api/models/user/other", http.StatusConflict},
+		{"one quant", http.MethodDelete, "/api/models/user/repo:q8_0", http.StatusOK},
+		{"gone", http.MethodDelete, "/api/models/user/repo:Q8_0", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleManageModel(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+
+	if hf.FindModelFile("user", "repo", "Q4_K_M") == "" {
+		t.Error("removing one quant deleted another")
+	}
+	if hf.FindModelFile("user", "other", "Q4_K_M") == "" {
+		t.Error("a loaded model was deleted")
+	}
+
+	// user/repo removes every quant
+	w := httptest.NewRecorder()
+	s.handleManageModel(w, httptest.NewRequest(http.MethodDelete, "/api/models/user/repo", nil))
+	var resp struct {
+		Models []string `json:"models"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Models) != 1 || resp.Models[0] != "user/repo:Q4_K_M" {
+		t.Errorf("removed = %v, want [user/repo:Q4_K_M]", resp.Models)
+	}
+}
+
+func TestHandleUpdateModelRefusesBusyModels(t *testing.T) {
+	useTestHome(t)
+	writeDownloadedModel(t, "user", "repo", "Q4_K_M")
+
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), nil)}
+
+	w := httptest.NewRecorder()
+	s.handleManageModel(w, httptest.NewRequest(http.MethodGet, "/api/models/user/repo/update", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+
+	s.manager.pulls.jobs = map[string]*pullJob{"user/repo:Q4_K_M": {done: make(chan struct{})}}
+	w = httptest.NewRecorder()
+	s.handleManageModel(w, httptest.NewRequest(http.MethodPost, "/api/models/user/repo/update", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("downloading status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func writeDownloadedModel(t *testing.T, user, repo, quant string) {
+	t.Helper()
+	if err := os.MkdirAll(hf.GetModelPath(user, repo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(hf.GetModelFilePath(user, repo, quant), []byte("GGUF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReserveForChange(t *testing.T) {
+	useTestHome(t)
+	writeDownloadedModel(t, "user", "repo", "Q4_K_M")
+	m := NewModelManager(DefaultConfig(), nil)
+	const name = "user/repo:Q4_K_M"
+
+	release, err := m.reserveForChange([]string{name}, changeRemove)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var busy *ModelBusyError
+	if _, err := m.GetOrLoadBackend(name, nil); !errors.As(err, &busy) {
+		t.Errorf("load while removing: error = %v, want ModelBusyError", err)
+	}
+	if err := m.checkRemoving(name); !errors.As(err, &busy) {
+		t.Errorf("pull while removing: error = %v, want ModelBusyError", err)
+	}
+	if _, err := m.reserveForChange([]string{name}, changeUpdate); !errors.As(err, &busy) {
+		t.Errorf("second change: error = %v, want ModelBusyError", err)
+	}
+	if len(m.backends) != 0 {
+		t.Error("a backend was started for a model being removed")
+	}
+
+	release()
+	release, err = m.reserveForChange([]string{name}, changeUpdate)
+	if err != nil {
+		t.Fatalf("reserve after release: %v", err)
+	}
+	defer release()
+	// An update pulls the model itself, so pulls go ahead
+	if err := m.checkRemoving(name); err != nil {
+		t.Errorf("pull while updating: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+// modelDetails is GGUF-derived metadata shown in /v1/models
+type modelDetails struct {
+	Parameters    int64
+	ContextLength int
+	Capabilities  []string
+	SizeBytes     int64
+}
+
+type cachedDetails struct {
+	modTime time.Time
+	details modelDetails
+}
+
+// detailsCache avoids re-parsing every GGUF on each /v1/models call. Entries
+// are keyed by path and refreshed when the file's modification time changes.
+var (
+	detailsMu    sync.Mutex
+	detailsCache = make(map[string]cachedDetails)
+)
+
+// readModelDetails returns metadata for a downloaded model. Fields that
+// can't be read are left zero, since the listing should never fail on them.
+func readModelDetails(m DownloadedModel) modelDetails {
+	info, err := os.Stat(m.ModelPath)
+	if err != nil {
+		return modelDetails{}
+	}
+
+	detailsMu.Lock()
+	cached, ok := detailsCache[m.ModelPath]
+	detailsMu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) {
+		return cached.details
+	}
+
+	d := modelDetails{SizeBytes: modelFilesSize(m.ModelPath)}
+	if params, err := hf.ReadGGUFModelParams(m.ModelPath); err == nil {
+		d.ContextLength = params.ContextLength
+		if params.IsEmbedding() {
+			d.Capabilities = append(d.Capabilities, "embedding")
+		}
+	}
+	if n, err := hf.ReadGGUFParameterCount(m.ModelPath); err == nil {
+		d.Parameters = n
+	}
+	if hf.FindMMProjFile(m.User, m.Repo, m.Quant) != "" {
+		d.Capabilities = append(d.Capabilities, "vision")
+	}
+
+	detailsMu.Lock()
+	detailsCache[m.ModelPath] = cachedDetails{modTime: info.ModTime(), details: d}
+	detailsMu.Unlock()
+	return d
+}
+
+// apply copies the details into a model's lleme extension object
+func (d modelDetails) apply(status *LlemeStatus, quant string) {
+	status.Quant = quant
+	status.Parameters = d.Parameters
+	status.ContextLength = d.ContextLength
+	status.Capabilities = d.Capabilities
+	status.SizeBytes = d.SizeBytes
+}
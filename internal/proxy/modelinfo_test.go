@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestReadModelDetails(t *testing.T) {
+	useTestHome(t)
+
+	path := writeTestModel(t, llama3B, 4096)
+	m := DownloadedModel{User: "user", Repo: "repo", Quant: "Q4_K_M", FullName: "user/repo:Q4_K_M", ModelPath: path}
+
+	d := readModelDetails(m)
+	if d.ContextLength != llama3B.ContextLength || d.SizeBytes != 4096 || d.Capabilities != nil {
+		t.Errorf("details = %+v", d)
+	}
+
+	// A vision projector shows up once the model file changes
+	mmproj := hf.GetMMProjFilePath(m.User, m.Repo, m.Quant)
+	if err := os.MkdirAll(filepath.Dir(mmproj), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mmproj, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if d := readModelDetails(m); d.Capabilities != nil {
+		t.Errorf("cached details should be reused, got %v", d.Capabilities)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if d := readModelDetails(m); !reflect.DeepEqual(d.Capabilities, []string{"vision"}) {
+		t.Errorf("Capabilities = %v, want [vision]", d.Capabilities)
+	}
+
+	var status LlemeStatus
+	d.apply(&status, m.Quant)
+	if status.Quant != "Q4_K_M" || status.SizeBytes != 4096 {
+		t.Errorf("status = %+v", status)
+	}
+}
@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// maxChoices caps n and best_of, since each choice is a full generation
+const maxChoices = 16
+
+// choiceRequest is how many completions a request asks for. llama-server
+// only generates one per request, so the proxy runs generate of them and
+// returns the best n.
+type choiceRequest struct {
+	n        int
+	generate int // best_of, or n when best_of isn't set
+}
+
+// parseChoiceRequest reads n and best_of. best_of is only part of the
+// completions API, and neither works with streaming.
+func parseChoiceRequest(path string, body []byte) (choiceRequest, *requestError) {
+	var req struct {
+		N      *int `json:"n"`
+		BestOf *int `json:"best_of"`
+		Stream bool `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return choiceRequest{}, invalidValue("n", "Invalid type for 'n' or 'best_of': expected an integer")
+	}
+
+	c := choiceRequest{n: 1}
+	if req.N != nil {
+		c.n = *req.N
+	}
+	c.generate = c.n
+	if req.BestOf != nil && path == "/v1/completions" {
+		c.generate = *req.BestOf
+	}
+
+	switch {
+	case c.n < 1 || c.n > maxChoices:
+		return c, invalidValue("n", "Invalid value for 'n': must be between 1 and %d", maxChoices)
+	case c.generate < c.n || c.generate > maxChoices:
+		return c, invalidValue("best_of", "Invalid value for 'best_of': must be between n and %d", maxChoices)
+	case c.generate > 1 && req.Stream:
+		return c, &requestError{param: "n", code: "unsupported_value", message: "n and best_of above 1 are not supported with stream"}
+	}
+	return c, nil
+}
+
+// choiceResult is one backend generation
+type choiceResult struct {
+	status int
+	body   []byte
+	resp   map[string]any
+}
+
+// generateChoices runs c.generate copies of the request against backendURL
+// with distinct seeds and merges them into one response with c.n choices.
+// With best_of, choices are ranked by total token log probability, which
+// the backend is asked to report. logprobs is what the client asked for,
+// or nil.
+func (s *Server) generateChoices(w http.ResponseWriter, r *http.Request, backendURL, path string, body []byte, c choiceRequest, logprobs *logprobsRequest) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return
+	}
+	delete(req, "n")
+	delete(req, "best_of")
+
+	seed := rand.Int64N(1 << 31)
+	if v, ok := req["seed"].(float64); ok {
+		seed = int64(v)
+	}
+	wantLogprobs := logprobs != nil
+	if c.generate > c.n && !wantLogprobs {
+		// Ranking needs each token's log probability, in completions format
+		req["logprobs"] = 1
+		req["n_probs"] = 1
+		logprobs = &logprobsRequest{}
+	}
+
+	results := make([]choiceResult, c.generate)
+	var wg sync.WaitGroup
+	for i := range c.generate {
+		one := make(map[string]any, len(req)+1)
+		for k, v := range req {
+			one[k] = v
+		}
+		one["seed"] = seed + int64(i)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = postChoice(r.Context(), backendURL+path, r.Header.Get(requestIDHeader), one)
+		}()
+	}
+	wg.Wait()
+
+	// Any failure fails the request, with the backend's error
+	for _, res := range results {
+		if res.status != http.StatusOK {
+			if res.status == 0 {
+				s.writeError(w, http.StatusBadGateway, "backend_error", "Backend server error: "+string(res.body))
+				return
+			}
+			resp := &http.Response{
+				StatusCode: res.status,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(bytes.NewReader(res.body)),
+				Request:    r,
+			}
+			mapBackendError(resp)
+			data, _ := io.ReadAll(resp.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+			w.Write(data)
+			return
+		}
+	}
+
+	if logprobs != nil {
+		for _, res := range results {
+			logprobs.convertLogprobs(res.resp)
+		}
+	}
+
+	merged := mergeChoices(results, c.n, c.generate > c.n, !wantLogprobs)
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, merged)
+}
+
+func postChoice(ctx context.Context, url, requestID string, req map[string]any) choiceResult {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return choiceResult{body: []byte(err.Error())}
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return choiceResult{body: []byte(err.Error())}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		httpReq.Header.Set(requestIDHeader, requestID)
+	}
+
+	resp, err := backendClient(0).Do(httpReq)
+	if err != nil {
+		return choiceResult{body: []byte(err.Error())}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return choiceResult{body: []byte(err.Error())}
+	}
+
+	res := choiceResult{status: resp.StatusCode, body: body}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, &res.resp); err != nil {
+			logs.Debug("Unparseable backend response", "error", err)
+			return choiceResult{body: []byte("unparseable backend response")}
+		}
+	}
+	return res
+}
+
+// mergeChoices combines single-choice responses into one. The first
+// response supplies the id and other top-level fields. Usage counts the
+// prompt once and every generated token.
+func mergeChoices(results []choiceResult, n int, rank, dropLogprobs bool) map[string]any {
+	var choices []map[string]any
+	var promptTokens, completionTokens float64
+	for _, res := range results {
+		list, _ := res.resp["choices"].([]any)
+		if len(list) > 0 {
+			if choice, ok := list[0].(map[string]any); ok {
+				choices = append(choices, choice)
+			}
+		}
+		if usage, ok := res.resp["usage"].(map[string]any); ok {
+			promptTokens, _ = usage["prompt_tokens"].(float64)
+			ct, _ := usage["completion_tokens"].(float64)
+			completionTokens += ct
+		}
+	}
+
+	if rank {
+		slices.SortStableFunc(choices, func(a, b map[string]any) int {
+			sa, sb := choiceLogprob(a), choiceLogprob(b)
+			switch {
+			case sa > sb:
+				return -1
+			case sa < sb:
+				return 1
+			}
+			return 0
+		})
+	}
+	choices = choices[:min(n, len(choices))]
+	for i, choice := range choices {
+		choice["index"] = i
+		if dropLogprobs {
+			delete(choice, "logprobs")
+		}
+	}
+
+	merged := results[0].resp
+	merged["choices"] = choices
+	if _, ok := merged["usage"]; ok {
+		merged["usage"] = map[string]any{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      promptTokens + completionTokens,
+		}
+	}
+	return merged
+}
+
+// choiceLogprob sums a completion choice's token log probabilities. Choices
+// without them rank last.
+func choiceLogprob(choice map[string]any) float64 {
+	lp, _ := choice["logprobs"].(map[string]any)
+	var sum float64
+	switch tokens := lp["token_logprobs"].(type) {
+	case []float64: // Converted by convertLogprobs
+		for _, v := range tokens {
+			sum += v
+		}
+	case []any:
+		for _, t := range tokens {
+			v, _ := t.(float64)
+			sum += v
+		}
+	default:
+		return -1e308
+	}
+	return sum
+}
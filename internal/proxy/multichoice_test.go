@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestParseChoiceRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		body      string
+		want      choiceRequest
+		wantParam string
+	}{
+		{"default", "/v1/chat/completions", `{}`, choiceRequest{1, 1}, ""},
+		{"n", "/v1/chat/completions", `{"n":3}`, choiceRequest{3, 3}, ""},
+		{"best_of", "/v1/completions", `{"n":2,"best_of":4}`, choiceRequest{2, 4}, ""},
+		{"best_of ignored for chat", "/v1/chat/completions", `{"best_of":4}`, choiceRequest{1, 1}, ""},
+		{"best_of below n", "/v1/completions", `{"n":3,"best_of":2}`, choiceRequest{}, "best_of"},
+		{"too many", "/v1/chat/completions", `{"n":100}`, choiceRequest{}, "n"},
+		{"streaming", "/v1/chat/completions", `{"n":2,"stream":true}`, choiceRequest{}, "n"},
+		{"streaming one", "/v1/chat/completions", `{"n":1,"stream":true}`, choiceRequest{1, 1}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChoiceRequest(tt.path, []byte(tt.body))
+			if tt.wantParam != "" {
+				if err == nil || err.param != tt.wantParam {
+					t.Fatalf("error = %v, want one on %s", err, tt.wantParam)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// choiceBackend answers each request with its seed as the content and a
+// log probability that grows with the seed
+func choiceBackend(seen *[]map[string]any) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		*seen = append(*seen, req)
+		mu.Unlock()
+
+		seed := req["seed"].(float64)
+		writeJSON(w, map[string]any{
+			"id":     "cmpl-1",
+			"object": "text_completion",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"text":          fmt.Sprint(seed),
+				"finish_reason": "stop",
+				"logprobs": map[string]any{"content": []any{
+					map[string]any{"token": fmt.Sprint(seed), "logprob": -10 + seed/100, "top_logprobs": []any{}},
+				}},
+			}},
+			"usage": map[string]any{"prompt_tokens": 5, "completion_tokens": 2, "total_tokens": 7},
+		})
+	}))
+}
+
+func TestGenerateChoices(t *testing.T) {
+	var seen []map[string]any
+	backend := choiceBackend(&seen)
+	defer backend.Close()
+
+	s := &Server{config: DefaultConfig()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/completions", nil)
+	w := httptest.NewRecorder()
+	s.generateChoices(w, req, backend.URL, "/v1/completions", []byte(`{"prompt":"hi","seed":100,"n":2,"best_of":3}`), choiceRequest{2, 3}, nil)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		ID      string
+		Choices []map[string]any
+		Usage   map[string]float64
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three generations with consecutive seeds, none asking for more than one
+	var seeds []float64
+	for _, r := range seen {
+		seeds = append(seeds, r["seed"].(float64))
+		if _, ok := r["n"]; ok {
+			t.Error("n should not reach the backend")
+		}
+		if r["logprobs"] != float64(1) || r["n_probs"] != float64(1) {
+			t.Error("best_of should request logprobs for ranking")
+		}
+	}
+	sort.Float64s(seeds)
+	if fmt.Sprint(seeds) != "[100 101 102]" {
+		t.Errorf("seeds = %v", seeds)
+	}
+
+	// The two most likely, re-indexed, without the logprobs nobody asked for
+	if len(resp.Choices) != 2 || resp.Choices[0]["text"] != "102" || resp.Choices[1]["text"] != "101" {
+		t.Fatalf("choices = %v", resp.Choices)
+	}
+	for i, c := range resp.Choices {
+		if c["index"] != float64(i) {
+			t.Errorf("choice %d index = %v", i, c["index"])
+		}
+		if _, ok := c["logprobs"]; ok {
+			t.Errorf("choice %d should not include logprobs", i)
+		}
+	}
+	if resp.ID != "cmpl-1" || resp.Usage["prompt_tokens"] != 5 || resp.Usage["completion_tokens"] != 6 || resp.Usage["total_tokens"] != 11 {
+		t.Errorf("id = %s, usage = %v", resp.ID, resp.Usage)
+	}
+}
+
+func TestGenerateChoicesBackendError(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":400,"message":"too long","type":"exceed_context_size_error"}}`))
+	}))
+	defer backend.Close()
+
+	s := &Server{config: DefaultConfig()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(""))
+	w := httptest.NewRecorder()
+	s.generateChoices(w, req, backend.URL, "/v1/chat/completions", []byte(`{"messages":[],"n":2}`), choiceRequest{2, 2}, nil)
+
+	var resp OpenAIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Error.Code != "context_length_exceeded" {
+		t.Errorf("status = %d, error = %+v", w.Code, resp.Error)
+	}
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// maxModelPeek bounds how much of a request body is read to find its model
+// before the rest is streamed to the backend untouched.
+const maxModelPeek = 64 << 10
+
+var errInvalidBody = errors.New("invalid request body")
+
+// peekRequestModel returns the top-level "model" of r's JSON body and
+// leaves r.Body ready to be proxied. Requests that name their model within
+// the first maxModelPeek bytes, as clients do, are never held in memory
+// whole; others are read in full, as before.
+func peekRequestModel(r *http.Request) (string, error) {
+	body := r.Body
+	model, peeked, found := peekModel(body)
+	if found {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(peeked), body), body}
+		return model, nil
+	}
+
+	rest, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return "", err
+	}
+	full := append(peeked, rest...)
+	var req struct {
+		Model string `json:"model"`
+	}
+	if err := json.Unmarshal(full, &req); err != nil {
+		return "", errInvalidBody
+	}
+	r.Body = io.NopCloser(bytes.NewReader(full))
+	r.ContentLength = int64(len(full))
+	return req.Model, nil
+}
+
+// peekModel scans body's top-level object for "model", reading at most
+// maxModelPeek bytes. It returns everything it read, which belongs in
+// front of the rest of body. found is false when the model comes later or
+// the JSON is malformed.
+func peekModel(body io.Reader) (model string, peeked []byte, found bool) {
+	var buf bytes.Buffer
+	dec := json.NewDecoder(io.TeeReader(io.LimitReader(body, maxModelPeek), &buf))
+
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", buf.Bytes(), false
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", buf.Bytes(), false
+		}
+		if key == "model" {
+			if err := dec.Decode(&model); err != nil {
+				return "", buf.Bytes(), false
+			}
+			return model, buf.Bytes(), true
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return "", buf.Bytes(), false
+		}
+	}
+	// No model in a complete object
+	if _, err := dec.Token(); err != nil {
+		return "", buf.Bytes(), false
+	}
+	return "", buf.Bytes(), true
+}
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/mock"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+func TestPeekRequestModel(t *testing.T) {
+	large := strings.Repeat("word ", maxModelPeek/4)
+	tests := []struct {
+		name      string
+		body      string
+		wantModel string
+		wantErr   bool
+	}{
+		{"model first", `{"model":"tiny","input":"hi"}`, "tiny", false},
+		{"model after small field", `{"input":"hi","model":"tiny"}`, "tiny", false},
+		{"no model", `{"input":"hi"}`, "", false},
+		{"model after large field", `{"input":"` + large + `","model":"tiny"}`, "tiny", false},
+		{"model before large field", `{"model":"tiny","input":"` + large + `"}`, "tiny", false},
+		{"model not a string", `{"model":1}`, "", true},
+		{"not an object", `["tiny"]`, "", true},
+		{"invalid", `{"model":`, "", true},
+		{"empty", ``, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(tt.body))
+			model, err := peekRequestModel(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("peekRequestModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if model != tt.wantModel {
+				t.Errorf("model = %q, want %q", model, tt.wantModel)
+			}
+			got, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("proxied body differs from the original (%d bytes, want %d)", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestPeekModelReadsLimitedPrefix(t *testing.T) {
+	body := `{"model":"tiny","input":"` + strings.Repeat("x", 4*maxModelPeek) + `"}`
+	_, peeked, found := peekModel(strings.NewReader(body))
+	if !found {
+		t.Fatal("model not found")
+	}
+	if len(peeked) > maxModelPeek {
+		t.Errorf("read %d bytes, want at most %d", len(peeked), maxModelPeek)
+	}
+}
+
+// setupMockModel puts a model named tiny in the store and serves it with the
+// mock backend.
+func setupMockModel(tb testing.TB) *Server {
+	tb.Helper()
+	tb.Setenv("LLEME_HOME", tb.TempDir())
+	tb.Setenv(mock.EnvVar, "1")
+
+	dir := filepath.Join(paths.Models(), "test", "tiny-GGUF")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Q4_K_M.gguf"), []byte("fake"), 0644); err != nil {
+		tb.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.StartupTimeout = 5 * time.Second
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil)}
+	tb.Cleanup(func() { s.manager.StopAllBackends() })
+	return s
+}
+
+func TestEmbeddingsStreamedToBackend(t *testing.T) {
+	s := setupMockModel(t)
+
+	for _, body := range []string{
+		`{"model":"tiny","input":"hello"}`,
+		`{"input":"hello","model":"tiny"}`,
+		`{"input":"` + strings.Repeat("hello ", maxModelPeek/4) + `","model":"tiny"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		s.handleEmbeddings(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Data []struct {
+				Embedding []float64 `json:"embedding"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Data) != 1 || len(resp.Data[0].Embedding) == 0 {
+			t.Errorf("unexpected response %s", w.Body.String())
+		}
+	}
+}
+
+func BenchmarkProxyStream(b *testing.B) {
+	s := setupMockModel(b)
+	srv := httptest.NewServer(http.HandlerFunc(s.handleChatCompletions))
+	defer srv.Close()
+
+	prompt := strings.Repeat("token ", 500)
+	body := fmt.Sprintf(`{"model":"tiny","stream":true,"messages":[{"role":"user","content":%q}]}`, prompt)
+	client := &http.Client{Transport: &http.Transport{MaxIdleConnsPerHost: 4}}
+
+	post := func() int64 {
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer resp.Body.Close()
+		n, err := io.Copy(io.Discard, resp.Body)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return n
+	}
+	b.SetBytes(post()) // Also loads the backend
+
+	b.ReportAllocs()
+	for b.Loop() {
+		post()
+	}
+}
+
+func BenchmarkProxyLargeRequest(b *testing.B) {
+	s := setupMockModel(b)
+	srv := httptest.NewServer(http.HandlerFunc(s.handleEmbeddings))
+	defer srv.Close()
+
+	body := []byte(`{"model":"tiny","input":"` + strings.Repeat("hello ", 1<<20) + `"}`)
+	post := func() {
+		resp, err := http.Post(srv.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	post()
+
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		post()
+	}
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// personaHeader names a saved persona to apply to a chat request
+const personaHeader = "X-LLeme-Persona"
+
+// personaSamplingFields maps persona option keys to the chat request fields
+// llama-server reads them from
+var personaSamplingFields = map[string]string{
+	"temp":              "temperature",
+	"top-p":             "top_p",
+	"top-k":             "top_k",
+	"min-p":             "min_p",
+	"repeat-penalty":    "repeat_penalty",
+	"presence-penalty":  "presence_penalty",
+	"frequency-penalty": "frequency_penalty",
+	"seed":              "seed",
+}
+
+// applyPersona fills in a chat request from the persona named in its
+// X-LLeme-Persona header: the persona's model when the request has none, its
+// system prompt when the request has no system message, and its sampling
+// options for fields the request leaves unset. Whatever the client sent wins.
+func applyPersona(name string, body []byte) ([]byte, *requestError) {
+	if name == "" {
+		return body, nil
+	}
+	if err := config.ValidatePersonaName(name); err != nil {
+		return nil, &requestError{code: "invalid_persona", message: err.Error()}
+	}
+	persona, err := config.LoadPersona(name)
+	if err != nil {
+		return nil, &requestError{code: "persona_not_found", message: fmt.Sprintf("Persona '%s' does not exist", name)}
+	}
+
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, &requestError{message: "Failed to parse request body"}
+	}
+
+	if model, _ := req["model"].(string); model == "" && persona.Model != "" {
+		req["model"] = persona.Model
+	}
+
+	if persona.System != "" {
+		messages, _ := req["messages"].([]any)
+		if !hasSystemMessage(messages) {
+			system := map[string]any{"role": "system", "content": persona.System}
+			req["messages"] = append([]any{system}, messages...)
+		}
+	}
+
+	for key, field := range personaSamplingFields {
+		val, ok := persona.Options[key]
+		if !ok || req[field] != nil {
+			continue
+		}
+		req[field] = val
+	}
+
+	out, err := json.Marshal(req)
+	if err != nil {
+		return nil, &requestError{message: "Failed to apply persona: " + err.Error()}
+	}
+	return out, nil
+}
+
+func hasSystemMessage(messages []any) bool {
+	for _, m := range messages {
+		msg, _ := m.(map[string]any)
+		if role, _ := msg["role"].(string); role == "system" || role == "developer" {
+			return true
+		}
+	}
+	return false
+}
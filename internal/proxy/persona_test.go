@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestApplyPersona(t *testing.T) {
+	useTestHome(t)
+	err := config.SavePersona("reviewer", &config.Persona{
+		Model:   "org/reviewer-GGUF",
+		System:  "You review code.",
+		Options: map[string]any{"temp": 0.2, "top-k": 20, "ctx-size": 8192},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		persona   string
+		body      string
+		wantModel string
+		wantFirst string // content of the first message
+		wantTemp  any
+		wantCode  string
+	}{
+		{
+			name:      "no header",
+			body:      `{"model":"m","messages":[{"role":"user","content":"hi"}]}`,
+			wantModel: "m",
+			wantFirst: "hi",
+		},
+		{
+			name:      "fills model, system prompt and sampling",
+			persona:   "reviewer",
+			body:      `{"messages":[{"role":"user","content":"hi"}]}`,
+			wantModel: "org/reviewer-GGUF",
+			wantFirst: "You review code.",
+			wantTemp:  0.2,
+		},
+		{
+			name:      "client values win",
+			persona:   "reviewer",
+			body:      `{"model":"m","temperature":1,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"hi"}]}`,
+			wantModel: "m",
+			wantFirst: "Be brief.",
+			wantTemp:  1.0,
+		},
+		{
+			name:     "unknown persona",
+			persona:  "nobody",
+			body:     `{"model":"m","messages":[]}`,
+			wantCode: "persona_not_found",
+		},
+		{
+			name:     "invalid name",
+			persona:  "../config",
+			body:     `{"model":"m","messages":[]}`,
+			wantCode: "invalid_persona",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, rerr := applyPersona(tt.persona, []byte(tt.body))
+			if tt.wantCode != "" {
+				if rerr == nil || rerr.code != tt.wantCode {
+					t.Fatalf("applyPersona() error = %v, want code %s", rerr, tt.wantCode)
+				}
+				return
+			}
+			if rerr != nil {
+				t.Fatalf("applyPersona() error = %v", rerr)
+			}
+
+			var req struct {
+				Model       string `json:"model"`
+				Temperature any    `json:"temperature"`
+				TopK        any    `json:"top_k"`
+				CtxSize     any    `json:"ctx-size"`
+				Messages    []struct {
+					Content string `json:"content"`
+				} `json:"messages"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Fatal(err)
+			}
+			if req.Model != tt.wantModel {
+				t.Errorf("model = %q, want %q", req.Model, tt.wantModel)
+			}
+			if len(req.Messages) == 0 || req.Messages[0].Content != tt.wantFirst {
+				t.Errorf("messages = %+v, want first %q", req.Messages, tt.wantFirst)
+			}
+			if req.Temperature != tt.wantTemp {
+				t.Errorf("temperature = %v, want %v", req.Temperature, tt.wantTemp)
+			}
+			if tt.persona != "" && req.TopK != 20.0 {
+				t.Errorf("top_k = %v, want 20", req.TopK)
+			}
+			if req.CtxSize != nil {
+				t.Error("load-time option was copied into the request")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"context"
+	"maps"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// powerCheckInterval is how long a power reading is reused, since taking one
+// runs pmset three times
+const powerCheckInterval = 30 * time.Second
+
+// detectPower reads the host's power state. Overridden in tests.
+var detectPower = hw.DetectPower
+
+// powerMonitor caches the host's power state. The zero value is ready to use.
+type powerMonitor struct {
+	mu    sync.Mutex
+	state hw.PowerState
+	at    time.Time
+}
+
+// current returns the power state, reading it again when the cached one is
+// older than powerCheckInterval at now
+func (p *powerMonitor) current(now time.Time) hw.PowerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.at.IsZero() || now.Sub(p.at) > powerCheckInterval {
+		p.state, p.at = detectPower(), now
+	}
+	return p.state
+}
+
+// powerConfig returns the power section of the app config
+func (m *ModelManager) powerConfig() config.Power {
+	if m.appConfig == nil {
+		return config.Power{}
+	}
+	return m.appConfig.Power
+}
+
+// powerSaving reports whether power.saver applies right now, and why
+func (m *ModelManager) powerSaving() (bool, string) {
+	switch m.powerConfig().Saver {
+	case config.PowerSaverAlways:
+		return true, "power.saver is always"
+	case config.PowerSaverAuto:
+		state := m.power.current(m.clock.Now())
+		return state.Constrained(), state.Reason()
+	default:
+		return false, ""
+	}
+}
+
+// applyPowerSaver lowers threads, and gpu-layers when configured, for a
+// model loaded while saving power. Options the request set itself are kept.
+func (m *ModelManager) applyPowerSaver(modelName string, options map[string]any) map[string]any {
+	saving, reason := m.powerSaving()
+	if !saving {
+		return options
+	}
+
+	cfg := m.powerConfig()
+	saved := maps.Clone(options)
+	if saved == nil {
+		saved = make(map[string]any)
+	}
+	if _, ok := saved["threads"]; !ok {
+		threads := cfg.Threads
+		if threads <= 0 {
+			threads = max(runtime.NumCPU()/2, 1)
+		}
+		saved["threads"] = threads
+	}
+	if _, ok := saved["gpu-layers"]; !ok && cfg.GPULayers != nil {
+		saved["gpu-layers"] = *cfg.GPULayers
+	}
+
+	logs.Info("Saving power while loading model", "model", modelName, "reason", reason,
+		"threads", saved["threads"], "gpu_layers", saved["gpu-layers"])
+	return saved
+}
+
+// PowerStatus returns the power saver's state for /api/status, or nil when
+// it's off
+func (m *ModelManager) PowerStatus() *PowerStatus {
+	cfg := m.powerConfig()
+	if cfg.Saver != config.PowerSaverAuto && cfg.Saver != config.PowerSaverAlways {
+		return nil
+	}
+	saving, reason := m.powerSaving()
+	return &PowerStatus{Saver: cfg.Saver, Saving: saving, Reason: reason}
+}
+
+// waitForPower holds a download while power.defer_pulls is set and the auto
+// saver is saving power, calling deferred once with the reason when it has
+// to wait. It returns early with ctx's error if ctx ends first.
+func (m *ModelManager) waitForPower(ctx context.Context, deferred func(reason string)) error {
+	if cfg := m.powerConfig(); !cfg.DeferPulls || cfg.Saver != config.PowerSaverAuto {
+		return nil
+	}
+	told := false
+	for {
+		saving, reason := m.powerSaving()
+		if !saving {
+			return nil
+		}
+		if !told {
+			deferred(reason)
+			told = true
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-m.clock.After(powerCheckInterval):
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hw"
+)
+
+// usePowerState makes detectPower report state for the test's duration
+func usePowerState(t *testing.T, state hw.PowerState) {
+	t.Helper()
+	orig := detectPower
+	detectPower = func() hw.PowerState { return state }
+	t.Cleanup(func() { detectPower = orig })
+}
+
+func newPowerManager(power config.Power) *ModelManager {
+	appCfg := config.DefaultConfig()
+	appCfg.Power = power
+	return NewModelManager(DefaultConfig(), appCfg)
+}
+
+func TestApplyPowerSaver(t *testing.T) {
+	layers := 10
+	halfCores := max(runtime.NumCPU()/2, 1)
+
+	tests := []struct {
+		name    string
+		power   config.Power
+		state   hw.PowerState
+		options map[string]any
+		want    map[string]any
+	}{
+		{
+			name:  "off leaves options alone",
+			power: config.Power{Saver: config.PowerSaverOff},
+			state: hw.PowerState{OnBattery: true},
+			want:  nil,
+		},
+		{
+			name:  "auto on AC power leaves options alone",
+			power: config.Power{Saver: config.PowerSaverAuto},
+			want:  nil,
+		},
+		{
+			name:  "auto on battery halves threads",
+			power: config.Power{Saver: config.PowerSaverAuto},
+			state: hw.PowerState{OnBattery: true},
+			want:  map[string]any{"threads": halfCores},
+		},
+		{
+			name:    "configured threads and gpu layers under thermal pressure",
+			power:   config.Power{Saver: config.PowerSaverAuto, Threads: 2, GPULayers: &layers},
+			state:   hw.PowerState{ThermalPressure: true},
+			options: map[string]any{"ctx-size": 8192},
+			want:    map[string]any{"ctx-size": 8192, "threads": 2, "gpu-layers": 10},
+		},
+		{
+			name:    "request options win",
+			power:   config.Power{Saver: config.PowerSaverAlways, Threads: 2, GPULayers: &layers},
+			options: map[string]any{"threads": 12, "gpu-layers": 99},
+			want:    map[string]any{"threads": 12, "gpu-layers": 99},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePowerState(t, tt.state)
+			m := newPowerManager(tt.power)
+			got := m.applyPowerSaver("test/model", tt.options)
+			if len(got) != len(tt.want) {
+				t.Fatalf("options = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("options[%s] = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPowerSaverKeepsCallerMap(t *testing.T) {
+	usePowerState(t, hw.PowerState{LowPowerMode: true})
+	m := newPowerManager(config.Power{Saver: config.PowerSaverAuto})
+	options := map[string]any{"ctx-size": 4096}
+	m.applyPowerSaver("test/model", options)
+	if _, ok := options["threads"]; ok {
+		t.Error("applyPowerSaver modified the caller's options")
+	}
+}
+
+func TestPowerStatus(t *testing.T) {
+	usePowerState(t, hw.PowerState{OnBattery: true, LowPowerMode: true})
+
+	if s := newPowerManager(config.Power{}).PowerStatus(); s != nil {
+		t.Errorf("status with the saver off = %+v, want nil", s)
+	}
+	s := newPowerManager(config.Power{Saver: config.PowerSaverAuto}).PowerStatus()
+	if s == nil || !s.Saving || s.Reason != "low power mode, on battery" {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestPowerMonitorCaches(t *testing.T) {
+	calls := 0
+	orig := detectPower
+	detectPower = func() hw.PowerState { calls++; return hw.PowerState{} }
+	defer func() { detectPower = orig }()
+
+	now := time.Now()
+	var p powerMonitor
+	p.current(now)
+	p.current(now.Add(powerCheckInterval / 2))
+	if calls != 1 {
+		t.Errorf("detected %d times, want 1 within the check interval", calls)
+	}
+	p.current(now.Add(2 * powerCheckInterval))
+	if calls != 2 {
+		t.Errorf("detected %d times, want 2 after the interval", calls)
+	}
+}
+
+func TestWaitForPower(t *testing.T) {
+	usePowerState(t, hw.PowerState{OnBattery: true})
+
+	// Without defer_pulls, or without the auto saver, pulls go ahead
+	for _, power := range []config.Power{
+		{Saver: config.PowerSaverAuto},
+		{Saver: config.PowerSaverAlways, DeferPulls: true},
+	} {
+		m := newPowerManager(power)
+		if err := m.waitForPower(context.Background(), func(string) { t.Error("pull deferred") }); err != nil {
+			t.Errorf("waitForPower(%+v) = %v", power, err)
+		}
+	}
+
+	m := newPowerManager(config.Power{Saver: config.PowerSaverAuto, DeferPulls: true})
+	ctx, cancel := context.WithCancel(context.Background())
+	var reasons []string
+	done := make(chan error, 1)
+	go func() {
+		done <- m.waitForPower(ctx, func(reason string) {
+			reasons = append(reasons, reason)
+			cancel()
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("waitForPower() = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitForPower did not return when the client went away")
+	}
+	if len(reasons) != 1 || reasons[0] != "on battery" {
+		t.Errorf("deferred reasons = %v", reasons)
+	}
+}
@@ -0,0 +1,270 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/peer"
+)
+
+// PullModel downloads a Hugging Face model referenced as "user/repo" or
+// "user/repo:quant" and returns its full name. Without a quant, the
+// configured default or the best available quantization is used.
+func (m *ModelManager) PullModel(ref string, progress func(hf.PullProgress)) (string, error) {
+	user, repo, ok := splitModelName(ref)
+	if !ok || user == "" || repo == "" {
+		return "", fmt.Errorf("invalid model reference %q, expected user/repo[:quant]", ref)
+	}
+	_, quant, _ := strings.Cut(ref, ":")
+
+	cfg := m.appConfig
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	source := hf.NewSource(cfg)
+
+	quants, err := source.ListQuantizations(user, repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to list files: %w", err)
+	}
+	if len(quants) == 0 {
+		return "", fmt.Errorf("no GGUF files found in %s/%s", user, repo)
+	}
+
+	if quant == "" {
+		quant = hf.GetBestQuantization(quants)
+		if q, ok := hf.FindQuantization(quants, cfg.HuggingFace.DefaultQuant); ok {
+			quant = q.Name
+		}
+	}
+	selected, ok := hf.FindQuantization(quants, quant)
+	if !ok {
+		return "", fmt.Errorf("quantization %s not found in %s/%s", quant, user, repo)
+	}
+	modelName := hf.FormatModelName(user, repo, selected.Name)
+
+	err = m.pulls.do(modelName, progress, func(progress func(hf.PullProgress)) error {
+		// The pull is already registered as running, so a removal that
+		// starts after this check sees it and is refused
+		if err := m.checkRemoving(modelName); err != nil {
+			return err
+		}
+		return m.pullSelected(source, cfg, user, repo, selected, progress)
+	})
+	if err != nil {
+		return "", err
+	}
+	return modelName, nil
+}
+
+// pullSelected downloads one quant unless it's already up to date
+func (m *ModelManager) pullSelected(source hf.ModelSource, cfg *config.Config, user, repo string, selected hf.Quantization, progress func(hf.PullProgress)) error {
+	modelName := hf.FormatModelName(user, repo, selected.Name)
+
+	upToDate, saveManifest, manifest, manifestJSON, err := hf.CheckForUpdates(source, user, repo, selected)
+	if err != nil {
+		return err
+	}
+	if upToDate {
+		if saveManifest {
+			if err := os.WriteFile(hf.GetManifestFilePath(user, repo, selected.Name), manifestJSON, 0644); err != nil {
+				return fmt.Errorf("failed to save manifest: %w", err)
+			}
+		}
+		return nil
+	}
+
+	opts := &hf.PullOptions{
+		Manifest:     manifest,
+		ManifestJSON: manifestJSON,
+	}
+	if cfg.Peer.Enabled {
+		opts.PeerDownload = peer.CreateDownloader()
+	}
+
+	if _, err := hf.PullModel(source, user, repo, selected, opts, progress); err != nil {
+		return err
+	}
+
+	if err := peer.RebuildPeerFileIndex(); err != nil {
+		logs.Warn("Failed to update peer index", "error", err)
+	}
+	logs.Info("Model pulled", "model", modelName)
+	return nil
+}
+
+// handlePull downloads a model on the server, streaming progress as
+// newline-delimited JSON so remote clients can add models without a shell on
+// the host. The model is listed and loadable as soon as the stream ends with
+// phase "done".
+func (s *Server) handlePull(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
+		return
+	}
+
+	var req PullRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+		return
+	}
+	if req.Model == "" {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Model field is required")
+		return
+	}
+	if user, repo, ok := splitModelName(req.Model); !ok || user == "" || repo == "" {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", "Model must be user/repo or user/repo:quant")
+		return
+	}
+	if client := s.matchClient(r); !clientAllowsModel(client, req.Model) {
+		s.handleModelError(w, &ModelNotAllowedError{Client: client.Name, Model: req.Model})
+		return
+	}
+
+	stream := newPullStream(w)
+	if err := s.manager.waitForPower(r.Context(), stream.deferred); err != nil {
+		return
+	}
+	modelName, err := s.manager.PullModel(req.Model, stream.progress)
+	s.audit(r, "pull", map[string]any{"model": req.Model}, err)
+	stream.finish(modelName, err)
+}
+
+// pullStream writes pull progress to an HTTP response as newline-delimited
+// PullEvents, throttled like the gRPC PullModel stream.
+type pullStream struct {
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	lastPhase string
+	lastSent  time.Time
+}
+
+func newPullStream(w http.ResponseWriter) *pullStream {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &pullStream{w: w, flusher: flusher}
+}
+
+func (p *pullStream) send(e PullEvent) {
+	// Keep downloading even if the client went away
+	writeJSON(p.w, e)
+	if p.flusher != nil {
+		p.flusher.Flush()
+	}
+}
+
+// deferred tells the client the pull waits until the laptop is back on power
+func (p *pullStream) deferred(reason string) {
+	p.send(PullEvent{Phase: "deferred", Reason: reason})
+}
+
+func (p *pullStream) progress(pp hf.PullProgress) {
+	done := pp.Total > 0 && pp.Current >= pp.Total
+	if pp.Phase == p.lastPhase && !done && time.Since(p.lastSent) < pullProgressInterval {
+		return
+	}
+	p.lastPhase, p.lastSent = pp.Phase, time.Now()
+	p.send(PullEvent{Phase: pp.Phase, Completed: pp.Current, Total: pp.Total})
+}
+
+// finish ends a model's pull with a done or error event
+func (p *pullStream) finish(modelName string, err error) {
+	if err != nil {
+		p.send(PullEvent{Phase: "error", Model: modelName, Error: err.Error()})
+		return
+	}
+	p.send(PullEvent{Phase: "done", Model: modelName})
+}
+
+// pullGroup coalesces concurrent pulls of the same model, so a second
+// request waits on the first download instead of writing the same files.
+// The zero value is ready to use.
+type pullGroup struct {
+	mu   sync.Mutex
+	jobs map[string]*pullJob
+}
+
+// pullJob is an in-flight pull and everyone waiting on it
+type pullJob struct {
+	done chan struct{}
+	err  error
+
+	mu   sync.Mutex
+	subs []func(hf.PullProgress)
+	last *hf.PullProgress // Latest progress, replayed to late joiners
+}
+
+// do runs pull for key, or, if a pull for key is already running, waits for
+// it. Either way progress receives the pull's progress and its error is
+// returned.
+func (g *pullGroup) do(key string, progress func(hf.PullProgress), pull func(progress func(hf.PullProgress)) error) error {
+	g.mu.Lock()
+	if job, ok := g.jobs[key]; ok {
+		last := job.subscribe(progress)
+		g.mu.Unlock()
+		if last != nil {
+			progress(*last)
+		}
+		logs.Debug("Joining pull in progress", "model", key)
+		<-job.done
+		return job.err
+	}
+	job := &pullJob{done: make(chan struct{})}
+	job.subscribe(progress)
+	if g.jobs == nil {
+		g.jobs = make(map[string]*pullJob)
+	}
+	g.jobs[key] = job
+	g.mu.Unlock()
+
+	job.err = pull(job.broadcast)
+
+	g.mu.Lock()
+	delete(g.jobs, key)
+	g.mu.Unlock()
+	close(job.done)
+	return job.err
+}
+
+// running reports whether a pull for key is in progress
+func (g *pullGroup) running(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.jobs[key]
+	return ok
+}
+
+// subscribe adds progress to the job's subscribers, returning the latest
+// progress for the caller to replay once it's let go of its locks
+func (j *pullJob) subscribe(progress func(hf.PullProgress)) *hf.PullProgress {
+	if progress == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.subs = append(j.subs, progress)
+	return j.last
+}
+
+// broadcast sends p to every subscriber. They're called without j.mu held,
+// so one slow client doesn't hold up the others joining.
+func (j *pullJob) broadcast(p hf.PullProgress) {
+	j.mu.Lock()
+	j.last = &p
+	subs := slices.Clone(j.subs)
+	j.mu.Unlock()
+	for _, sub := range subs {
+		sub(p)
+	}
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
+)
+
+func TestPullGroupCoalesces(t *testing.T) {
+	var g pullGroup
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var runs atomic.Int32
+	wantErr := errors.New("download failed")
+
+	pull := func(progress func(hf.PullProgress)) error {
+		runs.Add(1)
+		progress(hf.PullProgress{Phase: "download", Current: 50, Total: 100})
+		close(started)
+		<-release
+		progress(hf.PullProgress{Phase: "download", Current: 100, Total: 100})
+		return wantErr
+	}
+
+	var mu sync.Mutex
+	seen := map[string][]int64{}
+	recorder := func(name string) func(hf.PullProgress) {
+		return func(p hf.PullProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[name] = append(seen[name], p.Current)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs[0] = g.do("user/repo:Q4_K_M", recorder("first"), pull)
+	}()
+	<-started
+
+	joined := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(joined)
+		errs[1] = g.do("user/repo:Q4_K_M", recorder("second"), func(func(hf.PullProgress)) error {
+			t.Error("second pull of the same model ran its own download")
+			return nil
+		})
+	}()
+	<-joined
+	// Wait until the second caller has subscribed and seen the replayed progress
+	for {
+		mu.Lock()
+		n := len(seen["second"])
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if runs.Load() != 1 {
+		t.Errorf("pull ran %d times, want 1", runs.Load())
+	}
+	for i, err := range errs {
+		if !errors.Is(err, wantErr) {
+			t.Errorf("caller %d error = %v, want %v", i, err, wantErr)
+		}
+	}
+	if got := seen["first"]; len(got) != 2 || got[1] != 100 {
+		t.Errorf("first caller progress = %v, want [50 100]", got)
+	}
+	if got := seen["second"]; len(got) != 2 || got[0] != 50 || got[1] != 100 {
+		t.Errorf("second caller progress = %v, want replayed 50 then 100", got)
+	}
+
+	// Once finished, the next pull runs again
+	if err := g.do("user/repo:Q4_K_M", nil, func(func(hf.PullProgress)) error { return nil }); err != nil {
+		t.Errorf("later pull error = %v", err)
+	}
+}
+
+func TestPullGroupSlowSubscriber(t *testing.T) {
+	var g pullGroup
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+
+	pull := func(progress func(hf.PullProgress)) error {
+		progress(hf.PullProgress{Phase: "download", Current: 50, Total: 100})
+		return nil
+	}
+	// The first caller's client stops reading mid-pull
+	slow := func(hf.PullProgress) {
+		close(blocked)
+		<-release
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- g.do("user/repo:Q4_K_M", slow, pull) }()
+	<-blocked
+
+	replayed := make(chan struct{})
+	go func() {
+		g.do("user/repo:Q4_K_M", func(hf.PullProgress) {
+			select {
+			case <-replayed:
+			default:
+				close(replayed)
+			}
+		}, nil)
+	}()
+	select {
+	case <-replayed:
+	case <-time.After(5 * time.Second):
+		t.Error("a slow subscriber held up another joining the pull")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("do() error = %v", err)
+	}
+}
+
+func TestHandlePull(t *testing.T) {
+	useTestHome(t)
+	t.Setenv("HF_TOKEN", "")
+	t.Setenv("HF_ENDPOINT", "")
+	hub := &fakeHub{blob: []byte("gguf blob contents")}
+	sum := sha256.Sum256(hub.blob)
+	mux := http.NewServeMux()
+	mux.Handle("/", hub)
+	mux.HandleFunc("/v2/user/repo/manifests/Q4_K_M", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"ggufFile":{"rfilename":"model-Q4_K_M.gguf","size":%d,"lfs":{"sha256":"%x","size":%d}}}`,
+			len(hub.blob), sum, len(hub.blob))
+	})
+	upstream := httptest.NewServer(mux)
+	defer upstream.Close()
+
+	appCfg := config.DefaultConfig()
+	appCfg.HuggingFace.Endpoint = upstream.URL
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), appCfg)}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"bad body", http.MethodPost, "{", http.StatusBadRequest},
+		{"no model", http.MethodPost, `{}`, http.StatusBadRequest},
+		{"no repo", http.MethodPost, `{"model":"llama"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handlePull(w, httptest.NewRequest(tt.method, "/api/pull", strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	s.handlePull(w, httptest.NewRequest(http.MethodPost, "/api/pull", strings.NewReader(`{"model":"user/repo"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var events []PullEvent
+	dec := json.NewDecoder(w.Body)
+	for dec.More() {
+		var e PullEvent
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) < 2 {
+		t.Fatalf("events = %+v, want progress and done", events)
+	}
+	last := events[len(events)-1]
+	if last.Phase != "done" || last.Model != "user/repo:Q4_K_M" {
+		t.Errorf("last event = %+v", last)
+	}
+
+	// The pulled model is immediately resolvable
+	result, err := s.manager.Resolver().Resolve("user/repo")
+	if err != nil || result.Model == nil {
+		t.Fatalf("Resolve() = %+v, %v", result, err)
+	}
+}
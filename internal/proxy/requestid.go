@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// requestIDHeader carries a request's ID from the client through to the
+// backend and back. Anthropic clients also get it as request-id.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds client-supplied IDs so they stay readable in logs
+const maxRequestIDLen = 128
+
+// requestIDMiddleware assigns every request an ID before it is routed
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ensureRequestID(w, r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ensureRequestID returns the request's ID, assigning one if it has none.
+// A well-formed ID from the client is kept so calls can be traced across
+// systems. The ID is set on the request, which forwards it to the backend,
+// and on the response.
+func ensureRequestID(w http.ResponseWriter, r *http.Request) string {
+	if id := w.Header().Get(requestIDHeader); id != "" {
+		return id
+	}
+	id := r.Header.Get(requestIDHeader)
+	if !validRequestID(id) {
+		id = generateRequestID()
+	}
+	r.Header.Set(requestIDHeader, id)
+	w.Header().Set(requestIDHeader, id)
+	return id
+}
+
+// validRequestID accepts non-empty printable ASCII up to maxRequestIDLen
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// generateRequestID creates a unique request ID in Anthropic format
+func generateRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "req_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"generated", "", false},
+		{"client supplied", "trace-abc123", true},
+		{"with spaces", "bad id", false},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Header.Get(requestIDHeader)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(requestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			got := w.Header().Get(requestIDHeader)
+			if got == "" || got != seen {
+				t.Fatalf("response ID %q, request ID %q", got, seen)
+			}
+			if tt.keep && got != tt.header {
+				t.Errorf("ID = %q, want client's %q", got, tt.header)
+			}
+			if !tt.keep && !strings.HasPrefix(got, "req_") {
+				t.Errorf("ID = %q, want a generated one", got)
+			}
+		})
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var backendID string
+	backendSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendID = r.Header.Get(requestIDHeader)
+		w.Write([]byte(`{}`))
+	}))
+	defer backendSrv.Close()
+	u, _ := url.Parse(backendSrv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	cfg := DefaultConfig()
+	cfg.Host = u.Hostname()
+	s := &Server{config: cfg}
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.proxyToLoadedBackend(w, r, "/v1/chat/completions", func(string) (*Backend, error) {
+			return &Backend{ModelName: "test", Port: port}, nil
+		})
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{"model":"test","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set(requestIDHeader, "trace-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if backendID != "trace-1" {
+		t.Errorf("backend saw ID %q, want trace-1", backendID)
+	}
+	if got := w.Header().Values(requestIDHeader); len(got) != 1 || got[0] != "trace-1" {
+		t.Errorf("response IDs = %v, want [trace-1]", got)
+	}
+
+	// Errors carry the ID in the body too
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(`{}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	var resp OpenAIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.RequestID == "" || resp.RequestID != w.Header().Get(requestIDHeader) {
+		t.Errorf("error request_id = %q, header = %q", resp.RequestID, w.Header().Get(requestIDHeader))
+	}
+}
@@ -7,8 +7,9 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/nchapman/lleme/internal/config"
 	"github.com/nchapman/lleme/internal/hf"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 // DownloadedModel represents a model that has been downloaded locally
@@ -22,16 +23,24 @@ type DownloadedModel struct {
 
 // ModelResolver handles fuzzy matching of model names against downloaded models
 type ModelResolver struct {
-	modelsPath string
+	modelsPath     string
+	preferredQuant string
 }
 
 // NewModelResolver creates a new model resolver
 func NewModelResolver() *ModelResolver {
 	return &ModelResolver{
-		modelsPath: config.ModelsPath(),
+		modelsPath: paths.Models(),
 	}
 }
 
+// PreferQuant sets the quantization chosen when a query matches several
+// downloaded quants of the same model. Others fall back to hf.GetQuantPriority.
+func (r *ModelResolver) PreferQuant(quant string) *ModelResolver {
+	r.preferredQuant = quant
+	return r
+}
+
 // ListDownloadedModels returns all downloaded models
 func (r *ModelResolver) ListDownloadedModels() ([]DownloadedModel, error) {
 	var models []DownloadedModel
@@ -112,11 +121,31 @@ func (r *ModelResolver) ListDownloadedModels() ([]DownloadedModel, error) {
 	return models, nil
 }
 
+// MatchRule names the step of Resolve that matched a query. Steps are
+// tried in this order, and the first one with any candidates wins.
+type MatchRule string
+
+const (
+	MatchExact    MatchRule = "exact name" // user/repo:quant
+	MatchRepo     MatchRule = "user/repo"  // Any quant of user/repo
+	MatchSuffix   MatchRule = "repo name"  // repo or repo:quant
+	MatchContains MatchRule = "substring"  // Anywhere in the full name
+)
+
 // ResolveResult contains the result of a model resolution
 type ResolveResult struct {
 	Model       *DownloadedModel
 	Matches     []DownloadedModel // All matching models (for ambiguous case)
 	Suggestions []DownloadedModel // Fuzzy suggestions (for no match case)
+	Rule        MatchRule         // The step that found Matches, empty when nothing matched
+}
+
+// FuzzyScore is a downloaded model's edit distance from a query. Lower is
+// closer; models within the threshold are offered as suggestions.
+type FuzzyScore struct {
+	Model    DownloadedModel
+	Distance int
+	Close    bool
 }
 
 // Resolve attempts to find a downloaded model matching the given query
@@ -125,6 +154,23 @@ type ResolveResult struct {
 // - Ambiguous: Model is nil, Matches has multiple items
 // - No match: Model is nil, Matches is empty, Suggestions may have items
 func (r *ModelResolver) Resolve(query string) (*ResolveResult, error) {
+	result, err := r.resolve(query)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case result.Model != nil:
+		logs.Debug("Resolved model", "query", query, "model", result.Model.FullName, "rule", result.Rule, "candidates", len(result.Matches), "preferred_quant", r.preferredQuant)
+	case len(result.Matches) > 1:
+		logs.Debug("Model query is ambiguous", "query", query, "rule", result.Rule, "candidates", len(result.Matches))
+	default:
+		logs.Debug("No downloaded model matches", "query", query, "suggestions", len(result.Suggestions))
+	}
+	return result, nil
+}
+
+func (r *ModelResolver) resolve(query string) (*ResolveResult, error) {
 	models, err := r.ListDownloadedModels()
 	if err != nil {
 		return nil, fmt.Errorf("failed to list models: %w", err)
@@ -143,6 +189,7 @@ func (r *ModelResolver) Resolve(query string) (*ResolveResult, error) {
 			return &ResolveResult{
 				Model:   &models[i],
 				Matches: []DownloadedModel{models[i]},
+				Rule:    MatchExact,
 			}, nil
 		}
 	}
@@ -161,14 +208,16 @@ func (r *ModelResolver) Resolve(query string) (*ResolveResult, error) {
 			return &ResolveResult{
 				Model:   &repoMatches[0],
 				Matches: repoMatches,
+				Rule:    MatchRepo,
 			}, nil
 		}
 		if len(repoMatches) > 1 {
-			// Multiple quants - pick the best one (Q4_K_M preferred)
-			best := pickBestQuant(repoMatches)
+			// Multiple quants - pick the preferred or best one
+			best := r.pickBestQuant(repoMatches)
 			return &ResolveResult{
 				Model:   best,
 				Matches: repoMatches,
+				Rule:    MatchRepo,
 			}, nil
 		}
 	}
@@ -187,20 +236,23 @@ func (r *ModelResolver) Resolve(query string) (*ResolveResult, error) {
 		return &ResolveResult{
 			Model:   &suffixMatches[0],
 			Matches: suffixMatches,
+			Rule:    MatchSuffix,
 		}, nil
 	}
 	if len(suffixMatches) > 1 {
 		// If all from same repo, pick best quant
 		if allSameRepo(suffixMatches) {
-			best := pickBestQuant(suffixMatches)
+			best := r.pickBestQuant(suffixMatches)
 			return &ResolveResult{
 				Model:   best,
 				Matches: suffixMatches,
+				Rule:    MatchSuffix,
 			}, nil
 		}
 		// Ambiguous - different repos
 		return &ResolveResult{
 			Matches: suffixMatches,
+			Rule:    MatchSuffix,
 		}, nil
 	}
 
@@ -216,20 +268,23 @@ func (r *ModelResolver) Resolve(query string) (*ResolveResult, error) {
 		return &ResolveResult{
 			Model:   &containsMatches[0],
 			Matches: containsMatches,
+			Rule:    MatchContains,
 		}, nil
 	}
 	if len(containsMatches) > 1 {
 		// If all from same repo, pick best quant
 		if allSameRepo(containsMatches) {
-			best := pickBestQuant(containsMatches)
+			best := r.pickBestQuant(containsMatches)
 			return &ResolveResult{
 				Model:   best,
 				Matches: containsMatches,
+				Rule:    MatchContains,
 			}, nil
 		}
 		// Ambiguous - different repos
 		return &ResolveResult{
 			Matches: containsMatches,
+			Rule:    MatchContains,
 		}, nil
 	}
 
@@ -254,12 +309,21 @@ func allSameRepo(models []DownloadedModel) bool {
 	return true
 }
 
-// pickBestQuant returns the model with the best quantization
-func pickBestQuant(models []DownloadedModel) *DownloadedModel {
+// pickBestQuant returns the model with the preferred quantization if present,
+// otherwise the one with the best quantization priority
+func (r *ModelResolver) pickBestQuant(models []DownloadedModel) *DownloadedModel {
 	if len(models) == 0 {
 		return nil
 	}
 
+	if r.preferredQuant != "" {
+		for i := range models {
+			if strings.EqualFold(models[i].Quant, r.preferredQuant) {
+				return &models[i]
+			}
+		}
+	}
+
 	best := &models[0]
 	bestPriority := hf.GetQuantPriority(best.Quant)
 
@@ -276,39 +340,37 @@ func pickBestQuant(models []DownloadedModel) *DownloadedModel {
 
 // fuzzyMatch finds models with similar names (for typo suggestions)
 func fuzzyMatch(query string, models []DownloadedModel) []DownloadedModel {
-	type scored struct {
-		model DownloadedModel
-		score int
+	var suggestions []DownloadedModel
+	for _, s := range FuzzyScores(query, models) {
+		if !s.Close || len(suggestions) == 3 {
+			break
+		}
+		suggestions = append(suggestions, s.Model)
 	}
+	return suggestions
+}
 
-	var results []scored
-
+// FuzzyScores scores every model against query by edit distance to its
+// full name or repo name, whichever is closer, sorted closest first.
+func FuzzyScores(query string, models []DownloadedModel) []FuzzyScore {
+	query = strings.ToLower(strings.TrimSpace(query))
+	scores := make([]FuzzyScore, 0, len(models))
 	for _, m := range models {
-		// Calculate a simple edit distance score
-		score := levenshtein(query, strings.ToLower(m.FullName))
-		// Also check against just the repo name
-		repoScore := levenshtein(query, strings.ToLower(m.Repo))
-		if repoScore < score {
-			score = repoScore
-		}
-		// Only include if reasonably close
-		if score <= len(query)/2+3 {
-			results = append(results, scored{m, score})
-		}
+		distance := min(
+			levenshtein(query, strings.ToLower(m.FullName)),
+			levenshtein(query, strings.ToLower(m.Repo)),
+		)
+		scores = append(scores, FuzzyScore{
+			Model:    m,
+			Distance: distance,
+			Close:    distance <= len(query)/2+3,
+		})
 	}
 
-	// Sort by score
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].score < results[j].score
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Distance < scores[j].Distance
 	})
-
-	// Return top 3
-	var suggestions []DownloadedModel
-	for i := 0; i < len(results) && i < 3; i++ {
-		suggestions = append(suggestions, results[i].model)
-	}
-
-	return suggestions
+	return scores
 }
 
 // levenshtein calculates the edit distance between two strings
@@ -41,12 +41,26 @@ func TestPickBestQuant(t *testing.T) {
 		{Quant: "Q3_K_S"},
 	}
 
-	best := pickBestQuant(models)
-	if best == nil {
-		t.Fatal("pickBestQuant returned nil")
+	tests := []struct {
+		name      string
+		preferred string
+		want      string
+	}{
+		{"priority order", "", "Q4_K_M"},
+		{"preferred quant", "q8_0", "Q8_0"},
+		{"preferred quant not downloaded", "Q6_K", "Q4_K_M"},
 	}
-	if best.Quant != "Q4_K_M" {
-		t.Errorf("pickBestQuant() = %s, want Q4_K_M", best.Quant)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best := (&ModelResolver{}).PreferQuant(tt.preferred).pickBestQuant(models)
+			if best == nil {
+				t.Fatal("pickBestQuant returned nil")
+			}
+			if best.Quant != tt.want {
+				t.Errorf("pickBestQuant() = %s, want %s", best.Quant, tt.want)
+			}
+		})
 	}
 }
 
@@ -192,6 +206,7 @@ func TestResolve(t *testing.T) {
 		wantAmbiguous bool   // Should be ambiguous (multiple different repos)
 		wantModel     string // Expected model name (if unique match)
 		wantCount     int    // Expected number of matches/suggestions
+		wantRule      MatchRule
 	}{
 		// Exact matches
 		{
@@ -199,12 +214,14 @@ func TestResolve(t *testing.T) {
 			query:     "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M",
 			wantMatch: true,
 			wantModel: "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M",
+			wantRule:  MatchExact,
 		},
 		{
 			name:      "exact full name case insensitive",
 			query:     "bartowski/llama-3.2-3b-instruct-gguf:q4_k_m",
 			wantMatch: true,
 			wantModel: "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M",
+			wantRule:  MatchExact,
 		},
 
 		// User/repo without quant - picks best quant
@@ -213,6 +230,7 @@ func TestResolve(t *testing.T) {
 			query:     "bartowski/Llama-3.2-3B-Instruct-GGUF",
 			wantMatch: true,
 			wantModel: "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M", // Q4_K_M preferred over Q8_0
+			wantRule:  MatchRepo,
 		},
 
 		// Repo name only - unique
@@ -221,12 +239,14 @@ func TestResolve(t *testing.T) {
 			query:     "phi-2-gguf",
 			wantMatch: true,
 			wantModel: "microsoft/phi-2-gguf:Q4_0",
+			wantRule:  MatchSuffix,
 		},
 		{
 			name:      "unique repo name case insensitive",
 			query:     "PHI-2-GGUF",
 			wantMatch: true,
 			wantModel: "microsoft/phi-2-gguf:Q4_0",
+			wantRule:  MatchSuffix,
 		},
 
 		// Repo name - ambiguous (different users have similar repos)
@@ -235,6 +255,7 @@ func TestResolve(t *testing.T) {
 			query:         "Mistral",
 			wantAmbiguous: true,
 			wantCount:     2, // bartowski and mistralai both have Mistral
+			wantRule:      MatchContains,
 		},
 
 		// Contains match - unique
@@ -243,6 +264,7 @@ func TestResolve(t *testing.T) {
 			query:     "phi",
 			wantMatch: true,
 			wantModel: "microsoft/phi-2-gguf:Q4_0",
+			wantRule:  MatchContains,
 		},
 
 		// Contains match - same repo different quants
@@ -251,6 +273,7 @@ func TestResolve(t *testing.T) {
 			query:     "llama-3.2-3b",
 			wantMatch: true,
 			wantModel: "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M", // picks best quant
+			wantRule:  MatchContains,
 		},
 
 		// No match - should give suggestions
@@ -258,7 +281,8 @@ func TestResolve(t *testing.T) {
 			name:      "typo gives suggestions",
 			query:     "lama", // typo for llama
 			wantMatch: false,
-			wantCount: 0, // No contains match, might have suggestions
+			wantCount: 0,             // No contains match, might have suggestions
+			wantRule:  MatchContains, // "lama" is a substring of "llama"
 		},
 	}
 
@@ -268,6 +292,9 @@ func TestResolve(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Resolve() error = %v", err)
 			}
+			if result.Rule != tt.wantRule {
+				t.Errorf("Resolve(%q) rule = %q, want %q", tt.query, result.Rule, tt.wantRule)
+			}
 
 			if tt.wantMatch {
 				if result.Model == nil {
@@ -292,3 +319,25 @@ func TestResolve(t *testing.T) {
 		})
 	}
 }
+
+func TestFuzzyScores(t *testing.T) {
+	models := []DownloadedModel{
+		{User: "user", Repo: "deepseek-coder-v2", Quant: "Q4_K_M", FullName: "user/deepseek-coder-v2:Q4_K_M"},
+		{User: "user", Repo: "llama", Quant: "Q4_K_M", FullName: "user/llama:Q4_K_M"},
+	}
+
+	scores := FuzzyScores("lama", models)
+	if len(scores) != 2 {
+		t.Fatalf("FuzzyScores() returned %d scores, want 2", len(scores))
+	}
+	if scores[0].Model.Repo != "llama" || scores[0].Distance != 1 || !scores[0].Close {
+		t.Errorf("closest = %+v, want llama at distance 1", scores[0])
+	}
+	if scores[1].Distance <= scores[0].Distance {
+		t.Errorf("scores not sorted: %+v", scores)
+	}
+
+	if got := fuzzyMatch("lama", models); len(got) != 1 || got[0].Repo != "llama" {
+		t.Errorf("fuzzyMatch() = %+v, want only llama", got)
+	}
+}
@@ -0,0 +1,22 @@
+package proxy
+
+import (
+	"github.com/nchapman/lleme/internal/catalog"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// samplerPreset returns the registry's sampling defaults for modelName's
+// family, or nil when it has none. They sit below every configured option,
+// and llama-server uses them for requests that don't set their own.
+func samplerPreset(modelName string) map[string]any {
+	cat, err := catalog.Load()
+	if err != nil {
+		return nil
+	}
+	preset := cat.SamplerFor(modelName)
+	if preset == nil {
+		return nil
+	}
+	logs.Debug("Applied sampler preset", "model", modelName, "family", preset.Family)
+	return preset.Options
+}
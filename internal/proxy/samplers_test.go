@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/nchapman/lleme/internal/options"
+)
+
+func TestSamplerPreset(t *testing.T) {
+	useTestHome(t)
+
+	resolved := options.Resolve(options.Layers{
+		Preset: samplerPreset("bartowski/Qwen2.5-Coder-7B-Instruct-GGUF:Q4_K_M"),
+		Config: map[string]any{"temp": 0.2, "ctx-size": 8192},
+	})
+	if v, _ := resolved.Get("temp"); v.Value != 0.2 || v.Source != options.SourceConfig {
+		t.Errorf("temp = %+v, want the configured 0.2", v)
+	}
+	if v, _ := resolved.Get("top-k"); v.Value != float64(20) || v.Source != options.SourcePreset {
+		t.Errorf("top-k = %+v, want the preset's 20", v)
+	}
+
+	if preset := samplerPreset("microsoft/phi-2-gguf:Q4_0"); len(preset) != 0 {
+		t.Errorf("preset = %v for a model without a preset", preset)
+	}
+}
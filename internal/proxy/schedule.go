@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// quietHours is a daily window, in local time, during which no models run,
+// such as "22:00-07:00" to keep the GPU free overnight. A window whose end is
+// before its start runs past midnight.
+type quietHours struct {
+	start, end time.Duration // Since midnight
+	spec       string
+}
+
+// parseQuietHours parses server.unload_schedule, "HH:MM-HH:MM". An empty
+// spec returns nil, for no quiet hours.
+func parseQuietHours(spec string) (*quietHours, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	from, to, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid unload_schedule %q: expected HH:MM-HH:MM", spec)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unload_schedule %q: %w", spec, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unload_schedule %q: %w", spec, err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("invalid unload_schedule %q: start and end are the same", spec)
+	}
+	return &quietHours{start: start, end: end, spec: spec}, nil
+}
+
+// parseClock parses a 24-hour "HH:MM" time of day
+func parseClock(s string) (time.Duration, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("%q is not a time like 22:00", strings.TrimSpace(s))
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// clockTime returns t's time of day on the clock, which is what quiet hours
+// follow across daylight saving changes
+func clockTime(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// active reports whether t falls within quiet hours
+func (q *quietHours) active(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	now := clockTime(t)
+	if q.start < q.end {
+		return now >= q.start && now < q.end
+	}
+	return now >= q.start || now < q.end
+}
+
+// endsAt returns when the quiet hours t falls in end
+func (q *quietHours) endsAt(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	end := time.Date(y, mo, d, int(q.end/time.Hour), int(q.end%time.Hour/time.Minute), 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// QuietHoursError is returned when a model would be loaded during
+// server.unload_schedule
+type QuietHoursError struct {
+	Model string
+	Until time.Time
+}
+
+func (e *QuietHoursError) Error() string {
+	return fmt.Sprintf("not loading '%s' during quiet hours (server.unload_schedule); models can load again at %s",
+		e.Model, e.Until.Format("15:04"))
+}
+
+// checkQuietHours refuses to load a model during quiet hours
+func (m *ModelManager) checkQuietHours(modelName string) error {
+	now := m.clock.Now()
+	if !m.quietHours.active(now) {
+		return nil
+	}
+	return &QuietHoursError{Model: modelName, Until: m.quietHours.endsAt(now)}
+}
+
+// stopForQuietHours unloads every model once quiet hours have started
+func (m *ModelManager) stopForQuietHours() {
+	if !m.quietHours.active(m.clock.Now()) || m.LoadedCount() == 0 {
+		return
+	}
+	logs.Info("Unloading all models for quiet hours", "schedule", m.quietHours.spec)
+	if err := m.StopAllBackends(); err != nil {
+		logs.Warn("Failed to unload models for quiet hours", "error", err)
+	}
+}
+
+// waitForQuietHours blocks until quiet hours are over, or ctx ends. Detached
+// jobs use it to start once models can load again instead of failing.
+func (m *ModelManager) waitForQuietHours(ctx context.Context) error {
+	now := m.clock.Now()
+	if !m.quietHours.active(now) {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-m.clock.After(m.quietHours.endsAt(now).Sub(now)):
+		return nil
+	}
+}
+
+// QuietHoursStatus returns the state of server.unload_schedule for
+// /api/status, or nil without one
+func (m *ModelManager) QuietHoursStatus() *QuietHours {
+	if m.quietHours == nil {
+		return nil
+	}
+	now := m.clock.Now()
+	status := &QuietHours{Schedule: m.quietHours.spec, Active: m.quietHours.active(now)}
+	if status.Active {
+		status.Until = m.quietHours.endsAt(now)
+	}
+	return status
+}
+
+// setRetryAfter tells the client when quiet hours end
+func setRetryAfter(w http.ResponseWriter, until time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+}
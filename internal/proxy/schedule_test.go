@@ -0,0 +1,150 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nchapman/lleme/internal/clock"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"22:00-07:00", false},
+		{" 09:30 - 17:45 ", false},
+		{"0:00-6:00", false},
+		{"22:00", true},
+		{"25:00-07:00", true},
+		{"22:60-07:00", true},
+		{"ten-seven", true},
+		{"07:00-07:00", true},
+	}
+	for _, tt := range tests {
+		_, err := parseQuietHours(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseQuietHours(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+
+	if q, err := parseQuietHours(""); q != nil || err != nil {
+		t.Errorf("parseQuietHours(\"\") = %v, %v, want no quiet hours", q, err)
+	}
+}
+
+func TestQuietHoursActive(t *testing.T) {
+	at := func(clock string) time.Time {
+		tm, _ := time.ParseInLocation("2006-01-02 15:04", "2026-03-10 "+clock, time.Local)
+		return tm
+	}
+
+	overnight, _ := parseQuietHours("22:00-07:00")
+	daytime, _ := parseQuietHours("09:00-17:00")
+	tests := []struct {
+		q       *quietHours
+		clock   string
+		want    bool
+		wantEnd string // "" when not active
+	}{
+		{overnight, "21:59", false, ""},
+		{overnight, "22:00", true, "2026-03-11 07:00"},
+		{overnight, "03:15", true, "2026-03-10 07:00"},
+		{overnight, "07:00", false, ""},
+		{daytime, "08:59", false, ""},
+		{daytime, "12:00", true, "2026-03-10 17:00"},
+		{daytime, "17:00", false, ""},
+		{nil, "12:00", false, ""},
+	}
+	for _, tt := range tests {
+		got := tt.q.active(at(tt.clock))
+		if got != tt.want {
+			t.Errorf("%v active at %s = %v, want %v", tt.q, tt.clock, got, tt.want)
+		}
+		if got {
+			if end := tt.q.endsAt(at(tt.clock)).Format("2006-01-02 15:04"); end != tt.wantEnd {
+				t.Errorf("%s at %s ends %s, want %s", tt.q.spec, tt.clock, end, tt.wantEnd)
+			}
+		}
+	}
+}
+
+// alwaysQuiet returns quiet hours that cover now, ending in a few hours
+func alwaysQuiet(t *testing.T) *quietHours {
+	t.Helper()
+	now := time.Now()
+	start := now.Add(-time.Hour).Format("15:04")
+	end := now.Add(3 * time.Hour).Format("15:04")
+	q, err := parseQuietHours(start + "-" + end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestQuietHoursRefuseLoads(t *testing.T) {
+	m := NewModelManager(DefaultConfig(), nil)
+	m.quietHours = alwaysQuiet(t)
+
+	err := m.checkQuietHours("test/model")
+	var qe *QuietHoursError
+	if !errors.As(err, &qe) || qe.Model != "test/model" || !qe.Until.After(time.Now()) {
+		t.Fatalf("checkQuietHours() = %v", err)
+	}
+
+	s := &Server{config: DefaultConfig(), manager: m}
+	w := httptest.NewRecorder()
+	s.handleModelError(w, err)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" ||
+		!strings.Contains(w.Body.String(), "quiet hours") {
+		t.Errorf("response = %d %v %s", w.Code, w.Header(), w.Body)
+	}
+
+	if status := m.QuietHoursStatus(); status == nil || !status.Active || status.Until.IsZero() {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestWaitForQuietHours(t *testing.T) {
+	m := NewModelManager(DefaultConfig(), nil)
+	if err := m.waitForQuietHours(context.Background()); err != nil {
+		t.Errorf("wait without quiet hours = %v", err)
+	}
+
+	m.quietHours = alwaysQuiet(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.waitForQuietHours(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait during quiet hours = %v, want it to last until ctx ends", err)
+	}
+}
+
+func TestWaitForQuietHoursEnds(t *testing.T) {
+	clk := clock.NewFake(time.Date(2026, 3, 1, 23, 0, 0, 0, time.Local))
+	m := NewModelManagerWithClock(DefaultConfig(), nil, clk)
+	m.quietHours, _ = parseQuietHours("22:00-07:00")
+
+	if err := m.checkQuietHours("user/repo:Q4_K_M"); err == nil {
+		t.Fatal("checkQuietHours() at 23:00 should refuse")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.waitForQuietHours(context.Background()) }()
+	clk.Advance(8 * time.Hour)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("waitForQuietHours() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waitForQuietHours() should return once the clock reaches 07:00")
+	}
+	if err := m.checkQuietHours("user/repo:Q4_K_M"); err != nil {
+		t.Errorf("checkQuietHours() at 07:00 = %v", err)
+	}
+}
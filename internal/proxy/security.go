@@ -0,0 +1,250 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/logs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcpeer "google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// isLoopbackHost reports whether host only accepts connections from this
+// machine. Wildcards like "0.0.0.0" and "" listen on every interface.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// hasAPIKeys reports whether any server.clients profile has an API key
+func (c *Config) hasAPIKeys() bool {
+	for _, client := range c.Clients {
+		if client.APIKey != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Public reports whether the server listens beyond the loopback interface
+func (c *Config) Public() bool {
+	return !isLoopbackHost(c.Host)
+}
+
+// checkExposure refuses a public listen address when nothing would stop
+// anyone on the network from loading models, unless Insecure is set.
+func (c *Config) checkExposure() error {
+	if !c.Public() || c.hasAPIKeys() || c.Insecure {
+		return nil
+	}
+	return fmt.Errorf("refusing to listen on %s without API keys: add api_key to a server.clients entry, or pass --insecure to expose the server anyway", c.Host)
+}
+
+// ExposedEndpoints lists what a public listen address makes reachable, for
+// the startup warning
+func (c *Config) ExposedEndpoints() []string {
+	auth := "no authentication"
+	if c.hasAPIKeys() {
+		auth = "API key required"
+	}
+	endpoints := []string{
+		fmt.Sprintf("http://%s:%d/v1/* (%s)", c.Host, c.Port, auth),
+		fmt.Sprintf("http://%s:%d/api/* manage, run and stop models (%s)", c.Host, c.Port, auth),
+		fmt.Sprintf("http://%s:%d/ web UI", c.Host, c.Port),
+	}
+	if c.StatusToken != "" {
+		endpoints = append(endpoints, fmt.Sprintf("http://%s:%d/status read-only status (status token required)", c.Host, c.Port))
+	}
+	if c.Pprof && c.hasAPIKeys() {
+		endpoints = append(endpoints, fmt.Sprintf("http://%s:%d/debug/pprof/ Go profiler (%s)", c.Host, c.Port, auth))
+	}
+	if c.HFCache {
+		hfAuth := "no authentication"
+		if c.HFCacheToken {
+			hfAuth += "; this machine's Hub token only with an API key in x-api-key"
+		}
+		endpoints = append(endpoints, fmt.Sprintf("http://%s:%d%s/ model downloads (%s)", c.Host, c.Port, hfCachePrefix, hfAuth))
+	}
+	if c.GRPCPort > 0 {
+		endpoints = append(endpoints, fmt.Sprintf("%s:%d gRPC management API (%s)", c.Host, c.GRPCPort, auth))
+	}
+	return endpoints
+}
+
+// requireAPIKey rejects requests from other machines that don't carry a
+// server.clients API key. It's only installed on a public address with keys
+// configured. Local requests, health checks, the web UI's files and the
+// Hugging Face mirror, whose Authorization header is a Hub token, pass.
+func (s *Server) requireAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isLocalRequest(r) || !needsAPIKey(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c := s.matchClient(r); c != nil && c.APIKey != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logs.Debug("Rejected request without API key", "remote", r.RemoteAddr, "path", r.URL.Path)
+		const msg = "A valid API key is required. Send it as a bearer token or x-api-key."
+		if strings.HasPrefix(r.URL.Path, "/v1/messages") {
+			s.writeAnthropicError(w, ensureRequestID(w, r), http.StatusUnauthorized, AnthropicAuthentication, msg)
+			return
+		}
+		s.writeErrorDetail(w, http.StatusUnauthorized, OpenAIErrorDetail{
+			Message: msg,
+			Type:    "authentication_error",
+			Code:    "invalid_api_key",
+		})
+	})
+}
+
+// lendsHFToken reports whether an /hf request without its own Hub token may
+// use ours, with server.hf_cache_token set. On a public address only
+// requests from this machine, or with a client API key in x-api-key, since
+// Authorization carries Hub tokens there, get it.
+func (s *Server) lendsHFToken(r *http.Request) bool {
+	if !s.config.Public() || isLocalRequest(r) {
+		return true
+	}
+	return s.config.apiKeyClient(r.Header.Get("x-api-key")) != nil
+}
+
+// apiKeyClient returns the server.clients profile whose API key is key, or
+// nil
+func (c *Config) apiKeyClient(key string) *config.Client {
+	if key == "" {
+		return nil
+	}
+	for i := range c.Clients {
+		client := &c.Clients[i]
+		if client.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(client.APIKey)) == 1 {
+			return client
+		}
+	}
+	return nil
+}
+
+// grpcAuth returns interceptors that find each gRPC call's server.clients
+// profile, as matchClient does, from "authorization: Bearer", "x-api-key" or,
+// for clients without a key, x-lleme-client metadata, and keep it on the
+// call's context for the handlers' model restrictions. On a public address
+// with keys configured, they also hold calls to the same API keys as
+// requireAPIKey; calls from this machine pass.
+func (s *Server) grpcAuth() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := s.authorizeGRPC(ctx, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := s.authorizeGRPC(ss.Context(), info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &clientStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+// clientStream is a server stream with the context authorizeGRPC returned
+type clientStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *clientStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcClientKey holds the calling client on a gRPC call's context
+type grpcClientKey struct{}
+
+// grpcClient returns the server.clients profile authorizeGRPC found for a
+// call, or nil
+func grpcClient(ctx context.Context) *config.Client {
+	c, _ := ctx.Value(grpcClientKey{}).(*config.Client)
+	return c
+}
+
+// authorizeGRPC finds one gRPC call's client and returns ctx carrying it,
+// or an error when the call needs an API key and has none
+func (s *Server) authorizeGRPC(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(name string) string {
+		if values := md.Get(name); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	key := first("x-api-key")
+	if auth := first("authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	c := s.config.apiKeyClient(key)
+	if c == nil {
+		c = s.config.namedClient(first(clientHeader))
+	}
+	if c != nil {
+		ctx = context.WithValue(ctx, grpcClientKey{}, c)
+	}
+
+	if !s.config.Public() || !s.config.hasAPIKeys() || (c != nil && c.APIKey != "") {
+		return ctx, nil
+	}
+	remote := ""
+	if p, ok := grpcpeer.FromContext(ctx); ok {
+		remote = p.Addr.String()
+		if isLoopbackAddr(remote) {
+			return ctx, nil
+		}
+	}
+
+	logs.Debug("Rejected gRPC call without API key", "remote", remote, "method", method)
+	return nil, status.Error(codes.Unauthenticated, "a valid API key is required; send it as authorization: Bearer or x-api-key metadata")
+}
+
+// apiKeyPrefixes are the paths that need an API key: the APIs, and the
+// profiler when it's on. Anything that can load models, change the server
+// or show what it's doing belongs here.
+var apiKeyPrefixes = []string{"/v1/", "/api/", "/debug/"}
+
+// needsAPIKey reports whether requests for path must authenticate
+func needsAPIKey(path string) bool {
+	for _, prefix := range apiKeyPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalRequest reports whether r came from this machine
+func isLocalRequest(r *http.Request) bool {
+	return isLoopbackAddr(r.RemoteAddr)
+}
+
+// isLoopbackAddr reports whether a host:port address is on this machine
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+func TestIsLoopbackHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"127.0.0.1", true},
+		{"127.0.0.2", true},
+		{"localhost", true},
+		{"::1", true},
+		{"[::1]", true},
+		{"0.0.0.0", false},
+		{"", false},
+		{"::", false},
+		{"192.168.1.10", false},
+		{"myhost.lan", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopbackHost(tt.host); got != tt.want {
+			t.Errorf("isLoopbackHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestCheckExposure(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		clients  []config.Client
+		insecure bool
+		wantErr  bool
+	}{
+		{"loopback", "127.0.0.1", nil, false, false},
+		{"public without keys", "0.0.0.0", nil, false, true},
+		{"public with header-only clients", "0.0.0.0", []config.Client{{Name: "a"}}, false, true},
+		{"public with keys", "0.0.0.0", []config.Client{{Name: "a", APIKey: "k"}}, false, false},
+		{"public, insecure", "0.0.0.0", nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestHome(t)
+			cfg := DefaultConfig()
+			cfg.Host = tt.host
+			cfg.Clients = tt.clients
+			cfg.Insecure = tt.insecure
+			if err := cfg.checkExposure(); (err != nil) != tt.wantErr {
+				t.Errorf("checkExposure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExposedEndpoints(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Host = "0.0.0.0"
+	cfg.GRPCPort = 50051
+	cfg.Pprof = true
+	endpoints := strings.Join(cfg.ExposedEndpoints(), "\n")
+	for _, want := range []string{"/v1/*", "/api/*", "no authentication", "0.0.0.0:50051 gRPC"} {
+		if !strings.Contains(endpoints, want) {
+			t.Errorf("ExposedEndpoints() missing %q:\n%s", want, endpoints)
+		}
+	}
+	// Without keys, the profiler only answers this machine
+	if strings.Contains(endpoints, "/debug/pprof/") {
+		t.Errorf("ExposedEndpoints() lists the profiler without API keys:\n%s", endpoints)
+	}
+
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "secret"}}
+	endpoints = strings.Join(cfg.ExposedEndpoints(), "\n")
+	if !strings.Contains(endpoints, "/debug/pprof/ Go profiler (API key required)") {
+		t.Errorf("ExposedEndpoints() with keys missing the profiler:\n%s", endpoints)
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Host = "0.0.0.0"
+	cfg.Clients = []config.Client{{Name: "ci", APIKey: "secret"}, {Name: "notebook"}}
+	s := &Server{config: cfg}
+	handler := s.requireAPIKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		path       string
+		remote     string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"remote without key", "/v1/chat/completions", "192.168.1.5:5000", nil, http.StatusUnauthorized},
+		{"remote with wrong key", "/v1/chat/completions", "192.168.1.5:5000", map[string]string{"Authorization": "Bearer nope"}, http.StatusUnauthorized},
+		{"remote with key", "/v1/chat/completions", "192.168.1.5:5000", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"remote anthropic key", "/v1/messages", "192.168.1.5:5000", map[string]string{"x-api-key": "secret"}, http.StatusOK},
+		{"remote header-only client", "/api/run", "192.168.1.5:5000", map[string]string{clientHeader: "notebook"}, http.StatusUnauthorized},
+		{"local without key", "/v1/chat/completions", "127.0.0.1:5000", nil, http.StatusOK},
+		{"remote health check", "/health", "192.168.1.5:5000", nil, http.StatusOK},
+		{"remote web UI", "/index.html", "192.168.1.5:5000", nil, http.StatusOK},
+		{"remote profiler without key", "/debug/pprof/goroutine", "192.168.1.5:5000", nil, http.StatusUnauthorized},
+		{"remote profiler with key", "/debug/pprof/goroutine", "192.168.1.5:5000", map[string]string{"x-api-key": "secret"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestPprofExposure(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		clients    []config.Client
+		remote     string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{"insecure, remote", "0.0.0.0", nil, "192.168.1.5:5000", nil, http.StatusForbidden},
+		{"insecure, local", "0.0.0.0", nil, "127.0.0.1:5000", nil, http.StatusOK},
+		{"keys, remote without key", "0.0.0.0", []config.Client{{Name: "ci", APIKey: "secret"}}, "192.168.1.5:5000", nil, http.StatusUnauthorized},
+		{"keys, remote with key", "0.0.0.0", []config.Client{{Name: "ci", APIKey: "secret"}}, "192.168.1.5:5000", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestHome(t)
+			cfg := DefaultConfig()
+			cfg.Host = tt.host
+			cfg.Clients = tt.clients
+			cfg.Insecure = tt.clients == nil
+			cfg.Pprof = true
+			s := NewServer(cfg, config.DefaultConfig())
+
+			r := httptest.NewRequest(http.MethodGet, "/debug/pprof/cmdline", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			s.httpServer.Handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+func TestLendsHFToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		remote string
+		key    string
+		want   bool
+	}{
+		{"loopback server", "127.0.0.1", "127.0.0.1:5000", "", true},
+		{"public, local request", "0.0.0.0", "127.0.0.1:5000", "", true},
+		{"public, remote without key", "0.0.0.0", "192.168.1.5:5000", "", false},
+		{"public, remote with wrong key", "0.0.0.0", "192.168.1.5:5000", "nope", false},
+		{"public, remote with key", "0.0.0.0", "192.168.1.5:5000", "secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Host = tt.host
+			cfg.Clients = []config.Client{{Name: "office", APIKey: "secret"}}
+			s := &Server{config: cfg}
+
+			r := httptest.NewRequest(http.MethodGet, "/hf/api/whoami-v2", nil)
+			r.RemoteAddr = tt.remote
+			if tt.key != "" {
+				r.Header.Set("x-api-key", tt.key)
+			}
+			if got := s.lendsHFToken(r); got != tt.want {
+				t.Errorf("lendsHFToken() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,8 @@ package proxy
 import (
 	"bytes"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -13,28 +12,40 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/nchapman/lleme/internal/compress"
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/hf"
 	"github.com/nchapman/lleme/internal/logs"
 	"github.com/nchapman/lleme/internal/peer"
 	"github.com/nchapman/lleme/internal/version"
+	"google.golang.org/grpc"
 )
 
 // Server is the main proxy server that routes requests to backends
 type Server struct {
 	mu           sync.RWMutex
 	httpServer   *http.Server
+	grpcServer   *grpc.Server
 	manager      *ModelManager
 	idleMonitor  *IdleMonitor
+	usage        *UsageSampler
 	discovery    *peer.Discovery
 	peerServer   *peer.Server
 	config       *Config
 	startedAt    time.Time
+	executable   *Executable // The binary the server started from
 	shutdownChan chan struct{}
 	stateMu      sync.Mutex // protects state file writes
+	clients      clientLimiter
+	active       atomic.Int64 // Inference requests in progress
+	jobs         jobStore
 }
 
 // NewServer creates a new proxy server
@@ -58,6 +69,7 @@ func NewServer(cfg *Config, appCfg *config.Config) *Server {
 
 	// Create idle monitor
 	s.idleMonitor = NewIdleMonitor(manager, cfg.IdleTimeout, 60*time.Second)
+	s.usage = NewUsageSampler(manager, usageSampleInterval)
 
 	// Create peer discovery - advertise the peer port, not the main server port
 	peerPort := appCfg.Peer.Port
@@ -72,7 +84,17 @@ func NewServer(cfg *Config, appCfg *config.Config) *Server {
 
 	// Create peer server for model sharing (runs on separate port, binds to 0.0.0.0)
 	if appCfg.Peer.Enabled {
-		s.peerServer = peer.NewServer(peerPort)
+		access, err := peer.ParseAccessList(appCfg.Peer.Allow, appCfg.Peer.Deny)
+		if err != nil {
+			// Sharing with a broken access list would share with everyone
+			logs.Warn("Peer sharing disabled", "error", err)
+		} else {
+			s.peerServer = peer.NewServer(peerPort)
+			s.peerServer.Restrict(access)
+			if appCfg.Peer.AllowSync {
+				s.peerServer.AllowSync()
+			}
+		}
 	}
 
 	// Setup HTTP server
@@ -80,22 +102,55 @@ func NewServer(cfg *Config, appCfg *config.Config) *Server {
 	mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
 	mux.HandleFunc("/v1/completions", s.handleCompletions)
 	mux.HandleFunc("/v1/embeddings", s.handleEmbeddings)
+	mux.HandleFunc("/v1/images/generations", s.handleImageGenerations)
 	mux.HandleFunc("/v1/models", s.handleModels)
+	mux.HandleFunc("/v1/models/", s.handleModel)
 
 	// Anthropic Messages API
 	mux.HandleFunc("/v1/messages", s.handleAnthropicMessages)
 	mux.HandleFunc("/v1/messages/count_tokens", s.handleAnthropicCountTokens)
 	mux.HandleFunc("/health", s.handleHealth)
 	mux.HandleFunc("/api/status", s.handleStatus)
+	mux.HandleFunc("/status", s.handleStatusPage)
 	mux.HandleFunc("/api/run", s.handleRun)
 	mux.HandleFunc("/api/stop", s.handleStopModel)
 	mux.HandleFunc("/api/stop-all", s.handleStopAll)
+	mux.HandleFunc("/api/pull", s.handlePull)
+	mux.HandleFunc("/api/models/", s.handleManageModel)
+	mux.HandleFunc("/api/backends/", s.handleBackend)
+	if appCfg.FeatureEnabled(config.FeatureBackgroundJobs) {
+		mux.HandleFunc("/api/jobs", s.handleJobs)
+		mux.HandleFunc("/api/jobs/", s.handleJob)
+	} else {
+		mux.HandleFunc("/api/jobs", s.handleJobsOff)
+		mux.HandleFunc("/api/jobs/", s.handleJobsOff)
+	}
+
+	// Caching Hugging Face mirror for other lleme clients
+	if cfg.HFCache {
+		var lend func(*http.Request) bool
+		if cfg.HFCacheToken {
+			lend = s.lendsHFToken
+		}
+		mux.Handle(hfCachePrefix+"/", http.StripPrefix(hfCachePrefix, newHFCache(appCfg, lend)))
+	}
+
+	// Go profiles, for diagnosing a stuck or slow server
+	if cfg.Pprof {
+		registerPprof(mux, cfg.Public() && cfg.hasAPIKeys())
+	}
 
 	// Serve embedded web UI at root
 	mux.Handle("/", newWebUIHandler())
 
+	// Require API keys from other machines when listening publicly
+	var handler http.Handler = compress.JSON(mux)
+	if cfg.Public() && cfg.hasAPIKeys() {
+		handler = s.requireAPIKey(handler)
+	}
+
 	// Apply CORS middleware
-	handler := CORSMiddleware(cfg.CORSOrigins)(mux)
+	handler = CORSMiddleware(cfg.CORSOrigins)(requestIDMiddleware(handler))
 
 	s.httpServer = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
@@ -107,8 +162,25 @@ func NewServer(cfg *Config, appCfg *config.Config) *Server {
 
 // Start starts the proxy server
 func (s *Server) Start() error {
-	// Start idle monitor
+	if err := s.config.checkExposure(); err != nil {
+		return err
+	}
+	if _, err := parseQuietHours(s.config.UnloadSchedule); err != nil {
+		return err
+	}
+	if err := s.config.checkEviction(); err != nil {
+		return err
+	}
+	if s.config.Public() {
+		logs.Warn("Listening on a public address", "host", s.config.Host, "exposed", strings.Join(s.config.ExposedEndpoints(), "; "))
+	}
+
+	// Dump state to the log on SIGUSR1 where there is one
+	s.watchDumpSignal()
+
+	// Start idle monitor and usage sampling
 	s.idleMonitor.Start()
+	s.usage.Start()
 
 	// Start peer server (for model sharing on separate port)
 	if s.peerServer != nil {
@@ -135,9 +207,27 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	if s.config.GRPCPort > 0 {
+		if err := s.startGRPC(); err != nil {
+			s.httpServer.Close()
+			return err
+		}
+	}
+
+	// Record the binary before saving, so an upgrade that replaces it
+	// later can be noticed
+	if exe, err := currentExecutable(); err == nil {
+		s.executable = exe
+	} else {
+		logs.Debug("Could not identify executable", "error", err)
+	}
+
 	// Save initial state (no backends yet)
 	s.saveState()
 
+	// Reload models from before a restart
+	s.restoreBackends()
+
 	return nil
 }
 
@@ -155,8 +245,14 @@ func (s *Server) Stop() error {
 		s.peerServer.Stop()
 	}
 
-	// Stop idle monitor
+	// Stop idle monitor and usage sampling
 	s.idleMonitor.Stop()
+	s.usage.Stop()
+
+	// Stop gRPC server (event streams end on shutdownChan)
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
 
 	// Stop all backends
 	s.manager.StopAllBackends()
@@ -182,21 +278,26 @@ func (s *Server) saveState() {
 	var backendStates []BackendState
 	for _, b := range backends {
 		if b.Status == "ready" || b.Status == "starting" {
+			kind, options := s.manager.loadSpec(b.ModelName)
 			backendStates = append(backendStates, BackendState{
 				ModelName: b.ModelName,
 				PID:       b.PID,
 				Port:      b.Port,
 				StartedAt: b.StartedAt,
+				Kind:      kind.String(),
+				Options:   options,
 			})
 		}
 	}
 
 	state := &ProxyState{
-		PID:       os.Getpid(),
-		Host:      s.config.Host,
-		Port:      s.config.Port,
-		StartedAt: s.startedAt,
-		Backends:  backendStates,
+		PID:        os.Getpid(),
+		Host:       s.config.Host,
+		Port:       s.config.Port,
+		StartedAt:  s.startedAt,
+		Backends:   backendStates,
+		Version:    version.Version,
+		Executable: s.executable,
 	}
 
 	if err := SaveProxyState(state); err != nil {
@@ -224,6 +325,11 @@ func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
 	s.proxyToBackend(w, r, "/v1/embeddings")
 }
 
+// handleImageGenerations proxies image generation requests to a stable-diffusion.cpp backend
+func (s *Server) handleImageGenerations(w http.ResponseWriter, r *http.Request) {
+	s.proxyToLoadedBackend(w, r, "/v1/images/generations", s.manager.GetOrLoadImageBackend)
+}
+
 // handleAnthropicMessages proxies Anthropic Messages API requests
 func (s *Server) handleAnthropicMessages(w http.ResponseWriter, r *http.Request) {
 	s.proxyToBackendAnthropic(w, r, "/v1/messages")
@@ -236,34 +342,114 @@ func (s *Server) handleAnthropicCountTokens(w http.ResponseWriter, r *http.Reque
 
 // proxyToBackend handles the common logic of extracting model and proxying
 func (s *Server) proxyToBackend(w http.ResponseWriter, r *http.Request, path string) {
+	// No options override for chat endpoint
+	s.proxyToLoadedBackend(w, r, path, func(model string) (*Backend, error) {
+		return s.loadWithDefault(model, func(model string) (*Backend, error) {
+			return s.manager.GetOrLoadBackend(model, nil)
+		})
+	})
+}
+
+// proxyToLoadedBackend proxies a request to the backend that load returns for
+// the request's model.
+func (s *Server) proxyToLoadedBackend(w http.ResponseWriter, r *http.Request, path string, load func(model string) (*Backend, error)) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
 		return
 	}
-
-	// Read and parse body to get model
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	client := s.matchClient(r)
+	if ok, wait := s.clients.allow(client, time.Now()); !ok {
+		w.Header().Set("Retry-After", retryAfterSeconds(wait))
+		s.writeErrorDetail(w, http.StatusTooManyRequests, OpenAIErrorDetail{
+			Message: fmt.Sprintf("Rate limit of %d requests per minute exceeded", client.RequestsPerMinute),
+			Type:    "rate_limit_error",
+			Code:    "rate_limit_exceeded",
+		})
 		return
 	}
-	r.Body.Close()
 
+	// Chat and completions requests may be rewritten, so they're read in
+	// full. Others only need their model and are streamed through.
+	rewrite := path == "/v1/chat/completions" || path == "/v1/completions"
+	var body []byte
 	var req struct {
 		Model string `json:"model"`
 	}
-	if err := json.Unmarshal(body, &req); err != nil {
-		s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
-		return
+	if rewrite {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+			return
+		}
+		r.Body.Close()
+
+		if path == "/v1/chat/completions" {
+			var perr *requestError
+			if body, perr = applyPersona(r.Header.Get(personaHeader), body); perr != nil {
+				s.writeRequestError(w, perr)
+				return
+			}
+		}
+
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+			return
+		}
+	} else {
+		var err error
+		if req.Model, err = peekRequestModel(r); errors.Is(err, errInvalidBody) {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+			return
+		} else if err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to read request body")
+			return
+		}
 	}
 
-	if req.Model == "" {
+	// Image generation needs an image model, so it never uses the default
+	if req.Model == "" && (s.config.DefaultModel == "" || path == "/v1/images/generations") {
 		s.writeError(w, http.StatusBadRequest, "invalid_request", "Model field is required")
 		return
 	}
 
-	// Get or load the backend (no options override for chat endpoint)
-	backend, err := s.manager.GetOrLoadBackend(req.Model, nil)
+	if path == "/v1/chat/completions" {
+		if err := validateChatRequest(body); err != nil {
+			s.writeRequestError(w, err)
+			return
+		}
+	}
+
+	choices := choiceRequest{n: 1, generate: 1}
+	var logprobs *logprobsRequest
+	if rewrite {
+		var err error
+		if body, err = applyClientLimits(client, body); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+			return
+		}
+		if body, err = applyOutputLimits(body, s.config.MaxOutput, s.config.DefaultTokens); err != nil {
+			s.writeError(w, http.StatusBadRequest, "invalid_request", "Failed to parse request body")
+			return
+		}
+		var cerr *requestError
+		if choices, cerr = parseChoiceRequest(path, body); cerr != nil {
+			s.writeRequestError(w, cerr)
+			return
+		}
+		body, logprobs = mapLogprobsRequest(path, body)
+	}
+	var toolNames []string
+	var repair bool
+	if path == "/v1/chat/completions" {
+		toolNames = requestToolNames(body)
+		repair = wantsJSONRepair(r, body, s.config.JSONRepair)
+	}
+
+	backend, err := s.restrictModels(client, load)(req.Model)
 	if err != nil {
 		s.handleModelError(w, err)
 		return
@@ -272,8 +458,18 @@ func (s *Server) proxyToBackend(w http.ResponseWriter, r *http.Request, path str
 	// Update activity
 	backend.UpdateActivity()
 
+	requestID := ensureRequestID(w, r)
+	logs.Debug("Proxying request", "request_id", requestID, "model", backend.ModelName, "path", path, "client", clientName(client))
+
 	// Proxy the request
 	backendURL := fmt.Sprintf("http://%s:%d", s.config.Host, backend.Port)
+	if sessionID := r.Header.Get(sessionHeader); sessionID != "" && rewrite && choices.generate == 1 {
+		body = pinSession(backend, backendURL, sessionID, body)
+	}
+	if choices.generate > 1 {
+		s.generateChoices(w, r, backendURL, path, body, choices, logprobs)
+		return
+	}
 	target, err := url.Parse(backendURL)
 	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, "server_error", "invalid backend URL")
@@ -281,15 +477,50 @@ func (s *Server) proxyToBackend(w http.ResponseWriter, r *http.Request, path str
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	// Streamed (SSE) responses are flushed event by event; others are
+	// written in full rather than flushed on every read from the backend
+	proxy.Transport = backendTransport
 
-	// Handle streaming responses properly
-	proxy.FlushInterval = -1 // Flush immediately for SSE
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		stripCORSHeaders(resp)
+		if logprobs != nil {
+			if err := logprobs.convertLogprobsResponse(resp); err != nil {
+				return err
+			}
+		}
+		if len(toolNames) > 0 {
+			err := rewriteJSONResponse(resp, func(body map[string]any) bool {
+				return splitToolCalls(body, toolNames)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if repair {
+			err := rewriteJSONResponse(resp, func(body map[string]any) bool {
+				if !repairChoices(body) {
+					return false
+				}
+				resp.Header.Set(jsonRepairedHeader, "true")
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return mapBackendError(resp)
+	}
 
-	proxy.ModifyResponse = stripCORSHeaders
+	// Handle backend errors
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		s.writeError(w, http.StatusBadGateway, "backend_error", "Backend server error: "+err.Error())
+	}
 
 	// Restore the body for the proxied request
-	r.Body = io.NopCloser(bytes.NewReader(body))
-	r.ContentLength = int64(len(body))
+	if rewrite {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+	}
 	r.URL.Path = path
 
 	proxy.ServeHTTP(w, r)
@@ -297,12 +528,22 @@ func (s *Server) proxyToBackend(w http.ResponseWriter, r *http.Request, path str
 
 // proxyToBackendAnthropic handles Anthropic API requests with proper error format
 func (s *Server) proxyToBackendAnthropic(w http.ResponseWriter, r *http.Request, path string) {
-	requestID := generateRequestID()
+	requestID := ensureRequestID(w, r)
 
 	if r.Method != http.MethodPost {
 		s.writeAnthropicError(w, requestID, http.StatusMethodNotAllowed, AnthropicInvalidRequest, "Only POST is allowed")
 		return
 	}
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	client := s.matchClient(r)
+	if ok, wait := s.clients.allow(client, time.Now()); !ok {
+		w.Header().Set("Retry-After", retryAfterSeconds(wait))
+		s.writeAnthropicError(w, requestID, http.StatusTooManyRequests, AnthropicRateLimit,
+			fmt.Sprintf("Rate limit of %d requests per minute exceeded", client.RequestsPerMinute))
+		return
+	}
 
 	// Read and parse body to get model
 	body, err := io.ReadAll(r.Body)
@@ -320,13 +561,38 @@ func (s *Server) proxyToBackendAnthropic(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if req.Model == "" {
+	if req.Model == "" && s.config.DefaultModel == "" {
 		s.writeAnthropicError(w, requestID, http.StatusBadRequest, AnthropicInvalidRequest, "model: Field required")
 		return
 	}
 
+	body, err = prepareAnthropicRequest(r, body)
+	if err != nil {
+		var ae *anthropicError
+		if errors.As(err, &ae) {
+			s.writeAnthropicError(w, requestID, http.StatusBadRequest, ae.errType, ae.message)
+		} else {
+			s.writeAnthropicError(w, requestID, http.StatusInternalServerError, AnthropicAPIError, "Internal server error")
+		}
+		return
+	}
+	if path == "/v1/messages" {
+		if body, err = applyClientLimits(client, body); err != nil {
+			s.writeAnthropicError(w, requestID, http.StatusBadRequest, AnthropicInvalidRequest, "Failed to parse request body as JSON")
+			return
+		}
+		if body, err = applyOutputLimits(body, s.config.MaxOutput, s.config.DefaultTokens); err != nil {
+			s.writeAnthropicError(w, requestID, http.StatusBadRequest, AnthropicInvalidRequest, "Failed to parse request body as JSON")
+			return
+		}
+	}
+
 	// Get or load the backend
-	backend, err := s.manager.GetOrLoadBackend(req.Model, nil)
+	backend, err := s.restrictModels(client, func(model string) (*Backend, error) {
+		return s.loadWithDefault(model, func(model string) (*Backend, error) {
+			return s.manager.GetOrLoadBackend(model, nil)
+		})
+	})(req.Model)
 	if err != nil {
 		s.handleAnthropicModelError(w, requestID, err)
 		return
@@ -335,8 +601,13 @@ func (s *Server) proxyToBackendAnthropic(w http.ResponseWriter, r *http.Request,
 	// Update activity
 	backend.UpdateActivity()
 
+	logs.Debug("Proxying request", "request_id", requestID, "model", backend.ModelName, "path", path, "client", clientName(client))
+
 	// Proxy the request
 	backendURL := fmt.Sprintf("http://%s:%d", s.config.Host, backend.Port)
+	if sessionID := r.Header.Get(sessionHeader); sessionID != "" && path == "/v1/messages" {
+		body = pinSession(backend, backendURL, sessionID, body)
+	}
 	target, err := url.Parse(backendURL)
 	if err != nil {
 		s.writeAnthropicError(w, requestID, http.StatusInternalServerError, AnthropicAPIError, "Internal server error")
@@ -344,9 +615,9 @@ func (s *Server) proxyToBackendAnthropic(w http.ResponseWriter, r *http.Request,
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(target)
-
-	// Handle streaming responses properly
-	proxy.FlushInterval = -1 // Flush immediately for SSE
+	// Streamed (SSE) responses are flushed event by event; others are
+	// written in full rather than flushed on every read from the backend
+	proxy.Transport = backendTransport
 
 	proxy.ModifyResponse = func(resp *http.Response) error {
 		resp.Header.Set("request-id", requestID)
@@ -369,15 +640,9 @@ func (s *Server) proxyToBackendAnthropic(w http.ResponseWriter, r *http.Request,
 	proxy.ServeHTTP(w, r)
 }
 
-// generateRequestID creates a unique request ID in Anthropic format
-func generateRequestID() string {
-	b := make([]byte, 12)
-	_, _ = rand.Read(b)
-	return "req_" + hex.EncodeToString(b)
-}
-
 // writeAnthropicError writes an Anthropic-compatible error response
 func (s *Server) writeAnthropicError(w http.ResponseWriter, requestID string, status int, errType AnthropicErrorType, message string) {
+	logs.Debug("Request failed", "request_id", requestID, "status", status, "error", message)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("request-id", requestID)
 	w.WriteHeader(status)
@@ -404,6 +669,17 @@ func (s *Server) handleAnthropicModelError(w http.ResponseWriter, requestID stri
 			msg += fmt.Sprintf(". Did you mean: %s", strings.Join(e.Suggestions, ", "))
 		}
 		s.writeAnthropicError(w, requestID, http.StatusNotFound, AnthropicNotFound, msg)
+	case *InsufficientMemoryError:
+		s.writeAnthropicError(w, requestID, http.StatusServiceUnavailable, AnthropicOverloaded, e.Error())
+	case *QuietHoursError:
+		setRetryAfter(w, e.Until)
+		s.writeAnthropicError(w, requestID, http.StatusServiceUnavailable, AnthropicOverloaded, e.Error())
+	case *IncompatibleModelError:
+		s.writeAnthropicError(w, requestID, http.StatusServiceUnavailable, AnthropicAPIError, e.Error())
+	case *ModelNotAllowedError:
+		s.writeAnthropicError(w, requestID, http.StatusForbidden, AnthropicPermission, fmt.Sprintf("Model '%s' is not allowed for this client", e.Model))
+	case *ModelBusyError:
+		s.writeAnthropicError(w, requestID, http.StatusConflict, AnthropicInvalidRequest, fmt.Sprintf("Model '%s' %s", e.Model, e.Reason))
 	default:
 		s.writeAnthropicError(w, requestID, http.StatusInternalServerError, AnthropicAPIError, err.Error())
 	}
@@ -416,63 +692,167 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resp := OpenAIModelsResponse{
+		Object: "list",
+		Data:   s.clientModels(r),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, resp)
+}
+
+// handleModel retrieves one model by ID. Names are resolved the same way as
+// in completion requests, so SDKs that check a model before using it accept
+// whatever the completion would.
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/models/")
+	if id == "" {
+		s.handleModels(w, r)
+		return
+	}
+
+	models := s.clientModels(r)
+	name := id
+	if !slices.ContainsFunc(models, func(m OpenAIModelInfo) bool { return m.ID == id }) {
+		result, err := s.manager.Resolver().Resolve(id)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		if result.Model == nil {
+			s.writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("The model '%s' does not exist", id))
+			return
+		}
+		name = result.Model.FullName
+	}
+
+	for _, m := range models {
+		if m.ID == name {
+			w.Header().Set("Content-Type", "application/json")
+			writeJSON(w, m)
+			return
+		}
+	}
+	s.writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("The model '%s' does not exist", id))
+}
+
+// clientModels returns listModels, leaving out models the request's client
+// may not use
+func (s *Server) clientModels(r *http.Request) []OpenAIModelInfo {
+	models := s.listModels()
+	client := s.matchClient(r)
+	if client == nil || len(client.Models) == 0 {
+		return models
+	}
+	return slices.DeleteFunc(models, func(m OpenAIModelInfo) bool {
+		return !clientAllowsModel(client, m.ID)
+	})
+}
+
+// listModels returns loaded models followed by downloaded ones that aren't
+// running, each with its metadata under the lleme extension object.
+func (s *Server) listModels() []OpenAIModelInfo {
 	backends := s.manager.ListBackends()
+	downloaded, _ := s.manager.Resolver().ListDownloadedModels()
+	downloadedByName := make(map[string]DownloadedModel, len(downloaded))
+	for _, d := range downloaded {
+		downloadedByName[d.FullName] = d
+	}
 
 	var models []OpenAIModelInfo
 	for _, b := range backends {
+		status := &LlemeStatus{
+			Status:       b.Status,
+			Port:         b.Port,
+			LastActivity: b.LastActivity,
+			LoadedAt:     b.StartedAt,
+		}
+		if user, repo, ok := splitModelName(b.ModelName); ok {
+			status.Tags, status.Note = modelAnnotations(user, repo)
+		}
+		if d, ok := downloadedByName[b.ModelName]; ok {
+			readModelDetails(d).apply(status, d.Quant)
+		}
 		models = append(models, OpenAIModelInfo{
 			ID:      b.ModelName,
 			Object:  "model",
 			Created: b.StartedAt.Unix(),
 			OwnedBy: "local",
-			Lleme: &LlemeStatus{
-				Status:       b.Status,
-				Port:         b.Port,
-				LastActivity: b.LastActivity,
-				LoadedAt:     b.StartedAt,
-			},
+			Lleme:   status,
 		})
 	}
 
 	// Also include downloaded but not loaded models
-	downloaded, _ := s.manager.Resolver().ListDownloadedModels()
 	loadedSet := make(map[string]bool)
 	for _, b := range backends {
 		loadedSet[b.ModelName] = true
 	}
 	for _, d := range downloaded {
 		if !loadedSet[d.FullName] {
+			status := &LlemeStatus{}
+			status.Tags, status.Note = modelAnnotations(d.User, d.Repo)
+			readModelDetails(d).apply(status, d.Quant)
 			models = append(models, OpenAIModelInfo{
 				ID:      d.FullName,
 				Object:  "model",
 				Created: 0,
 				OwnedBy: "local",
+				Lleme:   status,
 			})
 		}
 	}
 
-	resp := OpenAIModelsResponse{
-		Object: "list",
-		Data:   models,
-	}
+	return models
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, resp)
+// modelAnnotations returns the user's tags and note for a model repo.
+func modelAnnotations(user, repo string) ([]string, string) {
+	meta, err := hf.LoadMetadata(user, repo)
+	if err != nil {
+		return nil, ""
+	}
+	return meta.Tags, meta.Note
 }
 
-// handleHealth returns basic health status
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, map[string]string{"status": "ok"})
+// splitModelName splits "user/repo:quant" into user and repo.
+func splitModelName(name string) (user, repo string, ok bool) {
+	userRepo, _, _ := strings.Cut(name, ":")
+	user, repo, ok = strings.Cut(userRepo, "/")
+	return user, repo, ok
 }
 
-// handleStatus returns detailed proxy status
+// handleStatus returns detailed proxy status. With ?wait=30s&etag=<etag>
+// it long-polls: the response comes as soon as the status differs from the
+// one etag was taken from, or when wait runs out.
 func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
 		return
 	}
 
+	wait, err := parseStatusWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	status := s.status()
+	if etag := r.URL.Query().Get("etag"); wait > 0 && etag == status.ETag {
+		status = s.waitForStatusChange(r.Context(), etag, wait)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", `"`+status.ETag+`"`)
+	writeJSON(w, status)
+}
+
+// status snapshots the proxy's state
+func (s *Server) status() ProxyStatus {
 	backends := s.manager.ListBackends()
 
 	status := ProxyStatus{
@@ -483,15 +863,22 @@ func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 		MaxModels:     s.config.MaxModels,
 		LoadedCount:   len(backends),
 		IdleTimeout:   s.config.IdleTimeout.String(),
+		Active:        s.active.Load(),
 		Models:        backends,
+		Power:         s.manager.PowerStatus(),
+		QuietHours:    s.manager.QuietHoursStatus(),
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	writeJSON(w, status)
+	status.ETag = statusETag(status)
+	return status
 }
 
 // handleModelError converts model errors to appropriate HTTP responses
 func (s *Server) handleModelError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrSDNotInstalled) {
+		s.writeError(w, http.StatusServiceUnavailable, "backend_not_installed", err.Error())
+		return
+	}
+
 	switch e := err.(type) {
 	case *AmbiguousModelError:
 		msg := fmt.Sprintf("Ambiguous model name '%s'. Matches: %s",
@@ -503,6 +890,17 @@ func (s *Server) handleModelError(w http.ResponseWriter, err error) {
 			msg += fmt.Sprintf(". Did you mean: %s", strings.Join(e.Suggestions, ", "))
 		}
 		s.writeError(w, http.StatusNotFound, "not_found", msg)
+	case *InsufficientMemoryError:
+		s.writeError(w, http.StatusServiceUnavailable, "insufficient_memory", e.Error())
+	case *QuietHoursError:
+		setRetryAfter(w, e.Until)
+		s.writeError(w, http.StatusServiceUnavailable, "quiet_hours", e.Error())
+	case *IncompatibleModelError:
+		s.writeError(w, http.StatusServiceUnavailable, "backend_outdated", e.Error())
+	case *ModelNotAllowedError:
+		s.writeError(w, http.StatusForbidden, "permission_error", fmt.Sprintf("Model '%s' is not allowed for this client", e.Model))
+	case *ModelBusyError:
+		s.writeError(w, http.StatusConflict, "model_in_use", fmt.Sprintf("Model '%s' %s", e.Model, e.Reason))
 	default:
 		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 	}
@@ -516,19 +914,63 @@ func writeJSON(w http.ResponseWriter, v any) {
 	}
 }
 
-// writeError writes an OpenAI-compatible error response
+// rewriteJSONResponse lets fn edit a successful JSON backend response. The
+// body is re-encoded only when fn reports a change.
+func rewriteJSONResponse(resp *http.Response, fn func(map[string]any) bool) error {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	var body map[string]any
+	if json.Unmarshal(data, &body) == nil && fn(body) {
+		if changed, err := json.Marshal(body); err == nil {
+			data = changed
+		}
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// writeError writes an OpenAI-compatible error response, tagged with the
+// request ID when one was assigned
 func (s *Server) writeError(w http.ResponseWriter, status int, errType, message string) {
+	s.writeErrorDetail(w, status, OpenAIErrorDetail{Message: message, Type: errType})
+}
+
+// writeRequestError rejects a request that failed validation
+func (s *Server) writeRequestError(w http.ResponseWriter, err *requestError) {
+	s.writeErrorDetail(w, http.StatusBadRequest, OpenAIErrorDetail{
+		Message: err.message,
+		Type:    "invalid_request_error",
+		Code:    err.code,
+		Param:   err.param,
+	})
+}
+
+// writeErrorDetail is writeError for errors that also carry a code or param
+func (s *Server) writeErrorDetail(w http.ResponseWriter, status int, detail OpenAIErrorDetail) {
+	requestID := w.Header().Get(requestIDHeader)
+	if requestID != "" {
+		logs.Debug("Request failed", "request_id", requestID, "status", status, "error", detail.Message)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	writeJSON(w, OpenAIError{
-		Error: OpenAIErrorDetail{
-			Message: message,
-			Type:    errType,
-		},
+		Error:     detail,
+		RequestID: requestID,
 	})
 }
 
-// handleRun loads a model with optional server options
+// handleRun loads a model with optional server options, or with dry_run
+// reports the command that would start it
 func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only POST is allowed")
@@ -546,27 +988,32 @@ func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Build options map: start with additional options, then explicit fields override
-	options := make(map[string]any)
-	// First, copy additional options (e.g., from persona)
-	// Normalize keys to hyphens (llama-server CLI format)
-	for k, v := range req.Options {
-		normalizedKey := strings.ReplaceAll(k, "_", "-")
-		options[normalizedKey] = v
-	}
-	// Explicit fields override additional options (CLI flags > persona options)
-	if req.CtxSize != nil {
-		options["ctx-size"] = *req.CtxSize
-	}
-	if req.GpuLayers != nil {
-		options["gpu-layers"] = *req.GpuLayers
+	options, err := req.LaunchOptions()
+	if err != nil {
+		s.writeError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
 	}
-	if req.Threads != nil {
-		options["threads"] = *req.Threads
+
+	client := s.matchClient(r)
+	if req.DryRun {
+		model, launch, err := s.manager.PlanLaunch(req.Model, options)
+		if err == nil && !clientAllowsModel(client, model) {
+			err = &ModelNotAllowedError{Client: client.Name, Model: model}
+		}
+		if err != nil {
+			s.handleModelError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, RunResponse{Success: true, Model: model, Status: "dry_run", Launch: launch})
+		return
 	}
 
 	// Load the backend with options
-	backend, err := s.manager.GetOrLoadBackend(req.Model, options)
+	backend, err := s.restrictModels(client, func(model string) (*Backend, error) {
+		return s.manager.GetOrLoadBackend(model, options)
+	})(req.Model)
+	s.audit(r, "run", map[string]any{"model": req.Model, "options": options}, err)
 	if err != nil {
 		s.handleModelError(w, err)
 		return
@@ -614,6 +1061,10 @@ func (s *Server) handleStopModel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	modelName := result.Model.FullName
+	if client := s.matchClient(r); !clientAllowsModel(client, modelName) {
+		s.handleModelError(w, &ModelNotAllowedError{Client: client.Name, Model: modelName})
+		return
+	}
 
 	// Check if it's actually loaded
 	if backend := s.manager.GetBackend(modelName); backend == nil {
@@ -622,7 +1073,9 @@ func (s *Server) handleStopModel(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Stop the backend
-	if err := s.manager.StopBackend(modelName); err != nil {
+	err = s.manager.StopBackend(modelName)
+	s.audit(r, "stop", map[string]any{"model": modelName}, err)
+	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 		return
 	}
@@ -641,8 +1094,16 @@ func (s *Server) handleStopAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A client limited to some models can't stop everyone else's
+	if client := s.matchClient(r); client != nil && len(client.Models) > 0 {
+		s.writeError(w, http.StatusForbidden, "permission_error", "This client may only stop the models it's allowed to use")
+		return
+	}
+
 	count := s.manager.LoadedCount()
-	if err := s.manager.StopAllBackends(); err != nil {
+	err := s.manager.StopAllBackends()
+	s.audit(r, "stop-all", map[string]any{"stopped": count}, err)
+	if err != nil {
 		s.writeError(w, http.StatusInternalServerError, "server_error", err.Error())
 		return
 	}
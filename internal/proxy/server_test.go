@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/nchapman/lleme/internal/mock"
 )
 
 func TestGenerateRequestID(t *testing.T) {
@@ -216,3 +218,121 @@ func TestOpenAIEndpointReturnsOpenAIErrors(t *testing.T) {
 		t.Errorf("expected OpenAI error type 'invalid_request', got '%s'", resp.Error.Type)
 	}
 }
+
+func TestImageGenerationsWithoutSD(t *testing.T) {
+	useTestHome(t)
+	s := &Server{config: DefaultConfig(), manager: NewModelManager(DefaultConfig(), nil)}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/images/generations", bytes.NewBufferString(`{"model": "sd-turbo"}`))
+	w := httptest.NewRecorder()
+
+	s.handleImageGenerations(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	var resp OpenAIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal as OpenAI error: %v", err)
+	}
+	if resp.Error.Type != "backend_not_installed" {
+		t.Errorf("expected error type 'backend_not_installed', got '%s'", resp.Error.Type)
+	}
+}
+
+func TestSplitModelName(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantUser string
+		wantRepo string
+		wantOK   bool
+	}{
+		{"user/repo:Q4_K_M", "user", "repo", true},
+		{"user/repo", "user", "repo", true},
+		{"repo:Q4_K_M", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, repo, ok := splitModelName(tt.name)
+			if ok != tt.wantOK || (ok && (user != tt.wantUser || repo != tt.wantRepo)) {
+				t.Errorf("splitModelName(%q) = %q, %q, %v; want %q, %q, %v",
+					tt.name, user, repo, ok, tt.wantUser, tt.wantRepo, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestChatCompletionsWithMockBackend(t *testing.T) {
+	s := setupMockModel(t)
+
+	body := `{"model": "tiny", "messages": [{"role": "user", "content": "hello"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	s.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != mock.Reply("hello") {
+		t.Errorf("expected reply %q, got %s", mock.Reply("hello"), w.Body.String())
+	}
+	if s.manager.LoadedCount() != 1 {
+		t.Errorf("expected 1 loaded backend, got %d", s.manager.LoadedCount())
+	}
+}
+
+func TestHandleModel(t *testing.T) {
+	useTestHome(t)
+	m := NewModelManager(DefaultConfig(), nil)
+	m.resolver = setupTestModels(t)
+	s := &Server{config: DefaultConfig(), manager: m}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantID     string
+	}{
+		{"/v1/models/bartowski/Llama-3.2-3B-Instruct-GGUF:Q8_0", http.StatusOK, "bartowski/Llama-3.2-3B-Instruct-GGUF:Q8_0"},
+		{"/v1/models/phi-2", http.StatusOK, "microsoft/phi-2-gguf:Q4_0"},
+		{"/v1/models/nonexistent-model-xyz", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			s.handleModel(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantID == "" {
+				return
+			}
+			var info OpenAIModelInfo
+			if err := json.Unmarshal(w.Body.Bytes(), &info); err != nil {
+				t.Fatal(err)
+			}
+			if info.ID != tt.wantID || info.Object != "model" {
+				t.Errorf("model = %+v, want %s", info, tt.wantID)
+			}
+			if info.Lleme == nil || info.Lleme.SizeBytes != 4 {
+				t.Errorf("lleme = %+v, want enrichment", info.Lleme)
+			}
+		})
+	}
+}
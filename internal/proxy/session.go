@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+// sessionHeader names a conversation. Its requests all go to the same
+// llama-server slot, so each turn reuses the KV cache the last one left
+// instead of recomputing the prompt after another conversation took it.
+const sessionHeader = "X-LLeme-Session"
+
+// maxSessions bounds the sessions a backend remembers; the least recently
+// used are forgotten first.
+const maxSessions = 1024
+
+type session struct {
+	slot     int
+	lastUsed time.Time
+}
+
+// sessionSlots pins sessions to a backend's slots. A backend has one slot
+// per request it serves in parallel, each with its own KV cache.
+type sessionSlots struct {
+	slots    int // From the backend's /props; 0 until known
+	sessions map[string]*session
+}
+
+// sessionSlot returns the slot for session id on the backend at url,
+// assigning the slot with the fewest sessions to a new one. It reports
+// false when the backend has a single slot, where pinning gains nothing.
+func (b *Backend) sessionSlot(url, id string) (int, bool) {
+	b.slotsOnce.Do(func() {
+		n, err := fetchTotalSlots(url)
+		if err != nil {
+			logs.Debug("Failed to read backend slots", "model", b.ModelName, "error", err)
+		}
+		b.mu.Lock()
+		b.sessions.slots = n
+		b.mu.Unlock()
+	})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ss := &b.sessions
+	if ss.slots < 2 {
+		return 0, false
+	}
+	now := b.now()
+	if s, ok := ss.sessions[id]; ok {
+		s.lastUsed = now
+		return s.slot, true
+	}
+
+	if ss.sessions == nil {
+		ss.sessions = make(map[string]*session)
+	}
+	if len(ss.sessions) >= maxSessions {
+		var oldest string
+		for key, s := range ss.sessions {
+			if oldest == "" || s.lastUsed.Before(ss.sessions[oldest].lastUsed) {
+				oldest = key
+			}
+		}
+		delete(ss.sessions, oldest)
+	}
+
+	counts := make([]int, ss.slots)
+	for _, s := range ss.sessions {
+		counts[s.slot]++
+	}
+	slot := 0
+	for i, n := range counts {
+		if n < counts[slot] {
+			slot = i
+		}
+	}
+	ss.sessions[id] = &session{slot: slot, lastUsed: now}
+	return slot, true
+}
+
+// pinSession points body at the backend slot kept for sessionID, leaving
+// it unchanged when the backend can't pin sessions.
+func pinSession(backend *Backend, url, sessionID string, body []byte) []byte {
+	slot, ok := backend.sessionSlot(url, sessionID)
+	if !ok {
+		return body
+	}
+	pinned, err := applySessionSlot(body, slot)
+	if err != nil {
+		return body
+	}
+	logs.Debug("Pinned session to slot", "model", backend.ModelName, "session", sessionID, "slot", slot)
+	return pinned
+}
+
+// fetchTotalSlots asks llama-server how many requests it serves at once
+func fetchTotalSlots(url string) (int, error) {
+	resp, err := backendClient(2 * time.Second).Get(url + "/props")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	var props struct {
+		TotalSlots int `json:"total_slots"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&props); err != nil {
+		return 0, err
+	}
+	return props.TotalSlots, nil
+}
+
+// applySessionSlot sets llama-server's id_slot in a request body, unless
+// the client chose a slot itself.
+func applySessionSlot(body []byte, slot int) ([]byte, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	if _, ok := req["id_slot"]; ok {
+		return body, nil
+	}
+	req["id_slot"] = slot
+	return json.Marshal(req)
+}
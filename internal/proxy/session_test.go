@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func propsServer(t *testing.T, slots int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"total_slots":%d}`, slots)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestSessionSlot(t *testing.T) {
+	url := propsServer(t, 3)
+	b := &Backend{ModelName: "user/repo:Q4_K_M"}
+
+	// New sessions spread over the slots, and each keeps its own
+	got := map[string]int{}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		slot, ok := b.sessionSlot(url, id)
+		if !ok {
+			t.Fatalf("sessionSlot(%q) not pinned", id)
+		}
+		got[id] = slot
+	}
+	if got["a"] == got["b"] || got["b"] == got["c"] || got["a"] == got["c"] {
+		t.Errorf("first three sessions share slots: %v", got)
+	}
+	for id, want := range got {
+		if slot, _ := b.sessionSlot(url, id); slot != want {
+			t.Errorf("session %q moved from slot %d to %d", id, want, slot)
+		}
+	}
+}
+
+func TestSessionSlotSingleSlot(t *testing.T) {
+	b := &Backend{}
+	if _, ok := b.sessionSlot(propsServer(t, 1), "a"); ok {
+		t.Error("pinned a session on a backend with one slot")
+	}
+}
+
+func TestSessionSlotForgetsOldest(t *testing.T) {
+	b := &Backend{}
+	url := propsServer(t, 2)
+	for i := range maxSessions + 1 {
+		b.sessionSlot(url, fmt.Sprint(i))
+	}
+	if n := len(b.sessions.sessions); n != maxSessions {
+		t.Errorf("remembered %d sessions, want %d", n, maxSessions)
+	}
+	if _, ok := b.sessions.sessions["0"]; ok {
+		t.Error("oldest session wasn't forgotten")
+	}
+}
+
+func TestApplySessionSlot(t *testing.T) {
+	body, err := applySessionSlot([]byte(`{"model":"m"}`), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req map[string]any
+	json.Unmarshal(body, &req)
+	if req["id_slot"] != float64(2) {
+		t.Errorf("id_slot = %v, want 2", req["id_slot"])
+	}
+
+	// A slot the client chose wins
+	body, _ = applySessionSlot([]byte(`{"model":"m","id_slot":0}`), 2)
+	json.Unmarshal(body, &req)
+	if req["id_slot"] != float64(0) {
+		t.Errorf("id_slot = %v, want 0", req["id_slot"])
+	}
+}
+
+func TestChatCompletionsWithSession(t *testing.T) {
+	s := setupMockModel(t)
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"tiny","messages":[{"role":"user","content":"hello"}]}`))
+		req.Header.Set(sessionHeader, "conversation-1")
+		w := httptest.NewRecorder()
+		s.handleChatCompletions(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	backend := s.manager.backends["test/tiny-GGUF:Q4_K_M"]
+	if backend == nil {
+		t.Fatalf("backend not loaded: %v", s.manager.backends)
+	}
+	if n := len(backend.sessions.sessions); n != 1 {
+		t.Errorf("backend has %d sessions, want 1", n)
+	}
+}
@@ -2,27 +2,39 @@ package proxy
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/clock"
 	"github.com/nchapman/lleme/internal/fileutil"
 	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+	"github.com/nchapman/lleme/internal/process"
 )
 
 const proxyStateFile = "proxy-state.json"
 
+// ErrCorruptState means the state file exists but can't be parsed, as when
+// an older release was cut off mid-write. The proxy it describes has to be
+// found some other way, such as by its port.
+var ErrCorruptState = errors.New("proxy state file is corrupt")
+
 // BackendState persists backend process info for orphan cleanup
 type BackendState struct {
 	ModelName string    `json:"model_name"`
 	PID       int       `json:"pid"`
 	Port      int       `json:"port"`
 	StartedAt time.Time `json:"started_at"`
+
+	// How the model was loaded, so a restarted server can load it the
+	// same way
+	Kind    string         `json:"kind,omitempty"`
+	Options map[string]any `json:"options,omitempty"`
 }
 
 // ProxyState persists proxy metadata for CLI commands to discover
@@ -32,16 +44,70 @@ type ProxyState struct {
 	Port      int            `json:"port"`
 	StartedAt time.Time      `json:"started_at"`
 	Backends  []BackendState `json:"backends,omitempty"`
+
+	// The lleme build serving, for noticing upgrades
+	Version    string      `json:"version,omitempty"`
+	Executable *Executable `json:"executable,omitempty"`
 }
 
 // ProxyStatePath returns the path to the proxy state file
 func ProxyStatePath() string {
-	return filepath.Join(config.PidsPath(), proxyStateFile)
+	return filepath.Join(paths.Pids(), proxyStateFile)
+}
+
+// StateStore reads and writes the proxy state file. The package-level state
+// functions use one on the real filesystem and clock; embedders and tests
+// can build their own with NewStateStore.
+type StateStore struct {
+	path  string
+	fs    fileutil.FS
+	clock clock.Clock
+}
+
+// NewStateStore returns a store for the state file at path
+func NewStateStore(path string, fsys fileutil.FS, clk clock.Clock) *StateStore {
+	return &StateStore{path: path, fs: fsys, clock: clk}
+}
+
+// defaultStateStore is the store in the pids directory
+func defaultStateStore() *StateStore {
+	return NewStateStore(ProxyStatePath(), fileutil.OS, clock.System)
 }
 
 // SaveProxyState saves the proxy state to disk using atomic writes
 func SaveProxyState(state *ProxyState) error {
-	if err := os.MkdirAll(filepath.Dir(ProxyStatePath()), 0755); err != nil {
+	return defaultStateStore().Save(state)
+}
+
+// LoadProxyState loads the proxy state from disk
+func LoadProxyState() (*ProxyState, error) {
+	return defaultStateStore().Load()
+}
+
+// ClearProxyState removes the proxy state file
+func ClearProxyState() error {
+	return defaultStateStore().Clear()
+}
+
+// IsProxyRunning checks if the proxy is running based on saved state
+func IsProxyRunning() bool {
+	return defaultStateStore().IsRunning()
+}
+
+// GetRunningProxyState returns the proxy state if the proxy is running
+func GetRunningProxyState() *ProxyState {
+	return defaultStateStore().Running()
+}
+
+// CleanupOrphanedBackends kills any orphaned backend server processes from a previous
+// proxy instance that crashed. Returns the number of processes killed.
+func CleanupOrphanedBackends() int {
+	return defaultStateStore().CleanupOrphanedBackends()
+}
+
+// Save writes the proxy state atomically
+func (s *StateStore) Save(state *ProxyState) error {
+	if err := s.fs.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
 		return fmt.Errorf("failed to create state directory: %w", err)
 	}
 
@@ -50,16 +116,17 @@ func SaveProxyState(state *ProxyState) error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	if err := fileutil.AtomicWriteFile(ProxyStatePath(), data, 0644); err != nil {
+	if err := s.fs.WriteFile(s.path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write state: %w", err)
 	}
 
 	return nil
 }
 
-// LoadProxyState loads the proxy state from disk
-func LoadProxyState() (*ProxyState, error) {
-	data, err := os.ReadFile(ProxyStatePath())
+// Load reads the proxy state, or returns nil if there is none. A file that
+// can't be parsed returns ErrCorruptState.
+func (s *StateStore) Load() (*ProxyState, error) {
+	data, err := s.fs.ReadFile(s.path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -69,23 +136,23 @@ func LoadProxyState() (*ProxyState, error) {
 
 	var state ProxyState
 	if err := json.Unmarshal(data, &state); err != nil {
-		return nil, fmt.Errorf("failed to parse state: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrCorruptState, err)
 	}
 
 	return &state, nil
 }
 
-// ClearProxyState removes the proxy state file
-func ClearProxyState() error {
-	if err := os.Remove(ProxyStatePath()); err != nil && !os.IsNotExist(err) {
+// Clear removes the proxy state
+func (s *StateStore) Clear() error {
+	if err := s.fs.Remove(s.path); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 	return nil
 }
 
-// IsProxyRunning checks if the proxy is running based on saved state
-func IsProxyRunning() bool {
-	state, err := LoadProxyState()
+// IsRunning checks if the proxy the state describes is running
+func (s *StateStore) IsRunning() bool {
+	state, err := s.Load()
 	if err != nil || state == nil {
 		return false
 	}
@@ -93,9 +160,9 @@ func IsProxyRunning() bool {
 	return isProcessRunning(state.PID)
 }
 
-// GetRunningProxyState returns the proxy state if the proxy is running
-func GetRunningProxyState() *ProxyState {
-	state, err := LoadProxyState()
+// Running returns the proxy state if the proxy is running
+func (s *StateStore) Running() *ProxyState {
+	state, err := s.Load()
 	if err != nil || state == nil {
 		return nil
 	}
@@ -128,10 +195,10 @@ func GetProxyURL() string {
 	return fmt.Sprintf("http://%s:%d", state.Host, state.Port)
 }
 
-// CleanupOrphanedBackends kills any orphaned llama-server processes from a previous
-// proxy instance that crashed. Returns the number of processes killed.
-func CleanupOrphanedBackends() int {
-	state, err := LoadProxyState()
+// CleanupOrphanedBackends kills the backends of a crashed proxy and clears
+// its state. Returns the number of processes killed.
+func (s *StateStore) CleanupOrphanedBackends() int {
+	state, err := s.Load()
 	if err != nil || state == nil {
 		return 0
 	}
@@ -151,42 +218,42 @@ func CleanupOrphanedBackends() int {
 			continue
 		}
 
-		// Verify this is actually a llama-server process
-		if !isLlamaServerProcess(backend.PID) {
+		// Verify this is actually a backend server process
+		if !isBackendServerProcess(backend.PID) {
 			continue
 		}
 
 		// Kill the orphaned backend
-		if killProcess(backend.PID) {
+		if killProcess(backend.PID, s.clock) {
 			logs.Info("Cleaned up orphaned backend", "model", backend.ModelName, "pid", backend.PID)
 			killed++
 		}
 	}
 
 	// Clean up stale state file since proxy is dead
-	ClearProxyState()
+	s.Clear()
 
 	return killed
 }
 
-// isLlamaServerProcess checks if the given PID is a llama-server process.
-// Uses ps command which works on both Linux and macOS.
-func isLlamaServerProcess(pid int) bool {
-	cmd := exec.Command("ps", "-p", fmt.Sprintf("%d", pid), "-o", "comm=")
-	output, err := cmd.Output()
+// isBackendServerProcess checks if the given PID is a llama-server or sd-server process.
+func isBackendServerProcess(pid int) bool {
+	table, err := process.Snapshot()
 	if err != nil {
 		return false
 	}
-	return containsLlamaServer(string(output))
+	p, ok := table.Get(pid)
+	return ok && containsBackendServer(p.Name)
 }
 
-// containsLlamaServer checks if a command line contains llama-server
-func containsLlamaServer(cmdline string) bool {
-	return strings.Contains(cmdline, "llama-server") || strings.Contains(cmdline, "llama_server")
+// containsBackendServer checks if a command line contains llama-server or sd-server
+func containsBackendServer(cmdline string) bool {
+	return strings.Contains(cmdline, "llama-server") || strings.Contains(cmdline, "llama_server") ||
+		strings.Contains(cmdline, "sd-server")
 }
 
 // killProcess sends SIGTERM, waits briefly, then SIGKILL if needed
-func killProcess(pid int) bool {
+func killProcess(pid int, clk clock.Clock) bool {
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		return false
@@ -199,7 +266,7 @@ func killProcess(pid int) bool {
 
 	// Wait up to 2 seconds for graceful exit
 	for range 20 {
-		time.Sleep(100 * time.Millisecond)
+		clk.Sleep(100 * time.Millisecond)
 		if !isProcessRunning(pid) {
 			return true
 		}
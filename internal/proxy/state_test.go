@@ -2,11 +2,20 @@ package proxy
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"testing"
 	"time"
+
+	"github.com/nchapman/lleme/internal/clock"
+	"github.com/nchapman/lleme/internal/fileutil"
 )
 
+// newTestStateStore returns a state store kept in memory
+func newTestStateStore() *StateStore {
+	return NewStateStore("/pids/proxy-state.json", fileutil.NewMemFS(), clock.NewFake(time.Now()))
+}
+
 // useTestHome sets LLEME_HOME to a temp directory for the duration of the test.
 func useTestHome(t *testing.T) {
 	t.Helper()
@@ -116,7 +125,7 @@ func TestProxyStateBackendsOmitEmpty(t *testing.T) {
 }
 
 func TestSaveLoadClearProxyState(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
 	now := time.Now().Truncate(time.Second)
 	state := &ProxyState{
@@ -134,11 +143,11 @@ func TestSaveLoadClearProxyState(t *testing.T) {
 		},
 	}
 
-	if err := SaveProxyState(state); err != nil {
+	if err := store.Save(state); err != nil {
 		t.Fatalf("SaveProxyState failed: %v", err)
 	}
 
-	loaded, err := LoadProxyState()
+	loaded, err := store.Load()
 	if err != nil {
 		t.Fatalf("LoadProxyState failed: %v", err)
 	}
@@ -163,11 +172,11 @@ func TestSaveLoadClearProxyState(t *testing.T) {
 	}
 
 	// Test clear
-	if err := ClearProxyState(); err != nil {
+	if err := store.Clear(); err != nil {
 		t.Fatalf("ClearProxyState failed: %v", err)
 	}
 
-	loaded, err = LoadProxyState()
+	loaded, err = store.Load()
 	if err != nil {
 		t.Fatalf("LoadProxyState after clear failed: %v", err)
 	}
@@ -177,9 +186,9 @@ func TestSaveLoadClearProxyState(t *testing.T) {
 }
 
 func TestLoadProxyStateNonExistent(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
-	state, err := LoadProxyState()
+	state, err := store.Load()
 	if err != nil {
 		t.Fatalf("LoadProxyState should not error on non-existent file: %v", err)
 	}
@@ -188,6 +197,34 @@ func TestLoadProxyStateNonExistent(t *testing.T) {
 	}
 }
 
+func TestLoadProxyStateCorrupt(t *testing.T) {
+	for name, data := range map[string]string{
+		"empty":     "",
+		"truncated": `{"pid": 1234, "host": "127.0.0.1", "po`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			store := newTestStateStore()
+			if err := store.fs.WriteFile(store.path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			state, err := store.Load()
+			if !errors.Is(err, ErrCorruptState) {
+				t.Errorf("Load() error = %v, want ErrCorruptState", err)
+			}
+			if state != nil {
+				t.Errorf("Load() = %+v, want nil", state)
+			}
+			if store.Running() != nil {
+				t.Error("Running() returned state from a corrupt file")
+			}
+			if n := store.CleanupOrphanedBackends(); n != 0 {
+				t.Errorf("CleanupOrphanedBackends() = %d, want 0", n)
+			}
+		})
+	}
+}
+
 func TestIsProcessRunning(t *testing.T) {
 	// Current process should be running
 	if !isProcessRunning(os.Getpid()) {
@@ -208,7 +245,7 @@ func TestIsProcessRunning(t *testing.T) {
 	}
 }
 
-func TestContainsLlamaServer(t *testing.T) {
+func TestContainsBackendServer(t *testing.T) {
 	tests := []struct {
 		name     string
 		cmdline  string
@@ -234,6 +271,11 @@ func TestContainsLlamaServer(t *testing.T) {
 			cmdline:  "",
 			expected: false,
 		},
+		{
+			name:     "sd-server",
+			cmdline:  "/home/user/.lleme/bin/sd-current/sd-server\x00--listen-port\x0049153",
+			expected: true,
+		},
 		{
 			name:     "llama-server as argument",
 			cmdline:  "/bin/sh\x00-c\x00llama-server --model test",
@@ -243,25 +285,25 @@ func TestContainsLlamaServer(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := containsLlamaServer(tt.cmdline)
+			result := containsBackendServer(tt.cmdline)
 			if result != tt.expected {
-				t.Errorf("containsLlamaServer(%q) = %v, want %v", tt.cmdline, result, tt.expected)
+				t.Errorf("containsBackendServer(%q) = %v, want %v", tt.cmdline, result, tt.expected)
 			}
 		})
 	}
 }
 
 func TestCleanupOrphanedBackendsNoState(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
-	killed := CleanupOrphanedBackends()
+	killed := store.CleanupOrphanedBackends()
 	if killed != 0 {
 		t.Errorf("expected 0 killed with no state, got %d", killed)
 	}
 }
 
 func TestCleanupOrphanedBackendsProxyStillRunning(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
 	state := &ProxyState{
 		PID:       os.Getpid(), // Current process - "running"
@@ -277,25 +319,25 @@ func TestCleanupOrphanedBackendsProxyStillRunning(t *testing.T) {
 			},
 		},
 	}
-	if err := SaveProxyState(state); err != nil {
+	if err := store.Save(state); err != nil {
 		t.Fatalf("SaveProxyState failed: %v", err)
 	}
 
 	// Should not kill anything since proxy is "running"
-	killed := CleanupOrphanedBackends()
+	killed := store.CleanupOrphanedBackends()
 	if killed != 0 {
 		t.Errorf("expected 0 killed when proxy is running, got %d", killed)
 	}
 
 	// State should still exist
-	loaded, _ := LoadProxyState()
+	loaded, _ := store.Load()
 	if loaded == nil {
 		t.Error("state should still exist when proxy is running")
 	}
 }
 
 func TestCleanupOrphanedBackendsStaleState(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
 	state := &ProxyState{
 		PID:       9999999, // Non-existent process
@@ -311,29 +353,29 @@ func TestCleanupOrphanedBackendsStaleState(t *testing.T) {
 			},
 		},
 	}
-	if err := SaveProxyState(state); err != nil {
+	if err := store.Save(state); err != nil {
 		t.Fatalf("SaveProxyState failed: %v", err)
 	}
 
 	// Should clean up stale state
-	killed := CleanupOrphanedBackends()
+	killed := store.CleanupOrphanedBackends()
 	// Backend PID doesn't exist, so nothing to kill
 	if killed != 0 {
 		t.Errorf("expected 0 killed for non-existent PIDs, got %d", killed)
 	}
 
 	// State should be cleared
-	loaded, _ := LoadProxyState()
+	loaded, _ := store.Load()
 	if loaded != nil {
 		t.Error("stale state should be cleared")
 	}
 }
 
 func TestGetRunningProxyState(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
 	// No state should return nil
-	if state := GetRunningProxyState(); state != nil {
+	if state := store.Running(); state != nil {
 		t.Error("expected nil with no state")
 	}
 
@@ -344,12 +386,12 @@ func TestGetRunningProxyState(t *testing.T) {
 		Port:      11313,
 		StartedAt: time.Now(),
 	}
-	if err := SaveProxyState(state); err != nil {
+	if err := store.Save(state); err != nil {
 		t.Fatalf("SaveProxyState failed: %v", err)
 	}
 
 	// Should return state since process is running
-	got := GetRunningProxyState()
+	got := store.Running()
 	if got == nil {
 		t.Fatal("expected non-nil state")
 	}
@@ -359,7 +401,7 @@ func TestGetRunningProxyState(t *testing.T) {
 }
 
 func TestGetRunningProxyStateStale(t *testing.T) {
-	useTestHome(t)
+	store := newTestStateStore()
 
 	state := &ProxyState{
 		PID:       9999999,
@@ -367,18 +409,18 @@ func TestGetRunningProxyStateStale(t *testing.T) {
 		Port:      11313,
 		StartedAt: time.Now(),
 	}
-	if err := SaveProxyState(state); err != nil {
+	if err := store.Save(state); err != nil {
 		t.Fatalf("SaveProxyState failed: %v", err)
 	}
 
 	// Should return nil for stale state
-	got := GetRunningProxyState()
+	got := store.Running()
 	if got != nil {
 		t.Error("expected nil for stale state")
 	}
 
 	// State file should still exist - cleanup happens via CleanupOrphanedBackends
-	loaded, _ := LoadProxyState()
+	loaded, _ := store.Load()
 	if loaded == nil {
 		t.Error("state file should still exist (cleanup is done by CleanupOrphanedBackends)")
 	}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/version"
+)
+
+// statusPageTemplate renders the status page for browsers. It refreshes
+// itself so it can be left open on a dashboard.
+var statusPageTemplate = template.Must(template.New("status").Funcs(template.FuncMap{
+	"uptime": func(secs float64) string {
+		return (time.Duration(secs) * time.Second).String()
+	},
+	"minutes": func(m float64) string {
+		return (time.Duration(m * float64(time.Minute))).Round(time.Second).String()
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>lleme status</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2rem; color: #222; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: 0.3rem 1.2rem 0.3rem 0; }
+.muted { color: #777; }
+</style>
+</head>
+<body>
+<h1>lleme</h1>
+<p>Up {{uptime .UptimeSeconds}} <span class="muted">· {{.Version}}</span></p>
+<p>{{.ActiveRequests}} request(s) in progress · {{len .Models}} of {{if .MaxModels}}{{.MaxModels}}{{else}}unlimited{{end}} models loaded</p>
+{{if .Models}}
+<table>
+<tr><th>Model</th><th>Status</th><th>Idle</th></tr>
+{{range .Models}}<tr><td>{{.Name}}</td><td>{{.Status}}</td><td>{{minutes .IdleMinutes}}</td></tr>
+{{end}}</table>
+{{else}}
+<p class="muted">No models loaded</p>
+{{end}}
+</body>
+</html>
+`))
+
+// handleStatusPage serves a read-only summary for teammates who shouldn't
+// have API access, gated by server.status_token rather than an API key. It's
+// HTML for browsers and JSON for ?format=json or an Accept: application/json
+// request. Without a token configured, the page doesn't exist.
+func (s *Server) handleStatusPage(w http.ResponseWriter, r *http.Request) {
+	if s.config.StatusToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Only GET is allowed")
+		return
+	}
+	token := r.URL.Query().Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.StatusToken)) != 1 {
+		s.writeError(w, http.StatusUnauthorized, "authentication_error", "Invalid or missing status token")
+		return
+	}
+
+	page := StatusPage{
+		Version:        version.Version,
+		UptimeSeconds:  time.Since(s.startedAt).Seconds(),
+		MaxModels:      s.config.MaxModels,
+		ActiveRequests: s.active.Load(),
+		Models:         []StatusPageModel{},
+	}
+	for _, b := range s.manager.ListBackends() {
+		page.Models = append(page.Models, StatusPageModel{
+			Name:        b.ModelName,
+			Status:      b.Status,
+			LoadedAt:    b.StartedAt,
+			IdleMinutes: b.IdleMinutes,
+		})
+	}
+
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		writeJSON(w, page)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := statusPageTemplate.Execute(w, page); err != nil {
+		logs.Debug("failed to render status page", "error", err)
+	}
+}
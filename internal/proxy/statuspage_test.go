@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleStatusPage(t *testing.T) {
+	cfg := DefaultConfig()
+	s := &Server{config: cfg, manager: NewModelManager(cfg, nil), startedAt: time.Now().Add(-time.Hour)}
+	s.manager.backends["user/repo:Q4_K_M"] = &Backend{
+		ModelName:    "user/repo:Q4_K_M",
+		Port:         49152,
+		Status:       BackendReady,
+		StartedAt:    time.Now(),
+		LastActivity: time.Now(),
+	}
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		s.handleStatusPage(w, req)
+		return w
+	}
+
+	// Disabled without a token
+	if w := get("/status?token=", nil); w.Code != http.StatusNotFound {
+		t.Errorf("disabled status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	cfg.StatusToken = "team-token"
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"missing token", "/status", http.StatusUnauthorized},
+		{"wrong token", "/status?token=guess", http.StatusUnauthorized},
+		{"valid token", "/status?token=team-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := get(tt.target, nil); w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+
+	w := get("/status?token=team-token", nil)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want HTML", ct)
+	}
+	if !strings.Contains(w.Body.String(), "user/repo:Q4_K_M") {
+		t.Error("HTML page doesn't list the loaded model")
+	}
+
+	for _, w := range []*httptest.ResponseRecorder{
+		get("/status?token=team-token&format=json", nil),
+		get("/status?token=team-token", http.Header{"Accept": {"application/json"}}),
+	} {
+		var page StatusPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("JSON status page: %v: %s", err, w.Body)
+		}
+		if len(page.Models) != 1 || page.Models[0].Name != "user/repo:Q4_K_M" || page.Models[0].Status != "ready" {
+			t.Errorf("Models = %+v", page.Models)
+		}
+		if page.UptimeSeconds < 3600 {
+			t.Errorf("UptimeSeconds = %v", page.UptimeSeconds)
+		}
+		if strings.Contains(w.Body.String(), "49152") {
+			t.Error("status page exposes backend ports")
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxStatusWait caps ?wait on /api/status so a long poll doesn't outlive
+// proxies and load balancers in front of the server
+const maxStatusWait = 60 * time.Second
+
+// statusRecheckInterval is how often a long poll looks for changes that
+// don't publish an event, like the power saver or quiet hours kicking in
+var statusRecheckInterval = time.Second
+
+// parseStatusWait parses ?wait, as a duration ("30s") or whole seconds
+func parseStatusWait(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait %q, want a duration like 30s", value)
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q, must not be negative", value)
+	}
+	return min(wait, maxStatusWait), nil
+}
+
+// statusETag fingerprints the parts of the status a watcher cares about:
+// which models are loaded and in what state, and whether the power saver
+// or quiet hours are in effect. Uptime, idle time, resource use and the
+// in-flight request count change constantly and are left out.
+func statusETag(status ProxyStatus) string {
+	type model struct {
+		Name      string
+		Status    string
+		Port      int
+		PID       int
+		StartedAt time.Time
+	}
+	key := struct {
+		MaxModels  int
+		Models     []model
+		Power      *PowerStatus
+		QuietHours bool
+	}{MaxModels: status.MaxModels, Power: status.Power}
+	for _, b := range status.Models {
+		key.Models = append(key.Models, model{b.ModelName, b.Status, b.Port, b.PID, b.StartedAt})
+	}
+	if status.QuietHours != nil {
+		key.QuietHours = status.QuietHours.Active
+	}
+
+	data, _ := json.Marshal(key)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// waitForStatusChange blocks until the status no longer matches etag, wait
+// passes, or the client goes away, and returns the status at that point
+func (s *Server) waitForStatusChange(ctx context.Context, etag string, wait time.Duration) ProxyStatus {
+	events, cancel := s.manager.Subscribe()
+	defer cancel()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	recheck := time.NewTicker(statusRecheckInterval)
+	defer recheck.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return s.status()
+		case <-timer.C:
+			return s.status()
+		case <-events:
+		case <-recheck.C:
+		}
+		if status := s.status(); status.ETag != etag {
+			return status
+		}
+	}
+}
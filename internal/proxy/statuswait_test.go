@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseStatusWait(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"15", 15 * time.Second, false},
+		{"500ms", 500 * time.Millisecond, false},
+		{"10m", maxStatusWait, false},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseStatusWait(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseStatusWait(%q) = %v, %v; want %v, err %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestStatusETag(t *testing.T) {
+	started := time.Now()
+	status := ProxyStatus{
+		MaxModels: 3,
+		Models:    []BackendInfo{{ModelName: "user/repo:Q4_K_M", Status: "ready", Port: 49152, StartedAt: started}},
+	}
+	etag := statusETag(status)
+
+	busy := status
+	busy.UptimeSeconds = 500
+	busy.Active = 4
+	busy.Models = []BackendInfo{{ModelName: "user/repo:Q4_K_M", Status: "ready", Port: 49152, StartedAt: started, IdleMinutes: 3, LastActivity: time.Now()}}
+	if statusETag(busy) != etag {
+		t.Error("ETag changed with uptime, activity and idle time")
+	}
+
+	stopping := status
+	stopping.Models = []BackendInfo{{ModelName: "user/repo:Q4_K_M", Status: "stopping", Port: 49152, StartedAt: started}}
+	if statusETag(stopping) == etag {
+		t.Error("ETag unchanged when a model's status changed")
+	}
+
+	quiet := status
+	quiet.QuietHours = &QuietHours{Schedule: "22:00-07:00", Active: true}
+	if statusETag(quiet) == etag {
+		t.Error("ETag unchanged when quiet hours began")
+	}
+}
+
+func TestStatusLongPoll(t *testing.T) {
+	newServer := func() *Server {
+		cfg := DefaultConfig()
+		return &Server{config: cfg, manager: NewModelManager(cfg, nil), startedAt: time.Now()}
+	}
+	get := func(s *Server, query string) (ProxyStatus, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		s.handleStatus(w, httptest.NewRequest(http.MethodGet, "/api/status"+query, nil))
+		var status ProxyStatus
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+		}
+		return status, w
+	}
+
+	t.Run("returns on change", func(t *testing.T) {
+		s := newServer()
+		initial, w := get(s, "")
+		if initial.ETag == "" || w.Header().Get("ETag") != `"`+initial.ETag+`"` {
+			t.Fatalf("ETag header = %q, body etag = %q", w.Header().Get("ETag"), initial.ETag)
+		}
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			m := s.manager
+			m.mu.Lock()
+			m.backends["user/repo:Q4_K_M"] = &Backend{ModelName: "user/repo:Q4_K_M", Status: BackendStarting}
+			m.mu.Unlock()
+			m.events.publish(EventLoading, "user/repo:Q4_K_M")
+		}()
+
+		start := time.Now()
+		changed, _ := get(s, "?wait=10s&etag="+initial.ETag)
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("long poll took %v, want it to return on the change", elapsed)
+		}
+		if changed.ETag == initial.ETag || len(changed.Models) != 1 {
+			t.Errorf("got %+v, want the new model", changed)
+		}
+	})
+
+	t.Run("returns after wait", func(t *testing.T) {
+		s := newServer()
+		initial, _ := get(s, "")
+		start := time.Now()
+		same, _ := get(s, "?wait=100ms&etag="+initial.ETag)
+		if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+			t.Errorf("returned after %v, before the wait", elapsed)
+		}
+		if same.ETag != initial.ETag {
+			t.Errorf("ETag = %q, want unchanged %q", same.ETag, initial.ETag)
+		}
+	})
+
+	t.Run("stale etag returns at once", func(t *testing.T) {
+		s := newServer()
+		start := time.Now()
+		get(s, "?wait=10s&etag=stale")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %v with a stale etag", elapsed)
+		}
+	})
+
+	t.Run("invalid wait", func(t *testing.T) {
+		if _, w := get(newServer(), "?wait=soon"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
@@ -2,13 +2,15 @@ package proxy
 
 import (
 	"crypto/sha256"
-	"encoding/binary"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
-	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/gguf"
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 // TemplatePatch defines a single, focused fix for a chat template issue.
@@ -36,6 +38,7 @@ type TemplatePatch struct {
 //   - Documented: Clear description of what and why
 var templatePatches = []TemplatePatch{
 	patchEmptyToolsArray,
+	patchSingleToolCall,
 }
 
 // patchEmptyToolsArray fixes llama-server passing tools=[] instead of tools=none.
@@ -95,10 +98,77 @@ var patchEmptyToolsArray = TemplatePatch{
 	},
 }
 
+// singleToolCallGuard matches the check that aborts rendering when an
+// assistant turn has more than one tool call
+var singleToolCallGuard = regexp.MustCompile(
+	`\{%-?\s*if\s+(?:not\s+message\.tool_calls\s*\|\s*length\s*==\s*1|message\.tool_calls\s*\|\s*length\s*!=\s*1)\s*-?%\}\s*` +
+		`\{\{-?\s*raise_exception\([^)]*\)\s*-?\}\}\s*` +
+		`\{%-?\s*endif\s*-?%\}\s*`)
+
+// firstToolCall and toolTurn bound the rest of the tool_calls branch: from
+// where it picks the first call to where the tool result branch begins
+var (
+	firstToolCall = regexp.MustCompile(`\{%-?\s*set tool_call = message\.tool_calls\[0\]\.function\s*-?%\}`)
+	toolTurn      = regexp.MustCompile(`\{%-?\s*elif message\.role == ["']tool["']`)
+)
+
+// patchSingleToolCall lets templates render turns with parallel tool calls.
+//
+// Problem:
+//
+//	Llama 3.x templates raise "This model only supports single tool-calls at
+//	once!" when an assistant message has several tool_calls, so a request
+//	fails once the model has made parallel calls and the client sends them
+//	back with their results.
+//
+// Fix:
+//
+//	Drop the guard and repeat the branch for each call, so every call is
+//	rendered as its own assistant turn:
+//	  {%- set tool_call = message.tool_calls[0].function %} ... ->
+//	  {%- for call in message.tool_calls %}{%- set tool_call = call.function %} ... {%- endfor %}
+//
+// Affected templates:
+//
+//	Llama 3.1, 3.2 and 3.3, and fine-tunes that reuse their template
+var patchSingleToolCall = TemplatePatch{
+	ID:          "single-tool-call",
+	Description: "Render assistant turns with several tool calls instead of raising",
+	Apply: func(template string) string {
+		guard := singleToolCallGuard.FindStringIndex(template)
+		if guard == nil {
+			return template
+		}
+		rest := template[guard[1]:]
+		first := firstToolCall.FindStringIndex(rest)
+		end := toolTurn.FindStringIndex(rest)
+		if first == nil || end == nil || first[0] != 0 || end[0] < first[1] {
+			// Not the layout this patch knows; leave the template alone
+			return template
+		}
+
+		// Keep the whitespace before the next branch outside the loop
+		body := strings.TrimRight(rest[first[1]:end[0]], " \t\n")
+		return template[:guard[0]] +
+			"{%- for call in message.tool_calls %}{%- set tool_call = call.function %}" +
+			body + "{%- endfor %}" + rest[first[1]+len(body):]
+	},
+}
+
 // ExtractAndPatchTemplate extracts the chat template from a GGUF file and
 // applies all registered patches. Returns the path to the patched template
 // file, or empty string if no patches were needed.
 func ExtractAndPatchTemplate(modelPath string) (string, error) {
+	patched, err := patchedTemplate(modelPath)
+	if err != nil || patched == "" {
+		return "", err
+	}
+	return writeTemplateCache(patched)
+}
+
+// patchedTemplate returns the model's chat template with patches applied, or
+// empty string if it has no template or none of the patches change it.
+func patchedTemplate(modelPath string) (string, error) {
 	template, err := extractChatTemplate(modelPath)
 	if err != nil {
 		return "", err
@@ -115,9 +185,7 @@ func ExtractAndPatchTemplate(modelPath string) (string, error) {
 	if patched == template {
 		return "", nil
 	}
-
-	// Write to cache
-	return writeTemplateCache(modelPath, patched)
+	return patched, nil
 }
 
 // applyPatches applies all registered patches to a template.
@@ -131,144 +199,80 @@ func applyPatches(template string) string {
 
 // extractChatTemplate reads the chat_template from a GGUF file's metadata.
 func extractChatTemplate(modelPath string) (string, error) {
-	f, err := os.Open(modelPath)
+	f, err := gguf.Open(modelPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to open model: %w", err)
 	}
 	defer f.Close()
 
-	// Read GGUF magic number
-	magic := make([]byte, 4)
-	if _, err := f.Read(magic); err != nil {
-		return "", fmt.Errorf("failed to read magic: %w", err)
-	}
-	if string(magic) != "GGUF" {
-		return "", fmt.Errorf("not a GGUF file")
-	}
-
-	// Read and validate version (we support v2 and v3)
-	var version uint32
-	if err := binary.Read(f, binary.LittleEndian, &version); err != nil {
-		return "", fmt.Errorf("failed to read version: %w", err)
-	}
-	if version < 2 || version > 3 {
-		return "", fmt.Errorf("unsupported GGUF version %d (expected 2 or 3)", version)
-	}
-
-	// Read tensor and key-value counts
-	var tensorCount, kvCount uint64
-	if err := binary.Read(f, binary.LittleEndian, &tensorCount); err != nil {
-		return "", fmt.Errorf("failed to read tensor count: %w", err)
-	}
-	if err := binary.Read(f, binary.LittleEndian, &kvCount); err != nil {
-		return "", fmt.Errorf("failed to read kv count: %w", err)
-	}
-
-	// Scan key-value pairs for chat_template
-	for i := uint64(0); i < kvCount; i++ {
-		key, err := readGGUFString(f)
-		if err != nil {
-			return "", fmt.Errorf("failed to read key: %w", err)
-		}
-
-		var vtype uint32
-		if err := binary.Read(f, binary.LittleEndian, &vtype); err != nil {
-			return "", fmt.Errorf("failed to read value type: %w", err)
-		}
-
-		// Found it - read and return
-		if key == "tokenizer.chat_template" && vtype == 8 { // 8 = string
-			value, err := readGGUFString(f)
-			if err != nil {
-				return "", fmt.Errorf("failed to read chat template: %w", err)
-			}
-			return value, nil
-		}
-
-		// Not what we want - skip this value
-		if err := skipGGUFValue(f, vtype); err != nil {
-			return "", fmt.Errorf("failed to skip value: %w", err)
-		}
+	values, err := f.Find("tokenizer.chat_template")
+	if err != nil {
+		return "", err
 	}
-
-	return "", nil // No chat template found
+	template, _ := values["tokenizer.chat_template"].(string)
+	return template, nil // Empty when the model has no chat template
 }
 
-// readGGUFString reads a length-prefixed string from a GGUF file.
-func readGGUFString(f *os.File) (string, error) {
-	var length uint64
-	if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
-		return "", err
-	}
-	data := make([]byte, length)
-	if _, err := f.Read(data); err != nil {
-		return "", err
-	}
-	return string(data), nil
+func templateCacheDir() string {
+	return filepath.Join(paths.Cache(), "templates")
 }
 
-// skipGGUFValue advances past a value of the given GGUF type.
-func skipGGUFValue(f *os.File, vtype uint32) error {
-	switch vtype {
-	case 0, 1, 7: // u8, i8, bool
-		_, err := f.Seek(1, 1)
-		return err
-	case 2, 3: // u16, i16
-		_, err := f.Seek(2, 1)
-		return err
-	case 4, 5, 6: // u32, i32, f32
-		_, err := f.Seek(4, 1)
-		return err
-	case 10, 11, 12: // u64, i64, f64
-		_, err := f.Seek(8, 1)
-		return err
-	case 8: // string
-		var length uint64
-		if err := binary.Read(f, binary.LittleEndian, &length); err != nil {
-			return err
-		}
-		_, err := f.Seek(int64(length), 1)
-		return err
-	case 9: // array
-		var atype uint32
-		if err := binary.Read(f, binary.LittleEndian, &atype); err != nil {
-			return err
-		}
-		var alen uint64
-		if err := binary.Read(f, binary.LittleEndian, &alen); err != nil {
-			return err
-		}
-		for j := uint64(0); j < alen; j++ {
-			if err := skipGGUFValue(f, atype); err != nil {
-				return err
-			}
-		}
-		return nil
-	default:
-		return fmt.Errorf("unknown GGUF value type: %d", vtype)
-	}
+// templateCacheName names a cached template by its content, so models that
+// share a template share one file and a re-downloaded model reuses it.
+func templateCacheName(template string) string {
+	hash := sha256.Sum256([]byte(template))
+	return fmt.Sprintf("%x.jinja", hash[:8])
 }
 
 // writeTemplateCache writes a patched template to a cache file and returns its path.
-func writeTemplateCache(modelPath, template string) (string, error) {
-	cacheDir := filepath.Join(config.CachePath(), "templates")
+func writeTemplateCache(template string) (string, error) {
+	cacheDir := templateCacheDir()
 	if err := os.MkdirAll(cacheDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create template cache dir: %w", err)
 	}
 
-	// Hash model path + mtime so cache invalidates when model is updated
-	info, err := os.Stat(modelPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to stat model: %w", err)
+	cachePath := filepath.Join(cacheDir, templateCacheName(template))
+	if data, err := os.ReadFile(cachePath); err == nil && string(data) == template {
+		return cachePath, nil
 	}
-	hashInput := fmt.Sprintf("%s:%d", modelPath, info.ModTime().UnixNano())
-	hash := sha256.Sum256([]byte(hashInput))
-	filename := fmt.Sprintf("%x.jinja", hash[:8])
-	cachePath := filepath.Join(cacheDir, filename)
-
-	if err := os.WriteFile(cachePath, []byte(template), 0644); err != nil {
+	if err := fileutil.AtomicWriteFile(cachePath, []byte(template), 0644); err != nil {
 		return "", fmt.Errorf("failed to write template cache: %w", err)
 	}
 
 	return cachePath, nil
 }
+
+// PruneTemplateCache removes cached templates that no downloaded model uses
+// and returns how many were removed. Models whose templates can't be read
+// don't keep anything; theirs is written again when they're next loaded.
+func PruneTemplateCache() (int, error) {
+	entries, err := os.ReadDir(templateCacheDir())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	models, err := NewModelResolver().ListDownloadedModels()
+	if err != nil {
+		return 0, err
+	}
+	used := make(map[string]bool)
+	for _, m := range models {
+		if patched, err := patchedTemplate(m.ModelPath); err == nil && patched != "" {
+			used[templateCacheName(patched)] = true
+		}
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || used[e.Name()] {
+			continue
+		}
+		if os.Remove(filepath.Join(templateCacheDir(), e.Name())) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -7,7 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
+
+	"github.com/nchapman/lleme/internal/paths"
 )
 
 func TestPatchEmptyToolsArray(t *testing.T) {
@@ -186,23 +187,65 @@ func TestAllPatchesAreIdempotent(t *testing.T) {
 	}
 }
 
-func TestWriteTemplateCache(t *testing.T) {
-	tmpDir := t.TempDir()
+func TestPatchSingleToolCall(t *testing.T) {
+	tests := []struct {
+		fixture string
+		changed bool
+	}{
+		{"llama3.1.jinja", true},
+		{"llama3.2.jinja", true},
+		{"qwen2.5.jinja", false}, // already loops over tool_calls
+	}
 
-	// Override config.BinPath for testing
-	originalBinPath := os.Getenv("LLEME_BIN_PATH")
-	os.Setenv("LLEME_BIN_PATH", tmpDir)
-	defer os.Setenv("LLEME_BIN_PATH", originalBinPath)
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "tools", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			input := string(data)
+			result := patchSingleToolCall.Apply(input)
 
-	// Create a fake model file (needed for mtime-based cache key)
-	modelPath := filepath.Join(tmpDir, "model.gguf")
-	if err := os.WriteFile(modelPath, []byte("fake"), 0644); err != nil {
-		t.Fatal(err)
+			if !tt.changed {
+				if result != input {
+					t.Errorf("patch modified a template that already supports parallel calls:\n%s", result)
+				}
+				return
+			}
+			if strings.Contains(result, "raise_exception") {
+				t.Error("single tool-call guard was not removed")
+			}
+			if !strings.Contains(result, "for call in message.tool_calls") {
+				t.Error("tool call branch was not wrapped in a loop")
+			}
+			if opens, closes := countTag(result, "for"), countTag(result, "endfor"); opens != closes {
+				t.Errorf("unbalanced for/endfor: %d/%d", opens, closes)
+			}
+			if opens, closes := countTag(result, "if"), countTag(result, "endif"); opens != closes {
+				t.Errorf("unbalanced if/endif: %d/%d", opens, closes)
+			}
+			if again := patchSingleToolCall.Apply(result); again != result {
+				t.Error("patch is not idempotent")
+			}
+		})
 	}
+}
+
+// countTag counts Jinja block tags named tag, with or without whitespace control
+func countTag(template, tag string) int {
+	n := 0
+	for _, open := range []string{"{%- ", "{% "} {
+		n += strings.Count(template, open+tag+" ")
+	}
+	return n
+}
+
+func TestWriteTemplateCache(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 
 	template := `{% if (tools is not none and tools | length > 0) %}tools{% endif %}`
 
-	cachePath, err := writeTemplateCache(modelPath, template)
+	cachePath, err := writeTemplateCache(template)
 	if err != nil {
 		t.Fatalf("writeTemplateCache() error = %v", err)
 	}
@@ -227,110 +270,74 @@ func TestWriteTemplateCache(t *testing.T) {
 	}
 }
 
-func TestWriteTemplateCacheConsistentHash(t *testing.T) {
-	tmpDir := t.TempDir()
-
-	originalBinPath := os.Getenv("LLEME_BIN_PATH")
-	os.Setenv("LLEME_BIN_PATH", tmpDir)
-	defer os.Setenv("LLEME_BIN_PATH", originalBinPath)
+func TestWriteTemplateCacheKeyedByContent(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 
-	// Create a fake model file
-	modelPath := filepath.Join(tmpDir, "model.gguf")
-	if err := os.WriteFile(modelPath, []byte("fake"), 0644); err != nil {
-		t.Fatal(err)
-	}
-
-	template := `test template`
-
-	// Write twice with same model path (without modifying the file)
-	path1, err := writeTemplateCache(modelPath, template)
+	// The same template from any model maps to one file
+	path1, err := writeTemplateCache("test template")
 	if err != nil {
 		t.Fatalf("First writeTemplateCache() error = %v", err)
 	}
-
-	path2, err := writeTemplateCache(modelPath, template)
+	path2, err := writeTemplateCache("test template")
 	if err != nil {
 		t.Fatalf("Second writeTemplateCache() error = %v", err)
 	}
-
-	// Should produce same path (deterministic hash based on path + mtime)
 	if path1 != path2 {
-		t.Errorf("Cache paths differ for same model: %q vs %q", path1, path2)
-	}
-}
-
-func TestWriteTemplateCacheDifferentModels(t *testing.T) {
-	tmpDir := t.TempDir()
-
-	originalBinPath := os.Getenv("LLEME_BIN_PATH")
-	os.Setenv("LLEME_BIN_PATH", tmpDir)
-	defer os.Setenv("LLEME_BIN_PATH", originalBinPath)
-
-	// Create two fake model files
-	modelPath1 := filepath.Join(tmpDir, "model1.gguf")
-	modelPath2 := filepath.Join(tmpDir, "model2.gguf")
-	if err := os.WriteFile(modelPath1, []byte("fake1"), 0644); err != nil {
-		t.Fatal(err)
+		t.Errorf("Cache paths differ for the same template: %q vs %q", path1, path2)
 	}
-	if err := os.WriteFile(modelPath2, []byte("fake2"), 0644); err != nil {
-		t.Fatal(err)
-	}
-
-	template := `test template`
 
-	path1, err := writeTemplateCache(modelPath1, template)
+	path3, err := writeTemplateCache("other template")
 	if err != nil {
-		t.Fatalf("First writeTemplateCache() error = %v", err)
+		t.Fatalf("Third writeTemplateCache() error = %v", err)
 	}
-
-	path2, err := writeTemplateCache(modelPath2, template)
-	if err != nil {
-		t.Fatalf("Second writeTemplateCache() error = %v", err)
+	if path1 == path3 {
+		t.Errorf("Cache paths should differ for different templates: both got %q", path1)
 	}
 
-	// Should produce different paths
-	if path1 == path2 {
-		t.Errorf("Cache paths should differ for different models: both got %q", path1)
+	entries, _ := os.ReadDir(templateCacheDir())
+	if len(entries) != 2 {
+		t.Errorf("cache has %d files, want 2", len(entries))
 	}
 }
 
-func TestWriteTemplateCacheInvalidatesOnMtimeChange(t *testing.T) {
-	tmpDir := t.TempDir()
-
-	originalBinPath := os.Getenv("LLEME_BIN_PATH")
-	os.Setenv("LLEME_BIN_PATH", tmpDir)
-	defer os.Setenv("LLEME_BIN_PATH", originalBinPath)
+func TestPruneTemplateCache(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
 
-	modelPath := filepath.Join(tmpDir, "model.gguf")
-	if err := os.WriteFile(modelPath, []byte("version1"), 0644); err != nil {
+	// A downloaded model whose template needs patching
+	ggufPath := createTestGGUF(t, map[string]string{
+		"tokenizer.chat_template": `{% if tools is not none %}Use tools{% endif %}`,
+	})
+	modelDir := filepath.Join(paths.Models(), "user", "repo")
+	if err := os.MkdirAll(modelDir, 0755); err != nil {
 		t.Fatal(err)
 	}
-
-	template := `test template`
-
-	path1, err := writeTemplateCache(modelPath, template)
-	if err != nil {
-		t.Fatalf("First writeTemplateCache() error = %v", err)
+	data, _ := os.ReadFile(ggufPath)
+	modelPath := filepath.Join(modelDir, "Q4_K_M.gguf")
+	if err := os.WriteFile(modelPath, data, 0644); err != nil {
+		t.Fatal(err)
 	}
 
-	// Update the model file with a different mtime
-	// Use Chtimes to ensure mtime is definitely different (some filesystems have low resolution)
-	if err := os.WriteFile(modelPath, []byte("version2"), 0644); err != nil {
-		t.Fatal(err)
+	used, err := ExtractAndPatchTemplate(modelPath)
+	if err != nil || used == "" {
+		t.Fatalf("ExtractAndPatchTemplate() = %q, %v", used, err)
 	}
-	futureTime := time.Now().Add(time.Hour)
-	if err := os.Chtimes(modelPath, futureTime, futureTime); err != nil {
+	stale, err := writeTemplateCache("template from a removed model")
+	if err != nil {
 		t.Fatal(err)
 	}
 
-	path2, err := writeTemplateCache(modelPath, template)
+	removed, err := PruneTemplateCache()
 	if err != nil {
-		t.Fatalf("Second writeTemplateCache() error = %v", err)
+		t.Fatalf("PruneTemplateCache() error = %v", err)
 	}
-
-	// Should produce different paths due to mtime change
-	if path1 == path2 {
-		t.Errorf("Cache paths should differ after model update: both got %q", path1)
+	if removed != 1 {
+		t.Errorf("PruneTemplateCache() removed %d, want 1", removed)
+	}
+	if _, err := os.Stat(used); err != nil {
+		t.Errorf("template in use was removed: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("unreferenced template should be removed")
 	}
 }
 
@@ -530,7 +537,7 @@ func TestExtractAndPatchTemplateNoTemplate(t *testing.T) {
 	}
 }
 
-// createTestGGUFWithTypes creates a GGUF file with various value types to test skipGGUFValue.
+// createTestGGUFWithTypes creates a GGUF file with various value types to test skipping them.
 // The chat_template is placed after other KV pairs to ensure skip logic is exercised.
 func createTestGGUFWithTypes(t *testing.T, chatTemplate string) string {
 	t.Helper()
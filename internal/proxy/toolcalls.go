@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// requestToolNames returns the function names a chat request offers
+func requestToolNames(body []byte) []string {
+	var req struct {
+		Tools []struct {
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tools"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return nil
+	}
+	var names []string
+	for _, t := range req.Tools {
+		if t.Function.Name != "" {
+			names = append(names, t.Function.Name)
+		}
+	}
+	return names
+}
+
+// splitToolCalls moves tool calls that a model wrote as plain content into
+// the message's tool_calls. Models whose templates only teach a single call,
+// like Llama 3.x, answer parallel calls with several JSON objects in a row,
+// which llama-server's parser leaves as content. The content is only
+// converted when all of it is calls to the offered tools. It reports whether
+// anything changed.
+func splitToolCalls(resp map[string]any, toolNames []string) bool {
+	choices, _ := resp["choices"].([]any)
+	changed := false
+	for _, c := range choices {
+		choice, _ := c.(map[string]any)
+		msg, _ := choice["message"].(map[string]any)
+		if msg == nil {
+			continue
+		}
+		if calls, _ := msg["tool_calls"].([]any); len(calls) > 0 {
+			continue
+		}
+		content, _ := msg["content"].(string)
+		calls := parseToolCalls(content, toolNames)
+		if calls == nil {
+			continue
+		}
+
+		msg["content"] = nil
+		msg["tool_calls"] = calls
+		choice["finish_reason"] = "tool_calls"
+		changed = true
+	}
+	return changed
+}
+
+// parseToolCalls reads content as a sequence of {"name", "parameters"} or
+// {"name", "arguments"} objects, or an array of them, separated by
+// whitespace, commas or semicolons. It returns nil unless every object
+// calls one of toolNames.
+func parseToolCalls(content string, toolNames []string) []any {
+	content = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content), "<|python_tag|>"))
+	if !strings.HasPrefix(content, "{") && !strings.HasPrefix(content, "[") {
+		return nil
+	}
+
+	var objects []map[string]any
+	if err := json.Unmarshal([]byte(content), &objects); err != nil {
+		objects = nil
+		for rest := content; rest != ""; {
+			dec := json.NewDecoder(strings.NewReader(rest))
+			var obj map[string]any
+			if err := dec.Decode(&obj); err != nil {
+				return nil
+			}
+			objects = append(objects, obj)
+			rest = strings.TrimLeft(rest[dec.InputOffset():], " \t\r\n,;")
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+
+	calls := make([]any, 0, len(objects))
+	for _, obj := range objects {
+		name, _ := obj["name"].(string)
+		if !slices.Contains(toolNames, name) {
+			return nil
+		}
+		args, ok := obj["parameters"]
+		if !ok {
+			args, ok = obj["arguments"]
+		}
+		if !ok {
+			return nil
+		}
+		arguments, isString := args.(string)
+		if !isString {
+			data, err := json.Marshal(args)
+			if err != nil {
+				return nil
+			}
+			arguments = string(data)
+		}
+		calls = append(calls, map[string]any{
+			"id":   newToolCallID(),
+			"type": "function",
+			"function": map[string]any{
+				"name":      name,
+				"arguments": arguments,
+			},
+		})
+	}
+	return calls
+}
+
+func newToolCallID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequestToolNames(t *testing.T) {
+	body := []byte(`{"tools":[{"type":"function","function":{"name":"get_weather"}},{"type":"function","function":{"name":"get_time"}}]}`)
+	names := requestToolNames(body)
+	if len(names) != 2 || names[0] != "get_weather" || names[1] != "get_time" {
+		t.Errorf("requestToolNames() = %v", names)
+	}
+	if names := requestToolNames([]byte(`{"messages":[]}`)); names != nil {
+		t.Errorf("requestToolNames() without tools = %v, want nil", names)
+	}
+}
+
+func TestParseToolCalls(t *testing.T) {
+	tools := []string{"get_weather", "get_time"}
+	tests := []struct {
+		name    string
+		content string
+		want    []string // expected function names, nil for no calls
+	}{
+		{"single object", `{"name": "get_weather", "parameters": {"city": "Paris"}}`, []string{"get_weather"}},
+		{"semicolon separated", `{"name": "get_weather", "parameters": {"city": "Paris"}}; {"name": "get_time", "parameters": {"tz": "CET"}}`, []string{"get_weather", "get_time"}},
+		{"newline separated", "{\"name\": \"get_weather\", \"parameters\": {}}\n{\"name\": \"get_weather\", \"parameters\": {}}", []string{"get_weather", "get_weather"}},
+		{"array", `[{"name": "get_weather", "arguments": {"city": "Oslo"}}, {"name": "get_time", "arguments": {}}]`, []string{"get_weather", "get_time"}},
+		{"python tag", `<|python_tag|>{"name": "get_time", "parameters": {}}`, []string{"get_time"}},
+		{"unknown tool", `{"name": "rm_rf", "parameters": {}}`, nil},
+		{"one unknown among many", `{"name": "get_time", "parameters": {}}; {"name": "rm_rf", "parameters": {}}`, nil},
+		{"missing arguments", `{"name": "get_time"}`, nil},
+		{"plain text", `The weather in Paris is sunny.`, nil},
+		{"trailing text", `{"name": "get_time", "parameters": {}} and that's it`, nil},
+		{"empty", ``, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := parseToolCalls(tt.content, tools)
+			if tt.want == nil {
+				if calls != nil {
+					t.Errorf("parseToolCalls() = %v, want nil", calls)
+				}
+				return
+			}
+			if len(calls) != len(tt.want) {
+				t.Fatalf("parseToolCalls() returned %d calls, want %d", len(calls), len(tt.want))
+			}
+			for i, c := range calls {
+				call := c.(map[string]any)
+				fn := call["function"].(map[string]any)
+				if fn["name"] != tt.want[i] {
+					t.Errorf("call %d name = %v, want %s", i, fn["name"], tt.want[i])
+				}
+				args, ok := fn["arguments"].(string)
+				if !ok || !json.Valid([]byte(args)) {
+					t.Errorf("call %d arguments = %#v, want a JSON string", i, fn["arguments"])
+				}
+				if call["type"] != "function" || call["id"] == "" {
+					t.Errorf("call %d missing type or id: %v", i, call)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitToolCalls(t *testing.T) {
+	tools := []string{"get_weather"}
+
+	var resp map[string]any
+	json.Unmarshal([]byte(`{"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant",
+		"content":"{\"name\": \"get_weather\", \"parameters\": {\"city\": \"Paris\"}}; {\"name\": \"get_weather\", \"parameters\": {\"city\": \"Oslo\"}}"}}]}`), &resp)
+	if !splitToolCalls(resp, tools) {
+		t.Fatal("splitToolCalls() reported no change")
+	}
+	choice := resp["choices"].([]any)[0].(map[string]any)
+	msg := choice["message"].(map[string]any)
+	if msg["content"] != nil {
+		t.Errorf("content = %v, want nil", msg["content"])
+	}
+	if calls := msg["tool_calls"].([]any); len(calls) != 2 {
+		t.Errorf("got %d tool_calls, want 2", len(calls))
+	}
+	if choice["finish_reason"] != "tool_calls" {
+		t.Errorf("finish_reason = %v, want tool_calls", choice["finish_reason"])
+	}
+
+	// Responses the backend already parsed are left alone
+	resp = nil
+	json.Unmarshal([]byte(`{"choices":[{"message":{"content":null,"tool_calls":[{"id":"a"}]}}]}`), &resp)
+	if splitToolCalls(resp, tools) {
+		t.Error("splitToolCalls() changed a response with existing tool_calls")
+	}
+	resp = nil
+	json.Unmarshal([]byte(`{"choices":[{"message":{"content":"Sunny."}}]}`), &resp)
+	if splitToolCalls(resp, tools) {
+		t.Error("splitToolCalls() changed a plain text response")
+	}
+}
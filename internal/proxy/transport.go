@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// backendTransport carries every request from the proxy to its backends.
+// Sharing one keeps connections to llama-server alive between requests, so
+// a burst of short completions doesn't pay for a new connection each, and
+// the idle pool is sized for backends serving many parallel slots rather
+// than http.DefaultTransport's two per host.
+//
+// HTTP/2 is negotiated with backends served over TLS. llama-server and
+// sd-server speak plain HTTP/1.1, where the pooled keep-alive connections
+// are what saves the round trips.
+var backendTransport = &http.Transport{
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:   true,
+	MaxIdleConns:        256,
+	MaxIdleConnsPerHost: 64,
+	IdleConnTimeout:     90 * time.Second,
+	// Backends are local, so compressing costs more than it saves; the
+	// client's own Accept-Encoding still passes through
+	DisableCompression: true,
+}
+
+// backendClient makes the proxy's own requests to backends, such as
+// health checks and extra choices, over the shared pool.
+func backendClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: backendTransport, Timeout: timeout}
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBackendTransportReusesConnections(t *testing.T) {
+	var dials atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"choices":[]}`)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			dials.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+	defer backendTransport.CloseIdleConnections()
+
+	// Two bursts of parallel requests; the second is served by the
+	// connections the first left idle
+	const burst = 16
+	client := backendClient(0)
+	for range 2 {
+		var wg sync.WaitGroup
+		for range burst {
+			wg.Go(func() {
+				resp, err := client.Post(srv.URL+"/v1/completions", "application/json", nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			})
+		}
+		wg.Wait()
+	}
+
+	// A connection goes back to the pool just after its body is read, so a
+	// few in the second burst can still miss one; most must be reused
+	if n := dials.Load(); n > burst+burst/2 {
+		t.Errorf("opened %d connections for two bursts of %d, want at most %d", n, burst, burst+burst/2)
+	}
+}
@@ -1,12 +1,18 @@
 package proxy
 
 import (
+	"fmt"
 	"io"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/nchapman/lleme/internal/clock"
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/options"
 )
 
 // BackendStatus represents the current state of a backend server
@@ -17,6 +23,7 @@ const (
 	BackendReady
 	BackendStopping
 	BackendStopped
+	BackendFailed // Process exited on its own
 )
 
 func (s BackendStatus) String() string {
@@ -29,25 +36,59 @@ func (s BackendStatus) String() string {
 		return "stopping"
 	case BackendStopped:
 		return "stopped"
+	case BackendFailed:
+		return "failed"
 	default:
 		return "unknown"
 	}
 }
 
-// Backend represents a running llama-server instance for a specific model
+// BackendKind identifies which inference server a backend runs
+type BackendKind int
+
+const (
+	BackendLlama BackendKind = iota // llama-server for text and embeddings
+	BackendImage                    // stable-diffusion.cpp sd-server for images
+)
+
+func (k BackendKind) String() string {
+	switch k {
+	case BackendLlama:
+		return "llama"
+	case BackendImage:
+		return "image"
+	default:
+		return "unknown"
+	}
+}
+
+// Backend represents a running inference server instance for a specific model
 type Backend struct {
 	mu           sync.RWMutex
+	Kind         BackendKind    // Which server binary this backend runs
 	ModelName    string         // Full model reference: "bartowski/Llama-3.2-3B-Instruct-GGUF:Q4_K_M"
 	ModelPath    string         // Absolute path to the .gguf file
 	Port         int            // Port this backend is listening on
-	Process      *os.Process    // The llama-server process
+	Process      *os.Process    // The server process
+	exited       chan struct{}  // Closed when Process exits; nil without one
+	exitErr      error          // Result of waiting on Process, set before exited closes
+	usage        usageHistory   // Recent CPU and GPU samples of Process
 	LogWriter    io.WriteCloser // Log file writer for this backend
 	LastActivity time.Time      // Last time a request was made to this backend
 	StartedAt    time.Time      // When this backend was started
 	Status       BackendStatus  // Current status
 	ReadyChan    chan struct{}  // Closed when backend is ready (for request coalescing)
 	readyOnce    sync.Once      // Ensures ReadyChan is closed exactly once
+	mock         io.Closer      // In-process mock server, used instead of Process
 	Options      map[string]any // Runtime options passed at load time (override config)
+	adjusted     map[string]any // Options the power saver or memory guard changed at load
+	memory       int64          // Estimated memory in use, from the check at load; 0 if unknown
+	gpuLayers    *int           // GPU layers found to fit in VRAM, overriding options
+	launched     *LaunchConfig  // How Process was started, guarded by mu
+	retrying     chan struct{}  // Signaled when startup restarts with fewer GPU layers
+	clock        clock.Clock    // Tells activity time; the system clock when nil
+	sessions     sessionSlots   // Conversations pinned to slots, guarded by mu
+	slotsOnce    sync.Once      // Reads the slot count on first use
 }
 
 // CloseReadyChan safely closes the ReadyChan exactly once
@@ -61,7 +102,7 @@ func (b *Backend) CloseReadyChan() {
 func (b *Backend) UpdateActivity() {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	b.LastActivity = time.Now()
+	b.LastActivity = b.now()
 }
 
 // GetLastActivity returns the last activity time
@@ -89,19 +130,45 @@ func (b *Backend) SetStatus(status BackendStatus) {
 func (b *Backend) IdleDuration() time.Duration {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return time.Since(b.LastActivity)
+	return b.now().Sub(b.LastActivity)
+}
+
+// now returns the time on the backend's clock
+func (b *Backend) now() time.Time {
+	if b.clock == nil {
+		return time.Now()
+	}
+	return b.clock.Now()
 }
 
 // Config holds proxy configuration
 type Config struct {
-	Host           string        // Proxy host (default: "127.0.0.1")
-	Port           int           // Proxy port (default: 11313)
-	MaxModels      int           // Maximum concurrent models (0 = unlimited)
-	IdleTimeout    time.Duration // How long before idle models are unloaded
-	BackendPortMin int           // Minimum port for backends
-	BackendPortMax int           // Maximum port for backends
-	StartupTimeout time.Duration // How long to wait for backend startup
-	CORSOrigins    []string      // Allowed CORS origins (empty = local only)
+	Host           string                 // Proxy host (default: "127.0.0.1")
+	Port           int                    // Proxy port (default: 11313)
+	MaxModels      int                    // Maximum concurrent models (0 = unlimited)
+	IdleTimeout    time.Duration          // How long before idle models are unloaded
+	BackendPortMin int                    // Minimum port for backends
+	BackendPortMax int                    // Maximum port for backends
+	StartupTimeout time.Duration          // How long to wait for backend startup
+	CORSOrigins    []string               // Allowed CORS origins (empty = local only)
+	MemoryGuard    string                 // Policy when a model may not fit in memory
+	MinFreeMemory  int64                  // Bytes to keep free after loading a model
+	GRPCPort       int                    // gRPC management API port (0 = disabled)
+	HFCache        bool                   // Serve a caching Hugging Face mirror at /hf
+	HFCacheToken   bool                   // Lend our Hub token to /hf requests without one
+	WarmUp         bool                   // Send a one-token request after loading a model
+	DefaultModel   string                 // Model for requests that name none or a hosted model
+	DefaultStrict  bool                   // Only use DefaultModel when no model is named
+	Clients        []config.Client        // Per-client profiles
+	Insecure       bool                   // Allow a public address without API keys
+	StatusToken    string                 // Token for the read-only /status page
+	UnloadSchedule string                 // Daily quiet hours ("22:00-07:00") when no models run
+	Eviction       string                 // Which model to unload for another: lru, size, or priority
+	Priorities     config.ModelPriorities // Weights for the priority eviction policy
+	Pprof          bool                   // Serve Go profiles at /debug/pprof
+	JSONRepair     bool                   // Repair almost-valid JSON-mode replies
+	MaxOutput      int                    // Cap on tokens generated per request (0 = none)
+	DefaultTokens  int                    // max_tokens for requests that set no length (0 = none)
 }
 
 // DefaultConfig returns the default proxy configuration
@@ -114,6 +181,9 @@ func DefaultConfig() *Config {
 		BackendPortMin: 49152,
 		BackendPortMax: 49200,
 		StartupTimeout: 120 * time.Second,
+		MemoryGuard:    MemoryGuardRefuse,
+		MinFreeMemory:  1 << 30,
+		Eviction:       EvictionLRU,
 	}
 }
 
@@ -145,6 +215,32 @@ func ConfigFromAppConfig(s config.Server) *Config {
 	if len(s.CORSOrigins) > 0 {
 		cfg.CORSOrigins = s.CORSOrigins
 	}
+	if s.MemoryGuard != "" {
+		cfg.MemoryGuard = s.MemoryGuard
+	}
+	if s.MinFreeMemoryMB > 0 {
+		cfg.MinFreeMemory = int64(s.MinFreeMemoryMB) << 20
+	}
+	if s.GRPCPort > 0 {
+		cfg.GRPCPort = s.GRPCPort
+	}
+	cfg.HFCache = s.HFCache
+	cfg.HFCacheToken = s.HFCacheToken
+	cfg.WarmUp = s.WarmUp
+	cfg.DefaultModel = s.DefaultModel
+	cfg.DefaultStrict = s.DefaultStrict
+	cfg.Clients = s.Clients
+	cfg.Insecure = s.Insecure
+	cfg.StatusToken = s.StatusToken
+	cfg.UnloadSchedule = s.UnloadSchedule
+	if s.Eviction != "" {
+		cfg.Eviction = s.Eviction
+	}
+	cfg.Priorities = s.Priorities
+	cfg.Pprof = s.Pprof
+	cfg.JSONRepair = s.JSONRepair
+	cfg.MaxOutput = s.MaxOutputTokens
+	cfg.DefaultTokens = s.DefaultTokens
 
 	return cfg
 }
@@ -158,6 +254,21 @@ type BackendInfo struct {
 	StartedAt    time.Time `json:"started_at"`
 	LastActivity time.Time `json:"last_activity"`
 	IdleMinutes  float64   `json:"idle_minutes"`
+
+	// Recent resource use, once the backend has been sampled
+	Usage *BackendUsage `json:"usage,omitempty"`
+}
+
+// BackendUsage is a backend process's recent CPU and GPU use, sampled every
+// few seconds. CPU percentages count one core as 100. GPU percentages are
+// only reported where per-process GPU use can be read (NVIDIA, via
+// nvidia-smi).
+type BackendUsage struct {
+	CPUPercent      float64  `json:"cpu_percent"`
+	CPUPercentAvg1m float64  `json:"cpu_percent_1m"`
+	GPUPercent      *float64 `json:"gpu_percent,omitempty"`
+	GPUPercentAvg1m *float64 `json:"gpu_percent_1m,omitempty"`
+	Samples         int      `json:"samples"` // Samples the averages are over
 }
 
 // ProxyStatus contains the full proxy status for API responses
@@ -169,12 +280,49 @@ type ProxyStatus struct {
 	MaxModels     int           `json:"max_models"`
 	LoadedCount   int           `json:"loaded_count"`
 	IdleTimeout   string        `json:"idle_timeout"`
+	Active        int64         `json:"active_requests"` // Inference requests in progress
 	Models        []BackendInfo `json:"models"`
+	Power         *PowerStatus  `json:"power,omitempty"`       // Set when power.saver is on
+	QuietHours    *QuietHours   `json:"quiet_hours,omitempty"` // Set when server.unload_schedule is
+	ETag          string        `json:"etag"`                  // Changes when models or their state do; see statusETag
+}
+
+// QuietHours is the state of server.unload_schedule
+type QuietHours struct {
+	Schedule string    `json:"schedule"`       // "22:00-07:00"
+	Active   bool      `json:"active"`         // Whether models are refused now
+	Until    time.Time `json:"until,omitzero"` // When loads are allowed again, while active
+}
+
+// PowerStatus is the state of the power saver
+type PowerStatus struct {
+	Saver  string `json:"saver"`            // "auto" or "always"
+	Saving bool   `json:"saving"`           // Whether models loaded now get fewer threads
+	Reason string `json:"reason,omitempty"` // Why, e.g. "on battery"
+}
+
+// StatusPage is the read-only view served at /status. It leaves out
+// addresses, ports and PIDs, which only the full API needs.
+type StatusPage struct {
+	Version        string            `json:"version"`
+	UptimeSeconds  float64           `json:"uptime_seconds"`
+	MaxModels      int               `json:"max_models"`
+	ActiveRequests int64             `json:"active_requests"`
+	Models         []StatusPageModel `json:"models"`
+}
+
+// StatusPageModel is one loaded model on the status page
+type StatusPageModel struct {
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	LoadedAt    time.Time `json:"loaded_at"`
+	IdleMinutes float64   `json:"idle_minutes"`
 }
 
 // OpenAIError represents an OpenAI-compatible error response
 type OpenAIError struct {
-	Error OpenAIErrorDetail `json:"error"`
+	Error     OpenAIErrorDetail `json:"error"`
+	RequestID string            `json:"request_id,omitempty"`
 }
 
 // OpenAIErrorDetail contains the error details
@@ -182,6 +330,7 @@ type OpenAIErrorDetail struct {
 	Message string `json:"message"`
 	Type    string `json:"type"`
 	Code    string `json:"code,omitempty"`
+	Param   string `json:"param,omitempty"`
 }
 
 // OpenAIModelsResponse represents the /v1/models response
@@ -200,11 +349,19 @@ type OpenAIModelInfo struct {
 }
 
 // LlemeStatus contains lleme-specific model status
+// Load fields are omitted for downloaded models that aren't running.
 type LlemeStatus struct {
-	Status       string    `json:"status"`
-	Port         int       `json:"port"`
-	LastActivity time.Time `json:"last_activity"`
-	LoadedAt     time.Time `json:"loaded_at"`
+	Status        string    `json:"status,omitempty"`
+	Port          int       `json:"port,omitempty"`
+	LastActivity  time.Time `json:"last_activity,omitzero"`
+	LoadedAt      time.Time `json:"loaded_at,omitzero"`
+	Tags          []string  `json:"tags,omitempty"`
+	Note          string    `json:"note,omitempty"`
+	Quant         string    `json:"quant,omitempty"`
+	Parameters    int64     `json:"parameters,omitempty"`     // Total weights across all tensors
+	ContextLength int       `json:"context_length,omitempty"` // Trained context length
+	Capabilities  []string  `json:"capabilities,omitempty"`   // "vision", "embedding"
+	SizeBytes     int64     `json:"size_bytes,omitempty"`     // On disk, all split parts
 }
 
 // RunRequest is the request body for POST /api/run
@@ -221,14 +378,130 @@ type RunRequest struct {
 
 	// Additional llama-server options can be passed as a map
 	Options map[string]any `json:"options,omitempty"`
+
+	// Passthrough options are passed to llama-server without checking, for
+	// flags the option catalog doesn't know. Options win over them.
+	Passthrough map[string]any `json:"passthrough,omitempty"`
+
+	// DryRun works out how the backend would be started without starting it
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// ServerOptions returns the llama-server options the request asks for, with
+// keys in CLI form and the explicit fields overriding Options
+func (r *RunRequest) ServerOptions() map[string]any {
+	opts := make(map[string]any)
+	// Normalize keys to hyphens (llama-server CLI format)
+	for k, v := range r.Options {
+		opts[options.NormalizeKey(k)] = v
+	}
+	// Explicit fields override additional options (CLI flags > persona options)
+	if r.CtxSize != nil {
+		opts["ctx-size"] = *r.CtxSize
+	}
+	if r.GpuLayers != nil {
+		opts["gpu-layers"] = *r.GpuLayers
+	}
+	if r.Threads != nil {
+		opts["threads"] = *r.Threads
+	}
+	return opts
+}
+
+// requestFlags are llama-server flags outside the option catalog that a
+// request's passthrough may set. They only tune how the backend runs.
+var requestFlags = map[string]bool{
+	"no-warmup": true, "cache-ram": true, "ctx-checkpoints": true, "no-context-shift": true,
+	"no-cont-batching": true, "no-repack": true, "no-op-offload": true, "check-tensors": true,
+	"prio": true, "poll": true,
+}
+
+// allowedInRequest reports whether a request may set the llama-server flag
+// key: catalog options that don't take a path, and requestFlags. The rest,
+// such as flags lleme manages, files on the server's machine, or flags that
+// fetch models or connect elsewhere (hf-repo, model-url, rpc), are
+// config-only.
+func allowedInRequest(key string) bool {
+	if opt, ok := config.LookupServerOption(key); ok {
+		return !opt.Path
+	}
+	return requestFlags[key]
+}
+
+// requestLaunchOptions checks a request's options against the catalog and
+// both them and its passthrough options against allowedInRequest, and
+// returns them merged, with options winning
+func requestLaunchOptions(opts, passthrough map[string]any) (map[string]any, error) {
+	merged := make(map[string]any, len(opts)+len(passthrough))
+	for k, v := range opts {
+		merged[options.NormalizeKey(k)] = v
+	}
+	if err := config.ValidateServerOptions(merged); err != nil {
+		return nil, err
+	}
+	for _, key := range slices.Sorted(maps.Keys(merged)) {
+		if !allowedInRequest(key) {
+			return nil, fmt.Errorf("option %q can't be set in a request; set it in config", key)
+		}
+	}
+	for k, v := range passthrough {
+		key := options.NormalizeKey(k)
+		if key == "" || strings.HasPrefix(key, "-") || strings.ContainsAny(key, "= \t") {
+			return nil, fmt.Errorf("invalid passthrough option %q: use the flag name without dashes", k)
+		}
+		if !allowedInRequest(key) {
+			return nil, fmt.Errorf("passthrough option %q can't be set in a request; set it in config", key)
+		}
+		if _, ok := merged[key]; !ok {
+			merged[key] = v
+		}
+	}
+	return merged, nil
+}
+
+// LaunchOptions checks the request's options and passthrough options with
+// requestLaunchOptions and returns them merged
+func (r *RunRequest) LaunchOptions() (map[string]any, error) {
+	return requestLaunchOptions(r.ServerOptions(), r.Passthrough)
 }
 
 // RunResponse is the response for POST /api/run
 type RunResponse struct {
-	Success bool   `json:"success"`
-	Model   string `json:"model"`
-	Status  string `json:"status"`
-	Port    int    `json:"port"`
+	Success bool          `json:"success"`
+	Model   string        `json:"model"`
+	Status  string        `json:"status"`
+	Port    int           `json:"port"`
+	Launch  *LaunchConfig `json:"launch,omitempty"` // The command a dry run would start
+}
+
+// PullRequest is the request body for POST /api/pull
+type PullRequest struct {
+	Model string `json:"model"` // user/repo or user/repo:quant
+}
+
+// PullEvent is one line of the newline-delimited JSON stream returned by
+// POST /api/pull. The last line has phase "done" with the model's full name,
+// or phase "error" with the reason.
+type PullEvent struct {
+	Phase     string `json:"phase"` // "deferred", "download", "verify", "done" or "error"
+	Completed int64  `json:"completed,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"` // Why a pull is deferred
+}
+
+// JobInfo describes a background job submitted with POST /api/jobs. Output
+// is only included when a single job is fetched.
+type JobInfo struct {
+	ID       string    `json:"id"`
+	Model    string    `json:"model"`
+	Client   string    `json:"client,omitempty"` // server.clients profile that started it
+	Status   string    `json:"status"`           // "running", "done", "error" or "cancelled"
+	Created  time.Time `json:"created"`
+	Finished time.Time `json:"finished,omitzero"`
+	Error    string    `json:"error,omitempty"`
+	Output   string    `json:"output,omitempty"`
 }
 
 // Anthropic API error types
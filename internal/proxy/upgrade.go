@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+const restoreFile = "restore.json"
+
+// Executable identifies the binary a server was started from, so the CLI
+// can tell when a package manager has replaced it underneath the server.
+type Executable struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// currentExecutable describes the running binary, with symlinks resolved
+// so that a Homebrew install records its versioned Cellar path.
+func currentExecutable() (*Executable, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Executable{Path: path, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// BinaryChanged reports whether the server described by state is running
+// an older binary than the one installed now: the file it started from is
+// gone or was rewritten, or this CLI is a different build from a different
+// path. State from servers that predate the check never reports a change.
+func BinaryChanged(state *ProxyState, cliVersion string) bool {
+	if state == nil || state.Executable == nil {
+		return false
+	}
+	recorded := state.Executable
+	info, err := os.Stat(recorded.Path)
+	if err != nil {
+		return true
+	}
+	if info.Size() != recorded.Size || !info.ModTime().Equal(recorded.ModTime) {
+		return true
+	}
+	cli, err := currentExecutable()
+	if err != nil {
+		return false
+	}
+	return cli.Path != recorded.Path && state.Version != "" && state.Version != cliVersion
+}
+
+// restorePath returns where the models to reload after a restart are kept
+func restorePath() string {
+	return filepath.Join(paths.Pids(), restoreFile)
+}
+
+// SaveRestoreList records models for the next server to load when it
+// starts, so a restart doesn't lose what was loaded.
+func SaveRestoreList(backends []BackendState) error {
+	if len(backends) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(backends, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(paths.Pids(), 0755); err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(restorePath(), data, 0644)
+}
+
+// takeRestoreList reads and removes the restore list. A missing list is
+// not an error.
+func takeRestoreList() ([]BackendState, error) {
+	path := restorePath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, err
+	}
+	var backends []BackendState
+	if err := json.Unmarshal(data, &backends); err != nil {
+		return nil, err
+	}
+	return backends, nil
+}
+
+// restoreBackends loads the models a previous server asked to have
+// reloaded. Loads run in the background so the server answers requests
+// meanwhile; failures are only logged.
+func (s *Server) restoreBackends() {
+	backends, err := takeRestoreList()
+	if err != nil {
+		logs.Warn("Failed to read models to restore", "error", err)
+		return
+	}
+	if len(backends) == 0 {
+		return
+	}
+	go func() {
+		for _, b := range backends {
+			logs.Info("Restoring model", "model", b.ModelName)
+			var err error
+			if b.Kind == BackendImage.String() {
+				_, err = s.manager.GetOrLoadImageBackend(b.ModelName)
+			} else {
+				_, err = s.manager.GetOrLoadBackend(b.ModelName, b.Options)
+			}
+			if err != nil {
+				logs.Warn("Failed to restore model", "model", b.ModelName, "error", err)
+			}
+		}
+	}()
+}
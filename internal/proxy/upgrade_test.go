@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func recordExecutable(t *testing.T, path string) *Executable {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Executable{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+}
+
+func TestBinaryChanged(t *testing.T) {
+	cli, err := currentExecutable()
+	if err != nil {
+		t.Skipf("can't identify test binary: %v", err)
+	}
+
+	if BinaryChanged(&ProxyState{}, "1.0.0") {
+		t.Error("state from an older server reports a change")
+	}
+	if BinaryChanged(&ProxyState{Version: "1.0.0", Executable: cli}, "2.0.0") {
+		t.Error("the same unchanged binary reports a change")
+	}
+
+	t.Run("replaced in place", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lleme")
+		if err := os.WriteFile(path, []byte("old build"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		state := &ProxyState{Version: "1.0.0", Executable: recordExecutable(t, path)}
+		if err := os.WriteFile(path, []byte("new build!"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if !BinaryChanged(state, "1.0.0") {
+			t.Error("a rewritten binary doesn't report a change")
+		}
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lleme")
+		if err := os.WriteFile(path, []byte("old build"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		state := &ProxyState{Version: "1.0.0", Executable: recordExecutable(t, path)}
+		os.Remove(path)
+		if !BinaryChanged(state, "1.0.0") {
+			t.Error("a removed binary doesn't report a change")
+		}
+	})
+
+	t.Run("different install", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "lleme")
+		if err := os.WriteFile(path, []byte("old build"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		state := &ProxyState{Version: "1.0.0", Executable: recordExecutable(t, path)}
+		if !BinaryChanged(state, "2.0.0") {
+			t.Error("a newer CLI elsewhere doesn't report a change")
+		}
+		if BinaryChanged(state, "1.0.0") {
+			t.Error("the same version elsewhere reports a change")
+		}
+	})
+}
+
+func TestRestoreList(t *testing.T) {
+	useTestHome(t)
+
+	if backends, err := takeRestoreList(); err != nil || backends != nil {
+		t.Fatalf("takeRestoreList() with no list = %v, %v", backends, err)
+	}
+
+	saved := []BackendState{
+		{ModelName: "u/r:Q4_K_M", Kind: "llama", Options: map[string]any{"ctx-size": float64(8192)}, StartedAt: time.Now()},
+		{ModelName: "u/sd:Q8_0", Kind: "image"},
+	}
+	if err := SaveRestoreList(saved); err != nil {
+		t.Fatalf("SaveRestoreList() error = %v", err)
+	}
+	backends, err := takeRestoreList()
+	if err != nil {
+		t.Fatalf("takeRestoreList() error = %v", err)
+	}
+	if len(backends) != 2 || backends[0].Options["ctx-size"] != float64(8192) || backends[1].Kind != "image" {
+		t.Errorf("takeRestoreList() = %+v", backends)
+	}
+	if backends, _ := takeRestoreList(); backends != nil {
+		t.Errorf("list still there after taking it: %+v", backends)
+	}
+}
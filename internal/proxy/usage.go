@@ -0,0 +1,184 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/nchapman/lleme/internal/hw"
+	"github.com/nchapman/lleme/internal/logs"
+)
+
+const (
+	// usageSampleInterval is how often backend CPU and GPU use is sampled
+	usageSampleInterval = 5 * time.Second
+
+	// usageSamples is how many samples are kept per backend, a minute's worth
+	usageSamples = int(time.Minute / usageSampleInterval)
+)
+
+// Process probes, overridden in tests
+var (
+	processCPUTime        = hw.ProcessCPUTime
+	gpuProcessUtilization = hw.GPUProcessUtilization
+)
+
+// usageSample is one measurement of a backend's CPU and GPU use
+type usageSample struct {
+	cpu    float64 // Percent, with one core as 100
+	gpu    float64 // Percent of the GPU's SMs, summed across GPUs
+	hasGPU bool    // Whether GPU use could be read for this sample
+}
+
+// usageHistory is a ring buffer of a backend's recent samples, with the CPU
+// time seen at the last one to measure the next against
+type usageHistory struct {
+	samples [usageSamples]usageSample
+	count   int // Samples stored, up to usageSamples
+	next    int // Index the next sample goes in
+
+	lastCPU time.Duration
+	lastAt  time.Time
+}
+
+// record turns a reading of the process's total CPU time into a sample. The
+// first reading only sets the baseline.
+func (h *usageHistory) record(at time.Time, cpuTime time.Duration, gpu float64, hasGPU bool) {
+	elapsed, used := at.Sub(h.lastAt), cpuTime-h.lastCPU
+	first := h.lastAt.IsZero()
+	h.lastCPU, h.lastAt = cpuTime, at
+	if first || elapsed <= 0 {
+		return
+	}
+
+	h.samples[h.next] = usageSample{
+		cpu:    float64(used) / float64(elapsed) * 100,
+		gpu:    gpu,
+		hasGPU: hasGPU,
+	}
+	h.next = (h.next + 1) % usageSamples
+	h.count = min(h.count+1, usageSamples)
+}
+
+// stats returns the latest sample and the average over the buffer, or nil
+// before there's a sample
+func (h *usageHistory) stats() *BackendUsage {
+	if h.count == 0 {
+		return nil
+	}
+	latest := h.samples[(h.next+usageSamples-1)%usageSamples]
+	usage := &BackendUsage{CPUPercent: round1(latest.cpu), Samples: h.count}
+
+	var cpuSum, gpuSum float64
+	gpuCount := 0
+	for i := range h.count {
+		s := h.samples[i]
+		cpuSum += s.cpu
+		if s.hasGPU {
+			gpuSum += s.gpu
+			gpuCount++
+		}
+	}
+	usage.CPUPercentAvg1m = round1(cpuSum / float64(h.count))
+	if latest.hasGPU {
+		gpu := round1(latest.gpu)
+		usage.GPUPercent = &gpu
+	}
+	if gpuCount > 0 {
+		avg := round1(gpuSum / float64(gpuCount))
+		usage.GPUPercentAvg1m = &avg
+	}
+	return usage
+}
+
+// round1 rounds to one decimal place, which is all a percentage needs
+func round1(f float64) float64 {
+	return float64(int64(f*10+0.5)) / 10
+}
+
+// recordUsage adds a reading of the backend's CPU time and GPU use
+func (b *Backend) recordUsage(at time.Time, cpuTime time.Duration, gpu float64, hasGPU bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.usage.record(at, cpuTime, gpu, hasGPU)
+}
+
+// Usage returns the backend's recent CPU and GPU use, or nil before it's
+// been sampled twice
+func (b *Backend) Usage() *BackendUsage {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.usage.stats()
+}
+
+// UsageSampler periodically samples the CPU and GPU use of each backend
+// process, for capacity planning from /api/status
+type UsageSampler struct {
+	manager     *ModelManager
+	interval    time.Duration
+	stopChan    chan struct{}
+	stoppedChan chan struct{}
+}
+
+// NewUsageSampler creates a new usage sampler
+func NewUsageSampler(manager *ModelManager, interval time.Duration) *UsageSampler {
+	return &UsageSampler{
+		manager:     manager,
+		interval:    interval,
+		stopChan:    make(chan struct{}),
+		stoppedChan: make(chan struct{}),
+	}
+}
+
+// Start begins the sampling loop
+func (u *UsageSampler) Start() {
+	go u.run()
+}
+
+// Stop stops the sampler
+func (u *UsageSampler) Stop() {
+	close(u.stopChan)
+	<-u.stoppedChan
+}
+
+func (u *UsageSampler) run() {
+	defer close(u.stoppedChan)
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.stopChan:
+			return
+		case <-ticker.C:
+			u.sample()
+		}
+	}
+}
+
+// sample reads each backend process's CPU time, and per-process GPU use
+// once for all of them where the platform reports it
+func (u *UsageSampler) sample() {
+	u.manager.mu.RLock()
+	backends := make([]*Backend, 0, len(u.manager.backends))
+	for _, b := range u.manager.backends {
+		if b.Process != nil {
+			backends = append(backends, b)
+		}
+	}
+	u.manager.mu.RUnlock()
+	if len(backends) == 0 {
+		return
+	}
+
+	gpu := gpuProcessUtilization()
+	now := u.manager.clock.Now()
+	for _, b := range backends {
+		cpuTime, err := processCPUTime(b.Process.Pid)
+		if err != nil {
+			logs.Debug("Failed to sample backend CPU time", "model", b.ModelName, "error", err)
+			continue
+		}
+		// A process missing from a GPU report isn't using the GPU
+		b.recordUsage(now, cpuTime, gpu[b.Process.Pid], gpu != nil)
+	}
+}
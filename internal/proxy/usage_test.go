@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestUsageHistory(t *testing.T) {
+	var h usageHistory
+	start := time.Now()
+
+	h.record(start, 10*time.Second, 0, false)
+	if h.stats() != nil {
+		t.Fatal("first reading should only set the baseline")
+	}
+
+	// 1.5s of CPU in 1s: one and a half cores
+	h.record(start.Add(time.Second), 11500*time.Millisecond, 40, true)
+	u := h.stats()
+	if u == nil || u.CPUPercent != 150 || u.CPUPercentAvg1m != 150 || u.Samples != 1 {
+		t.Fatalf("stats = %+v", u)
+	}
+	if u.GPUPercent == nil || *u.GPUPercent != 40 || *u.GPUPercentAvg1m != 40 {
+		t.Errorf("gpu = %v / %v", u.GPUPercent, u.GPUPercentAvg1m)
+	}
+
+	// Idle for a second, without a GPU reading
+	h.record(start.Add(2*time.Second), 11500*time.Millisecond, 0, false)
+	u = h.stats()
+	if u.CPUPercent != 0 || u.CPUPercentAvg1m != 75 || u.Samples != 2 {
+		t.Errorf("stats = %+v", u)
+	}
+	if u.GPUPercent != nil || u.GPUPercentAvg1m == nil || *u.GPUPercentAvg1m != 40 {
+		t.Errorf("gpu = %v / %v, want none now and 40 on average", u.GPUPercent, u.GPUPercentAvg1m)
+	}
+}
+
+func TestUsageHistoryWraps(t *testing.T) {
+	var h usageHistory
+	at := time.Now()
+	cpu := time.Duration(0)
+	h.record(at, cpu, 0, false)
+
+	// A full buffer at 100%, then half the buffer at 50%
+	for range usageSamples {
+		at, cpu = at.Add(time.Second), cpu+time.Second
+		h.record(at, cpu, 0, false)
+	}
+	for range usageSamples / 2 {
+		at, cpu = at.Add(time.Second), cpu+time.Second/2
+		h.record(at, cpu, 0, false)
+	}
+
+	u := h.stats()
+	if u.Samples != usageSamples || u.CPUPercent != 50 || u.CPUPercentAvg1m != 75 {
+		t.Errorf("stats = %+v", u)
+	}
+}
+
+func TestUsageSamplerSample(t *testing.T) {
+	cpuTimes := map[int]time.Duration{100: time.Second, 200: time.Second}
+	origCPU, origGPU := processCPUTime, gpuProcessUtilization
+	defer func() { processCPUTime, gpuProcessUtilization = origCPU, origGPU }()
+	processCPUTime = func(pid int) (time.Duration, error) {
+		if d, ok := cpuTimes[pid]; ok {
+			return d, nil
+		}
+		return 0, errors.New("no such process")
+	}
+	gpuProcessUtilization = func() map[int]float64 { return map[int]float64{100: 80} }
+
+	m := NewModelManager(DefaultConfig(), nil)
+	withGPU := &Backend{ModelName: "a", Process: &os.Process{Pid: 100}}
+	cpuOnly := &Backend{ModelName: "b", Process: &os.Process{Pid: 200}}
+	gone := &Backend{ModelName: "c", Process: &os.Process{Pid: 300}}
+	mock := &Backend{ModelName: "d"}
+	for _, b := range []*Backend{withGPU, cpuOnly, gone, mock} {
+		m.backends[b.ModelName] = b
+	}
+
+	u := NewUsageSampler(m, time.Second)
+	u.sample()
+	cpuTimes[100] += 500 * time.Millisecond
+	time.Sleep(20 * time.Millisecond)
+	u.sample()
+
+	if got := withGPU.Usage(); got == nil || got.CPUPercent <= 0 || got.GPUPercent == nil || *got.GPUPercent != 80 {
+		t.Errorf("usage of a GPU backend = %+v", got)
+	}
+	if got := cpuOnly.Usage(); got == nil || got.CPUPercent != 0 || got.GPUPercent == nil || *got.GPUPercent != 0 {
+		t.Errorf("usage of a backend not on the GPU = %+v", got)
+	}
+	if gone.Usage() != nil || mock.Usage() != nil {
+		t.Error("backends without a readable process should have no usage")
+	}
+
+	for _, info := range m.ListBackends() {
+		if info.ModelName == "a" && info.Usage == nil {
+			t.Error("ListBackends should include usage")
+		}
+	}
+}
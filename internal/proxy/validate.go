@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// requestError is a client mistake caught before the request reaches a
+// backend. Param names the offending field in OpenAI's path notation, e.g.
+// "messages[2].role".
+type requestError struct {
+	param   string
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string { return e.message }
+
+func invalidValue(param, format string, args ...any) *requestError {
+	return &requestError{param: param, code: "invalid_value", message: fmt.Sprintf(format, args...)}
+}
+
+// chatRoles are the message roles llama-server's chat templates handle
+var chatRoles = []string{"system", "developer", "user", "assistant", "tool", "function"}
+
+// numberRange bounds a numeric request field
+type numberRange struct {
+	param    string
+	min, max float64
+	integer  bool
+}
+
+var chatRanges = []numberRange{
+	{"temperature", 0, 2, false},
+	{"top_p", 0, 1, false},
+	{"presence_penalty", -2, 2, false},
+	{"frequency_penalty", -2, 2, false},
+	{"max_tokens", 1, math.MaxInt32, true},
+	{"max_completion_tokens", 1, math.MaxInt32, true},
+	{"n", 1, maxChoices, true},
+	{"top_logprobs", 0, maxTopLogprobs, true},
+}
+
+// validateChatRequest checks a chat completion body for mistakes that
+// llama-server would otherwise answer with a vague error or ignore. Null
+// values count as absent.
+func validateChatRequest(body []byte) *requestError {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return &requestError{message: "Failed to parse request body"}
+	}
+
+	if err := validateMessages(req["messages"]); err != nil {
+		return err
+	}
+
+	for _, r := range chatRanges {
+		if err := r.check(req); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := req["logprobs"]; ok && v != nil {
+		if _, ok := v.(bool); !ok {
+			return invalidValue("logprobs", "Invalid type for 'logprobs': expected a boolean")
+		}
+	}
+	if top, ok := req["top_logprobs"]; ok && top != nil && req["logprobs"] != true {
+		return invalidValue("top_logprobs", "Invalid value for 'top_logprobs': logprobs must be true when top_logprobs is set")
+	}
+	if v, ok := req["stream"]; ok && v != nil {
+		if _, ok := v.(bool); !ok {
+			return invalidValue("stream", "Invalid type for 'stream': expected a boolean")
+		}
+	}
+	return nil
+}
+
+func (r numberRange) check(req map[string]any) *requestError {
+	v, ok := req[r.param]
+	if !ok || v == nil {
+		return nil
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return invalidValue(r.param, "Invalid type for '%s': expected a number", r.param)
+	}
+	if r.integer && n != math.Trunc(n) {
+		return invalidValue(r.param, "Invalid type for '%s': expected an integer", r.param)
+	}
+	// llama.cpp clients pass -1 for no token limit
+	if n == -1 && (r.param == "max_tokens" || r.param == "max_completion_tokens") {
+		return nil
+	}
+	if n < r.min || n > r.max {
+		if r.max == math.MaxInt32 {
+			return invalidValue(r.param, "Invalid value for '%s': must be at least %g", r.param, r.min)
+		}
+		return invalidValue(r.param, "Invalid value for '%s': must be between %g and %g", r.param, r.min, r.max)
+	}
+	return nil
+}
+
+func validateMessages(v any) *requestError {
+	if v == nil {
+		return &requestError{param: "messages", code: "missing_required_parameter", message: "Missing required parameter: 'messages'"}
+	}
+	messages, ok := v.([]any)
+	if !ok {
+		return invalidValue("messages", "Invalid type for 'messages': expected an array")
+	}
+	if len(messages) == 0 {
+		return invalidValue("messages", "Invalid 'messages': empty array. Expected at least one message")
+	}
+
+	for i, m := range messages {
+		param := fmt.Sprintf("messages[%d]", i)
+		msg, ok := m.(map[string]any)
+		if !ok {
+			return invalidValue(param, "Invalid type for '%s': expected an object", param)
+		}
+
+		role, _ := msg["role"].(string)
+		if role == "" {
+			return &requestError{param: param + ".role", code: "missing_required_parameter", message: fmt.Sprintf("Missing required parameter: '%s.role'", param)}
+		}
+		if !slices.Contains(chatRoles, role) {
+			return invalidValue(param+".role", "Invalid value for '%s.role': %q is not one of %v", param, role, chatRoles)
+		}
+
+		switch msg["content"].(type) {
+		case string, []any:
+		case nil:
+			// Assistant turns that only call tools have no content
+			if role != "assistant" {
+				return &requestError{param: param + ".content", code: "missing_required_parameter", message: fmt.Sprintf("Missing required parameter: '%s.content'", param)}
+			}
+		default:
+			return invalidValue(param+".content", "Invalid type for '%s.content': expected a string or an array of content parts", param)
+		}
+
+		if role == "tool" {
+			if id, _ := msg["tool_call_id"].(string); id == "" {
+				return &requestError{param: param + ".tool_call_id", code: "missing_required_parameter", message: fmt.Sprintf("Missing required parameter: '%s.tool_call_id'", param)}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateChatRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantParam string // "" when valid
+		wantCode  string
+	}{
+		{"valid", `{"model":"m","messages":[{"role":"user","content":"hi"}],"temperature":0.7,"max_tokens":100,"stream":true}`, "", ""},
+		{"content parts", `{"messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`, "", ""},
+		{"tool call turn", `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":null,"tool_calls":[]},{"role":"tool","tool_call_id":"1","content":"ok"}]}`, "", ""},
+		{"nulls are absent", `{"messages":[{"role":"user","content":"hi"}],"temperature":null,"n":null}`, "", ""},
+		{"unlimited tokens", `{"messages":[{"role":"user","content":"hi"}],"max_tokens":-1}`, "", ""},
+		{"n of one", `{"messages":[{"role":"user","content":"hi"}],"n":1}`, "", ""},
+		{"missing messages", `{"model":"m"}`, "messages", "missing_required_parameter"},
+		{"messages not array", `{"messages":"hi"}`, "messages", "invalid_value"},
+		{"empty messages", `{"messages":[]}`, "messages", "invalid_value"},
+		{"message not object", `{"messages":["hi"]}`, "messages[0]", "invalid_value"},
+		{"missing role", `{"messages":[{"content":"hi"}]}`, "messages[0].role", "missing_required_parameter"},
+		{"bad role", `{"messages":[{"role":"user","content":"hi"},{"role":"bot","content":"x"}]}`, "messages[1].role", "invalid_value"},
+		{"missing user content", `{"messages":[{"role":"user"}]}`, "messages[0].content", "missing_required_parameter"},
+		{"numeric content", `{"messages":[{"role":"user","content":5}]}`, "messages[0].content", "invalid_value"},
+		{"tool without id", `{"messages":[{"role":"tool","content":"ok"}]}`, "messages[0].tool_call_id", "missing_required_parameter"},
+		{"temperature too high", `{"messages":[{"role":"user","content":"hi"}],"temperature":3}`, "temperature", "invalid_value"},
+		{"top_p negative", `{"messages":[{"role":"user","content":"hi"}],"top_p":-0.1}`, "top_p", "invalid_value"},
+		{"temperature string", `{"messages":[{"role":"user","content":"hi"}],"temperature":"hot"}`, "temperature", "invalid_value"},
+		{"zero max_tokens", `{"messages":[{"role":"user","content":"hi"}],"max_tokens":0}`, "max_tokens", "invalid_value"},
+		{"fractional max_tokens", `{"messages":[{"role":"user","content":"hi"}],"max_completion_tokens":1.5}`, "max_completion_tokens", "invalid_value"},
+		{"n above one", `{"messages":[{"role":"user","content":"hi"}],"n":2}`, "", ""},
+		{"n above limit", `{"messages":[{"role":"user","content":"hi"}],"n":17}`, "n", "invalid_value"},
+		{"logprobs", `{"messages":[{"role":"user","content":"hi"}],"logprobs":true,"top_logprobs":5}`, "", ""},
+		{"top_logprobs without logprobs", `{"messages":[{"role":"user","content":"hi"}],"top_logprobs":5}`, "top_logprobs", "invalid_value"},
+		{"top_logprobs too high", `{"messages":[{"role":"user","content":"hi"}],"logprobs":true,"top_logprobs":21}`, "top_logprobs", "invalid_value"},
+		{"stream not bool", `{"messages":[{"role":"user","content":"hi"}],"stream":"yes"}`, "stream", "invalid_value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChatRequest([]byte(tt.body))
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v (param %s)", err, err.param)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error on %s", tt.wantParam)
+			}
+			if err.param != tt.wantParam || err.code != tt.wantCode {
+				t.Errorf("param, code = %s, %s; want %s, %s (%s)", err.param, err.code, tt.wantParam, tt.wantCode, err.message)
+			}
+		})
+	}
+}
+
+func TestChatCompletionsValidationResponse(t *testing.T) {
+	s := &Server{config: DefaultConfig()}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		bytes.NewBufferString(`{"model":"m","messages":[{"role":"user","content":"hi"}],"temperature":5}`))
+	w := httptest.NewRecorder()
+
+	// Rejected before the model is resolved, so no manager is needed
+	s.handleChatCompletions(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	var resp OpenAIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := OpenAIErrorDetail{
+		Message: "Invalid value for 'temperature': must be between 0 and 2",
+		Type:    "invalid_request_error",
+		Code:    "invalid_value",
+		Param:   "temperature",
+	}
+	if resp.Error != want {
+		t.Errorf("error = %+v, want %+v", resp.Error, want)
+	}
+}
@@ -0,0 +1,204 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/options"
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// Client is the server a script's prompts are sent to; *server.APIClient
+// is one
+type Client interface {
+	Run(model string, opts *server.RunOptions) error
+	StreamChatCompletion(ctx context.Context, req *server.ChatCompletionRequest, cb server.StreamCallback) error
+}
+
+// Runner runs scripts against a server
+type Runner struct {
+	Client Client
+
+	// ResolveModel turns a model line's query into the name to load. When
+	// nil, the query is used as is.
+	ResolveModel func(query string) (string, error)
+
+	Out      io.Writer    // Replies and print output
+	Progress func(string) // Told which prompt is running and what's loading; may be nil
+	Quiet    bool         // Only write print output, not every reply
+
+	// Vars holds variables, and can be filled before running to pass
+	// values in
+	Vars map[string]string
+}
+
+// requestOptions are set on each chat request rather than when the model
+// is loaded, so changing them doesn't reload it
+var requestOptions = map[string]bool{
+	"temp": true, "top-p": true, "top-k": true, "min-p": true, "repeat-penalty": true, "n-predict": true,
+}
+
+// runState is what a script has set so far
+type runState struct {
+	model   string
+	options map[string]any
+	system  string
+
+	// What the model was last loaded with, to reload only on a change
+	loadedModel   string
+	loadedOptions map[string]any
+}
+
+// Run runs the script's statements in order, stopping at the first error.
+func (r *Runner) Run(ctx context.Context, s *Script) error {
+	if r.Vars == nil {
+		r.Vars = make(map[string]string)
+	}
+	st := &runState{options: make(map[string]any)}
+
+	for i, stmt := range s.Statements {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.run(ctx, st, stmt, i+1, len(s.Statements)); err != nil {
+			return &Error{Script: s.Name, Line: stmt.Line, Err: err}
+		}
+	}
+	return nil
+}
+
+// run runs one statement, the nth of total
+func (r *Runner) run(ctx context.Context, st *runState, stmt Statement, n, total int) error {
+	switch stmt.Kind {
+	case Model:
+		model := stmt.Name
+		if r.ResolveModel != nil {
+			resolved, err := r.ResolveModel(model)
+			if err != nil {
+				return err
+			}
+			model = resolved
+		}
+		st.model = model
+
+	case Set:
+		st.options[stmt.Name] = stmt.Value
+
+	case Unset:
+		delete(st.options, stmt.Name)
+
+	case System:
+		text, err := expand(stmt.Text(), r.Vars)
+		if err != nil {
+			return err
+		}
+		st.system = strings.TrimSpace(text)
+
+	case Let:
+		text, err := expand(stmt.Text(), r.Vars)
+		if err != nil {
+			return err
+		}
+		r.Vars[stmt.Name] = text
+
+	case Print:
+		text, err := expand(stmt.Text(), r.Vars)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.Out, text)
+
+	case Prompt:
+		text, err := expand(stmt.Text(), r.Vars)
+		if err != nil {
+			return err
+		}
+		r.progress("[%d/%d] prompt at line %d (%s)", n, total, stmt.Line, st.model)
+		if err := r.load(st); err != nil {
+			return err
+		}
+		reply, err := r.ask(ctx, st, text)
+		if err != nil {
+			return err
+		}
+		if stmt.Name != "" {
+			r.Vars[stmt.Name] = reply
+		}
+	}
+	return nil
+}
+
+// load loads the model with the options set so far, unless it already was.
+// Request options are left out, since they don't need a reload.
+func (r *Runner) load(st *runState) error {
+	opts := make(map[string]any)
+	for key, value := range st.options {
+		if !requestOptions[key] {
+			opts[key] = value
+		}
+	}
+	if st.model == st.loadedModel && reflect.DeepEqual(opts, st.loadedOptions) {
+		return nil
+	}
+
+	r.progress("Loading %s", st.model)
+	var runOpts *server.RunOptions
+	if len(opts) > 0 {
+		runOpts = &server.RunOptions{Options: opts}
+	}
+	if err := r.Client.Run(st.model, runOpts); err != nil {
+		return fmt.Errorf("failed to load %s: %w", st.model, err)
+	}
+	st.loadedModel, st.loadedOptions = st.model, opts
+	return nil
+}
+
+// ask sends one prompt, writing the reply as it streams unless quiet, and
+// returns it
+func (r *Runner) ask(ctx context.Context, st *runState, prompt string) (string, error) {
+	var messages []server.ChatMessage
+	if st.system != "" {
+		messages = append(messages, server.ChatMessage{Role: "system", Content: st.system})
+	}
+	messages = append(messages, server.ChatMessage{Role: "user", Content: prompt})
+
+	resolved := options.Resolve(options.Layers{Flags: st.options})
+	req := &server.ChatCompletionRequest{
+		Model:           st.model,
+		Messages:        messages,
+		Stream:          true,
+		Temperature:     resolved.Float("temp"),
+		TopP:            resolved.Float("top-p"),
+		TopK:            resolved.Int("top-k"),
+		MinP:            resolved.Float("min-p"),
+		RepeatPenalty:   resolved.Float("repeat-penalty"),
+		MaxTokens:       resolved.Int("n-predict"),
+		ReasoningFormat: "auto",
+	}
+
+	var reply strings.Builder
+	err := r.Client.StreamChatCompletion(ctx, req, server.StreamCallback{
+		ContentCallback: func(s string) {
+			reply.WriteString(s)
+			if !r.Quiet {
+				io.WriteString(r.Out, s)
+			}
+		},
+	})
+	if !r.Quiet && reply.Len() > 0 {
+		fmt.Fprintln(r.Out)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(reply.String()), nil
+}
+
+func (r *Runner) progress(format string, args ...any) {
+	if r.Progress != nil {
+		r.Progress(fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,148 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// fakeClient records loads and answers each prompt with a canned reply
+type fakeClient struct {
+	loads    []string
+	requests []*server.ChatCompletionRequest
+	reply    func(prompt string) string
+	err      error
+}
+
+func (c *fakeClient) Run(model string, opts *server.RunOptions) error {
+	load := model
+	if opts != nil {
+		for _, key := range slices.Sorted(maps.Keys(opts.Options)) {
+			load += fmt.Sprintf(" %s=%v", key, opts.Options[key])
+		}
+	}
+	c.loads = append(c.loads, load)
+	return nil
+}
+
+func (c *fakeClient) StreamChatCompletion(ctx context.Context, req *server.ChatCompletionRequest, cb server.StreamCallback) error {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return c.err
+	}
+	prompt := req.Messages[len(req.Messages)-1].Content
+	for _, word := range strings.SplitAfter(c.reply(prompt), " ") {
+		cb.ContentCallback(word)
+	}
+	return nil
+}
+
+func runScript(t *testing.T, client *fakeClient, text string, quiet bool) (string, error) {
+	t.Helper()
+	s, err := Parse("test.lls", strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	var out strings.Builder
+	r := &Runner{Client: client, Out: &out, Quiet: quiet, Vars: map[string]string{"who": "Ada"}}
+	err = r.Run(context.Background(), s)
+	return out.String(), err
+}
+
+func TestRunChain(t *testing.T) {
+	client := &fakeClient{reply: func(prompt string) string { return "reply to " + prompt }}
+	out, err := runScript(t, client, example+"print {{who}}\n", false)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The first reply feeds the second prompt
+	if len(client.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(client.requests))
+	}
+	second := client.requests[1]
+	if got := second.Messages[1].Content; got != "Expand:\nreply to Outline RAID 5." {
+		t.Errorf("second prompt = %q", got)
+	}
+	if second.Messages[0].Role != "system" || second.Messages[0].Content != "You are terse." {
+		t.Errorf("system message = %+v", second.Messages[0])
+	}
+	if second.Temperature != 0.2 || second.Model != "llama3.2" {
+		t.Errorf("request = %+v, want temp 0.2 for llama3.2", second)
+	}
+
+	// Load options are sent once, request options never
+	if len(client.loads) != 1 || client.loads[0] != "llama3.2 ctx-size=8192" {
+		t.Errorf("loads = %q", client.loads)
+	}
+
+	want := "reply to Outline RAID 5.\nreply to Expand:\nreply to Outline RAID 5.\ndone\nAda\n"
+	if out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+}
+
+func TestRunQuiet(t *testing.T) {
+	client := &fakeClient{reply: func(string) string { return "42" }}
+	out, err := runScript(t, client, "model m\nprompt -> answer\nWhat is it?\nend\nprint The answer is {{answer}}\n", true)
+	if err != nil || out != "The answer is 42\n" {
+		t.Errorf("Run() = %q, %v", out, err)
+	}
+}
+
+func TestRunReloadsOnChange(t *testing.T) {
+	client := &fakeClient{reply: func(string) string { return "ok" }}
+	text := `model a
+prompt
+one
+end
+set temp 0.5
+prompt
+two
+end
+set ctx-size 4096
+prompt
+three
+end
+model b
+prompt
+four
+end
+`
+	if _, err := runScript(t, client, text, true); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "a ctx-size=4096", "b ctx-size=4096"}
+	if strings.Join(client.loads, "|") != strings.Join(want, "|") {
+		t.Errorf("loads = %q, want %q", client.loads, want)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	client := &fakeClient{reply: func(string) string { return "ok" }}
+	_, err := runScript(t, client, "model m\nprint {{nope}}\n", false)
+	var scriptErr *Error
+	if !errors.As(err, &scriptErr) || scriptErr.Line != 2 || !strings.Contains(err.Error(), "undefined variable 'nope'") {
+		t.Errorf("Run() error = %v, want undefined variable at line 2", err)
+	}
+
+	client.err = errors.New("backend crashed")
+	out, err := runScript(t, client, "model m\nprompt\nhi\nend\nprint after\n", false)
+	if err == nil || !strings.Contains(err.Error(), "test.lls:2: backend crashed") || strings.Contains(out, "after") {
+		t.Errorf("Run() = %q, %v; want it to stop at the failed prompt", out, err)
+	}
+
+	s, _ := Parse("test.lls", strings.NewReader("model m\n"))
+	r := &Runner{Client: client, Out: &strings.Builder{}, ResolveModel: func(string) (string, error) {
+		return "", errors.New("no downloaded model matches 'm'")
+	}}
+	if err := r.Run(context.Background(), s); err == nil || !strings.Contains(err.Error(), "no downloaded model") {
+		t.Errorf("Run() error = %v, want the resolve error", err)
+	}
+}
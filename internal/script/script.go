@@ -0,0 +1,227 @@
+// Package script runs .lls files: short prompt chains that pick a model,
+// set its options, and feed one reply into the next prompt through
+// variables, so an experiment can be rerun exactly without writing code.
+//
+// A script is a list of statements, one per line:
+//
+//	# Comments start with #
+//	model llama3.2              Use this model for the prompts that follow
+//	set temp 0.2                Set a llama-server option, as with /set
+//	unset temp                  Go back to the option's default
+//	system You are terse.       System prompt for the prompts that follow
+//	let topic = RAID 5          Set a variable
+//	prompt -> outline           Ask the lines up to "end", keeping the reply
+//	Outline {{topic}}.
+//	end
+//	print {{outline}}           Write text out
+//
+// {{name}} is replaced with a variable's value in system, let, prompt and
+// print text. system and let without a value on the line read a block up to
+// "end" instead, like prompt.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/config"
+)
+
+// Statement kinds
+const (
+	Model  = "model"
+	Set    = "set"
+	Unset  = "unset"
+	System = "system"
+	Let    = "let"
+	Prompt = "prompt"
+	Print  = "print"
+)
+
+// Statement is one parsed line of a script, or one block
+type Statement struct {
+	Line  int    // Line the statement starts on
+	Kind  string // One of the statement kinds
+	Name  string // Model query, option or variable name
+	Value any    // Option value for set; text for the others
+}
+
+// Text returns the statement's text, before variables are filled in
+func (s Statement) Text() string {
+	text, _ := s.Value.(string)
+	return text
+}
+
+// Script is a parsed script
+type Script struct {
+	Name       string
+	Statements []Statement
+}
+
+// Error is a problem with a script, at a line
+type Error struct {
+	Script string
+	Line   int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Script, e.Line, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// varNameRe matches variable names
+	varNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+	// varRefRe matches {{name}} references, with optional spaces
+	varRefRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_-]*)\s*\}\}`)
+)
+
+// Parse reads a script, checking its statements and option values before
+// anything runs, so a typo doesn't show up halfway through.
+func Parse(name string, r io.Reader) (*Script, error) {
+	s := &Script{Name: name}
+	fail := func(line int, format string, args ...any) error {
+		return &Error{Script: name, Line: line, Err: fmt.Errorf(format, args...)}
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	// block reads the lines after start up to "end"
+	block := func(start int) (string, int, error) {
+		for i := start + 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "end" {
+				return strings.Join(lines[start+1:i], "\n"), i, nil
+			}
+		}
+		return "", 0, fail(start+1, "%s block has no end", strings.Fields(lines[start])[0])
+	}
+
+	hasModel := false
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		stmt := Statement{Line: i + 1, Kind: keyword}
+
+		switch keyword {
+		case Model:
+			if rest == "" {
+				return nil, fail(stmt.Line, "model needs a model name")
+			}
+			stmt.Name = rest
+			hasModel = true
+
+		case Set:
+			key, value, _ := strings.Cut(rest, " ")
+			value = strings.TrimSpace(value)
+			if key == "" || value == "" {
+				return nil, fail(stmt.Line, "usage: set <option> <value>")
+			}
+			opt, ok := config.LookupServerOption(key)
+			if !ok {
+				return nil, fail(stmt.Line, "unknown option '%s'", key)
+			}
+			stmt.Name = opt.Name
+			stmt.Value = opt.Parse(value)
+			if err := config.ValidateServerOption(opt.Name, stmt.Value); err != nil {
+				return nil, fail(stmt.Line, "%v", err)
+			}
+
+		case Unset:
+			opt, ok := config.LookupServerOption(rest)
+			if !ok {
+				return nil, fail(stmt.Line, "unknown option '%s'", rest)
+			}
+			stmt.Name = opt.Name
+
+		case System:
+			stmt.Value = rest
+			if rest == "" {
+				text, end, err := block(i)
+				if err != nil {
+					return nil, err
+				}
+				stmt.Value, i = text, end
+			}
+
+		case Let:
+			name, value, hasValue := strings.Cut(rest, "=")
+			stmt.Name = strings.TrimSpace(name)
+			if !varNameRe.MatchString(stmt.Name) {
+				return nil, fail(stmt.Line, "usage: let <name> = <text>")
+			}
+			stmt.Value = strings.TrimSpace(value)
+			if !hasValue {
+				text, end, err := block(i)
+				if err != nil {
+					return nil, err
+				}
+				stmt.Value, i = text, end
+			}
+
+		case Prompt:
+			if rest != "" {
+				name, ok := strings.CutPrefix(rest, "->")
+				stmt.Name = strings.TrimSpace(name)
+				if !ok || !varNameRe.MatchString(stmt.Name) {
+					return nil, fail(stmt.Line, "usage: prompt [-> <variable>], then the prompt and end on their own lines")
+				}
+			}
+			if !hasModel {
+				return nil, fail(stmt.Line, "prompt before any model; add a model line first")
+			}
+			text, end, err := block(i)
+			if err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(text) == "" {
+				return nil, fail(stmt.Line, "empty prompt")
+			}
+			stmt.Value, i = text, end
+
+		case Print:
+			stmt.Value = rest
+
+		default:
+			return nil, fail(stmt.Line, "unknown statement '%s'", keyword)
+		}
+		s.Statements = append(s.Statements, stmt)
+	}
+	return s, nil
+}
+
+// expand replaces {{name}} references in text with vars' values
+func expand(text string, vars map[string]string) (string, error) {
+	var missing string
+	out := varRefRe.ReplaceAllStringFunc(text, func(ref string) string {
+		name := varRefRe.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable '%s'", missing)
+	}
+	return out, nil
+}
@@ -0,0 +1,107 @@
+package script
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const example = `# Outline, then expand
+model llama3.2
+set temp 0.2
+set ctx_size 8192
+system You are terse.
+let topic = RAID 5
+
+prompt -> outline
+Outline {{topic}}.
+end
+
+prompt
+Expand:
+{{ outline }}
+end
+
+unset temp
+print done
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse("example.lls", strings.NewReader(example))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	want := []Statement{
+		{Line: 2, Kind: Model, Name: "llama3.2"},
+		{Line: 3, Kind: Set, Name: "temp", Value: 0.2},
+		{Line: 4, Kind: Set, Name: "ctx-size", Value: 8192},
+		{Line: 5, Kind: System, Value: "You are terse."},
+		{Line: 6, Kind: Let, Name: "topic", Value: "RAID 5"},
+		{Line: 8, Kind: Prompt, Name: "outline", Value: "Outline {{topic}}."},
+		{Line: 12, Kind: Prompt, Value: "Expand:\n{{ outline }}"},
+		{Line: 17, Kind: Unset, Name: "temp"},
+		{Line: 18, Kind: Print, Value: "done"},
+	}
+	if len(s.Statements) != len(want) {
+		t.Fatalf("Parse() = %d statements, want %d: %+v", len(s.Statements), len(want), s.Statements)
+	}
+	for i, stmt := range s.Statements {
+		if stmt != want[i] {
+			t.Errorf("statement %d = %+v, want %+v", i, stmt, want[i])
+		}
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	s, err := Parse("blocks.lls", strings.NewReader("system\nLine one\nLine two\nend\nlet notes\n  indented\nend\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(s.Statements) != 2 || s.Statements[0].Text() != "Line one\nLine two" || s.Statements[1].Text() != "  indented" {
+		t.Errorf("Parse() = %+v", s.Statements)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		script string
+		line   int
+		want   string
+	}{
+		{"model llama3.2\nask hi\n", 2, "unknown statement 'ask'"},
+		{"model\n", 1, "needs a model name"},
+		{"set temp\n", 1, "usage: set"},
+		{"set warmth 2\n", 1, "unknown option 'warmth'"},
+		{"set top-p 2\n", 1, "top-p"},
+		{"set ctx-size lots\n", 1, "ctx-size"},
+		{"unset warmth\n", 1, "unknown option"},
+		{"let 2x = y\n", 1, "usage: let"},
+		{"prompt\nhi\nend\n", 1, "before any model"},
+		{"model m\n\nprompt -> \nhi\nend\n", 3, "usage: prompt"},
+		{"model m\nprompt\nhi\n", 2, "no end"},
+		{"model m\nprompt\n\nend\n", 2, "empty prompt"},
+	}
+	for _, tt := range tests {
+		_, err := Parse("bad.lls", strings.NewReader(tt.script))
+		var scriptErr *Error
+		if !errors.As(err, &scriptErr) {
+			t.Errorf("Parse(%q) error = %v, want a script error", tt.script, err)
+			continue
+		}
+		if scriptErr.Line != tt.line || !strings.Contains(err.Error(), tt.want) || !strings.HasPrefix(err.Error(), "bad.lls:") {
+			t.Errorf("Parse(%q) error = %v, want line %d containing %q", tt.script, err, tt.line, tt.want)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"topic": "RAID", "n": "3"}
+	got, err := expand("{{n}} facts about {{ topic }}, {not a var}", vars)
+	if err != nil || got != "3 facts about RAID, {not a var}" {
+		t.Errorf("expand() = %q, %v", got, err)
+	}
+	if _, err := expand("{{missing}}", vars); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("expand() of an undefined variable error = %v", err)
+	}
+}
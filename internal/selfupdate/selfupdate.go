@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/nchapman/lleme/internal/logs"
 	"github.com/nchapman/lleme/internal/version"
 )
 
@@ -47,7 +48,7 @@ func GetLatestVersion() (string, error) {
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", version.UserAgent())
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Transport: logs.Transport(nil), Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to fetch release: %w", err)
@@ -92,18 +93,24 @@ func DetectInstallMethod() InstallMethod {
 }
 
 func Update(method InstallMethod) error {
+	return UpdateWithOutput(method, os.Stdout)
+}
+
+// UpdateWithOutput is Update with the package manager's output sent to out
+// instead of stdout.
+func UpdateWithOutput(method InstallMethod, out io.Writer) error {
 	switch method {
 	case InstallHomebrew:
 		// Update Homebrew formulas first to ensure we get the latest version
 		updateCmd := exec.Command("brew", "update")
-		updateCmd.Stdout = os.Stdout
+		updateCmd.Stdout = out
 		updateCmd.Stderr = os.Stderr
 		if err := updateCmd.Run(); err != nil {
 			return fmt.Errorf("brew update failed: %w", err)
 		}
 
 		upgradeCmd := exec.Command("brew", "upgrade", "lleme")
-		upgradeCmd.Stdout = os.Stdout
+		upgradeCmd.Stdout = out
 		upgradeCmd.Stderr = os.Stderr
 		if err := upgradeCmd.Run(); err != nil {
 			return fmt.Errorf("brew upgrade failed: %w", err)
@@ -112,7 +119,7 @@ func Update(method InstallMethod) error {
 
 	case InstallGo:
 		cmd := exec.Command("go", "install", "github.com/nchapman/lleme@latest")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = out
 		cmd.Stderr = os.Stderr
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("go install failed: %w", err)
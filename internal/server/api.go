@@ -8,7 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/nchapman/lleme/internal/lang"
+	"github.com/nchapman/lleme/internal/logs"
 )
 
 type APIClient struct {
@@ -17,8 +21,75 @@ type APIClient struct {
 }
 
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"-"` // Data URLs for vision models, sent as image_url parts
+}
+
+// contentPart is one entry of an OpenAI multi-part message content
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON writes content as a plain string, or as text and image_url
+// parts when the message has images
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Images) == 0 {
+		return json.Marshal(struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}{m.Role, m.Content})
+	}
+
+	parts := []contentPart{{Type: "text", Text: m.Content}}
+	for _, url := range m.Images {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []contentPart `json:"content"`
+	}{m.Role, parts})
+}
+
+// UnmarshalJSON accepts content as a string or as multi-part content
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = ChatMessage{Role: raw.Role}
+
+	if len(raw.Content) == 0 || raw.Content[0] != '[' {
+		if len(raw.Content) > 0 && string(raw.Content) != "null" {
+			return json.Unmarshal(raw.Content, &m.Content)
+		}
+		return nil
+	}
+
+	var parts []contentPart
+	if err := json.Unmarshal(raw.Content, &parts); err != nil {
+		return err
+	}
+	var text []string
+	for _, p := range parts {
+		switch {
+		case p.Type == "text":
+			text = append(text, p.Text)
+		case p.Type == "image_url" && p.ImageURL != nil:
+			m.Images = append(m.Images, p.ImageURL.URL)
+		}
+	}
+	m.Content = strings.Join(text, "\n")
+	return nil
 }
 
 type StreamOptions struct {
@@ -106,17 +177,48 @@ func checkResponse(resp *http.Response, operation string) error {
 func NewAPIClient(host string, port int) *APIClient {
 	return &APIClient{
 		baseURL: fmt.Sprintf("http://%s:%d", host, port),
-		client:  &http.Client{},
+		client:  &http.Client{Transport: logs.Transport(nil)},
 	}
 }
 
 func NewAPIClientFromURL(baseURL string) *APIClient {
 	return &APIClient{
 		baseURL: baseURL,
-		client:  &http.Client{},
+		client:  &http.Client{Transport: logs.Transport(nil)},
 	}
 }
 
+// WithAPIKey sends key as a bearer token with every request, for servers
+// that require one from other machines. An empty key changes nothing.
+func (api *APIClient) WithAPIKey(key string) *APIClient {
+	if key != "" {
+		api.client.Transport = APIKeyTransport(key, api.client.Transport)
+	}
+	return api
+}
+
+// APIKeyTransport wraps base so requests without an Authorization header
+// carry key as a bearer token. A nil base uses http.DefaultTransport.
+func APIKeyTransport(key string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &apiKeyTransport{key: key, base: base}
+}
+
+type apiKeyTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Authorization", "Bearer "+t.key)
+	}
+	return t.base.RoundTrip(req)
+}
+
 func (api *APIClient) Health() error {
 	url := fmt.Sprintf("%s/health", api.baseURL)
 
@@ -166,46 +268,145 @@ func (api *APIClient) ChatCompletion(req *ChatCompletionRequest) (*ChatCompletio
 // ContentCallback is called for regular response content.
 // ReasoningCallback is called for reasoning/thinking content (optional).
 // TimingsCallback is called with timing stats from the final chunk (optional).
+// UsageCallback is called with token counts from the final chunk (optional);
+// a continued completion reports each request's usage separately.
 type StreamCallback struct {
 	ContentCallback   func(string)
 	ReasoningCallback func(string)
 	TimingsCallback   func(*Timings)
+	UsageCallback     func(*Usage)
 }
 
 func (api *APIClient) StreamChatCompletion(ctx context.Context, req *ChatCompletionRequest, cb StreamCallback) error {
+	_, err := api.streamChatCompletion(ctx, req, cb)
+	return err
+}
+
+// StreamChatCompletionContinued streams a chat completion like
+// StreamChatCompletion, but when the reply stops at the token limit it asks
+// the model to continue, up to maxContinues times. Each follow-up sends the
+// reply so far as a trailing assistant message, which llama-server extends
+// in place, so the callbacks see one uninterrupted reply. A request that
+// already ends with an assistant message continues that message.
+func (api *APIClient) StreamChatCompletionContinued(ctx context.Context, req *ChatCompletionRequest, cb StreamCallback, maxContinues int) error {
+	var content strings.Builder
+	messages := req.Messages
+	if n := len(messages); n > 0 && messages[n-1].Role == "assistant" {
+		content.WriteString(messages[n-1].Content)
+		messages = messages[:n-1]
+	}
+	prefill := content.Len()
+
+	inner := cb
+	inner.ContentCallback = func(s string) {
+		content.WriteString(s)
+		if cb.ContentCallback != nil {
+			cb.ContentCallback(s)
+		}
+	}
+	for i := 0; ; i++ {
+		finishReason, err := api.streamChatCompletion(ctx, req, inner)
+		if err != nil || finishReason != "length" || i >= maxContinues || content.Len() == prefill {
+			return err
+		}
+
+		next := *req
+		next.Messages = append(slices.Clone(messages), ChatMessage{Role: "assistant", Content: content.String()})
+		req = &next
+	}
+}
+
+// StreamChatCompletionInLanguage streams a chat completion like
+// StreamChatCompletionContinued, but holds back the start of the reply until
+// it can tell the reply's language. If that is clearly not language, an ISO
+// 639-1 code, the reply is dropped unseen and asked for once more with the
+// last user message reminding the model which language to use. An empty or
+// unknown language, or a reply being continued, streams unchecked.
+func (api *APIClient) StreamChatCompletionInLanguage(ctx context.Context, req *ChatCompletionRequest, cb StreamCallback, maxContinues int, language string) error {
+	guard := lang.NewGuard(language)
+	n := len(req.Messages)
+	if guard == nil || n == 0 || req.Messages[n-1].Role != "user" {
+		return api.StreamChatCompletionContinued(ctx, req, cb, maxContinues)
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	drifted := false
+	inner := cb
+	inner.ContentCallback = func(s string) {
+		if drifted {
+			return
+		}
+		out, ok := guard.Write(s)
+		if !ok {
+			drifted = true
+			cancel()
+			return
+		}
+		if out != "" && cb.ContentCallback != nil {
+			cb.ContentCallback(out)
+		}
+	}
+
+	err := api.StreamChatCompletionContinued(attemptCtx, req, inner, maxContinues)
+	if drifted && ctx.Err() != nil {
+		return err
+	}
+	if !drifted {
+		// A reply too short to judge while streaming is judged once
+		// complete; one cut short by an error or cancellation is shown as is
+		rest, ok := guard.Flush()
+		if ok || err != nil {
+			if rest != "" && cb.ContentCallback != nil {
+				cb.ContentCallback(rest)
+			}
+			return err
+		}
+	}
+
+	logs.Debug("Reply drifted from the requested language, asking again", "language", language)
+	retry := *req
+	retry.Messages = slices.Clone(req.Messages)
+	retry.Messages[n-1].Content += "\n\n(" + lang.Instruction(language) + ")"
+	return api.StreamChatCompletionContinued(ctx, &retry, cb, maxContinues)
+}
+
+// streamChatCompletion streams one completion and returns its finish reason.
+func (api *APIClient) streamChatCompletion(ctx context.Context, req *ChatCompletionRequest, cb StreamCallback) (string, error) {
 	url := fmt.Sprintf("%s/v1/chat/completions", api.baseURL)
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return "", fmt.Errorf("marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return "", fmt.Errorf("create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := api.client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("send request: %w", err)
+		return "", fmt.Errorf("send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if err := checkResponse(resp, "chat completion"); err != nil {
-		return err
+		return "", err
 	}
 
 	scanner := bufio.NewScanner(resp.Body)
 	parseErrors := 0
 	var lastParseErr error
+	var finishReason string
 
 	for scanner.Scan() {
 		// Check for context cancellation. The HTTP request was created with context,
 		// so the response body read will also be interrupted on cancellation.
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return "", ctx.Err()
 		}
 
 		line := scanner.Text()
@@ -230,24 +431,30 @@ func (api *APIClient) StreamChatCompletion(ctx context.Context, req *ChatComplet
 				if delta.Content != "" && cb.ContentCallback != nil {
 					cb.ContentCallback(delta.Content)
 				}
+				if reason := chunk.Choices[0].FinishReason; reason != "" {
+					finishReason = reason
+				}
 			}
 
 			// Call timings callback if we got timing stats (usually in final chunk)
 			if chunk.Timings != nil && cb.TimingsCallback != nil {
 				cb.TimingsCallback(chunk.Timings)
 			}
+			if chunk.Usage != nil && cb.UsageCallback != nil {
+				cb.UsageCallback(chunk.Usage)
+			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		return "", err
 	}
 
 	if parseErrors > 10 {
-		return fmt.Errorf("stream had %d JSON parse errors, last: %w", parseErrors, lastParseErr)
+		return "", fmt.Errorf("stream had %d JSON parse errors, last: %w", parseErrors, lastParseErr)
 	}
 
-	return nil
+	return finishReason, nil
 }
 
 // StopModel unloads a model from the proxy server.
@@ -317,6 +524,10 @@ type RunOptions struct {
 	GpuLayers *int           `json:"gpu_layers,omitempty"`
 	Threads   *int           `json:"threads,omitempty"`
 	Options   map[string]any `json:"options,omitempty"` // Additional llama-server options
+
+	// Passthrough options skip the server's option checks, for llama-server
+	// flags it doesn't know
+	Passthrough map[string]any `json:"passthrough,omitempty"`
 }
 
 // IntPtr is a helper to create a pointer to an int value.
@@ -328,17 +539,67 @@ func IntPtr(v int) *int {
 // This calls /api/run which loads the model with the specified options.
 // Explicit fields (CtxSize, etc.) take precedence over Options map.
 func (api *APIClient) Run(model string, opts *RunOptions) error {
+	resp, err := api.postRun(model, opts, false)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp, "run model")
+}
+
+// LaunchPlan is how the server would start a model's backend
+type LaunchPlan struct {
+	Model    string            `json:"-"`
+	Path     string            `json:"path"`
+	Args     []string          `json:"args"`
+	Env      []string          `json:"env"`
+	Dir      string            `json:"dir"`
+	Template string            `json:"chat_template"`
+	MMProj   string            `json:"mmproj"`
+	Options  map[string]any    `json:"options"`
+	Sources  map[string]string `json:"sources"` // Where each option came from, such as "config" or "preset"
+}
+
+// PlanRun asks the server how it would start model with opts, without
+// starting anything. Options are resolved as Run resolves them.
+func (api *APIClient) PlanRun(model string, opts *RunOptions) (*LaunchPlan, error) {
+	resp, err := api.postRun(model, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, "plan model launch"); err != nil {
+		return nil, err
+	}
+	var result struct {
+		Model  string      `json:"model"`
+		Launch *LaunchPlan `json:"launch"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.Launch == nil {
+		return nil, fmt.Errorf("plan model launch: server doesn't support dry runs")
+	}
+	result.Launch.Model = result.Model
+	return result.Launch, nil
+}
+
+func (api *APIClient) postRun(model string, opts *RunOptions, dryRun bool) (*http.Response, error) {
 	type RunRequest struct {
 		Model     string         `json:"model"`
 		CtxSize   *int           `json:"ctx_size,omitempty"`
 		GpuLayers *int           `json:"gpu_layers,omitempty"`
 		Threads   *int           `json:"threads,omitempty"`
 		Options   map[string]any `json:"options,omitempty"`
+		DryRun    bool           `json:"dry_run,omitempty"`
 	}
 
 	url := fmt.Sprintf("%s/api/run", api.baseURL)
 
-	req := RunRequest{Model: model}
+	req := RunRequest{Model: model, DryRun: dryRun}
 	if opts != nil {
 		req.CtxSize = opts.CtxSize
 		req.GpuLayers = opts.GpuLayers
@@ -348,21 +609,19 @@ func (api *APIClient) Run(model string, opts *RunOptions) error {
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return nil, fmt.Errorf("create request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := api.client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("send request: %w", err)
+		return nil, fmt.Errorf("send request: %w", err)
 	}
-	defer resp.Body.Close()
-
-	return checkResponse(resp, "run model")
+	return resp, nil
 }
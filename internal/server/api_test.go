@@ -47,6 +47,29 @@ func TestNewAPIClientFromURL(t *testing.T) {
 	})
 }
 
+func TestWithAPIKey(t *testing.T) {
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("Authorization")
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer ts.Close()
+
+	if err := NewAPIClientFromURL(ts.URL).WithAPIKey("secret").Health(); err != nil {
+		t.Fatal(err)
+	}
+	if got != "Bearer secret" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer secret")
+	}
+
+	if err := NewAPIClientFromURL(ts.URL).WithAPIKey("").Health(); err != nil {
+		t.Fatal(err)
+	}
+	if got != "" {
+		t.Errorf("Authorization = %q without a key", got)
+	}
+}
+
 func TestHealth(t *testing.T) {
 	t.Run("returns nil on successful health check", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -331,6 +354,37 @@ func TestStreamChatCompletion(t *testing.T) {
 		}
 	})
 
+	t.Run("reports usage from the final chunk", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		}))
+		defer ts.Close()
+
+		api := &APIClient{
+			baseURL: ts.URL,
+			client:  ts.Client(),
+		}
+
+		var usage []Usage
+		err := api.StreamChatCompletion(context.Background(), &ChatCompletionRequest{}, StreamCallback{
+			UsageCallback: func(u *Usage) {
+				usage = append(usage, *u)
+			},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		want := Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+		if len(usage) != 1 || usage[0] != want {
+			t.Errorf("usage = %+v, want %+v", usage, want)
+		}
+	})
+
 	t.Run("returns error on failed request", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
@@ -682,6 +736,38 @@ func TestChatMessageSerialization(t *testing.T) {
 	}
 }
 
+func TestChatMessageImages(t *testing.T) {
+	msg := ChatMessage{
+		Role:    "user",
+		Content: "What's this?",
+		Images:  []string{"data:image/png;base64,iVBORw0KGgo="},
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal ChatMessage: %v", err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"What's this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded ChatMessage
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal ChatMessage: %v", err)
+	}
+	if decoded.Content != msg.Content || len(decoded.Images) != 1 || decoded.Images[0] != msg.Images[0] {
+		t.Errorf("Unmarshal() = %+v, want %+v", decoded, msg)
+	}
+
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null}`), &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal null content: %v", err)
+	}
+	if decoded.Role != "assistant" || decoded.Content != "" || decoded.Images != nil {
+		t.Errorf("Unmarshal(null content) = %+v", decoded)
+	}
+}
+
 func TestStreamChunkSerialization(t *testing.T) {
 	chunk := StreamChunk{
 		ID:      "test-id",
@@ -716,3 +802,193 @@ func TestStreamChunkSerialization(t *testing.T) {
 		t.Errorf("Expected content 'Hello', got %s", decoded.Choices[0].Delta.Content)
 	}
 }
+
+func TestStreamChatCompletionContinuedFromAssistant(t *testing.T) {
+	replies := []string{" brown fox", " jumps."}
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		// The reply being continued is extended, never repeated
+		want := "The quick" + strings.Join(replies[:requests], "")
+		if n := len(req.Messages); n != 2 || req.Messages[1].Role != "assistant" || req.Messages[1].Content != want {
+			t.Errorf("request %d messages = %+v, want assistant %q last", requests, req.Messages, want)
+		}
+
+		finishReason := "length"
+		if requests == len(replies)-1 {
+			finishReason = "stop"
+		}
+		data, _ := json.Marshal(StreamChunk{Choices: []StreamChoice{{
+			Delta:        StreamDelta{Content: replies[requests]},
+			FinishReason: finishReason,
+		}}})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+		requests++
+	}))
+	defer ts.Close()
+
+	api := NewAPIClientFromURL(ts.URL)
+	req := &ChatCompletionRequest{
+		Model: "test-model",
+		Messages: []ChatMessage{
+			{Role: "user", Content: "Tell me about foxes"},
+			{Role: "assistant", Content: "The quick"},
+		},
+		Stream: true,
+	}
+
+	var got strings.Builder
+	cb := StreamCallback{ContentCallback: func(s string) { got.WriteString(s) }}
+	if err := api.StreamChatCompletionContinued(context.Background(), req, cb, 5); err != nil {
+		t.Fatalf("StreamChatCompletionContinued() error = %v", err)
+	}
+	if got.String() != " brown fox jumps." || requests != 2 {
+		t.Errorf("content = %q after %d requests", got.String(), requests)
+	}
+}
+
+func TestStreamChatCompletionContinued(t *testing.T) {
+	// Each reply is cut off at the token limit until the third request
+	replies := []string{"The quick", " brown fox", " jumps."}
+
+	tests := []struct {
+		name         string
+		maxContinues int
+		want         string
+		wantRequests int
+	}{
+		{"disabled", 0, "The quick", 1},
+		{"stops at limit", 1, "The quick brown fox", 2},
+		{"finishes reply", 5, "The quick brown fox jumps.", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ChatCompletionRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Fatalf("Failed to decode request: %v", err)
+				}
+
+				// Follow-ups carry the reply so far as a trailing assistant message
+				wantPrefill := strings.Join(replies[:requests], "")
+				last := req.Messages[len(req.Messages)-1]
+				if requests == 0 && last.Role != "user" {
+					t.Errorf("First request ends with %s message, want user", last.Role)
+				}
+				if requests > 0 && (last.Role != "assistant" || last.Content != wantPrefill) {
+					t.Errorf("Request %d ends with %s %q, want assistant %q", requests, last.Role, last.Content, wantPrefill)
+				}
+
+				finishReason := "length"
+				if requests == len(replies)-1 {
+					finishReason = "stop"
+				}
+				chunk := StreamChunk{Choices: []StreamChoice{{
+					Delta:        StreamDelta{Content: replies[requests]},
+					FinishReason: finishReason,
+				}}}
+				data, _ := json.Marshal(chunk)
+				fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+				requests++
+			}))
+			defer ts.Close()
+
+			api := NewAPIClientFromURL(ts.URL)
+			req := &ChatCompletionRequest{
+				Model:    "test-model",
+				Messages: []ChatMessage{{Role: "user", Content: "Tell me about foxes"}},
+				Stream:   true,
+			}
+
+			var got strings.Builder
+			cb := StreamCallback{ContentCallback: func(s string) { got.WriteString(s) }}
+			if err := api.StreamChatCompletionContinued(context.Background(), req, cb, tt.maxContinues); err != nil {
+				t.Fatalf("StreamChatCompletionContinued() error = %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("content = %q, want %q", got.String(), tt.want)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if len(req.Messages) != 1 {
+				t.Errorf("original request was modified: %d messages", len(req.Messages))
+			}
+		})
+	}
+}
+
+func TestStreamChatCompletionInLanguage(t *testing.T) {
+	const (
+		english = "The weather is nice today. It is a good day for a walk in the park."
+		german  = "Das Wetter ist heute schön. Es ist ein guter Tag für einen Spaziergang."
+	)
+
+	tests := []struct {
+		name         string
+		replies      []string
+		want         string
+		wantRequests int
+	}{
+		{"in language", []string{german}, german, 1},
+		{"asks again once", []string{english, german}, german, 2},
+		{"keeps second drift", []string{english, english}, english, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ChatCompletionRequest
+				json.NewDecoder(r.Body).Decode(&req)
+
+				// The retry reminds the model in the last user message
+				last := req.Messages[len(req.Messages)-1].Content
+				if reminded := strings.Contains(last, "Always reply in German"); reminded != (requests > 0) {
+					t.Errorf("request %d user message = %q", requests, last)
+				}
+
+				// Stream word by word so a drifted reply is cut off mid-way
+				flusher := w.(http.Flusher)
+				for word := range strings.SplitAfterSeq(tt.replies[requests], " ") {
+					data, _ := json.Marshal(StreamChunk{Choices: []StreamChoice{{Delta: StreamDelta{Content: word}}}})
+					if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+						break
+					}
+					flusher.Flush()
+				}
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				requests++
+			}))
+			defer ts.Close()
+
+			api := NewAPIClientFromURL(ts.URL)
+			req := &ChatCompletionRequest{
+				Model:    "test-model",
+				Messages: []ChatMessage{{Role: "user", Content: "How is the weather?"}},
+				Stream:   true,
+			}
+
+			var got strings.Builder
+			cb := StreamCallback{ContentCallback: func(s string) { got.WriteString(s) }}
+			if err := api.StreamChatCompletionInLanguage(context.Background(), req, cb, 0, "de"); err != nil {
+				t.Fatalf("StreamChatCompletionInLanguage() error = %v", err)
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("content = %q, want %q", got.String(), tt.want)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if req.Messages[0].Content != "How is the weather?" {
+				t.Errorf("original request was modified: %q", req.Messages[0].Content)
+			}
+		})
+	}
+}
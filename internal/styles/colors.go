@@ -3,24 +3,41 @@ package styles
 
 import "github.com/charmbracelet/lipgloss"
 
-// Color palette using AdaptiveColor for light/dark terminal support.
-// Dark values match the original CLI color scheme.
+// Color palette of the current theme, used by every style in the CLI and
+// chat UI. The default auto theme uses AdaptiveColor for light/dark terminal
+// support; its dark values match the original CLI color scheme.
 var (
-	ColorPrimary   = lipgloss.AdaptiveColor{Light: "62", Dark: "12"}   // Blue - headers, user messages
-	ColorSecondary = lipgloss.AdaptiveColor{Light: "240", Dark: "250"} // Gray - secondary text
-	ColorSuccess   = lipgloss.AdaptiveColor{Light: "34", Dark: "10"}   // Green - success messages
-	ColorError     = lipgloss.AdaptiveColor{Light: "160", Dark: "9"}   // Red - error messages
-	ColorWarning   = lipgloss.AdaptiveColor{Light: "214", Dark: "11"}  // Yellow - warnings
-	ColorAccent    = lipgloss.AdaptiveColor{Light: "99", Dark: "13"}   // Purple - keywords, accents
-	ColorMuted     = lipgloss.AdaptiveColor{Light: "246", Dark: "243"} // Dim gray - muted text
-	ColorBorder    = lipgloss.AdaptiveColor{Light: "250", Dark: "238"} // Border color
-	ColorValue     = lipgloss.AdaptiveColor{Light: "38", Dark: "14"}   // Cyan - values
+	ColorPrimary   lipgloss.TerminalColor // Blue - headers, user messages
+	ColorSecondary lipgloss.TerminalColor // Gray - secondary text
+	ColorSuccess   lipgloss.TerminalColor // Green - success messages
+	ColorError     lipgloss.TerminalColor // Red - error messages
+	ColorWarning   lipgloss.TerminalColor // Yellow - warnings
+	ColorAccent    lipgloss.TerminalColor // Purple - keywords, accents
+	ColorMuted     lipgloss.TerminalColor // Dim gray - muted text
+	ColorBorder    lipgloss.TerminalColor // Border color
+	ColorValue     lipgloss.TerminalColor // Cyan - values
 )
 
-// ANSI color codes as strings for use with glamour/markdown rendering.
-const (
-	ColorMutedCode = "243"
-)
+func init() {
+	SetTheme(Themes[DefaultTheme])
+}
+
+// ColorCode returns c as a single color string, such as "243" or "#268bd2",
+// for renderers like glamour that don't take lipgloss colors. Adaptive colors
+// resolve against the terminal background.
+func ColorCode(c lipgloss.TerminalColor) string {
+	switch c := c.(type) {
+	case lipgloss.Color:
+		return string(c)
+	case lipgloss.AdaptiveColor:
+		if DarkBackground() {
+			return c.Dark
+		}
+		return c.Light
+	default:
+		return ""
+	}
+}
 
 // Icon constants for consistent output.
 const (
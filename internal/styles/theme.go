@@ -0,0 +1,211 @@
+package styles
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme is a color palette for the CLI and chat UI
+type Theme struct {
+	Name       string
+	Background string // "dark" or "light" for markdown rendering, or "" to follow the terminal
+
+	Primary   lipgloss.TerminalColor
+	Secondary lipgloss.TerminalColor
+	Success   lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+	Warning   lipgloss.TerminalColor
+	Accent    lipgloss.TerminalColor
+	Muted     lipgloss.TerminalColor
+	Border    lipgloss.TerminalColor
+	Value     lipgloss.TerminalColor
+}
+
+// DefaultTheme adapts to the terminal background
+const DefaultTheme = "auto"
+
+// Themes are the built-in themes by name
+var Themes = map[string]Theme{
+	"auto": {
+		Name:      "auto",
+		Primary:   lipgloss.AdaptiveColor{Light: "62", Dark: "12"},
+		Secondary: lipgloss.AdaptiveColor{Light: "240", Dark: "250"},
+		Success:   lipgloss.AdaptiveColor{Light: "34", Dark: "10"},
+		Error:     lipgloss.AdaptiveColor{Light: "160", Dark: "9"},
+		Warning:   lipgloss.AdaptiveColor{Light: "214", Dark: "11"},
+		Accent:    lipgloss.AdaptiveColor{Light: "99", Dark: "13"},
+		Muted:     lipgloss.AdaptiveColor{Light: "246", Dark: "243"},
+		Border:    lipgloss.AdaptiveColor{Light: "250", Dark: "238"},
+		Value:     lipgloss.AdaptiveColor{Light: "38", Dark: "14"},
+	},
+	"dark": {
+		Name:       "dark",
+		Background: "dark",
+		Primary:    lipgloss.Color("12"),
+		Secondary:  lipgloss.Color("250"),
+		Success:    lipgloss.Color("10"),
+		Error:      lipgloss.Color("9"),
+		Warning:    lipgloss.Color("11"),
+		Accent:     lipgloss.Color("13"),
+		Muted:      lipgloss.Color("243"),
+		Border:     lipgloss.Color("238"),
+		Value:      lipgloss.Color("14"),
+	},
+	"light": {
+		Name:       "light",
+		Background: "light",
+		Primary:    lipgloss.Color("25"),
+		Secondary:  lipgloss.Color("238"),
+		Success:    lipgloss.Color("28"),
+		Error:      lipgloss.Color("160"),
+		Warning:    lipgloss.Color("130"),
+		Accent:     lipgloss.Color("91"),
+		Muted:      lipgloss.Color("242"),
+		Border:     lipgloss.Color("250"),
+		Value:      lipgloss.Color("30"),
+	},
+	// Solarized accents read on both of its backgrounds; the grays follow
+	// the terminal
+	"solarized": {
+		Name:      "solarized",
+		Primary:   lipgloss.Color("#268bd2"),
+		Secondary: lipgloss.AdaptiveColor{Light: "#586e75", Dark: "#93a1a1"},
+		Success:   lipgloss.Color("#859900"),
+		Error:     lipgloss.Color("#dc322f"),
+		Warning:   lipgloss.Color("#b58900"),
+		Accent:    lipgloss.Color("#6c71c4"),
+		Muted:     lipgloss.AdaptiveColor{Light: "#93a1a1", Dark: "#657b83"},
+		Border:    lipgloss.AdaptiveColor{Light: "#eee8d5", Dark: "#073642"},
+		Value:     lipgloss.Color("#2aa198"),
+	},
+}
+
+// ColorNames are the palette entries a config can override
+var ColorNames = []string{"primary", "secondary", "success", "error", "warning", "accent", "muted", "border", "value"}
+
+var (
+	current Theme
+	hooks   []func()
+)
+
+// ThemeNames returns the built-in theme names, sorted
+func ThemeNames() []string {
+	var names []string
+	for name := range Themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolveTheme returns the named built-in theme with colors overridden by a
+// map of palette entry to hex ("#268bd2") or ANSI ("33") color.
+func ResolveTheme(name string, colors map[string]string) (Theme, error) {
+	if name == "" {
+		name = DefaultTheme
+	}
+	t, ok := Themes[name]
+	if !ok {
+		return Theme{}, fmt.Errorf("unknown theme %q: must be one of %s", name, strings.Join(ThemeNames(), ", "))
+	}
+
+	for key, value := range colors {
+		c, err := parseColor(value)
+		if err != nil {
+			return Theme{}, fmt.Errorf("invalid color for %s: %w", key, err)
+		}
+		if slot := t.color(strings.ToLower(key)); slot != nil {
+			*slot = c
+		} else {
+			return Theme{}, fmt.Errorf("unknown color %q: must be one of %s", key, strings.Join(ColorNames, ", "))
+		}
+	}
+	return t, nil
+}
+
+// color returns the palette entry for name, or nil if there isn't one
+func (t *Theme) color(name string) *lipgloss.TerminalColor {
+	switch name {
+	case "primary":
+		return &t.Primary
+	case "secondary":
+		return &t.Secondary
+	case "success":
+		return &t.Success
+	case "error":
+		return &t.Error
+	case "warning":
+		return &t.Warning
+	case "accent":
+		return &t.Accent
+	case "muted":
+		return &t.Muted
+	case "border":
+		return &t.Border
+	case "value":
+		return &t.Value
+	}
+	return nil
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// parseColor accepts a hex color or an ANSI 256 color number
+func parseColor(s string) (lipgloss.Color, error) {
+	s = strings.TrimSpace(s)
+	if hexColor.MatchString(s) {
+		return lipgloss.Color(s), nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return lipgloss.Color(s), nil
+	}
+	return "", fmt.Errorf("%q is not a hex color like #268bd2 or an ANSI color from 0 to 255", s)
+}
+
+// SetTheme makes t the palette for all styles, rebuilding the styles
+// registered with OnThemeChange
+func SetTheme(t Theme) {
+	current = t
+	ColorPrimary = t.Primary
+	ColorSecondary = t.Secondary
+	ColorSuccess = t.Success
+	ColorError = t.Error
+	ColorWarning = t.Warning
+	ColorAccent = t.Accent
+	ColorMuted = t.Muted
+	ColorBorder = t.Border
+	ColorValue = t.Value
+	for _, f := range hooks {
+		f()
+	}
+}
+
+// CurrentTheme returns the theme in use
+func CurrentTheme() Theme {
+	return current
+}
+
+// OnThemeChange registers f to rebuild styles derived from the palette.
+// f runs once now and again whenever the theme changes.
+func OnThemeChange(f func()) {
+	hooks = append(hooks, f)
+	f()
+}
+
+// DarkBackground reports whether the theme is meant for a dark background,
+// asking the terminal when the theme follows it
+func DarkBackground() bool {
+	switch current.Background {
+	case "dark":
+		return true
+	case "light":
+		return false
+	default:
+		return lipgloss.HasDarkBackground()
+	}
+}
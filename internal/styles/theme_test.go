@@ -0,0 +1,91 @@
+package styles
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestResolveTheme(t *testing.T) {
+	tests := []struct {
+		name        string
+		theme       string
+		colors      map[string]string
+		wantPrimary lipgloss.TerminalColor
+		wantErr     string
+	}{
+		{"default", "", nil, Themes["auto"].Primary, ""},
+		{"built-in", "light", nil, lipgloss.Color("25"), ""},
+		{"hex override", "dark", map[string]string{"primary": "#005f87"}, lipgloss.Color("#005f87"), ""},
+		{"short hex", "dark", map[string]string{"Primary": "#08f"}, lipgloss.Color("#08f"), ""},
+		{"ansi override", "solarized", map[string]string{"primary": "33"}, lipgloss.Color("33"), ""},
+		{"unknown theme", "neon", nil, nil, "unknown theme"},
+		{"unknown color", "dark", map[string]string{"background": "#000000"}, nil, "unknown color"},
+		{"bad hex", "dark", map[string]string{"primary": "#12345"}, nil, "invalid color for primary"},
+		{"ansi out of range", "dark", map[string]string{"muted": "256"}, nil, "invalid color for muted"},
+		{"color name", "dark", map[string]string{"error": "red"}, nil, "invalid color for error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTheme(tt.theme, tt.colors)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveTheme() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveTheme() error = %v", err)
+			}
+			if got.Primary != tt.wantPrimary {
+				t.Errorf("Primary = %v, want %v", got.Primary, tt.wantPrimary)
+			}
+		})
+	}
+}
+
+func TestResolveThemeKeepsBuiltIns(t *testing.T) {
+	if _, err := ResolveTheme("dark", map[string]string{"primary": "#005f87"}); err != nil {
+		t.Fatal(err)
+	}
+	if Themes["dark"].Primary != lipgloss.Color("12") {
+		t.Errorf("overriding a color changed the built-in theme: %v", Themes["dark"].Primary)
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	t.Cleanup(func() { SetTheme(Themes[DefaultTheme]) })
+
+	var rebuilt lipgloss.TerminalColor
+	OnThemeChange(func() { rebuilt = ColorAccent })
+	t.Cleanup(func() { hooks = hooks[:len(hooks)-1] })
+
+	if rebuilt != Themes[DefaultTheme].Accent {
+		t.Errorf("hook didn't run on registration, got %v", rebuilt)
+	}
+
+	SetTheme(Themes["light"])
+	if ColorAccent != lipgloss.Color("91") || rebuilt != ColorAccent {
+		t.Errorf("ColorAccent = %v, hook saw %v, want the light theme's accent", ColorAccent, rebuilt)
+	}
+	if CurrentTheme().Name != "light" || DarkBackground() {
+		t.Errorf("current theme = %q, dark = %v, want light", CurrentTheme().Name, DarkBackground())
+	}
+}
+
+func TestColorCode(t *testing.T) {
+	t.Cleanup(func() { SetTheme(Themes[DefaultTheme]) })
+	SetTheme(Themes["light"])
+
+	if got := ColorCode(lipgloss.Color("#268bd2")); got != "#268bd2" {
+		t.Errorf("ColorCode(Color) = %q", got)
+	}
+	if got := ColorCode(lipgloss.AdaptiveColor{Light: "246", Dark: "243"}); got != "246" {
+		t.Errorf("ColorCode(AdaptiveColor) on a light theme = %q, want 246", got)
+	}
+	if got := ColorCode(lipgloss.NoColor{}); got != "" {
+		t.Errorf("ColorCode(NoColor) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package telemetry keeps anonymous, opt-in usage counts: which commands
+// run how often, on what platform, with which lleme and llama.cpp builds.
+//
+// Nothing is ever sent anywhere. Counts are kept in a JSON file on this
+// machine, and sinks can copy them elsewhere, such as a shared directory an
+// organization collects from. No arguments, model names, prompts, paths or
+// hostnames are recorded; the report's ID is random, made when counting
+// starts.
+package telemetry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// Report is everything telemetry records
+type Report struct {
+	ID       string         `json:"id"`       // Random, identifying only this install's report
+	Platform string         `json:"platform"` // GOOS/GOARCH
+	Version  string         `json:"version"`  // lleme version
+	LlamaCpp string         `json:"llama_cpp,omitempty"`
+	Since    time.Time      `json:"since"`
+	Updated  time.Time      `json:"updated"`
+	Commands map[string]int `json:"commands"` // Runs by command, like "run" or "config set"
+}
+
+// Sink receives the report each time it changes
+type Sink interface {
+	Write(r *Report) error
+}
+
+// DirSink writes the report to <dir>/<id>.json, so reports from many
+// machines can be gathered in one shared directory
+type DirSink string
+
+func (d DirSink) Write(r *Report) error {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+	return writeJSON(filepath.Join(string(d), r.ID+".json"), r)
+}
+
+// Path returns where the report is kept
+func Path() string {
+	return filepath.Join(paths.StateDir(), "telemetry.json")
+}
+
+// Load returns the report so far, or nil if nothing has been counted
+func Load() (*Report, error) {
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", Path(), err)
+	}
+	return &r, nil
+}
+
+// Record counts one run of command, noting the lleme and llama.cpp
+// versions in use, and hands the updated report to each sink
+func Record(command, version, llamaCpp string, sinks ...Sink) error {
+	if err := os.MkdirAll(filepath.Dir(Path()), 0755); err != nil {
+		return err
+	}
+	// Commands can run side by side; the lock keeps their counts
+	lock, err := fileutil.Lock(Path()+".lock", nil)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	r, err := Load()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if r == nil {
+		r = &Report{ID: newID(), Since: now, Commands: map[string]int{}}
+	}
+	if r.Commands == nil {
+		r.Commands = map[string]int{}
+	}
+	r.Platform = runtime.GOOS + "/" + runtime.GOARCH
+	r.Version = version
+	if llamaCpp != "" {
+		r.LlamaCpp = llamaCpp
+	}
+	r.Updated = now
+	r.Commands[command]++
+
+	if err := writeJSON(Path(), r); err != nil {
+		return err
+	}
+	for _, s := range sinks {
+		if err := s.Write(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset deletes the report, so counting starts over with a new ID
+func Reset() error {
+	err := os.Remove(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(path string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(path, append(data, '\n'), 0644)
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	if r, err := Load(); err != nil || r != nil {
+		t.Fatalf("Load() before recording = %v, %v", r, err)
+	}
+
+	export := filepath.Join(t.TempDir(), "shared")
+	Record("run", "1.0.0", "b5000", DirSink(export))
+	Record("run", "1.0.1", "", DirSink(export))
+	if err := Record("config set", "1.0.1", "", DirSink(export)); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	r, err := Load()
+	if err != nil || r == nil {
+		t.Fatalf("Load() = %v, %v", r, err)
+	}
+	if r.Commands["run"] != 2 || r.Commands["config set"] != 1 {
+		t.Errorf("Commands = %v", r.Commands)
+	}
+	if r.Version != "1.0.1" || r.LlamaCpp != "b5000" || r.Platform != runtime.GOOS+"/"+runtime.GOARCH {
+		t.Errorf("report = %+v", r)
+	}
+	if r.ID == "" || r.Since.IsZero() || r.Updated.Before(r.Since) {
+		t.Errorf("report = %+v", r)
+	}
+
+	// The sink gets the same report, named by its ID
+	data, err := os.ReadFile(filepath.Join(export, r.ID+".json"))
+	if err != nil {
+		t.Fatalf("export missing: %v", err)
+	}
+	var exported Report
+	if err := json.Unmarshal(data, &exported); err != nil || exported.Commands["run"] != 2 {
+		t.Errorf("exported = %+v, %v", exported, err)
+	}
+
+	// Reset starts over with a new ID
+	if err := Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := Load(); r != nil {
+		t.Errorf("Load() after Reset() = %+v", r)
+	}
+	Record("run", "1.0.1", "")
+	if again, _ := Load(); again.ID == r.ID || again.Commands["run"] != 1 {
+		t.Errorf("report after reset = %+v", again)
+	}
+	if err := Reset(); err != nil {
+		t.Errorf("Reset() twice error = %v", err)
+	}
+}
@@ -4,15 +4,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/conversation"
+	"github.com/nchapman/lleme/internal/filter"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/lang"
 	"github.com/nchapman/lleme/internal/options"
 	"github.com/nchapman/lleme/internal/server"
 	"github.com/nchapman/lleme/internal/tui/components"
+	"github.com/nchapman/lleme/internal/tui/history"
+	"github.com/nchapman/lleme/internal/tui/termimg"
 )
 
 // Message types for communication with the model
@@ -30,7 +40,8 @@ type (
 	// StreamDoneMsg indicates streaming is complete
 	StreamDoneMsg struct {
 		Error   error
-		Content string // Full content for history
+		Content string     // Full content for history
+		Usage   tokenUsage // Tokens the exchange used
 	}
 
 	// StreamCancelledMsg indicates streaming was cancelled by the user
@@ -46,6 +57,7 @@ type (
 		Message string
 		IsError bool
 		Exit    bool
+		Input   string // Put back in the input for editing
 	}
 )
 
@@ -72,17 +84,30 @@ type Model struct {
 	persona     *config.Persona
 	personaName string
 	resolver    *options.Resolver
+	filter      *filter.Filter
 
 	// Session state
 	chatMessages         []server.ChatMessage
 	options              SessionOptions
+	serverOptions        map[string]any // persona/CLI llama-server options sent on load
 	pendingReload        bool
 	systemPromptOverride string
+	maxContinues         int             // Times to continue a reply cut off by max tokens
+	smoothStream         bool            // Pace bursty replies for reading (ui.smooth_stream)
+	respondIn            string          // Language code replies must be in (respond_in)
+	tee                  *teeFile        // Receives a copy of each response (/tee)
+	pendingImages        []termimg.Image // Sent with the next message (/image)
+	history              *history.History
+	usage                tokenUsage                 // Tokens used by every exchange this session
+	costRate             *config.CostRate           // Hosted API prices for cost estimates, if set
+	saved                *conversation.Conversation // Where /save writes, once it has
+	interrupted          bool                       // The last reply was cancelled and can be continued (/continue)
 
 	// UI state
 	width        int
 	height       int
 	streaming    bool
+	continuing   bool // The stream extends the last reply
 	quitting     bool
 	focusedPane  FocusedPane
 	cancelStream context.CancelFunc
@@ -113,6 +138,55 @@ type SessionOptions struct {
 	CtxSizeSet   bool
 	GpuLayersSet bool
 	ThreadsSet   bool
+
+	// Other llama-server options from the catalog set with /set, by name
+	Server map[string]any
+}
+
+// set returns the options set for this session, keyed by llama-server name.
+// Zero sampling options count as unset.
+func (o SessionOptions) set() map[string]any {
+	set := make(map[string]any)
+	for key, val := range map[string]float64{"temp": o.Temp, "top-p": o.TopP, "repeat-penalty": o.RepeatPenalty, "min-p": o.MinP} {
+		if val != 0 {
+			set[key] = val
+		}
+	}
+	if o.TopK != 0 {
+		set["top-k"] = o.TopK
+	}
+	if o.CtxSizeSet {
+		set["ctx-size"] = o.CtxSize
+	}
+	if o.GpuLayersSet {
+		set["gpu-layers"] = o.GpuLayers
+	}
+	if o.ThreadsSet {
+		set["threads"] = o.Threads
+	}
+	maps.Copy(set, o.Server)
+	return set
+}
+
+// runOptions returns the options to load the model with: serverOpts, with
+// the session's server options over them, and passthrough sent unchecked
+func (o SessionOptions) runOptions(serverOpts, passthrough map[string]any) *server.RunOptions {
+	opts := &server.RunOptions{Options: serverOpts, Passthrough: passthrough}
+	if len(o.Server) > 0 {
+		opts.Options = make(map[string]any, len(serverOpts)+len(o.Server))
+		maps.Copy(opts.Options, serverOpts)
+		maps.Copy(opts.Options, o.Server)
+	}
+	if o.CtxSizeSet {
+		opts.CtxSize = server.IntPtr(o.CtxSize)
+	}
+	if o.GpuLayersSet {
+		opts.GpuLayers = server.IntPtr(o.GpuLayers)
+	}
+	if o.ThreadsSet {
+		opts.Threads = server.IntPtr(o.Threads)
+	}
+	return opts
 }
 
 // New creates a new chat TUI model
@@ -128,11 +202,18 @@ func New(api *server.APIClient, modelName string, cfg *config.Config, persona *c
 		cfg:         cfg,
 		persona:     persona,
 		personaName: personaName,
-		resolver:    options.NewResolver(persona, cfg),
+		resolver:    options.NewResolver(persona, cfg).WithModel(modelName),
 
-		chatMessages: []server.ChatMessage{},
-		keys:         DefaultKeyMap(),
+		chatMessages:  []server.ChatMessage{},
+		serverOptions: persona.GetServerOptions(),
+		maxContinues:  cfg.Chat.MaxContinues,
+		smoothStream:  cfg.UI.SmoothStream,
+		respondIn:     persona.ResponseLanguage(cfg),
+	}
+	if rate, ok := cfg.Chat.CostRateForModel(modelName); ok {
+		m.costRate = &rate
 	}
+	m.SetKeyMap(DefaultKeyMap())
 
 	// Initialize system prompt
 	m.initSystemPrompt()
@@ -161,6 +242,53 @@ func (m *Model) SetInitialServerOptions(ctxSize, gpuLayers, threads int, ctxSize
 	m.options.ThreadsSet = threadsSet
 }
 
+// SetServerOptions sets the llama-server options sent when loading the model.
+// These replace the persona's server options.
+func (m *Model) SetServerOptions(opts map[string]any) {
+	m.serverOptions = opts
+}
+
+// SetKeyMap sets the key bindings for the input, messages pane and hints
+func (m *Model) SetKeyMap(k KeyMap) {
+	m.keys = k
+	m.input.SetKeys(components.InputKeys{
+		Send:          k.Send,
+		Newline:       k.Newline,
+		HistoryPrev:   k.HistoryPrev,
+		HistoryNext:   k.HistoryNext,
+		HistorySearch: k.HistoryFind,
+	})
+	m.messages.SetKeyMap(k.Viewport(), k.Top, k.Bottom)
+	m.status.SetKeys(components.StatusKeys{
+		Send:   k.Send.Help().Key,
+		Focus:  k.Focus.Help().Key,
+		Cancel: k.Cancel.Help().Key,
+		Quit:   k.Quit.Help().Key,
+	})
+}
+
+// SetHistory sets the input history recalled with up, down and ctrl+r
+func (m *Model) SetHistory(h *history.History) {
+	m.history = h
+	m.input.SetHistory(h)
+}
+
+// SetMaxContinues sets how many times a reply cut off by max tokens is continued
+func (m *Model) SetMaxContinues(n int) {
+	m.maxContinues = n
+}
+
+// SetSmoothStream paces the display of streamed replies so bursts of
+// tokens appear as steady typing
+func (m *Model) SetSmoothStream(on bool) {
+	m.smoothStream = on
+}
+
+// SetOutputFilter sets the filter applied to responses before they're displayed
+func (m *Model) SetOutputFilter(f *filter.Filter) {
+	m.filter = f
+}
+
 // SetSamplingOptions sets the sampling options from CLI flags
 func (m *Model) SetSamplingOptions(temp, topP, minP, repeatPenalty float64, topK, maxTokens int) {
 	if temp != 0 {
@@ -205,24 +333,13 @@ func (m *Model) preloadModel() tea.Cmd {
 	api := m.api
 	model := m.model
 	options := m.options
-	var personaOpts map[string]any
-	if m.persona != nil {
-		personaOpts = m.persona.GetServerOptions()
-	}
+	serverOpts := m.serverOptions
+	passthrough := m.persona.GetPassthrough()
 
 	return func() tea.Msg {
 		var opts *server.RunOptions
-		if options.CtxSizeSet || options.GpuLayersSet || options.ThreadsSet || personaOpts != nil {
-			opts = &server.RunOptions{Options: personaOpts}
-			if options.CtxSizeSet {
-				opts.CtxSize = server.IntPtr(options.CtxSize)
-			}
-			if options.GpuLayersSet {
-				opts.GpuLayers = server.IntPtr(options.GpuLayers)
-			}
-			if options.ThreadsSet {
-				opts.Threads = server.IntPtr(options.Threads)
-			}
+		if options.CtxSizeSet || options.GpuLayersSet || options.ThreadsSet || serverOpts != nil || passthrough != nil {
+			opts = options.runOptions(serverOpts, passthrough)
 		}
 
 		// Fire and forget - errors will surface when user sends first message
@@ -244,34 +361,41 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		// Handle global keys first
 		switch {
-		case msg.Type == tea.KeyCtrlC:
+		case key.Matches(msg, m.keys.Quit):
 			m.quitting = true
 			return m, tea.Quit
 
-		case msg.Type == tea.KeyEsc:
-			if m.streaming {
-				// Cancel streaming - this cancels the HTTP request
-				if m.cancelStream != nil {
-					m.cancelStream()
-				}
-				m.messages.CancelStreaming()
-				m.stopStreaming()
-				return m, nil
-			}
-			// Esc returns focus to input
-			if m.focusedPane == PaneMessages {
-				m.focusedPane = PaneInput
-				return m, m.input.Focus()
+		case m.streaming && key.Matches(msg, m.keys.Cancel):
+			// Cancel streaming - this cancels the HTTP request
+			if m.cancelStream != nil {
+				m.cancelStream()
 			}
+			m.keepPartialReply(m.messages.InterruptStreaming())
+			m.stopStreaming()
+			return m, nil
+
+		case m.focusedPane == PaneMessages && key.Matches(msg, m.keys.FocusInput):
+			m.focusedPane = PaneInput
+			return m, m.input.Focus()
+
+		case m.focusedPane == PaneInput && !m.streaming && !m.input.IsCompletionsOpen() && !m.input.IsSearching() && key.Matches(msg, m.keys.FocusHistory):
+			return m, m.toggleFocus()
 
-		case msg.Type == tea.KeyTab && !m.input.IsCompletionsOpen():
+		case !m.input.IsCompletionsOpen() && !m.input.IsSearching() && key.Matches(msg, m.keys.Focus):
 			// Toggle focus between input and messages (not when completions open)
 			return m, m.toggleFocus()
 
-		case msg.Type == tea.KeyEnter && m.focusedPane == PaneInput && !m.streaming && !m.input.IsCompletionsOpen():
+		case !m.streaming && key.Matches(msg, m.keys.Clear):
+			return m, m.handleCommand("/clear")
+
+		case !m.streaming && key.Matches(msg, m.keys.Help):
+			return m, m.handleCommand("/help")
+
+		case m.focusedPane == PaneInput && !m.streaming && !m.input.IsCompletionsOpen() && !m.input.IsSearching() && key.Matches(msg, m.keys.Send):
 			// Send message (only when input is focused and completions not open)
 			value := m.input.Value()
 			if value != "" {
+				m.input.Remember(value)
 				m.input.Reset()
 
 				// Check for slash commands
@@ -299,7 +423,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case StreamContentMsg:
-		m.messages.AppendStreamContent(msg.Content)
+		// Paced text can still arrive after the user cancels
+		if m.streaming {
+			m.messages.AppendStreamContent(msg.Content)
+		}
 
 	case StreamThinkingMsg:
 		m.messages.AppendStreamThinking(msg.Content)
@@ -312,13 +439,19 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		})
 
 	case StreamDoneMsg:
+		continuing := m.continuing
 		m.messages.FinishStreaming()
 		m.stopStreaming()
+		m.recordUsage(msg.Usage)
 		if msg.Error != nil {
 			m.messages.AddMessage(components.Message{
 				Role:    components.RoleError,
 				Content: msg.Error.Error(),
 			})
+			// History still ends with the cut-off reply, so it can be retried
+			m.interrupted = continuing
+		} else if continuing {
+			m.chatMessages[len(m.chatMessages)-1].Content += msg.Content
 		} else if msg.Content != "" {
 			// Add to chat history
 			m.chatMessages = append(m.chatMessages, server.ChatMessage{
@@ -326,11 +459,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Content: msg.Content,
 			})
 		}
+		m.showTeeWriteError()
 		cmds = append(cmds, m.input.Focus())
 
 	case StreamCancelledMsg:
 		// Stream was cancelled by user - just clean up, no error message
 		m.stopStreaming()
+		m.showTeeWriteError()
 		cmds = append(cmds, m.input.Focus())
 
 	case CommandResultMsg:
@@ -348,6 +483,9 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Content: msg.Message,
 			})
 		}
+		if msg.Input != "" {
+			m.input.SetValue(msg.Input)
+		}
 
 	case spinner.TickMsg:
 		if m.streaming {
@@ -377,7 +515,7 @@ func (m *Model) View() string {
 	}
 
 	if m.width == 0 || m.height == 0 {
-		return "Initializing..."
+		return i18n.T("Initializing...")
 	}
 
 	// Update scroll percentage for status bar
@@ -453,6 +591,9 @@ func (m *Model) initSystemPrompt() {
 	if sysPrompt == "" {
 		sysPrompt = config.DefaultSystemPrompt()
 	}
+	if m.respondIn != "" {
+		sysPrompt += "\n\n" + lang.Instruction(m.respondIn)
+	}
 	m.chatMessages = []server.ChatMessage{{Role: "system", Content: sysPrompt}}
 }
 
@@ -465,18 +606,61 @@ func (m *Model) sendMessage(content string) tea.Cmd {
 		}
 	}
 
+	images, urls := m.takeImages()
+
 	// Add to UI
 	m.messages.AddMessage(components.Message{
 		Role:    components.RoleUser,
 		Content: content,
+		Images:  images,
 	})
 
 	// Add to chat history
 	m.chatMessages = append(m.chatMessages, server.ChatMessage{
 		Role:    "user",
 		Content: content,
+		Images:  urls,
 	})
+	m.interrupted = false
+
+	return m.streamReply()
+}
+
+// continueReply resumes the reply that was cancelled, streaming the rest
+// into the same message
+func (m *Model) continueReply() tea.Cmd {
+	if !m.interrupted {
+		return func() tea.Msg {
+			return CommandResultMsg{Message: i18n.T("No cancelled reply to continue")}
+		}
+	}
+	if m.program == nil {
+		return func() tea.Msg {
+			return StreamDoneMsg{Error: fmt.Errorf("internal error: program not initialized")}
+		}
+	}
+	m.interrupted = false
+	m.continuing = true
+	return m.streamReply()
+}
+
+// keepPartialReply records what arrived of a cancelled reply, which
+// includes the earlier part when it was being continued
+func (m *Model) keepPartialReply(content string) {
+	switch {
+	case m.continuing:
+		m.chatMessages[len(m.chatMessages)-1].Content = content
+	case content != "":
+		m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "assistant", Content: content})
+	default:
+		return
+	}
+	m.interrupted = true
+}
 
+// streamReply streams the model's reply to the conversation so far. When
+// continuing, the conversation ends with the reply to extend.
+func (m *Model) streamReply() tea.Cmd {
 	// Start streaming and get spinner tick command
 	spinnerCmd := m.startStreaming()
 
@@ -486,35 +670,49 @@ func (m *Model) sendMessage(content string) tea.Cmd {
 
 	// Capture values for the goroutine
 	api := m.api
-	model := m.model
 	messages := make([]server.ChatMessage, len(m.chatMessages))
 	copy(messages, m.chatMessages)
 	program := m.program
-
-	// Build request
-	req := &server.ChatCompletionRequest{
-		Model:           model,
-		Messages:        messages,
-		Stream:          true,
-		StreamOptions:   &server.StreamOptions{IncludeUsage: true},
-		MaxTokens:       m.options.MaxTokens,
-		ReasoningFormat: "auto",
+	stream := m.filter.Stream()
+	maxContinues := m.maxContinues
+	smooth := m.smoothStream
+	respondIn := m.respondIn
+	var tee io.Writer
+	if m.tee != nil {
+		tee = m.tee
 	}
-	req.Temperature = m.resolver.ResolveFloat(m.options.Temp, "temp")
-	req.TopP = m.resolver.ResolveFloat(m.options.TopP, "top-p")
-	req.TopK = m.resolver.ResolveInt(m.options.TopK, "top-k")
-	req.MinP = m.resolver.ResolveFloat(m.options.MinP, "min-p")
-	req.RepeatPenalty = m.resolver.ResolveFloat(m.options.RepeatPenalty, "repeat-penalty")
+
+	req := m.chatRequest(messages)
 
 	streamCmd := func() tea.Msg {
 		var fullContent strings.Builder
+		var usage tokenUsage
+
+		display := func(content string) {
+			if program != nil {
+				program.Send(StreamContentMsg{Content: content})
+			}
+		}
+		var pacer *smoother
+		if smooth && program != nil {
+			pacer = newSmoother(display)
+			display = pacer.Write
+		}
+
+		sendContent := func(content string) {
+			if content == "" {
+				return
+			}
+			fullContent.WriteString(content)
+			if tee != nil {
+				io.WriteString(tee, content)
+			}
+			display(content)
+		}
 
 		cb := server.StreamCallback{
 			ContentCallback: func(content string) {
-				fullContent.WriteString(content)
-				if program != nil {
-					program.Send(StreamContentMsg{Content: content})
-				}
+				sendContent(stream.Write(content))
 			},
 			ReasoningCallback: func(reasoning string) {
 				if program != nil {
@@ -526,31 +724,65 @@ func (m *Model) sendMessage(content string) tea.Cmd {
 					program.Send(StreamTimingsMsg{TokensPerSecond: timings.PredictedPerSecond})
 				}
 			},
+			UsageCallback: usage.add,
 		}
 
-		err := api.StreamChatCompletion(ctx, req, cb)
+		err := api.StreamChatCompletionInLanguage(ctx, req, cb, maxContinues, respondIn)
+		sendContent(stream.Flush())
+		if tee != nil && fullContent.Len() > 0 {
+			io.WriteString(tee, "\n\n")
+		}
 
 		// Handle cancellation distinctly - no error shown to user
 		if errors.Is(err, context.Canceled) {
+			if pacer != nil {
+				pacer.Stop()
+			}
 			return StreamCancelledMsg{}
 		}
+		if pacer != nil {
+			pacer.Close()
+		}
 
-		return StreamDoneMsg{Error: err, Content: fullContent.String()}
+		return StreamDoneMsg{Error: err, Content: fullContent.String(), Usage: usage}
 	}
 
 	return tea.Batch(spinnerCmd, streamCmd)
 }
 
+// chatRequest builds a streaming request for messages with the session's
+// sampling options
+func (m *Model) chatRequest(messages []server.ChatMessage) *server.ChatCompletionRequest {
+	req := &server.ChatCompletionRequest{
+		Model:           m.model,
+		Messages:        messages,
+		Stream:          true,
+		StreamOptions:   &server.StreamOptions{IncludeUsage: true},
+		MaxTokens:       m.options.MaxTokens,
+		ReasoningFormat: "auto",
+	}
+	req.Temperature = m.resolver.ResolveFloat(m.options.Temp, "temp")
+	req.TopP = m.resolver.ResolveFloat(m.options.TopP, "top-p")
+	req.TopK = m.resolver.ResolveInt(m.options.TopK, "top-k")
+	req.MinP = m.resolver.ResolveFloat(m.options.MinP, "min-p")
+	req.RepeatPenalty = m.resolver.ResolveFloat(m.options.RepeatPenalty, "repeat-penalty")
+	return req
+}
+
 // startStreaming sets streaming state consistently and returns spinner tick command
 func (m *Model) startStreaming() tea.Cmd {
 	m.streaming = true
 	m.status.SetState(components.StatusStreaming)
+	if m.continuing {
+		return m.messages.ResumeStreaming()
+	}
 	return m.messages.StartStreaming()
 }
 
 // stopStreaming clears streaming state consistently
 func (m *Model) stopStreaming() {
 	m.streaming = false
+	m.continuing = false
 	m.status.SetState(components.StatusReady)
 	m.cancelStream = nil
 }
@@ -561,20 +793,31 @@ func commandCompletions() []components.Completion {
 	for _, cmd := range Commands {
 		items = append(items, components.Completion{
 			Text:        cmd.Name,
-			Description: cmd.Description,
+			Description: i18n.T(cmd.Description),
 			Value:       cmd.Name,
 		})
 	}
 	return items
 }
 
-// setOptionCompletions converts set option definitions to completion items
+// setOptionCompletions converts set option definitions to completion items,
+// followed by the catalog's other load-time llama-server options
 func setOptionCompletions() []components.Completion {
 	var items []components.Completion
 	for _, opt := range SetOptions {
 		items = append(items, components.Completion{
 			Text:        opt.Name,
-			Description: opt.Description,
+			Description: i18n.T(opt.Description),
+			Value:       opt.Name,
+		})
+	}
+	for _, opt := range config.ServerOptions() {
+		if !opt.Restart || slices.ContainsFunc(SetOptions, func(d SetOptionDef) bool { return d.Name == opt.Name }) {
+			continue
+		}
+		items = append(items, components.Completion{
+			Text:        opt.Name,
+			Description: i18n.Tf("%s (requires /reload)", opt.Description),
 			Value:       opt.Name,
 		})
 	}
@@ -11,10 +11,16 @@ type CommandDef struct {
 var Commands = []CommandDef{
 	{Name: "/help", Aliases: []string{"/?"}, Description: "Show help"},
 	{Name: "/clear", Description: "Clear conversation"},
-	{Name: "/system", Description: "Show/set system prompt"},
+	{Name: "/undo", Description: "Remove the last exchange"},
+	{Name: "/continue", Description: "Continue a cancelled reply"},
+	{Name: "/system", Description: "Show/set system prompt (@name for a saved one)"},
 	{Name: "/set", Description: "Change a setting"},
 	{Name: "/show", Description: "Show current settings"},
 	{Name: "/reload", Description: "Reload model"},
+	{Name: "/tee", Description: "Append responses to a file"},
+	{Name: "/save", Description: "Save the conversation under a title"},
+	{Name: "/image", Description: "Attach an image to the next message"},
+	{Name: "/keys", Description: "Show key bindings"},
 	{Name: "/bye", Aliases: []string{"/exit", "/quit"}, Description: "Exit chat"},
 }
 
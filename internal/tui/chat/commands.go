@@ -2,10 +2,15 @@ package chat
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/options"
 	"github.com/nchapman/lleme/internal/server"
 	"github.com/nchapman/lleme/internal/tui/components"
 )
@@ -20,37 +25,52 @@ func (m *Model) handleCommand(input string) tea.Cmd {
 	cmd := strings.ToLower(parts[0])
 	args := parts[1:]
 
+	// Starts a stream, so it runs now rather than as a command result
+	if cmd == "/continue" {
+		return m.continueReply()
+	}
+
 	return func() tea.Msg {
 		switch cmd {
 		case "/help", "/?":
 			return CommandResultMsg{Message: m.helpText()}
 
 		case "/bye", "/exit", "/quit":
-			return CommandResultMsg{Message: "Goodbye!", Exit: true}
+			return CommandResultMsg{Message: i18n.T("Goodbye!"), Exit: true}
 
 		case "/clear":
 			m.initSystemPrompt()
+			m.interrupted = false
 			m.messages.ClearMessages()
-			return CommandResultMsg{Message: "Conversation cleared"}
+			m.saved = nil // A new conversation saves to a new file
+			return CommandResultMsg{Message: i18n.T("Conversation cleared")}
+
+		case "/undo":
+			return m.handleUndo()
 
 		case "/system":
 			if len(args) == 0 {
 				// Show current system prompt
 				if len(m.chatMessages) > 0 && m.chatMessages[0].Role == "system" {
-					return CommandResultMsg{Message: "System prompt:\n" + m.chatMessages[0].Content}
+					return CommandResultMsg{Message: i18n.T("System prompt:\n") + m.chatMessages[0].Content}
 				}
-				return CommandResultMsg{Message: "No system prompt set"}
+				return CommandResultMsg{Message: i18n.T("No system prompt set")}
 			}
 			// Set new system prompt
-			newPrompt := strings.Join(args, " ")
+			newPrompt, err := config.ResolveSystemPrompt(strings.Join(args, " "))
+			if err != nil {
+				return CommandResultMsg{Message: err.Error(), IsError: true}
+			}
 			m.chatMessages = []server.ChatMessage{{Role: "system", Content: newPrompt}}
+			m.interrupted = false
 			m.messages.ClearMessages()
-			return CommandResultMsg{Message: "System prompt updated, conversation cleared"}
+			m.saved = nil
+			return CommandResultMsg{Message: i18n.T("System prompt updated, conversation cleared")}
 
 		case "/set":
 			if len(args) < 2 {
 				return CommandResultMsg{
-					Message: "Usage: /set <option> <value>\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads",
+					Message: i18n.T("Usage: /set <option> <value>\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads, or another llama-server load option"),
 					IsError: true,
 				}
 			}
@@ -62,15 +82,50 @@ func (m *Model) handleCommand(input string) tea.Cmd {
 		case "/show":
 			return CommandResultMsg{Message: m.showSettings()}
 
+		case "/tee":
+			return m.handleTee(args)
+
+		case "/save":
+			return m.handleSave(args)
+
+		case "/image":
+			return m.handleImage(args)
+
+		case "/keys":
+			return CommandResultMsg{Message: m.keysText()}
+
 		default:
 			return CommandResultMsg{
-				Message: fmt.Sprintf("Unknown command: %s (type /? for help)", cmd),
+				Message: i18n.Tf("Unknown command: %s (type /? for help)", cmd),
 				IsError: true,
 			}
 		}
 	}
 }
 
+// handleUndo removes the last user message and the reply to it, putting
+// the message and its images back to be rephrased
+func (m *Model) handleUndo() CommandResultMsg {
+	last := -1
+	for i := len(m.chatMessages) - 1; i >= 0; i-- {
+		if m.chatMessages[i].Role == "user" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return CommandResultMsg{Message: i18n.T("Nothing to undo")}
+	}
+
+	content := m.chatMessages[last].Content
+	m.chatMessages = m.chatMessages[:last]
+	m.interrupted = false
+	if removed, ok := m.messages.RemoveLastExchange(); ok {
+		m.pendingImages = append(removed.Images, m.pendingImages...)
+	}
+	return CommandResultMsg{Message: i18n.T("Removed the last exchange"), Input: content}
+}
+
 // handleSet processes the /set command
 func (m *Model) handleSet(option, value string) CommandResultMsg {
 	option = strings.ToLower(option)
@@ -78,125 +133,154 @@ func (m *Model) handleSet(option, value string) CommandResultMsg {
 	floatVal, floatErr := strconv.ParseFloat(value, 64)
 	intVal, intErr := strconv.Atoi(value)
 
+	// Numbers are checked against the option catalog's ranges
+	name := option
+	if name == "temperature" {
+		name = "temp"
+	}
+	if opt, ok := config.LookupServerOption(name); ok && floatErr == nil {
+		if err := config.ValidateServerOption(opt.Name, opt.Parse(value)); err != nil {
+			return CommandResultMsg{Message: err.Error(), IsError: true}
+		}
+	}
+
 	switch option {
 	case "temp", "temperature":
 		if floatErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for temp: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for temp: %s", value), IsError: true}
 		}
 		m.options.Temp = floatVal
-		return CommandResultMsg{Message: fmt.Sprintf("Set temp = %g", floatVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set temp = %g", floatVal)}
 
 	case "top-p":
 		if floatErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for top-p: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for top-p: %s", value), IsError: true}
 		}
 		m.options.TopP = floatVal
-		return CommandResultMsg{Message: fmt.Sprintf("Set top-p = %g", floatVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set top-p = %g", floatVal)}
 
 	case "top-k":
 		if intErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for top-k: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for top-k: %s", value), IsError: true}
 		}
 		m.options.TopK = intVal
-		return CommandResultMsg{Message: fmt.Sprintf("Set top-k = %d", intVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set top-k = %d", intVal)}
 
 	case "repeat-penalty":
 		if floatErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for repeat-penalty: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for repeat-penalty: %s", value), IsError: true}
 		}
 		m.options.RepeatPenalty = floatVal
-		return CommandResultMsg{Message: fmt.Sprintf("Set repeat-penalty = %g", floatVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set repeat-penalty = %g", floatVal)}
 
 	case "min-p":
 		if floatErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for min-p: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for min-p: %s", value), IsError: true}
 		}
 		m.options.MinP = floatVal
-		return CommandResultMsg{Message: fmt.Sprintf("Set min-p = %g", floatVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set min-p = %g", floatVal)}
 
 	case "ctx-size":
 		if intErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for ctx-size: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for ctx-size: %s", value), IsError: true}
 		}
 		m.options.CtxSize = intVal
 		m.options.CtxSizeSet = true
 		m.pendingReload = true
-		return CommandResultMsg{Message: fmt.Sprintf("Set ctx-size = %d (use /reload to apply)", intVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set ctx-size = %d (use /reload to apply)", intVal)}
 
 	case "gpu-layers":
 		if intErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for gpu-layers: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for gpu-layers: %s", value), IsError: true}
 		}
 		m.options.GpuLayers = intVal
 		m.options.GpuLayersSet = true
 		m.pendingReload = true
-		return CommandResultMsg{Message: fmt.Sprintf("Set gpu-layers = %d (use /reload to apply)", intVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set gpu-layers = %d (use /reload to apply)", intVal)}
 
 	case "threads":
 		if intErr != nil {
-			return CommandResultMsg{Message: fmt.Sprintf("Invalid value for threads: %s", value), IsError: true}
+			return CommandResultMsg{Message: i18n.Tf("Invalid value for threads: %s", value), IsError: true}
 		}
 		m.options.Threads = intVal
 		m.options.ThreadsSet = true
 		m.pendingReload = true
-		return CommandResultMsg{Message: fmt.Sprintf("Set threads = %d (use /reload to apply)", intVal)}
+		return CommandResultMsg{Message: i18n.Tf("Set threads = %d (use /reload to apply)", intVal)}
 
 	default:
-		return CommandResultMsg{
-			Message: fmt.Sprintf("Unknown option: %s\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads", option),
-			IsError: true,
+		// Any other load-time option in the catalog applies on /reload
+		opt, ok := config.LookupServerOption(option)
+		if !ok || !opt.Restart {
+			return CommandResultMsg{
+				Message: i18n.Tf("Unknown option: %s\nOptions: temp, top-p, top-k, repeat-penalty, min-p, ctx-size, gpu-layers, threads, or another llama-server load option", option),
+				IsError: true,
+			}
 		}
+		val := opt.Parse(value)
+		if err := config.ValidateServerOption(opt.Name, val); err != nil {
+			return CommandResultMsg{Message: err.Error(), IsError: true}
+		}
+		if m.options.Server == nil {
+			m.options.Server = make(map[string]any)
+		}
+		m.options.Server[opt.Name] = val
+		m.pendingReload = true
+		return CommandResultMsg{Message: i18n.Tf("Set %s = %v (use /reload to apply)", opt.Name, val)}
 	}
 }
 
 // handleReload reloads the model with new server options
 func (m *Model) handleReload() CommandResultMsg {
 	if !m.pendingReload {
-		return CommandResultMsg{Message: "No pending server option changes to apply"}
+		return CommandResultMsg{Message: i18n.T("No pending server option changes to apply")}
 	}
 
 	// Stop the current model
 	if err := m.api.StopModel(m.model); err != nil {
-		return CommandResultMsg{Message: fmt.Sprintf("Failed to stop model: %v", err), IsError: true}
+		return CommandResultMsg{Message: i18n.Tf("Failed to stop model: %v", err), IsError: true}
 	}
 
 	// Reload with persona options as base, session options override
-	opts := &server.RunOptions{}
-	if m.persona != nil {
-		opts.Options = m.persona.GetServerOptions()
-	}
-	if m.options.CtxSizeSet {
-		opts.CtxSize = server.IntPtr(m.options.CtxSize)
-	}
-	if m.options.GpuLayersSet {
-		opts.GpuLayers = server.IntPtr(m.options.GpuLayers)
-	}
-	if m.options.ThreadsSet {
-		opts.Threads = server.IntPtr(m.options.Threads)
-	}
-	if err := m.api.Run(m.model, opts); err != nil {
-		return CommandResultMsg{Message: fmt.Sprintf("Failed to reload model: %v", err), IsError: true}
+	if err := m.api.Run(m.model, m.options.runOptions(m.serverOptions, m.persona.GetPassthrough())); err != nil {
+		return CommandResultMsg{Message: i18n.Tf("Failed to reload model: %v", err), IsError: true}
 	}
 
 	m.pendingReload = false
-	return CommandResultMsg{Message: "Model reloaded"}
+	return CommandResultMsg{Message: i18n.T("Model reloaded")}
 }
 
 // helpText returns the help message
 func (m *Model) helpText() string {
 	var sb strings.Builder
-	sb.WriteString("Commands:\n")
+	sb.WriteString(i18n.T("Commands:") + "\n")
 	for _, cmd := range Commands {
 		// Format: name + aliases, padded to 22 chars, then description
 		names := cmd.Name
 		if len(cmd.Aliases) > 0 {
 			names += ", " + strings.Join(cmd.Aliases, ", ")
 		}
-		fmt.Fprintf(&sb, "  %-20s %s\n", names, cmd.Description)
+		fmt.Fprintf(&sb, "  %-20s %s\n", names, i18n.T(cmd.Description))
 	}
-	sb.WriteString("\nOptions for /set:\n")
+	sb.WriteString("\n" + i18n.T("Options for /set:") + "\n")
 	sb.WriteString("  temp, top-p, top-k, repeat-penalty, min-p\n")
-	sb.WriteString("  ctx-size*, gpu-layers*, threads*  (* require /reload)")
+	sb.WriteString("  ctx-size*, gpu-layers*, threads*  " + i18n.T("(* require /reload)") + "\n")
+	sb.WriteString("  " + i18n.T("Other llama-server load options, like cache-type-k* or rope-scaling*"))
+	return sb.String()
+}
+
+// keysText lists the key bindings for each action
+func (m *Model) keysText() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.Tf("Key bindings (%s):", m.keys.preset) + "\n")
+	for _, action := range keyActions {
+		b := m.keys.binding(action)
+		keys := i18n.T("(unbound)")
+		if b.Enabled() {
+			keys = strings.Join(keyNames(b.Keys()), ", ")
+		}
+		fmt.Fprintf(&sb, "  %-16s %-24s %s\n", action, keys, i18n.T(b.Help().Desc))
+	}
+	sb.WriteString("\n" + i18n.T("Change them with chat.keymap and chat.keys in config.yaml"))
 	return sb.String()
 }
 
@@ -204,8 +288,8 @@ func (m *Model) helpText() string {
 func (m *Model) showSettings() string {
 	var sb strings.Builder
 
-	sb.WriteString("Current Settings\n\n")
-	sb.WriteString(fmt.Sprintf("  Model: %s\n\n", m.model))
+	sb.WriteString(i18n.T("Current Settings") + "\n\n")
+	sb.WriteString(i18n.Tf("  Model: %s\n\n", m.model))
 
 	// Show system prompt (truncated if long)
 	if len(m.chatMessages) > 0 && m.chatMessages[0].Role == "system" {
@@ -213,69 +297,52 @@ func (m *Model) showSettings() string {
 		if len(prompt) > 80 {
 			prompt = prompt[:77] + "..."
 		}
-		sb.WriteString(fmt.Sprintf("  System: %s\n\n", prompt))
+		sb.WriteString(i18n.Tf("  System: %s\n\n", prompt))
 	}
 
+	// Each option's winning value, and where it came from
+	resolved := m.resolver.Resolve(m.options.set())
+
 	// Request-time options
-	sb.WriteString("  Sampling:\n")
-	sb.WriteString(m.formatOption("temp", m.options.Temp, m.resolver.GetConfigFloat("temp")))
-	sb.WriteString(m.formatOption("top-p", m.options.TopP, m.resolver.GetConfigFloat("top-p")))
-	sb.WriteString(m.formatOptionInt("top-k", m.options.TopK, m.resolver.GetConfigInt("top-k")))
-	sb.WriteString(m.formatOption("repeat-penalty", m.options.RepeatPenalty, m.resolver.GetConfigFloat("repeat-penalty")))
-	sb.WriteString(m.formatOption("min-p", m.options.MinP, m.resolver.GetConfigFloat("min-p")))
+	sb.WriteString(i18n.T("  Sampling:\n"))
+	for _, key := range []string{"temp", "top-p", "top-k", "repeat-penalty", "min-p"} {
+		sb.WriteString(formatSetting(resolved, key))
+	}
 	sb.WriteString("\n")
 
 	// Server options
-	sb.WriteString("  Server:\n")
-	sb.WriteString(m.formatServerOption("ctx-size", m.options.CtxSize, m.options.CtxSizeSet, m.resolver.GetConfigInt("ctx-size")))
-	sb.WriteString(m.formatServerOption("gpu-layers", m.options.GpuLayers, m.options.GpuLayersSet, m.resolver.GetConfigInt("gpu-layers")))
-	sb.WriteString(m.formatServerOption("threads", m.options.Threads, m.options.ThreadsSet, m.resolver.GetConfigInt("threads")))
-
-	return sb.String()
-}
-
-// formatSetting formats a setting line showing session/config/default value.
-func formatSetting(name, sessionVal, configVal string) string {
-	if sessionVal != "" {
-		return fmt.Sprintf("    %s = %s (session)\n", name, sessionVal)
+	sb.WriteString(i18n.T("  Server:\n"))
+	for _, key := range []string{"ctx-size", "gpu-layers", "threads"} {
+		sb.WriteString(formatSetting(resolved, key))
 	}
-	if configVal != "" {
-		return fmt.Sprintf("    %s = %s (config)\n", name, configVal)
+	for _, key := range slices.Sorted(maps.Keys(m.options.Server)) {
+		sb.WriteString(formatSetting(resolved, key))
 	}
-	return fmt.Sprintf("    %s = default\n", name)
-}
+	sb.WriteString("\n")
 
-func (m *Model) formatOption(name string, sessionVal, configVal float64) string {
-	var session, config string
-	if sessionVal != 0 {
-		session = fmt.Sprintf("%g", sessionVal)
-	}
-	if configVal != 0 {
-		config = fmt.Sprintf("%g", configVal)
-	}
-	return formatSetting(name, session, config)
-}
+	sb.WriteString(m.usageSummary())
 
-func (m *Model) formatOptionInt(name string, sessionVal, configVal int) string {
-	var session, config string
-	if sessionVal != 0 {
-		session = fmt.Sprintf("%d", sessionVal)
-	}
-	if configVal != 0 {
-		config = fmt.Sprintf("%d", configVal)
-	}
-	return formatSetting(name, session, config)
+	return sb.String()
 }
 
-func (m *Model) formatServerOption(name string, sessionVal int, isSet bool, configVal int) string {
-	var session, config string
-	if isSet {
-		session = fmt.Sprintf("%d", sessionVal)
+// formatSetting formats a setting line showing key's value and where it
+// came from: the session, the persona, per-model config or config.
+func formatSetting(resolved options.Resolved, key string) string {
+	v, ok := resolved.Get(key)
+	if !ok {
+		return i18n.Tf("    %s = default\n", key)
 	}
-	if configVal != 0 {
-		config = fmt.Sprintf("%d", configVal)
+	value := fmt.Sprint(v.Value)
+	switch v.Source {
+	case options.SourceFlag:
+		return i18n.Tf("    %s = %s (session)\n", key, value)
+	case options.SourcePersona:
+		return i18n.Tf("    %s = %s (persona)\n", key, value)
+	case options.SourceModel:
+		return i18n.Tf("    %s = %s (model options)\n", key, value)
+	default:
+		return i18n.Tf("    %s = %s (config)\n", key, value)
 	}
-	return formatSetting(name, session, config)
 }
 
 // ClearMessages clears the messages viewport (called from command handler)
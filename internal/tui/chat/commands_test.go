@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/tui/components"
+	"github.com/nchapman/lleme/internal/tui/termimg"
+)
+
+func TestHandleUndo(t *testing.T) {
+	i18n.SetLanguage("en")
+	img := termimg.Image{Name: "cat.png"}
+
+	m := &Model{messages: components.NewMessages()}
+	m.chatMessages = []server.ChatMessage{
+		{Role: "system", Content: "Be brief"},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "one"},
+		{Role: "user", Content: "what's this?", Images: []string{"data:image/png;base64,"}},
+		{Role: "assistant", Content: "a cat"},
+	}
+	m.messages.AddMessage(components.Message{Role: components.RoleUser, Content: "first"})
+	m.messages.AddMessage(components.Message{Role: components.RoleAssistant, Content: "one"})
+	m.messages.AddMessage(components.Message{Role: components.RoleUser, Content: "what's this?", Images: []termimg.Image{img}})
+	m.messages.AddMessage(components.Message{Role: components.RoleAssistant, Content: "a cat"})
+
+	msg := m.handleUndo()
+	if msg.IsError || msg.Input != "what's this?" {
+		t.Fatalf("/undo = %+v, want the message put back in the input", msg)
+	}
+	if len(m.chatMessages) != 3 || m.chatMessages[2].Content != "one" {
+		t.Errorf("history = %+v, want the last exchange removed", m.chatMessages)
+	}
+	if n := len(m.messages.MessagesList()); n != 2 {
+		t.Errorf("UI has %d messages, want 2", n)
+	}
+	if len(m.pendingImages) != 1 || m.pendingImages[0].Name != "cat.png" {
+		t.Errorf("pending images = %+v, want the message's image back", m.pendingImages)
+	}
+
+	// A message whose reply failed is undone on its own
+	m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "user", Content: "again"})
+	if msg := m.handleUndo(); msg.Input != "again" || len(m.chatMessages) != 3 {
+		t.Errorf("/undo after a failed reply = %+v, history %d messages", msg, len(m.chatMessages))
+	}
+
+	m.handleUndo()
+	if len(m.chatMessages) != 1 || m.chatMessages[0].Role != "system" {
+		t.Errorf("history = %+v, want only the system prompt", m.chatMessages)
+	}
+	if msg := m.handleUndo(); msg.Message != "Nothing to undo" || msg.Input != "" {
+		t.Errorf("/undo with nothing left = %+v", msg)
+	}
+}
+
+func TestContinueCancelledReply(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	i18n.SetLanguage("en")
+
+	m := New(server.NewAPIClientFromURL("http://127.0.0.1:0"), "user/repo:Q4_K_M", config.DefaultConfig(), nil, "")
+	if msg, _ := m.handleCommand("/continue")().(CommandResultMsg); msg.Message != "No cancelled reply to continue" {
+		t.Errorf("/continue with nothing cancelled = %+v", msg)
+	}
+
+	// Esc keeps what arrived of the reply
+	m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "user", Content: "tell a story"})
+	m.startStreaming()
+	m.messages.AppendStreamContent("Once upon")
+	m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	last := m.chatMessages[len(m.chatMessages)-1]
+	if !m.interrupted || last.Role != "assistant" || last.Content != "Once upon" {
+		t.Fatalf("after cancel: interrupted = %v, last message %+v", m.interrupted, last)
+	}
+
+	// The continuation lands in the same message
+	m.interrupted = false
+	m.continuing = true
+	m.startStreaming()
+	m.messages.AppendStreamContent(" a time")
+	m.Update(StreamDoneMsg{Content: " a time"})
+
+	if n := len(m.chatMessages); n != 3 || m.chatMessages[2].Content != "Once upon a time" {
+		t.Errorf("history = %+v, want the reply extended", m.chatMessages)
+	}
+	msgs := m.messages.MessagesList()
+	if len(msgs) != 1 || msgs[0].Content != "Once upon a time" {
+		t.Errorf("UI messages = %+v, want one extended reply", msgs)
+	}
+	if m.interrupted || m.continuing {
+		t.Errorf("interrupted = %v, continuing = %v after the reply finished", m.interrupted, m.continuing)
+	}
+}
+
+func TestHandleSet(t *testing.T) {
+	i18n.SetLanguage("en")
+	m := &Model{}
+
+	tests := []struct {
+		option, value string
+		wantErr       bool
+	}{
+		{"temp", "0.7", false},
+		{"temp", "-1", true},
+		{"top-p", "1.5", true},
+		{"top-k", "abc", true},
+		{"ctx-size", "8192", false},
+		{"cache-type-k", "q8_0", false},
+		{"cache-type-k", "q3", true},
+		{"gpu-layers-draft", "auto", false},
+		{"seed", "42", true}, // Sampling options other than the built-in ones aren't settable
+		{"no-such-option", "1", true},
+	}
+	for _, tt := range tests {
+		if msg := m.handleSet(tt.option, tt.value); msg.IsError != tt.wantErr {
+			t.Errorf("/set %s %s = %q, wantErr %v", tt.option, tt.value, msg.Message, tt.wantErr)
+		}
+	}
+
+	if m.options.Temp != 0.7 || m.options.CtxSize != 8192 || !m.pendingReload {
+		t.Errorf("options = %+v, pendingReload %v", m.options, m.pendingReload)
+	}
+	want := map[string]any{"cache-type-k": "q8_0", "gpu-layers-draft": "auto"}
+	if len(m.options.Server) != len(want) || m.options.Server["cache-type-k"] != "q8_0" || m.options.Server["gpu-layers-draft"] != "auto" {
+		t.Errorf("server options = %v, want %v", m.options.Server, want)
+	}
+
+	// Catalog options set this session are loaded with on /reload
+	opts := m.options.runOptions(map[string]any{"cache-type-k": "f16", "mlock": true}, map[string]any{"some-new-flag": 1})
+	if opts.Options["cache-type-k"] != "q8_0" || opts.Options["mlock"] != true || opts.Passthrough["some-new-flag"] != 1 {
+		t.Errorf("runOptions() = %+v", opts)
+	}
+	if *opts.CtxSize != 8192 {
+		t.Errorf("CtxSize = %d, want 8192", *opts.CtxSize)
+	}
+}
@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"strings"
+
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/tui/termimg"
+)
+
+// SetImageProtocol sets how images in the conversation are drawn
+func (m *Model) SetImageProtocol(p termimg.Protocol) {
+	m.messages.SetImageProtocol(p)
+}
+
+// AttachImage loads an image to send with the next message. The model
+// needs vision support (an mmproj) to see it.
+func (m *Model) AttachImage(path string) (termimg.Image, error) {
+	img, err := termimg.Load(path)
+	if err != nil {
+		return termimg.Image{}, err
+	}
+	m.pendingImages = append(m.pendingImages, img)
+	return img, nil
+}
+
+// takeImages returns the attached images and their data URLs, clearing them
+// for the next message
+func (m *Model) takeImages() ([]termimg.Image, []string) {
+	images := m.pendingImages
+	m.pendingImages = nil
+	var urls []string
+	for _, img := range images {
+		urls = append(urls, img.DataURL())
+	}
+	return images, urls
+}
+
+// handleImage processes the /image command
+func (m *Model) handleImage(args []string) CommandResultMsg {
+	if len(args) == 0 {
+		if len(m.pendingImages) == 0 {
+			return CommandResultMsg{Message: i18n.T("No images attached\nUsage: /image <file> or /image clear")}
+		}
+		var names []string
+		for _, img := range m.pendingImages {
+			names = append(names, img.Name)
+		}
+		return CommandResultMsg{Message: i18n.Tf("Attached to your next message: %s", strings.Join(names, ", "))}
+	}
+
+	if args[0] == "clear" && len(args) == 1 {
+		m.pendingImages = nil
+		return CommandResultMsg{Message: i18n.T("Removed attached images")}
+	}
+
+	img, err := m.AttachImage(strings.Join(args, " "))
+	if err != nil {
+		return CommandResultMsg{Message: err.Error(), IsError: true}
+	}
+	return CommandResultMsg{Message: i18n.Tf("Attached %s, sent with your next message", img.Placeholder())}
+}
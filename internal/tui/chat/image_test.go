@@ -0,0 +1,53 @@
+package chat
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/i18n"
+)
+
+func TestHandleImage(t *testing.T) {
+	i18n.SetLanguage("en")
+	m := &Model{}
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 3)))
+	path := filepath.Join(dir, "my photo.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paths with spaces arrive split into fields
+	msg := m.handleImage(strings.Fields(path))
+	if msg.IsError || !strings.Contains(msg.Message, "[image: my photo.png, 4×3]") {
+		t.Fatalf("/image %s = %+v", path, msg)
+	}
+	if msg := m.handleImage(nil); !strings.Contains(msg.Message, "my photo.png") {
+		t.Errorf("/image = %q, want the attached file listed", msg.Message)
+	}
+
+	if msg := m.handleImage([]string{filepath.Join(dir, "missing.png")}); !msg.IsError {
+		t.Errorf("/image of a missing file = %+v, want an error", msg)
+	}
+
+	images, urls := m.takeImages()
+	if len(images) != 1 || len(urls) != 1 || !strings.HasPrefix(urls[0], "data:image/png;base64,") {
+		t.Fatalf("takeImages() = %d images, %v", len(images), urls)
+	}
+	if len(m.pendingImages) != 0 {
+		t.Error("takeImages() left images attached")
+	}
+
+	m.handleImage([]string{path})
+	m.handleImage([]string{"clear"})
+	if len(m.pendingImages) != 0 {
+		t.Error("/image clear left images attached")
+	}
+}
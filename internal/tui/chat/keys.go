@@ -1,33 +1,90 @@
 package chat
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+)
 
 // KeyMap defines all key bindings for the chat TUI
 type KeyMap struct {
-	Send       key.Binding
-	Quit       key.Binding
-	ScrollUp   key.Binding
-	ScrollDown key.Binding
-	PageUp     key.Binding
-	PageDown   key.Binding
-	Top        key.Binding
-	Bottom     key.Binding
-	Clear      key.Binding
-	Help       key.Binding
-	Cancel     key.Binding
+	Send         key.Binding
+	Newline      key.Binding
+	HistoryPrev  key.Binding // Recall older input on the first line
+	HistoryNext  key.Binding // Recall newer input on the last line
+	HistoryFind  key.Binding // Search input history
+	Quit         key.Binding
+	Cancel       key.Binding
+	Focus        key.Binding // Toggle between input and messages
+	FocusInput   key.Binding // From the messages pane
+	FocusHistory key.Binding // From the input pane
+	ScrollUp     key.Binding
+	ScrollDown   key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	HalfPageUp   key.Binding
+	HalfPageDown key.Binding
+	Top          key.Binding
+	Bottom       key.Binding
+	Clear        key.Binding
+	Help         key.Binding
+
+	preset string
 }
 
+// Keymap presets for chat.keymap
+const (
+	KeymapDefault = "default"
+	KeymapVim     = "vim"
+)
+
 // DefaultKeyMap returns the default key bindings
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
+		preset: KeymapDefault,
 		Send: key.NewBinding(
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "send"),
 		),
+		Newline: key.NewBinding(
+			key.WithKeys("shift+enter", "ctrl+j"),
+			key.WithHelp("shift+enter", "new line"),
+		),
+		HistoryPrev: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑", "previous input"),
+		),
+		HistoryNext: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓", "next input"),
+		),
+		HistoryFind: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "search history"),
+		),
 		Quit: key.NewBinding(
-			key.WithKeys("ctrl+c", "ctrl+d"),
+			key.WithKeys("ctrl+c"),
 			key.WithHelp("ctrl+c", "quit"),
 		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		Focus: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch pane"),
+		),
+		FocusInput: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back to input"),
+		),
+		FocusHistory: key.NewBinding(
+			key.WithHelp("", "to messages"),
+			key.WithDisabled(),
+		),
 		ScrollUp: key.NewBinding(
 			key.WithKeys("up", "k"),
 			key.WithHelp("↑/k", "scroll up"),
@@ -37,13 +94,21 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("↓/j", "scroll down"),
 		),
 		PageUp: key.NewBinding(
-			key.WithKeys("pgup", "ctrl+u"),
+			key.WithKeys("pgup", "b"),
 			key.WithHelp("pgup", "page up"),
 		),
 		PageDown: key.NewBinding(
-			key.WithKeys("pgdown"),
+			key.WithKeys("pgdown", "f", " "),
 			key.WithHelp("pgdn", "page down"),
 		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("u", "ctrl+u"),
+			key.WithHelp("u", "half page up"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("d", "ctrl+d"),
+			key.WithHelp("d", "half page down"),
+		),
 		Top: key.NewBinding(
 			key.WithKeys("home", "g"),
 			key.WithHelp("home", "top"),
@@ -57,16 +122,166 @@ func DefaultKeyMap() KeyMap {
 			key.WithHelp("ctrl+l", "clear"),
 		),
 		Help: key.NewBinding(
-			key.WithKeys("ctrl+?", "f1"),
-			key.WithHelp("/?", "help"),
-		),
-		Cancel: key.NewBinding(
-			key.WithKeys("esc"),
-			key.WithHelp("esc", "cancel"),
+			key.WithKeys("f1"),
+			key.WithHelp("f1", "help"),
 		),
 	}
 }
 
+// VimKeyMap returns modal bindings: esc leaves the input for the messages
+// pane, where hjkl-style keys scroll, and i or a return to typing
+func VimKeyMap() KeyMap {
+	k := DefaultKeyMap()
+	k.preset = KeymapVim
+	k.FocusInput = key.NewBinding(
+		key.WithKeys("i", "a"),
+		key.WithHelp("i", "insert"),
+	)
+	k.FocusHistory = key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "normal mode"),
+	)
+	k.ScrollUp = key.NewBinding(
+		key.WithKeys("k", "up", "ctrl+y"),
+		key.WithHelp("k", "scroll up"),
+	)
+	k.ScrollDown = key.NewBinding(
+		key.WithKeys("j", "down", "ctrl+e"),
+		key.WithHelp("j", "scroll down"),
+	)
+	k.PageUp = key.NewBinding(
+		key.WithKeys("ctrl+b", "pgup"),
+		key.WithHelp("ctrl+b", "page up"),
+	)
+	k.PageDown = key.NewBinding(
+		key.WithKeys("ctrl+f", "pgdown"),
+		key.WithHelp("ctrl+f", "page down"),
+	)
+	k.HalfPageUp = key.NewBinding(
+		key.WithKeys("ctrl+u"),
+		key.WithHelp("ctrl+u", "half page up"),
+	)
+	k.HalfPageDown = key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "half page down"),
+	)
+	k.Top = key.NewBinding(
+		key.WithKeys("g", "home"),
+		key.WithHelp("g", "top"),
+	)
+	k.Bottom = key.NewBinding(
+		key.WithKeys("G", "end"),
+		key.WithHelp("G", "bottom"),
+	)
+	return k
+}
+
+// keyActions names the bindings chat.keys can set, in /keys order
+var keyActions = []string{
+	"send", "newline", "history-prev", "history-next", "history-search", "cancel", "quit", "focus", "focus-input", "focus-history",
+	"scroll-up", "scroll-down", "page-up", "page-down", "half-page-up", "half-page-down",
+	"top", "bottom", "clear", "help",
+}
+
+// binding returns the binding for a chat.keys action name
+func (k *KeyMap) binding(action string) *key.Binding {
+	switch action {
+	case "send":
+		return &k.Send
+	case "newline":
+		return &k.Newline
+	case "history-prev":
+		return &k.HistoryPrev
+	case "history-next":
+		return &k.HistoryNext
+	case "history-search":
+		return &k.HistoryFind
+	case "cancel":
+		return &k.Cancel
+	case "quit":
+		return &k.Quit
+	case "focus":
+		return &k.Focus
+	case "focus-input":
+		return &k.FocusInput
+	case "focus-history":
+		return &k.FocusHistory
+	case "scroll-up":
+		return &k.ScrollUp
+	case "scroll-down":
+		return &k.ScrollDown
+	case "page-up":
+		return &k.PageUp
+	case "page-down":
+		return &k.PageDown
+	case "half-page-up":
+		return &k.HalfPageUp
+	case "half-page-down":
+		return &k.HalfPageDown
+	case "top":
+		return &k.Top
+	case "bottom":
+		return &k.Bottom
+	case "clear":
+		return &k.Clear
+	case "help":
+		return &k.Help
+	}
+	return nil
+}
+
+// NewKeyMap builds the key bindings for chat.keymap with the chat.keys
+// overrides applied. An empty key list unbinds an action.
+func NewKeyMap(preset string, overrides map[string][]string) (KeyMap, error) {
+	var k KeyMap
+	switch preset {
+	case "", KeymapDefault:
+		k = DefaultKeyMap()
+	case KeymapVim:
+		k = VimKeyMap()
+	default:
+		return KeyMap{}, fmt.Errorf("unknown chat.keymap %q: must be %s or %s", preset, KeymapDefault, KeymapVim)
+	}
+
+	for action, keys := range overrides {
+		b := k.binding(action)
+		if b == nil {
+			return KeyMap{}, fmt.Errorf("unknown chat.keys action %q: must be one of %s", action, strings.Join(keyActions, ", "))
+		}
+		if len(keys) == 0 {
+			b.SetEnabled(false)
+			continue
+		}
+		b.SetKeys(keys...)
+		b.SetHelp(keys[0], b.Help().Desc)
+		b.SetEnabled(true)
+	}
+
+	if k.Send.Enabled() && k.Newline.Enabled() {
+		for _, send := range k.Send.Keys() {
+			if slices.Contains(k.Newline.Keys(), send) {
+				return KeyMap{}, fmt.Errorf("chat.keys: %q can't both send and insert a new line", send)
+			}
+		}
+	}
+	if !k.Send.Enabled() {
+		return KeyMap{}, fmt.Errorf("chat.keys: send needs at least one key")
+	}
+	return k, nil
+}
+
+// Viewport returns the scrolling bindings for the messages pane
+func (k KeyMap) Viewport() viewport.KeyMap {
+	return viewport.KeyMap{
+		Up:           k.ScrollUp,
+		Down:         k.ScrollDown,
+		PageUp:       k.PageUp,
+		PageDown:     k.PageDown,
+		HalfPageUp:   k.HalfPageUp,
+		HalfPageDown: k.HalfPageDown,
+	}
+}
+
 // ShortHelp returns key bindings for the short help view
 func (k KeyMap) ShortHelp() []key.Binding {
 	return []key.Binding{k.Send, k.Help, k.Quit}
@@ -75,9 +290,24 @@ func (k KeyMap) ShortHelp() []key.Binding {
 // FullHelp returns key bindings for the expanded help view
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Send, k.Cancel},
-		{k.ScrollUp, k.ScrollDown, k.PageUp, k.PageDown},
+		{k.Send, k.Newline, k.Cancel},
+		{k.HistoryPrev, k.HistoryNext, k.HistoryFind},
+		{k.Focus, k.FocusInput, k.FocusHistory},
+		{k.ScrollUp, k.ScrollDown, k.PageUp, k.PageDown, k.HalfPageUp, k.HalfPageDown},
 		{k.Top, k.Bottom},
 		{k.Clear, k.Help, k.Quit},
 	}
 }
+
+// keyNames returns keys as they're written in config.yaml, spelling out
+// keys that would be invisible
+func keyNames(keys []string) []string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		if k == " " {
+			k = "space"
+		}
+		names[i] = k
+	}
+	return names
+}
@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewKeyMap(t *testing.T) {
+	tests := []struct {
+		name        string
+		preset      string
+		overrides   map[string][]string
+		wantSend    []string
+		wantNewline []string
+		wantTop     []string
+		wantClear   bool
+		wantErr     string
+	}{
+		{"default", "", nil, []string{"enter"}, []string{"shift+enter", "ctrl+j"}, []string{"home", "g"}, true, ""},
+		{"vim", "vim", nil, []string{"enter"}, []string{"shift+enter", "ctrl+j"}, []string{"g", "home"}, true, ""},
+		{"swap send and newline", "default", map[string][]string{"send": {"ctrl+s"}, "newline": {"enter"}},
+			[]string{"ctrl+s"}, []string{"enter"}, []string{"home", "g"}, true, ""},
+		{"unbind", "vim", map[string][]string{"clear": {}}, []string{"enter"}, []string{"shift+enter", "ctrl+j"}, []string{"g", "home"}, false, ""},
+		{"unknown preset", "emacs", nil, nil, nil, nil, false, "unknown chat.keymap"},
+		{"unknown action", "", map[string][]string{"submit": {"enter"}}, nil, nil, nil, false, "unknown chat.keys action"},
+		{"send and newline conflict", "", map[string][]string{"newline": {"enter"}}, nil, nil, nil, false, "can't both send and insert"},
+		{"send unbound", "", map[string][]string{"send": {}}, nil, nil, nil, false, "send needs at least one key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := NewKeyMap(tt.preset, tt.overrides)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewKeyMap() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewKeyMap() error = %v", err)
+			}
+			if fmt.Sprint(k.Send.Keys()) != fmt.Sprint(tt.wantSend) {
+				t.Errorf("send = %v, want %v", k.Send.Keys(), tt.wantSend)
+			}
+			if fmt.Sprint(k.Newline.Keys()) != fmt.Sprint(tt.wantNewline) {
+				t.Errorf("newline = %v, want %v", k.Newline.Keys(), tt.wantNewline)
+			}
+			if fmt.Sprint(k.Top.Keys()) != fmt.Sprint(tt.wantTop) {
+				t.Errorf("top = %v, want %v", k.Top.Keys(), tt.wantTop)
+			}
+			if k.Clear.Enabled() != tt.wantClear {
+				t.Errorf("clear enabled = %v, want %v", k.Clear.Enabled(), tt.wantClear)
+			}
+		})
+	}
+}
+
+func TestNewKeyMapHelp(t *testing.T) {
+	k, err := NewKeyMap(KeymapVim, map[string][]string{"send": {"ctrl+s", "alt+enter"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := k.Send.Help(); h.Key != "ctrl+s" || h.Desc != "send" {
+		t.Errorf("send help = %+v, want the first key and the original description", h)
+	}
+	if !k.FocusHistory.Enabled() || k.FocusHistory.Keys()[0] != "esc" {
+		t.Errorf("vim focus-history = %v, want esc", k.FocusHistory.Keys())
+	}
+	if DefaultKeyMap().FocusHistory.Enabled() {
+		t.Error("default focus-history is enabled, want it unbound")
+	}
+}
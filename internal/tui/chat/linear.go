@@ -0,0 +1,295 @@
+package chat
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/tui/components"
+	"github.com/nchapman/lleme/internal/tui/lineedit"
+)
+
+// linearPrompt is shown before each line of input in accessible mode
+const linearPrompt = ">>> "
+
+// RunLinear runs the chat for screen readers (ui.accessible): no alternate
+// screen, spinners or repainting, just one line of input at a time and replies
+// written as they stream. Slash commands work as in the TUI, and a partial
+// command is completed when it's unambiguous or answered with its matches.
+// In a terminal, lines are edited like a shell's, with the model's input
+// history. Ctrl+C cancels a reply in progress, or exits at the prompt.
+func (m *Model) RunLinear(in io.Reader, out io.Writer) error {
+	go m.preloadModel()()
+
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	// Lines are read one at a time, only at the prompt, so the terminal is
+	// out of raw mode and Ctrl+C interrupts while a reply streams
+	reader := m.lineReader(in, out)
+	prompts := make(chan string)
+	defer close(prompts)
+	results := make(chan lineResult, 1)
+	go func() {
+		for prompt := range prompts {
+			line, err := reader.ReadLine(prompt)
+			results <- lineResult{line, err}
+		}
+	}()
+
+	fmt.Fprintln(out, i18n.Tf("Chatting with %s. Type /? for help or /bye to exit.", m.model))
+	for {
+		prompts <- linearPrompt
+
+		var line string
+		select {
+		case r := <-results:
+			switch {
+			case errors.Is(r.err, lineedit.ErrInterrupted):
+				return nil
+			case errors.Is(r.err, io.EOF):
+				fmt.Fprintln(out)
+				return nil
+			case r.err != nil:
+				fmt.Fprintln(out)
+				return r.err
+			}
+			line = strings.TrimSpace(r.line)
+		case <-interrupts:
+			fmt.Fprintln(out)
+			return nil
+		}
+
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "/"):
+			if m.runLinearCommand(line, out, interrupts) {
+				return nil
+			}
+		default:
+			m.streamLinear(line, out, interrupts)
+		}
+	}
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// lineReader returns a line editor with history when in is a terminal, and
+// otherwise reads plain lines, as from a pipe
+func (m *Model) lineReader(in io.Reader, out io.Writer) lineReader {
+	if f, ok := in.(*os.File); ok && lineedit.IsTerminal(f) {
+		return lineedit.New(f, out, m.history)
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &scanLines{scanner: scanner, out: out}
+}
+
+// scanLines reads lines without editing
+type scanLines struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (s *scanLines) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(s.out, prompt)
+	if s.scanner.Scan() {
+		return s.scanner.Text(), nil
+	}
+	if err := s.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// runLinearCommand runs a slash command and prints its result, reporting
+// whether the chat should end
+func (m *Model) runLinearCommand(line string, out io.Writer, interrupts <-chan os.Signal) bool {
+	line, matches := completeLine(line)
+	if len(matches) > 0 {
+		fmt.Fprintln(out, i18n.T("Did you mean:"))
+		for _, c := range matches {
+			fmt.Fprintf(out, "  %-16s %s\n", c.Text, c.Description)
+		}
+		return false
+	}
+	if strings.ToLower(line) == "/continue" {
+		m.continueLinear(out, interrupts)
+		return false
+	}
+
+	result, _ := m.handleCommand(line)().(CommandResultMsg)
+	if result.IsError {
+		fmt.Fprintln(out, i18n.T("Error:"), result.Message)
+	} else if result.Message != "" {
+		fmt.Fprintln(out, result.Message)
+	}
+	return result.Exit
+}
+
+// completeLine stands in for the completions popup. It expands an
+// unambiguous prefix of a command or /set option to the full name, or
+// returns the candidates when the prefix matches several or none is typed.
+func completeLine(line string) (string, []components.Completion) {
+	fields := strings.Fields(line)
+	word := strings.ToLower(fields[0])
+
+	if !isCommand(word) {
+		matches := matchCompletions(commandCompletions(), word)
+		if len(matches) != 1 {
+			if len(matches) == 0 {
+				return line, nil // Let the command handler report it
+			}
+			return line, matches
+		}
+		fields[0] = matches[0].Value
+	}
+
+	if fields[0] == "/set" && len(fields) >= 2 {
+		option := strings.ToLower(fields[1])
+		matches := matchCompletions(setOptionCompletions(), option)
+		exact := slices.ContainsFunc(matches, func(c components.Completion) bool { return c.Value == option })
+		switch {
+		case len(matches) == 1:
+			fields[1] = matches[0].Value
+		case len(matches) > 1 && !exact:
+			return line, matches
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// isCommand reports whether word is a command name or alias
+func isCommand(word string) bool {
+	return slices.ContainsFunc(Commands, func(cmd CommandDef) bool {
+		return word == cmd.Name || slices.Contains(cmd.Aliases, word)
+	})
+}
+
+// matchCompletions returns the items that start with prefix
+func matchCompletions(items []components.Completion, prefix string) []components.Completion {
+	var matches []components.Completion
+	for _, item := range items {
+		if strings.HasPrefix(item.Value, prefix) {
+			matches = append(matches, item)
+		}
+	}
+	return matches
+}
+
+// streamLinear sends a user message and writes the reply as it streams.
+// An interrupt cancels the reply.
+func (m *Model) streamLinear(content string, out io.Writer, interrupts <-chan os.Signal) {
+	images, urls := m.takeImages()
+	for _, img := range images {
+		fmt.Fprintln(out, img.Placeholder())
+	}
+	m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "user", Content: content, Images: urls})
+	m.interrupted = false
+	m.streamLinearReply(out, interrupts, false)
+}
+
+// continueLinear resumes the reply that was cancelled
+func (m *Model) continueLinear(out io.Writer, interrupts <-chan os.Signal) {
+	if !m.interrupted {
+		fmt.Fprintln(out, i18n.T("No cancelled reply to continue"))
+		return
+	}
+	m.interrupted = false
+	m.streamLinearReply(out, interrupts, true)
+}
+
+// streamLinearReply writes the model's reply to the conversation so far.
+// When continuing, the conversation ends with the reply to extend.
+func (m *Model) streamLinearReply(out io.Writer, interrupts <-chan os.Signal, continuing bool) {
+	messages := make([]server.ChatMessage, len(m.chatMessages))
+	copy(messages, m.chatMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var fullContent strings.Builder
+	var usage tokenUsage
+	inReasoning := false
+	stream := m.filter.Stream()
+
+	writeContent := func(s string) {
+		if s == "" {
+			return
+		}
+		if inReasoning {
+			fmt.Fprint(out, "\n\n")
+			inReasoning = false
+		}
+		fullContent.WriteString(s)
+		fmt.Fprint(out, s)
+		if m.tee != nil {
+			io.WriteString(m.tee, s)
+		}
+	}
+
+	cb := server.StreamCallback{
+		ReasoningCallback: func(reasoning string) {
+			if !inReasoning && fullContent.Len() == 0 {
+				fmt.Fprintln(out, i18n.T("Thinking:"))
+			}
+			inReasoning = true
+			fmt.Fprint(out, reasoning)
+		},
+		ContentCallback: func(s string) {
+			writeContent(stream.Write(s))
+		},
+		UsageCallback: usage.add,
+	}
+
+	err := m.api.StreamChatCompletionInLanguage(ctx, m.chatRequest(messages), cb, m.maxContinues, m.respondIn)
+	writeContent(stream.Flush())
+	if m.tee != nil && fullContent.Len() > 0 {
+		io.WriteString(m.tee, "\n\n")
+	}
+	fmt.Fprintln(out)
+	if msg := m.teeWriteError(); msg != "" {
+		fmt.Fprintln(out, msg)
+	}
+	m.recordUsage(usage)
+
+	canceled := errors.Is(err, context.Canceled)
+	switch {
+	case err != nil && !canceled:
+		fmt.Fprintln(out, i18n.T("Error:"), err)
+		m.interrupted = continuing // History still ends with the cut-off reply
+	case continuing:
+		m.chatMessages[len(m.chatMessages)-1].Content += fullContent.String()
+	case fullContent.Len() > 0:
+		m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "assistant", Content: fullContent.String()})
+	}
+	if canceled {
+		fmt.Fprintln(out, i18n.T("Cancelled"))
+		// What arrived stays in the history for /continue
+		m.interrupted = continuing || fullContent.Len() > 0
+	}
+	fmt.Fprintln(out)
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+)
+
+func TestCompleteLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		want        string
+		wantMatches []string
+	}{
+		{"full command", "/show", "/show", nil},
+		{"alias", "/?", "/?", nil},
+		{"unique prefix", "/sh", "/show", nil},
+		{"unique prefix keeps args", "/sys be brief", "/system be brief", nil},
+		{"ambiguous prefix", "/s", "/s", []string{"/system", "/set", "/show", "/save"}},
+		{"bare slash lists everything", "/", "/", []string{"/help", "/clear", "/undo", "/continue", "/system", "/set", "/show", "/reload", "/tee", "/save", "/image", "/keys", "/bye"}},
+		{"unknown command", "/nope", "/nope", nil},
+		{"set option prefix", "/set tem 0.7", "/set temp 0.7", nil},
+		{"set option prefix via command prefix", "/se rep 1.1", "/set repeat-penalty 1.1", nil},
+		{"ambiguous set option", "/set t 1", "/set t 1", []string{"temp", "top-p", "top-k", "threads", "threads-batch", "tensor-split", "timeout"}},
+		{"catalog set option prefix", "/set flash on", "/set flash-attn on", nil},
+		{"exact set option", "/set top-p 0.9", "/set top-p 0.9", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, matches := completeLine(tt.line)
+			if got != tt.want {
+				t.Errorf("completeLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+			var names []string
+			for _, m := range matches {
+				names = append(names, m.Value)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantMatches) {
+				t.Errorf("completeLine(%q) matches = %v, want %v", tt.line, names, tt.wantMatches)
+			}
+		})
+	}
+}
+
+func TestRunLinear(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	i18n.SetLanguage("en")
+
+	var received []server.ChatMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req server.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = req.Messages
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Hello", " there"} {
+			data, _ := json.Marshal(server.StreamChunk{
+				Choices: []server.StreamChoice{{Delta: server.StreamDelta{Content: chunk}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer ts.Close()
+
+	m := New(server.NewAPIClientFromURL(ts.URL), "user/repo:Q4_K_M", config.DefaultConfig(), nil, "")
+	in := strings.NewReader("/set tem 0.5\nhi\n/sh\n/s\n/bye\nnever read\n")
+	var out strings.Builder
+
+	if err := m.RunLinear(in, &out); err != nil {
+		t.Fatalf("RunLinear() error = %v", err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		"Chatting with user/repo:Q4_K_M",
+		"Set temp = 0.5",
+		">>> Hello there\n",
+		"Current Settings",
+		"temp = 0.5 (session)",
+		"Did you mean:",
+		"Goodbye!",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Errorf("output contains escape sequences:\n%q", got)
+	}
+
+	if len(received) != 2 || received[1].Content != "hi" {
+		t.Errorf("request messages = %+v, want the system prompt and \"hi\"", received)
+	}
+	if n := len(m.chatMessages); n != 3 || m.chatMessages[2].Content != "Hello there" {
+		t.Errorf("history = %+v, want the reply appended", m.chatMessages)
+	}
+}
+
+func TestRunLinearContinue(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	i18n.SetLanguage("en")
+
+	var received []server.ChatMessage
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		var req server.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		received = req.Messages
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		data, _ := json.Marshal(server.StreamChunk{
+			Choices: []server.StreamChoice{{Delta: server.StreamDelta{Content: " a time"}}},
+		})
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	defer ts.Close()
+
+	m := New(server.NewAPIClientFromURL(ts.URL), "user/repo:Q4_K_M", config.DefaultConfig(), nil, "")
+	m.chatMessages = append(m.chatMessages,
+		server.ChatMessage{Role: "user", Content: "tell a story"},
+		server.ChatMessage{Role: "assistant", Content: "Once upon"},
+	)
+	m.interrupted = true
+
+	var out strings.Builder
+	if err := m.RunLinear(strings.NewReader("/continue\n/continue\n/bye\n"), &out); err != nil {
+		t.Fatalf("RunLinear() error = %v", err)
+	}
+
+	if n := len(received); n != 3 || received[2].Role != "assistant" || received[2].Content != "Once upon" {
+		t.Errorf("request messages = %+v, want the cancelled reply last", received)
+	}
+	if n := len(m.chatMessages); n != 3 || m.chatMessages[2].Content != "Once upon a time" {
+		t.Errorf("history = %+v, want the reply extended", m.chatMessages)
+	}
+	if !strings.Contains(out.String(), "No cancelled reply to continue") {
+		t.Errorf("second /continue should have nothing to continue:\n%s", out.String())
+	}
+}
@@ -0,0 +1,34 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/conversation"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// handleSave processes the /save command. The first save titles the chat,
+// with the words given or else a title the model writes; later saves update
+// the same file.
+func (m *Model) handleSave(args []string) CommandResultMsg {
+	if !slices.ContainsFunc(m.chatMessages, func(msg server.ChatMessage) bool { return msg.Role == "user" }) {
+		return CommandResultMsg{Message: i18n.T("Nothing to save yet")}
+	}
+
+	if m.saved == nil {
+		m.saved = conversation.New(m.model)
+	}
+	if title := strings.Join(args, " "); title != "" {
+		m.saved.Title = title
+	} else if m.saved.Title == "" {
+		m.saved.Title = conversation.GenerateTitle(m.api, m.model, m.chatMessages)
+	}
+	m.saved.Messages = slices.Clone(m.chatMessages)
+
+	if err := m.saved.Save(); err != nil {
+		return CommandResultMsg{Message: i18n.Tf("Failed to save conversation: %v", err), IsError: true}
+	}
+	return CommandResultMsg{Message: i18n.Tf("Saved \"%s\" (%s)", m.saved.Title, m.saved.ID)}
+}
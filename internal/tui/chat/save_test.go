@@ -0,0 +1,52 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/conversation"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/tui/components"
+)
+
+func TestHandleSave(t *testing.T) {
+	i18n.SetLanguage("en")
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	m := &Model{model: "user/repo:Q4_K_M", messages: components.NewMessages()}
+	m.chatMessages = []server.ChatMessage{{Role: "system", Content: "Be brief"}}
+	if msg := m.handleSave(nil); msg.Message != "Nothing to save yet" {
+		t.Errorf("/save with no messages = %+v", msg)
+	}
+
+	// Without a server the title comes from the first message
+	m.chatMessages = append(m.chatMessages,
+		server.ChatMessage{Role: "user", Content: "how do tides work?"},
+		server.ChatMessage{Role: "assistant", Content: "the moon"},
+	)
+	msg := m.handleSave(nil)
+	if msg.IsError || !strings.Contains(msg.Message, `"how do tides work?"`) {
+		t.Fatalf("/save = %+v", msg)
+	}
+	id := m.saved.ID
+
+	// Saving again updates the same conversation, renamed when a title is given
+	m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "user", Content: "and waves?"})
+	m.handleSave([]string{"Ocean", "questions"})
+	c, err := conversation.Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Title != "Ocean questions" || len(c.Messages) != 4 || c.Model != "user/repo:Q4_K_M" {
+		t.Errorf("saved conversation = %+v", c)
+	}
+
+	// After /clear the next save starts a new file
+	m.handleCommand("/clear")()
+	m.chatMessages = append(m.chatMessages, server.ChatMessage{Role: "user", Content: "new topic"})
+	m.handleSave(nil)
+	if list, _ := conversation.List(); len(list) != 2 {
+		t.Errorf("%d conversations saved, want 2", len(list))
+	}
+}
@@ -0,0 +1,130 @@
+package chat
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// smoothFrame is how often paced text is released, about 60 fps
+	smoothFrame = 16 * time.Millisecond
+	// smoothFrames is how many frames a backlog is spread over, so text
+	// never trails what has arrived by much more than 200ms
+	smoothFrames = 12
+)
+
+// smoother paces bursty chunks of a streamed reply for display. Text is
+// buffered as it arrives and released a little every frame, in amounts
+// that grow with the backlog, so a burst of tokens types out evenly instead
+// of landing at once. Only the display is paced; the stream itself is read
+// as fast as the server sends it.
+type smoother struct {
+	emit func(string)
+
+	mu      sync.Mutex
+	pending string
+	closing bool
+	stopped bool
+
+	wake chan struct{}
+	done chan struct{}
+}
+
+// newSmoother starts pacing text to emit, which is called from a single
+// goroutine.
+func newSmoother(emit func(string)) *smoother {
+	s := &smoother{
+		emit: emit,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write queues text for display.
+func (s *smoother) Write(text string) {
+	if text == "" {
+		return
+	}
+	s.mu.Lock()
+	s.pending += text
+	s.mu.Unlock()
+	s.signal()
+}
+
+// Close releases what's still queued, at the same pace, and returns once
+// it has all been emitted.
+func (s *smoother) Close() {
+	s.mu.Lock()
+	s.closing = true
+	s.mu.Unlock()
+	s.signal()
+	<-s.done
+}
+
+// Stop discards what's queued, for a cancelled reply.
+func (s *smoother) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.pending = ""
+	s.mu.Unlock()
+	s.signal()
+	<-s.done
+}
+
+func (s *smoother) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *smoother) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(smoothFrame)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		idle := s.pending == ""
+		finished := s.stopped || (s.closing && idle)
+		s.mu.Unlock()
+		if finished {
+			return
+		}
+		if idle {
+			<-s.wake
+			continue
+		}
+
+		<-ticker.C
+		if chunk := s.next(); chunk != "" {
+			s.emit(chunk)
+		}
+	}
+}
+
+// next takes this frame's share of the backlog, splitting on a rune
+// boundary
+func (s *smoother) next() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := utf8.RuneCountInString(s.pending)
+	if n == 0 {
+		return ""
+	}
+	take := max(1, (n+smoothFrames-1)/smoothFrames)
+	end := len(s.pending)
+	for i := range s.pending {
+		if take == 0 {
+			end = i
+			break
+		}
+		take--
+	}
+	chunk := s.pending[:end]
+	s.pending = s.pending[end:]
+	return chunk
+}
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+type emitted struct {
+	mu     sync.Mutex
+	chunks []string
+}
+
+func (e *emitted) emit(s string) {
+	e.mu.Lock()
+	e.chunks = append(e.chunks, s)
+	e.mu.Unlock()
+}
+
+func TestSmootherPacesBursts(t *testing.T) {
+	var out emitted
+	s := newSmoother(out.emit)
+
+	burst := strings.Repeat("word ", 40) // 200 characters at once
+	s.Write(burst)
+	s.Close()
+
+	if got := strings.Join(out.chunks, ""); got != burst {
+		t.Fatalf("emitted %q, want %q", got, burst)
+	}
+	if len(out.chunks) < smoothFrames {
+		t.Errorf("burst released in %d frames, want at least %d", len(out.chunks), smoothFrames)
+	}
+	if first := utf8.RuneCountInString(out.chunks[0]); first > len(burst)/smoothFrames+1 {
+		t.Errorf("first frame released %d characters of %d", first, len(burst))
+	}
+}
+
+func TestSmootherKeepsRunesWhole(t *testing.T) {
+	var out emitted
+	s := newSmoother(out.emit)
+	text := strings.Repeat("héllo wörld 👋 ", 5)
+	for _, r := range text {
+		s.Write(string(r))
+	}
+	s.Close()
+
+	for _, chunk := range out.chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %q splits a rune", chunk)
+		}
+	}
+	if got := strings.Join(out.chunks, ""); got != text {
+		t.Errorf("emitted %q, want %q", got, text)
+	}
+}
+
+func TestSmootherStopDiscards(t *testing.T) {
+	var out emitted
+	s := newSmoother(out.emit)
+	s.Write(strings.Repeat("x", 10000))
+	s.Stop()
+
+	out.mu.Lock()
+	defer out.mu.Unlock()
+	if n := len(strings.Join(out.chunks, "")); n >= 10000 {
+		t.Errorf("Stop emitted all %d characters", n)
+	}
+}
+
+func TestSmootherCloseWhenIdle(t *testing.T) {
+	s := newSmoother(func(string) { t.Error("emitted without input") })
+	s.Close()
+}
@@ -0,0 +1,142 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/tui/components"
+)
+
+// StartTee appends every assistant response to path as it streams,
+// replacing any file already being written.
+func (m *Model) StartTee(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	f, err := os.OpenFile(abs, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	m.StopTee()
+	m.tee = &teeFile{f: f}
+	return nil
+}
+
+// StopTee stops writing responses to a file.
+func (m *Model) StopTee() error {
+	if m.tee == nil {
+		return nil
+	}
+	err := m.tee.Close()
+	m.tee = nil
+	return err
+}
+
+// teeWriteError stops the tee after a failed write, returning the message
+// to show, or "" when every write went through
+func (m *Model) teeWriteError() string {
+	if m.tee == nil {
+		return ""
+	}
+	err := m.tee.writeErr()
+	if err == nil {
+		return ""
+	}
+	name := m.tee.Name()
+	m.StopTee()
+	return i18n.Tf("Failed to write to %s, stopped writing responses to it: %v", name, err)
+}
+
+// showTeeWriteError adds an error message when a write to the tee failed
+func (m *Model) showTeeWriteError() {
+	if msg := m.teeWriteError(); msg != "" {
+		m.messages.AddMessage(components.Message{Role: components.RoleError, Content: msg})
+	}
+}
+
+// teeFile is the file responses are copied to. Streams write to it from
+// their own goroutine while /tee can close it, so writes and Close are
+// locked, and writes after Close are dropped.
+type teeFile struct {
+	mu  sync.Mutex
+	f   *os.File
+	err error // First failed write; later writes are skipped
+}
+
+func (t *teeFile) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.f == nil || t.err != nil {
+		return len(p), nil
+	}
+	if _, err := t.f.Write(p); err != nil {
+		t.err = err
+	}
+	return len(p), nil
+}
+
+// Name returns the file's path
+func (t *teeFile) Name() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.f == nil {
+		return ""
+	}
+	return t.f.Name()
+}
+
+// Close closes the file; writes after it are dropped
+func (t *teeFile) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.f == nil {
+		return nil
+	}
+	err := t.f.Close()
+	t.f = nil
+	return err
+}
+
+// writeErr returns the first write that failed
+func (t *teeFile) writeErr() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Close releases resources held by the chat session.
+func (m *Model) Close() error {
+	return m.StopTee()
+}
+
+// handleTee processes the /tee command
+func (m *Model) handleTee(args []string) CommandResultMsg {
+	if len(args) == 0 {
+		if m.tee == nil {
+			return CommandResultMsg{Message: i18n.T("Not writing responses to a file\nUsage: /tee <file> or /tee off")}
+		}
+		return CommandResultMsg{Message: i18n.Tf("Appending responses to %s", m.tee.Name())}
+	}
+
+	if args[0] == "off" {
+		if m.tee == nil {
+			return CommandResultMsg{Message: i18n.T("Not writing responses to a file")}
+		}
+		name := m.tee.Name()
+		if err := m.StopTee(); err != nil {
+			return CommandResultMsg{Message: i18n.Tf("Failed to close %s: %v", name, err), IsError: true}
+		}
+		return CommandResultMsg{Message: i18n.Tf("Stopped writing to %s", name)}
+	}
+
+	if err := m.StartTee(args[0]); err != nil {
+		return CommandResultMsg{Message: err.Error(), IsError: true}
+	}
+	return CommandResultMsg{Message: i18n.Tf("Appending responses to %s", m.tee.Name())}
+}
@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHandleTee(t *testing.T) {
+	m := &Model{}
+	path := filepath.Join(t.TempDir(), "out.md")
+
+	if msg := m.handleTee([]string{path}); msg.IsError {
+		t.Fatalf("/tee %s failed: %s", path, msg.Message)
+	}
+	if m.tee == nil || m.tee.Name() != path {
+		t.Fatalf("tee = %v, want %s", m.tee, path)
+	}
+	io.WriteString(m.tee, "first")
+
+	// Starting again appends to the existing file
+	if msg := m.handleTee([]string{path}); msg.IsError {
+		t.Fatalf("/tee %s failed: %s", path, msg.Message)
+	}
+	io.WriteString(m.tee, " second")
+
+	if msg := m.handleTee([]string{"off"}); msg.IsError {
+		t.Fatalf("/tee off failed: %s", msg.Message)
+	}
+	if m.tee != nil {
+		t.Error("tee should be nil after /tee off")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first second" {
+		t.Errorf("file = %q, want %q", data, "first second")
+	}
+}
+
+func TestHandleTeeBadPath(t *testing.T) {
+	m := &Model{}
+	path := filepath.Join(t.TempDir(), "missing", "out.md")
+
+	if msg := m.handleTee([]string{path}); !msg.IsError {
+		t.Errorf("/tee into a missing directory should fail, got %q", msg.Message)
+	}
+	if m.tee != nil {
+		t.Error("tee should stay nil after a failed /tee")
+	}
+}
+
+func TestTeeStopWhileWriting(t *testing.T) {
+	m := &Model{}
+	path := filepath.Join(t.TempDir(), "out.md")
+	if err := m.StartTee(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stream keeps writing from its goroutine while /tee off closes the file
+	tee := m.tee
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 1000 {
+			io.WriteString(tee, "x")
+		}
+	}()
+	if err := m.StopTee(); err != nil {
+		t.Errorf("StopTee: %v", err)
+	}
+	wg.Wait()
+
+	if msg := m.teeWriteError(); msg != "" {
+		t.Errorf("writes after /tee off should be dropped quietly, got %q", msg)
+	}
+}
+
+func TestTeeWriteError(t *testing.T) {
+	m := &Model{}
+	path := filepath.Join(t.TempDir(), "out.md")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	// Writing to a closed file fails like a full disk would
+	m.tee = &teeFile{f: f}
+
+	io.WriteString(m.tee, "first")
+	io.WriteString(m.tee, "second")
+
+	msg := m.teeWriteError()
+	if !strings.Contains(msg, path) || !strings.Contains(msg, os.ErrClosed.Error()) {
+		t.Errorf("message = %q, want the path and the first write's error", msg)
+	}
+	if m.tee != nil {
+		t.Error("tee should stop after a failed write")
+	}
+	if msg := m.teeWriteError(); msg != "" {
+		t.Errorf("error should be shown once, got %q again", msg)
+	}
+}
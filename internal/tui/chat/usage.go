@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+)
+
+// tokenUsage counts the tokens of one exchange, or of a whole session
+type tokenUsage struct {
+	Prompt     int
+	Completion int
+}
+
+// add counts the usage the server reported for one request
+func (u *tokenUsage) add(su *server.Usage) {
+	if su == nil {
+		return
+	}
+	u.Prompt += su.PromptTokens
+	u.Completion += su.CompletionTokens
+}
+
+// Total returns the prompt and completion tokens together
+func (u tokenUsage) Total() int {
+	return u.Prompt + u.Completion
+}
+
+// recordUsage adds an exchange's tokens to the session and shows them under
+// its reply
+func (m *Model) recordUsage(u tokenUsage) {
+	if u.Total() == 0 {
+		return
+	}
+	m.usage.Prompt += u.Prompt
+	m.usage.Completion += u.Completion
+	m.messages.SetLastMeta(m.usageText(u))
+	m.updateUsageStatus()
+}
+
+// updateUsageStatus shows the session totals in the status bar
+func (m *Model) updateUsageStatus() {
+	if m.usage.Total() == 0 {
+		m.status.SetUsage("")
+		return
+	}
+	text := i18n.Tf("%d tokens", m.usage.Total())
+	if m.costRate != nil {
+		text += " · " + formatCost(m.costRate.Cost(m.usage.Prompt, m.usage.Completion))
+	}
+	m.status.SetUsage(text)
+}
+
+// usageText describes an exchange's tokens, with its equivalent API cost
+// when a rate is set
+func (m *Model) usageText(u tokenUsage) string {
+	text := i18n.Tf("%d prompt + %d completion tokens", u.Prompt, u.Completion)
+	if m.costRate != nil {
+		text += " · " + formatCost(m.costRate.Cost(u.Prompt, u.Completion))
+	}
+	return text
+}
+
+// usageSummary is the session section of /show
+func (m *Model) usageSummary() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("  Session:\n"))
+	sb.WriteString(i18n.Tf("    tokens = %d prompt + %d completion (%d total)\n", m.usage.Prompt, m.usage.Completion, m.usage.Total()))
+	if m.costRate != nil {
+		sb.WriteString(i18n.Tf("    API cost = %s (at $%g / $%g per million tokens)\n",
+			formatCost(m.costRate.Cost(m.usage.Prompt, m.usage.Completion)), m.costRate.Input, m.costRate.Output))
+	}
+	return sb.String()
+}
+
+// formatCost formats dollars, keeping fractions of a cent visible
+func formatCost(dollars float64) string {
+	if dollars > 0 && dollars < 0.01 {
+		return fmt.Sprintf("≈$%.4f", dollars)
+	}
+	return fmt.Sprintf("≈$%.2f", dollars)
+}
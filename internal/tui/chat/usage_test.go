@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/config"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/server"
+	"github.com/nchapman/lleme/internal/tui/components"
+)
+
+func TestRecordUsage(t *testing.T) {
+	i18n.SetLanguage("en")
+
+	m := &Model{messages: components.NewMessages()}
+	m.messages.AddMessage(components.Message{Role: components.RoleAssistant, Content: "one"})
+
+	var u tokenUsage
+	u.add(&server.Usage{PromptTokens: 100, CompletionTokens: 20})
+	u.add(&server.Usage{PromptTokens: 130, CompletionTokens: 10}) // A continuation
+	u.add(nil)
+	m.recordUsage(u)
+	m.recordUsage(tokenUsage{Prompt: 50, Completion: 5})
+
+	if m.usage != (tokenUsage{Prompt: 280, Completion: 35}) {
+		t.Errorf("session usage = %+v", m.usage)
+	}
+	list := m.messages.MessagesList()
+	if got := list[len(list)-1].Meta; got != "50 prompt + 5 completion tokens" {
+		t.Errorf("reply meta = %q", got)
+	}
+	if show := m.usageSummary(); !strings.Contains(show, "280 prompt + 35 completion (315 total)") || strings.Contains(show, "cost") {
+		t.Errorf("/show usage without a rate = %q", show)
+	}
+
+	m.costRate = &config.CostRate{Input: 3, Output: 15}
+	m.messages.AddMessage(components.Message{Role: components.RoleAssistant, Content: "two"})
+	m.recordUsage(tokenUsage{Prompt: 1000, Completion: 1000})
+	list = m.messages.MessagesList()
+	if got := list[0].Meta; got != "50 prompt + 5 completion tokens" {
+		t.Errorf("meta of an earlier reply changed to %q", got)
+	}
+	if got := list[1].Meta; got != "1000 prompt + 1000 completion tokens · ≈$0.02" {
+		t.Errorf("reply meta with a rate = %q", got)
+	}
+	if show := m.usageSummary(); !strings.Contains(show, "API cost = ≈$0.02") {
+		t.Errorf("/show usage with a rate = %q", show)
+	}
+}
+
+func TestFormatCost(t *testing.T) {
+	tests := []struct {
+		dollars float64
+		want    string
+	}{
+		{0, "≈$0.00"},
+		{0.00042, "≈$0.0004"},
+		{0.0123, "≈$0.01"},
+		{12.5, "≈$12.50"},
+	}
+	for _, tt := range tests {
+		if got := formatCost(tt.dollars); got != tt.want {
+			t.Errorf("formatCost(%v) = %q, want %q", tt.dollars, got, tt.want)
+		}
+	}
+}
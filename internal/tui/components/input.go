@@ -3,9 +3,12 @@ package components
 import (
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/tui/history"
 	"github.com/nchapman/lleme/internal/tui/styles"
 )
 
@@ -29,6 +32,24 @@ type Input struct {
 	completions    *Completions
 	cmdItems       []Completion // Available command completions
 	setOptionItems []Completion // Available /set option completions
+	keys           InputKeys
+
+	// Input history (up/down to recall, ctrl+r to search)
+	history     *history.History
+	browser     *history.Browser
+	searching   bool
+	searchQuery string
+	searchMatch int    // Index of the entry shown, or -1
+	searchDraft string // Input to restore if the search is cancelled
+}
+
+// InputKeys are the input's configurable key bindings
+type InputKeys struct {
+	Send          key.Binding
+	Newline       key.Binding
+	HistoryPrev   key.Binding
+	HistoryNext   key.Binding
+	HistorySearch key.Binding
 }
 
 // NewInput creates a new input component
@@ -39,13 +60,15 @@ func NewInput() Input {
 // NewInputWithCompletions creates a new input component with command completions
 func NewInputWithCompletions(cmdItems, setOptionItems []Completion) Input {
 	ta := textarea.New()
-	ta.Placeholder = "Type a message..."
+	ta.Placeholder = i18n.T("Type a message...")
 	ta.ShowLineNumbers = false
 	ta.CharLimit = 0 // No limit
 	ta.SetHeight(1)
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	// No prompt - the border indicates input area
 	ta.Prompt = ""
+	// Newlines come from the configured newline keys instead
+	ta.KeyMap.InsertNewline.SetEnabled(false)
 	ta.Focus()
 
 	return Input{
@@ -56,9 +79,41 @@ func NewInputWithCompletions(cmdItems, setOptionItems []Completion) Input {
 		completions:    NewCompletions(),
 		cmdItems:       cmdItems,
 		setOptionItems: setOptionItems,
+		keys: InputKeys{
+			Send:          key.NewBinding(key.WithKeys("enter")),
+			Newline:       key.NewBinding(key.WithKeys("shift+enter", "ctrl+j")),
+			HistoryPrev:   key.NewBinding(key.WithKeys("up", "ctrl+p")),
+			HistoryNext:   key.NewBinding(key.WithKeys("down", "ctrl+n")),
+			HistorySearch: key.NewBinding(key.WithKeys("ctrl+r")),
+		},
+		browser: (*history.History)(nil).Browse(),
 	}
 }
 
+// SetKeys sets the keys that send, insert a new line and recall history
+func (i *Input) SetKeys(keys InputKeys) {
+	i.keys = keys
+}
+
+// SetHistory sets the history recalled with up, down and ctrl+r
+func (i *Input) SetHistory(h *history.History) {
+	i.history = h
+	i.browser = h.Browse()
+}
+
+// Remember adds a sent line to the history and returns to a new line.
+// History is best effort: a line that can't be saved is still recalled
+// for the rest of the session.
+func (i *Input) Remember(line string) {
+	i.history.Add(line)
+	i.browser.Reset()
+}
+
+// IsSearching returns whether a history search (ctrl+r) is in progress
+func (i Input) IsSearching() bool {
+	return i.searching
+}
+
 // Init returns the initial command
 func (i Input) Init() tea.Cmd {
 	return textarea.Blink
@@ -68,6 +123,12 @@ func (i Input) Init() tea.Cmd {
 func (i Input) Update(msg tea.Msg) (Input, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if i.searching {
+			if done := i.updateSearch(msg); !done {
+				return i, i.checkHeightChange()
+			}
+		}
+
 		// Handle completions navigation when open
 		if i.completions != nil && i.completions.IsOpen() {
 			switch msg.String() {
@@ -104,14 +165,30 @@ func (i Input) Update(msg tea.Msg) (Input, tea.Cmd) {
 			}
 		}
 
-		switch msg.String() {
-		case "shift+enter", "ctrl+j":
-			// Insert newline
+		switch {
+		case key.Matches(msg, i.keys.Newline):
 			i.textarea.InsertRune('\n')
 			return i, i.checkHeightChange()
-		case "enter":
-			// Plain enter - let parent handle send
+		case key.Matches(msg, i.keys.Send):
+			// Let parent handle send
+			return i, nil
+		case key.Matches(msg, i.keys.HistorySearch) && i.history.Len() > 0:
+			i.searching = true
+			i.searchQuery = ""
+			i.searchMatch = -1
+			i.searchDraft = i.textarea.Value()
 			return i, nil
+		case key.Matches(msg, i.keys.HistoryPrev) && i.textarea.Line() == 0:
+			// Up on the first line recalls history; elsewhere it moves the cursor
+			if entry, ok := i.browser.Prev(i.textarea.Value()); ok {
+				i.setRecalled(entry)
+				return i, i.checkHeightChange()
+			}
+		case key.Matches(msg, i.keys.HistoryNext) && i.textarea.Line() == i.textarea.LineCount()-1 && i.browser.Browsing():
+			if entry, ok := i.browser.Next(); ok {
+				i.setRecalled(entry)
+				return i, i.checkHeightChange()
+			}
 		}
 	}
 
@@ -128,6 +205,75 @@ func (i Input) Update(msg tea.Msg) (Input, tea.Cmd) {
 	return i, cmd
 }
 
+// setRecalled shows a history entry with the cursor at its end
+func (i *Input) setRecalled(entry string) {
+	i.textarea.SetValue(entry)
+	i.textarea.CursorEnd()
+	i.updateCompletions()
+	if i.completions != nil {
+		// Recalling a command shouldn't pop up completions over it
+		i.completions.Close()
+	}
+}
+
+// updateSearch handles a key during a history search, like a shell's
+// reverse-i-search. It returns true when the search is over and the key
+// should be handled as usual.
+func (i *Input) updateSearch(msg tea.KeyMsg) bool {
+	switch {
+	case key.Matches(msg, i.keys.HistorySearch):
+		// Again for the next older match
+		if i.searchQuery != "" {
+			if match := i.history.Search(i.searchQuery, i.searchMatch); match >= 0 {
+				i.showMatch(match)
+			}
+		}
+		return false
+	case msg.Type == tea.KeyEsc || msg.Type == tea.KeyCtrlG:
+		i.searching = false
+		i.textarea.SetValue(i.searchDraft)
+		return false
+	case msg.Type == tea.KeyBackspace:
+		if r := []rune(i.searchQuery); len(r) > 0 {
+			i.searchQuery = string(r[:len(r)-1])
+			i.searchMatch = -1
+			i.research()
+		}
+		return false
+	case msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace:
+		i.searchQuery += string(msg.Runes)
+		i.research()
+		return false
+	case key.Matches(msg, i.keys.Send):
+		// Accept the match for editing rather than sending it
+		i.searching = false
+		return false
+	}
+
+	// Any other key accepts the match and acts as usual
+	i.searching = false
+	return true
+}
+
+// research finds the newest entry matching the query, keeping the current
+// match if it still matches
+func (i *Input) research() {
+	from := i.history.Len()
+	if i.searchMatch >= 0 {
+		from = i.searchMatch + 1
+	}
+	if match := i.history.Search(i.searchQuery, from); match >= 0 {
+		i.showMatch(match)
+	}
+}
+
+// showMatch shows history entry n as the input
+func (i *Input) showMatch(n int) {
+	i.searchMatch = n
+	i.textarea.SetValue(i.history.Entry(n))
+	i.textarea.CursorEnd()
+}
+
 // checkHeightChange adjusts height based on line count and returns a command if changed
 func (i *Input) checkHeightChange() tea.Cmd {
 	lines := i.textarea.LineCount()
@@ -150,7 +296,12 @@ func (i Input) View() string {
 		style = styles.InputFocusedStyle
 	}
 	divider := styles.HorizontalDivider(i.width)
-	return lipgloss.JoinVertical(lipgloss.Left, "", divider, style.Render(i.textarea.View()))
+	top := ""
+	if i.searching {
+		// Shown in the blank line above the divider so the layout doesn't move
+		top = styles.StatusDescStyle.Render("  " + i18n.Tf("history search: %s", i.searchQuery) + "▏")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, top, divider, style.Render(i.textarea.View()))
 }
 
 // SetWidth sets the input width
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nchapman/lleme/internal/tui/history"
 )
 
 func TestInput_Basic(t *testing.T) {
@@ -345,3 +346,79 @@ func TestInput_CompletionTabSelectsItem(t *testing.T) {
 		t.Error("expected cmd to be returned")
 	}
 }
+
+func TestInput_History(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	h, _ := history.Load("test", 0)
+	h.Add("first")
+	h.Add("second")
+
+	input := NewInput()
+	input.SetHistory(h)
+	input.SetValue("draft")
+
+	up := tea.KeyMsg{Type: tea.KeyUp}
+	down := tea.KeyMsg{Type: tea.KeyDown}
+
+	input, _ = input.Update(up)
+	if input.Value() != "second" {
+		t.Errorf("after up: got %q, want second", input.Value())
+	}
+	input, _ = input.Update(up)
+	input, _ = input.Update(up)
+	if input.Value() != "first" {
+		t.Errorf("after up at the oldest entry: got %q, want first", input.Value())
+	}
+	input, _ = input.Update(down)
+	input, _ = input.Update(down)
+	if input.Value() != "draft" {
+		t.Errorf("after down past the newest: got %q, want the draft back", input.Value())
+	}
+
+	input.Remember("third")
+	input.Reset()
+	input, _ = input.Update(up)
+	if input.Value() != "third" {
+		t.Errorf("after Remember and up: got %q, want third", input.Value())
+	}
+}
+
+func TestInput_HistorySearch(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	h, _ := history.Load("test", 0)
+	for _, line := range []string{"tell me a joke", "/show", "another joke"} {
+		h.Add(line)
+	}
+
+	input := NewInput()
+	input.SetHistory(h)
+	input.SetValue("draft")
+
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if !input.IsSearching() {
+		t.Fatal("expected ctrl+r to start a search")
+	}
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("joke")})
+	if input.Value() != "another joke" {
+		t.Errorf("search: got %q, want the newest match", input.Value())
+	}
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if input.Value() != "tell me a joke" {
+		t.Errorf("search again: got %q, want the older match", input.Value())
+	}
+
+	// Enter accepts the match for editing without sending it
+	input, cmd := input.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if input.IsSearching() || cmd != nil || input.Value() != "tell me a joke" {
+		t.Errorf("enter: searching = %v, value = %q", input.IsSearching(), input.Value())
+	}
+
+	// Esc cancels and restores what was typed
+	input.SetValue("draft")
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("show")})
+	input, _ = input.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if input.IsSearching() || input.Value() != "draft" {
+		t.Errorf("esc: searching = %v, value = %q, want draft", input.IsSearching(), input.Value())
+	}
+}
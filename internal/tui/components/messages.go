@@ -9,6 +9,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/nchapman/lleme/internal/tui/styles"
+	"github.com/nchapman/lleme/internal/tui/termimg"
 )
 
 // MessageRole represents who sent the message
@@ -25,10 +26,15 @@ const (
 type Message struct {
 	Role     MessageRole
 	Content  string
-	Thinking string // Reasoning/thinking content (shown muted)
-	rendered string // Cached rendered content
+	Thinking string          // Reasoning/thinking content (shown muted)
+	Images   []termimg.Image // Attached images, shown below the content
+	Meta     string          // Details such as token counts, shown muted below a reply
+	rendered string          // Cached rendered content
 }
 
+// maxImageCols caps how wide an attached image is drawn
+const maxImageCols = 60
+
 // Messages manages the scrollable message viewport
 type Messages struct {
 	viewport viewport.Model
@@ -42,6 +48,11 @@ type Messages struct {
 	streamingThinking string
 	spinner           spinner.Model
 	showSpinner       bool // true until first content arrives
+
+	top    key.Binding
+	bottom key.Binding
+
+	imageProtocol termimg.Protocol
 }
 
 // NewMessages creates a new messages viewport
@@ -54,9 +65,12 @@ func NewMessages() Messages {
 	s.Style = lipgloss.NewStyle().Foreground(styles.ColorAccent)
 
 	return Messages{
-		viewport: vp,
-		messages: []Message{},
-		spinner:  s,
+		viewport:      vp,
+		messages:      []Message{},
+		spinner:       s,
+		top:           key.NewBinding(key.WithKeys("home", "g")),
+		imageProtocol: termimg.None,
+		bottom:        key.NewBinding(key.WithKeys("end", "G")),
 	}
 }
 
@@ -91,13 +105,10 @@ func (m Messages) Update(msg tea.Msg) (Messages, tea.Cmd) {
 		case key.Matches(msg, m.viewport.KeyMap.HalfPageDown):
 			m.viewport.HalfPageDown()
 			return m, nil
-		}
-		// Handle home/end keys for go to top/bottom
-		switch msg.String() {
-		case "home", "g":
+		case key.Matches(msg, m.top):
 			m.viewport.GotoTop()
 			return m, nil
-		case "end", "G":
+		case key.Matches(msg, m.bottom):
 			m.viewport.GotoBottom()
 			return m, nil
 		}
@@ -123,6 +134,22 @@ func (m Messages) View() string {
 	return m.viewport.View()
 }
 
+// SetKeyMap sets the scrolling keys
+func (m *Messages) SetKeyMap(km viewport.KeyMap, top, bottom key.Binding) {
+	m.viewport.KeyMap = km
+	m.top = top
+	m.bottom = bottom
+}
+
+// SetImageProtocol sets how attached images are drawn
+func (m *Messages) SetImageProtocol(p termimg.Protocol) {
+	m.imageProtocol = p
+	for i := range m.messages {
+		m.messages[i].rendered = ""
+	}
+	m.refresh()
+}
+
 // SetSize sets the viewport dimensions
 func (m *Messages) SetSize(width, height int) {
 	// Clear render cache when width changes
@@ -156,6 +183,21 @@ func (m *Messages) ClearMessages() {
 	m.refresh()
 }
 
+// RemoveLastExchange removes the last user message and everything after it,
+// returning the removed user message
+func (m *Messages) RemoveLastExchange() (Message, bool) {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == RoleUser {
+			removed := m.messages[i]
+			m.messages = m.messages[:i]
+			m.refresh()
+			m.viewport.GotoBottom()
+			return removed, true
+		}
+	}
+	return Message{}, false
+}
+
 // StartStreaming begins a streaming response and returns a command to start the spinner
 func (m *Messages) StartStreaming() tea.Cmd {
 	m.streaming = true
@@ -199,6 +241,17 @@ func (m *Messages) FinishStreaming() {
 	}
 }
 
+// SetLastMeta sets the details shown below the last message when it's a reply
+func (m *Messages) SetLastMeta(meta string) {
+	n := len(m.messages)
+	if n == 0 || m.messages[n-1].Role != RoleAssistant {
+		return
+	}
+	m.messages[n-1].Meta = meta
+	m.messages[n-1].rendered = ""
+	m.refresh()
+}
+
 // CancelStreaming cancels the current streaming without adding message
 func (m *Messages) CancelStreaming() {
 	m.streaming = false
@@ -208,6 +261,37 @@ func (m *Messages) CancelStreaming() {
 	m.refresh()
 }
 
+// InterruptStreaming stops the current streaming, keeping what arrived as
+// the reply so it can be continued. It returns the reply's content, empty
+// when nothing arrived and no message was added.
+func (m *Messages) InterruptStreaming() string {
+	content := m.streamingContent
+	if content == "" {
+		m.CancelStreaming()
+		return ""
+	}
+	m.FinishStreaming()
+	return content
+}
+
+// ResumeStreaming reopens the last reply for streaming, so what arrives is
+// appended to it
+func (m *Messages) ResumeStreaming() tea.Cmd {
+	n := len(m.messages)
+	if n == 0 || m.messages[n-1].Role != RoleAssistant {
+		return m.StartStreaming()
+	}
+	last := m.messages[n-1]
+	m.messages = m.messages[:n-1]
+	m.streaming = true
+	m.streamingContent = last.Content
+	m.streamingThinking = last.Thinking
+	m.showSpinner = false
+	m.refresh()
+	m.viewport.GotoBottom()
+	return nil
+}
+
 // IsStreaming returns whether currently streaming
 func (m Messages) IsStreaming() bool {
 	return m.streaming
@@ -261,6 +345,13 @@ func (m Messages) renderMessage(msg Message, width int) string {
 			}
 			sb.WriteString(prefix + line)
 		}
+		for _, img := range msg.Images {
+			sb.WriteString("\n")
+			rendered := termimg.Render(img, m.imageProtocol, min(width-2, maxImageCols))
+			for line := range strings.SplitSeq(rendered, "\n") {
+				sb.WriteString("\n" + prefix + line)
+			}
+		}
 
 	case RoleAssistant:
 		// Render thinking first if present
@@ -279,6 +370,9 @@ func (m Messages) renderMessage(msg Message, width int) string {
 			rendered = msg.Content
 		}
 		sb.WriteString(strings.TrimSpace(rendered))
+		if msg.Meta != "" {
+			sb.WriteString("\n" + styles.MessageMetaStyle.Render(msg.Meta))
+		}
 
 	case RoleSystem:
 		content := styles.SystemMessageStyle.Width(width).Render(msg.Content)
@@ -1,6 +1,11 @@
 package components
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/tui/termimg"
+)
 
 func TestMessages_AddMessage(t *testing.T) {
 	m := NewMessages()
@@ -136,6 +141,40 @@ func TestMessages_CancelStreaming(t *testing.T) {
 	}
 }
 
+func TestMessages_InterruptAndResumeStreaming(t *testing.T) {
+	m := NewMessages()
+	m.SetSize(80, 24)
+
+	m.StartStreaming()
+	if got := m.InterruptStreaming(); got != "" {
+		t.Errorf("InterruptStreaming() with nothing streamed = %q, want empty", got)
+	}
+	if len(m.MessagesList()) != 0 {
+		t.Fatalf("expected 0 messages, got %d", len(m.MessagesList()))
+	}
+
+	m.StartStreaming()
+	m.AppendStreamContent("Partial")
+	if got := m.InterruptStreaming(); got != "Partial" {
+		t.Errorf("InterruptStreaming() = %q, want %q", got, "Partial")
+	}
+	if m.IsStreaming() || len(m.MessagesList()) != 1 {
+		t.Fatalf("expected the partial reply kept, got %d messages", len(m.MessagesList()))
+	}
+
+	m.ResumeStreaming()
+	m.AppendStreamContent(" content")
+	m.FinishStreaming()
+
+	msgs := m.MessagesList()
+	if len(msgs) != 1 {
+		t.Fatalf("expected 1 message after resuming, got %d", len(msgs))
+	}
+	if msgs[0].Content != "Partial content" {
+		t.Errorf("content = %q, want %q", msgs[0].Content, "Partial content")
+	}
+}
+
 func TestMessages_FinishStreamingIdempotent(t *testing.T) {
 	m := NewMessages()
 	m.SetSize(80, 24)
@@ -207,3 +246,15 @@ func TestMessages_StreamingEmptyContent(t *testing.T) {
 		t.Errorf("expected empty content, got '%s'", msg.Content)
 	}
 }
+
+func TestMessages_Images(t *testing.T) {
+	m := NewMessages()
+	m.SetSize(80, 24)
+
+	img := termimg.Image{Name: "cat.png", Format: "png", Width: 640, Height: 480}
+	m.AddMessage(Message{Role: RoleUser, Content: "What's this?", Images: []termimg.Image{img}})
+
+	if view := m.View(); !strings.Contains(view, "[image: cat.png, 640×480]") {
+		t.Errorf("view missing image placeholder:\n%s", view)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/nchapman/lleme/internal/i18n"
 	"github.com/nchapman/lleme/internal/tui/styles"
 )
 
@@ -23,15 +24,31 @@ type StatusBar struct {
 	message       string
 	width         int
 	scrollPercent float64
+	keys          StatusKeys
+	usage         string
+}
+
+// StatusKeys are the keys named in the status bar's hints
+type StatusKeys struct {
+	Send   string
+	Focus  string
+	Cancel string
+	Quit   string
 }
 
 // NewStatusBar creates a new status bar
 func NewStatusBar() StatusBar {
 	return StatusBar{
 		state: StatusReady,
+		keys:  StatusKeys{Send: "enter", Focus: "tab", Cancel: "esc", Quit: "ctrl+c"},
 	}
 }
 
+// SetKeys sets the keys shown in hints to match the configured bindings
+func (s *StatusBar) SetKeys(keys StatusKeys) {
+	s.keys = keys
+}
+
 // SetState sets the status bar state
 func (s *StatusBar) SetState(state StatusState) {
 	s.state = state
@@ -43,6 +60,11 @@ func (s *StatusBar) SetMessage(msg string) {
 	s.message = msg
 }
 
+// SetUsage sets the session's token usage shown when ready, or "" for none
+func (s *StatusBar) SetUsage(usage string) {
+	s.usage = usage
+}
+
 // SetWidth sets the status bar width
 func (s *StatusBar) SetWidth(width int) {
 	s.width = width
@@ -82,46 +104,49 @@ func (s StatusBar) View() string {
 }
 
 func (s StatusBar) readyView() string {
-	result := s.keyHint("enter", "send") +
+	result := s.keyHint(s.keys.Send, i18n.T("send")) +
 		styles.StatusDivider.String() +
-		s.keyHint("tab", "scroll") +
+		s.keyHint(s.keys.Focus, i18n.T("scroll")) +
 		styles.StatusDivider.String() +
-		s.keyHint("/?", "help") +
+		s.keyHint("/?", i18n.T("help")) +
 		styles.StatusDivider.String() +
-		s.keyHint("ctrl+c", "quit")
+		s.keyHint(s.keys.Quit, i18n.T("quit"))
 
 	// Add scroll position indicator
 	if s.scrollPercent > 0 && s.scrollPercent < 1 {
 		result += styles.StatusDivider.String() +
 			styles.StatusDescStyle.Render(fmt.Sprintf("%.0f%%", s.scrollPercent*100))
 	}
+	if s.usage != "" {
+		result += styles.StatusDivider.String() + styles.StatusDescStyle.Render(s.usage)
+	}
 	return result
 }
 
 func (s StatusBar) streamingView() string {
-	return styles.StatusStreamingStyle.Render("● Streaming") +
+	return styles.StatusStreamingStyle.Render("● "+i18n.T("Streaming")) +
 		styles.StatusDivider.String() +
-		s.keyHint("esc", "cancel") +
+		s.keyHint(s.keys.Cancel, i18n.T("cancel")) +
 		styles.StatusDivider.String() +
-		s.keyHint("ctrl+c", "quit")
+		s.keyHint(s.keys.Quit, i18n.T("quit"))
 }
 
 func (s StatusBar) errorView() string {
-	return styles.ErrorMessageStyle.Render("Error occurred") +
+	return styles.ErrorMessageStyle.Render(i18n.T("Error occurred")) +
 		styles.StatusDivider.String() +
-		s.keyHint("enter", "retry") +
+		s.keyHint(s.keys.Send, i18n.T("retry")) +
 		styles.StatusDivider.String() +
-		s.keyHint("ctrl+c", "quit")
+		s.keyHint(s.keys.Quit, i18n.T("quit"))
 }
 
 func (s StatusBar) helpView() string {
-	return s.keyHint("enter", "send") +
+	return s.keyHint(s.keys.Send, i18n.T("send")) +
 		styles.StatusDivider.String() +
-		s.keyHint("/help", "commands") +
+		s.keyHint("/help", i18n.T("commands")) +
 		styles.StatusDivider.String() +
-		s.keyHint("/clear", "clear") +
+		s.keyHint("/clear", i18n.T("clear")) +
 		styles.StatusDivider.String() +
-		s.keyHint("ctrl+c", "quit")
+		s.keyHint(s.keys.Quit, i18n.T("quit"))
 }
 
 func (s StatusBar) keyHint(key, desc string) string {
@@ -0,0 +1,199 @@
+// Package history keeps chat input history per model, saved across
+// sessions like a shell's.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nchapman/lleme/internal/fileutil"
+	"github.com/nchapman/lleme/internal/logs"
+	"github.com/nchapman/lleme/internal/paths"
+)
+
+// DefaultSize is how many entries are kept when chat.history_size is unset
+const DefaultSize = 1000
+
+// History is the input history for one model, oldest entry first. A nil
+// History is empty and doesn't save anything.
+type History struct {
+	path    string
+	size    int
+	entries []string
+}
+
+// Path returns the history file for a model
+func Path(model string) string {
+	return filepath.Join(paths.History(), logs.SanitizeModelName(model))
+}
+
+// Load reads the history for a model, keeping the newest size entries.
+// A size of 0 means DefaultSize; a negative size disables history and
+// returns nil.
+func Load(model string, size int) (*History, error) {
+	if size < 0 {
+		return nil, nil
+	}
+	if size == 0 {
+		size = DefaultSize
+	}
+	h := &History{path: Path(model), size: size}
+
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	// Each entry is a JSON string on its own line, so multi-line input
+	// survives. Lines that don't parse, such as one cut short, are skipped.
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry string
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			h.entries = append(h.entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return h, err
+	}
+
+	if len(h.entries) > size {
+		h.entries = h.entries[len(h.entries)-size:]
+		return h, h.rewrite()
+	}
+	return h, nil
+}
+
+// Len returns the number of entries
+func (h *History) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.entries)
+}
+
+// Entry returns entry i, where 0 is the oldest
+func (h *History) Entry(i int) string {
+	return h.entries[i]
+}
+
+// Add appends a line and saves it. Blank lines and repeats of the last
+// entry are skipped.
+func (h *History) Add(line string) error {
+	if h == nil || strings.TrimSpace(line) == "" {
+		return nil
+	}
+	if n := len(h.entries); n > 0 && h.entries[n-1] == line {
+		return nil
+	}
+
+	h.entries = append(h.entries, line)
+	if len(h.entries) > h.size {
+		h.entries = h.entries[len(h.entries)-h.size:]
+		return h.rewrite()
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// rewrite replaces the file with the entries in memory
+func (h *History) rewrite() error {
+	var sb strings.Builder
+	for _, entry := range h.entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteByte('\n')
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0700); err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(h.path, []byte(sb.String()), 0600)
+}
+
+// Search returns the index of the newest entry before index before that
+// contains query, or -1 if there isn't one
+func (h *History) Search(query string, before int) int {
+	for i := min(before, h.Len()) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Browser steps through the history with up and down, keeping the line
+// being typed so stepping past the newest entry brings it back
+type Browser struct {
+	h     *History
+	pos   int
+	draft string
+}
+
+// Browse starts at the line being typed, after the newest entry
+func (h *History) Browse() *Browser {
+	return &Browser{h: h, pos: h.Len()}
+}
+
+// Prev moves to the next older entry. current is the line being typed,
+// saved when leaving it. It reports false at the oldest entry.
+func (b *Browser) Prev(current string) (string, bool) {
+	if b.pos == 0 {
+		return "", false
+	}
+	if b.pos == b.h.Len() {
+		b.draft = current
+	}
+	b.pos--
+	return b.h.Entry(b.pos), true
+}
+
+// Next moves to the next newer entry, or back to the line being typed. It
+// reports false when already there.
+func (b *Browser) Next() (string, bool) {
+	if b.pos >= b.h.Len() {
+		return "", false
+	}
+	b.pos++
+	if b.pos == b.h.Len() {
+		return b.draft, true
+	}
+	return b.h.Entry(b.pos), true
+}
+
+// Browsing reports whether an entry, not the line being typed, is shown
+func (b *Browser) Browsing() bool {
+	return b.pos < b.h.Len()
+}
+
+// Reset returns to the line being typed, after the newest entry
+func (b *Browser) Reset() {
+	b.pos = b.h.Len()
+	b.draft = ""
+}
@@ -0,0 +1,158 @@
+package history
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+	const model = "user/Llama-3-GGUF:Q4_K_M"
+
+	h, err := Load(model, 0)
+	if err != nil || h.Len() != 0 {
+		t.Fatalf("Load() = %d entries, %v, want an empty history", h.Len(), err)
+	}
+	for _, line := range []string{"hello", "", "  ", "hello", "two\nlines", "/show"} {
+		if err := h.Add(line); err != nil {
+			t.Fatalf("Add(%q) error = %v", line, err)
+		}
+	}
+
+	// Blank lines and repeats are skipped, and multi-line input survives
+	reloaded, err := Load(model, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"hello", "two\nlines", "/show"}
+	if got := entries(reloaded); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("entries = %q, want %q", got, want)
+	}
+
+	if info, err := os.Stat(Path(model)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("history file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+
+	other, _ := Load("user/Other-GGUF:Q8_0", 0)
+	if other.Len() != 0 {
+		t.Errorf("another model's history has %d entries, want 0", other.Len())
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	h, _ := Load("m", 3)
+	for _, line := range []string{"a", "b", "c", "d"} {
+		h.Add(line)
+	}
+	if got := entries(h); strings.Join(got, "") != "bcd" {
+		t.Errorf("entries = %q, want the newest 3", got)
+	}
+
+	// A smaller size trims the file on load
+	h, _ = Load("m", 2)
+	h, _ = Load("m", 0)
+	if got := entries(h); strings.Join(got, "") != "cd" {
+		t.Errorf("entries after loading with size 2 = %q, want cd", got)
+	}
+
+	if h, err := Load("m", -1); h != nil || err != nil {
+		t.Errorf("Load(size -1) = %v, %v, want no history", h, err)
+	}
+	var none *History
+	if err := none.Add("x"); err != nil || none.Len() != 0 {
+		t.Errorf("nil history Add() = %v, Len() = %d", err, none.Len())
+	}
+}
+
+func TestHistorySkipsCorruptLines(t *testing.T) {
+	t.Setenv("LLEME_HOME", t.TempDir())
+
+	h, _ := Load("m", 0)
+	h.Add("first")
+	f, _ := os.OpenFile(Path("m"), os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("\"cut sho\n")
+	f.Close()
+	h.Add("second")
+
+	h, err := Load("m", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entries(h); strings.Join(got, ",") != "first,second" {
+		t.Errorf("entries = %q, want the lines that parse", got)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h := &History{entries: []string{"tell me a joke", "/set temp 0.5", "another joke", "/show"}}
+
+	tests := []struct {
+		query  string
+		before int
+		want   int
+	}{
+		{"joke", 4, 2},
+		{"joke", 2, 0},
+		{"joke", 0, -1},
+		{"/s", 10, 3},
+		{"missing", 4, -1},
+	}
+	for _, tt := range tests {
+		if got := h.Search(tt.query, tt.before); got != tt.want {
+			t.Errorf("Search(%q, %d) = %d, want %d", tt.query, tt.before, got, tt.want)
+		}
+	}
+}
+
+func TestBrowser(t *testing.T) {
+	h := &History{entries: []string{"one", "two"}}
+	b := h.Browse()
+
+	if _, ok := b.Next(); ok {
+		t.Error("Next() on the line being typed moved")
+	}
+	steps := []struct {
+		prev bool
+		want string
+		ok   bool
+	}{
+		{true, "two", true},
+		{true, "one", true},
+		{true, "", false},
+		{false, "two", true},
+		{false, "draft", true},
+		{false, "", false},
+	}
+	for i, s := range steps {
+		var got string
+		var ok bool
+		if s.prev {
+			got, ok = b.Prev("draft")
+		} else {
+			got, ok = b.Next()
+		}
+		if got != s.want || ok != s.ok {
+			t.Errorf("step %d = %q, %v, want %q, %v", i, got, ok, s.want, s.ok)
+		}
+	}
+
+	b.Prev("x")
+	if !b.Browsing() {
+		t.Error("Browsing() = false after Prev")
+	}
+	b.Reset()
+	if b.Browsing() {
+		t.Error("Browsing() = true after Reset")
+	}
+}
+
+func entries(h *History) []string {
+	var out []string
+	for i := range h.Len() {
+		out = append(out, h.Entry(i))
+	}
+	return out
+}
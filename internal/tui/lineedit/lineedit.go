@@ -0,0 +1,357 @@
+// Package lineedit reads lines from a terminal with shell-style editing:
+// arrow keys, emacs keys, history recall and Ctrl+R search. It redraws as
+// little as possible so screen readers announce only what changed.
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/tui/history"
+)
+
+// ErrInterrupted is returned when Ctrl+C is pressed at the prompt
+var ErrInterrupted = errors.New("interrupted")
+
+// IsTerminal reports whether f is a terminal an Editor can read from
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(f.Fd())
+}
+
+// Editor reads lines from a terminal. Lines it returns are added to its
+// history.
+type Editor struct {
+	in      *os.File
+	out     io.Writer
+	r       *bufio.Reader
+	history *history.History
+}
+
+// New creates an editor reading from the terminal in and echoing to out.
+// h may be nil for no history.
+func New(in *os.File, out io.Writer, h *history.History) *Editor {
+	return &Editor{in: in, out: out, r: bufio.NewReader(in), history: h}
+}
+
+// ReadLine shows prompt and reads a line with the terminal in raw mode,
+// restoring it before returning. Ctrl+D on an empty line returns io.EOF.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(e.in.Fd())
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(e.in.Fd(), state)
+	return e.readLine(prompt)
+}
+
+// Key codes read in raw mode
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyDelete    = 127
+
+	// Escape sequences are mapped to codes past the runes
+	keyUp = -iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyUnknown
+)
+
+// line is the line being edited and the cursor's position in it
+type line struct {
+	runes  []rune
+	cursor int
+}
+
+func (e *Editor) readLine(prompt string) (string, error) {
+	io.WriteString(e.out, prompt)
+	var l line
+	browser := e.history.Browse()
+
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		if k == keyCtrlR && e.history.Len() > 0 {
+			var done bool
+			if k, done = e.search(prompt, &l); done {
+				return e.accept(l)
+			}
+			e.redraw(prompt, l)
+			if k == 0 {
+				continue
+			}
+		}
+
+		switch k {
+		case keyCR, keyLF:
+			return e.accept(l)
+		case keyCtrlC:
+			io.WriteString(e.out, "^C\r\n")
+			return "", ErrInterrupted
+		case keyCtrlD:
+			if len(l.runes) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if l.cursor < len(l.runes) {
+				l.runes = append(l.runes[:l.cursor], l.runes[l.cursor+1:]...)
+				e.redraw(prompt, l)
+			}
+		case keyDeleteForward:
+			if l.cursor < len(l.runes) {
+				l.runes = append(l.runes[:l.cursor], l.runes[l.cursor+1:]...)
+				e.redraw(prompt, l)
+			}
+		case keyBackspace, keyDelete:
+			if l.cursor == 0 {
+				continue
+			}
+			l.cursor--
+			removed := l.runes[l.cursor]
+			l.runes = append(l.runes[:l.cursor], l.runes[l.cursor+1:]...)
+			if l.cursor == len(l.runes) {
+				// Erase the last character in place
+				w := lipgloss.Width(string(removed))
+				io.WriteString(e.out, strings.Repeat("\b", w)+strings.Repeat(" ", w)+strings.Repeat("\b", w))
+			} else {
+				e.redraw(prompt, l)
+			}
+		case keyCtrlA, keyHome:
+			l.cursor = 0
+			e.redraw(prompt, l)
+		case keyCtrlE, keyEnd:
+			l.cursor = len(l.runes)
+			e.redraw(prompt, l)
+		case keyCtrlB, keyLeft:
+			if l.cursor > 0 {
+				l.cursor--
+				e.redraw(prompt, l)
+			}
+		case keyCtrlF, keyRight:
+			if l.cursor < len(l.runes) {
+				l.cursor++
+				e.redraw(prompt, l)
+			}
+		case keyCtrlK:
+			l.runes = l.runes[:l.cursor]
+			e.redraw(prompt, l)
+		case keyCtrlU:
+			l.runes = l.runes[l.cursor:]
+			l.cursor = 0
+			e.redraw(prompt, l)
+		case keyCtrlW:
+			start := l.cursor
+			for start > 0 && l.runes[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && l.runes[start-1] != ' ' {
+				start--
+			}
+			l.runes = append(l.runes[:start], l.runes[l.cursor:]...)
+			l.cursor = start
+			e.redraw(prompt, l)
+		case keyCtrlP, keyUp:
+			if entry, ok := browser.Prev(string(l.runes)); ok {
+				l = line{runes: []rune(entry), cursor: len([]rune(entry))}
+				e.redraw(prompt, l)
+			}
+		case keyCtrlN, keyDown:
+			if entry, ok := browser.Next(); ok {
+				l = line{runes: []rune(entry), cursor: len([]rune(entry))}
+				e.redraw(prompt, l)
+			}
+		default:
+			if k < ' ' {
+				continue // Other control keys, including tab and esc
+			}
+			r := rune(k)
+			l.runes = append(l.runes[:l.cursor], append([]rune{r}, l.runes[l.cursor:]...)...)
+			l.cursor++
+			if l.cursor == len(l.runes) {
+				// Typing at the end echoes just the character
+				io.WriteString(e.out, string(r))
+			} else {
+				e.redraw(prompt, l)
+			}
+		}
+	}
+}
+
+// accept ends the line and adds it to the history
+func (e *Editor) accept(l line) (string, error) {
+	io.WriteString(e.out, "\r\n")
+	s := string(l.runes)
+	e.history.Add(s)
+	return s, nil
+}
+
+// search runs a reverse incremental history search, like a shell's Ctrl+R,
+// leaving the match in l. It returns the key that ended the search, to be
+// handled as usual, or 0 when the search was cancelled or the key was used,
+// and whether Enter accepted the line.
+func (e *Editor) search(prompt string, l *line) (int, bool) {
+	saved := *l
+	var query []rune
+	match := -1
+
+	show := func() {
+		text := ""
+		if match >= 0 {
+			text = e.history.Entry(match)
+		}
+		io.WriteString(e.out, "\r\x1b[K"+i18n.Tf("history search: %s", string(query))+" > "+text)
+	}
+	find := func(before int) {
+		if m := e.history.Search(string(query), before); m >= 0 {
+			match = m
+		}
+	}
+	show()
+
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			return 0, false
+		}
+		switch {
+		case k == keyCtrlR:
+			if len(query) > 0 && match >= 0 {
+				find(match)
+			}
+		case k == keyCtrlG || k == keyCtrlC:
+			*l = saved
+			return 0, false
+		case k == keyBackspace || k == keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = -1
+				find(e.history.Len())
+			}
+		case k >= ' ':
+			query = append(query, rune(k))
+			from := e.history.Len()
+			if match >= 0 {
+				from = match + 1 // Keep the match while it still matches
+			}
+			find(from)
+		default:
+			if match >= 0 {
+				entry := []rune(e.history.Entry(match))
+				*l = line{runes: entry, cursor: len(entry)}
+			}
+			if k == keyCR || k == keyLF {
+				e.redraw(prompt, *l)
+				return 0, true
+			}
+			if k == keyEsc {
+				return 0, false
+			}
+			return k, false
+		}
+		show()
+	}
+}
+
+// redraw rewrites the prompt and line and puts the cursor in place
+func (e *Editor) redraw(prompt string, l line) {
+	var sb strings.Builder
+	sb.WriteString("\r\x1b[K")
+	sb.WriteString(prompt)
+	sb.WriteString(string(l.runes))
+	if back := lipgloss.Width(string(l.runes[l.cursor:])); back > 0 {
+		sb.WriteString("\x1b[" + strconv.Itoa(back) + "D")
+	}
+	io.WriteString(e.out, sb.String())
+}
+
+// readKey reads one key, mapping escape sequences for the arrow, home, end
+// and delete keys to key codes
+func (e *Editor) readKey() (int, error) {
+	r, _, err := e.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r != keyEsc {
+		return int(r), nil
+	}
+	// A sequence arrives in one read; a lone Esc has nothing after it
+	if e.r.Buffered() == 0 {
+		return keyEsc, nil
+	}
+
+	next, _, err := e.r.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil // Alt+key
+	}
+	var params []rune
+	for {
+		c, _, err := e.r.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return csiKey(string(params), c), nil
+		}
+		params = append(params, c)
+	}
+}
+
+// csiKey maps the final byte and parameters of an escape sequence to a key
+func csiKey(params string, final rune) int {
+	switch final {
+	case 'A':
+		return keyUp
+	case 'B':
+		return keyDown
+	case 'C':
+		return keyRight
+	case 'D':
+		return keyLeft
+	case 'H':
+		return keyHome
+	case 'F':
+		return keyEnd
+	case '~':
+		switch params {
+		case "1", "7":
+			return keyHome
+		case "4", "8":
+			return keyEnd
+		case "3":
+			return keyDeleteForward
+		}
+	}
+	return keyUnknown
+}
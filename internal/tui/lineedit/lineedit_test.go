@@ -0,0 +1,118 @@
+package lineedit
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nchapman/lleme/internal/i18n"
+	"github.com/nchapman/lleme/internal/tui/history"
+)
+
+// editor returns an editor reading keys from input, with a history holding
+// entries
+func editor(t *testing.T, input string, entries ...string) (*Editor, *strings.Builder) {
+	t.Helper()
+	t.Setenv("LLEME_HOME", t.TempDir())
+	h, err := history.Load("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		h.Add(e)
+	}
+	var out strings.Builder
+	return &Editor{out: &out, r: bufio.NewReader(strings.NewReader(input)), history: h}, &out
+}
+
+func TestReadLine(t *testing.T) {
+	i18n.SetLanguage("en")
+
+	tests := []struct {
+		name    string
+		input   string
+		history []string
+		want    string
+	}{
+		{"typing", "hello\r", nil, "hello"},
+		{"backspace", "helo\x7f\x7fllo\r", nil, "hello"},
+		{"insert after moving left", "hllo\x1b[D\x1b[D\x1b[De\r", nil, "hello"},
+		{"home and end", "ello\x01h\x05!\r", nil, "hello!"},
+		{"home and end sequences", "b\x1b[Ha\x1b[Fc\r", nil, "abc"},
+		{"delete forward", "xhello\x01\x1b[3~\r", nil, "hello"},
+		{"kill to end", "hello world\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x0b\r", nil, "hello"},
+		{"kill to start", "junk hello\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x15\r", nil, "hello"},
+		{"delete word", "hello big world\x17\x17world\r", nil, "hello world"},
+		{"up recalls", "\x1b[A\r", []string{"one", "two"}, "two"},
+		{"up twice", "\x1b[A\x10\r", []string{"one", "two"}, "one"},
+		{"down returns to draft", "dr\x1b[A\x1b[Baft\r", []string{"one"}, "draft"},
+		{"search", "\x12on\r", []string{"one", "two", "three"}, "one"},
+		{"search again for older", "\x12o\x12\r", []string{"one", "two", "three"}, "one"},
+		{"search then edit", "\x12tw\x05!\r", []string{"one", "two"}, "two!"},
+		{"search cancelled", "ab\x12tw\x07c\r", []string{"two"}, "abc"},
+		{"search backspace", "\x12thx\x7f\x7f\r", []string{"three", "two"}, "two"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, _ := editor(t, tt.input, tt.history...)
+			got, err := e.readLine("> ")
+			if err != nil {
+				t.Fatalf("readLine() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("readLine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineAddsHistory(t *testing.T) {
+	e, _ := editor(t, "first\rsecond\r\x1b[A\x1b[A\r")
+	for range 2 {
+		if _, err := e.readLine("> "); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, _ := e.readLine("> "); got != "first" {
+		t.Errorf("readLine() = %q, want the line read two lines ago", got)
+	}
+	if e.history.Len() != 3 {
+		t.Errorf("history has %d entries, want 3", e.history.Len())
+	}
+}
+
+func TestReadLineEnds(t *testing.T) {
+	e, _ := editor(t, "abc\x03")
+	if _, err := e.readLine("> "); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("Ctrl+C error = %v, want ErrInterrupted", err)
+	}
+
+	e, _ = editor(t, "\x04")
+	if _, err := e.readLine("> "); !errors.Is(err, io.EOF) {
+		t.Errorf("Ctrl+D on an empty line error = %v, want EOF", err)
+	}
+
+	// Ctrl+D deletes under the cursor when there's text
+	e, _ = editor(t, "ab\x02\x04\r")
+	if got, _ := e.readLine("> "); got != "a" {
+		t.Errorf("Ctrl+D with text = %q, want a", got)
+	}
+
+	e, _ = editor(t, "partial")
+	if _, err := e.readLine("> "); !errors.Is(err, io.EOF) {
+		t.Errorf("end of input error = %v, want EOF", err)
+	}
+}
+
+func TestReadLineEcho(t *testing.T) {
+	// Typing at the end echoes characters without redrawing, so screen
+	// readers hear each one once
+	e, out := editor(t, "hi\x7f!\r")
+	e.readLine("> ")
+	if got := out.String(); got != "> hi\b \b!\r\n" {
+		t.Errorf("output = %q", got)
+	}
+}
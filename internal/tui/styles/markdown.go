@@ -5,7 +5,9 @@ import (
 	"sync"
 
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/ansi"
 	"github.com/charmbracelet/glamour/styles"
+	palette "github.com/nchapman/lleme/internal/styles"
 )
 
 var (
@@ -20,7 +22,7 @@ func getRenderer(width int) (*glamour.TermRenderer, error) {
 	}
 
 	r, err := glamour.NewTermRenderer(
-		glamour.WithStyles(styles.DarkStyleConfig),
+		glamour.WithStyles(markdownStyle()),
 		glamour.WithWordWrap(width),
 	)
 	if err != nil {
@@ -37,8 +39,8 @@ func getThinkingRenderer(width int) (*glamour.TermRenderer, error) {
 		return cached.(*glamour.TermRenderer), nil
 	}
 
-	style := styles.DarkStyleConfig
-	mutedColor := stringPtr(ColorMutedCode)
+	style := markdownStyle()
+	mutedColor := stringPtr(palette.ColorCode(ColorMuted))
 	style.Document.Color = mutedColor
 	style.Paragraph.Color = mutedColor
 	style.Text.Color = mutedColor
@@ -57,6 +59,20 @@ func getThinkingRenderer(width int) (*glamour.TermRenderer, error) {
 	return r, nil
 }
 
+// markdownStyle returns the glamour style for the theme's background
+func markdownStyle() ansi.StyleConfig {
+	if palette.DarkBackground() {
+		return styles.DarkStyleConfig
+	}
+	return styles.LightStyleConfig
+}
+
+// clearRenderers drops cached renderers so they pick up a new theme
+func clearRenderers() {
+	rendererCache.Clear()
+	thinkingRendererCache.Clear()
+}
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -7,108 +7,126 @@ import (
 	"github.com/nchapman/lleme/internal/styles"
 )
 
-// Re-export colors from shared styles package for convenience.
+// Colors and styles of the current theme, rebuilt when it changes
 var (
-	ColorPrimary   = styles.ColorPrimary
-	ColorSecondary = styles.ColorSecondary
-	ColorMuted     = styles.ColorMuted
-	ColorSuccess   = styles.ColorSuccess
-	ColorError     = styles.ColorError
-	ColorWarning   = styles.ColorWarning
-	ColorAccent    = styles.ColorAccent
-	ColorBorder    = styles.ColorBorder
-	ColorValue     = styles.ColorValue
+	ColorPrimary   lipgloss.TerminalColor
+	ColorSecondary lipgloss.TerminalColor
+	ColorMuted     lipgloss.TerminalColor
+	ColorSuccess   lipgloss.TerminalColor
+	ColorError     lipgloss.TerminalColor
+	ColorWarning   lipgloss.TerminalColor
+	ColorAccent    lipgloss.TerminalColor
+	ColorBorder    lipgloss.TerminalColor
+	ColorValue     lipgloss.TerminalColor
+
+	HeaderStyle          lipgloss.Style
+	HeaderDivider        lipgloss.Style
+	HeaderModelStyle     lipgloss.Style
+	HeaderStatStyle      lipgloss.Style
+	HeaderStatValueStyle lipgloss.Style
+	UserMessageStyle     lipgloss.Style
+	UserPrefixStyle      lipgloss.Style
+	ErrorMessageStyle    lipgloss.Style
+	SystemMessageStyle   lipgloss.Style
+	MessageMetaStyle     lipgloss.Style
+	InputStyle           lipgloss.Style
+	InputFocusedStyle    lipgloss.Style
+	StatusBarStyle       lipgloss.Style
+	StatusKeyStyle       lipgloss.Style
+	StatusDescStyle      lipgloss.Style
+	StatusDivider        lipgloss.Style
+	StatusStreamingStyle lipgloss.Style
+	ViewportStyle        lipgloss.Style
+	DividerStyle         lipgloss.Style
 )
 
-// Re-export color codes for glamour markdown styling.
-const ColorMutedCode = styles.ColorMutedCode
-
-// Header styles
-var (
-	HeaderStyle = lipgloss.NewStyle().
+func init() {
+	styles.OnThemeChange(func() {
+		ColorPrimary = styles.ColorPrimary
+		ColorSecondary = styles.ColorSecondary
+		ColorMuted = styles.ColorMuted
+		ColorSuccess = styles.ColorSuccess
+		ColorError = styles.ColorError
+		ColorWarning = styles.ColorWarning
+		ColorAccent = styles.ColorAccent
+		ColorBorder = styles.ColorBorder
+		ColorValue = styles.ColorValue
+
+		HeaderStyle = lipgloss.NewStyle().
 			Bold(true).
 			Foreground(ColorPrimary).
 			Padding(0, 1)
 
-	HeaderDivider = lipgloss.NewStyle().
+		HeaderDivider = lipgloss.NewStyle().
 			Foreground(ColorMuted).
 			SetString("│")
 
-	HeaderModelStyle = lipgloss.NewStyle().
-				Bold(true).
-				Foreground(ColorAccent)
+		HeaderModelStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(ColorAccent)
 
-	HeaderStatStyle = lipgloss.NewStyle().
+		HeaderStatStyle = lipgloss.NewStyle().
 			Foreground(ColorMuted)
 
-	HeaderStatValueStyle = lipgloss.NewStyle().
-				Foreground(ColorSecondary)
-)
+		HeaderStatValueStyle = lipgloss.NewStyle().
+			Foreground(ColorSecondary)
 
-// Message styles
-var (
-	UserMessageStyle = lipgloss.NewStyle().
-				Foreground(ColorPrimary).
-				Bold(true)
+		UserMessageStyle = lipgloss.NewStyle().
+			Foreground(ColorPrimary).
+			Bold(true)
 
-	UserPrefixStyle = lipgloss.NewStyle().
+		UserPrefixStyle = lipgloss.NewStyle().
 			Foreground(ColorPrimary).
 			Bold(true).
 			SetString("┃ ")
 
-	ErrorMessageStyle = lipgloss.NewStyle().
-				Foreground(ColorError)
+		ErrorMessageStyle = lipgloss.NewStyle().
+			Foreground(ColorError)
 
-	SystemMessageStyle = lipgloss.NewStyle().
-				Foreground(ColorWarning).
-				Italic(true)
-)
+		SystemMessageStyle = lipgloss.NewStyle().
+			Foreground(ColorWarning).
+			Italic(true)
 
-// Input styles
-var (
-	InputStyle = lipgloss.NewStyle().
+		MessageMetaStyle = lipgloss.NewStyle().
+			Foreground(ColorMuted)
+
+		InputStyle = lipgloss.NewStyle().
 			PaddingLeft(2).
 			PaddingRight(2).
 			Foreground(ColorMuted)
 
-	InputFocusedStyle = lipgloss.NewStyle().
-				PaddingLeft(2).
-				PaddingRight(2)
-)
+		InputFocusedStyle = lipgloss.NewStyle().
+			PaddingLeft(2).
+			PaddingRight(2)
 
-// Status bar styles
-var (
-	StatusBarStyle = lipgloss.NewStyle().
+		StatusBarStyle = lipgloss.NewStyle().
 			Foreground(ColorMuted).
 			Padding(0, 1)
 
-	StatusKeyStyle = lipgloss.NewStyle().
+		StatusKeyStyle = lipgloss.NewStyle().
 			Foreground(ColorSecondary).
 			Bold(true)
 
-	StatusDescStyle = lipgloss.NewStyle().
+		StatusDescStyle = lipgloss.NewStyle().
 			Foreground(ColorMuted)
 
-	StatusDivider = lipgloss.NewStyle().
+		StatusDivider = lipgloss.NewStyle().
 			Foreground(ColorMuted).
 			SetString(" │ ")
 
-	StatusStreamingStyle = lipgloss.NewStyle().
-				Foreground(ColorAccent).
-				Bold(true)
-)
+		StatusStreamingStyle = lipgloss.NewStyle().
+			Foreground(ColorAccent).
+			Bold(true)
 
-// Viewport styles
-var (
-	ViewportStyle = lipgloss.NewStyle().
-		Padding(0, 1)
-)
+		ViewportStyle = lipgloss.NewStyle().
+			Padding(0, 1)
 
-// Border styles
-var (
-	DividerStyle = lipgloss.NewStyle().
-		Foreground(ColorBorder)
-)
+		DividerStyle = lipgloss.NewStyle().
+			Foreground(ColorBorder)
+
+		clearRenderers()
+	})
+}
 
 // HorizontalDivider creates a horizontal line of the given width
 func HorizontalDivider(width int) string {
@@ -0,0 +1,115 @@
+package termimg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Pixels per cell assumed when scaling an image for sixel, which is drawn
+// in pixels rather than cells
+const (
+	cellWidth  = 10
+	cellHeight = 20
+)
+
+// sixel scales the image to cover cols×rows cells and encodes it with a
+// 6×6×6 color cube. Transparent pixels are left undrawn.
+func sixel(img Image, cols, rows int) (string, error) {
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return "", err
+	}
+	width, height := cols*cellWidth, rows*cellHeight
+	pixels := quantize(src, width, height)
+
+	var sb strings.Builder
+	// P2=1 keeps the background under transparent pixels; the raster
+	// attributes give a 1:1 aspect and the size
+	fmt.Fprintf(&sb, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i := range 216 {
+		r, g, b := i/36, i/6%6, i%6
+		fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", i, r*20, g*20, b*20)
+	}
+
+	for y0 := 0; y0 < height; y0 += 6 {
+		// Each color used in the band is one pass over it
+		var used [216]bool
+		for y := y0; y < min(y0+6, height); y++ {
+			for _, c := range pixels[y*width : (y+1)*width] {
+				if c >= 0 {
+					used[c] = true
+				}
+			}
+		}
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			fmt.Fprintf(&sb, "#%d", c)
+			writeSixelRow(&sb, pixels, width, height, y0, c)
+			sb.WriteByte('$')
+		}
+		sb.WriteByte('-')
+	}
+	sb.WriteString("\x1b\\")
+	return sb.String(), nil
+}
+
+// writeSixelRow writes the six-pixel-tall band starting at y0 for color c,
+// run-length encoding repeats
+func writeSixelRow(sb *strings.Builder, pixels []int, width, height, y0, c int) {
+	var prev byte
+	run := 0
+	flush := func() {
+		switch {
+		case run > 3:
+			fmt.Fprintf(sb, "!%d%c", run, prev)
+		case run > 0:
+			sb.WriteString(strings.Repeat(string(prev), run))
+		}
+	}
+
+	for x := range width {
+		var bits byte
+		for i := range 6 {
+			if y := y0 + i; y < height && pixels[y*width+x] == c {
+				bits |= 1 << i
+			}
+		}
+		ch := 63 + bits
+		if ch == prev {
+			run++
+			continue
+		}
+		flush()
+		prev, run = ch, 1
+	}
+	flush()
+}
+
+// quantize scales src to width×height with nearest-neighbor sampling and
+// maps each pixel to the color cube, or -1 where it's transparent
+func quantize(src image.Image, width, height int) []int {
+	bounds := src.Bounds()
+	pixels := make([]int, width*height)
+	for y := range height {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := range width {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			r, g, b, a := src.At(sx, sy).RGBA()
+			if a < 0x8000 {
+				pixels[y*width+x] = -1
+				continue
+			}
+			pixels[y*width+x] = cube(r)*36 + cube(g)*6 + cube(b)
+		}
+	}
+	return pixels
+}
+
+// cube maps a 16-bit channel to one of six levels
+func cube(v uint32) int {
+	return int((v*5 + 0x7fff) / 0xffff)
+}
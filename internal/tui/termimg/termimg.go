@@ -0,0 +1,219 @@
+// Package termimg shows images inline in the terminal using the kitty,
+// iTerm2 or sixel graphics protocols, with a text placeholder elsewhere.
+package termimg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for Load
+	_ "image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Protocol is a terminal graphics protocol
+type Protocol string
+
+const (
+	None   Protocol = "none" // Placeholder text only
+	Kitty  Protocol = "kitty"
+	ITerm2 Protocol = "iterm2"
+	Sixel  Protocol = "sixel"
+)
+
+// MaxSize is the largest image file Load accepts
+const MaxSize = 20 << 20
+
+// Images are drawn at most this many rows tall so they don't push the
+// conversation off screen
+const maxRows = 20
+
+// ParseProtocol parses the chat.images setting: auto (or empty) detects the
+// terminal, off shows placeholders, and a protocol name forces it.
+func ParseProtocol(s string) (Protocol, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return Detect(os.Getenv), nil
+	case "off", string(None):
+		return None, nil
+	case string(Kitty), string(ITerm2), string(Sixel):
+		return Protocol(strings.ToLower(s)), nil
+	}
+	return "", fmt.Errorf("unknown chat.images %q: must be auto, kitty, iterm2, sixel or off", s)
+}
+
+// Detect picks a protocol from the environment the terminal sets. Inside
+// tmux or screen, which don't pass graphics through, it returns None.
+func Detect(getenv func(string) string) Protocol {
+	if getenv("TMUX") != "" || strings.HasPrefix(getenv("TERM"), "screen") {
+		return None
+	}
+
+	term := getenv("TERM")
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "", term == "xterm-kitty", term == "xterm-ghostty":
+		return Kitty
+	}
+
+	switch getenv("TERM_PROGRAM") {
+	case "ghostty":
+		return Kitty
+	case "iTerm.app", "WezTerm":
+		return ITerm2
+	case "mlterm", "contour":
+		return Sixel
+	}
+	if getenv("LC_TERMINAL") == "iTerm2" {
+		return ITerm2
+	}
+	if term == "foot" || strings.HasPrefix(term, "foot-") || strings.Contains(term, "sixel") {
+		return Sixel
+	}
+	return None
+}
+
+// Image is an encoded image file
+type Image struct {
+	Name   string // Base name of the file, for placeholders
+	Format string // png, jpeg or gif
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Load reads an image file, checking that it decodes
+func Load(path string) (Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, err
+	}
+	if info.Size() > MaxSize {
+		return Image{}, fmt.Errorf("%s is larger than %d MB", path, MaxSize>>20)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, err
+	}
+	return Decode(filepath.Base(path), data)
+}
+
+// Decode reads the format and size of an encoded image
+func Decode(name string, data []byte) (Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("%s is not a PNG, JPEG or GIF image: %w", name, err)
+	}
+	return Image{Name: name, Format: format, Width: cfg.Width, Height: cfg.Height, Data: data}, nil
+}
+
+// DataURL returns the image as a data URL for the chat completions API
+func (img Image) DataURL() string {
+	return "data:image/" + img.Format + ";base64," + base64.StdEncoding.EncodeToString(img.Data)
+}
+
+// Placeholder describes the image in text
+func (img Image) Placeholder() string {
+	return fmt.Sprintf("[image: %s, %d×%d]", img.Name, img.Width, img.Height)
+}
+
+// Render returns the image drawn with p at most maxCols wide, followed by
+// blank lines for the rows it covers, or the placeholder for None or an
+// image that can't be drawn
+func Render(img Image, p Protocol, maxCols int) string {
+	cols, rows := cells(img.Width, img.Height, maxCols)
+	if cols == 0 {
+		return img.Placeholder()
+	}
+
+	var seq string
+	var err error
+	switch p {
+	case Kitty:
+		seq, err = kitty(img, cols, rows)
+	case ITerm2:
+		seq = iterm2(img, cols, rows)
+	case Sixel:
+		seq, err = sixel(img, cols, rows)
+	default:
+		return img.Placeholder()
+	}
+	if err != nil {
+		return img.Placeholder()
+	}
+	return seq + strings.Repeat("\n", rows-1)
+}
+
+// cells sizes an image in terminal cells, assuming cells twice as tall as
+// they are wide and about 10 pixels across. Small images aren't scaled up.
+func cells(width, height, maxCols int) (cols, rows int) {
+	if width <= 0 || height <= 0 || maxCols <= 0 {
+		return 0, 0
+	}
+	cols = min(maxCols, max(1, width/10))
+	rows = max(1, cols*height/width/2)
+	if rows > maxRows {
+		rows = maxRows
+		cols = max(1, rows*2*width/height)
+	}
+	return cols, rows
+}
+
+// kittyChunk is the most base64 data the kitty protocol takes per escape
+const kittyChunk = 4096
+
+// kitty transmits the image as PNG and places it at the cursor without
+// moving it. Each image gets a stable ID, so drawing it again on the next
+// frame moves the placement rather than adding one.
+func kitty(img Image, cols, rows int) (string, error) {
+	data := img.Data
+	if img.Format != "png" {
+		decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, decoded); err != nil {
+			return "", err
+		}
+		data = buf.Bytes()
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	id := imageID(img.Data)
+	var sb strings.Builder
+	for i := 0; i < len(encoded); i += kittyChunk {
+		end := min(i+kittyChunk, len(encoded))
+		more := 0
+		if end < len(encoded) {
+			more = 1
+		}
+		if i == 0 {
+			fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,i=%d,p=1,c=%d,r=%d,C=1,q=2,m=%d;%s\x1b\\", id, cols, rows, more, encoded[i:end])
+		} else {
+			fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, encoded[i:end])
+		}
+	}
+	return sb.String(), nil
+}
+
+// imageID derives a kitty image ID from the image data
+func imageID(data []byte) uint32 {
+	// FNV-1a, kept nonzero since zero means no ID
+	h := uint32(2166136261)
+	for _, b := range data {
+		h ^= uint32(b)
+		h *= 16777619
+	}
+	return h>>1 | 1
+}
+
+// iterm2 sends the file as is; iTerm2 and WezTerm decode it themselves
+func iterm2(img Image, cols, rows int) string {
+	name := base64.StdEncoding.EncodeToString([]byte(img.Name))
+	return fmt.Sprintf("\x1b]1337;File=name=%s;size=%d;inline=1;width=%d;height=%d;preserveAspectRatio=1:%s\a",
+		name, len(img.Data), cols, rows, base64.StdEncoding.EncodeToString(img.Data))
+}
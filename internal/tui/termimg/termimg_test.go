@@ -0,0 +1,181 @@
+package termimg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Protocol
+	}{
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{"kitty window", map[string]string{"TERM": "xterm-256color", "KITTY_WINDOW_ID": "1"}, Kitty},
+		{"ghostty", map[string]string{"TERM_PROGRAM": "ghostty"}, Kitty},
+		{"iterm2", map[string]string{"TERM_PROGRAM": "iTerm.app"}, ITerm2},
+		{"iterm2 over ssh", map[string]string{"LC_TERMINAL": "iTerm2"}, ITerm2},
+		{"wezterm", map[string]string{"TERM_PROGRAM": "WezTerm"}, ITerm2},
+		{"foot", map[string]string{"TERM": "foot"}, Sixel},
+		{"tmux", map[string]string{"TERM": "tmux-256color", "TMUX": "/tmp/tmux", "KITTY_WINDOW_ID": "1"}, None},
+		{"screen", map[string]string{"TERM": "screen-256color", "TERM_PROGRAM": "iTerm.app"}, None},
+		{"plain xterm", map[string]string{"TERM": "xterm-256color"}, None},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(func(k string) string { return tt.env[k] }); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProtocol(t *testing.T) {
+	for in, want := range map[string]Protocol{"off": None, "none": None, "Kitty": Kitty, "iterm2": ITerm2, "sixel": Sixel} {
+		if got, err := ParseProtocol(in); err != nil || got != want {
+			t.Errorf("ParseProtocol(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseProtocol("braille"); err == nil {
+		t.Error("ParseProtocol(braille) succeeded, want an error")
+	}
+}
+
+func TestCells(t *testing.T) {
+	tests := []struct {
+		width, height, maxCols int
+		wantCols, wantRows     int
+	}{
+		{800, 400, 40, 40, 10},
+		{800, 400, 100, 80, 20},
+		{100, 1000, 60, 4, 20},
+		{20, 20, 60, 2, 1},
+		{0, 10, 60, 0, 0},
+	}
+	for _, tt := range tests {
+		cols, rows := cells(tt.width, tt.height, tt.maxCols)
+		if cols != tt.wantCols || rows != tt.wantRows {
+			t.Errorf("cells(%d, %d, %d) = %d×%d, want %d×%d", tt.width, tt.height, tt.maxCols, cols, rows, tt.wantCols, tt.wantRows)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	img, err := Decode("cat.png", testPNG(t, 200, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 200×100 pixels is 20 cells across and 5 rows
+	const rows = 5
+
+	if got := Render(img, None, 40); got != "[image: cat.png, 200×100]" {
+		t.Errorf("Render(None) = %q", got)
+	}
+
+	tests := []struct {
+		protocol   Protocol
+		prefix     string
+		terminator string
+	}{
+		{Kitty, "\x1b_Ga=T,f=100,", "\x1b\\"},
+		{ITerm2, "\x1b]1337;File=", "\a"},
+		{Sixel, "\x1bP0;1;0q", "\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.protocol), func(t *testing.T) {
+			got := Render(img, tt.protocol, 40)
+			if !strings.HasPrefix(got, tt.prefix) {
+				t.Errorf("Render() starts %q, want %q", got[:min(len(got), 20)], tt.prefix)
+			}
+			seq := strings.TrimRight(got, "\n")
+			if !strings.HasSuffix(seq, tt.terminator) {
+				t.Errorf("Render() sequence ends %q, want %q", seq[max(0, len(seq)-10):], tt.terminator)
+			}
+			if n := strings.Count(got, "\n"); n != rows-1 {
+				t.Errorf("Render() reserves %d extra lines, want %d", n, rows-1)
+			}
+		})
+	}
+}
+
+func TestKittyChunks(t *testing.T) {
+	// Noise doesn't compress, so the PNG spans several chunks
+	noise := image.NewGray(image.Rect(0, 0, 100, 100))
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range noise.Pix {
+		noise.Pix[i] = uint8(r.UintN(256))
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, noise)
+	img, err := Decode("noise.png", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seq, err := kitty(img, 10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := strings.Split(strings.TrimSuffix(seq, "\x1b\\"), "\x1b\\")
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunk(s), want several", len(chunks))
+	}
+	for i, c := range chunks {
+		last := i == len(chunks)-1
+		if last != strings.Contains(c, "m=0;") {
+			t.Errorf("chunk %d: %q, want m=0 only on the last", i, c[:20])
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(path, testPNG(t, 3, 2), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if img.Name != "photo.png" || img.Format != "png" || img.Width != 3 || img.Height != 2 {
+		t.Errorf("Load() = %s %s %d×%d", img.Name, img.Format, img.Width, img.Height)
+	}
+	if !strings.HasPrefix(img.DataURL(), "data:image/png;base64,iVBORw0KGgo") {
+		t.Errorf("DataURL() = %q", img.DataURL()[:40])
+	}
+
+	notes := filepath.Join(dir, "notes.txt")
+	os.WriteFile(notes, []byte("not an image"), 0644)
+	if _, err := Load(notes); err == nil || !strings.Contains(err.Error(), "not a PNG, JPEG or GIF") {
+		t.Errorf("Load(text file) error = %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("Load(missing file) succeeded")
+	}
+}
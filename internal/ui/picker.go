@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ErrCancelled is returned when the user dismisses a prompt.
+var ErrCancelled = errors.New("cancelled")
+
+type pickerModel struct {
+	title     string
+	header    string
+	rows      []string
+	cursor    int
+	selected  bool
+	cancelled bool
+}
+
+func (m pickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch key.String() {
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.rows)-1 {
+			m.cursor++
+		}
+	case "home", "g":
+		m.cursor = 0
+	case "end", "G":
+		m.cursor = len(m.rows) - 1
+	case "enter":
+		m.selected = true
+		return m, tea.Quit
+	case "esc", "q", "ctrl+c":
+		m.cancelled = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+func (m pickerModel) View() string {
+	if m.selected || m.cancelled {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(m.title + "\n\n")
+	if m.header != "" {
+		b.WriteString("  " + m.header + "\n")
+	}
+	for i, row := range m.rows {
+		if i == m.cursor {
+			b.WriteString(Keyword(IconArrow+" "+row) + "\n")
+		} else {
+			b.WriteString("  " + row + "\n")
+		}
+	}
+	b.WriteString("\n" + Muted("↑/↓ to move, enter to select, esc to cancel") + "\n")
+	return b.String()
+}
+
+// Pick shows an interactive list and returns the index of the chosen row.
+// The table's first line is shown as a header and each following line is a
+// choice. Returns ErrCancelled if the user backs out.
+func Pick(title string, table *Table, defaultIndex int) (int, error) {
+	lines := strings.Split(strings.TrimRight(table.Indent(0).Render(), "\n"), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("nothing to pick from")
+	}
+
+	if accessible {
+		return pickLinear(os.Stdin, os.Stdout, title, lines[0], lines[1:], defaultIndex)
+	}
+
+	m := pickerModel{
+		title:  title,
+		header: lines[0],
+		rows:   lines[1:],
+		cursor: max(0, min(defaultIndex, len(lines)-2)),
+	}
+
+	final, err := tea.NewProgram(m).Run()
+	if err != nil {
+		return 0, err
+	}
+	result := final.(pickerModel)
+	if !result.selected {
+		return 0, ErrCancelled
+	}
+	return result.cursor, nil
+}
+
+// pickLinear is Pick for screen readers: a numbered list and a typed choice
+// instead of a cursor that repaints
+func pickLinear(in io.Reader, out io.Writer, title, header string, rows []string, defaultIndex int) (int, error) {
+	defaultIndex = max(0, min(defaultIndex, len(rows)-1))
+
+	fmt.Fprintln(out, title)
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "     %s\n", header)
+	for i, row := range rows {
+		fmt.Fprintf(out, "%3d. %s\n", i+1, row)
+	}
+	fmt.Fprintln(out)
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Fprintf(out, "Choose 1-%d (enter for %d, q to cancel): ", len(rows), defaultIndex+1)
+		line, err := reader.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if err != nil && answer == "" {
+			return 0, ErrCancelled
+		}
+
+		switch answer {
+		case "":
+			return defaultIndex, nil
+		case "q", "quit":
+			return 0, ErrCancelled
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(rows) {
+			return n - 1, nil
+		}
+		fmt.Fprintf(out, "%q isn't a choice\n", answer)
+	}
+}
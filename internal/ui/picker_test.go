@@ -0,0 +1,104 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPickerModelUpdate(t *testing.T) {
+	key := func(s string) tea.KeyMsg {
+		switch s {
+		case "up":
+			return tea.KeyMsg{Type: tea.KeyUp}
+		case "down":
+			return tea.KeyMsg{Type: tea.KeyDown}
+		case "enter":
+			return tea.KeyMsg{Type: tea.KeyEnter}
+		case "esc":
+			return tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+
+	tests := []struct {
+		name          string
+		keys          []string
+		wantCursor    int
+		wantSelected  bool
+		wantCancelled bool
+	}{
+		{"down moves cursor", []string{"down"}, 2, false, false},
+		{"stops at bottom", []string{"down", "down", "down"}, 2, false, false},
+		{"up and k move up", []string{"up", "k"}, 0, false, false},
+		{"stops at top", []string{"up", "up", "up"}, 0, false, false},
+		{"enter selects", []string{"j", "enter"}, 2, true, false},
+		{"esc cancels", []string{"esc"}, 1, false, true},
+		{"q cancels", []string{"q"}, 1, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m tea.Model = pickerModel{rows: []string{"a", "b", "c"}, cursor: 1}
+			for _, k := range tt.keys {
+				m, _ = m.Update(key(k))
+			}
+			got := m.(pickerModel)
+			if got.cursor != tt.wantCursor {
+				t.Errorf("cursor = %d, want %d", got.cursor, tt.wantCursor)
+			}
+			if got.selected != tt.wantSelected || got.cancelled != tt.wantCancelled {
+				t.Errorf("selected/cancelled = %v/%v, want %v/%v", got.selected, got.cancelled, tt.wantSelected, tt.wantCancelled)
+			}
+		})
+	}
+}
+
+func TestPickerModelView(t *testing.T) {
+	m := pickerModel{title: "Pick one", header: "NAME", rows: []string{"first", "second"}, cursor: 1}
+	view := m.View()
+	for _, want := range []string{"Pick one", "NAME", "first", IconArrow + " second"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q:\n%s", want, view)
+		}
+	}
+
+	m.selected = true
+	if view := m.View(); view != "" {
+		t.Errorf("View() after selection = %q, want empty", view)
+	}
+}
+
+func TestPickLinear(t *testing.T) {
+	rows := []string{"Q4_K_M  4.9 GB", "Q8_0    8.5 GB"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr error
+	}{
+		{"number", "2\n", 1, nil},
+		{"default", "\n", 0, nil},
+		{"retry after a bad answer", "9\nx\n2\n", 1, nil},
+		{"cancel", "q\n", 0, ErrCancelled},
+		{"eof", "", 0, ErrCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			got, err := pickLinear(strings.NewReader(tt.input), &out, "Pick a quant", "QUANT   SIZE", rows, 0)
+			if err != tt.wantErr {
+				t.Fatalf("pickLinear() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("pickLinear() = %d, want %d", got, tt.want)
+			}
+			if !strings.Contains(out.String(), "  2. Q8_0") {
+				t.Errorf("output doesn't number the choices:\n%s", out.String())
+			}
+		})
+	}
+}
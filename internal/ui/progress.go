@@ -7,6 +7,7 @@ import (
 
 	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nchapman/lleme/internal/styles"
 )
 
 type progressModel struct {
@@ -34,8 +35,14 @@ func tickProgress() tea.Cmd {
 }
 
 func initialProgressModel(message string, total int64) progressModel {
+	// The default gradient suits the auto theme; others fill with their
+	// primary color
+	fill := progress.WithDefaultGradient()
+	if styles.CurrentTheme().Name != styles.DefaultTheme {
+		fill = progress.WithSolidFill(styles.ColorCode(styles.ColorPrimary))
+	}
 	p := progress.New(
-		progress.WithDefaultGradient(),
+		fill,
 		progress.WithWidth(50),
 		progress.WithoutPercentage(),
 	)
@@ -169,6 +176,8 @@ func FormatNumber(n int64) string {
 	return fmt.Sprintf("%.1fB", float64(n)/1000000000)
 }
 
+// ProgressBar shows download progress. In plain and accessible modes it doesn't repaint and
+// only prints the final message.
 type ProgressBar struct {
 	program *tea.Program
 	done    chan struct{}
@@ -181,6 +190,9 @@ func NewProgressBar() *ProgressBar {
 }
 
 func (p *ProgressBar) Start(message string, total int64) {
+	if static() {
+		return
+	}
 	m := initialProgressModel(message, total)
 	p.program = tea.NewProgram(m)
 	go func() {
@@ -197,6 +209,9 @@ func (p *ProgressBar) Update(downloaded, total int64) {
 
 func (p *ProgressBar) Finish(message string) {
 	if p.program == nil {
+		if static() {
+			fmt.Println(Success(message))
+		}
 		return
 	}
 	p.program.Send(progressFinishMsg{message: Success(message)})
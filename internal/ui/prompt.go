@@ -2,7 +2,10 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+
+	"github.com/nchapman/lleme/internal/i18n"
 )
 
 // PromptYesNo asks a yes/no question and returns true if user confirms.
@@ -10,9 +13,9 @@ import (
 // On error (e.g., EOF or closed stdin), returns false as a safe default.
 func PromptYesNo(prompt string, defaultYes bool) bool {
 	if defaultYes {
-		fmt.Printf("%s [Y/n] ", prompt)
+		fmt.Printf("%s %s ", prompt, i18n.T("[Y/n]"))
 	} else {
-		fmt.Printf("%s [y/N] ", prompt)
+		fmt.Printf("%s %s ", prompt, i18n.T("[y/N]"))
 	}
 
 	var response string
@@ -21,8 +24,13 @@ func PromptYesNo(prompt string, defaultYes bool) bool {
 	}
 	response = strings.TrimSpace(strings.ToLower(response))
 
-	if defaultYes {
-		return response == "" || response == "y" || response == "yes"
+	if defaultYes && response == "" {
+		return true
 	}
-	return response == "y" || response == "yes"
+	return isYes(response)
+}
+
+// isYes accepts English answers in every language, plus the translated ones
+func isYes(response string) bool {
+	return slices.Contains([]string{"y", "yes", i18n.T("y"), i18n.T("yes")}, response)
 }
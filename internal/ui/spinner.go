@@ -69,6 +69,8 @@ func (m spinModel) View() string {
 	return fmt.Sprintf("%s %s", m.spinner.View(), m.message)
 }
 
+// Spinner animates while work is in progress. In plain and accessible modes it only prints
+// the final message.
 type Spinner struct {
 	prog *tea.Program
 }
@@ -78,6 +80,9 @@ func NewSpinner() *Spinner {
 }
 
 func (s *Spinner) Start(message string) {
+	if static() {
+		return
+	}
 	m := initialSpinModel(message)
 	s.prog = tea.NewProgram(m)
 	go func() {
@@ -86,6 +91,12 @@ func (s *Spinner) Start(message string) {
 }
 
 func (s *Spinner) Stop(success bool, message string) {
+	if static() {
+		if message != "" {
+			fmt.Println(message)
+		}
+		return
+	}
 	if s.prog != nil {
 		s.prog.Send(spinFinishMsg{success: success, message: message})
 		s.prog.Wait()
@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"github.com/nchapman/lleme/internal/i18n"
 	"github.com/nchapman/lleme/internal/styles"
 )
 
@@ -16,14 +18,14 @@ const (
 )
 
 var (
-	headerStyle   = lipgloss.NewStyle().Bold(true).Foreground(styles.ColorPrimary)
-	successStyle  = lipgloss.NewStyle().Foreground(styles.ColorSuccess)
-	errorStyle    = lipgloss.NewStyle().Foreground(styles.ColorError)
-	warningStyle  = lipgloss.NewStyle().Foreground(styles.ColorWarning)
-	mutedStyle    = lipgloss.NewStyle().Foreground(styles.ColorMuted)
+	headerStyle   lipgloss.Style
+	successStyle  lipgloss.Style
+	errorStyle    lipgloss.Style
+	warningStyle  lipgloss.Style
+	mutedStyle    lipgloss.Style
 	boldStyle     = lipgloss.NewStyle().Bold(true)
-	keywordStyle  = lipgloss.NewStyle().Bold(true).Foreground(styles.ColorAccent)
-	valueStyle    = lipgloss.NewStyle().Foreground(styles.ColorValue)
+	keywordStyle  lipgloss.Style
+	valueStyle    lipgloss.Style
 	borderStyle   = lipgloss.NewStyle().Border(lipgloss.RoundedBorder())
 	borderPadding = lipgloss.NewStyle().Padding(1, 2)
 
@@ -32,6 +34,88 @@ var (
 	ExitFunc = os.Exit
 )
 
+func init() {
+	styles.OnThemeChange(func() {
+		headerStyle = lipgloss.NewStyle().Bold(true).Foreground(styles.ColorPrimary)
+		successStyle = lipgloss.NewStyle().Foreground(styles.ColorSuccess)
+		errorStyle = lipgloss.NewStyle().Foreground(styles.ColorError)
+		warningStyle = lipgloss.NewStyle().Foreground(styles.ColorWarning)
+		mutedStyle = lipgloss.NewStyle().Foreground(styles.ColorMuted)
+		keywordStyle = lipgloss.NewStyle().Bold(true).Foreground(styles.ColorAccent)
+		valueStyle = lipgloss.NewStyle().Foreground(styles.ColorValue)
+	})
+}
+
+// SetTheme applies the ui.theme setting, with ui.colors overriding entries
+// of its palette
+func SetTheme(name string, colors map[string]string) error {
+	t, err := styles.ResolveTheme(name, colors)
+	if err != nil {
+		return err
+	}
+	styles.SetTheme(t)
+	return nil
+}
+
+// Color modes for the ui.color setting
+const (
+	ColorAuto   = "auto"   // Color on terminals unless NO_COLOR is set
+	ColorAlways = "always" // Color even when output is piped
+	ColorNever  = "never"  // Plain text
+)
+
+// plain drops box drawing along with color, for output read by logs and
+// other programs rather than people
+var plain bool
+
+// SetColorMode applies the ui.color setting to everything the ui package
+// renders and returns the resulting color profile. In auto mode, output that
+// isn't a terminal and NO_COLOR (https://no-color.org) both mean plain text.
+func SetColorMode(mode string) (termenv.Profile, error) {
+	switch mode {
+	case "", ColorAuto:
+		if os.Getenv("NO_COLOR") != "" {
+			lipgloss.SetColorProfile(termenv.Ascii)
+		}
+	case ColorAlways:
+		if lipgloss.ColorProfile() == termenv.Ascii {
+			lipgloss.SetColorProfile(termenv.ANSI256)
+		}
+	case ColorNever:
+		lipgloss.SetColorProfile(termenv.Ascii)
+	default:
+		return lipgloss.ColorProfile(), fmt.Errorf("invalid ui.color %q: must be %s, %s or %s", mode, ColorAuto, ColorAlways, ColorNever)
+	}
+	profile := lipgloss.ColorProfile()
+	plain = profile == termenv.Ascii
+	return profile, nil
+}
+
+// Plain reports whether output is plain text, without color or box drawing
+func Plain() bool {
+	return plain
+}
+
+// accessible is set by the ui.accessible setting for screen readers
+var accessible bool
+
+// SetAccessible turns on output that reads well with a screen reader: no
+// spinners, repainting or box drawing, and prompts that take typed answers
+func SetAccessible(on bool) {
+	accessible = on
+}
+
+// Accessible reports whether the ui.accessible setting is on
+func Accessible() bool {
+	return accessible
+}
+
+// static reports whether output must be written once, without animation or
+// box drawing
+func static() bool {
+	return plain || accessible
+}
+
 func Header(text string) string {
 	return headerStyle.Render(text)
 }
@@ -65,6 +149,9 @@ func Value(text string) string {
 }
 
 func Box(text string) string {
+	if static() {
+		return text
+	}
 	return borderPadding.Render(borderStyle.Render(text))
 }
 
@@ -75,11 +162,11 @@ func LlamaCppCredit(version string) string {
 
 // Fatal prints an error message to stderr and exits with code 1.
 func Fatal(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "%s %s\n", ErrorMsg("Error:"), fmt.Sprintf(format, args...))
+	fmt.Fprintf(os.Stderr, "%s %s\n", ErrorMsg(i18n.T("Error:")), i18n.Tf(format, args...))
 	ExitFunc(1)
 }
 
 // PrintError prints an error message to stderr without exiting.
 func PrintError(format string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, "%s %s\n", ErrorMsg("Error:"), fmt.Sprintf(format, args...))
+	fmt.Fprintf(os.Stderr, "%s %s\n", ErrorMsg(i18n.T("Error:")), i18n.Tf(format, args...))
 }
@@ -3,6 +3,9 @@ package ui
 import (
 	"strings"
 	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 func TestStyleFunctions(t *testing.T) {
@@ -102,3 +105,53 @@ func TestFatal_ExitFuncOverride(t *testing.T) {
 		t.Errorf("expected exit code 1, got %d", exitCode)
 	}
 }
+
+func TestSetColorMode(t *testing.T) {
+	original := lipgloss.ColorProfile()
+	t.Cleanup(func() {
+		lipgloss.SetColorProfile(original)
+		plain = false
+	})
+
+	testCases := []struct {
+		name      string
+		mode      string
+		noColor   string
+		start     termenv.Profile
+		wantPlain bool
+		wantErr   bool
+	}{
+		{"auto keeps a color terminal", ColorAuto, "", termenv.TrueColor, false, false},
+		{"auto honors NO_COLOR", ColorAuto, "1", termenv.TrueColor, true, false},
+		{"empty is auto", "", "", termenv.ANSI256, false, false},
+		{"always colors piped output", ColorAlways, "", termenv.Ascii, false, false},
+		{"always ignores NO_COLOR", ColorAlways, "1", termenv.TrueColor, false, false},
+		{"never", ColorNever, "", termenv.TrueColor, true, false},
+		{"invalid", "sometimes", "", termenv.TrueColor, false, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			lipgloss.SetColorProfile(tc.start)
+			plain = false
+
+			_, err := SetColorMode(tc.mode)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SetColorMode(%q) error = %v, wantErr %v", tc.mode, err, tc.wantErr)
+			}
+			if Plain() != tc.wantPlain {
+				t.Errorf("Plain() = %v, want %v", Plain(), tc.wantPlain)
+			}
+		})
+	}
+}
+
+func TestBoxPlain(t *testing.T) {
+	plain = true
+	t.Cleanup(func() { plain = false })
+
+	if got := Box("test text"); got != "test text" {
+		t.Errorf("Box() in plain mode = %q, want the text unchanged", got)
+	}
+}
@@ -1,8 +1,77 @@
 // Package version provides version information for lleme.
 package version
 
-// Version is the application version, set via ldflags at build time.
-var Version = "dev"
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build information, set via ldflags at build time:
+//
+//	-X github.com/nchapman/lleme/internal/version.Version=1.2.3
+//	-X github.com/nchapman/lleme/internal/version.Commit=abc1234
+//	-X github.com/nchapman/lleme/internal/version.Date=2025-01-01T00:00:00Z
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the build information. Commit and date fall back to the VCS
+// stamp Go embeds in binaries built from a checkout without ldflags.
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		applyBuildSettings(&info, bi.Settings)
+	}
+	return info
+}
+
+func applyBuildSettings(info *Info, settings []debug.BuildSetting) {
+	// ldflags take precedence over the VCS stamp
+	if info.Commit != "" {
+		return
+	}
+	for _, s := range settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+}
+
+// ShortCommit returns the first 7 characters of the commit hash.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 7 {
+		return i.Commit[:7]
+	}
+	return i.Commit
+}
 
 // UserAgent returns the User-Agent string for HTTP requests.
 func UserAgent() string {
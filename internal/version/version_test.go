@@ -0,0 +1,33 @@
+package version
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestApplyBuildSettings(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "0123456789abcdef"},
+		{Key: "vcs.time", Value: "2025-01-02T03:04:05Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+
+	t.Run("fills from vcs stamp", func(t *testing.T) {
+		info := Info{}
+		applyBuildSettings(&info, settings)
+		if info.Commit != "0123456789abcdef" || info.Date != "2025-01-02T03:04:05Z" || !info.Modified {
+			t.Errorf("applyBuildSettings() = %+v", info)
+		}
+		if got := info.ShortCommit(); got != "0123456" {
+			t.Errorf("ShortCommit() = %s, want 0123456", got)
+		}
+	})
+
+	t.Run("ldflags take precedence", func(t *testing.T) {
+		info := Info{Commit: "abc", Date: "2024-12-31"}
+		applyBuildSettings(&info, settings)
+		if info.Commit != "abc" || info.Date != "2024-12-31" || info.Modified {
+			t.Errorf("applyBuildSettings() = %+v", info)
+		}
+	})
+}
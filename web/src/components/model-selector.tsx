@@ -21,10 +21,19 @@ function getDisplayName(id: string) {
 
 function ModelOption({ model }: { model: LlemeModel }) {
   const isLoaded = model.lleme?.status === "ready";
+  const tags = model.lleme?.tags ?? [];
   return (
-    <span className="flex items-center gap-2">
+    <span className="flex items-center gap-2" title={model.lleme?.note}>
       {isLoaded && <Circle className="size-2 fill-green-500 text-green-500" />}
       <span>{getDisplayName(model.id)}</span>
+      {tags.map((tag) => (
+        <span
+          key={tag}
+          className="rounded bg-muted px-1.5 text-xs text-muted-foreground"
+        >
+          {tag}
+        </span>
+      ))}
     </span>
   );
 }
@@ -11,10 +11,17 @@ export interface LlemeModel {
   created: number;
   owned_by: string;
   lleme?: {
-    status: string;
-    port: number;
-    last_activity: string;
-    loaded_at: string;
+    status?: string;
+    port?: number;
+    last_activity?: string;
+    loaded_at?: string;
+    tags?: string[];
+    note?: string;
+    quant?: string;
+    parameters?: number;
+    context_length?: number;
+    capabilities?: string[];
+    size_bytes?: number;
   };
 }
 